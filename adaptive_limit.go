@@ -0,0 +1,242 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Adaptive concurrency defaults applied when the config leaves a field
+// zero.
+const (
+	DefaultAdaptiveInitialLimit     = 10
+	DefaultAdaptiveMinLimit         = 1
+	DefaultAdaptiveBackoff          = 0.9
+	DefaultAdaptiveLatencyTolerance = 2.0
+	// adaptiveBaselineWindow is how many responses the latency baseline is
+	// taken over before it is measured afresh, so it follows an upstream
+	// that got lastingly slower instead of counting it as overloaded
+	// forever.
+	adaptiveBaselineWindow = 100
+)
+
+// AdaptiveConcurrencyConfig caps the requests in flight to each host at a
+// limit that adapts to how the host copes (AIMD): every response that is
+// neither a failure nor slow raises it by 1/limit, about one per round of
+// requests, and a failure or slow response cuts it by Backoff. During a
+// partial outage the limit falls to what the host still serves, instead of
+// a static cap piling requests onto it. It is disabled while MaxLimit is 0.
+type AdaptiveConcurrencyConfig struct {
+	// MaxLimit is the highest the limit grows to. 0 disables the limiter.
+	MaxLimit int `json:"max_limit"`
+	// InitialLimit is where each host's limit starts. Defaults to
+	// DefaultAdaptiveInitialLimit, capped at MaxLimit.
+	InitialLimit int `json:"initial_limit"`
+	// MinLimit is the lowest the limit falls to. Defaults to
+	// DefaultAdaptiveMinLimit.
+	MinLimit int `json:"min_limit"`
+	// Backoff multiplies the limit on a failure or slow response. Defaults
+	// to DefaultAdaptiveBackoff.
+	Backoff float64 `json:"backoff"`
+	// LatencyTolerance counts a response slower than this multiple of the
+	// host's fastest recent one as slow, the sign of a queue building up
+	// (as in TCP Vegas). Defaults to DefaultAdaptiveLatencyTolerance.
+	LatencyTolerance float64 `json:"latency_tolerance"`
+	// OnLimitChange, when set, is called when a host's limit moves to a new
+	// whole number.
+	OnLimitChange func(host string, from, to int) `json:"-"`
+}
+
+func (c AdaptiveConcurrencyConfig) enabled() bool { return c.MaxLimit > 0 }
+
+// AdaptiveLimiter holds one adaptive concurrency limit per host. Every
+// attempt, retries included, takes a slot until its response headers
+// arrive; callers wait for one rather than fail, up to their context's
+// deadline. A failure is a transport error, a 429, or a 5xx.
+type AdaptiveLimiter struct {
+	cfg AdaptiveConcurrencyConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+type hostLimit struct {
+	limit    float64
+	inFlight int
+	// waiters are granted slots in order; a granted waiter's channel is
+	// closed.
+	waiters []chan struct{}
+	// baseline is the fastest latency of the last full window, window the
+	// fastest of the current one.
+	baseline time.Duration
+	window   time.Duration
+	samples  int
+}
+
+// NewAdaptiveLimiter builds an AdaptiveLimiter from cfg.
+func NewAdaptiveLimiter(cfg AdaptiveConcurrencyConfig) *AdaptiveLimiter {
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = DefaultAdaptiveMinLimit
+	}
+	if cfg.MaxLimit < cfg.MinLimit {
+		cfg.MaxLimit = cfg.MinLimit
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = DefaultAdaptiveInitialLimit
+	}
+	if cfg.InitialLimit > cfg.MaxLimit {
+		cfg.InitialLimit = cfg.MaxLimit
+	}
+	if cfg.InitialLimit < cfg.MinLimit {
+		cfg.InitialLimit = cfg.MinLimit
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = DefaultAdaptiveBackoff
+	}
+	if cfg.LatencyTolerance <= 1 {
+		cfg.LatencyTolerance = DefaultAdaptiveLatencyTolerance
+	}
+	return &AdaptiveLimiter{cfg: cfg, hosts: make(map[string]*hostLimit)}
+}
+
+// WithAdaptiveLimiter limits every request through l, which may be shared
+// by several clients so they adapt to a host together.
+func WithAdaptiveLimiter(l *AdaptiveLimiter) Option {
+	return func(h *httpClient) {
+		h.adaptive = l
+	}
+}
+
+// Limit returns host's current limit, as "host:port" for explicit ports.
+func (l *AdaptiveLimiter) Limit(host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if hl, ok := l.hosts[host]; ok {
+		return int(hl.limit)
+	}
+	return l.cfg.InitialLimit
+}
+
+// Limits returns the current limit of every host seen so far.
+func (l *AdaptiveLimiter) Limits() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]int, len(l.hosts))
+	for host, hl := range l.hosts {
+		out[host] = int(hl.limit)
+	}
+	return out
+}
+
+// InFlight returns how many requests to host hold a slot.
+func (l *AdaptiveLimiter) InFlight(host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if hl, ok := l.hosts[host]; ok {
+		return hl.inFlight
+	}
+	return 0
+}
+
+// acquire blocks until a request to host may be sent, or ctx is done.
+func (l *AdaptiveLimiter) acquire(ctx context.Context, host string) error {
+	l.mu.Lock()
+	hl, ok := l.hosts[host]
+	if !ok {
+		hl = &hostLimit{limit: float64(l.cfg.InitialLimit)}
+		l.hosts[host] = hl
+	}
+	if len(hl.waiters) == 0 && hl.inFlight < int(hl.limit) {
+		hl.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	hl.waiters = append(hl.waiters, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, w := range hl.waiters {
+			if w == ready {
+				hl.waiters = append(hl.waiters[:i], hl.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// granted while giving up: pass the slot on
+		hl.inFlight--
+		l.grant(hl)
+		return ctx.Err()
+	}
+}
+
+// release frees a slot and adjusts the limit for the outcome: latency is
+// how long the host took, failed whether it failed, and counted false for
+// a request the caller gave up on, which says nothing about the host.
+func (l *AdaptiveLimiter) release(host string, latency time.Duration, failed, counted bool) {
+	l.mu.Lock()
+	hl := l.hosts[host]
+	hl.inFlight--
+	from := int(hl.limit)
+	if counted {
+		l.adjust(hl, latency, failed)
+	}
+	to := int(hl.limit)
+	l.grant(hl)
+	l.mu.Unlock()
+	if from != to && l.cfg.OnLimitChange != nil {
+		l.cfg.OnLimitChange(host, from, to)
+	}
+}
+
+func (l *AdaptiveLimiter) adjust(hl *hostLimit, latency time.Duration, failed bool) {
+	if !failed {
+		if hl.window == 0 || latency < hl.window {
+			hl.window = latency
+		}
+		if hl.baseline == 0 || latency < hl.baseline {
+			hl.baseline = latency
+		}
+		hl.samples++
+		if hl.samples >= adaptiveBaselineWindow {
+			hl.baseline, hl.window, hl.samples = hl.window, 0, 0
+		}
+	}
+	slow := hl.baseline > 0 && float64(latency) > float64(hl.baseline)*l.cfg.LatencyTolerance
+	if failed || slow {
+		hl.limit = math.Max(float64(l.cfg.MinLimit), hl.limit*l.cfg.Backoff)
+		return
+	}
+	hl.limit = math.Min(float64(l.cfg.MaxLimit), hl.limit+1/hl.limit)
+}
+
+// grant hands free slots to waiters in order.
+func (l *AdaptiveLimiter) grant(hl *hostLimit) {
+	for len(hl.waiters) > 0 && hl.inFlight < int(hl.limit) {
+		hl.inFlight++
+		close(hl.waiters[0])
+		hl.waiters = hl.waiters[1:]
+	}
+}
+
+func (l *AdaptiveLimiter) wrap(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		host := req.URL.Host
+		if err := l.acquire(req.Context(), host); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := next(req)
+		counted := err == nil || !(errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+		failed := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		l.release(host, time.Since(start), failed, counted)
+		return resp, err
+	}
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_AdaptiveLimiter_AIMD(t *testing.T) {
+	var changes []int
+	l := NewAdaptiveLimiter(AdaptiveConcurrencyConfig{
+		MaxLimit:      4,
+		InitialLimit:  2,
+		Backoff:       0.5,
+		OnLimitChange: func(host string, from, to int) { changes = append(changes, to) },
+	})
+	ctx := context.Background()
+	ok := func() {
+		if err := l.acquire(ctx, "h"); err != nil {
+			t.Fatal(err)
+		}
+		l.release("h", 10*time.Millisecond, false, true)
+	}
+
+	// each success adds 1/limit: two to go from 2 to 3, three more to 4
+	for i := 0; i < 20; i++ {
+		ok()
+	}
+	if got := l.Limit("h"); got != 4 {
+		t.Fatalf("limit after successes = %d, want MaxLimit 4", got)
+	}
+
+	_ = l.acquire(ctx, "h")
+	l.release("h", 10*time.Millisecond, true, true)
+	if got := l.Limit("h"); got != 2 {
+		t.Fatalf("limit after failure = %d, want 2", got)
+	}
+
+	// a response far slower than the baseline counts as congestion
+	_ = l.acquire(ctx, "h")
+	l.release("h", time.Second, false, true)
+	if got := l.Limit("h"); got != 1 {
+		t.Fatalf("limit after slow response = %d, want 1", got)
+	}
+
+	// a canceled request leaves the limit alone
+	_ = l.acquire(ctx, "h")
+	l.release("h", time.Second, true, false)
+	if got := l.Limit("h"); got != 1 || l.InFlight("h") != 0 {
+		t.Fatalf("limit = %d, in flight = %d", got, l.InFlight("h"))
+	}
+	if len(changes) != 4 || changes[0] != 3 || changes[3] != 1 {
+		t.Fatalf("changes = %v", changes)
+	}
+}
+
+func Test_AdaptiveLimiter_WaitsForSlot(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveConcurrencyConfig{MaxLimit: 1})
+	if err := l.acquire(context.Background(), "h"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, "h"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire over the limit = %v, want the context error", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- l.acquire(context.Background(), "h") }()
+	time.Sleep(10 * time.Millisecond)
+	l.release("h", time.Millisecond, false, true)
+	if err := <-acquired; err != nil || l.InFlight("h") != 1 {
+		t.Fatalf("waiter: err = %v, in flight = %d", err, l.InFlight("h"))
+	}
+}
+
+func Test_Client_AdaptiveConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	limiter := NewAdaptiveLimiter(AdaptiveConcurrencyConfig{MaxLimit: 8, InitialLimit: 4})
+	c := newTestClient(t, srv.URL, WithAdaptiveLimiter(limiter))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 4 {
+		t.Fatalf("peak concurrency = %d, want at most the initial limit 4", peak.Load())
+	}
+	if got := limiter.Limit(srv.Listener.Addr().String()); got != 1 {
+		t.Fatalf("limit after a run of 503s = %d, want 1", got)
+	}
+}
@@ -0,0 +1,195 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamReducer folds the events of one upstream stream into a single
+// result. A new reducer is made for every request.
+type StreamReducer interface {
+	// Add folds in a DATA frame; an error aborts the request with 502.
+	Add(frame StreamResponse) error
+	// Result is encoded as the JSON response once the stream ended.
+	Result() (any, error)
+}
+
+// CollectEvents returns a reducer whose result is the list of event
+// payloads, each as JSON when the data is valid JSON and as a string
+// otherwise.
+func CollectEvents() StreamReducer { return &collectReducer{items: []any{}} }
+
+type collectReducer struct{ items []any }
+
+func (c *collectReducer) Add(frame StreamResponse) error {
+	if json.Valid(frame.Body) {
+		c.items = append(c.items, json.RawMessage(append([]byte(nil), frame.Body...)))
+	} else {
+		c.items = append(c.items, string(frame.Body))
+	}
+	return nil
+}
+
+func (c *collectReducer) Result() (any, error) { return c.items, nil }
+
+// ConcatData returns a reducer whose result is the data of every event
+// joined, as {"data": "..."}: the usual way to collapse a token stream into
+// its text.
+func ConcatData() StreamReducer { return &concatReducer{} }
+
+type concatReducer struct{ b strings.Builder }
+
+func (c *concatReducer) Add(frame StreamResponse) error {
+	c.b.Write(frame.Body)
+	return nil
+}
+
+func (c *concatReducer) Result() (any, error) {
+	return map[string]string{"data": c.b.String()}, nil
+}
+
+// FoldStream returns a reducer factory for AggregateConfig.Reducer that
+// folds each frame into a typed accumulator starting at init.
+//
+//	Reducer: http.FoldStream(Usage{}, func(u Usage, f http.StreamResponse) (Usage, error) {
+//		var delta Usage
+//		err := json.Unmarshal(f.Body, &delta)
+//		return u.Plus(delta), err
+//	}),
+func FoldStream[T any](init T, fn func(acc T, frame StreamResponse) (T, error)) func() StreamReducer {
+	return func() StreamReducer { return &foldReducer[T]{acc: init, fn: fn} }
+}
+
+type foldReducer[T any] struct {
+	acc T
+	fn  func(T, StreamResponse) (T, error)
+}
+
+func (f *foldReducer[T]) Add(frame StreamResponse) error {
+	acc, err := f.fn(f.acc, frame)
+	if err != nil {
+		return err
+	}
+	f.acc = acc
+	return nil
+}
+
+func (f *foldReducer[T]) Result() (any, error) { return f.acc, nil }
+
+// AggregateConfig configures AggregateStream.
+type AggregateConfig struct {
+	// Request is the upstream streaming endpoint. The incoming body is sent
+	// as its body, with the incoming Content-Type unless Request sets one.
+	Request Request
+	// Translate, when set, builds the upstream request from the incoming
+	// request and its body instead of Request.
+	Translate func(r *http.Request, body []byte) (PostRequest, error)
+	// Reducer makes the reducer of each request; defaults to CollectEvents.
+	Reducer func() StreamReducer
+	// MaxBodyBytes caps the incoming body; 0 means 10 MB.
+	MaxBodyBytes int64
+}
+
+// defaultAggregateMaxBody is AggregateConfig.MaxBodyBytes when unset.
+const defaultAggregateMaxBody = 10 << 20
+
+// AggregateStream returns a handler that answers a plain POST by calling an
+// upstream streaming endpoint through c with PostStream, folding its events
+// with the configured reducer, and writing the result as one JSON response:
+// the inverse of a streaming proxy, shielding callers from streaming APIs.
+// A rejected upstream call answers with the upstream status, or 502 when
+// there was none; so does a stream that fails midway or a reducer error.
+// The upstream stream is cancelled as soon as the caller goes away.
+func AggregateStream(c Client, cfg AggregateConfig) http.Handler {
+	newReducer := cfg.Reducer
+	if newReducer == nil {
+		newReducer = CollectEvents
+	}
+	maxBody := cfg.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = defaultAggregateMaxBody
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		req, err := cfg.upstream(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		// one slot holds the error frame of a stream that fails to start
+		stream := make(chan StreamResponse, 1)
+		if err := c.PostStream(ctx, stream, req); err != nil {
+			writeBridgeError(w, err)
+			return
+		}
+		// the reader goroutine blocks on stream until it is drained
+		defer func() {
+			cancel()
+			for range stream {
+			}
+		}()
+
+		result, err := reduceStream(stream, newReducer())
+		if err != nil {
+			writeBridgeError(w, err)
+			return
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to marshal aggregated response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+}
+
+// upstream builds the upstream call for an incoming request.
+func (cfg AggregateConfig) upstream(r *http.Request, body []byte) (PostRequest, error) {
+	if cfg.Translate != nil {
+		return cfg.Translate(r, body)
+	}
+	req := cfg.Request
+	headers := make(map[string]string, len(req.Headers)+1)
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		headers["Content-Type"] = ct
+	}
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	req.Headers = headers
+	return PostRequest{Request: req, Body: body}, nil
+}
+
+// reduceStream folds the DATA frames of stream into reducer until the
+// stream ends. A stream that ends with an error other than the server
+// closing it fails the aggregation.
+func reduceStream(stream chan StreamResponse, reducer StreamReducer) (any, error) {
+	for frame := range stream {
+		switch frame.Type {
+		case StreamResponseTypeData:
+			if err := reducer.Add(frame); err != nil {
+				return nil, fmt.Errorf("failed to reduce stream event: %w", err)
+			}
+		case StreamResponseTypeEOF:
+			if frame.Error != nil && !errors.Is(frame.Error, io.EOF) {
+				return nil, fmt.Errorf("upstream stream failed: %w", frame.Error)
+			}
+			return reducer.Result()
+		}
+	}
+	return reducer.Result()
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sseUpstream answers every POST with the given events, after recording
+// the body and Content-Type it received.
+func sseUpstream(t *testing.T, got *string, events ...string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got != nil {
+			*got = r.Header.Get("Content-Type") + "|" + string(body)
+		}
+		w.Header().Set("Content-Type", ContentTypeEventStream)
+		for _, e := range events {
+			_, _ = io.WriteString(w, "data: "+e+"\n\n")
+		}
+	}))
+}
+
+func postAggregate(t *testing.T, h http.Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", ContentTypeJSON)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func Test_AggregateStream_CollectEvents(t *testing.T) {
+	var sent string
+	upstream := sseUpstream(t, &sent, `{"n":1}`, `plain`, `[DONE]`)
+	defer upstream.Close()
+
+	h := AggregateStream(newTestClient(t, upstream.URL), AggregateConfig{Request: Request{Path: "/stream"}})
+	rec := postAggregate(t, h, `{"q":"hi"}`)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ContentTypeJSON {
+		t.Fatalf("status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Body.String() != `[{"n":1},"plain"]` {
+		t.Fatalf("body = %s", rec.Body.String())
+	}
+	if sent != ContentTypeJSON+`|{"q":"hi"}` {
+		t.Fatalf("upstream got %q", sent)
+	}
+}
+
+func Test_AggregateStream_Reducers(t *testing.T) {
+	upstream := sseUpstream(t, nil, `Hel`, `lo`, `[DONE]`)
+	defer upstream.Close()
+	client := newTestClient(t, upstream.URL)
+
+	rec := postAggregate(t, AggregateStream(client, AggregateConfig{Request: Request{Path: "/"}, Reducer: ConcatData}), "")
+	if rec.Body.String() != `{"data":"Hello"}` {
+		t.Fatalf("concat body = %s", rec.Body.String())
+	}
+
+	count := FoldStream(0, func(n int, f StreamResponse) (int, error) { return n + len(f.Body), nil })
+	rec = postAggregate(t, AggregateStream(client, AggregateConfig{Request: Request{Path: "/"}, Reducer: count}), "")
+	var n int
+	if err := json.Unmarshal(rec.Body.Bytes(), &n); err != nil || n != 5 {
+		t.Fatalf("fold body = %s", rec.Body.String())
+	}
+}
+
+func Test_AggregateStream_Translate(t *testing.T) {
+	var sent string
+	upstream := sseUpstream(t, &sent, `x`)
+	defer upstream.Close()
+
+	h := AggregateStream(newTestClient(t, upstream.URL), AggregateConfig{
+		Translate: func(r *http.Request, body []byte) (PostRequest, error) {
+			return PostRequest{
+				Request: Request{Path: "/v2", Headers: map[string]string{"Content-Type": ContentTypeText}},
+				Body:    append([]byte("wrapped:"), body...),
+			}, nil
+		},
+	})
+	if rec := postAggregate(t, h, "in"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if sent != ContentTypeText+"|wrapped:in" {
+		t.Fatalf("upstream got %q", sent)
+	}
+}
+
+func Test_AggregateStream_Failures(t *testing.T) {
+	rejected := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer rejected.Close()
+	rec := postAggregate(t, AggregateStream(newTestClient(t, rejected.URL), AggregateConfig{}), "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("rejected upstream: status = %d", rec.Code)
+	}
+
+	upstream := sseUpstream(t, nil, `a`)
+	defer upstream.Close()
+	failing := FoldStream("", func(string, StreamResponse) (string, error) { return "", errors.New("bad event") })
+	rec = postAggregate(t, AggregateStream(newTestClient(t, upstream.URL), AggregateConfig{Reducer: failing}), "")
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "bad event") {
+		t.Fatalf("reducer error: status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+}
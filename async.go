@@ -0,0 +1,189 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Async dispatcher defaults applied when AsyncConfig leaves a field zero.
+const (
+	DefaultAsyncWorkers   = 4
+	DefaultAsyncQueueSize = 1024
+)
+
+// ErrAsyncQueueFull is handed to the ResponseHandler of an async call that
+// found the dispatcher's queue full; the call is not made.
+var ErrAsyncQueueFull = errors.New("async queue full")
+
+// AsyncConfig bounds the dispatcher behind GoGet, GoPost, and friends.
+type AsyncConfig struct {
+	// Workers caps the async calls made at once. Defaults to
+	// DefaultAsyncWorkers.
+	Workers int `json:"workers"`
+	// QueueSize caps the async calls waiting for a worker; once it is full
+	// new ones are rejected with ErrAsyncQueueFull instead of blocking.
+	// Defaults to DefaultAsyncQueueSize.
+	QueueSize int `json:"queue_size"`
+}
+
+// ResponseHandler receives the outcome of an async call, on a dispatcher
+// worker. It must not keep the worker long, as other calls wait for it.
+type ResponseHandler func(resp *Response, err error)
+
+// AsyncCaller is implemented by clients built with NewClient, for
+// fire-and-forget calls such as telemetry or analytics that must not block
+// a hot path:
+//
+//	if a, ok := client.(http.AsyncCaller); ok {
+//		a.GoPost(ctx, http.PostRequest{Request: http.Request{Path: "/events"}, Body: event}, nil)
+//	}
+//
+// Calls are queued and made by a bounded set of workers through the full
+// client, so rate limits, circuit breakers, retries, and Timeouts.Total
+// apply as to any call. They keep ctx's values, trace headers included, but
+// not its cancellation or deadline, so an async call made while handling a
+// request outlives the request. Drain waits for queued calls too. A nil
+// handler logs failures instead.
+type AsyncCaller interface {
+	GoGet(ctx context.Context, req GetRequest, done ResponseHandler)
+	GoPost(ctx context.Context, req PostRequest, done ResponseHandler)
+	GoPut(ctx context.Context, req PutRequest, done ResponseHandler)
+	GoPatch(ctx context.Context, req PatchRequest, done ResponseHandler)
+	GoDelete(ctx context.Context, req Request, done ResponseHandler)
+}
+
+var _ AsyncCaller = httpClient{}
+
+func (h httpClient) GoGet(ctx context.Context, req GetRequest, done ResponseHandler) {
+	h.goDo(ctx, http.MethodGet, req.Request, nil, done)
+}
+
+func (h httpClient) GoPost(ctx context.Context, req PostRequest, done ResponseHandler) {
+	h.goDo(ctx, http.MethodPost, req.Request, req.Body, done)
+}
+
+func (h httpClient) GoPut(ctx context.Context, req PutRequest, done ResponseHandler) {
+	h.goDo(ctx, http.MethodPut, req.Request, req.Body, done)
+}
+
+func (h httpClient) GoPatch(ctx context.Context, req PatchRequest, done ResponseHandler) {
+	h.goDo(ctx, http.MethodPatch, req.Request, req.Body, done)
+}
+
+func (h httpClient) GoDelete(ctx context.Context, req Request, done ResponseHandler) {
+	h.goDo(ctx, http.MethodDelete, req, nil, done)
+}
+
+// goDo queues one call. It is tracked as in flight from the moment it is
+// queued, so the call itself runs untracked and is not refused by Drain.
+func (h httpClient) goDo(ctx context.Context, method string, req Request, body []byte, done ResponseHandler) {
+	ctx, cancel := context.WithCancel(detachedContext{ctx})
+	release, err := h.inflight.track(cancel)
+	if err != nil {
+		cancel()
+		h.asyncDone(method, req, done, nil, err)
+		return
+	}
+	call := h
+	call.inflight = nil
+	queued := h.async.submit(func() {
+		defer func() { release(); cancel() }()
+		resp, err := call.do(ctx, method, req, body)
+		h.asyncDone(method, req, done, resp, err)
+	})
+	if !queued {
+		release()
+		cancel()
+		h.asyncDone(method, req, done, nil, ErrAsyncQueueFull)
+	}
+}
+
+// asyncDone hands an async call's outcome to done, recovering a panic in
+// it, or logs a failure when there is no handler.
+func (h httpClient) asyncDone(method string, req Request, done ResponseHandler, resp *Response, err error) {
+	if done == nil {
+		if err != nil {
+			h.logger.Warn("http-client", "type", "async", "method", method, "url", h.redact.url(req.Path), "error", err)
+		} else if resp.Reader != nil {
+			resp.Reader.Close()
+		}
+		return
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			h.logger.Error("http-client", "type", "async", "method", method, "url", h.redact.url(req.Path), "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+		}
+	}()
+	done(resp, err)
+}
+
+// dispatcher runs queued jobs on at most workers goroutines, started as
+// jobs arrive and stopped once the queue is empty. It is shared by every
+// copy of the client value.
+type dispatcher struct {
+	queue   chan func()
+	workers int
+
+	mu     sync.Mutex
+	active int
+}
+
+func newDispatcher(cfg AsyncConfig) *dispatcher {
+	workers, size := cfg.Workers, cfg.QueueSize
+	if workers <= 0 {
+		workers = DefaultAsyncWorkers
+	}
+	if size <= 0 {
+		size = DefaultAsyncQueueSize
+	}
+	return &dispatcher{queue: make(chan func(), size), workers: workers}
+}
+
+// submit queues job, reporting false when the queue is full.
+func (d *dispatcher) submit(job func()) bool {
+	select {
+	case d.queue <- job:
+	default:
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.active < d.workers {
+		d.active++
+		go d.work()
+	}
+	return true
+}
+
+func (d *dispatcher) work() {
+	for {
+		select {
+		case job := <-d.queue:
+			job()
+			continue
+		default:
+		}
+		d.mu.Lock()
+		// a job queued after the check above found this worker still active
+		if len(d.queue) == 0 {
+			d.active--
+			d.mu.Unlock()
+			return
+		}
+		d.mu.Unlock()
+	}
+}
+
+// detachedContext keeps the values of a context but not its cancellation
+// or deadline.
+type detachedContext struct{ parent context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+func (c detachedContext) Value(key any) any         { return c.parent.Value(key) }
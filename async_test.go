@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Client_GoPost_OutlivesCallerContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL).(AsyncCaller)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan *Response, 1)
+	c.GoPost(ctx, PostRequest{Request: Request{Path: "/events"}, Body: []byte("event")}, func(resp *Response, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		done <- resp
+	})
+	cancel()
+
+	select {
+	case resp := <-done:
+		if resp == nil || string(resp.Body) != "event" {
+			t.Fatalf("resp = %+v", resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not called")
+	}
+}
+
+func Test_Client_GoPost_QueueFullAndDrain(t *testing.T) {
+	arrived := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-unblock
+	}))
+	defer srv.Close()
+
+	client := NewClient(Config{BaseURL: srv.URL, Async: AsyncConfig{Workers: 1, QueueSize: 1}})
+	c := client.(AsyncCaller)
+	results := make(chan error, 3)
+	handler := func(_ *Response, err error) { results <- err }
+
+	c.GoPost(context.Background(), PostRequest{Request: Request{Path: "/1"}}, handler)
+	<-arrived // the only worker is busy
+	c.GoPost(context.Background(), PostRequest{Request: Request{Path: "/2"}}, handler)
+	c.GoPost(context.Background(), PostRequest{Request: Request{Path: "/3"}}, handler)
+	if err := <-results; !errors.Is(err, ErrAsyncQueueFull) {
+		t.Fatalf("third call: err = %v, want ErrAsyncQueueFull", err)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- client.(Drainer).Drain(context.Background()) }()
+	select {
+	case <-drained:
+		t.Fatal("Drain returned with calls queued")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("queued call: %v", err)
+		}
+	}
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	}
+
+	c.GoGet(context.Background(), GetRequest{Request: Request{Path: "/"}}, handler)
+	if err := <-results; !errors.Is(err, ErrClientDraining) {
+		t.Fatalf("after drain: err = %v", err)
+	}
+}
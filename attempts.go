@@ -0,0 +1,138 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// AttemptInfo records one try of a request.
+type AttemptInfo struct {
+	// Attempt is 1-based.
+	Attempt int
+	// StatusCode is zero when the attempt failed before a response arrived.
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	// Backoff is how long the client waited before this attempt; zero for the
+	// first.
+	Backoff time.Duration
+}
+
+// AttemptsError wraps the final error of a request that was tried more than
+// once, carrying every attempt. errors.Is/As see through it to Err.
+type AttemptsError struct {
+	Attempts []AttemptInfo
+	Err      error
+}
+
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("%v (after %d attempts)", e.Err, len(e.Attempts))
+}
+
+func (e *AttemptsError) Unwrap() error { return e.Err }
+
+// AttemptsOf returns the attempts recorded on err, or nil when the request
+// was tried only once.
+func AttemptsOf(err error) []AttemptInfo {
+	var aerr *AttemptsError
+	if errors.As(err, &aerr) {
+		return aerr.Attempts
+	}
+	return nil
+}
+
+// sendAttempts sends httpReq, retrying per h.retry, and records every
+// attempt. Each retry replays body on a clone of httpReq, re-signed when the
+// client signs requests so the server never sees a reused nonce. The last
+// response is returned even when it was retryable.
+func (h httpClient) sendAttempts(client *http.Client, httpReq *http.Request, body []byte) (*http.Response, []AttemptInfo, error) {
+	maxAttempts := h.retry.attempts(httpReq.Method)
+	// a streamed body (Request.BodyReader) is consumed by the first try
+	replayable := body != nil || httpReq.Body == nil || httpReq.Body == http.NoBody
+	if !replayable {
+		maxAttempts = 1
+	}
+	refreshed := false
+	attempts := make([]AttemptInfo, 0, maxAttempts)
+	ctx := httpReq.Context()
+
+	var backoff time.Duration
+	for n := 1; ; n++ {
+		req := httpReq
+		if n > 1 {
+			req = httpReq.Clone(ctx)
+			// share the trailer map, whose values may be filled while sending
+			req.Trailer = httpReq.Trailer
+			if body != nil {
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			if h.signingKey != nil {
+				if err := signRequest(req, *h.signingKey, body, time.Now()); err != nil {
+					return nil, attempts, fmt.Errorf("failed to sign request: %w", err)
+				}
+			}
+		}
+		if err := h.authenticate(req); err != nil {
+			return nil, attempts, fmt.Errorf("failed to authenticate request: %w", err)
+		}
+
+		start := time.Now()
+		resp, err := h.roundTrip(client, req)
+		attempt := AttemptInfo{Attempt: n, Err: err, Duration: time.Since(start), Backoff: backoff}
+		if resp != nil {
+			attempt.StatusCode = resp.StatusCode
+		}
+		attempts = append(attempts, attempt)
+
+		// a 401 gets one retry with refreshed credentials, outside the retry
+		// budget
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized && h.auth != nil && !refreshed && replayable {
+			ok, refreshErr := h.refreshAuth(ctx)
+			if refreshErr != nil {
+				resp.Body.Close()
+				return nil, attempts, fmt.Errorf("failed to refresh credentials: %w", refreshErr)
+			}
+			if ok {
+				refreshed = true
+				maxAttempts++
+				h.logger.Debug("http-client", "type", "auth-refresh", "method", req.Method, "url", h.redact.url(req.URL.String()))
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+				resp.Body.Close()
+				backoff = 0
+				continue
+			}
+		}
+
+		if n >= maxAttempts || !h.retry.retryOn(httpReq.Method, resp, err) {
+			return resp, attempts, err
+		}
+
+		backoff = h.retry.backoff(n, resp)
+		h.logger.Debug("http-client", "type", "retry", "method", req.Method, "url", h.redact.url(req.URL.String()), "attempt", n, "status", attempt.StatusCode, "error", err, "backoff", backoff)
+		if resp != nil {
+			// drain so the connection can be reused for the next attempt
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempts, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// withAttempts wraps err in an AttemptsError when more than one attempt ran.
+func withAttempts(err error, attempts []AttemptInfo) error {
+	if len(attempts) <= 1 {
+		return err
+	}
+	return &AttemptsError{Attempts: attempts, Err: err}
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Response_Attempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(resp.Attempts) != 1 || resp.Attempts[0].Attempt != 1 || resp.Attempts[0].StatusCode != http.StatusAccepted || resp.Attempts[0].Duration <= 0 {
+		t.Fatalf("Attempts = %+v", resp.Attempts)
+	}
+}
+
+func Test_AttemptsError(t *testing.T) {
+	attempts := []AttemptInfo{
+		{Attempt: 1, StatusCode: http.StatusServiceUnavailable, Duration: time.Millisecond},
+		{Attempt: 2, Err: io.ErrUnexpectedEOF, Backoff: 100 * time.Millisecond},
+	}
+	err := withAttempts(io.ErrUnexpectedEOF, attempts)
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("AttemptsError must unwrap to the final error")
+	}
+	if got := AttemptsOf(err); len(got) != 2 || got[1].Backoff != 100*time.Millisecond {
+		t.Fatalf("AttemptsOf = %+v", got)
+	}
+	if err.Error() != "unexpected EOF (after 2 attempts)" {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+	if single := withAttempts(io.EOF, attempts[:1]); single != io.EOF || AttemptsOf(single) != nil {
+		t.Fatal("a single attempt must leave the error untouched")
+	}
+}
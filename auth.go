@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuthProvider sets credentials on each outgoing request. It is called for
+// every attempt, so short-lived tokens are fetched fresh rather than baked
+// into Config.Headers. Implementations must be safe for concurrent use.
+type AuthProvider interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+// AuthRefresher is implemented by providers whose credentials can be renewed.
+// When a request comes back 401, the client calls Refresh and retries it
+// once with the new credentials.
+type AuthRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// TokenSource returns the current token, e.g. a JWT.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenFunc adapts a function to TokenSource.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Token implements TokenSource.
+func (f TokenFunc) Token(ctx context.Context) (string, error) { return f(ctx) }
+
+// WithAuth authenticates every request through p, retrying once after a
+// refresh when p is an AuthRefresher and the server answers 401. Requests
+// with a streamed body are not retried.
+func WithAuth(p AuthProvider) Option {
+	return func(h *httpClient) {
+		h.auth = p
+	}
+}
+
+// BearerAuth sends "Authorization: Bearer <token>" with tokens from src. It
+// refreshes when src is an AuthRefresher, as CachedToken is.
+func BearerAuth(src TokenSource) AuthProvider {
+	return bearerAuth{src: src}
+}
+
+type bearerAuth struct {
+	src TokenSource
+}
+
+func (b bearerAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := b.src.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (b bearerAuth) Refresh(ctx context.Context) error {
+	if r, ok := b.src.(AuthRefresher); ok {
+		return r.Refresh(ctx)
+	}
+	return errNotRefreshable
+}
+
+// errNotRefreshable tells the client a 401 cannot be fixed by refreshing.
+var errNotRefreshable = errors.New("credentials cannot be refreshed")
+
+// BasicAuth sends HTTP basic credentials.
+func BasicAuth(username, password string) AuthProvider {
+	return basicAuth{username: username, password: password}
+}
+
+type basicAuth struct {
+	username, password string
+}
+
+func (b basicAuth) Authenticate(_ context.Context, req *http.Request) error {
+	req.SetBasicAuth(b.username, b.password)
+	return nil
+}
+
+// APIKeyAuth sends key in header, e.g. APIKeyAuth("X-API-Key", key).
+func APIKeyAuth(header, key string) AuthProvider {
+	return apiKeyAuth{header: header, key: key}
+}
+
+type apiKeyAuth struct {
+	header, key string
+}
+
+func (a apiKeyAuth) Authenticate(_ context.Context, req *http.Request) error {
+	req.Header.Set(a.header, a.key)
+	return nil
+}
+
+// CachedToken is a TokenSource that caches the token fetch returns until
+// shortly before it expires. Concurrent callers share one fetch. Refresh
+// discards the cached token, so a 401 triggers a new fetch.
+type CachedToken struct {
+	fetch func(ctx context.Context) (token string, expiresAt time.Time, err error)
+	// leeway renews the token this long before it expires.
+	leeway time.Duration
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+var (
+	_ TokenSource   = (*CachedToken)(nil)
+	_ AuthRefresher = (*CachedToken)(nil)
+)
+
+// NewCachedToken builds a CachedToken around fetch, renewing leeway before
+// the expiry fetch reports. A zero expiresAt means the token never expires.
+func NewCachedToken(fetch func(ctx context.Context) (string, time.Time, error), leeway time.Duration) *CachedToken {
+	return &CachedToken{fetch: fetch, leeway: leeway}
+}
+
+// Token returns the cached token, fetching a new one when it is missing or
+// about to expire.
+func (c *CachedToken) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.expiresAt.IsZero() || time.Now().Add(c.leeway).Before(c.expiresAt)) {
+		return c.token, nil
+	}
+	token, expiresAt, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiresAt = token, expiresAt
+	return token, nil
+}
+
+// Refresh drops the cached token; the next Token call fetches a new one.
+func (c *CachedToken) Refresh(context.Context) error {
+	c.mu.Lock()
+	c.token = ""
+	c.mu.Unlock()
+	return nil
+}
+
+// authenticate stamps the service identity token and applies h.auth to
+// req, if any.
+func (h httpClient) authenticate(req *http.Request) error {
+	if err := h.stampServiceToken(req); err != nil {
+		return err
+	}
+	if h.auth == nil {
+		return nil
+	}
+	return h.auth.Authenticate(req.Context(), req)
+}
+
+// refreshAuth renews the credentials after a 401. ok is false when the
+// provider cannot refresh.
+func (h httpClient) refreshAuth(ctx context.Context) (ok bool, err error) {
+	r, isRefresher := h.auth.(AuthRefresher)
+	if !isRefresher {
+		return false, nil
+	}
+	if err := r.Refresh(ctx); err != nil {
+		if errors.Is(err, errNotRefreshable) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
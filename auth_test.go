@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Client_WithAuth_Schemes(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name           string
+		auth           AuthProvider
+		header, wanted string
+	}{
+		{"bearer", BearerAuth(TokenFunc(func(context.Context) (string, error) { return "tok", nil })), "Authorization", "Bearer tok"},
+		{"basic", BasicAuth("user", "pass"), "Authorization", "Basic dXNlcjpwYXNz"},
+		{"api key", APIKeyAuth("X-API-Key", "k1"), "X-Api-Key", "k1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, srv.URL, WithAuth(tc.auth))
+			if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+				t.Fatal(err)
+			}
+			if got.Get(tc.header) != tc.wanted {
+				t.Fatalf("%s = %q want %q", tc.header, got.Get(tc.header), tc.wanted)
+			}
+		})
+	}
+}
+
+func Test_Client_WithAuth_RefreshesOn401(t *testing.T) {
+	var fetches atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// only the second token is accepted
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	token := NewCachedToken(func(context.Context) (string, time.Time, error) {
+		n := fetches.Add(1)
+		return "token-" + strconv.FormatInt(n, 10), time.Now().Add(time.Hour), nil
+	}, time.Minute)
+	c := newTestClient(t, srv.URL, WithAuth(BearerAuth(token)))
+
+	resp, err := c.Post(context.Background(), PostRequest{Request: Request{Path: "/"}, Body: []byte("{}")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "ok" || fetches.Load() != 2 {
+		t.Fatalf("status=%d body=%q fetches=%d", resp.StatusCode, resp.Body, fetches.Load())
+	}
+
+	// the refreshed token is cached
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil || fetches.Load() != 2 {
+		t.Fatalf("err=%v fetches=%d", err, fetches.Load())
+	}
+}
+
+func Test_Client_WithAuth_RetriesOnlyOnce(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	token := NewCachedToken(func(context.Context) (string, time.Time, error) { return "bad", time.Time{}, nil }, 0)
+	resp, err := newTestClient(t, srv.URL, WithAuth(BearerAuth(token))).Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || calls.Load() != 2 {
+		t.Fatalf("status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+
+	// static credentials are not retried
+	calls.Store(0)
+	if _, err := newTestClient(t, srv.URL, WithAuth(BasicAuth("u", "p"))).Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d", calls.Load())
+	}
+}
+
+func Test_Client_WithAuth_TokenError(t *testing.T) {
+	boom := errors.New("idp down")
+	c := newTestClient(t, "http://127.0.0.1:1", WithAuth(BearerAuth(TokenFunc(func(context.Context) (string, error) { return "", boom }))))
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); !errors.Is(err, boom) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func Test_CachedToken_RenewsBeforeExpiry(t *testing.T) {
+	var fetches int
+	token := NewCachedToken(func(context.Context) (string, time.Time, error) {
+		fetches++
+		return "t", time.Now().Add(30 * time.Second), nil
+	}, time.Minute)
+	_, _ = token.Token(context.Background())
+	_, _ = token.Token(context.Background())
+	if fetches != 2 {
+		t.Fatalf("fetches = %d, want a fetch per call inside the leeway", fetches)
+	}
+}
@@ -25,7 +25,7 @@ func Benchmark_BuildRequestParams(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, _, err := h.buildRequestParams(req); err != nil {
+		if _, _, err := h.buildRequestParams(context.Background(), req); err != nil {
 			b.Fatalf("buildRequestParams returned error: %v", err)
 		}
 	}
@@ -77,9 +77,9 @@ func Benchmark_Client_PostStream_SSE(b *testing.B) {
 		w.WriteHeader(http.StatusOK)
 		for i := 0; i < 10; i++ {
 			_, _ = io.WriteString(w, "event: tick\n")
-			_, _ = io.WriteString(w, "data: payload\n")
+			_, _ = io.WriteString(w, "data: payload\n\n")
 		}
-		_, _ = io.WriteString(w, "data: [DONE]\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
 	}))
 	defer srv.Close()
 
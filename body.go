@@ -0,0 +1,110 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// errStreamedBodySigning is returned when a signing client is asked to send a
+// Request.BodyReader: the signature covers a hash of the whole body, which a
+// stream does not have up front.
+var errStreamedBodySigning = errors.New("streamed request bodies cannot be signed")
+
+// ProgressFunc reports upload progress: bytes sent so far and the total, or
+// -1 when the total is unknown.
+type ProgressFunc func(sent, total int64)
+
+// newBodyRequest builds the *http.Request for req, sending req.BodyReader
+// when set and body otherwise, and reporting progress to req.OnProgress.
+func newBodyRequest(ctx context.Context, method, path string, req Request, body []byte) (*http.Request, error) {
+	var (
+		reader io.Reader
+		size   int64 = -1
+	)
+	switch {
+	case req.BodyReader != nil:
+		reader = req.BodyReader
+		if l, ok := reader.(interface{ Len() int }); ok {
+			size = int64(l.Len())
+		}
+	case body != nil:
+		reader, size = bytes.NewReader(body), int64(len(body))
+	default:
+		httpReq, err := http.NewRequestWithContext(ctx, method, path, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Host = req.Host
+		return httpReq, nil
+	}
+	if req.OnProgress != nil {
+		reader = &progressReader{r: reader, total: size, fn: req.OnProgress}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, path, reader)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Host = req.Host
+	if size >= 0 {
+		httpReq.ContentLength = size
+	}
+	if len(req.Trailers) > 0 {
+		// trailers need chunked encoding, which a known length rules out
+		httpReq.Trailer = req.Trailers
+		httpReq.ContentLength = -1
+	}
+	if body != nil && req.BodyReader == nil {
+		httpReq.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return httpReq, nil
+}
+
+// progressReader counts bytes as the transport reads the body.
+type progressReader struct {
+	r     io.Reader
+	sent  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		p.fn(p.sent, p.total)
+	}
+	return n, err
+}
+
+// Close closes the underlying reader when it is closable, so an abandoned
+// pipe-backed body (see MultipartRequest) releases its writer.
+func (p *progressReader) Close() error {
+	if c, ok := p.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// trailerReader copies the response trailers into dst once the body has
+// been read to the end, when net/http fills them in.
+type trailerReader struct {
+	io.ReadCloser
+	resp *http.Response
+	dst  http.Header
+}
+
+func (t *trailerReader) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if err == io.EOF {
+		for k, v := range t.resp.Trailer {
+			t.dst[k] = v
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,245 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, without contacting the host, for requests to a
+// host whose circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of one host's circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through and counts failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects requests with ErrCircuitOpen until OpenTimeout
+	// has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets a few probe requests through; their outcome
+	// closes or reopens the breaker.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Circuit breaker defaults applied when the config leaves a field zero.
+const (
+	DefaultBreakerOpenTimeout = 30 * time.Second
+	DefaultBreakerWindow      = time.Minute
+	DefaultBreakerMinRequests = 10
+)
+
+// CircuitBreakerConfig configures a per-host circuit breaker. A failure is a
+// transport error or a 5xx response. It is disabled while neither
+// ConsecutiveFailures nor FailureRatio is set.
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures opens the breaker after this many failures in a
+	// row.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// FailureRatio opens the breaker when at least this fraction of the
+	// requests in the current Window failed, once MinRequests were seen.
+	FailureRatio float64 `json:"failure_ratio"`
+	// MinRequests is how many requests a Window needs before FailureRatio
+	// applies. Defaults to DefaultBreakerMinRequests.
+	MinRequests int `json:"min_requests"`
+	// Window is the span FailureRatio is measured over. Defaults to
+	// DefaultBreakerWindow.
+	Window time.Duration `json:"window"`
+	// OpenTimeout is how long the breaker stays open before probing.
+	// Defaults to DefaultBreakerOpenTimeout.
+	OpenTimeout time.Duration `json:"open_timeout"`
+	// HalfOpenRequests is how many probes run while half-open; all must
+	// succeed to close the breaker. Defaults to 1.
+	HalfOpenRequests int `json:"half_open_requests"`
+	// OnStateChange, when set, is called on every transition, e.g. to log
+	// or alert when a host's breaker trips.
+	OnStateChange func(host string, from, to BreakerState) `json:"-"`
+}
+
+func (c CircuitBreakerConfig) enabled() bool {
+	return c.ConsecutiveFailures > 0 || c.FailureRatio > 0
+}
+
+// CircuitBreaker tracks one breaker per host. Keep the pointer to inspect it
+// with State and States.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+type hostBreaker struct {
+	state       BreakerState
+	consecutive int
+	windowStart time.Time
+	total       int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+// NewCircuitBreaker builds a CircuitBreaker from cfg.
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultBreakerMinRequests
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultBreakerWindow
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultBreakerOpenTimeout
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &CircuitBreaker{cfg: cfg, hosts: make(map[string]*hostBreaker)}
+}
+
+// WithCircuitBreaker guards every request with b, which may be shared by
+// several clients.
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(h *httpClient) {
+		h.breaker = b
+	}
+}
+
+// State returns the state of host's breaker, as "host:port" for explicit
+// ports.
+func (b *CircuitBreaker) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if hb, ok := b.hosts[host]; ok {
+		return hb.state
+	}
+	return BreakerClosed
+}
+
+// States returns the state of every host seen so far.
+func (b *CircuitBreaker) States() map[string]BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make(map[string]BreakerState, len(b.hosts))
+	for host, hb := range b.hosts {
+		out[host] = hb.state
+	}
+	return out
+}
+
+// allow reports whether a request to host may go out now.
+func (b *CircuitBreaker) allow(host string, now time.Time) error {
+	b.mu.Lock()
+	hb, ok := b.hosts[host]
+	if !ok {
+		hb = &hostBreaker{windowStart: now}
+		b.hosts[host] = hb
+	}
+	from := hb.state
+	var err error
+	switch hb.state {
+	case BreakerOpen:
+		if now.Sub(hb.openedAt) < b.cfg.OpenTimeout {
+			err = fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+			break
+		}
+		hb.state, hb.probes, hb.successes = BreakerHalfOpen, 1, 0
+	case BreakerHalfOpen:
+		if hb.probes >= b.cfg.HalfOpenRequests {
+			err = fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+			break
+		}
+		hb.probes++
+	}
+	to := hb.state
+	b.mu.Unlock()
+	b.notify(host, from, to)
+	return err
+}
+
+// record counts the outcome of a request to host.
+func (b *CircuitBreaker) record(host string, failed bool, now time.Time) {
+	b.mu.Lock()
+	hb := b.hosts[host]
+	from := hb.state
+	switch hb.state {
+	case BreakerHalfOpen:
+		if failed {
+			hb.state, hb.openedAt = BreakerOpen, now
+			break
+		}
+		hb.successes++
+		if hb.successes >= b.cfg.HalfOpenRequests {
+			*hb = hostBreaker{windowStart: now}
+		}
+	case BreakerClosed:
+		if now.Sub(hb.windowStart) >= b.cfg.Window {
+			hb.windowStart, hb.total, hb.failures = now, 0, 0
+		}
+		hb.total++
+		if failed {
+			hb.failures++
+			hb.consecutive++
+		} else {
+			hb.consecutive = 0
+		}
+		tripped := b.cfg.ConsecutiveFailures > 0 && hb.consecutive >= b.cfg.ConsecutiveFailures
+		if b.cfg.FailureRatio > 0 && hb.total >= b.cfg.MinRequests && float64(hb.failures)/float64(hb.total) >= b.cfg.FailureRatio {
+			tripped = true
+		}
+		if tripped {
+			hb.state, hb.openedAt = BreakerOpen, now
+		}
+	}
+	to := hb.state
+	b.mu.Unlock()
+	b.notify(host, from, to)
+}
+
+func (b *CircuitBreaker) notify(host string, from, to BreakerState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(host, from, to)
+	}
+}
+
+func (b *CircuitBreaker) wrap(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		host := req.URL.Host
+		if err := b.allow(host, time.Now()); err != nil {
+			return nil, err
+		}
+		resp, err := next(req)
+		// the caller giving up says nothing about the host
+		if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			b.undo(host)
+			return resp, err
+		}
+		b.record(host, err != nil || resp.StatusCode >= http.StatusInternalServerError, time.Now())
+		return resp, err
+	}
+}
+
+// undo releases the half-open probe slot of a request that was canceled.
+func (b *CircuitBreaker) undo(host string) {
+	b.mu.Lock()
+	if hb := b.hosts[host]; hb.state == BreakerHalfOpen && hb.probes > 0 {
+		hb.probes--
+	}
+	b.mu.Unlock()
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_CircuitBreaker_ConsecutiveFailures(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	var transitions []string
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         50 * time.Millisecond,
+		OnStateChange: func(host string, from, to BreakerState) {
+			transitions = append(transitions, from.String()+">"+to.String())
+		},
+	})
+	c := newTestClient(t, srv.URL, WithCircuitBreaker(breaker))
+	get := func() error {
+		_, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	host := srv.Listener.Addr().String()
+	if breaker.State(host) != BreakerOpen {
+		t.Fatalf("state = %v", breaker.State(host))
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) || calls.Load() != 3 {
+		t.Fatalf("open breaker: err=%v calls=%d", err, calls.Load())
+	}
+
+	// after the open timeout a failing probe reopens it, a passing one closes it
+	time.Sleep(60 * time.Millisecond)
+	_ = get()
+	if breaker.State(host) != BreakerOpen {
+		t.Fatalf("state after failed probe = %v", breaker.State(host))
+	}
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v", transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions = %v", transitions)
+		}
+	}
+}
+
+func Test_CircuitBreaker_FailureRatio(t *testing.T) {
+	b := NewCircuitBreaker(CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4})
+	now := time.Now()
+	for _, failed := range []bool{true, false, true} {
+		_ = b.allow("h", now)
+		b.record("h", failed, now)
+	}
+	if b.State("h") != BreakerClosed {
+		t.Fatal("opened below MinRequests")
+	}
+	_ = b.allow("h", now)
+	b.record("h", false, now)
+	if b.States()["h"] != BreakerOpen {
+		t.Fatalf("states = %v", b.States())
+	}
+}
+
+func Test_CircuitBreaker_NotRetried(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{
+		BaseURL:        srv.URL,
+		Retry:          RetryConfig{MaxAttempts: 5, BaseDelay: time.Millisecond},
+		CircuitBreaker: CircuitBreakerConfig{ConsecutiveFailures: 2},
+	})
+	_, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	if !errors.Is(err, ErrCircuitOpen) || len(AttemptsOf(err)) != 3 {
+		t.Fatalf("err = %v, attempts = %d", err, len(AttemptsOf(err)))
+	}
+}
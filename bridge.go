@@ -0,0 +1,214 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// BinaryEventName is the SSE event type WebSocketToSSE gives binary
+// messages, whose data is base64 encoded.
+const BinaryEventName = "binary"
+
+// WebSocketToSSE returns a handler that opens req as a WebSocket through c
+// for each incoming request and relays its messages to the caller as
+// Server-Sent Events: text messages as default "message" events, binary
+// messages base64 encoded under BinaryEventName. A failed upstream handshake
+// answers with the upstream status, or 502 when there was none. The upstream
+// connection is closed as soon as the caller goes away, and the event stream
+// ends when the upstream closes.
+func WebSocketToSSE(c Client, req Request) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		upstream, err := c.WebSocket(r.Context(), req)
+		if err != nil {
+			writeBridgeError(w, err)
+			return
+		}
+		defer upstream.Close()
+
+		// closing the upstream is the only way to interrupt a blocked read
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-r.Context().Done():
+				_ = upstream.CloseWithReason(WebSocketCloseGoingAway, "")
+			case <-done:
+			}
+		}()
+
+		h := w.Header()
+		h.Set("Content-Type", ContentTypeEventStream)
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			typ, data, err := upstream.ReadMessage()
+			if err != nil {
+				return
+			}
+			event := ""
+			if typ == WebSocketBinary {
+				event, data = BinaryEventName, []byte(base64.StdEncoding.EncodeToString(data))
+			}
+			if _, err := io.WriteString(w, formatSSEEvent(event, data)); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+// SSEToWebSocket returns a handler that accepts a WebSocket from the caller,
+// opens req as an event stream through c, and forwards the data of each
+// event as a text message. Messages from the caller are read and discarded
+// so pings are answered and a close is noticed, which cancels the upstream.
+// The socket closes normally when the upstream stream ends, or with
+// "going away" when it fails. The upstream is opened before the upgrade, so
+// a rejected stream answers with the upstream status, or 502 when there was
+// none.
+func SSEToWebSocket(c Client, req Request) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkWebSocketUpgrade(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		// one slot holds the error frame of a stream that fails to start
+		stream := make(chan StreamResponse, 1)
+		if err := c.GetStream(ctx, stream, req); err != nil {
+			writeBridgeError(w, err)
+			return
+		}
+		// the reader goroutine blocks on stream until it is drained
+		defer func() {
+			cancel()
+			for range stream {
+			}
+		}()
+
+		conn, err := acceptWebSocket(w, r)
+		if err != nil {
+			if !errors.Is(err, errWebSocketHijacked) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		defer conn.Close()
+
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for frame := range stream {
+			switch frame.Type {
+			case StreamResponseTypeData:
+				if err := conn.WriteMessage(WebSocketText, frame.Body); err != nil {
+					return
+				}
+			case StreamResponseTypeEOF:
+				// a server ending the body is a normal end of stream
+				if frame.Error != nil && !errors.Is(frame.Error, io.EOF) {
+					_ = conn.CloseWithReason(WebSocketCloseGoingAway, "upstream stream failed")
+				}
+				return
+			}
+		}
+	})
+}
+
+// writeBridgeError answers a failed upstream call with its status, or 502.
+func writeBridgeError(w http.ResponseWriter, err error) {
+	status := StatusOf(err)
+	if status == 0 {
+		status = http.StatusBadGateway
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// formatSSEEvent renders data as one event, one data: line per line of data.
+func formatSSEEvent(event string, data []byte) string {
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// errWebSocketHijacked reports a failure after the connection was taken
+// over, when no HTTP response can be written any more.
+var errWebSocketHijacked = errors.New("websocket: upgrade failed after hijack")
+
+// checkWebSocketUpgrade validates the handshake of an incoming upgrade.
+func checkWebSocketUpgrade(r *http.Request) error {
+	switch {
+	case r.Method != http.MethodGet:
+		return fmt.Errorf("%w: upgrade must use GET", errWebSocketProtocol)
+	case !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket"):
+		return fmt.Errorf("%w: not a websocket upgrade", errWebSocketProtocol)
+	case r.Header.Get(WebSocketVersionHeaderName) != "13":
+		return fmt.Errorf("%w: unsupported version", errWebSocketProtocol)
+	case r.Header.Get(WebSocketKeyHeaderName) == "":
+		return fmt.Errorf("%w: missing key", errWebSocketProtocol)
+	}
+	return nil
+}
+
+// acceptWebSocket completes the server side of a handshake checked by
+// checkWebSocketUpgrade and returns the connection in the server role.
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("%w: connection cannot be hijacked", errWebSocketProtocol)
+	}
+	netConn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+	_, err = io.WriteString(netConn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		WebSocketAcceptHeaderName+": "+websocketAccept(r.Header.Get(WebSocketKeyHeaderName))+"\r\n\r\n")
+	if err != nil {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("%w: %v", errWebSocketHijacked, err)
+	}
+	br := brw.Reader
+	if br == nil {
+		br = bufio.NewReader(netConn)
+	}
+	return &WebSocketConn{rwc: netConn, br: br, server: true}, nil
+}
+
+// headerContainsToken reports whether a comma-separated header has token.
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
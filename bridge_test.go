@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_WebSocketToSSE_RelaysMessages(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			WebSocketAcceptHeaderName+": "+websocketAccept(r.Header.Get(WebSocketKeyHeaderName))+"\r\n\r\n")
+		_ = writeWebSocketFrame(conn, opText, []byte("line one\nline two"), false)
+		_ = writeWebSocketFrame(conn, opBinary, []byte{0xff, 0x00}, false)
+		_ = writeWebSocketFrame(conn, opClose, []byte{0x03, 0xe8}, false)
+	}))
+	defer upstream.Close()
+
+	bridge := httptest.NewServer(WebSocketToSSE(newTestClient(t, upstream.URL), Request{Path: "/ws"}))
+	defer bridge.Close()
+
+	stream := make(chan StreamResponse)
+	if err := newTestClient(t, bridge.URL).GetStream(context.Background(), stream, Request{Path: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for frame := range stream {
+		if frame.Type == StreamResponseTypeData {
+			got = append(got, frame.Event+"|"+string(frame.Body))
+		}
+	}
+	want := []string{"|line one\nline two", "binary|" + base64.StdEncoding.EncodeToString([]byte{0xff, 0x00})}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+}
+
+func Test_WebSocketToSSE_UpstreamRejected(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer upstream.Close()
+
+	bridge := httptest.NewServer(WebSocketToSSE(newTestClient(t, upstream.URL), Request{Path: "/ws"}))
+	defer bridge.Close()
+
+	resp, err := http.Get(bridge.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+}
+
+func Test_SSEToWebSocket_ForwardsEvents(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: first\n\nevent: update\ndata: second\n\n")
+	}))
+	defer upstream.Close()
+
+	bridge := httptest.NewServer(SSEToWebSocket(newTestClient(t, upstream.URL), Request{Path: "/events"}))
+	defer bridge.Close()
+
+	conn, err := newTestClient(t, "ws"+strings.TrimPrefix(bridge.URL, "http")).WebSocket(context.Background(), Request{Path: "/"})
+	if err != nil {
+		t.Fatalf("WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	for _, want := range []string{"first", "second"} {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if typ != WebSocketText || string(data) != want {
+			t.Fatalf("got %d %q, want %q", typ, data, want)
+		}
+	}
+	var cerr *WebSocketCloseError
+	if _, _, err := conn.ReadMessage(); !errors.As(err, &cerr) || cerr.Code != WebSocketCloseNormal {
+		t.Fatalf("err = %v, want a normal close", err)
+	}
+}
+
+func Test_SSEToWebSocket_RejectsPlainRequests(t *testing.T) {
+	bridge := httptest.NewServer(SSEToWebSocket(newTestClient(t, "http://127.0.0.1:1"), Request{Path: "/"}))
+	defer bridge.Close()
+
+	resp, err := http.Get(bridge.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+// DefaultBrowserUserAgent is the User-Agent browser mode sends unless
+// Config.UserAgent or Config.Headers set one.
+const DefaultBrowserUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+// DefaultBrowserMaxRedirects is how many redirects browser mode follows,
+// as Chrome does.
+const DefaultBrowserMaxRedirects = 20
+
+// browserHeaders are the request headers browser mode defaults.
+var browserHeaders = map[string]string{
+	"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+	"Accept-Language": "en-US,en;q=0.9",
+	"Accept-Encoding": "gzip, deflate",
+}
+
+// BrowserConfig switches the client from strict API-client defaults to a
+// browser's, for scraping and automation: cookies are kept in a jar and
+// sent back, redirects carry a Referer under the strict-origin-when-cross-
+// origin policy and drop Authorization once they leave the origin, gzip and
+// deflate responses are accepted and decoded, and requests send a browser's
+// User-Agent, Accept, and Accept-Language unless the config sets them.
+type BrowserConfig struct {
+	// Enabled turns browser mode on.
+	Enabled bool `json:"enabled"`
+	// MaxRedirects caps the redirects followed. Defaults to
+	// DefaultBrowserMaxRedirects.
+	MaxRedirects int `json:"max_redirects"`
+	// Jar keeps the cookies, e.g. one persisted between runs. Defaults to an
+	// in-memory jar without a public suffix list, which lets a site set
+	// cookies for its whole registrable domain but cannot stop one from
+	// setting them for a public suffix such as co.uk. A client given via
+	// WithHTTPClient keeps its own jar.
+	Jar http.CookieJar `json:"-"`
+}
+
+// headers adds the browser's default headers to headers, keeping those it
+// already sets whatever their case.
+func (c BrowserConfig) headers(headers map[string]string) map[string]string {
+	defaults := map[string]string{ClientUserAgentHeaderName: DefaultBrowserUserAgent}
+	for name, value := range browserHeaders {
+		defaults[name] = value
+	}
+	for name, value := range defaults {
+		if !hasHeader(headers, name) {
+			headers[name] = value
+		}
+	}
+	return headers
+}
+
+// apply returns a copy of base with a cookie jar and browser redirect
+// semantics.
+func (c BrowserConfig) apply(base *http.Client) (*http.Client, error) {
+	client := *base
+	if client.Jar == nil {
+		client.Jar = c.Jar
+	}
+	if client.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return base, fmt.Errorf("failed to create cookie jar: %w", err)
+		}
+		client.Jar = jar
+	}
+
+	limit := c.MaxRedirects
+	if limit <= 0 {
+		limit = DefaultBrowserMaxRedirects
+	}
+	next := base.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= limit {
+			return fmt.Errorf("stopped after %d redirects", limit)
+		}
+		prev := via[len(via)-1].URL
+		if referer := browserReferer(prev, req.URL); referer != "" {
+			req.Header.Set("Referer", referer)
+		} else {
+			req.Header.Del("Referer")
+		}
+		if !sameOrigin(prev, req.URL) {
+			req.Header.Del("Authorization")
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
+	}
+	return &client, nil
+}
+
+// browserReferer is the Referer a browser sends when from leads to to under
+// the strict-origin-when-cross-origin policy: the full URL within an
+// origin, only the origin across origins, and nothing from https to http.
+// Credentials and fragments are never sent.
+func browserReferer(from, to *url.URL) string {
+	if strings.EqualFold(from.Scheme, "https") && !strings.EqualFold(to.Scheme, "https") {
+		return ""
+	}
+	ref := *from
+	ref.User, ref.Fragment, ref.RawFragment = nil, "", ""
+	if !sameOrigin(from, to) {
+		ref.Path, ref.RawPath, ref.RawQuery, ref.ForceQuery = "/", "", "", false
+	}
+	return ref.String()
+}
+
+// sameOrigin reports whether a and b share scheme, host, and port.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(originHost(a), originHost(b))
+}
+
+// originHost is u's host with its scheme's default port made explicit.
+func originHost(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return u.Host + ":443"
+	case "http":
+		return u.Host + ":80"
+	}
+	return u.Host
+}
+
+// decompressBody decodes a gzip or deflate response the transport left
+// compressed because the request set Accept-Encoding, dropping the headers
+// that describe the encoded body as the transport does.
+func decompressBody(resp *http.Response) {
+	body := decodedBody(resp)
+	if body == resp.Body {
+		return
+	}
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"compress/zlib"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_Browser_CookiesAndRedirects(t *testing.T) {
+	var foreign struct{ auth, referer string }
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreign.auth, foreign.referer = r.Header.Get("Authorization"), r.Header.Get("Referer")
+	}))
+	defer other.Close()
+
+	var home struct{ auth, referer, cookie string }
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.Redirect(w, r, "/home?tab=1", http.StatusFound)
+		case "/home":
+			home.auth, home.referer = r.Header.Get("Authorization"), r.Header.Get("Referer")
+			if c, err := r.Cookie("session"); err == nil {
+				home.cookie = c.Value
+			}
+			http.Redirect(w, r, other.URL+"/landing", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Browser: BrowserConfig{Enabled: true}, Headers: map[string]string{"Authorization": "Bearer t"}})
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/login", Query: url.Values{"next": {"1"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if home.cookie != "abc" || home.auth != "Bearer t" || home.referer != srv.URL+"/login?next=1" {
+		t.Fatalf("same-origin hop: %+v", home)
+	}
+	if foreign.auth != "" || foreign.referer != srv.URL+"/" {
+		t.Fatalf("cross-origin hop: %+v", foreign)
+	}
+}
+
+func Test_Browser_HeadersAndCompression(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "bot/1" || !strings.HasPrefix(r.Header.Get("Accept"), "text/html") || !strings.Contains(r.Header.Get("Accept-Encoding"), "deflate") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Encoding", "deflate")
+		zw := zlib.NewWriter(w)
+		_, _ = zw.Write([]byte("<html>hi</html>"))
+		_ = zw.Close()
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, UserAgent: "bot/1", Browser: BrowserConfig{Enabled: true}})
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "<html>hi</html>" || resp.Headers.Get("Content-Encoding") != "" {
+		t.Fatalf("status=%d body=%q headers=%v", resp.StatusCode, resp.Body, resp.Headers)
+	}
+}
+
+func Test_browserReferer(t *testing.T) {
+	tests := []struct {
+		from, to, want string
+	}{
+		{"https://a.example/p?q=1#frag", "https://a.example/next", "https://a.example/p?q=1"},
+		{"https://user:pw@a.example/p", "https://b.example/", "https://a.example/"},
+		{"https://a.example/p", "http://a.example/p", ""},
+		{"http://a.example/p", "https://a.example/p", "http://a.example/"},
+		{"https://a.example:443/p", "https://a.example/q", "https://a.example:443/p"},
+	}
+	for _, tt := range tests {
+		from, _ := url.Parse(tt.from)
+		to, _ := url.Parse(tt.to)
+		if got := browserReferer(from, to); got != tt.want {
+			t.Errorf("browserReferer(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
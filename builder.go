@@ -0,0 +1,234 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// RequestBuilder assembles one call fluently, replacing hand-built paths and
+// url.Values:
+//
+//	var user User
+//	_, err := http.NewRequest(client).
+//		Path("/users/{id}").PathParam("id", id).
+//		QueryStruct(opts).
+//		Do(ctx, &user)
+//
+// Mistakes such as a parameter without a placeholder are collected and
+// returned by Send or Do. A builder is not safe for concurrent use.
+type RequestBuilder struct {
+	client Client
+	method string
+	path   string
+	params map[string]string
+	req    Request
+	body   []byte
+	expect []int
+	codec  Codec
+	err    error
+}
+
+// NewRequest starts a GET through c.
+func NewRequest(c Client) *RequestBuilder {
+	return &RequestBuilder{client: c, method: http.MethodGet}
+}
+
+// Method sets the HTTP method: GET, POST, PUT, PATCH, or DELETE.
+func (b *RequestBuilder) Method(method string) *RequestBuilder {
+	b.method = strings.ToUpper(method)
+	return b
+}
+
+// Path sets the path, which may hold {name} placeholders for PathParam.
+func (b *RequestBuilder) Path(path string) *RequestBuilder {
+	b.path = path
+	return b
+}
+
+// PathParam fills the {name} placeholder of the path, escaped.
+func (b *RequestBuilder) PathParam(name string, value any) *RequestBuilder {
+	if b.params == nil {
+		b.params = make(map[string]string)
+	}
+	b.params[name] = fmt.Sprint(value)
+	return b
+}
+
+// Query adds a query parameter.
+func (b *RequestBuilder) Query(name string, value any) *RequestBuilder {
+	if b.req.Query == nil {
+		b.req.Query = url.Values{}
+	}
+	b.req.Query.Add(name, fmt.Sprint(value))
+	return b
+}
+
+// QueryStruct adds the fields of v, a struct or pointer to one, tagged
+// `query:"name"`, as Endpoint does. Zero fields are skipped and slices add
+// one value per element.
+func (b *RequestBuilder) QueryStruct(v any) *RequestBuilder {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		b.fail(fmt.Errorf("query struct: want a struct, got %T", v))
+		return b
+	}
+	_ = eachTaggedField(rv, func(kind, name string, field reflect.Value) error {
+		if kind != "query" || field.IsZero() {
+			return nil
+		}
+		if field.Kind() == reflect.Slice || field.Kind() == reflect.Array {
+			for i := 0; i < field.Len(); i++ {
+				b.Query(name, field.Index(i).Interface())
+			}
+			return nil
+		}
+		b.Query(name, field.Interface())
+		return nil
+	})
+	return b
+}
+
+// Header sets a request header.
+func (b *RequestBuilder) Header(name, value string) *RequestBuilder {
+	if b.req.Headers == nil {
+		b.req.Headers = make(map[string]string)
+	}
+	b.req.Headers[name] = value
+	return b
+}
+
+// Body sets a raw body and its content type.
+func (b *RequestBuilder) Body(body []byte, contentType string) *RequestBuilder {
+	b.body = body
+	return b.Header("Content-Type", contentType)
+}
+
+// JSONBody marshals v as the body with the builder's codec, JSON by
+// default.
+func (b *RequestBuilder) JSONBody(v any) *RequestBuilder {
+	data, err := b.getCodec().Marshal(v)
+	if err != nil {
+		b.fail(fmt.Errorf("failed to marshal request body: %w", err))
+		return b
+	}
+	return b.Body(data, b.getCodec().ContentType())
+}
+
+// Codec sets the codec JSONBody and Do use; JSONCodec by default.
+func (b *RequestBuilder) Codec(c Codec) *RequestBuilder {
+	b.codec = c
+	return b
+}
+
+// Expect lists the statuses that count as success; by default any 2xx.
+func (b *RequestBuilder) Expect(statuses ...int) *RequestBuilder {
+	b.expect = statuses
+	return b
+}
+
+// With applies fn to the underlying Request, for the fields the builder has
+// no method for (timeouts, streaming, checksums, ...).
+func (b *RequestBuilder) With(fn func(*Request)) *RequestBuilder {
+	fn(&b.req)
+	return b
+}
+
+func (b *RequestBuilder) getCodec() Codec {
+	if b.codec == nil {
+		return JSONCodec{}
+	}
+	return b.codec
+}
+
+func (b *RequestBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Send performs the call and returns the response whatever its status.
+func (b *RequestBuilder) Send(ctx context.Context) (*Response, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	path, err := expandPath(b.path, b.params)
+	if err != nil {
+		return nil, err
+	}
+	req := b.req
+	req.Path = path
+
+	switch b.method {
+	case http.MethodGet:
+		return b.client.Get(ctx, GetRequest{Request: req})
+	case http.MethodDelete:
+		return b.client.Delete(ctx, req)
+	case http.MethodPost:
+		return b.client.Post(ctx, PostRequest{Request: req, Body: b.body})
+	case http.MethodPut:
+		return b.client.Put(ctx, PutRequest{Request: req, Body: b.body})
+	case http.MethodPatch:
+		return b.client.Patch(ctx, PatchRequest{Request: req, Body: b.body})
+	}
+	return nil, fmt.Errorf("unsupported request method %q", b.method)
+}
+
+// Do performs the call and decodes the response body into out, when out is
+// non-nil and the body is not empty. A status outside Expect is returned as
+// *HTTPError alongside the response.
+func (b *RequestBuilder) Do(ctx context.Context, out any) (*Response, error) {
+	if out != nil && b.req.Headers["Accept"] == "" {
+		b.Header("Accept", b.getCodec().ContentType())
+	}
+	resp, err := b.Send(ctx)
+	if err != nil {
+		return resp, err
+	}
+	if !expectedStatus(resp.StatusCode, b.expect) {
+		return resp, newHTTPError(resp)
+	}
+	if out != nil && len(resp.Body) > 0 {
+		if err := b.getCodec().Unmarshal(resp.Body, out); err != nil {
+			return resp, fmt.Errorf("failed to unmarshal %s %s response: %w", b.method, b.path, err)
+		}
+	}
+	return resp, nil
+}
+
+// expectedStatus reports whether status is one of expect, or a 2xx when
+// expect is empty.
+func expectedStatus(status int, expect []int) bool {
+	if len(expect) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, s := range expect {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPath fills the {name} placeholders of template from params, path
+// escaping each value.
+func expandPath(template string, params map[string]string) (string, error) {
+	path := template
+	for name, value := range params {
+		placeholder := "{" + name + "}"
+		if !strings.Contains(path, placeholder) {
+			return "", fmt.Errorf("path %q has no {%s}", template, name)
+		}
+		path = strings.ReplaceAll(path, placeholder, url.PathEscape(value))
+	}
+	if strings.Contains(path, "{") {
+		return "", fmt.Errorf("path %q has unfilled parameters", path)
+	}
+	return path, nil
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_RequestBuilder_Do(t *testing.T) {
+	var gotMethod, gotPath, gotQuery, gotBody, gotAccept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotQuery, gotBody, gotAccept = r.Method, r.URL.EscapedPath(), r.URL.RawQuery, string(body), r.Header.Get("Accept")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"id":"a b","name":"widget"}`)
+	}))
+	defer srv.Close()
+	c := NewClient(Config{BaseURL: srv.URL})
+
+	type listOptions struct {
+		Tags  []string `query:"tag"`
+		Limit int      `query:"limit"`
+		Page  int      `query:"page"`
+	}
+	var out item
+	resp, err := NewRequest(c).Method("post").
+		Path("/items/{id}/copies").PathParam("id", "a b").
+		QueryStruct(&listOptions{Tags: []string{"x", "y"}, Limit: 10}).
+		Query("dry", true).
+		JSONBody(map[string]string{"name": "widget"}).
+		Expect(http.StatusCreated).
+		Do(context.Background(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || out.ID != "a b" || out.Name != "widget" {
+		t.Fatalf("resp = %d, out = %+v", resp.StatusCode, out)
+	}
+	if gotMethod != http.MethodPost || gotPath != "/items/a%20b/copies" || gotQuery != "dry=true&limit=10&tag=x&tag=y" {
+		t.Fatalf("sent %s %s?%s", gotMethod, gotPath, gotQuery)
+	}
+	if gotBody != `{"name":"widget"}` || gotAccept != "application/json" {
+		t.Fatalf("body = %q accept = %q", gotBody, gotAccept)
+	}
+
+	// 201 is not in this Expect
+	_, err = NewRequest(c).Path("/items").Expect(http.StatusOK).Do(context.Background(), nil)
+	if StatusOf(err) != http.StatusCreated {
+		t.Fatalf("unexpected status: err = %v", err)
+	}
+}
+
+func Test_RequestBuilder_Errors(t *testing.T) {
+	c := NewClient(Config{BaseURL: "http://127.0.0.1:1"})
+	for name, b := range map[string]*RequestBuilder{
+		"unknown placeholder": NewRequest(c).Path("/items").PathParam("id", 1),
+		"unfilled":            NewRequest(c).Path("/items/{id}"),
+		"not a struct":        NewRequest(c).Path("/items").QueryStruct(42),
+		"unmarshalable body":  NewRequest(c).Method(http.MethodPost).Path("/items").JSONBody(func() {}),
+		"method":              NewRequest(c).Method("TRACE").Path("/items"),
+	} {
+		if _, err := b.Send(context.Background()); err == nil || errors.As(err, new(*RequestError)) {
+			t.Errorf("%s: err = %v, want a build error", name, err)
+		}
+	}
+}
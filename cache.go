@@ -0,0 +1,307 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheEntry is a stored response.
+type CacheEntry struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	// Vary fingerprints the request headers the response's Vary named, so
+	// only a request with the same values is served the entry.
+	Vary map[string]string `json:"vary,omitempty"`
+}
+
+// Fresh reports whether the entry can be served at now without asking the
+// server.
+func (e CacheEntry) Fresh(now time.Time) bool {
+	return now.Before(e.ExpiresAt)
+}
+
+// CacheStore holds cached responses by key. Implementations must be safe for
+// concurrent use.
+type CacheStore interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry) error
+	Delete(key string) error
+	// Purge removes every entry.
+	Purge() error
+}
+
+// CacheConfig enables the response cache from Config.
+type CacheConfig struct {
+	// Enabled turns the cache on, with a MemoryCache when Store is nil.
+	Enabled bool `json:"enabled"`
+	// Store holds the responses; setting it also enables the cache.
+	Store CacheStore `json:"-"`
+	// TTL is how long a response the server gave no freshness information
+	// (no max-age or Expires) is served without asking again. 0 stores such
+	// responses only when they carry an ETag or Last-Modified to revalidate.
+	TTL time.Duration `json:"ttl"`
+	// VaryHeaders are request headers, e.g. Accept-Language or a per-request
+	// Authorization, whose values always key separate entries, even when
+	// the server omits them from Vary.
+	VaryHeaders []string `json:"vary_headers"`
+}
+
+func (c CacheConfig) store() CacheStore {
+	if c.Store != nil {
+		return c.Store
+	}
+	if c.Enabled {
+		return NewMemoryCache(0)
+	}
+	return nil
+}
+
+// CachePurger is implemented by clients built with NewClient:
+//
+//	if p, ok := client.(http.CachePurger); ok { err = p.CachePurge() }
+type CachePurger interface {
+	CachePurge() error
+}
+
+var _ CachePurger = httpClient{}
+
+// WithCache serves buffered GET responses from store while they are fresh per
+// the server's Cache-Control max-age or Expires. Once stale, or when marked
+// no-cache, a response with an ETag or Last-Modified is revalidated with
+// If-None-Match/If-Modified-Since and a 304 answers with the stored body.
+// Responses marked no-store or "Vary: *", and streamed requests, bypass the
+// cache; an entry is served only to requests matching the header values its
+// Vary names. Pass a DiskCache to keep the cache warm across process
+// restarts.
+func WithCache(store CacheStore) Option {
+	return func(h *httpClient) {
+		h.cache = store
+	}
+}
+
+// CachePurge empties the response cache. It is a no-op without WithCache.
+func (h httpClient) CachePurge() error {
+	if h.cache == nil {
+		return nil
+	}
+	return h.cache.Purge()
+}
+
+// cacheKey identifies a cached response by RequestHash over the method,
+// URL, and the VaryHeaders values.
+func (h httpClient) cacheKey(req *http.Request) string {
+	return RequestHash(req, nil, h.cacheVary...)
+}
+
+// headerFingerprint hashes header values, so credentials used in a key or
+// a Vary match are never written to a store.
+func headerFingerprint(values []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// varyNames lists the header names of a response's Vary; ok is false for
+// "Vary: *", which matches no later request.
+func varyNames(headers http.Header) (names []string, ok bool) {
+	for _, v := range headers.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			switch name {
+			case "":
+			case "*":
+				return nil, false
+			default:
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names, true
+}
+
+// varyFingerprints records req's values for the headers names.
+func varyFingerprints(req *http.Request, names []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		out[name] = headerFingerprint(req.Header.Values(name))
+	}
+	return out
+}
+
+// matchesVary reports whether req carries the header values entry was
+// stored for.
+func (e CacheEntry) matchesVary(req *http.Request) bool {
+	for name, fingerprint := range e.Vary {
+		if headerFingerprint(req.Header.Values(name)) != fingerprint {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedResponse returns a fresh stored response for req, or else the stale
+// entry the server can revalidate. Stale entries without validators are
+// dropped.
+func (h httpClient) cachedResponse(req *http.Request) (*Response, *CacheEntry) {
+	key := h.cacheKey(req)
+	entry, ok := h.cache.Get(key)
+	if !ok || !entry.matchesVary(req) {
+		return nil, nil
+	}
+	if entry.Fresh(time.Now()) {
+		h.logger.Debug("http-client", "type", "cache", "msg", "hit", "url", h.redact.url(req.URL.String()))
+		return &Response{StatusCode: entry.StatusCode, Body: entry.Body, Headers: entry.Headers}, nil
+	}
+	if entry.revalidatable() {
+		return nil, &entry
+	}
+	if err := h.cache.Delete(key); err != nil {
+		h.logger.Warn("http-client", "type", "cache", "msg", "failed to delete stale entry", "url", h.redact.url(req.URL.String()), "error", err)
+	}
+	return nil, nil
+}
+
+// revalidatable reports whether the entry carries a validator the server
+// can check.
+func (e CacheEntry) revalidatable() bool {
+	return e.Headers.Get("ETag") != "" || e.Headers.Get("Last-Modified") != ""
+}
+
+// addValidators makes req conditional on entry. It leaves requests the
+// caller already made conditional alone and reports false for them, so
+// their 304 reaches the caller.
+func addValidators(req *http.Request, entry *CacheEntry) bool {
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return false
+	}
+	if etag := entry.Headers.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if modified := entry.Headers.Get("Last-Modified"); modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+	return true
+}
+
+// revalidated answers a 304 for req with the stored entry, updated by the
+// headers of the 304 and stored again.
+func (h httpClient) revalidated(req *http.Request, entry CacheEntry, notModified http.Header) *Response {
+	headers := entry.Headers.Clone()
+	for k, v := range notModified {
+		headers[k] = v
+	}
+	entry.Headers = headers
+	now := time.Now()
+	lifetime, _ := h.cacheLifetime(headers, now)
+	entry.StoredAt, entry.ExpiresAt = now, now.Add(lifetime)
+	if err := h.cache.Set(h.cacheKey(req), entry); err != nil {
+		h.logger.Warn("http-client", "type", "cache", "msg", "failed to store entry", "url", h.redact.url(req.URL.String()), "error", err)
+	}
+	h.logger.Debug("http-client", "type", "cache", "msg", "revalidated", "url", h.redact.url(req.URL.String()))
+	return &Response{StatusCode: entry.StatusCode, Body: entry.Body, Headers: headers}
+}
+
+// storeResponse caches resp for req when the server allows it.
+func (h httpClient) storeResponse(req *http.Request, resp *Response) {
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	now := time.Now()
+	lifetime, storable := h.cacheLifetime(resp.Headers, now)
+	if !storable {
+		return
+	}
+	vary, ok := varyNames(resp.Headers)
+	if !ok {
+		return
+	}
+	entry := CacheEntry{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Headers,
+		Body:       resp.Body,
+		StoredAt:   now,
+		ExpiresAt:  now.Add(lifetime),
+		Vary:       varyFingerprints(req, vary),
+	}
+	if lifetime <= 0 && !entry.revalidatable() {
+		return
+	}
+	if err := h.cache.Set(h.cacheKey(req), entry); err != nil {
+		h.logger.Warn("http-client", "type", "cache", "msg", "failed to store entry", "url", h.redact.url(req.URL.String()), "error", err)
+	}
+}
+
+// cacheLifetime is how long a response may be served without revalidation,
+// falling back to the configured TTL when the server says nothing. storable
+// is false for no-store.
+func (h httpClient) cacheLifetime(headers http.Header, now time.Time) (lifetime time.Duration, storable bool) {
+	cc := parseCacheControl(headers)
+	switch {
+	case cc.noStore:
+		return 0, false
+	case cc.noCache:
+		return 0, true
+	case cc.maxAge < 0 && headers.Get("Expires") == "":
+		return h.cacheTTL, true
+	}
+	return freshnessLifetime(headers, now), true
+}
+
+// cacheControl holds the response directives the cache acts on.
+type cacheControl struct {
+	noStore, noCache bool
+	// maxAge is -1 when absent.
+	maxAge int
+}
+
+func parseCacheControl(headers http.Header) cacheControl {
+	cc := cacheControl{maxAge: -1}
+	for _, v := range headers.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-store":
+				cc.noStore = true
+			case "no-cache":
+				cc.noCache = true
+			case "max-age":
+				if n, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+					cc.maxAge = n
+				}
+			}
+		}
+	}
+	return cc
+}
+
+// freshnessLifetime is how long a response may be served from cache, from
+// Cache-Control max-age or else Expires. Zero means do not cache.
+func freshnessLifetime(headers http.Header, now time.Time) time.Duration {
+	cc := parseCacheControl(headers)
+	if cc.noStore || cc.noCache {
+		return 0
+	}
+	if cc.maxAge >= 0 {
+		return time.Duration(cc.maxAge) * time.Second
+	}
+	if expires := headers.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		if date, err := http.ParseTime(headers.Get("Date")); err == nil {
+			now = date
+		}
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,208 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_WithCache_ServesFreshResponses(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/nostore" {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer srv.Close()
+
+	store, err := NewDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	c := newTestClient(t, srv.URL, WithCache(store))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(ctx, GetRequest{Request: Request{Path: "/data"}})
+		if err != nil || string(resp.Body) != "payload" {
+			t.Fatalf("Get #%d: %v %q", i, err, resp.Body)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("server hits = %d, want 1", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(ctx, GetRequest{Request: Request{Path: "/nostore"}}); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("no-store responses must not be cached; hits = %d", n)
+	}
+
+	if err := c.(CachePurger).CachePurge(); err != nil {
+		t.Fatalf("CachePurge: %v", err)
+	}
+	if _, err := c.Get(ctx, GetRequest{Request: Request{Path: "/data"}}); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if n := hits.Load(); n != 4 {
+		t.Fatalf("purged entry was served; hits = %d", n)
+	}
+}
+
+func Test_Cache_RevalidatesWithETag(t *testing.T) {
+	var hits, notModified atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Cache: CacheConfig{Enabled: true}})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(ctx, GetRequest{Request: Request{Path: "/data"}})
+		if err != nil || resp.StatusCode != http.StatusOK || string(resp.Body) != "payload" {
+			t.Fatalf("Get #%d: %v %d %q", i, err, resp.StatusCode, resp.Body)
+		}
+	}
+	if hits.Load() != 3 || notModified.Load() != 2 {
+		t.Fatalf("hits = %d, 304s = %d", hits.Load(), notModified.Load())
+	}
+
+	// a caller's own conditional request gets the 304 back
+	resp, err := c.Get(ctx, GetRequest{Request: Request{Path: "/data", Headers: map[string]string{"If-None-Match": `"v1"`}}})
+	if err != nil || resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("conditional Get: %v %d", err, resp.StatusCode)
+	}
+}
+
+func Test_Cache_TTLAndLastModified(t *testing.T) {
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/modified" {
+			w.Header().Set("Last-Modified", modified)
+			if r.Header.Get("If-Modified-Since") == modified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	store := NewMemoryCache(0)
+	c := NewClient(Config{BaseURL: srv.URL, Cache: CacheConfig{Store: store, TTL: 50 * time.Millisecond}})
+	ctx := context.Background()
+	get := func(path string) string {
+		t.Helper()
+		resp, err := c.Get(ctx, GetRequest{Request: Request{Path: path}})
+		if err != nil {
+			t.Fatalf("Get %s: %v", path, err)
+		}
+		return string(resp.Body)
+	}
+
+	get("/plain")
+	get("/plain")
+	if hits.Load() != 1 {
+		t.Fatalf("TTL not applied; hits = %d", hits.Load())
+	}
+	get("/modified")
+	time.Sleep(60 * time.Millisecond)
+	if get("/modified") != "/modified" || hits.Load() != 3 {
+		t.Fatalf("stale entry not revalidated; hits = %d", hits.Load())
+	}
+	// the stale entry without validators is dropped
+	get("/plain")
+	if hits.Load() != 4 || store.Len() != 2 {
+		t.Fatalf("hits = %d, entries = %d", hits.Load(), store.Len())
+	}
+}
+
+func Test_Cache_VaryKeys(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.URL.Path == "/star" {
+			w.Header().Set("Vary", "*")
+		} else {
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language") + "|" + r.Header.Get("X-Tenant")))
+	}))
+	defer srv.Close()
+
+	store := NewMemoryCache(0)
+	c := NewClient(Config{BaseURL: srv.URL, Cache: CacheConfig{Store: store, VaryHeaders: []string{"x-tenant"}}})
+	get := func(path, lang, tenant string) string {
+		t.Helper()
+		resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: path, Headers: map[string]string{"Accept-Language": lang, "X-Tenant": tenant}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(resp.Body)
+	}
+
+	// the Vary of the response replaces a variant that does not match
+	get("/greeting", "en", "a")
+	if got := get("/greeting", "de", "a"); got != "de|a" || hits.Load() != 2 {
+		t.Fatalf("got %q, hits = %d", got, hits.Load())
+	}
+	// VaryHeaders keep a variant per tenant
+	get("/greeting", "de", "b")
+	if get("/greeting", "de", "a") != "de|a" || get("/greeting", "de", "b") != "de|b" || hits.Load() != 3 {
+		t.Fatalf("tenant variants not cached separately; hits = %d", hits.Load())
+	}
+	for key := range store.entries {
+		if strings.HasSuffix(key, "=a") || strings.HasSuffix(key, "=b") {
+			t.Fatalf("header value stored in the clear: %q", key)
+		}
+	}
+
+	get("/star", "en", "a")
+	get("/star", "en", "a")
+	if hits.Load() != 5 {
+		t.Fatalf("Vary: * cached; hits = %d", hits.Load())
+	}
+}
+
+func Test_FreshnessLifetime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		headers http.Header
+		want    time.Duration
+	}{
+		{"max-age", http.Header{"Cache-Control": {"max-age=30"}}, 30 * time.Second},
+		{"max-age wins over expires", http.Header{"Cache-Control": {"max-age=5"}, "Expires": {now.Add(time.Hour).Format(http.TimeFormat)}}, 5 * time.Second},
+		{"expires", http.Header{"Expires": {now.Add(time.Minute).Format(http.TimeFormat)}}, time.Minute},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache, max-age=30"}}, 0},
+		{"bad expires", http.Header{"Expires": {"0"}}, 0},
+		{"none", http.Header{}, 0},
+	} {
+		if got := freshnessLifetime(tc.headers, now); got != tc.want {
+			t.Errorf("%s: got %v want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CanonicalRequest returns the canonical form of a request, one field per
+// line: the method upper-cased, the lower-cased scheme and host when the URL
+// has them (client-side requests), the normalized path, the query sorted by
+// key, each selected header as "name:value" sorted by name, and BodyDigest
+// of body. Requests that differ only in query order, header order or case,
+// dot segments, or doubled slashes share a form, so it suits cache and
+// deduplication keys and golden files. Pass the body separately, as reading
+// r.Body would consume it; a header r lacks is left out.
+//
+// The form carries the selected headers' values as they are, credentials
+// included; key stores with RequestHash instead.
+func CanonicalRequest(r *http.Request, body []byte, headers ...string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(r.Method))
+	b.WriteByte('\n')
+	if r.URL.Host != "" {
+		b.WriteString(strings.ToLower(r.URL.Scheme) + "://" + strings.ToLower(r.URL.Host))
+	}
+	b.WriteByte('\n')
+	b.WriteString(canonicalPath(r.URL.EscapedPath()))
+	b.WriteByte('\n')
+	b.WriteString(r.URL.Query().Encode())
+	b.WriteByte('\n')
+
+	// match names whatever their case, in r.Header too
+	selected := make(map[string][]string, len(headers))
+	for _, name := range headers {
+		selected[strings.ToLower(name)] = nil
+	}
+	keys := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		if lower := strings.ToLower(name); len(r.Header[name]) > 0 {
+			if _, ok := selected[lower]; ok {
+				selected[lower] = append(selected[lower], r.Header[name]...)
+			}
+		}
+	}
+	names := make([]string, 0, len(selected))
+	for name, values := range selected {
+		if len(values) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := selected[name]
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		b.WriteString(name + ":" + strings.Join(trimmed, ","))
+		b.WriteByte('\n')
+	}
+	b.WriteString(BodyDigest(body))
+	return b.String()
+}
+
+// RequestHash is the hex SHA-256 of CanonicalRequest, a stable key for the
+// request that never reveals the headers it covers.
+func RequestHash(r *http.Request, body []byte, headers ...string) string {
+	sum := sha256.Sum256([]byte(CanonicalRequest(r, body, headers...)))
+	return hex.EncodeToString(sum[:])
+}
+
+// BodyDigest is the hex SHA-256 of body; an empty body has the digest of
+// no bytes.
+func BodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalPath resolves dot segments, collapses repeated slashes, and
+// re-escapes each segment uniformly in an escaped path, keeping a trailing
+// slash, which servers often route apart.
+func canonicalPath(p string) string {
+	var segments []string
+	for _, seg := range strings.Split(p, "/") {
+		if unescaped, err := url.PathUnescape(seg); err == nil {
+			seg = url.PathEscape(unescaped)
+		}
+		switch seg {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, seg)
+		}
+	}
+	out := "/" + strings.Join(segments, "/")
+	if len(segments) > 0 && (strings.HasSuffix(p, "/") || strings.HasSuffix(p, "/.") || strings.HasSuffix(p, "/..")) {
+		out += "/"
+	}
+	return out
+}
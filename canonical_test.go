@@ -0,0 +1,106 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_CanonicalRequest(t *testing.T) {
+	r := httptest.NewRequest("post", "https://API.Example.com/v1//orders/./7/../8/?b=2&a=1&a=0", nil)
+	r.Header.Set("X-Tenant", "  acme   corp ")
+	r.Header.Add("Accept", "application/json")
+	r.Header.Add("Accept", "text/plain")
+
+	got := CanonicalRequest(r, []byte(`{"n":1}`), "x-tenant", "Accept", "Authorization", "ACCEPT")
+	want := strings.Join([]string{
+		"POST",
+		"https://api.example.com",
+		"/v1/orders/8/",
+		"a=1&a=0&b=2",
+		"accept:application/json,text/plain",
+		"x-tenant:acme corp",
+		BodyDigest([]byte(`{"n":1}`)),
+	}, "\n")
+	if got != want {
+		t.Fatalf("canonical =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func Test_RequestHash_Equivalent(t *testing.T) {
+	base := func(target string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("Accept", "application/json")
+		return r
+	}
+	ref := RequestHash(base("/items?a=1&b=2"), nil, "Accept")
+	if len(ref) != 64 {
+		t.Fatalf("hash = %q", ref)
+	}
+
+	same := map[string]*http.Request{
+		"query order":     base("/items?b=2&a=1"),
+		"doubled slashes": base("//items?a=1&b=2"),
+		"dot segments":    base("/x/./../items?a=1&b=2"),
+		"escaping":        base("/%69tems?a=1&b=2"),
+	}
+	headerCase := base("/items?a=1&b=2")
+	headerCase.Header = http.Header{"accept": {"application/json"}}
+	same["header case"] = headerCase
+	unselected := base("/items?a=1&b=2")
+	unselected.Header.Set("User-Agent", "other")
+	same["unselected header"] = unselected
+	for name, r := range same {
+		if got := RequestHash(r, nil, "accept"); got != ref {
+			t.Errorf("%s: hash differs", name)
+		}
+	}
+
+	different := map[string]*http.Request{
+		"method":         httptest.NewRequest(http.MethodHead, "/items?a=1&b=2", nil),
+		"path":           base("/items/?a=1&b=2"),
+		"query value":    base("/items?a=1&b=3"),
+		"repeated order": base("/items?a=1&b=2&a=0"),
+		"host":           base("http://other/items?a=1&b=2"),
+	}
+	header := base("/items?a=1&b=2")
+	header.Header.Set("Accept", "text/plain")
+	different["header value"] = header
+	for name, r := range different {
+		r.Header.Set("Accept", r.Header.Get("Accept"))
+		if RequestHash(r, nil, "Accept") == ref {
+			t.Errorf("%s: hash should differ", name)
+		}
+	}
+	if RequestHash(base("/items?a=1&b=2"), []byte("x"), "Accept") == ref {
+		t.Error("body: hash should differ")
+	}
+}
+
+func Test_CanonicalRequest_Paths(t *testing.T) {
+	for in, want := range map[string]string{
+		"":              "/",
+		"/":             "/",
+		"/a/b/..":       "/a/",
+		"/a/b/.":        "/a/b/",
+		"/../../a":      "/a",
+		"/a%2Fb/c":      "/a%2Fb/c",
+		"/%7euser":      "/~user",
+		"/a%20b":        "/a%20b",
+		"/%2e%2e/a":     "/a",
+		"/a//b///c/":    "/a/b/c/",
+		"/caf%C3%A9/":   "/caf%C3%A9/",
+		"/bad%zzescape": "/bad%zzescape",
+	} {
+		if got := canonicalPath(in); got != want {
+			t.Errorf("canonicalPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func Test_BodyDigest(t *testing.T) {
+	if got := BodyDigest(nil); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Fatalf("empty digest = %s", got)
+	}
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"bytes"
+	"crypto/md5"  //nolint:gosec // verifying what the server sent, not hashing secrets
+	"crypto/sha1" //nolint:gosec // verifying what the server sent, not hashing secrets
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Checksum algorithms understood by VerifyChecksum and ExpectedDigest.
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA1   = "sha1"
+	ChecksumSHA256 = "sha256"
+	ChecksumSHA512 = "sha512"
+	ChecksumCRC32  = "crc32"
+	ChecksumCRC32C = "crc32c"
+)
+
+// ChecksumError reports a response body whose digest does not match the
+// expected one. Sums are hex-encoded.
+type ChecksumError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+	// Source names where the expected sum came from: a header name, or
+	// "expected" for Request.ExpectedDigest.
+	Source string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch (%s from %s): expected %s, got %s", e.Algorithm, e.Source, e.Expected, e.Actual)
+}
+
+type expectedChecksum struct {
+	algorithm string
+	sum       []byte
+	source    string
+}
+
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case ChecksumMD5:
+		return md5.New() //nolint:gosec // see import
+	case ChecksumSHA1:
+		return sha1.New() //nolint:gosec // see import
+	case ChecksumSHA256:
+		return sha256.New()
+	case ChecksumSHA512:
+		return sha512.New()
+	case ChecksumCRC32:
+		return crc32.NewIEEE()
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	default:
+		return nil
+	}
+}
+
+// parseExpectedDigest parses Request.ExpectedDigest: "<algorithm>:<hex>".
+func parseExpectedDigest(digest string) (*expectedChecksum, error) {
+	algorithm, encoded, ok := strings.Cut(digest, ":")
+	algorithm = strings.ToLower(algorithm)
+	if !ok || newChecksumHash(algorithm) == nil {
+		return nil, fmt.Errorf("unsupported expected digest %q: want <algorithm>:<hex>", digest)
+	}
+	sum, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode expected digest: %w", err)
+	}
+	return &expectedChecksum{algorithm: algorithm, sum: sum, source: "expected"}, nil
+}
+
+// headerChecksum picks the strongest checksum the server advertised across
+// Repr-Digest/Content-Digest (RFC 9530), Digest (RFC 3230), x-amz-checksum-*,
+// and Content-MD5. When two headers carry the same algorithm, the earlier one
+// in that list wins. Repr-Digest covers the whole representation, so it is
+// ignored for a partial body.
+func headerChecksum(h http.Header, partial bool) *expectedChecksum {
+	found := make(map[string]*expectedChecksum)
+	add := func(algorithm, encoded, source string) {
+		if _, ok := found[algorithm]; ok || newChecksumHash(algorithm) == nil {
+			return
+		}
+		sum, err := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimSpace(encoded), ":"))
+		if err == nil {
+			found[algorithm] = &expectedChecksum{algorithm: algorithm, sum: sum, source: source}
+		}
+	}
+
+	for _, name := range []string{"Repr-Digest", "Content-Digest", "Digest"} {
+		if partial && name == "Repr-Digest" {
+			continue
+		}
+		for _, value := range h.Values(name) {
+			for _, item := range strings.Split(value, ",") {
+				algorithm, encoded, ok := strings.Cut(strings.TrimSpace(item), "=")
+				if ok {
+					add(strings.ReplaceAll(strings.ToLower(algorithm), "-", ""), encoded, name)
+				}
+			}
+		}
+	}
+	for _, algorithm := range []string{ChecksumSHA256, ChecksumSHA1, ChecksumCRC32C, ChecksumCRC32} {
+		name := "X-Amz-Checksum-" + algorithm
+		if v := h.Get(name); v != "" {
+			add(algorithm, v, name)
+		}
+	}
+	if v := h.Get("Content-MD5"); v != "" {
+		add(ChecksumMD5, v, "Content-MD5")
+	}
+
+	for _, algorithm := range []string{ChecksumSHA512, ChecksumSHA256, ChecksumSHA1, ChecksumCRC32C, ChecksumCRC32, ChecksumMD5} {
+		if c, ok := found[algorithm]; ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// responseChecksums returns what a response must be verified against: the
+// caller's expected digest and/or the strongest header checksum. Header
+// checksums cover the content-coded bytes, so they are skipped once the
+// transport or the client has decoded the body.
+func responseChecksums(expected *expectedChecksum, verifyHeaders bool, resp *http.Response) []*expectedChecksum {
+	var out []*expectedChecksum
+	if expected != nil {
+		out = append(out, expected)
+	}
+	if verifyHeaders && !resp.Uncompressed {
+		if c := headerChecksum(resp.Header, resp.StatusCode == http.StatusPartialContent); c != nil {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func verifyChecksums(body []byte, checks []*expectedChecksum) error {
+	for _, c := range checks {
+		sum := newChecksumHash(c.algorithm)
+		sum.Write(body)
+		if err := c.compare(sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *expectedChecksum) compare(sum hash.Hash) error {
+	actual := sum.Sum(nil)
+	if bytes.Equal(actual, c.sum) {
+		return nil
+	}
+	return &ChecksumError{Algorithm: c.algorithm, Expected: hex.EncodeToString(c.sum), Actual: hex.EncodeToString(actual), Source: c.source}
+}
+
+// checksumReader hashes a streamed body as the caller reads it and turns
+// the final io.EOF into a *ChecksumError on mismatch.
+type checksumReader struct {
+	io.ReadCloser
+	checks []*expectedChecksum
+	hashes []hash.Hash
+}
+
+func newChecksumReader(body io.ReadCloser, checks []*expectedChecksum) *checksumReader {
+	r := &checksumReader{ReadCloser: body, checks: checks}
+	for _, c := range checks {
+		r.hashes = append(r.hashes, newChecksumHash(c.algorithm))
+	}
+	return r
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	for _, h := range r.hashes {
+		h.Write(p[:n])
+	}
+	if err == io.EOF {
+		for i, c := range r.checks {
+			if cerr := c.compare(r.hashes[i]); cerr != nil {
+				return n, cerr
+			}
+		}
+	}
+	return n, err
+}
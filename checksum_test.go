@@ -0,0 +1,143 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5" //nolint:gosec // test fixture
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const payload = "model weights"
+
+func b64(b []byte) string { return base64.StdEncoding.EncodeToString(b) }
+
+func Test_Client_VerifyChecksum(t *testing.T) {
+	sha := sha256.Sum256([]byte(payload))
+	md := md5.Sum([]byte(payload)) //nolint:gosec // test fixture
+	crc := crc32.Checksum([]byte(payload), crc32.MakeTable(crc32.Castagnoli))
+	crcBytes := []byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		body     string
+		wantAlgo string // empty means the call succeeds
+	}{
+		{name: "no checksum header", body: payload},
+		{name: "content-md5 ok", headers: map[string]string{"Content-MD5": b64(md[:])}, body: payload},
+		{name: "repr-digest mismatch", headers: map[string]string{"Repr-Digest": "sha-256=:" + b64(sha[:]) + ":"}, body: "tampered", wantAlgo: ChecksumSHA256},
+		{name: "digest prefers sha256 over md5", headers: map[string]string{"Digest": "md5=" + b64(md[:]) + ", SHA-256=" + b64(make([]byte, 32))}, body: payload, wantAlgo: ChecksumSHA256},
+		{name: "amz crc32c ok", headers: map[string]string{"X-Amz-Checksum-Crc32c": b64(crcBytes)}, body: payload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			_, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{Request: Request{Path: "/", VerifyChecksum: true}})
+			var cerr *ChecksumError
+			if tt.wantAlgo == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &cerr) || cerr.Algorithm != tt.wantAlgo {
+				t.Fatalf("err = %v, want ChecksumError(%s)", err, tt.wantAlgo)
+			}
+		})
+	}
+}
+
+func Test_Client_ExpectedDigest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, payload)
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+	sha := sha256.Sum256([]byte(payload))
+	good := "sha256:" + hex.EncodeToString(sha[:])
+	bad := "sha256:" + hex.EncodeToString(make([]byte, 32))
+
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", ExpectedDigest: good}}); err != nil {
+		t.Fatalf("matching digest: %v", err)
+	}
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", ExpectedDigest: "blake3:00"}}); err == nil {
+		t.Fatal("unsupported algorithm should fail before sending")
+	}
+
+	t.Run("streamed mismatch surfaces on read", func(t *testing.T) {
+		resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", Stream: true, ExpectedDigest: bad}})
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		defer resp.Close()
+		var cerr *ChecksumError
+		if _, err := io.ReadAll(resp); !errors.As(err, &cerr) {
+			t.Fatalf("ReadAll err = %v, want ChecksumError", err)
+		}
+	})
+
+	t.Run("streamed match reads cleanly", func(t *testing.T) {
+		resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", Stream: true, ExpectedDigest: good}})
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		defer resp.Close()
+		if body, err := io.ReadAll(resp); err != nil || string(body) != payload {
+			t.Fatalf("ReadAll = %q, %v", body, err)
+		}
+	})
+}
+
+func Test_Client_VerifyChecksum_EncodedBody(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = io.WriteString(zw, payload)
+	_ = zw.Close()
+	encoded := sha256.Sum256(gz.Bytes())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Digest", "sha-256=:"+b64(encoded[:])+":")
+		_, _ = w.Write(gz.Bytes())
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{Request: Request{Path: "/", VerifyChecksum: true}})
+	if err != nil {
+		t.Fatalf("decoded body checked against the encoded digest: %v", err)
+	}
+	if string(resp.Body) != payload {
+		t.Fatalf("body = %q", resp.Body)
+	}
+}
+
+func Test_Client_VerifyChecksum_PartialIgnoresReprDigest(t *testing.T) {
+	full := sha256.Sum256([]byte(payload))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Repr-Digest", "sha-256=:"+b64(full[:])+":")
+		w.Header().Set("Content-Range", "bytes 0-4/13")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, payload[:5])
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{Request: Request{Path: "/", VerifyChecksum: true}}); err != nil {
+		t.Fatalf("partial body checked against Repr-Digest: %v", err)
+	}
+}
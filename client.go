@@ -1,13 +1,15 @@
 package http
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"runtime/debug"
+	"time"
 )
 
 // Client performs HTTP requests against a configured base URL, buffering responses
@@ -20,6 +22,7 @@ type Client interface {
 	Put(ctx context.Context, req PutRequest) (*Response, error)
 	Patch(ctx context.Context, req PatchRequest) (*Response, error)
 	Delete(ctx context.Context, req Request) (*Response, error)
+	WebSocket(ctx context.Context, req Request) (*WebSocketConn, error)
 }
 
 // Response is the outcome of a request: a buffered Body or, for a streamed request,
@@ -37,6 +40,15 @@ type Response struct {
 	Reader  io.ReadCloser
 	Headers http.Header
 	Error   error
+	// ServerTiming holds the metrics the server reported in its Server-Timing
+	// header, in order. It is nil when the header is absent.
+	ServerTiming []ServerTiming
+	// Attempts lists every try the client made for this response, the last
+	// one being the response itself.
+	Attempts []AttemptInfo
+	// Trailers holds the trailers sent after the body. For a streamed
+	// response it is filled once Reader has been read to the end.
+	Trailers http.Header
 }
 
 var _ io.ReadCloser = (*Response)(nil)
@@ -59,10 +71,32 @@ func (r *Response) Close() error {
 	return r.Reader.Close()
 }
 
+// RequestID returns the X-Request-ID the server echoed, "" when it sent none.
+func (r *Response) RequestID() string {
+	return r.Headers.Get(ClientRequestIDHeaderName)
+}
+
+// ServedBy returns the X-Served-By instance name, "" when the server sent
+// none.
+func (r *Response) ServedBy() string {
+	return r.Headers.Get(ServedByHeaderName)
+}
+
+// ResponseTime returns the server-side time the server reported in
+// X-Response-Time, and false when it sent none or a malformed value.
+func (r *Response) ResponseTime() (time.Duration, bool) {
+	d, err := time.ParseDuration(r.Headers.Get(ResponseTimeHeaderName))
+	return d, err == nil
+}
+
 // StreamResponseType classifies a decoded Server-Sent Events frame.
 type StreamResponseType string
 
-// Server-Sent Events frame types decoded from a stream.
+// Server-Sent Events frame types decoded from a stream. A stream delivers one
+// DATA frame per dispatched event, with its event: type and id: on Event and
+// ID, and ends with a single EOF frame. EVENT, ID, RETRY, and COMMENT are no
+// longer emitted, as those fields are folded into the DATA frame or handled
+// by the client; they remain so existing switches keep compiling.
 const (
 	StreamResponseTypeEOF     StreamResponseType = "EOF"
 	StreamResponseTypeData    StreamResponseType = "DATA"
@@ -76,9 +110,33 @@ const (
 type StreamResponse struct {
 	StatusCode int
 	Body       []byte
-	Headers    http.Header
-	Error      error
-	Type       StreamResponseType
+	// Headers are the response's, or on the DATA frames of a multipart
+	// stream the part's own (Content-Type, Content-Range, ...).
+	Headers http.Header
+	Error   error
+	Type    StreamResponseType
+	// Event is the event: type of a DATA frame, empty for the default
+	// "message".
+	Event string
+	// ID is the last event ID in effect for a DATA frame.
+	ID string
+	// Trailers holds the trailers of the response on the terminal EOF frame
+	// of a stream the server ended.
+	Trailers http.Header
+	// Retry is the reconnect interval the server last set with retry: when
+	// the frame was delivered, which automatic reconnects wait instead of
+	// StreamConfig.ReconnectDelay. 0 until the server sends one.
+	Retry time.Duration
+	// Disconnect says how the stream ended, on the terminal EOF frame.
+	Disconnect StreamDisconnectReason
+	// Seq numbers the DATA frames of one GetStream or PostStream call from
+	// 1, across reconnects, so consumers reducing over events can order them
+	// and tell a frame seen twice from a repeated payload.
+	Seq uint64
+	// Gap is set on a DATA frame whose event ID skipped past the previous
+	// one's, events having been lost, when StreamConfig.SequentialIDs says
+	// the server numbers its events.
+	Gap *StreamGap
 }
 
 // Request is the shared shape of every request: path, query, headers, identifiers,
@@ -94,6 +152,43 @@ type Request struct {
 	// round-trip through memory. The caller must Close the Response. Default false
 	// keeps the buffered Body behavior every other caller relies on.
 	Stream bool
+	// Proxy routes this one request through the given proxy URL (http://,
+	// https://, or socks5://), overriding Config.Proxies and the environment.
+	Proxy string
+	// Host overrides the Host header without touching the URL, e.g. to call
+	// a load balancer or an IP directly for a virtual host.
+	Host string
+	// ServerName overrides the TLS server name (SNI) and the name the
+	// server's certificate is verified against. Set it with Host when calling
+	// an HTTPS endpoint by IP. Such requests get their own connections.
+	ServerName string
+	// VerifyChecksum checks the response body against the strongest checksum
+	// the server advertised (Repr-Digest, Content-Digest, Digest,
+	// x-amz-checksum-*, Content-MD5); responses without one pass, as do
+	// bodies that were decoded from a Content-Encoding, since the headers
+	// cover the encoded bytes.
+	VerifyChecksum bool
+	// ExpectedDigest, as "<algorithm>:<hex>" (e.g. "sha256:9f86..."), checks the
+	// body against a hash the caller already knows. Mismatches fail with a
+	// *ChecksumError; for a streamed request it surfaces from the final Read.
+	ExpectedDigest string
+	// BodyReader, when set, is streamed as the request body instead of the
+	// []byte body of PostRequest/PutRequest/PatchRequest, so uploads need not
+	// fit in memory. It is read once: such requests are never retried and
+	// cannot be signed. It is closed after sending when it is an io.Closer.
+	BodyReader io.Reader
+	// OnProgress, when set, is called as the request body is sent.
+	OnProgress ProgressFunc
+	// Trailers declares trailers sent after the request body, which forces
+	// chunked encoding. The keys must be set before the call; the values may
+	// be filled in while the body is read (e.g. a checksum of a BodyReader)
+	// and are sent once it ends.
+	Trailers http.Header
+	// Timeout bounds this call, retries and reading the body included,
+	// overriding Timeouts.Total; negative disables the default. For a
+	// Stream request the deadline runs until the Response is closed. It is
+	// ignored by GetStream and PostStream.
+	Timeout time.Duration
 }
 
 // GetRequest is a GET request.
@@ -117,9 +212,81 @@ type PutRequest PostRequest
 type httpClient struct {
 	baseURL string
 
-	client  *http.Client
-	headers map[string]string
-	logger  Logger
+	client *http.Client
+	proxy  *proxyRouter
+	// serverNames holds the clients of Request.ServerName overrides.
+	serverNames *serverNameClients
+	pool        *poolCounters
+	// streams counts how streams ended (see StreamStats).
+	streams *streamCounters
+	egress  *EgressPolicy
+	// headerPolicy adds and strips headers per Config.HeaderPolicy.
+	headerPolicy *headerPolicy
+	// browser decodes the gzip and deflate bodies browser mode asks for.
+	browser bool
+	// async runs the calls of GoGet, GoPost, and friends.
+	async *dispatcher
+	// expect sends large bodies only after 100 Continue (see
+	// Config.ExpectContinue).
+	expect ExpectContinueConfig
+	// requestTimeout is the default deadline of buffered calls.
+	requestTimeout time.Duration
+	// attemptTimeout bounds each attempt until its headers arrive.
+	attemptTimeout time.Duration
+	limiter        *RateLimiter
+	errorOnStatus  bool
+	breaker        *CircuitBreaker
+	// adaptive, when set, caps requests in flight per host (see
+	// Config.AdaptiveConcurrency).
+	adaptive *AdaptiveLimiter
+	// skew measures each host's clock against the local one.
+	skew *skewTracker
+	// openAPI, when set, validates requests before sending
+	// (see WithOpenAPIValidation).
+	openAPI     *OpenAPISpec
+	openAPIMode OpenAPIMode
+	// signingKey, when set, signs every request (see WithSigningKey).
+	signingKey *SigningKey
+	retry      RetryConfig
+	stream     StreamConfig
+	// middlewares wrap every send, outermost first (see WithMiddleware).
+	middlewares []Middleware
+	// cache, when set, serves fresh GET responses (see WithCache).
+	cache CacheStore
+	// cacheTTL is the lifetime of responses without freshness information.
+	cacheTTL time.Duration
+	// cacheVary are request headers keying separate cache entries.
+	cacheVary []string
+	// tracer, when set, wraps every call in a client span (see
+	// WithTracerProvider and Config.Tracing).
+	tracer Tracer
+	// auth, when set, authenticates every attempt (see WithAuth).
+	auth AuthProvider
+	// identity, when set, stamps a service identity token on every attempt
+	// (see WithServiceIdentity).
+	identity *ServiceIdentity
+	// tasks run between Start and Close (see WithBackgroundTask).
+	tasks  []BackgroundTask
+	daemon *daemon
+	// inflight tracks unfinished calls for Drain.
+	inflight *inflight
+	headers  map[string]string
+	logger   Logger
+	metrics  MetricsHook
+	// contextHeaders, when set, contributes headers derived from the request
+	// context (see WithContextHeaders).
+	contextHeaders func(ctx context.Context) map[string]string
+
+	// redact masks sensitive headers, query parameters, and body fields in
+	// logs and hooks.
+	redact     redactor
+	onRequest  func(RequestInfo)
+	onResponse func(ResponseInfo)
+
+	// logBodyLimit and logStreamBodyLimit cap how much of a body reaches the
+	// logger for buffered and streamed requests. 0 means no cap.
+	logBodyLimit       int64
+	logStreamBodyLimit int64
 }
 
 var _ Client = httpClient{}
@@ -134,6 +301,90 @@ type Config struct {
 	AppVersion  string            `json:"app_version"`
 	ClientID    string            `json:"client_id"`
 	Headers     map[string]string `json:"headers"`
+	// Proxies routes requests for matching hosts through a proxy, checked in
+	// order. Hosts no rule matches use Proxy.
+	Proxies []ProxyRule `json:"proxies"`
+	// Retry retries transient failures of idempotent requests with
+	// exponential backoff. The zero value never retries.
+	Retry RetryConfig `json:"retry"`
+	// Stream tunes GetStream/PostStream: the done sentinel and reconnects.
+	Stream StreamConfig `json:"stream"`
+	// Tracing wraps every call in a client span written to the logger at
+	// Debug and sends W3C traceparent/baggage headers. WithTracerProvider
+	// swaps in a real tracer.
+	Tracing bool `json:"tracing"`
+	// ExpectContinue holds back large request bodies until the server
+	// accepts the headers.
+	ExpectContinue ExpectContinueConfig `json:"expect_continue"`
+	// Timeouts bounds each phase of a call: connecting, the TLS handshake,
+	// waiting for headers, each attempt, the whole call, and stream
+	// silences. An incoherent hierarchy is logged as a config error.
+	Timeouts Timeouts `json:"timeouts"`
+	// ConnectTimeout bounds dialing and the TLS handshake.
+	//
+	// Deprecated: use Timeouts.Connect, which it fills when unset.
+	ConnectTimeout time.Duration `json:"connect_timeout"`
+	// ResponseHeaderTimeout bounds the wait for response headers.
+	//
+	// Deprecated: use Timeouts.ResponseHeader, which it fills when unset.
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout"`
+	// RequestTimeout is the default deadline of a buffered call.
+	//
+	// Deprecated: use Timeouts.Total, which it fills when unset.
+	RequestTimeout time.Duration `json:"request_timeout"`
+	// Profiles holds per-environment settings by name; Profile selects one
+	// to overlay on the rest of Config.
+	Profiles map[string]Profile `json:"profiles"`
+	Profile  string             `json:"profile"`
+	// TLS sets extra CAs, a client certificate for mutual TLS, or skips
+	// verification in development. A profile's TLS replaces it.
+	TLS TLSConfig `json:"tls"`
+	// Proxy sends requests through one proxy, or opts out of the proxy
+	// environment variables. Proxies rules and Request.Proxy take precedence.
+	Proxy ProxyConfig `json:"proxy"`
+	// ErrorOnStatus makes Get, Post, Put, Patch, and Delete return an
+	// *HTTPError instead of a Response for statuses >= 400, so a failed call
+	// cannot be mistaken for a successful one.
+	ErrorOnStatus bool `json:"error_on_status"`
+	// RateLimit caps the request rate per host. WithRateLimiter shares a
+	// limiter between clients instead.
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	// CircuitBreaker stops calling a host that keeps failing.
+	// WithCircuitBreaker shares a breaker between clients instead.
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+	// AdaptiveConcurrency caps the requests in flight per host at a limit
+	// that follows the host's latency and errors. WithAdaptiveLimiter shares
+	// a limiter between clients instead.
+	AdaptiveConcurrency AdaptiveConcurrencyConfig `json:"adaptive_concurrency"`
+	// ClockSkew tunes the warning logged when a host's Date header shows
+	// its clock drifting from the local one.
+	ClockSkew ClockSkewConfig `json:"clock_skew"`
+	// OnRequest is called with the redacted method, URL, headers, and body
+	// of every call before it is sent.
+	OnRequest func(RequestInfo) `json:"-"`
+	// OnResponse is called with the redacted outcome of every call.
+	OnResponse func(ResponseInfo) `json:"-"`
+	// Redact adds header, query parameter, and JSON or form body field
+	// names to DefaultRedact, masking them in logs and hooks.
+	Redact []string `json:"redact"`
+	// Transport tunes the connection pool and HTTP/2 of the client's own
+	// transport. It does not apply to a client given via WithHTTPClient.
+	Transport TransportConfig `json:"transport"`
+	// Cache serves GET responses from a store, revalidating them with ETag
+	// and Last-Modified. WithCache replaces the store.
+	Cache CacheConfig `json:"cache"`
+	// HeaderPolicy adds and strips headers on every outgoing request and
+	// redirect, after authentication and middleware, rule by rule in order.
+	HeaderPolicy []HeaderRule `json:"header_policy"`
+	// Mock answers every call from fixture files instead of the network;
+	// MockEnvName switches it on from the environment.
+	Mock MockConfig `json:"mock"`
+	// Browser trades the strict API-client defaults for a browser's cookie,
+	// redirect, and header semantics, for scraping and automation.
+	Browser BrowserConfig `json:"browser"`
+	// Async bounds the workers and queue of fire-and-forget calls made with
+	// GoGet, GoPost, and friends.
+	Async AsyncConfig `json:"async"`
 }
 
 // Option configures a Client at construction time.
@@ -151,7 +402,8 @@ func WithLogger(logger Logger) Option {
 
 // WithHTTPClient swaps the underlying *http.Client used for every request, so
 // callers can set custom timeouts, transports, or inject a stub in tests.
-// Without it the client uses http.DefaultClient. A nil client is ignored.
+// Without it the client builds its own transport from Config.Transport. A nil
+// client is ignored.
 func WithHTTPClient(client *http.Client) Option {
 	return func(h *httpClient) {
 		if client != nil {
@@ -160,34 +412,134 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
-// NewClient builds a Client from config and options, defaulting to
-// http.DefaultClient and a silent logger when none are supplied.
-func NewClient(config Config, opts ...Option) Client {
-	if config.Headers == nil {
-		config.Headers = make(map[string]string)
+// WithLogBodyLimits caps how many bytes of each body are written to the logger:
+// body applies to buffered requests and responses, stream to the request body
+// of GetStream/PostStream. 0 means no cap. Without it buffered bodies are
+// logged whole and stream request bodies are cut at 100 bytes. Bodies detected
+// as binary are always replaced by a size placeholder.
+func WithLogBodyLimits(body, stream int) Option {
+	return func(h *httpClient) {
+		h.logBodyLimit = int64(body)
+		h.logStreamBodyLimit = int64(stream)
 	}
-	headers := map[string]string{
-		ClientUserAgentHeaderName:  config.UserAgent,
-		ClientPlatformHeaderName:   config.Platform,
-		ClientAppVersionHeaderName: config.AppVersion,
-		ClientIDHeaderName:         config.ClientID,
-		ServiceNameHeaderName:      config.ServiceName,
+}
+
+// WithContextHeaders derives headers from each call's context, so values an
+// inbound request carried (request id, session, client id) flow to upstream
+// calls without manual copying. They override the client's default headers;
+// Request.Headers, ID, and SessionID still win. The server package's
+// OutboundHeaders has exactly this shape:
+//
+//	client := http.NewClient(cfg, http.WithContextHeaders(server.OutboundHeaders))
+func WithContextHeaders(fn func(ctx context.Context) map[string]string) Option {
+	return func(h *httpClient) {
+		h.contextHeaders = fn
 	}
-	for k, v := range headers {
-		if v != "" {
-			config.Headers[k] = v
-		}
+}
+
+// NewClient builds a Client from config and options, defaulting to a client
+// with its own transport (see Config.Transport) and a silent logger when none
+// are supplied.
+func NewClient(config Config, opts ...Option) Client {
+	config, profile, profileOK := config.applyProfile()
+	config.Headers = config.allHeaders()
+	if config.Browser.Enabled {
+		config.Headers = config.Browser.headers(config.Headers)
 	}
+	timeouts := config.timeouts()
+	config.Stream.IdleTimeout = timeouts.StreamIdle
 
 	h := httpClient{
-		client:  http.DefaultClient,
-		baseURL: config.BaseURL,
-		headers: config.Headers,
-		logger:  nopLogger{},
+		client:             http.DefaultClient,
+		baseURL:            config.BaseURL,
+		headers:            config.Headers,
+		logger:             nopLogger{},
+		pool:               &poolCounters{},
+		streams:            &streamCounters{},
+		daemon:             &daemon{},
+		inflight:           newInflight(),
+		async:              newDispatcher(config.Async),
+		retry:              config.Retry,
+		requestTimeout:     timeouts.Total,
+		attemptTimeout:     timeouts.Attempt,
+		errorOnStatus:      config.ErrorOnStatus,
+		cache:              config.Cache.store(),
+		cacheTTL:           config.Cache.TTL,
+		cacheVary:          config.Cache.VaryHeaders,
+		redact:             newRedactor(config.Redact),
+		onRequest:          config.OnRequest,
+		onResponse:         config.OnResponse,
+		stream:             config.Stream,
+		logStreamBodyLimit: size,
+		auth:               profile.Auth.provider(),
+	}
+	if config.RateLimit.enabled() {
+		h.limiter = NewRateLimiter(config.RateLimit)
+	}
+	if config.CircuitBreaker.enabled() {
+		h.breaker = NewCircuitBreaker(config.CircuitBreaker)
+	}
+	if config.AdaptiveConcurrency.enabled() {
+		h.adaptive = NewAdaptiveLimiter(config.AdaptiveConcurrency)
 	}
 	for _, opt := range opts {
 		opt(&h)
 	}
+	if path := config.Mock.fixtures(); path != "" {
+		return newMockModeClient(path, config.ErrorOnStatus, h.logger)
+	}
+	// every client gets its own pool rather than sharing
+	// http.DefaultTransport with the rest of the process
+	if h.client == http.DefaultClient {
+		h.client = withTransport(h.client, config.Transport.apply)
+	}
+	if !profileOK {
+		h.logger.Error("http-client", "type", "config", "error", "unknown profile", "profile", config.Profile, "profiles", config.ProfileNames())
+	}
+	if !config.TLS.empty() {
+		tlsConfig, err := config.TLS.build()
+		if err != nil {
+			h.logger.Error("http-client", "type", "config", "profile", config.Profile, "error", err)
+		} else {
+			h.client = withTransport(h.client, func(t *http.Transport) { t.TLSClientConfig = tlsConfig })
+		}
+	}
+	if !config.Proxy.empty() {
+		h.client = withTransport(h.client, config.Proxy.apply)
+	}
+	h.skew = newSkewTracker(config.ClockSkew, h.logger)
+	if h.tracer == nil && config.Tracing {
+		h.tracer = logTracer{logger: h.logger}
+	}
+	if err := timeouts.Validate(); err != nil {
+		h.logger.Error("http-client", "type", "config", "error", err)
+	}
+	h.client = applyTimeouts(h.client, config)
+	if config.Browser.Enabled {
+		client, err := config.Browser.apply(h.client)
+		if err != nil {
+			h.logger.Error("http-client", "type", "config", "error", err)
+		}
+		h.client, h.browser = client, true
+	}
+	if config.ExpectContinue.enabled() {
+		h.expect = config.ExpectContinue
+		h.client = h.expect.apply(h.client)
+	}
+	if h.egress != nil {
+		h.client = h.egress.apply(h.client)
+	}
+	if policy, err := newHeaderPolicy(config.HeaderPolicy, config.BaseURL); err != nil {
+		h.logger.Error("http-client", "type", "config", "error", err)
+	} else if policy != nil {
+		h.headerPolicy = policy
+		h.client = policy.applyRedirects(h.client)
+	}
+	h.proxy = newProxyRouter(h.client, config.Proxies)
+	if h.proxy == nil && len(config.Proxies) > 0 {
+		h.logger.Error("http-client", "type", "config", "error", errProxyUnsupported)
+	}
+	h.serverNames = &serverNameClients{clients: make(map[serverNameKey]*http.Client)}
 	return h
 }
 
@@ -230,119 +582,293 @@ func logArgs(base []any, extra ...any) []any {
 }
 
 func limitBodySize(body []byte, maxSize int64) string {
-	if int64(len(body)) > maxSize {
+	if maxSize > 0 && int64(len(body)) > maxSize {
 		return string(body[:maxSize]) + "..."
 	}
 	return string(body)
 }
 
+// size is the default cap on stream request bodies written to the logger.
 const size = 100
 
 func (h httpClient) do(ctx context.Context, method string, req Request, body []byte) (*Response, error) {
-	path, headers, err := h.buildRequestParams(req)
+	ctx, release, err := h.inflight.acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build request URI: %w", err)
+		return nil, err
+	}
+	ctx, cancelTimeout := h.withTimeout(ctx, req)
+	cancel := func() { cancelTimeout(); release() }
+	ctx, span := h.startSpan(ctx, method, req)
+	var info RequestInfo
+	start := time.Now()
+	resp, err := h.doRequest(ctx, method, req, body, &info)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
 	}
+	if err == nil && h.errorOnStatus && status >= http.StatusBadRequest {
+		resp, err = nil, statusError(resp)
+	}
+	h.reportResponse(info, start, resp, err)
+	if resp != nil && resp.Reader != nil {
+		resp.Reader = &cancelOnClose{ReadCloser: resp.Reader, cancel: cancel}
+	} else {
+		cancel()
+	}
+	span.end(status, err)
+	return resp, err
+}
 
-	h.logger.Trace("http-client", "type", "request", "method", method, "headers", headers, "url", path, "query", req.Query, "body", string(body))
+// doRequest performs one buffered or streamed-body call, describing it in
+// info once the request is built.
+func (h httpClient) doRequest(ctx context.Context, method string, req Request, body []byte, info *RequestInfo) (*Response, error) {
+	path, headers, err := h.buildRequestParams(ctx, req)
+	if err != nil {
+		return nil, &RequestError{Op: "build request URI", Method: method, URL: req.Path, Err: err}
+	}
 
-	var httpReq *http.Request
-	// prepare request
-	if body != nil {
-		httpReq, err = http.NewRequestWithContext(ctx, method, path, bytes.NewBuffer(body))
-	} else {
-		httpReq, err = http.NewRequestWithContext(ctx, method, path, http.NoBody)
+	var expected *expectedChecksum
+	if req.ExpectedDigest != "" {
+		if expected, err = parseExpectedDigest(req.ExpectedDigest); err != nil {
+			return nil, err
+		}
 	}
+
+	*info = h.requestInfo(method, path, headers, req, body, h.logBodyLimit, false)
+	h.logger.Trace("http-client", "type", "request", "method", method, "headers", info.Headers, "url", info.URL, "query", h.redact.query(req.Query), "body", info.Body)
+
+	// prepare request
+	httpReq, err := newBodyRequest(ctx, method, path, req, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, &RequestError{Op: "create request", Method: method, URL: path, Err: err}
 	}
 
 	// set headers
 	for k, v := range headers {
 		httpReq.Header.Add(k, v)
 	}
+	h.expectContinue(httpReq)
+
+	if h.egress != nil {
+		if err := h.egress.check(httpReq.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	cacheable := h.cache != nil && method == http.MethodGet && !req.Stream
+	var stale *CacheEntry
+	if cacheable {
+		cached, entry := h.cachedResponse(httpReq)
+		if cached != nil {
+			return cached, nil
+		}
+		if entry != nil && addValidators(httpReq, entry) {
+			stale = entry
+		}
+	}
+
+	if err := h.validateOpenAPI(httpReq, body); err != nil {
+		return nil, err
+	}
+
+	if h.signingKey != nil {
+		if req.BodyReader != nil {
+			return nil, &RequestError{Op: "sign request", Method: method, URL: path, Err: errStreamedBodySigning}
+		}
+		if err := signRequest(httpReq, *h.signingKey, body, time.Now()); err != nil {
+			return nil, &RequestError{Op: "sign request", Method: method, URL: path, Err: err}
+		}
+	}
+
+	client, httpReq, err := h.clientFor(httpReq, req)
+	if err != nil {
+		return nil, &RequestError{Op: "select transport", Method: method, URL: path, Err: err}
+	}
 
 	// send request
-	resp, err := h.client.Do(httpReq)
+	resp, attempts, err := h.sendAttempts(client, httpReq, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, withAttempts(&RequestError{Op: opSend, Method: method, URL: path, Err: err}, attempts)
+	}
+
+	if h.browser {
+		decompressBody(resp)
+	}
+
+	timings := ParseServerTiming(resp.Header.Values(ServerTimingHeaderName))
+	if len(timings) > 0 {
+		h.logger.Debug("http-client", "type", "server-timing", "method", method, "url", info.URL, "timings", serverTimingArgs(timings))
 	}
 
 	// a streamed request hands the live body back to the caller unread, so large
 	// downloads never round-trip through memory. The caller owns Close.
+	checks := responseChecksums(expected, req.VerifyChecksum, resp)
 	if req.Stream {
-		h.logger.Trace("http-client", "type", "response", "method", method, "url", path, "status", resp.StatusCode, "body", "<streamed>")
+		h.logger.Trace("http-client", "type", "response", "method", method, "url", info.URL, "status", resp.StatusCode, "body", "<streamed>")
+		trailers := make(http.Header, len(resp.Trailer))
+		var reader io.ReadCloser = &trailerReader{ReadCloser: resp.Body, resp: resp, dst: trailers}
+		if len(checks) > 0 {
+			reader = newChecksumReader(reader, checks)
+		}
 		return &Response{
-			StatusCode: resp.StatusCode,
-			Reader:     resp.Body,
-			Headers:    resp.Header,
+			StatusCode:   resp.StatusCode,
+			Reader:       reader,
+			Headers:      resp.Header,
+			ServerTiming: timings,
+			Attempts:     attempts,
+			Trailers:     trailers,
 		}, nil
 	}
 
 	defer resp.Body.Close()
 
+	if stale != nil && resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		out := h.revalidated(httpReq, *stale, resp.Header)
+		out.ServerTiming, out.Attempts = timings, attempts
+		return out, nil
+	}
+
 	// read response body
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, &RequestError{Op: "read response body", Method: method, URL: path, Err: err}
 	}
 
-	h.logger.Trace("http-client", "type", "response", "method", method, "url", path, "status", resp.StatusCode, "body", string(data))
+	h.logger.Trace("http-client", "type", "response", "method", method, "url", info.URL, "status", resp.StatusCode, "body", h.redact.body(data, resp.Header.Get("Content-Type"), h.logBodyLimit))
 
-	return &Response{
-		StatusCode: resp.StatusCode,
-		Body:       data,
-		Headers:    resp.Header,
-	}, nil
+	if err := verifyChecksums(data, checks); err != nil {
+		return nil, err
+	}
+
+	out := &Response{
+		StatusCode:   resp.StatusCode,
+		Body:         data,
+		Headers:      resp.Header,
+		ServerTiming: timings,
+		Attempts:     attempts,
+		Trailers:     resp.Trailer,
+	}
+	if cacheable {
+		h.storeResponse(httpReq, out)
+	}
+	return out, nil
 }
 
 func (h httpClient) doStream(ctx context.Context, method string, stream chan StreamResponse, req Request, body []byte) error {
-	path, headers, err := h.buildRequestParams(req)
+	ctx, release, err := h.inflight.acquire(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to build request URI: %w", err)
+		return err
+	}
+	ctx, span := h.startSpan(ctx, method, req)
+	err = h.doStreamRequest(ctx, method, stream, req, body, release)
+	if err != nil {
+		release()
+	}
+	status := 0
+	var herr *HTTPError
+	switch {
+	case err == nil:
+		status = http.StatusOK
+	case errors.As(err, &herr):
+		status = herr.StatusCode
+	}
+	span.end(status, err)
+	return err
+}
+
+// doStreamRequest starts the stream; release is called once a stream that
+// started has ended.
+func (h httpClient) doStreamRequest(ctx context.Context, method string, stream chan StreamResponse, req Request, body []byte, release func()) (err error) {
+	var (
+		info   RequestInfo
+		opened *Response
+		start  = time.Now()
+	)
+	defer func() { h.reportResponse(info, start, opened, err) }()
+
+	path, headers, err := h.buildRequestParams(ctx, req)
+	if err != nil {
+		return &RequestError{Op: "build request URI", Method: method, URL: req.Path, Err: err}
 	}
 
-	logCtx := []any{"type", "stream-request", "method", method, "url", path, "query", req.Query, "req-body", limitBodySize(body, size)}
+	info = h.requestInfo(method, path, headers, req, body, h.logStreamBodyLimit, true)
+	logCtx := []any{"type", "stream-request", "method", method, "url", info.URL, "query", h.redact.query(req.Query), "req-body", info.Body}
 
 	h.logger.Debug("http-client", logCtx...)
 
-	var bodyReader io.Reader
-	if body != nil {
-		bodyReader = bytes.NewBuffer(body)
-	}
-	httpReq, err := http.NewRequestWithContext(ctx, method, path, bodyReader)
+	httpReq, err := newBodyRequest(ctx, method, path, req, body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return &RequestError{Op: "create request", Method: method, URL: path, Err: err}
 	}
 
 	for k, v := range headers {
 		httpReq.Header.Add(k, v)
 	}
-	httpReq.Header.Set("Accept", "text/event-stream")
+	h.expectContinue(httpReq)
+	// a per-request Accept asks for another kind of stream, e.g. multipart
+	if !hasHeader(req.Headers, "Accept") {
+		httpReq.Header.Set("Accept", ContentTypeEventStream)
+	}
 	httpReq.Header.Set("Cache-Control", "no-cache")
 	httpReq.Header.Set("Connection", "keep-alive")
 
+	if h.egress != nil {
+		if err := h.egress.check(httpReq.URL); err != nil {
+			return err
+		}
+	}
+
+	if err := h.validateOpenAPI(httpReq, body); err != nil {
+		return err
+	}
+
+	if h.signingKey != nil {
+		if req.BodyReader != nil {
+			return &RequestError{Op: "sign request", Method: method, URL: path, Err: errStreamedBodySigning}
+		}
+		if err := signRequest(httpReq, *h.signingKey, body, time.Now()); err != nil {
+			return &RequestError{Op: "sign request", Method: method, URL: path, Err: err}
+		}
+	}
+
+	client, httpReq, err := h.clientFor(httpReq, req)
+	if err != nil {
+		return &RequestError{Op: "select transport", Method: method, URL: path, Err: err}
+	}
+
+	if err := h.authenticate(httpReq); err != nil {
+		return &RequestError{Op: "authenticate request", Method: method, URL: path, Err: err}
+	}
+
+	observer := newStreamObserver(h.metrics, h.streams, method, h.redact.url(path))
+
 	//nolint:bodyclose // body is closed by the deferred close in the non-OK branch below and in the consumer goroutine on success
-	resp, err := h.client.Do(httpReq)
+	resp, err := h.roundTrip(client, httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		observer.end(0, readErrorReason(ctx.Err(), err))
+		return &RequestError{Op: opSend, Method: method, URL: path, Err: err}
 	}
+	resp.Body = decodedBody(resp)
 
 	logCtx = logArgs(logCtx, "status", resp.StatusCode)
 
 	h.logger.Debug("http-client", logArgs(logCtx, "request", "sent")...)
 
-	if resp.StatusCode != http.StatusOK {
+	contentType := resp.Header.Get("Content-Type")
+	multipartStream := IsMultipartStream(contentType)
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusPartialContent && multipartStream) {
 		defer resp.Body.Close()
 		defer close(stream)
+		defer observer.end(resp.StatusCode, statusReason(resp.StatusCode))
 		respBody, err := io.ReadAll(resp.Body)
 		if err != nil {
-			err = fmt.Errorf("failed to read error response body: %w", err)
+			err = &RequestError{Op: "read error response body", Method: method, URL: path, Err: err}
 			h.logger.Error("http-client", logArgs(logCtx, "error", err)...)
 			return err
 		}
 
-		err = fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, string(respBody))
+		err = &HTTPError{StatusCode: resp.StatusCode, Body: respBody, Headers: resp.Header}
 
 		h.logger.Error("http-client", logArgs(logCtx, "stream", "started-with-error", "error", err)...)
 
@@ -352,80 +878,201 @@ func (h httpClient) doStream(ctx context.Context, method string, stream chan Str
 			Headers:    resp.Header,
 			Error:      err,
 			Body:       respBody,
+			Disconnect: statusReason(resp.StatusCode),
 		}
 
 		return err
 	}
 
+	if contentType != "" && !IsEventStream(contentType) && !multipartStream {
+		h.logger.Warn("http-client", logArgs(logCtx, "stream", "unexpected-content-type", "content-type", contentType)...)
+	}
 	h.logger.Debug("http-client", logArgs(logCtx, "stream", "started")...)
+	opened = &Response{StatusCode: resp.StatusCode, Headers: resp.Header}
+
+	// a streamed request body is gone after the first send, so such streams
+	// cannot resume
+	reopen := func(lastEventID string) (*http.Response, error) {
+		r := httpReq.Clone(ctx)
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if lastEventID != "" {
+			r.Header.Set(LastEventIDHeaderName, lastEventID)
+		}
+		if err := h.authenticate(r); err != nil {
+			return nil, &RequestError{Op: "authenticate request", Method: method, URL: path, Err: err}
+		}
+		if h.signingKey != nil {
+			if err := signRequest(r, *h.signingKey, body, time.Now()); err != nil {
+				return nil, &RequestError{Op: "sign request", Method: method, URL: path, Err: err}
+			}
+		}
+		return h.roundTrip(client, r)
+	}
+	if req.BodyReader != nil {
+		reopen = nil
+	}
 
 	go func() {
-		defer resp.Body.Close()
-		defer close(stream)
+		defer release()
+		if multipartStream {
+			_, params := ParseMediaType(contentType)
+			h.readParts(ctx, stream, resp, params["boundary"], observer, logCtx)
+			return
+		}
+		h.readStream(ctx, stream, resp, reopen, observer, logCtx)
+	}()
 
-		reader := bufio.NewReader(resp.Body)
-		for {
-			line, err := reader.ReadBytes('\n')
-			h.logger.Debug("http-client", logArgs(logCtx, "raw-line", string(line))...)
-			if err != nil {
-				stream <- StreamResponse{
-					Type:       StreamResponseTypeEOF,
-					StatusCode: resp.StatusCode,
-					Headers:    resp.Header,
-					Error:      fmt.Errorf("failed to read response body: %w", err),
-				}
-				h.logger.Error("http-client", logArgs(logCtx, "stream", "ended-with-error", "error", err)...)
-				break
-			}
+	return nil
+}
 
-			resType := StreamResponseTypeData
-			line = bytes.TrimSpace(line)
+// readStream delivers resp's events on stream until the done sentinel, the
+// end of the stream, or a read error, then sends a terminal EOF frame and
+// closes stream. With StreamConfig.MaxReconnects set and a non-nil reopen it
+// resumes interrupted streams from the last event ID. A panic ends the
+// stream with a *StreamPanicError instead of crashing the process.
+func (h httpClient) readStream(ctx context.Context, stream chan StreamResponse, resp *http.Response, reopen func(lastEventID string) (*http.Response, error), observer *streamObserver, logCtx []any) {
+	defer close(stream)
+	ended := false
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		err := &StreamPanicError{Value: rec, Stack: debug.Stack()}
+		h.logger.Error("http-client", logArgs(logCtx, "stream", "panicked", "panic", fmt.Sprint(rec), "stack", string(err.Stack))...)
+		if ended {
+			return
+		}
+		resp.Body.Close()
+		stream <- StreamResponse{
+			Type:       StreamResponseTypeEOF,
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Header,
+			Error:      err,
+			Disconnect: StreamDisconnectPanic,
+		}
+		observer.end(resp.StatusCode, StreamDisconnectPanic)
+	}()
 
-			if len(line) == 0 {
+	cfg := h.stream
+	resp.Body = watchIdle(resp.Body, cfg.IdleTimeout)
+	reader := NewSSEReader(resp.Body)
+	sequencer := &streamSequencer{sequential: cfg.SequentialIDs}
+	reconnects := 0
+	for {
+		ev, err := reader.Next()
+		if err == nil {
+			reconnects = 0
+			if !cfg.NoDoneSentinel && string(ev.Data) == cfg.doneSentinel() {
+				resp.Body.Close()
+				ended = true
+				stream <- StreamResponse{Type: StreamResponseTypeEOF, StatusCode: resp.StatusCode, Headers: resp.Header, Retry: reader.Retry(), Disconnect: StreamDisconnectDone}
+				observer.end(resp.StatusCode, StreamDisconnectDone)
+				return
+			}
+			seq, gap, duplicate := sequencer.next(ev.ID)
+			if duplicate {
+				observer.metrics.Duplicates++
+				h.logger.Debug("http-client", logArgs(logCtx, "stream", "dropped-duplicate", "sse-id", ev.ID)...)
 				continue
 			}
-			h.logger.Debug("http-client", logArgs(logCtx, "type", resType, "pre-processed-line", string(line))...)
-			if bytes.HasPrefix(line, []byte("data: ")) {
-				line = bytes.TrimPrefix(line, []byte("data: "))
-				if bytes.Equal(line, []byte("[DONE]")) {
-					stream <- StreamResponse{
-						Type:       StreamResponseTypeEOF,
-						StatusCode: resp.StatusCode,
-						Headers:    resp.Header,
-					}
-					return
-				}
-			} else if bytes.HasPrefix(line, []byte("event: ")) {
-				resType = StreamResponseTypeEvent
-				line = bytes.TrimPrefix(line, []byte("event: "))
-			} else if bytes.HasPrefix(line, []byte("id: ")) {
-				resType = StreamResponseTypeID
-				line = bytes.TrimPrefix(line, []byte("id: "))
-			} else if bytes.HasPrefix(line, []byte("retry: ")) {
-				resType = StreamResponseTypeRetry
-				line = bytes.TrimPrefix(line, []byte("retry: "))
-			} else if bytes.HasPrefix(line, []byte(":")) {
-				resType = StreamResponseTypeComment
+			if gap != nil {
+				observer.metrics.Missed += gap.Missed
+				h.logger.Warn("http-client", logArgs(logCtx, "stream", "gap", "sse-id", ev.ID, "last-id", gap.LastID, "missed", gap.Missed, "reconnected", gap.Reconnected)...)
 			}
-
 			stream <- StreamResponse{
-				Type:       resType,
+				Type:       StreamResponseTypeData,
 				StatusCode: resp.StatusCode,
 				Headers:    resp.Header,
-				Body:       line,
+				Body:       ev.Data,
+				Event:      ev.Event,
+				ID:         ev.ID,
+				Retry:      reader.Retry(),
+				Seq:        seq,
+				Gap:        gap,
 			}
-			h.logger.Debug("http-client", logArgs(logCtx, "type", resType, "sse-processed-line", string(line))...)
+			observer.event()
+			h.logger.Debug("http-client", logArgs(logCtx, "sse-event", ev.Event, "sse-id", ev.ID, "sse-data", h.redact.body(ev.Data, "", h.logStreamBodyLimit))...)
+			continue
+		}
+		resp.Body.Close()
+		err = fmt.Errorf("failed to read response body: %w", err)
+
+		if reopen != nil && reconnects < cfg.MaxReconnects && ctx.Err() == nil {
+			reconnects++
+			next, reconnectErr := h.reconnectStream(ctx, reopen, reader, reconnects, logCtx)
+			if reconnectErr == nil {
+				resp = next
+				resp.Body = watchIdle(decodedBody(resp), cfg.IdleTimeout)
+				reader.reset(resp.Body)
+				sequencer.reconnected = true
+				continue
+			}
+			err = reconnectErr
 		}
-	}()
 
-	return nil
+		ended = true
+		reason := readErrorReason(ctx.Err(), err)
+		stream <- StreamResponse{
+			Type:       StreamResponseTypeEOF,
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Header,
+			Error:      err,
+			Trailers:   resp.Trailer,
+			Retry:      reader.Retry(),
+			Disconnect: reason,
+		}
+		h.logger.Error("http-client", logArgs(logCtx, "stream", "ended-with-error", "error", err, "disconnect", reason)...)
+		observer.end(resp.StatusCode, reason)
+		return
+	}
 }
 
-func (h httpClient) buildRequestParams(req Request) (string, map[string]string, error) {
+// reconnectStream waits the server's retry: delay (or the configured one)
+// and reopens the stream from the reader's last event ID.
+func (h httpClient) reconnectStream(ctx context.Context, reopen func(string) (*http.Response, error), reader *SSEReader, attempt int, logCtx []any) (*http.Response, error) {
+	delay := h.stream.retryDelay(reader)
+	h.logger.Debug("http-client", logArgs(logCtx, "stream", "reconnecting", "attempt", attempt, "last-event-id", reader.LastEventID(), "delay", delay)...)
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	resp, err := reopen(reader.LastEventID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconnect stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to reconnect stream: unexpected status code: %d: %s", resp.StatusCode, respBody)
+	}
+	return resp, nil
+}
+
+func (h httpClient) buildRequestParams(ctx context.Context, req Request) (string, map[string]string, error) {
 	headers := make(map[string]string)
 	for k, v := range h.headers {
 		headers[k] = v
 	}
+	if h.contextHeaders != nil {
+		for k, v := range h.contextHeaders(ctx) {
+			if v != "" {
+				headers[k] = v
+			}
+		}
+	}
+	if h.tracer != nil {
+		for k, v := range traceHeaders(ctx, headers) {
+			headers[k] = v
+		}
+	}
 	for k, v := range req.Headers {
 		headers[k] = v
 	}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func newTestClient(t *testing.T, baseURL string, opts ...Option) Client {
@@ -274,7 +276,9 @@ func Test_Client_PostStream_SSE(t *testing.T) {
 		_, _ = io.WriteString(w, "event: greeting\n")
 		_, _ = io.WriteString(w, "retry: 1000\n")
 		_, _ = io.WriteString(w, "data: hello\n")
-		_, _ = io.WriteString(w, "data: [DONE]\n")
+		_, _ = io.WriteString(w, "data:  world\n\n")
+		_, _ = io.WriteString(w, "data: second\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
 	}))
 	defer srv.Close()
 
@@ -285,27 +289,19 @@ func Test_Client_PostStream_SSE(t *testing.T) {
 		t.Fatalf("PostStream returned error: %v", err)
 	}
 
-	var types []StreamResponseType
-	var bodies []string
+	var got []StreamResponse
 	for msg := range stream {
-		types = append(types, msg.Type)
-		bodies = append(bodies, string(msg.Body))
+		msg.Headers = nil
+		got = append(got, msg)
 	}
 
-	wantTypes := []StreamResponseType{
-		StreamResponseTypeComment,
-		StreamResponseTypeID,
-		StreamResponseTypeEvent,
-		StreamResponseTypeRetry,
-		StreamResponseTypeData,
-		StreamResponseTypeEOF,
+	want := []StreamResponse{
+		{Type: StreamResponseTypeData, StatusCode: http.StatusOK, Event: "greeting", ID: "42", Body: []byte("hello\n world"), Retry: time.Second, Seq: 1},
+		{Type: StreamResponseTypeData, StatusCode: http.StatusOK, ID: "42", Body: []byte("second"), Retry: time.Second, Seq: 2},
+		{Type: StreamResponseTypeEOF, StatusCode: http.StatusOK, Retry: time.Second, Disconnect: StreamDisconnectDone},
 	}
-	if !reflect.DeepEqual(types, wantTypes) {
-		t.Errorf("stream types = %v, want %v", types, wantTypes)
-	}
-	wantBodies := []string{": a comment", "42", "greeting", "1000", "hello", ""}
-	if !reflect.DeepEqual(bodies, wantBodies) {
-		t.Errorf("stream bodies = %v, want %v", bodies, wantBodies)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stream = %+v, want %+v", got, want)
 	}
 }
 
@@ -341,6 +337,51 @@ func Test_Client_GetStream_NonOKStatus(t *testing.T) {
 	}
 }
 
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) { panic("malformed frame") }
+
+func Test_Client_GetStream_Panic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: hi\n\n")
+	}))
+	defer srv.Close()
+
+	var metrics StreamMetrics
+	client := newTestClient(t, srv.URL,
+		WithMiddleware(func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				resp, err := next(req)
+				if err == nil {
+					resp.Body = io.NopCloser(io.MultiReader(resp.Body, panicReader{}))
+				}
+				return resp, err
+			}
+		}),
+		WithMetricsHook(MetricsHook{OnStream: func(m StreamMetrics) { metrics = m }}),
+	)
+	stream := make(chan StreamResponse, 4)
+	if err := client.GetStream(context.Background(), stream, Request{Path: "/sse"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []StreamResponse
+	for msg := range stream {
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) != 2 || string(msgs[0].Body) != "hi" || msgs[1].Type != StreamResponseTypeEOF {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	var perr *StreamPanicError
+	if !errors.As(msgs[1].Error, &perr) || perr.Value != "malformed frame" || len(perr.Stack) == 0 {
+		t.Fatalf("error = %v, want *StreamPanicError", msgs[1].Error)
+	}
+	if metrics.DisconnectReason != StreamDisconnectPanic {
+		t.Errorf("disconnect reason = %q, want %q", metrics.DisconnectReason, StreamDisconnectPanic)
+	}
+}
+
 func Test_Client_WithLogger(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -492,7 +533,7 @@ func Test_BuildRequestParams(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			h := httpClient{baseURL: tt.baseURL, headers: tt.clientHdrs}
-			path, headers, err := h.buildRequestParams(tt.req)
+			path, headers, err := h.buildRequestParams(context.Background(), tt.req)
 			if tt.wantErr {
 				if err == nil {
 					t.Fatal("expected error, got nil")
@@ -517,7 +558,7 @@ func Test_BuildRequestParams(t *testing.T) {
 func Test_BuildRequestParams_DoesNotMutateClientHeaders(t *testing.T) {
 	clientHdrs := map[string]string{"X-Base": "base"}
 	h := httpClient{baseURL: "https://api.example.com", headers: clientHdrs}
-	_, headers, err := h.buildRequestParams(Request{Path: "/x", Headers: map[string]string{"X-Req": "req"}})
+	_, headers, err := h.buildRequestParams(context.Background(), Request{Path: "/x", Headers: map[string]string{"X-Req": "req"}})
 	if err != nil {
 		t.Fatalf("buildRequestParams returned error: %v", err)
 	}
@@ -529,6 +570,35 @@ func Test_BuildRequestParams_DoesNotMutateClientHeaders(t *testing.T) {
 	}
 }
 
+type ctxHeaderKey struct{}
+
+func Test_BuildRequestParams_ContextHeaders(t *testing.T) {
+	h := httpClient{
+		headers: map[string]string{ClientIDHeaderName: "self", "X-Base": "base"},
+		contextHeaders: func(ctx context.Context) map[string]string {
+			id, _ := ctx.Value(ctxHeaderKey{}).(string)
+			return map[string]string{ClientRequestIDHeaderName: id, ClientIDHeaderName: "caller", ClientSessionIDHeaderName: ""}
+		},
+	}
+	ctx := context.WithValue(context.Background(), ctxHeaderKey{}, "req-in")
+
+	_, headers, err := h.buildRequestParams(ctx, Request{Path: "/x"})
+	if err != nil {
+		t.Fatalf("buildRequestParams returned error: %v", err)
+	}
+	if headers[ClientRequestIDHeaderName] != "req-in" || headers[ClientIDHeaderName] != "caller" || headers["X-Base"] != "base" {
+		t.Errorf("headers = %v", headers)
+	}
+	if _, ok := headers[ClientSessionIDHeaderName]; ok {
+		t.Error("empty context headers must not be sent")
+	}
+
+	_, headers, _ = h.buildRequestParams(ctx, Request{Path: "/x", ID: "explicit"})
+	if headers[ClientRequestIDHeaderName] != "explicit" {
+		t.Errorf("Request.ID must win over context: %q", headers[ClientRequestIDHeaderName])
+	}
+}
+
 func Test_UserAgent(t *testing.T) {
 	got := UserAgent("awee-cli", "1.0.0", "darwin", "23.0", "arm64")
 	want := "awee-cli/1.0.0 (darwin 23.0; arm64)"
@@ -548,6 +618,7 @@ func Test_LimitBodySize(t *testing.T) {
 		{name: "at limit", body: "abc", maxSize: 3, want: "abc"},
 		{name: "over limit truncated", body: "abcdef", maxSize: 3, want: "abc..."},
 		{name: "empty", body: "", maxSize: 10, want: ""},
+		{name: "zero means no cap", body: "abcdef", maxSize: 0, want: "abcdef"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -576,6 +647,7 @@ func Test_LogArgs(t *testing.T) {
 type recordingLogger struct {
 	traces int
 	debugs int
+	warns  int
 	errs   int
 }
 
@@ -584,5 +656,28 @@ var _ Logger = (*recordingLogger)(nil)
 func (l *recordingLogger) Trace(string, ...any) { l.traces++ }
 func (l *recordingLogger) Debug(string, ...any) { l.debugs++ }
 func (l *recordingLogger) Info(string, ...any)  {}
-func (l *recordingLogger) Warn(string, ...any)  {}
+func (l *recordingLogger) Warn(string, ...any)  { l.warns++ }
 func (l *recordingLogger) Error(string, ...any) { l.errs++ }
+
+func Test_Response_Correlation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ClientRequestIDHeaderName, r.Header.Get(ClientRequestIDHeaderName))
+		w.Header().Set(ServedByHeaderName, "api-1")
+		w.Header().Set(ResponseTimeHeaderName, "12.5ms")
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{Request: Request{ID: "req-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RequestID() != "req-1" || resp.ServedBy() != "api-1" {
+		t.Fatalf("request id = %q, served by = %q", resp.RequestID(), resp.ServedBy())
+	}
+	if d, ok := resp.ResponseTime(); !ok || d != 12500*time.Microsecond {
+		t.Fatalf("response time = %v, %v", d, ok)
+	}
+	if _, ok := (&Response{Headers: http.Header{}}).ResponseTime(); ok {
+		t.Fatal("a missing X-Response-Time was reported")
+	}
+}
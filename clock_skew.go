@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultClockSkewThreshold is ClockSkewConfig.Threshold when unset.
+const DefaultClockSkewThreshold = 30 * time.Second
+
+// ClockSkewConfig tunes how the client reports the difference between a
+// host's clock, read from the Date header of its responses, and the local
+// one. Skew silently breaks signed requests, token expiry, and cache
+// freshness, so the client always measures it.
+type ClockSkewConfig struct {
+	// Threshold logs a warning when a host's clock drifts further than
+	// this, once per excursion. Defaults to DefaultClockSkewThreshold;
+	// negative never warns.
+	Threshold time.Duration `json:"threshold"`
+	// OnSkew, when set, is called with every measurement, e.g. to export
+	// it as a gauge.
+	OnSkew func(host string, skew time.Duration) `json:"-"`
+}
+
+// ClockSkewProvider is implemented by clients built with NewClient. Skew is
+// the host's clock minus the local one, so positive means the host is
+// ahead; the Date header has whole seconds, so readings within a second or
+// so are noise.
+//
+//	if p, ok := client.(http.ClockSkewProvider); ok { skew := p.ClockSkew() }
+type ClockSkewProvider interface {
+	ClockSkew() map[string]time.Duration
+}
+
+var _ ClockSkewProvider = httpClient{}
+
+// skewTracker keeps the latest skew of every host.
+type skewTracker struct {
+	threshold time.Duration
+	onSkew    func(string, time.Duration)
+	logger    Logger
+
+	mu    sync.Mutex
+	hosts map[string]time.Duration
+}
+
+func newSkewTracker(cfg ClockSkewConfig, logger Logger) *skewTracker {
+	threshold := cfg.Threshold
+	if threshold == 0 {
+		threshold = DefaultClockSkewThreshold
+	}
+	return &skewTracker{threshold: threshold, onSkew: cfg.OnSkew, logger: logger, hosts: make(map[string]time.Duration)}
+}
+
+// ClockSkew implements ClockSkewProvider.
+func (h httpClient) ClockSkew() map[string]time.Duration {
+	out := make(map[string]time.Duration)
+	if h.skew == nil {
+		return out
+	}
+	h.skew.mu.Lock()
+	defer h.skew.mu.Unlock()
+	for host, skew := range h.skew.hosts {
+		out[host] = skew
+	}
+	return out
+}
+
+// observe records the skew resp's Date header shows, taking the host to
+// have stamped it halfway between sent and received.
+func (t *skewTracker) observe(host string, resp *http.Response, sent, received time.Time) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	skew := date.Sub(sent.Add(received.Sub(sent) / 2)).Round(time.Second)
+
+	t.mu.Lock()
+	previous, seen := t.hosts[host]
+	t.hosts[host] = skew
+	t.mu.Unlock()
+
+	if t.onSkew != nil {
+		t.onSkew(host, skew)
+	}
+	if t.threshold > 0 && exceeds(skew, t.threshold) && !(seen && exceeds(previous, t.threshold)) {
+		t.logger.Warn("http-client", "type", "clock-skew", "host", host, "skew", skew, "threshold", t.threshold)
+	}
+}
+
+func exceeds(skew, threshold time.Duration) bool {
+	return skew > threshold || skew < -threshold
+}
+
+func (t *skewTracker) wrap(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		sent := time.Now()
+		resp, err := next(req)
+		if err == nil {
+			t.observe(req.URL.Host, resp, sent, time.Now())
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Client_ClockSkew(t *testing.T) {
+	var offset atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Duration(offset.Load())).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	log := &recordingLogger{}
+	var reported []time.Duration
+	c := NewClient(Config{
+		BaseURL:   srv.URL,
+		ClockSkew: ClockSkewConfig{OnSkew: func(_ string, skew time.Duration) { reported = append(reported, skew) }},
+	}, WithLogger(log))
+	get := func() {
+		if _, err := c.Get(context.Background(), GetRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	host := srv.Listener.Addr().String()
+
+	get()
+	if skew := c.(ClockSkewProvider).ClockSkew()[host]; skew < -time.Second || skew > time.Second || log.warns != 0 {
+		t.Fatalf("in-sync skew = %v, warnings = %d", skew, log.warns)
+	}
+
+	// the host running two minutes ahead warns once per excursion
+	offset.Store(int64(2 * time.Minute))
+	get()
+	get()
+	skew := c.(ClockSkewProvider).ClockSkew()[host]
+	if skew < 119*time.Second || skew > 121*time.Second {
+		t.Fatalf("skew = %v, want about 2m", skew)
+	}
+	if log.warns != 1 {
+		t.Fatalf("warnings = %d, want 1", log.warns)
+	}
+	offset.Store(0)
+	get()
+	offset.Store(int64(-2 * time.Minute))
+	get()
+	if log.warns != 2 || len(reported) != 5 {
+		t.Fatalf("warnings = %d, reports = %d", log.warns, len(reported))
+	}
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// secretSuffixes mark config field and map key names whose values
+// MaskConfig hides, matched against the end of the name lowercased with
+// "_" and "-" removed, so bearer_token and X-Api-Key are caught but
+// api_key_header is not.
+var secretSuffixes = []string{
+	"password",
+	"secret",
+	"token",
+	"apikey",
+	"privatekey",
+	"credentials",
+	"authorization",
+	"cookie",
+}
+
+// maxConfigDepth stops MaskConfig following pointers that loop.
+const maxConfigDepth = 16
+
+// Dump returns the configuration a client built from c runs with - the
+// selected Profile overlaid, the identifying headers merged into Headers,
+// and transport defaults filled in - as JSON-ready values with secrets
+// masked (see MaskConfig). Serve it from an admin endpoint, e.g. the server
+// package's ConfigHandler, to see what a deployment actually runs with.
+func (c Config) Dump() map[string]any {
+	effective, _, _ := c.applyProfile()
+	effective.Headers = effective.allHeaders()
+	if effective.Transport.MaxIdleConnsPerHost <= 0 {
+		effective.Transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	out, _ := MaskConfig(effective, c.Redact...).(map[string]any)
+	return out
+}
+
+// allHeaders returns Headers with the identifying headers set from their
+// fields, in a new map.
+func (c Config) allHeaders() map[string]string {
+	headers := make(map[string]string, len(c.Headers)+5)
+	for k, v := range c.Headers {
+		headers[k] = v
+	}
+	identifying := map[string]string{
+		ClientUserAgentHeaderName:  c.UserAgent,
+		ClientPlatformHeaderName:   c.Platform,
+		ClientAppVersionHeaderName: c.AppVersion,
+		ClientIDHeaderName:         c.ClientID,
+		ServiceNameHeaderName:      c.ServiceName,
+	}
+	for k, v := range identifying {
+		if v != "" {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+// MaskConfig turns a configuration value into maps, slices, and scalars
+// ready to encode as JSON, replacing secrets with RedactedValue: fields and
+// map keys named like a password, secret, token, API key, private key, or
+// credential, those in DefaultRedact or names, and the password of any URL
+// (written "xxxxx"). Struct fields are keyed by their json tag, or their
+// name when untagged; fields tagged "-", functions, and channels are left
+// out. Durations are written as "1m30s". Empty secrets stay empty, so an
+// unset one shows.
+func MaskConfig(v any, names ...string) any {
+	m := configMasker{redact: newRedactor(names)}
+	out, _ := m.value(reflect.ValueOf(v), 0)
+	return out
+}
+
+type configMasker struct {
+	redact redactor
+}
+
+func (m configMasker) secret(name string) bool {
+	if m.redact.match(name) {
+		return true
+	}
+	name = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// field masks v when it is a non-empty secret called name.
+func (m configMasker) field(name string, v reflect.Value, depth int) (any, bool) {
+	if m.secret(name) && v.IsValid() && !v.IsZero() {
+		switch v.Kind() {
+		case reflect.Func, reflect.Chan:
+		default:
+			return RedactedValue, true
+		}
+	}
+	return m.value(v, depth+1)
+}
+
+// value converts v, reporting false for what is left out.
+func (m configMasker) value(v reflect.Value, depth int) (any, bool) {
+	if !v.IsValid() {
+		return nil, true
+	}
+	if depth > maxConfigDepth {
+		return fmt.Sprintf("%s(...)", v.Type()), true
+	}
+	switch v.Type() {
+	case reflect.TypeOf(time.Duration(0)):
+		return time.Duration(v.Int()).String(), true
+	case reflect.TypeOf(time.Time{}):
+		return v.Interface().(time.Time).Format(time.RFC3339), true
+	}
+	switch v.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil, false
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, true
+		}
+		return m.value(v.Elem(), depth)
+	case reflect.Struct:
+		return m.structValue(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, true
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if item, ok := m.field(key, iter.Value(), depth); ok {
+				out[key] = item
+			}
+		}
+		return out, true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, true
+		}
+		out := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if item, ok := m.value(v.Index(i), depth+1); ok {
+				out = append(out, item)
+			}
+		}
+		return out, true
+	case reflect.String:
+		return maskURLPassword(v.String()), true
+	}
+	return v.Interface(), true
+}
+
+// structValue converts the exported fields of v; a struct with none, such
+// as a store or a logger behind an interface, is written as its type name.
+func (m configMasker) structValue(v reflect.Value, depth int) (any, bool) {
+	t := v.Type()
+	out := make(map[string]any, t.NumField())
+	exported := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		exported = true
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		if item, ok := m.field(name, v.Field(i), depth); ok {
+			out[name] = item
+		}
+	}
+	if !exported && t.NumField() > 0 {
+		return t.String(), true
+	}
+	return out, true
+}
+
+// maskURLPassword hides the password of a URL with credentials as
+// url.URL.Redacted does, leaving every other string as it is.
+func maskURLPassword(s string) string {
+	if !strings.Contains(s, "@") || !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
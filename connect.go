@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Connect protocol headers (https://connectrpc.com/docs/protocol).
+const (
+	ConnectProtocolVersionHeaderName = "Connect-Protocol-Version"
+	ConnectTimeoutHeaderName         = "Connect-Timeout-Ms"
+)
+
+// ConnectRequest is a Connect unary call. Path is the procedure, e.g.
+// "/acme.user.v1.UserService/GetUser", resolved against the client's base URL
+// like any other request. Message is marshaled as the JSON request message.
+//
+// Only the JSON codec is spoken: the client stays free of protobuf, and every
+// Connect server accepts application/json for unary calls. The gRPC and
+// gRPC-Web protocols (application/grpc-web+json) are out of scope: their
+// length-prefixed framing and trailer-borne status are not implemented.
+type ConnectRequest struct {
+	Request
+
+	Message any
+	// Timeout, when set, is sent as Connect-Timeout-Ms so the server can give
+	// up at the same time the caller does. It also bounds the call when
+	// Request.Timeout is unset.
+	Timeout time.Duration
+}
+
+// ConnectError is the error envelope a Connect server returns for a failed
+// call. Code is one of the Connect/gRPC code names ("not_found",
+// "unavailable", ...). When the server did not send an envelope (a proxy error
+// page, say), Code is derived from StatusCode.
+type ConnectError struct {
+	Code       string            `json:"code"`
+	Message    string            `json:"message,omitempty"`
+	Details    []json.RawMessage `json:"details,omitempty"`
+	StatusCode int               `json:"-"`
+}
+
+// Error renders the code and, when present, the server's message.
+func (e *ConnectError) Error() string {
+	if e.Message == "" {
+		return "connect: " + e.Code
+	}
+	return "connect: " + e.Code + ": " + e.Message
+}
+
+// CallConnect performs a Connect unary call through c and decodes the
+// response message into T. A failed call returns a *ConnectError.
+func CallConnect[T any](ctx context.Context, c Client, req ConnectRequest) (T, error) {
+	var out T
+
+	message := req.Message
+	if message == nil {
+		message = struct{}{}
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return out, fmt.Errorf("failed to marshal connect request: %w", err)
+	}
+
+	headers := make(map[string]string, len(req.Headers)+3)
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	headers["Content-Type"] = ContentTypeJSON
+	headers[ConnectProtocolVersionHeaderName] = "1"
+	if req.Timeout > 0 {
+		headers[ConnectTimeoutHeaderName] = strconv.FormatInt(req.Timeout.Milliseconds(), 10)
+	}
+	req.Headers = headers
+	if req.Request.Timeout == 0 {
+		req.Request.Timeout = req.Timeout
+	}
+
+	resp, err := c.Post(ctx, PostRequest{Request: req.Request, Body: body})
+	if err != nil {
+		// a client with ErrorOnStatus reports the failed call as *HTTPError
+		var herr *HTTPError
+		if errors.As(err, &herr) {
+			return out, connectErrorFrom(&Response{StatusCode: herr.StatusCode, Body: herr.Body, Headers: herr.Headers})
+		}
+		return out, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return out, connectErrorFrom(resp)
+	}
+	if err := json.Unmarshal(resp.Body, &out); err != nil {
+		return out, fmt.Errorf("failed to unmarshal connect response: %w", err)
+	}
+	return out, nil
+}
+
+// connectErrorFrom decodes the server's error envelope, falling back to the
+// protocol's HTTP-status-to-code mapping when there is none.
+func connectErrorFrom(resp *Response) *ConnectError {
+	cerr := &ConnectError{StatusCode: resp.StatusCode}
+	if mediaType, _ := ParseMediaType(resp.Headers.Get("Content-Type")); mediaType == ContentTypeJSON {
+		if json.Unmarshal(resp.Body, cerr) == nil && cerr.Code != "" {
+			return cerr
+		}
+	}
+	cerr.Code = connectCodeFromStatus(resp.StatusCode)
+	cerr.Message = http.StatusText(resp.StatusCode)
+	return cerr
+}
+
+func connectCodeFromStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "internal"
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return "permission_denied"
+	case http.StatusNotFound:
+		return "unimplemented"
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "unavailable"
+	default:
+		return "unknown"
+	}
+}
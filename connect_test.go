@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type greetRequest struct {
+	Name string `json:"name"`
+}
+
+type greetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func Test_CallConnect_Success(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/greet.v1.GreetService/Greet" {
+			t.Errorf("got %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get(ConnectProtocolVersionHeaderName) != "1" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		if r.Header.Get(ConnectTimeoutHeaderName) != "1500" {
+			t.Errorf("timeout header = %q, want 1500", r.Header.Get(ConnectTimeoutHeaderName))
+		}
+		var in greetRequest
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(greetResponse{Greeting: "hello " + in.Name})
+	}))
+	defer srv.Close()
+
+	out, err := CallConnect[greetResponse](context.Background(), newTestClient(t, srv.URL), ConnectRequest{
+		Request: Request{Path: "/greet.v1.GreetService/Greet"},
+		Message: greetRequest{Name: "ada"},
+		Timeout: 1500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("CallConnect returned error: %v", err)
+	}
+	if out.Greeting != "hello ada" {
+		t.Errorf("greeting = %q", out.Greeting)
+	}
+}
+
+func Test_CallConnect_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantCode    string
+		wantMessage string
+	}{
+		{name: "envelope", status: http.StatusNotFound, contentType: "application/json", body: `{"code":"not_found","message":"no such user"}`, wantCode: "not_found", wantMessage: "no such user"},
+		{name: "no envelope", status: http.StatusServiceUnavailable, contentType: "text/html", body: "<h1>down</h1>", wantCode: "unavailable", wantMessage: "Service Unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			for _, errorOnStatus := range []bool{false, true} {
+				c := NewClient(Config{BaseURL: srv.URL, ErrorOnStatus: errorOnStatus})
+				_, err := CallConnect[greetResponse](context.Background(), c, ConnectRequest{Request: Request{Path: "/svc/M"}})
+				var cerr *ConnectError
+				if !errors.As(err, &cerr) {
+					t.Fatalf("ErrorOnStatus=%v: err = %v, want *ConnectError", errorOnStatus, err)
+				}
+				if cerr.Code != tt.wantCode || cerr.Message != tt.wantMessage || cerr.StatusCode != tt.status {
+					t.Errorf("ErrorOnStatus=%v: ConnectError = %+v", errorOnStatus, cerr)
+				}
+			}
+		})
+	}
+}
+
+func Test_CallConnect_TimeoutBoundsCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	_, err := CallConnect[greetResponse](context.Background(), newTestClient(t, srv.URL), ConnectRequest{
+		Request: Request{Path: "/svc/M"},
+		Timeout: 50 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("expected the call to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("call took %v, Timeout was not applied", elapsed)
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"mime"
+	"strings"
+)
+
+// Media types the client sends, accepts, and detects.
+const (
+	ContentTypeJSON         = "application/json"
+	ContentTypeProblemJSON  = "application/problem+json"
+	ContentTypeNDJSON       = "application/x-ndjson"
+	ContentTypeEventStream  = "text/event-stream"
+	ContentTypeForm         = "application/x-www-form-urlencoded"
+	ContentTypeMultipart    = "multipart/form-data"
+	ContentTypeMixedReplace = "multipart/x-mixed-replace"
+	ContentTypeByteRanges   = "multipart/byteranges"
+	ContentTypeOctetStream  = "application/octet-stream"
+	ContentTypeText         = "text/plain"
+)
+
+// ParseMediaType returns the lowercased media type of a Content-Type or
+// Accept value and its parameters. Unlike mime.ParseMediaType it never
+// fails: a malformed value yields "" and a malformed parameter is dropped.
+func ParseMediaType(contentType string) (string, map[string]string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil && err != mime.ErrInvalidMediaParameter {
+		return "", nil
+	}
+	return mediaType, params
+}
+
+// IsJSON reports whether contentType is JSON: application/json or a +json
+// type such as application/problem+json. Streams of JSON values such as
+// NDJSON are not.
+func IsJSON(contentType string) bool {
+	mediaType, _ := ParseMediaType(contentType)
+	return mediaType == ContentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// IsEventStream reports whether contentType is a server-sent event stream.
+func IsEventStream(contentType string) bool {
+	mediaType, _ := ParseMediaType(contentType)
+	return mediaType == ContentTypeEventStream
+}
+
+// IsMultipartStream reports whether contentType is a multipart response
+// GetStream delivers part by part: multipart/x-mixed-replace (MJPEG and
+// other feeds) or multipart/byteranges, with a boundary.
+func IsMultipartStream(contentType string) bool {
+	mediaType, params := ParseMediaType(contentType)
+	return (mediaType == ContentTypeMixedReplace || mediaType == ContentTypeByteRanges) && params["boundary"] != ""
+}
+
+// IsForm reports whether contentType is a URL-encoded form.
+func IsForm(contentType string) bool {
+	mediaType, _ := ParseMediaType(contentType)
+	return mediaType == ContentTypeForm
+}
@@ -0,0 +1,31 @@
+package http
+
+import "testing"
+
+func Test_ContentTypeHelpers(t *testing.T) {
+	tests := []struct {
+		contentType       string
+		mediaType         string
+		json, eventStream bool
+	}{
+		{"application/json", ContentTypeJSON, true, false},
+		{"Application/JSON; charset=utf-8", ContentTypeJSON, true, false},
+		{"application/vnd.api+json", "application/vnd.api+json", true, false},
+		{"application/x-ndjson", ContentTypeNDJSON, false, false},
+		{"text/event-stream", ContentTypeEventStream, false, true},
+		{"text/event-stream; charset", ContentTypeEventStream, false, true},
+		{"", "", false, false},
+		{"/;", "", false, false},
+	}
+	for _, tt := range tests {
+		if mt, _ := ParseMediaType(tt.contentType); mt != tt.mediaType {
+			t.Errorf("ParseMediaType(%q) = %q, want %q", tt.contentType, mt, tt.mediaType)
+		}
+		if IsJSON(tt.contentType) != tt.json || IsEventStream(tt.contentType) != tt.eventStream {
+			t.Errorf("%q: IsJSON = %v, IsEventStream = %v", tt.contentType, IsJSON(tt.contentType), IsEventStream(tt.contentType))
+		}
+	}
+	if _, params := ParseMediaType("multipart/form-data; boundary=x"); params["boundary"] != "x" {
+		t.Errorf("params = %v", params)
+	}
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errDaemonStarted is returned by Start on a client already running.
+var errDaemonStarted = errors.New("background tasks already started")
+
+// BackgroundTask is periodic work a client runs between Start and Close:
+// token refresh, endpoint re-resolution, cache cleanup, health probing.
+type BackgroundTask struct {
+	Name string
+	// Interval between runs; the first run happens right after Start.
+	Interval time.Duration
+	// Run does one round of work. Errors are logged and the task keeps its
+	// schedule. ctx ends on Close.
+	Run func(ctx context.Context) error
+}
+
+// BackgroundRunner is implemented by clients built with NewClient:
+//
+//	if r, ok := client.(http.BackgroundRunner); ok { err = r.Start(ctx) }
+type BackgroundRunner interface {
+	// Start launches the background tasks and returns; it fails if they
+	// already run. Tasks stop when ctx ends or on Close.
+	Start(ctx context.Context) error
+	// Close stops the tasks and waits for running rounds to return.
+	Close() error
+}
+
+var _ BackgroundRunner = httpClient{}
+
+// WithBackgroundTask registers tasks run between Start and Close. Tasks with
+// no Run or a non-positive Interval are ignored.
+func WithBackgroundTask(tasks ...BackgroundTask) Option {
+	return func(h *httpClient) {
+		for _, task := range tasks {
+			if task.Run != nil && task.Interval > 0 {
+				h.tasks = append(h.tasks, task)
+			}
+		}
+	}
+}
+
+// CachePruner is implemented by cache stores that can drop expired entries
+// in bulk, DiskCache among them.
+type CachePruner interface {
+	Prune(now time.Time) error
+}
+
+// CacheJanitor is a task that prunes store every interval, so a long-running
+// process does not keep stale responses around until they are next read.
+func CacheJanitor(store CachePruner, interval time.Duration) BackgroundTask {
+	return BackgroundTask{
+		Name:     "cache-janitor",
+		Interval: interval,
+		Run: func(context.Context) error {
+			return store.Prune(time.Now())
+		},
+	}
+}
+
+// HealthProbe is a task that runs HealthCheck against path every interval
+// and calls report with the outcome (nil when healthy).
+func HealthProbe(c Client, path string, interval time.Duration, report func(err error)) BackgroundTask {
+	return BackgroundTask{
+		Name:     "health-probe",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			err := HealthCheck(ctx, c, path)
+			if report != nil {
+				report(err)
+			}
+			return err
+		},
+	}
+}
+
+// daemon is the running state of the background tasks, shared by every copy
+// of the client value.
+type daemon struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start launches the registered background tasks.
+func (h httpClient) Start(ctx context.Context) error {
+	h.daemon.mu.Lock()
+	defer h.daemon.mu.Unlock()
+	if h.daemon.cancel != nil {
+		return errDaemonStarted
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	h.daemon.cancel, h.daemon.done = cancel, done
+
+	var wg sync.WaitGroup
+	for _, task := range h.tasks {
+		wg.Add(1)
+		go func(task BackgroundTask) {
+			defer wg.Done()
+			h.runTask(ctx, task)
+		}(task)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return nil
+}
+
+// Close stops the background tasks and waits for them. It is a no-op when
+// they are not running.
+func (h httpClient) Close() error {
+	h.daemon.mu.Lock()
+	cancel, done := h.daemon.cancel, h.daemon.done
+	h.daemon.cancel, h.daemon.done = nil, nil
+	h.daemon.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+	return nil
+}
+
+func (h httpClient) runTask(ctx context.Context, task BackgroundTask) {
+	ticker := time.NewTicker(task.Interval)
+	defer ticker.Stop()
+	for {
+		if err := task.Run(ctx); err != nil && ctx.Err() == nil {
+			h.logger.Warn("http-client", "type", "background-task", "task", task.Name, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Service adapts a client to the {Name, Start, Stop} lifecycle the server
+// package's Server implements, so its background tasks start and stop with
+// the rest of the application.
+type Service struct {
+	client Client
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewService wraps c. Clients that are not a BackgroundRunner simply idle
+// between Start and Stop.
+func NewService(c Client) *Service {
+	return &Service{client: c, stop: make(chan struct{})}
+}
+
+// Name identifies the service.
+func (s *Service) Name() string { return "http-client" }
+
+// Start runs the client's background tasks and blocks until Stop.
+func (s *Service) Start() error {
+	select {
+	case <-s.stop:
+		return nil
+	default:
+	}
+	if r, ok := s.client.(BackgroundRunner); ok {
+		if err := r.Start(context.Background()); err != nil {
+			return err
+		}
+	}
+	<-s.stop
+	return nil
+}
+
+// Stop stops the background tasks, giving up when ctx ends first.
+func (s *Service) Stop(ctx context.Context) error {
+	s.once.Do(func() { close(s.stop) })
+	r, ok := s.client.(BackgroundRunner)
+	if !ok {
+		return nil
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- r.Close() }()
+	select {
+	case err := <-closed:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
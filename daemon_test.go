@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Client_BackgroundTasks(t *testing.T) {
+	var runs atomic.Int64
+	started := make(chan struct{}, 16)
+	c := NewClient(Config{}, WithBackgroundTask(
+		BackgroundTask{Name: "tick", Interval: 5 * time.Millisecond, Run: func(ctx context.Context) error {
+			runs.Add(1)
+			started <- struct{}{}
+			return nil
+		}},
+		BackgroundTask{Name: "ignored"},
+	))
+	r := c.(BackgroundRunner)
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(context.Background()); !errors.Is(err, errDaemonStarted) {
+		t.Fatalf("second Start: %v", err)
+	}
+	<-started
+	<-started
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	after := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if runs.Load() != after {
+		t.Fatal("task ran after Close")
+	}
+	// restartable and Close is idempotent
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+	_ = r.Close()
+}
+
+func Test_HealthProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	got := make(chan error, 1)
+	task := HealthProbe(newTestClient(t, srv.URL), "/healthz", time.Hour, func(err error) { got <- err })
+	_ = task.Run(context.Background())
+	if err := <-got; err == nil {
+		t.Fatal("unhealthy upstream reported healthy")
+	}
+}
+
+func Test_CacheJanitor_PrunesDiskCache(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	_ = cache.Set("fresh", CacheEntry{StatusCode: 200, ExpiresAt: now.Add(time.Hour)})
+	_ = cache.Set("stale", CacheEntry{StatusCode: 200, ExpiresAt: now.Add(-time.Minute)})
+
+	if err := CacheJanitor(cache, time.Minute).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get("stale"); ok {
+		t.Fatal("stale entry survived")
+	}
+	if _, ok := cache.Get("fresh"); !ok {
+		t.Fatal("fresh entry pruned")
+	}
+}
+
+func Test_Service_Lifecycle(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	c := NewClient(Config{}, WithBackgroundTask(BackgroundTask{Name: "t", Interval: time.Hour, Run: func(context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	}}))
+	svc := NewService(c)
+	if svc.Name() != "http-client" {
+		t.Fatalf("name = %q", svc.Name())
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- svc.Start() }()
+	<-ran
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := svc.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Start returned %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package http
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decoder is one way of reading a response body into a value, tried in turn
+// by a FallbackCodec.
+type Decoder struct {
+	// Name identifies the decoder to FallbackCodec.OnDecode and in errors.
+	Name   string
+	Decode func(data []byte, v any) error
+}
+
+// StrictJSONDecoder decodes the body as JSONCodec does.
+var StrictJSONDecoder = Decoder{Name: "json", Decode: json.Unmarshal}
+
+// LenientJSONDecoder decodes JSON whose values do not match the target's
+// types, coercing them where the intent is clear: numbers sent as strings
+// ("42") and strings sent as numbers, booleans as "true" or 0/1, integers
+// as 42.0, an empty string as a zero number, and a single value where a
+// list is expected. Field names match as with encoding/json.
+var LenientJSONDecoder = Decoder{Name: "lenient-json", Decode: lenientJSON}
+
+// TextDecoder stores the raw body into a *string, *[]byte, *any (as a
+// string), or a value implementing encoding.TextUnmarshaler.
+var TextDecoder = Decoder{Name: "text", Decode: decodeText}
+
+// FallbackCodec is a Codec for sloppy upstreams: Unmarshal tries each of
+// Decoders in order and keeps the first result that decodes without error.
+// It marshals request bodies as JSON.
+//
+//	codec := http.FallbackCodec{OnDecode: func(name string) { metrics.Inc("decoder", name) }}
+//	http.NewRequest(client).Path("/legacy").Codec(codec).Do(ctx, &out)
+type FallbackCodec struct {
+	// Decoders defaults to StrictJSONDecoder, LenientJSONDecoder, and
+	// TextDecoder.
+	Decoders []Decoder
+	// OnDecode, when set, is told the name of the decoder that succeeded.
+	OnDecode func(decoder string)
+}
+
+var _ Codec = FallbackCodec{}
+
+// ContentType implements Codec.
+func (FallbackCodec) ContentType() string { return ContentTypeJSON }
+
+// Marshal implements Codec.
+func (FallbackCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements Codec.
+func (c FallbackCodec) Unmarshal(data []byte, v any) error {
+	_, err := c.Decode(data, v)
+	return err
+}
+
+// Decode is Unmarshal that also returns the name of the decoder that
+// succeeded. v is only written by a successful decoder. When all fail the
+// error lists each failure and wraps the first.
+func (c FallbackCodec) Decode(data []byte, v any) (string, error) {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return "", fmt.Errorf("decode target must be a non-nil pointer, got %T", v)
+	}
+	decoders := c.Decoders
+	if len(decoders) == 0 {
+		decoders = []Decoder{StrictJSONDecoder, LenientJSONDecoder, TextDecoder}
+	}
+
+	var first error
+	var failures []string
+	for _, d := range decoders {
+		// decode into a fresh value, as a failed decoder may have written
+		// part of it
+		fresh := reflect.New(target.Elem().Type())
+		err := d.Decode(data, fresh.Interface())
+		if err == nil {
+			target.Elem().Set(fresh.Elem())
+			if c.OnDecode != nil {
+				c.OnDecode(d.Name)
+			}
+			return d.Name, nil
+		}
+		if first == nil {
+			first = err
+		}
+		failures = append(failures, d.Name+": "+err.Error())
+	}
+	if first == nil {
+		return "", fmt.Errorf("no decoders configured")
+	}
+	return "", fmt.Errorf("no decoder accepted the body (%s): %w", strings.Join(failures, "; "), first)
+}
+
+func lenientJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	coerced, err := json.Marshal(coerce(tree, reflect.TypeOf(v)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(coerced, v)
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// coerce reshapes a generic JSON value, decoded with UseNumber, towards what
+// json.Unmarshal expects for t. Values it cannot make sense of are left for
+// json.Unmarshal to reject.
+func coerce(v any, t reflect.Type) any {
+	if v == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// types decoding themselves get the value as sent
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return v
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := coerceNumber(v); ok {
+			if _, err := n.Int64(); err == nil {
+				return n
+			}
+			if f, err := n.Float64(); err == nil && f == float64(int64(f)) {
+				return json.Number(strconv.FormatInt(int64(f), 10))
+			}
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := coerceNumber(v); ok {
+			return n
+		}
+	case reflect.String:
+		if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+			return v
+		}
+		switch v := v.(type) {
+		case json.Number:
+			return v.String()
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	case reflect.Bool:
+		switch v := v.(type) {
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b
+			}
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f != 0
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// []byte is base64 text in JSON
+			return v
+		}
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = coerce(item, t.Elem())
+		}
+		return out
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		out := make(map[string]any, len(m))
+		for k, item := range m {
+			out[k] = coerce(item, t.Elem())
+		}
+		return out
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		fields := jsonFields(t)
+		out := make(map[string]any, len(m))
+		for k, item := range m {
+			if ft, ok := fields[strings.ToLower(k)]; ok {
+				item = coerce(item, ft)
+			}
+			out[k] = item
+		}
+		return out
+	}
+	return v
+}
+
+// coerceNumber reads a number sent as a number, a numeric string, or a
+// boolean. An empty string reads as 0.
+func coerceNumber(v any) (json.Number, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		return v, true
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return "0", true
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(s), true
+		}
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	}
+	return "", false
+}
+
+// jsonFields maps the lowercased JSON names of t's fields, including those
+// of embedded structs, to their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k, v := range jsonFields(ft) {
+				if _, ok := fields[k]; !ok {
+					fields[k] = v
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
+
+func decodeText(data []byte, v any) error {
+	switch v := v.(type) {
+	case *string:
+		*v = string(data)
+	case *[]byte:
+		*v = append([]byte(nil), data...)
+	case *any:
+		*v = string(data)
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(data)
+	default:
+		return fmt.Errorf("cannot store text in %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type sloppyItem struct {
+	ID     int      `json:"id"`
+	Price  float64  `json:"price"`
+	Active bool     `json:"active"`
+	Code   string   `json:"code"`
+	Tags   []string `json:"tags"`
+}
+
+type sloppyPage struct {
+	sloppyItem
+	Items []sloppyItem     `json:"items"`
+	Count map[string]int64 `json:"count"`
+}
+
+func Test_FallbackCodec_Strict(t *testing.T) {
+	var used []string
+	codec := FallbackCodec{OnDecode: func(name string) { used = append(used, name) }}
+	var out sloppyItem
+	name, err := codec.Decode([]byte(`{"id":1,"price":2.5,"active":true,"code":"x","tags":["a"]}`), &out)
+	if err != nil || name != "json" {
+		t.Fatalf("decoder = %q, err = %v", name, err)
+	}
+	want := sloppyItem{ID: 1, Price: 2.5, Active: true, Code: "x", Tags: []string{"a"}}
+	if !reflect.DeepEqual(out, want) || !reflect.DeepEqual(used, []string{"json"}) {
+		t.Fatalf("out = %+v, used = %v", out, used)
+	}
+}
+
+func Test_FallbackCodec_Lenient(t *testing.T) {
+	body := `{"id":"7","price":"","active":"1","code":42,"tags":"solo",
+		"items":[{"id":3.0,"active":0,"price":"1.25"}],"count":{"a":"10"}}`
+	var out sloppyPage
+	name, err := FallbackCodec{}.Decode([]byte(body), &out)
+	if err != nil || name != "lenient-json" {
+		t.Fatalf("decoder = %q, err = %v", name, err)
+	}
+	want := sloppyPage{
+		sloppyItem: sloppyItem{ID: 7, Active: true, Code: "42", Tags: []string{"solo"}},
+		Items:      []sloppyItem{{ID: 3, Price: 1.25}},
+		Count:      map[string]int64{"a": 10},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("out = %+v, want %+v", out, want)
+	}
+}
+
+func Test_FallbackCodec_LenientKeepsLargeIntegers(t *testing.T) {
+	var out struct {
+		ID int64 `json:"id"`
+	}
+	if err := LenientJSONDecoder.Decode([]byte(`{"id":"9007199254740993"}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != 9007199254740993 {
+		t.Fatalf("id = %d", out.ID)
+	}
+}
+
+func Test_FallbackCodec_Text(t *testing.T) {
+	var s string
+	name, err := FallbackCodec{}.Decode([]byte("OK"), &s)
+	if err != nil || name != "text" || s != "OK" {
+		t.Fatalf("decoder = %q, s = %q, err = %v", name, s, err)
+	}
+}
+
+func Test_FallbackCodec_AllFail(t *testing.T) {
+	out := sloppyItem{ID: 9}
+	err := FallbackCodec{}.Unmarshal([]byte("<html>"), &out)
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		t.Fatalf("err = %v, want the strict JSON error wrapped", err)
+	}
+	if out.ID != 9 {
+		t.Fatalf("failed decode wrote %+v", out)
+	}
+}
+
+func Test_FallbackCodec_CustomOrder(t *testing.T) {
+	codec := FallbackCodec{Decoders: []Decoder{TextDecoder, StrictJSONDecoder}}
+	var v any
+	name, err := codec.Decode([]byte(`{"a":1}`), &v)
+	if err != nil || name != "text" || v != `{"a":1}` {
+		t.Fatalf("decoder = %q, v = %v, err = %v", name, v, err)
+	}
+}
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCache is a CacheStore that keeps one JSON file per entry under a
+// directory, so CLI tools get a warm cache across invocations. Files are laid
+// out as <dir>/<aa>/<sha256 of key>.json. When the files outgrow MaxBytes the
+// least recently used entries are evicted.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	files map[string]*diskCacheFile
+	size  int64
+}
+
+type diskCacheFile struct {
+	path string
+	size int64
+	used time.Time
+}
+
+type diskCacheRecord struct {
+	Key   string     `json:"key"`
+	Entry CacheEntry `json:"entry"`
+}
+
+var _ CacheStore = (*DiskCache)(nil)
+
+// NewDiskCache opens (creating if needed) a cache rooted at dir, indexing the
+// entries a previous process left behind. maxBytes caps the total file size;
+// 0 means no cap. os.UserCacheDir is a good place for dir.
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	c := &DiskCache{dir: dir, maxBytes: maxBytes, files: make(map[string]*diskCacheFile)}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		c.files[name] = &diskCacheFile{path: path, size: info.Size(), used: info.ModTime()}
+		c.size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index cache dir: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+	return c, nil
+}
+
+// Size returns the total bytes the cache files occupy.
+func (c *DiskCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *DiskCache) Get(key string) (CacheEntry, bool) {
+	name := diskCacheName(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.files[name]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		c.remove(name)
+		return CacheEntry{}, false
+	}
+	var record diskCacheRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Key != key {
+		c.remove(name)
+		return CacheEntry{}, false
+	}
+	// the file mtime doubles as the last-used time, so LRU order survives a
+	// restart
+	f.used = time.Now()
+	_ = os.Chtimes(f.path, f.used, f.used)
+	return record.Entry, true
+}
+
+func (c *DiskCache) Set(key string, entry CacheEntry) error {
+	data, err := json.Marshal(diskCacheRecord{Key: key, Entry: entry})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	name := diskCacheName(key)
+	path := filepath.Join(c.dir, name[:2], name+".json")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	// write then rename, so a crash never leaves a torn entry behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if old, ok := c.files[name]; ok {
+		c.size -= old.size
+	}
+	c.files[name] = &diskCacheFile{path: path, size: int64(len(data)), used: time.Now()}
+	c.size += int64(len(data))
+	c.evict()
+	return nil
+}
+
+func (c *DiskCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remove(diskCacheName(key))
+}
+
+// Purge removes every entry and the files behind them.
+func (c *DiskCache) Purge() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to purge cache: %w", err)
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(c.dir, e.Name())); err != nil {
+			return fmt.Errorf("failed to purge cache: %w", err)
+		}
+	}
+	c.files = make(map[string]*diskCacheFile)
+	c.size = 0
+	return nil
+}
+
+// Prune removes the entries that are no longer fresh at now.
+func (c *DiskCache) Prune(now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, f := range c.files {
+		data, err := os.ReadFile(f.path)
+		var record diskCacheRecord
+		if err == nil {
+			err = json.Unmarshal(data, &record)
+		}
+		if err != nil || !record.Entry.Fresh(now) {
+			if err := c.remove(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// remove deletes one entry; c.mu must be held.
+func (c *DiskCache) remove(name string) error {
+	f, ok := c.files[name]
+	if !ok {
+		return nil
+	}
+	delete(c.files, name)
+	c.size -= f.size
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
+
+// evict drops least recently used entries until the cache fits maxBytes;
+// c.mu must be held.
+func (c *DiskCache) evict() {
+	if c.maxBytes <= 0 || c.size <= c.maxBytes {
+		return
+	}
+	names := make([]string, 0, len(c.files))
+	for name := range c.files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return c.files[names[i]].used.Before(c.files[names[j]].used)
+	})
+	for _, name := range names {
+		if c.size <= c.maxBytes {
+			return
+		}
+		_ = c.remove(name)
+	}
+}
+
+func diskCacheName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
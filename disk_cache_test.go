@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DiskCache_PersistsAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	entry := CacheEntry{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"text/plain"}},
+		Body:       []byte("hello"),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+
+	first, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	if err := first.Set("GET https://api.test/a", entry); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	second, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	got, ok := second.Get("GET https://api.test/a")
+	if !ok || string(got.Body) != "hello" || got.Headers.Get("Content-Type") != "text/plain" {
+		t.Fatalf("Get = %+v, %v", got, ok)
+	}
+	if second.Size() != first.Size() {
+		t.Fatalf("Size = %d, want %d", second.Size(), first.Size())
+	}
+
+	if err := second.Delete("GET https://api.test/a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := second.Get("GET https://api.test/a"); ok {
+		t.Fatal("deleted entry still served")
+	}
+}
+
+func Test_DiskCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	body := []byte(strings.Repeat("x", 100))
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Set(key, CacheEntry{Body: body}); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+
+	// room for two entries: b is now the least recently used
+	c.maxBytes = c.Size() * 2 / 3
+	if err := c.Set("c", CacheEntry{Body: body}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%s should remain", key)
+		}
+	}
+}
+
+func Test_DiskCache_Purge(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	_ = c.Set("a", CacheEntry{Body: []byte("1")})
+	_ = c.Set("b", CacheEntry{Body: []byte("2")})
+
+	if err := c.Purge(); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if c.Size() != 0 {
+		t.Fatalf("Size = %d after purge", c.Size())
+	}
+	reopened, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	if _, ok := reopened.Get("a"); ok {
+		t.Fatal("purged entry survived a reopen")
+	}
+}
@@ -0,0 +1,282 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DownloadRequest is a GET whose body is copied to a writer instead of
+// memory, so multi-GB artifacts download in constant space.
+type DownloadRequest struct {
+	Request
+
+	// Offset resumes a transfer: the first Offset bytes are taken as already
+	// written, and only the rest is requested with a Range header.
+	Offset int64
+	// IfRange is the validator (ETag or Last-Modified) of the version the
+	// first Offset bytes came from, as a DownloadInterruptedError reports
+	// it. It is sent as If-Range so a changed file is not appended to.
+	IfRange string
+	// MaxResumes is how many times a transfer broken mid-body is resumed from
+	// where it stopped. 0 means never.
+	MaxResumes int
+	// OnProgress, when set, is called as the body is written, with the bytes
+	// written so far (Offset included) and the total, or -1 when unknown.
+	OnProgress ProgressFunc
+}
+
+// ErrDownloadChanged is returned by Download when a resumed request finds
+// the file changed on the server and w cannot be rewound to start over.
+var ErrDownloadChanged = errors.New("download: file changed since the bytes already written")
+
+// DownloadInterruptedError is returned by Download for a transfer that broke
+// off and could not be resumed. Store Written and Validator to resume later
+// with DownloadRequest.Offset and IfRange.
+type DownloadInterruptedError struct {
+	Written   int64
+	Validator string
+	Err       error
+}
+
+func (e *DownloadInterruptedError) Error() string {
+	return fmt.Sprintf("download interrupted after %d bytes: %v", e.Written, e.Err)
+}
+
+func (e *DownloadInterruptedError) Unwrap() error { return e.Err }
+
+// Download streams the response body of req to w. Resumed requests carry
+// If-Range with the version's validator, so a file that changed on the
+// server is never stitched together from two versions: its full body is
+// written from the start when w can be truncated (an *os.File), and
+// ErrDownloadChanged is returned otherwise. A server that ignores Range has
+// the already-written prefix skipped, but only when no validator is known or
+// the response carries the same one. The returned Response has the status
+// and headers of the last exchange and no body. A non-2xx status is an
+// *HTTPError.
+func Download(ctx context.Context, c Client, req DownloadRequest, w io.Writer) (*Response, error) {
+	var (
+		written   = req.Offset
+		total     = int64(-1)
+		validator = req.IfRange
+		resumes   int
+	)
+	for {
+		resp, err := downloadFrom(ctx, c, req.Request, written, validator)
+		if err != nil {
+			return nil, err
+		}
+
+		// a range starting at the end of the file: nothing left to fetch
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && written > 0 {
+			_ = resp.Close()
+			if size, ok := contentRangeSize(resp.Headers.Get("Content-Range")); ok && size == written {
+				resp.StatusCode, resp.Reader = http.StatusOK, nil
+				return resp, nil
+			}
+			return nil, &HTTPError{StatusCode: resp.StatusCode, Headers: resp.Headers}
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Reader, errorBodyLimit))
+			_ = resp.Close()
+			return nil, &HTTPError{StatusCode: resp.StatusCode, Body: body, Headers: resp.Headers}
+		}
+
+		body := io.Reader(resp.Reader)
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+			start, size, ok := parseContentRange(resp.Headers.Get("Content-Range"))
+			if !ok || start != written {
+				_ = resp.Close()
+				return nil, fmt.Errorf("download: unexpected Content-Range %q for offset %d", resp.Headers.Get("Content-Range"), written)
+			}
+			total = size
+		default:
+			if written > 0 && validator != "" && responseValidator(resp.Headers) != validator {
+				// If-Range failed: this is a new version of the file
+				if err := rewind(w); err != nil {
+					_ = resp.Close()
+					return nil, err
+				}
+				written, validator = 0, ""
+			}
+			// the server sent the whole body: skip what is already written
+			if written > 0 {
+				if _, err := io.CopyN(io.Discard, body, written); err != nil {
+					_ = resp.Close()
+					return nil, fmt.Errorf("download: failed to skip written prefix: %w", err)
+				}
+			}
+			if size, err := strconv.ParseInt(resp.Headers.Get("Content-Length"), 10, 64); err == nil {
+				total = size
+			}
+		}
+		if validator == "" {
+			validator = responseValidator(resp.Headers)
+		}
+
+		n, err := io.Copy(&downloadWriter{w: w}, &downloadProgress{r: body, written: written, total: total, fn: req.OnProgress})
+		written += n
+		_ = resp.Close()
+		if err == nil {
+			resp.Reader = nil
+			return resp, nil
+		}
+
+		var werr *downloadWriteError
+		if errors.As(err, &werr) || ctx.Err() != nil || resumes >= req.MaxResumes || validator == "" {
+			return nil, &DownloadInterruptedError{Written: written, Validator: validator, Err: err}
+		}
+		resumes++
+	}
+}
+
+// DownloadFile downloads req into path. It resumes from the bytes a previous
+// attempt left in the file only when req.IfRange holds their version's
+// validator, and otherwise starts over.
+func DownloadFile(ctx context.Context, c Client, req DownloadRequest, path string) (*Response, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open download file: %w", err)
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open download file: %w", err)
+	}
+	if offset > 0 && req.IfRange == "" {
+		// no validator: the bytes may be of another version
+		if err := rewind(f); err != nil {
+			return nil, err
+		}
+		offset = 0
+	}
+	req.Offset = offset
+
+	resp, err := Download(ctx, c, req, f)
+	if err != nil {
+		return nil, err
+	}
+	return resp, f.Close()
+}
+
+// responseValidator is the validator of the version h describes: a strong
+// ETag, or Last-Modified.
+func responseValidator(h http.Header) string {
+	validator := h.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = h.Get("Last-Modified")
+	}
+	return validator
+}
+
+// rewind empties w so a download can start over, failing with
+// ErrDownloadChanged when w is not a truncatable file.
+func rewind(w io.Writer) error {
+	f, ok := w.(interface {
+		io.Seeker
+		Truncate(size int64) error
+	})
+	if !ok {
+		return ErrDownloadChanged
+	}
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate download file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to truncate download file: %w", err)
+	}
+	return nil
+}
+
+func downloadFrom(ctx context.Context, c Client, req Request, offset int64, validator string) (*Response, error) {
+	req.Stream = true
+	if offset > 0 {
+		headers := make(map[string]string, len(req.Headers)+2)
+		for k, v := range req.Headers {
+			headers[k] = v
+		}
+		headers["Range"] = "bytes=" + strconv.FormatInt(offset, 10) + "-"
+		if validator != "" {
+			headers["If-Range"] = validator
+		}
+		req.Headers = headers
+	}
+	return c.Get(ctx, GetRequest{Request: req})
+}
+
+// parseContentRange parses "bytes <start>-<end>/<size>"; size is -1 for "*".
+func parseContentRange(v string) (start, size int64, ok bool) {
+	if !strings.HasPrefix(v, "bytes ") {
+		return 0, 0, false
+	}
+	span, sizeStr, found := strings.Cut(v[len("bytes "):], "/")
+	if !found {
+		return 0, 0, false
+	}
+	startStr, _, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	size = -1
+	if sizeStr != "*" {
+		if size, err = strconv.ParseInt(sizeStr, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
+
+// contentRangeSize parses the "bytes */<size>" of a 416 response.
+func contentRangeSize(v string) (int64, bool) {
+	if !strings.HasPrefix(v, "bytes */") {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(v[len("bytes */"):], 10, 64)
+	return size, err == nil
+}
+
+// downloadProgress reports bytes as they are read from the body.
+type downloadProgress struct {
+	r       io.Reader
+	written int64
+	total   int64
+	fn      ProgressFunc
+}
+
+func (p *downloadProgress) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 && p.fn != nil {
+		p.written += int64(n)
+		p.fn(p.written, p.total)
+	}
+	return n, err
+}
+
+// downloadWriter marks destination failures, which resuming cannot fix.
+type downloadWriter struct {
+	w io.Writer
+}
+
+type downloadWriteError struct{ err error }
+
+func (e *downloadWriteError) Error() string { return e.err.Error() }
+func (e *downloadWriteError) Unwrap() error { return e.err }
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	if err != nil {
+		return n, &downloadWriteError{err: err}
+	}
+	return n, nil
+}
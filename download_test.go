@@ -0,0 +1,192 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var downloadPayload = []byte(strings.Repeat("0123456789", 1000))
+
+// rangeServer serves downloadPayload with Range support. When cutAt is
+// positive, the first full response is cut off after cutAt bytes.
+func rangeServer(t *testing.T, cutAt int, ranges *[]string) *httptest.Server {
+	t.Helper()
+	cut := cutAt > 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range")+"|"+r.Header.Get("If-Range"))
+		if cut {
+			cut = false
+			w.Header().Set("Content-Length", strconv.Itoa(len(downloadPayload)))
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write(downloadPayload[:cutAt])
+			// hijack and drop the connection mid-body
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "payload.bin", time.Unix(0, 0), bytes.NewReader(downloadPayload))
+	}))
+}
+
+func Test_Download_ResumesBrokenTransfer(t *testing.T) {
+	var ranges []string
+	srv := rangeServer(t, 4000, &ranges)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	var lastProgress, lastTotal int64
+	resp, err := Download(context.Background(), newTestClient(t, srv.URL), DownloadRequest{
+		Request:    Request{Path: "/file"},
+		MaxResumes: 2,
+		OnProgress: func(sent, total int64) { lastProgress, lastTotal = sent, total },
+	}, &buf)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), downloadPayload) {
+		t.Fatalf("got %d bytes, want %d", buf.Len(), len(downloadPayload))
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if len(ranges) != 2 || ranges[1] != `bytes=4000-|"v1"` {
+		t.Fatalf("ranges = %q", ranges)
+	}
+	if lastProgress != int64(len(downloadPayload)) || lastTotal != int64(len(downloadPayload)) {
+		t.Fatalf("progress = %d/%d", lastProgress, lastTotal)
+	}
+}
+
+func Test_Download_NoResumeByDefault(t *testing.T) {
+	var ranges []string
+	srv := rangeServer(t, 4000, &ranges)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	_, err := Download(context.Background(), newTestClient(t, srv.URL), DownloadRequest{Request: Request{Path: "/"}}, &buf)
+	if err == nil || len(ranges) != 1 {
+		t.Fatalf("err = %v, requests = %d", err, len(ranges))
+	}
+}
+
+func Test_Download_ServerIgnoresRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(downloadPayload)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	if _, err := Download(context.Background(), newTestClient(t, srv.URL), DownloadRequest{Request: Request{Path: "/"}, Offset: 10}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), downloadPayload[10:]) {
+		t.Fatalf("got %d bytes, want the %d after the offset", buf.Len(), len(downloadPayload)-10)
+	}
+}
+
+func Test_DownloadFile_ResumesAndCompletes(t *testing.T) {
+	var ranges []string
+	srv := rangeServer(t, 0, &ranges)
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	path := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(path, downloadPayload[:1234], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DownloadFile(context.Background(), c, DownloadRequest{Request: Request{Path: "/"}, IfRange: `"v1"`}, path); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || ranges[0] != `bytes=1234-|"v1"` {
+		t.Fatalf("ranges = %q", ranges)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, downloadPayload) {
+		t.Fatalf("file has %d bytes, want %d", len(got), len(downloadPayload))
+	}
+
+	// already complete: the server answers 416 and nothing changes
+	resp, err := DownloadFile(context.Background(), c, DownloadRequest{Request: Request{Path: "/"}, IfRange: `"v1"`}, path)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("complete file: resp=%v err=%v", resp, err)
+	}
+}
+
+// changingServer serves "AAAAAAAAAA" as v1, cut off after 5 bytes, then
+// "BBBBBBBBBB" as v2, honouring Range only through If-Range.
+func changingServer() *httptest.Server {
+	first := true
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if first {
+			first = false
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", "10")
+			_, _ = w.Write([]byte("AAAAA"))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "file.bin", time.Unix(0, 0), strings.NewReader("BBBBBBBBBB"))
+	}))
+}
+
+func Test_Download_ChangedFile(t *testing.T) {
+	srv := changingServer()
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	_, err := Download(context.Background(), newTestClient(t, srv.URL), DownloadRequest{Request: Request{Path: "/"}, MaxResumes: 1}, &buf)
+	if !errors.Is(err, ErrDownloadChanged) {
+		t.Fatalf("err = %v, buf = %q", err, buf.String())
+	}
+}
+
+func Test_DownloadFile_ChangedFileStartsOver(t *testing.T) {
+	srv := changingServer()
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if _, err := DownloadFile(context.Background(), newTestClient(t, srv.URL), DownloadRequest{Request: Request{Path: "/"}, MaxResumes: 1}, path); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "BBBBBBBBBB" {
+		t.Fatalf("file = %q", got)
+	}
+}
+
+func Test_Download_InterruptedReportsValidator(t *testing.T) {
+	var ranges []string
+	srv := rangeServer(t, 4000, &ranges)
+	defer srv.Close()
+
+	_, err := Download(context.Background(), newTestClient(t, srv.URL), DownloadRequest{Request: Request{Path: "/"}}, &bytes.Buffer{})
+	var ierr *DownloadInterruptedError
+	if !errors.As(err, &ierr) || ierr.Written != 4000 || ierr.Validator != `"v1"` {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func Test_Download_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := Download(context.Background(), newTestClient(t, srv.URL), DownloadRequest{Request: Request{Path: "/"}}, &bytes.Buffer{})
+	var herr *HTTPError
+	if !errors.As(err, &herr) || herr.StatusCode != http.StatusNotFound {
+		t.Fatalf("err = %v", err)
+	}
+}
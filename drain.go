@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClientDraining is returned for calls made after Drain began.
+var ErrClientDraining = errors.New("client is draining")
+
+// Drainer is implemented by clients built with NewClient, so outbound
+// traffic can take part in graceful shutdown:
+//
+//	if d, ok := client.(http.Drainer); ok { err = d.Drain(shutdownCtx) }
+type Drainer interface {
+	// Drain stops accepting new calls, which fail with ErrClientDraining,
+	// and waits for in-flight calls - buffered requests, unread streamed
+	// bodies, event streams, and open WebSockets - to finish. When ctx ends
+	// first, the remaining calls are cancelled and ctx's error is returned.
+	// Background tasks are left to Close.
+	Drain(ctx context.Context) error
+}
+
+var _ Drainer = httpClient{}
+
+// Drain implements Drainer.
+func (h httpClient) Drain(ctx context.Context) error {
+	return h.inflight.drain(ctx)
+}
+
+// inflight tracks the calls a client has not finished yet.
+type inflight struct {
+	mu       sync.Mutex
+	draining bool
+	next     uint64
+	calls    map[uint64]func()
+	// idle is closed once draining with no calls left.
+	idle chan struct{}
+}
+
+func newInflight() *inflight {
+	return &inflight{calls: make(map[uint64]func())}
+}
+
+// track registers a call that stop aborts. release must be called once the
+// call is finished; it is safe to call more than once.
+func (f *inflight) track(stop func()) (release func(), err error) {
+	if f == nil {
+		return func() {}, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.draining {
+		return nil, ErrClientDraining
+	}
+	id := f.next
+	f.next++
+	f.calls[id] = stop
+	var once sync.Once
+	return func() { once.Do(func() { f.done(id) }) }, nil
+}
+
+// acquire tracks a call bound to ctx, returning the context to run it under.
+func (f *inflight) acquire(ctx context.Context) (context.Context, func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+	release, err := f.track(cancel)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return ctx, func() { release(); cancel() }, nil
+}
+
+func (f *inflight) done(id uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.calls, id)
+	if f.draining && len(f.calls) == 0 {
+		f.signalIdle()
+	}
+}
+
+// signalIdle closes idle once; f.mu must be held.
+func (f *inflight) signalIdle() {
+	select {
+	case <-f.idle:
+	default:
+		close(f.idle)
+	}
+}
+
+// count returns the number of unfinished calls.
+func (f *inflight) count() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.calls)
+}
+
+func (f *inflight) drain(ctx context.Context) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	if !f.draining {
+		f.draining = true
+		f.idle = make(chan struct{})
+		if len(f.calls) == 0 {
+			f.signalIdle()
+		}
+	}
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	f.mu.Lock()
+	stops := make([]func(), 0, len(f.calls))
+	for _, stop := range f.calls {
+		stops = append(stops, stop)
+	}
+	f.mu.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+	return ctx.Err()
+}
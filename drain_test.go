@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Client_Drain_WaitsForInFlight(t *testing.T) {
+	arrived, finish := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-finish
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	type result struct {
+		resp *Response
+		err  error
+	}
+	first := make(chan result, 1)
+	go func() {
+		resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+		first <- result{resp, err}
+	}()
+	<-arrived
+
+	drained := make(chan error, 1)
+	go func() { drained <- c.(Drainer).Drain(context.Background()) }()
+
+	// wait for Drain to take effect, then check new calls are refused
+	f := c.(httpClient).inflight
+	for draining := false; !draining; {
+		time.Sleep(time.Millisecond)
+		f.mu.Lock()
+		draining = f.draining
+		f.mu.Unlock()
+	}
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); !errors.Is(err, ErrClientDraining) {
+		t.Fatalf("new call err = %v", err)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v with a call in flight", err)
+	default:
+	}
+
+	close(finish)
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if res := <-first; res.err != nil || string(res.resp.Body) != "done" {
+		t.Fatalf("in-flight call: resp=%v err=%v", res.resp, res.err)
+	}
+}
+
+func Test_Client_Drain_CancelsAtDeadline(t *testing.T) {
+	slowArrived := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "data: hello\n\n")
+			w.(http.Flusher).Flush()
+		} else {
+			close(slowArrived)
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	stream := make(chan StreamResponse, 4)
+	if err := c.GetStream(context.Background(), stream, Request{Path: "/events"}); err != nil {
+		t.Fatal(err)
+	}
+	if frame := <-stream; frame.Type != StreamResponseTypeData {
+		t.Fatalf("frame = %+v", frame)
+	}
+	buffered := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/slow"}})
+		buffered <- err
+	}()
+	<-slowArrived
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.(Drainer).Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain = %v", err)
+	}
+	if err := <-buffered; !errors.Is(err, context.Canceled) {
+		t.Fatalf("buffered call err = %v", err)
+	}
+	for frame := range stream {
+		if frame.Type == StreamResponseTypeEOF && frame.Error == nil {
+			t.Fatalf("stream ended cleanly, want a cancellation")
+		}
+	}
+}
+
+func Test_Client_Drain_Idle(t *testing.T) {
+	c := newTestClient(t, "http://127.0.0.1:1")
+	if err := c.(Drainer).Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WebSocket(context.Background(), Request{Path: "/ws"}); !errors.Is(err, ErrClientDraining) {
+		t.Fatalf("WebSocket err = %v", err)
+	}
+	stream := make(chan StreamResponse, 1)
+	if err := c.GetStream(context.Background(), stream, Request{Path: "/"}); !errors.Is(err, ErrClientDraining) {
+		t.Fatalf("GetStream err = %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// ErrEgressDenied is wrapped by every error the egress policy returns, so
+// callers can tell a blocked call from a failed one with errors.Is.
+var ErrEgressDenied = errors.New("egress denied")
+
+// EgressPolicy restricts where the client may connect, guarding against SSRF
+// when paths or URLs come from user input. The zero value allows everything.
+type EgressPolicy struct {
+	// AllowedHosts, when non-empty, lists the only hosts the client may call,
+	// matched like ProxyRule.Host: exact, ".suffix", or "*".
+	AllowedHosts []string `json:"allowed_hosts"`
+	// AllowedSchemes defaults to http and https.
+	AllowedSchemes []string `json:"allowed_schemes"`
+	// DenyPrivateIPs refuses connections to loopback, private, link-local
+	// (cloud metadata), CGNAT, and unspecified addresses. It is checked on the
+	// dialed address, so DNS names resolving to private IPs are caught too.
+	// When a proxy is configured the check applies to the proxy's address.
+	DenyPrivateIPs bool `json:"deny_private_ips"`
+	// MaxRedirects caps redirects followed; 0 keeps net/http's limit of 10 and
+	// a negative value disables redirects. Every redirect target must also pass
+	// the host and scheme checks.
+	MaxRedirects int `json:"max_redirects"`
+}
+
+// WithEgressPolicy enforces policy on every request the client sends,
+// including redirects. DenyPrivateIPs needs an *http.Transport to hook its
+// dialer; with any other transport only literal IP hosts are checked.
+func WithEgressPolicy(policy EgressPolicy) Option {
+	return func(h *httpClient) {
+		h.egress = &policy
+	}
+}
+
+// check validates a URL against the scheme and host lists, and literal IP
+// hosts against DenyPrivateIPs.
+func (p *EgressPolicy) check(u *url.URL) error {
+	schemes := p.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	allowed := false
+	for _, s := range schemes {
+		if strings.EqualFold(s, u.Scheme) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrEgressDenied, u.Scheme)
+	}
+
+	host := u.Hostname()
+	if len(p.AllowedHosts) > 0 {
+		allowed = false
+		for _, pattern := range p.AllowedHosts {
+			if (ProxyRule{Host: pattern}).matches(host) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: host %q is not allowed", ErrEgressDenied, host)
+		}
+	}
+
+	if p.DenyPrivateIPs {
+		if addr, err := netip.ParseAddr(host); err == nil && isPrivateAddr(addr) {
+			return fmt.Errorf("%w: address %s is private", ErrEgressDenied, addr)
+		}
+	}
+	return nil
+}
+
+// apply returns a copy of base that enforces the policy on redirects and, for
+// DenyPrivateIPs, on every dialed connection.
+func (p *EgressPolicy) apply(base *http.Client) *http.Client {
+	client := *base
+
+	limit := p.MaxRedirects
+	if limit == 0 {
+		limit = 10
+	}
+	next := base.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > limit {
+			return fmt.Errorf("%w: stopped after %d redirects", ErrEgressDenied, limit)
+		}
+		if err := p.check(req.URL); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
+	}
+
+	if !p.DenyPrivateIPs {
+		return &client
+	}
+	rt := base.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return &client
+	}
+	clone := transport.Clone()
+	dial := clone.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	// checking the connected peer rather than a resolved name closes the
+	// DNS-rebinding gap between lookup and dial
+	clone.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			if addr, ok := netip.AddrFromSlice(tcp.IP); ok && isPrivateAddr(addr.Unmap()) {
+				_ = conn.Close()
+				return nil, fmt.Errorf("%w: address %s is private", ErrEgressDenied, addr.Unmap())
+			}
+		}
+		return conn, nil
+	}
+	client.Transport = clone
+	return &client
+}
+
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPrivateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified() || cgnat.Contains(addr)
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_EgressPolicy_Check(t *testing.T) {
+	p := &EgressPolicy{AllowedHosts: []string{"api.example.com", ".internal.example.com"}, DenyPrivateIPs: true}
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://api.example.com/v1", allowed: true},
+		{url: "https://billing.internal.example.com", allowed: true},
+		{url: "https://evil.example.org", allowed: false},
+		{url: "file:///etc/passwd", allowed: false},
+		{url: "gopher://api.example.com", allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			err := p.check(u)
+			if (err == nil) != tt.allowed {
+				t.Fatalf("check(%s) = %v, want allowed=%v", tt.url, err, tt.allowed)
+			}
+			if err != nil && !errors.Is(err, ErrEgressDenied) {
+				t.Fatalf("error %v does not wrap ErrEgressDenied", err)
+			}
+		})
+	}
+}
+
+func Test_IsPrivateAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":          true,
+		"10.1.2.3":           true,
+		"169.254.169.254":    true,
+		"100.64.0.1":         true,
+		"0.0.0.0":            true,
+		"::1":                true,
+		"fd00::1":            true,
+		"::ffff:192.168.0.1": true,
+		"93.184.216.34":      false,
+		"2606:4700::1111":    false,
+	} {
+		if got := isPrivateAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPrivateAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func Test_Client_EgressPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, "http://evil.example.org/", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+	// a DNS name, so only the dial-time check can see the loopback address
+	localhost := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	tests := []struct {
+		name    string
+		baseURL string
+		policy  EgressPolicy
+		path    string
+		denied  bool
+	}{
+		{name: "no policy restrictions", baseURL: srv.URL, path: "/"},
+		{name: "private literal ip", baseURL: srv.URL, policy: EgressPolicy{DenyPrivateIPs: true}, path: "/", denied: true},
+		{name: "name resolving to loopback", baseURL: localhost, policy: EgressPolicy{DenyPrivateIPs: true}, path: "/", denied: true},
+		{name: "redirect to disallowed host", baseURL: srv.URL, policy: EgressPolicy{AllowedHosts: []string{"127.0.0.1"}}, path: "/away", denied: true},
+		{name: "too many redirects", baseURL: srv.URL, policy: EgressPolicy{MaxRedirects: 2}, path: "/loop", denied: true},
+		{name: "redirects disabled", baseURL: srv.URL, policy: EgressPolicy{MaxRedirects: -1}, path: "/away", denied: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, tt.baseURL, WithHTTPClient(&http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}), WithEgressPolicy(tt.policy))
+			_, err := c.Get(context.Background(), GetRequest{Request: Request{Path: tt.path}})
+			if errors.Is(err, ErrEgressDenied) != tt.denied {
+				t.Fatalf("err = %v, want denied=%v", err, tt.denied)
+			}
+		})
+	}
+}
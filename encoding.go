@@ -0,0 +1,50 @@
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// decodedBody returns resp's body decompressed per its Content-Encoding
+// (gzip or deflate), for responses the transport left compressed: when the
+// caller set Accept-Encoding itself, or the server compresses unasked. Other
+// bodies are returned as is. Decoding starts on the first Read, so a stream
+// whose first event is slow to arrive does not block the caller.
+func decodedBody(resp *http.Response) io.ReadCloser {
+	if resp.Uncompressed {
+		return resp.Body
+	}
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "gzip", "x-gzip", "deflate":
+		return &decodingBody{body: resp.Body, encoding: encoding}
+	}
+	return resp.Body
+}
+
+type decodingBody struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (d *decodingBody) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		if d.encoding == "deflate" {
+			d.r, d.err = zlib.NewReader(d.body)
+		} else {
+			d.r, d.err = gzip.NewReader(d.body)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+func (d *decodingBody) Close() error {
+	return d.body.Close()
+}
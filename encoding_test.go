@@ -0,0 +1,63 @@
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// compressedSSE streams two events compressed with encoding, flushing after
+// each as a real provider does.
+func compressedSSE(encoding string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Encoding", encoding)
+		var zw interface {
+			io.Writer
+			Flush() error
+			Close() error
+		}
+		if encoding == "deflate" {
+			zw = zlib.NewWriter(w)
+		} else {
+			zw = gzip.NewWriter(w)
+		}
+		for _, ev := range []string{"data: one\n\n", "data: two\n\n"} {
+			_, _ = io.WriteString(zw, ev)
+			_ = zw.Flush()
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		_ = zw.Close()
+	}
+}
+
+func Test_Client_Stream_DecompressesSSE(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			srv := httptest.NewServer(compressedSSE(encoding))
+			defer srv.Close()
+
+			stream := make(chan StreamResponse, 4)
+			// an explicit Accept-Encoding stops the transport from decoding
+			req := Request{Path: "/", Headers: map[string]string{"Accept-Encoding": encoding}}
+			if err := newTestClient(t, srv.URL).GetStream(context.Background(), stream, req); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for f := range stream {
+				if f.Type == StreamResponseTypeData {
+					got = append(got, string(f.Body))
+				}
+			}
+			if len(got) != 2 || got[0] != "one" || got[1] != "two" {
+				t.Fatalf("events = %q", got)
+			}
+		})
+	}
+}
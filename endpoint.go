@@ -0,0 +1,272 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Codec encodes and decodes endpoint bodies.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the default Codec.
+type JSONCodec struct{}
+
+// ContentType implements Codec.
+func (JSONCodec) ContentType() string { return ContentTypeJSON }
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// Endpoint describes one API operation once, for both sides: Call performs it
+// through a Client, Handler serves it. Req is a struct whose fields tagged
+// `path:"name"` fill {name} placeholders in Path and whose fields tagged
+// `query:"name"` become query parameters; for POST, PUT, and PATCH the whole
+// Req is also the body, so tag path/query fields `json:"-"` to keep them out.
+//
+//	var GetUser = http.Endpoint[GetUserRequest, User]{Method: "GET", Path: "/users/{id}"}
+//
+//	user, err := GetUser.Call(ctx, client, GetUserRequest{ID: "42"})
+//	router.Handle(GetUser.Method, GetUser.Path, GetUser.Handler(getUser, server.Param))
+type Endpoint[Req, Resp any] struct {
+	Method string
+	Path   string
+	// Status is the success status Handler writes; defaults to 200.
+	Status int
+	// Expect lists the statuses Call accepts; by default any 2xx.
+	Expect []int
+	// Codec defaults to JSONCodec.
+	Codec Codec
+}
+
+func (e Endpoint[Req, Resp]) codec() Codec {
+	if e.Codec == nil {
+		return JSONCodec{}
+	}
+	return e.Codec
+}
+
+func (e Endpoint[Req, Resp]) hasBody() bool {
+	switch strings.ToUpper(e.Method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// Call performs the endpoint through c. A status outside Expect is returned
+// as *HTTPError.
+func (e Endpoint[Req, Resp]) Call(ctx context.Context, c Client, req Req) (Resp, error) {
+	var out Resp
+
+	path, query, err := e.encodeParams(req)
+	if err != nil {
+		return out, err
+	}
+	r := Request{Path: path, Query: query}
+
+	var body []byte
+	if e.hasBody() {
+		if body, err = e.codec().Marshal(req); err != nil {
+			return out, fmt.Errorf("failed to marshal %s %s request: %w", e.Method, e.Path, err)
+		}
+		r.Headers = map[string]string{"Content-Type": e.codec().ContentType()}
+	}
+
+	var resp *Response
+	switch strings.ToUpper(e.Method) {
+	case http.MethodGet:
+		resp, err = c.Get(ctx, GetRequest{Request: r})
+	case http.MethodDelete:
+		resp, err = c.Delete(ctx, r)
+	case http.MethodPost:
+		resp, err = c.Post(ctx, PostRequest{Request: r, Body: body})
+	case http.MethodPut:
+		resp, err = c.Put(ctx, PutRequest{Request: r, Body: body})
+	case http.MethodPatch:
+		resp, err = c.Patch(ctx, PatchRequest{Request: r, Body: body})
+	default:
+		return out, fmt.Errorf("unsupported endpoint method %q", e.Method)
+	}
+	if err != nil {
+		return out, err
+	}
+	if !expectedStatus(resp.StatusCode, e.Expect) {
+		return out, newHTTPError(resp)
+	}
+	if len(resp.Body) > 0 {
+		if err := e.codec().Unmarshal(resp.Body, &out); err != nil {
+			return out, fmt.Errorf("failed to unmarshal %s %s response: %w", e.Method, e.Path, err)
+		}
+	}
+	return out, nil
+}
+
+// Handler serves the endpoint with fn. param reads a path parameter from the
+// routed request (server.Param for this module's server). Malformed input is
+// answered 400; an error from fn is answered with its StatusCode() when it
+// has one, else 500, as {"error": "..."}.
+func (e Endpoint[Req, Resp]) Handler(fn func(ctx context.Context, req Req) (Resp, error), param func(r *http.Request, name string) string) http.Handler {
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if e.hasBody() && r.Body != nil {
+			data, err := io.ReadAll(r.Body)
+			if err == nil && len(data) > 0 {
+				err = e.codec().Unmarshal(data, &req)
+			}
+			if err != nil {
+				e.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to decode request body: %w", err))
+				return
+			}
+		}
+		if err := e.decodeParams(&req, r, param); err != nil {
+			e.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			code := http.StatusInternalServerError
+			var sc interface{ StatusCode() int }
+			if errors.As(err, &sc) {
+				code = sc.StatusCode()
+			}
+			e.writeError(w, code, err)
+			return
+		}
+		data, err := e.codec().Marshal(resp)
+		if err != nil {
+			e.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to marshal response: %w", err))
+			return
+		}
+		w.Header().Set("Content-Type", e.codec().ContentType())
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	})
+}
+
+func (e Endpoint[Req, Resp]) writeError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// encodeParams fills the path template and query from req's tagged fields.
+func (e Endpoint[Req, Resp]) encodeParams(req Req) (string, url.Values, error) {
+	params := make(map[string]string)
+	query := url.Values{}
+	_ = eachTaggedField(reflect.ValueOf(&req).Elem(), func(kind, name string, field reflect.Value) error {
+		value := fmt.Sprint(field.Interface())
+		switch kind {
+		case "path":
+			params[name] = value
+		case "query":
+			if !field.IsZero() {
+				query.Set(name, value)
+			}
+		}
+		return nil
+	})
+	path, err := expandPath(e.Path, params)
+	if err != nil {
+		return "", nil, fmt.Errorf("endpoint %w", err)
+	}
+	return path, query, nil
+}
+
+// decodeParams sets req's tagged fields from the routed request.
+func (e Endpoint[Req, Resp]) decodeParams(req *Req, r *http.Request, param func(*http.Request, string) string) error {
+	query := r.URL.Query()
+	return eachTaggedField(reflect.ValueOf(req).Elem(), func(kind, name string, field reflect.Value) error {
+		var raw string
+		switch kind {
+		case "path":
+			raw = param(r, name)
+		case "query":
+			if !query.Has(name) {
+				return nil
+			}
+			raw = query.Get(name)
+		}
+		if err := setFromString(field, raw); err != nil {
+			return fmt.Errorf("invalid %s parameter %q: %w", kind, name, err)
+		}
+		return nil
+	})
+}
+
+// eachTaggedField calls fn for every exported field of a struct value
+// carrying a path or query tag. Non-struct values have none.
+func eachTaggedField(v reflect.Value, fn func(kind, name string, field reflect.Value) error) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		for _, kind := range [...]string{"path", "query"} {
+			if name, ok := f.Tag.Lookup(kind); ok {
+				if err := fn(kind, name, v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func setFromString(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field kind %s", field.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type getItemRequest struct {
+	ID      string `path:"id" json:"-"`
+	Verbose bool   `query:"verbose" json:"-"`
+}
+
+type updateItemRequest struct {
+	ID   int    `path:"id" json:"-"`
+	Name string `json:"name"`
+}
+
+type item struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Verbose bool   `json:"verbose,omitempty"`
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string   { return "item not found" }
+func (notFoundError) StatusCode() int { return http.StatusNotFound }
+
+var (
+	getItem    = Endpoint[getItemRequest, item]{Method: http.MethodGet, Path: "/items/{id}"}
+	updateItem = Endpoint[updateItemRequest, item]{Method: http.MethodPut, Path: "/items/{id}"}
+)
+
+// pathParam stands in for server.Param: the test mux routes by prefix only.
+func pathParam(r *http.Request, name string) string {
+	if name != "id" {
+		return ""
+	}
+	return strings.TrimPrefix(r.URL.Path, "/items/")
+}
+
+func Test_Endpoint_RoundTrip(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/items/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			getItem.Handler(func(_ context.Context, req getItemRequest) (item, error) {
+				if req.ID == "missing" {
+					return item{}, notFoundError{}
+				}
+				return item{ID: req.ID, Name: "widget", Verbose: req.Verbose}, nil
+			}, pathParam).ServeHTTP(w, r)
+			return
+		}
+		updateItem.Handler(func(_ context.Context, req updateItemRequest) (item, error) {
+			return item{ID: strconv.Itoa(req.ID), Name: req.Name}, nil
+		}, pathParam).ServeHTTP(w, r)
+	}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+	ctx := context.Background()
+
+	got, err := getItem.Call(ctx, c, getItemRequest{ID: "a b", Verbose: true})
+	if err != nil || got != (item{ID: "a b", Name: "widget", Verbose: true}) {
+		t.Fatalf("get = %+v, %v", got, err)
+	}
+
+	got, err = updateItem.Call(ctx, c, updateItemRequest{ID: 7, Name: "gadget"})
+	if err != nil || got != (item{ID: "7", Name: "gadget"}) {
+		t.Fatalf("update = %+v, %v", got, err)
+	}
+
+	_, err = getItem.Call(ctx, c, getItemRequest{ID: "missing"})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound || !strings.Contains(string(httpErr.Body), "item not found") {
+		t.Fatalf("missing err = %v", err)
+	}
+}
+
+func Test_Endpoint_Handler_BadInput(t *testing.T) {
+	h := updateItem.Handler(func(context.Context, updateItemRequest) (item, error) { return item{}, nil }, pathParam)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "non-numeric path param", path: "/items/abc", body: `{"name":"x"}`},
+		{name: "malformed body", path: "/items/1", body: `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", rec.Code)
+			}
+		})
+	}
+}
+
+func Test_Endpoint_EncodeParams_Errors(t *testing.T) {
+	bad := Endpoint[getItemRequest, item]{Method: http.MethodGet, Path: "/things"}
+	if _, err := bad.Call(context.Background(), NewClient(Config{}), getItemRequest{ID: "1"}); err == nil {
+		t.Fatal("a path tag without a placeholder should fail")
+	}
+}
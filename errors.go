@@ -0,0 +1,123 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// errorBodyLimit caps how much of the body an HTTPError message quotes.
+const errorBodyLimit = 200
+
+// HTTPError is a response whose status the caller treats as a failure
+// (anything outside 2xx for the typed helpers, >= 400 with
+// Config.ErrorOnStatus). Body is the raw response body.
+type HTTPError struct {
+	StatusCode int
+	Body       []byte
+	Headers    http.Header
+}
+
+func (e *HTTPError) Error() string {
+	body := limitBodySize(e.Body, errorBodyLimit)
+	if body == "" {
+		return fmt.Sprintf("http status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, body)
+}
+
+func newHTTPError(resp *Response) *HTTPError {
+	return &HTTPError{StatusCode: resp.StatusCode, Body: resp.Body, Headers: resp.Headers}
+}
+
+// statusError turns resp into an *HTTPError, reading and closing a streamed
+// body.
+func statusError(resp *Response) *HTTPError {
+	if resp.Reader != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Reader, errorBodyMaxRead))
+		_ = resp.Close()
+		return &HTTPError{StatusCode: resp.StatusCode, Body: body, Headers: resp.Headers}
+	}
+	return newHTTPError(resp)
+}
+
+// errorBodyMaxRead caps how much of a streamed error body is read into an
+// HTTPError.
+const errorBodyMaxRead = 64 << 10
+
+// RequestError is a request that failed before a response was read: it
+// could not be built, signed, or authenticated, the transport failed, or
+// the body could not be read. Op names the step.
+type RequestError struct {
+	Op     string
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("failed to %s: %v", e.Op, e.Err)
+}
+
+func (e *RequestError) Unwrap() error { return e.Err }
+
+// StreamPanicError is the error of a stream's terminal EOF frame when
+// reading the stream panicked, e.g. in a middleware's body or a metrics hook.
+// The panic is recovered so it cannot take the process down.
+type StreamPanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *StreamPanicError) Error() string {
+	return fmt.Sprintf("stream panicked: %v", e.Value)
+}
+
+// opSend is the RequestError.Op of a transport failure.
+const opSend = "send request"
+
+// StatusOf returns the status of the *HTTPError in err's chain, or 0.
+func StatusOf(err error) int {
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	return StatusOf(err) == http.StatusNotFound
+}
+
+// IsRateLimited reports whether err is a 429 response. RetryAfter says how
+// long the server asked to wait.
+func IsRateLimited(err error) bool {
+	return StatusOf(err) == http.StatusTooManyRequests
+}
+
+// IsRetryable reports whether err is worth retrying, by DefaultRetryOn's
+// rules: a transport failure other than a canceled or expired context or an
+// open circuit breaker, or a 408, 429, 502, 503, or 504 response.
+func IsRetryable(err error) bool {
+	if status := StatusOf(err); status != 0 {
+		return DefaultRetryOn(&http.Response{StatusCode: status}, nil)
+	}
+	var rerr *RequestError
+	if errors.As(err, &rerr) && rerr.Op == opSend {
+		return DefaultRetryOn(nil, err)
+	}
+	return false
+}
+
+// RetryAfter returns the wait the Retry-After header of the *HTTPError in
+// err's chain asks for, given as seconds or an HTTP date.
+func RetryAfter(err error) (time.Duration, bool) {
+	var herr *HTTPError
+	if !errors.As(err, &herr) || herr.Headers == nil {
+		return 0, false
+	}
+	return retryAfter(herr.Headers.Get("Retry-After"), time.Now())
+}
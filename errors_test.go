@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Client_ErrorOnStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.Error(w, "no such item", http.StatusNotFound)
+		case "/busy":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(Config{BaseURL: srv.URL, ErrorOnStatus: true})
+	ctx := context.Background()
+
+	resp, err := c.Get(ctx, GetRequest{Request: Request{Path: "/missing"}})
+	var herr *HTTPError
+	if resp != nil || !errors.As(err, &herr) || string(herr.Body) != "no such item\n" || !IsNotFound(err) || IsRetryable(err) {
+		t.Fatalf("404: resp=%v err=%v", resp, err)
+	}
+
+	_, err = c.Get(ctx, GetRequest{Request: Request{Path: "/busy", Stream: true}})
+	if !IsRateLimited(err) || !IsRetryable(err) {
+		t.Fatalf("429: err=%v", err)
+	}
+	if d, ok := RetryAfter(err); !ok || d != 7*time.Second {
+		t.Fatalf("RetryAfter = %v, %v", d, ok)
+	}
+
+	if resp, err := c.Get(ctx, GetRequest{Request: Request{Path: "/"}}); err != nil || string(resp.Body) != "ok" {
+		t.Fatalf("200: resp=%v err=%v", resp, err)
+	}
+
+	// without the flag the response comes back as is
+	if resp, err := newTestClient(t, srv.URL).Get(ctx, GetRequest{Request: Request{Path: "/missing"}}); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("default: resp=%v err=%v", resp, err)
+	}
+}
+
+func Test_RequestError_Classification(t *testing.T) {
+	_, err := newTestClient(t, "http://127.0.0.1:1").Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	var rerr *RequestError
+	if !errors.As(err, &rerr) || rerr.Op != opSend || rerr.Method != http.MethodGet || rerr.URL != "http://127.0.0.1:1/" {
+		t.Fatalf("err = %#v", err)
+	}
+	if !IsRetryable(err) || StatusOf(err) != 0 {
+		t.Fatalf("transport failure: retryable=%v status=%d", IsRetryable(err), StatusOf(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = newTestClient(t, "http://127.0.0.1:1").Get(ctx, GetRequest{Request: Request{Path: "/"}})
+	if IsRetryable(err) {
+		t.Fatalf("canceled call reported retryable: %v", err)
+	}
+}
+
+func Test_Client_StreamStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	stream := make(chan StreamResponse, 1)
+	err := newTestClient(t, srv.URL).GetStream(context.Background(), stream, Request{Path: "/"})
+	if !IsNotFound(err) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultExpectContinueTimeout is how long a request carrying
+// "Expect: 100-continue" waits for the server's go-ahead before sending its
+// body anyway.
+const DefaultExpectContinueTimeout = time.Second
+
+// ExpectContinueConfig sends large bodies only once the server has accepted
+// the headers. A server that rejects the request on its headers alone (401,
+// 413, ...) answers before the body leaves, saving the upload on every
+// rejected attempt and retry.
+type ExpectContinueConfig struct {
+	// Threshold sends "Expect: 100-continue" with bodies of at least this
+	// many bytes, and with streamed bodies of unknown size. 0 disables.
+	Threshold int64 `json:"threshold"`
+	// Timeout is how long to wait for "100 Continue" before sending the body
+	// regardless, for servers that never answer it. Defaults to
+	// DefaultExpectContinueTimeout.
+	Timeout time.Duration `json:"timeout"`
+}
+
+func (c ExpectContinueConfig) enabled() bool { return c.Threshold > 0 }
+
+// apply returns a copy of base whose transport waits for 100 Continue.
+func (c ExpectContinueConfig) apply(base *http.Client) *http.Client {
+	return withTransport(base, func(t *http.Transport) {
+		t.ExpectContinueTimeout = c.Timeout
+		if t.ExpectContinueTimeout <= 0 {
+			t.ExpectContinueTimeout = DefaultExpectContinueTimeout
+		}
+	})
+}
+
+// expectContinue marks httpReq to wait for 100 Continue when its body is
+// large enough.
+func (h httpClient) expectContinue(httpReq *http.Request) {
+	if !h.expect.enabled() || httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return
+	}
+	// a zero ContentLength with a body means the size is unknown
+	if httpReq.ContentLength <= 0 || httpReq.ContentLength >= h.expect.Threshold {
+		httpReq.Header.Set("Expect", "100-continue")
+	}
+}
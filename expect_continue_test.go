@@ -0,0 +1,77 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// uploadServer rejects requests without credentials on their headers alone,
+// and reads the body of the rest.
+func uploadServer(t *testing.T, expects *[]string, received *atomic.Int64) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*expects = append(*expects, r.Header.Get("Expect"))
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n, _ := io.Copy(io.Discard, r.Body)
+		received.Add(n)
+	}))
+}
+
+func Test_Client_ExpectContinue_SkipsRejectedBody(t *testing.T) {
+	var expects []string
+	var received atomic.Int64
+	srv := uploadServer(t, &expects, &received)
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, ExpectContinue: ExpectContinueConfig{Threshold: 1024}})
+	body := bytes.Repeat([]byte("x"), 1<<20)
+
+	var sent int64
+	resp, err := c.Post(context.Background(), PostRequest{
+		Request: Request{Path: "/upload", OnProgress: func(n, _ int64) { sent = n }},
+		Body:    body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || sent != 0 {
+		t.Fatalf("status=%d sent=%d, want the body held back", resp.StatusCode, sent)
+	}
+
+	resp, err = c.Post(context.Background(), PostRequest{
+		Request: Request{Path: "/upload", Headers: map[string]string{"Authorization": "Bearer t"}},
+		Body:    body,
+	})
+	if err != nil || resp.StatusCode != http.StatusOK || received.Load() != int64(len(body)) {
+		t.Fatalf("accepted upload: resp=%v err=%v received=%d", resp, err, received.Load())
+	}
+	if expects[0] != "100-continue" || expects[1] != "100-continue" {
+		t.Fatalf("Expect headers = %q", expects)
+	}
+}
+
+func Test_Client_ExpectContinue_SmallBodiesSentDirectly(t *testing.T) {
+	var expects []string
+	var received atomic.Int64
+	srv := uploadServer(t, &expects, &received)
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, ExpectContinue: ExpectContinueConfig{Threshold: 1024}})
+	if _, err := c.Post(context.Background(), PostRequest{Request: Request{Path: "/"}, Body: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestClient(t, srv.URL).Post(context.Background(), PostRequest{Request: Request{Path: "/"}, Body: bytes.Repeat([]byte("x"), 4096)}); err != nil {
+		t.Fatal(err)
+	}
+	if expects[0] != "" || expects[1] != "" {
+		t.Fatalf("Expect headers = %q", expects)
+	}
+}
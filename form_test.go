@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_FormRequest_JSONWithoutFiles(t *testing.T) {
+	req, err := FormRequest{
+		Request: Request{Path: "/users", Headers: map[string]string{"X-Keep": "1"}},
+		Fields:  map[string]any{"name": "ada", "age": 36},
+	}.PostRequest()
+	if err != nil {
+		t.Fatalf("PostRequest returned error: %v", err)
+	}
+	if req.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", req.Headers["Content-Type"])
+	}
+	if req.Headers["X-Keep"] != "1" {
+		t.Errorf("X-Keep = %q, want request headers preserved", req.Headers["X-Keep"])
+	}
+	if string(req.Body) != `{"age":36,"name":"ada"}` {
+		t.Errorf("body = %s", req.Body)
+	}
+}
+
+func Test_FormRequest_DoesNotMutateRequestHeaders(t *testing.T) {
+	headers := map[string]string{"X-Keep": "1"}
+	if _, err := (FormRequest{Request: Request{Headers: headers}}).PostRequest(); err != nil {
+		t.Fatalf("PostRequest returned error: %v", err)
+	}
+	if _, ok := headers["Content-Type"]; ok {
+		t.Error("PostRequest must not write into the caller's header map")
+	}
+}
+
+func Test_FormRequest_MultipartWithFiles(t *testing.T) {
+	var (
+		fields   = map[string]string{}
+		fileName string
+		fileType string
+		fileBody string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/form-data" {
+			t.Errorf("Content-Type = %q, want multipart/form-data", r.Header.Get("Content-Type"))
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			if part.FileName() != "" {
+				fileName, fileType, fileBody = part.FileName(), part.Header.Get("Content-Type"), string(data)
+				continue
+			}
+			fields[part.FormName()] = string(data)
+		}
+	}))
+	defer srv.Close()
+
+	req, err := FormRequest{
+		Request: Request{Path: "/upload"},
+		Fields:  map[string]any{"title": "cat", "tags": []string{"a", "b"}, "public": true},
+		Files:   []FormFile{{Field: "file", Name: "cat.png", ContentType: "image/png", Content: []byte("png-bytes")}},
+	}.PostRequest()
+	if err != nil {
+		t.Fatalf("PostRequest returned error: %v", err)
+	}
+	if !strings.HasPrefix(req.Headers["Content-Type"], "multipart/form-data; boundary=") {
+		t.Fatalf("Content-Type = %q, want multipart/form-data", req.Headers["Content-Type"])
+	}
+
+	if _, err := newTestClient(t, srv.URL).Post(context.Background(), req); err != nil {
+		t.Fatalf("Post returned error: %v", err)
+	}
+	if fields["title"] != "cat" || fields["tags"] != `["a","b"]` || fields["public"] != "true" {
+		t.Errorf("fields = %v", fields)
+	}
+	if fileName != "cat.png" || fileType != "image/png" || fileBody != "png-bytes" {
+		t.Errorf("file = %q (%s) %q", fileName, fileType, fileBody)
+	}
+}
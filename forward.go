@@ -0,0 +1,215 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// forwardSkipHeaders are connection-scoped or recomputed by the transport, so
+// Forward never copies them.
+var forwardSkipHeaders = map[string]bool{
+	"Connection":          true,
+	"Proxy-Connection":    true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Host":                true,
+	"Content-Length":      true,
+}
+
+// Forward replays an inbound request through c: same method, path (resolved
+// against c's base URL), query, body, and end-to-end headers. It suits
+// shadow traffic (the server package's Mirror) and simple pass-through
+// gateways. Multi-valued headers are joined with ", ".
+func Forward(ctx context.Context, c Client, r *http.Request) (*Response, error) {
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		if !forwardSkipHeaders[http.CanonicalHeaderKey(k)] {
+			headers[k] = strings.Join(v, ", ")
+		}
+	}
+	req := Request{Path: r.URL.Path, Query: r.URL.Query(), Headers: headers}
+
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, fmt.Errorf("failed to read forwarded body: %w", err)
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return c.Get(ctx, GetRequest{Request: req})
+	case http.MethodDelete:
+		return c.Delete(ctx, req)
+	case http.MethodPost:
+		return c.Post(ctx, PostRequest{Request: req, Body: body})
+	case http.MethodPut:
+		return c.Put(ctx, PutRequest{Request: req, Body: body})
+	case http.MethodPatch:
+		return c.Patch(ctx, PatchRequest{Request: req, Body: body})
+	default:
+		return nil, fmt.Errorf("unsupported forwarded method %q", r.Method)
+	}
+}
+
+// responseSkipHeaders are never passed downstream: connection-scoped, or
+// describing the body as the upstream sent it, which the client already
+// decompressed and the downstream server frames anew.
+var responseSkipHeaders = map[string]bool{
+	"Connection":         true,
+	"Proxy-Connection":   true,
+	"Keep-Alive":         true,
+	"Proxy-Authenticate": true,
+	"Te":                 true,
+	"Trailer":            true,
+	"Transfer-Encoding":  true,
+	"Upgrade":            true,
+	"Content-Length":     true,
+	"Content-Encoding":   true,
+}
+
+// DefaultInternalResponseHeaders reveal the upstream's software or topology,
+// so ResponseHeaderFilter strips them unless KeepInternal is set.
+var DefaultInternalResponseHeaders = []string{
+	"Server",
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-Runtime",
+	ServedByHeaderName,
+	ResponseTimeHeaderName,
+	"Server-Timing",
+}
+
+// HeaderRewrite replaces Old with New in the values of one header, e.g. to
+// point a Location at the gateway instead of the upstream. Func, when set,
+// rewrites each value instead.
+type HeaderRewrite struct {
+	Name string                    `json:"name"`
+	Old  string                    `json:"old"`
+	New  string                    `json:"new"`
+	Func func(value string) string `json:"-"`
+}
+
+// ResponseHeaderFilter picks the upstream response headers a gateway passes
+// downstream. Hop-by-hop headers, those named by Connection, and
+// Content-Length and Content-Encoding are always dropped. Names in Allow and
+// Deny are case-insensitive; a trailing "*" matches a prefix, as in
+// "X-RateLimit-*". The zero value forwards every end-to-end header but
+// DefaultInternalResponseHeaders.
+//
+//	filter := http.ResponseHeaderFilter{
+//		Deny:    []string{"Set-Cookie"},
+//		Rename:  map[string]string{"X-Upstream-Cache": "X-Cache"},
+//		Rewrite: []http.HeaderRewrite{{Name: "Location", Old: "http://orders.internal", New: "https://api.example.com/orders"}},
+//	}
+type ResponseHeaderFilter struct {
+	// Allow, when set, forwards only the headers it matches.
+	Allow []string `json:"allow"`
+	// Deny drops the headers it matches, even allowed ones.
+	Deny []string `json:"deny"`
+	// KeepInternal forwards DefaultInternalResponseHeaders too.
+	KeepInternal bool `json:"keep_internal"`
+	// Rename maps upstream header names to the names sent downstream; Allow
+	// and Deny match the upstream names.
+	Rename map[string]string `json:"rename"`
+	// Rewrite edits the values of forwarded headers, by downstream name.
+	Rewrite []HeaderRewrite `json:"rewrite"`
+}
+
+// Apply returns the headers of upstream f forwards, renamed and rewritten.
+func (f ResponseHeaderFilter) Apply(upstream http.Header) http.Header {
+	hop := make(map[string]bool)
+	for _, v := range upstream.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			hop[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	out := make(http.Header, len(upstream))
+	for name, values := range upstream {
+		name = http.CanonicalHeaderKey(name)
+		if responseSkipHeaders[name] || hop[name] || !f.forwards(name) {
+			continue
+		}
+		to := name
+		for from, renamed := range f.Rename {
+			if strings.EqualFold(from, name) {
+				to = http.CanonicalHeaderKey(renamed)
+				break
+			}
+		}
+		for _, v := range values {
+			out.Add(to, f.rewrite(to, v))
+		}
+	}
+	return out
+}
+
+func (f ResponseHeaderFilter) forwards(name string) bool {
+	if len(f.Allow) > 0 && !matchHeaderName(f.Allow, name) {
+		return false
+	}
+	if matchHeaderName(f.Deny, name) {
+		return false
+	}
+	return f.KeepInternal || !matchHeaderName(DefaultInternalResponseHeaders, name)
+}
+
+func (f ResponseHeaderFilter) rewrite(name, value string) string {
+	for _, rw := range f.Rewrite {
+		if !strings.EqualFold(rw.Name, name) {
+			continue
+		}
+		if rw.Func != nil {
+			value = rw.Func(value)
+		} else if rw.Old != "" {
+			value = strings.ReplaceAll(value, rw.Old, rw.New)
+		}
+	}
+	return value
+}
+
+// matchHeaderName reports whether name matches one of patterns: a header
+// name, or a prefix ending in "*".
+func matchHeaderName(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if prefix := strings.TrimSuffix(p, "*"); prefix != p {
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteForwarded writes resp downstream to w: the headers filter passes,
+// the status, and the body, buffered or streamed, closing a streamed one.
+// With Forward it makes a pass-through gateway handler; streams that need
+// flushing per chunk go through the server package's StreamProxy instead.
+func WriteForwarded(w http.ResponseWriter, resp *Response, filter ResponseHeaderFilter) (int64, error) {
+	h := w.Header()
+	for name, values := range filter.Apply(resp.Headers) {
+		h[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	if resp.Reader == nil {
+		n, err := w.Write(resp.Body)
+		return int64(n), err
+	}
+	defer resp.Reader.Close()
+	n, err := io.Copy(w, resp.Reader)
+	if err != nil {
+		return n, fmt.Errorf("failed to forward response body: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Forward(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	in := httptest.NewRequest(http.MethodPatch, "/items/7?fields=a&fields=b", strings.NewReader(`{"n":1}`))
+	in.Header.Set("Authorization", "Bearer t")
+	in.Header.Add("Accept", "application/json")
+	in.Header.Add("Accept", "text/plain")
+	in.Header.Set("Connection", "keep-alive")
+	in.Header.Set("Keep-Alive", "timeout=5")
+
+	resp, err := Forward(context.Background(), newTestClient(t, srv.URL), in)
+	if err != nil || resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Forward: %v %v", resp, err)
+	}
+	if got.Method != http.MethodPatch || got.URL.Path != "/items/7" || got.URL.Query()["fields"][1] != "b" {
+		t.Fatalf("forwarded %s %s", got.Method, got.URL)
+	}
+	if gotBody != `{"n":1}` || got.Header.Get("Authorization") != "Bearer t" || got.Header.Get("Accept") != "application/json, text/plain" {
+		t.Fatalf("forwarded body %q headers %v", gotBody, got.Header)
+	}
+	if got.Header.Get("Keep-Alive") != "" {
+		t.Fatal("hop-by-hop header forwarded")
+	}
+
+	if _, err := Forward(context.Background(), newTestClient(t, srv.URL), httptest.NewRequest(http.MethodOptions, "/", http.NoBody)); err == nil {
+		t.Fatal("OPTIONS should be unsupported")
+	}
+}
+
+func Test_ResponseHeaderFilter(t *testing.T) {
+	upstream := http.Header{
+		"Content-Type":     {"application/json"},
+		"Content-Length":   {"12"},
+		"Content-Encoding": {"gzip"},
+		"Connection":       {"close, X-Hop"},
+		"X-Hop":            {"1"},
+		"Server":           {"nginx"},
+		"X-Served-By":      {"orders-3"},
+		"Set-Cookie":       {"a=1"},
+		"X-Ratelimit-Left": {"9"},
+		"X-Upstream-Cache": {"HIT"},
+		"Location":         {"http://orders.internal/orders/7"},
+	}
+
+	got := ResponseHeaderFilter{
+		Deny:    []string{"set-cookie"},
+		Rename:  map[string]string{"x-upstream-cache": "X-Cache"},
+		Rewrite: []HeaderRewrite{{Name: "Location", Old: "http://orders.internal", New: "https://api.example.com"}},
+	}.Apply(upstream)
+	want := http.Header{
+		"Content-Type":     {"application/json"},
+		"X-Ratelimit-Left": {"9"},
+		"X-Cache":          {"HIT"},
+		"Location":         {"https://api.example.com/orders/7"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("filtered = %v, want %v", got, want)
+	}
+
+	got = ResponseHeaderFilter{Allow: []string{"X-RateLimit-*", "Server"}, KeepInternal: true}.Apply(upstream)
+	if len(got) != 2 || got.Get("Server") != "nginx" || got.Get("X-Ratelimit-Left") != "9" {
+		t.Fatalf("allowed = %v", got)
+	}
+}
+
+func Test_WriteForwarded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "upstream")
+		w.Header().Set("X-Request-Cost", "3")
+		w.WriteHeader(http.StatusTeapot)
+		fmt.Fprint(w, "short and stout")
+	}))
+	defer srv.Close()
+
+	for _, stream := range []bool{false, true} {
+		gateway := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, err := newTestClient(t, srv.URL).Get(r.Context(), GetRequest{Request: Request{Path: r.URL.Path, Stream: stream}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := WriteForwarded(w, resp, ResponseHeaderFilter{}); err != nil {
+				t.Fatal(err)
+			}
+		})
+		rec := httptest.NewRecorder()
+		gateway.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pot", nil))
+		if rec.Code != http.StatusTeapot || rec.Body.String() != "short and stout" {
+			t.Fatalf("stream=%v: %d %q", stream, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Server") != "" || rec.Header().Get("X-Request-Cost") != "3" {
+			t.Fatalf("stream=%v: headers %v", stream, rec.Header())
+		}
+	}
+}
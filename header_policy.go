@@ -0,0 +1,138 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HeaderAction is what a HeaderRule does to its header.
+type HeaderAction string
+
+// Header rule actions.
+const (
+	// HeaderAlways sets the header to Value, replacing what is there.
+	HeaderAlways HeaderAction = "always"
+	// HeaderDefault sets the header to Value unless the request has it.
+	HeaderDefault HeaderAction = "default"
+	// HeaderNever strips the header.
+	HeaderNever HeaderAction = "never"
+)
+
+// HeaderRule adds or strips one header on the outgoing requests it matches.
+// A rule with no conditions matches every request.
+//
+//	// never send credentials anywhere but the API itself, redirects included
+//	{Name: "Authorization", Action: http.HeaderNever, ForeignHost: true}
+type HeaderRule struct {
+	Name   string       `json:"name"`
+	Action HeaderAction `json:"action"`
+	// Value is the value HeaderAlways and HeaderDefault set.
+	Value string `json:"value"`
+	// Hosts limits the rule to these hosts, matched like ProxyRule.Host:
+	// exact, ".suffix", or "*".
+	Hosts []string `json:"hosts"`
+	// PathPrefix limits the rule to paths under it.
+	PathPrefix string `json:"path_prefix"`
+	// ForeignHost limits the rule to hosts other than BaseURL's, reached by
+	// an absolute Request.Path or a redirect. Without a BaseURL every host
+	// is foreign.
+	ForeignHost bool `json:"foreign_host"`
+}
+
+func (r HeaderRule) matches(u *url.URL, baseHost string) bool {
+	if len(r.Hosts) > 0 {
+		matched := false
+		for _, pattern := range r.Hosts {
+			if (ProxyRule{Host: pattern}).matches(u.Hostname()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if r.PathPrefix != "" && !strings.HasPrefix(u.Path, r.PathPrefix) {
+		return false
+	}
+	if r.ForeignHost && strings.EqualFold(u.Host, baseHost) {
+		return false
+	}
+	return true
+}
+
+// headerPolicy applies Config.HeaderPolicy to every request the client
+// sends and every redirect it follows.
+type headerPolicy struct {
+	rules []HeaderRule
+	// baseHost is the host:port of BaseURL.
+	baseHost string
+}
+
+func newHeaderPolicy(rules []HeaderRule, baseURL string) (*headerPolicy, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	for _, rule := range rules {
+		switch rule.Action {
+		case HeaderAlways, HeaderDefault, HeaderNever:
+		default:
+			return nil, fmt.Errorf("header rule for %q: unknown action %q", rule.Name, rule.Action)
+		}
+	}
+	p := &headerPolicy{rules: rules}
+	if u, err := url.Parse(baseURL); err == nil {
+		p.baseHost = u.Host
+	}
+	return p, nil
+}
+
+// apply runs the rules in order on req.
+func (p *headerPolicy) apply(req *http.Request) {
+	for _, rule := range p.rules {
+		if !rule.matches(req.URL, p.baseHost) {
+			continue
+		}
+		switch rule.Action {
+		case HeaderAlways:
+			req.Header.Set(rule.Name, rule.Value)
+		case HeaderDefault:
+			if req.Header.Get(rule.Name) == "" {
+				req.Header.Set(rule.Name, rule.Value)
+			}
+		case HeaderNever:
+			req.Header.Del(rule.Name)
+		}
+	}
+}
+
+// wrap runs the policy innermost, on the request as it goes out.
+func (p *headerPolicy) wrap(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		p.apply(req)
+		return next(req)
+	}
+}
+
+// applyRedirects returns a copy of base that also runs the policy on each
+// redirect, whose headers net/http copies from the previous request.
+func (p *headerPolicy) applyRedirects(base *http.Client) *http.Client {
+	client := *base
+	next := base.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if next != nil {
+			if err := next(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			// net/http's default limit, which a custom CheckRedirect replaces
+			return errors.New("stopped after 10 redirects")
+		}
+		p.apply(req)
+		return nil
+	}
+	return &client
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_HeaderPolicy_Rules(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{
+		BaseURL: srv.URL,
+		Headers: map[string]string{"X-Debug": "1", "X-Tenant": "caller"},
+		HeaderPolicy: []HeaderRule{
+			{Name: "X-Version", Action: HeaderAlways, Value: "2"},
+			{Name: "X-Tenant", Action: HeaderDefault, Value: "default"},
+			{Name: "X-Region", Action: HeaderDefault, Value: "eu"},
+			{Name: "X-Debug", Action: HeaderNever},
+			{Name: "X-Admin", Action: HeaderAlways, Value: "yes", PathPrefix: "/admin"},
+			{Name: "X-Other", Action: HeaderAlways, Value: "yes", Hosts: []string{"api.example.com"}},
+		},
+	})
+
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/users"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"X-Version": "2", "X-Tenant": "caller", "X-Region": "eu", "X-Debug": "", "X-Admin": "", "X-Other": ""}
+	for name, value := range want {
+		if got.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, got.Get(name), value)
+		}
+	}
+
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/admin/users"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("X-Admin") != "yes" {
+		t.Fatalf("X-Admin = %q under /admin", got.Get("X-Admin"))
+	}
+}
+
+func Test_HeaderPolicy_StripsOnForeignRedirect(t *testing.T) {
+	var foreign http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreign = r.Header.Clone()
+	}))
+	defer other.Close()
+	var home http.Header
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		home = r.Header.Clone()
+		http.Redirect(w, r, other.URL+"/landing", http.StatusFound)
+	}))
+	defer api.Close()
+
+	c := NewClient(Config{
+		BaseURL:      api.URL,
+		Headers:      map[string]string{"X-Api-Key": "secret"},
+		HeaderPolicy: []HeaderRule{{Name: "X-Api-Key", Action: HeaderNever, ForeignHost: true}},
+	})
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/start"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || foreign == nil {
+		t.Fatalf("redirect not followed: status %d", resp.StatusCode)
+	}
+	if home.Get("X-Api-Key") != "secret" {
+		t.Fatal("X-Api-Key not sent to the base URL's host")
+	}
+	if foreign.Get("X-Api-Key") != "" {
+		t.Fatal("X-Api-Key sent to a foreign host after a redirect")
+	}
+}
+
+func Test_HeaderPolicy_UnknownAction(t *testing.T) {
+	if _, err := newHeaderPolicy([]HeaderRule{{Name: "X", Action: "sometimes"}}, ""); err == nil {
+		t.Fatal("unknown action accepted")
+	}
+}
@@ -40,6 +40,15 @@ const ClientRequestIDHeaderName = "X-Request-ID"
 // maps to: service.name (otel resource attribute)
 const ServiceNameHeaderName = "X-Service-Name"
 
+// ServedByHeaderName names the server instance that handled the request
+// set by the server's Correlate middleware; read it with Response.ServedBy
+const ServedByHeaderName = "X-Served-By"
+
+// ResponseTimeHeaderName carries the server-side time to the response header
+// as a Go duration (e.g. "12.5ms"); read it with Response.ResponseTime
+// and compare it to the call's latency to see how much went to the network
+const ResponseTimeHeaderName = "X-Response-Time"
+
 // UserAgent returns a formatted user agent string
 // e.g. "awee-cli/1.0.0 (darwin ?; amd64)" or
 func UserAgent(app, version, os, osVersion, arch string) string {
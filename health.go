@@ -0,0 +1,36 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HealthCheck GETs path through c and reports the upstream healthy when it
+// answers 2xx. Transport failures and other statuses are returned as errors.
+func HealthCheck(ctx context.Context, c Client, path string) error {
+	resp, err := c.Get(ctx, GetRequest{Request: Request{Path: path}})
+	if err != nil {
+		return fmt.Errorf("health check %s failed: %w", path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("health check %s failed: status %d", path, resp.StatusCode)
+	}
+	return nil
+}
+
+// HealthChecker adapts HealthCheck to the func(ctx) error shape readiness
+// registries take (server.Readiness among them), bounding each probe by
+// timeout when it is positive:
+//
+//	readiness.Register("billing", true, http.HealthChecker(billing, "/healthz", 2*time.Second))
+func HealthChecker(c Client, path string, timeout time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return HealthCheck(ctx, c, path)
+	}
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_HealthCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusNoContent)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	if err := HealthCheck(context.Background(), c, "/healthz"); err != nil {
+		t.Fatalf("healthy upstream: %v", err)
+	}
+	if err := HealthCheck(context.Background(), c, "/down"); err == nil {
+		t.Fatal("503 should be unhealthy")
+	}
+	if err := HealthChecker(c, "/slow", 20*time.Millisecond)(context.Background()); err == nil {
+		t.Fatal("probe exceeding its timeout should be unhealthy")
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -23,3 +24,80 @@ func FromJSON[T any](data []byte) (T, error) {
 	}
 	return result, nil
 }
+
+// GetJSON performs a GET and decodes the JSON response into T. A non-2xx
+// response is returned as *HTTPError; the raw *Response comes back either way
+// once the server answered, for headers and status.
+func GetJSON[T any](ctx context.Context, c Client, req GetRequest) (T, *Response, error) {
+	req.Headers = jsonHeaders(req.Headers, false)
+	resp, err := c.Get(ctx, req)
+	return decodeJSONResponse[T](resp, err)
+}
+
+// PostJSON marshals body as the JSON request body of a POST and decodes the
+// JSON response into Resp, like GetJSON.
+func PostJSON[Req, Resp any](ctx context.Context, c Client, req Request, body Req) (Resp, *Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		var out Resp
+		return out, nil, fmt.Errorf("failed to marshal data to JSON: %w", err)
+	}
+	req.Headers = jsonHeaders(req.Headers, true)
+	resp, err := c.Post(ctx, PostRequest{Request: req, Body: data})
+	return decodeJSONResponse[Resp](resp, err)
+}
+
+// PutJSON is PostJSON for PUT.
+func PutJSON[Req, Resp any](ctx context.Context, c Client, req Request, body Req) (Resp, *Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		var out Resp
+		return out, nil, fmt.Errorf("failed to marshal data to JSON: %w", err)
+	}
+	req.Headers = jsonHeaders(req.Headers, true)
+	resp, err := c.Put(ctx, PutRequest{Request: req, Body: data})
+	return decodeJSONResponse[Resp](resp, err)
+}
+
+// PatchJSON is PostJSON for PATCH.
+func PatchJSON[Req, Resp any](ctx context.Context, c Client, req Request, body Req) (Resp, *Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		var out Resp
+		return out, nil, fmt.Errorf("failed to marshal data to JSON: %w", err)
+	}
+	req.Headers = jsonHeaders(req.Headers, true)
+	resp, err := c.Patch(ctx, PatchRequest{Request: req, Body: data})
+	return decodeJSONResponse[Resp](resp, err)
+}
+
+// jsonHeaders copies headers and adds Accept, plus Content-Type when the
+// request carries a body. Values the caller set win.
+func jsonHeaders(headers map[string]string, withBody bool) map[string]string {
+	out := make(map[string]string, len(headers)+2)
+	out["Accept"] = ContentTypeJSON
+	if withBody {
+		out["Content-Type"] = ContentTypeJSON
+	}
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
+
+func decodeJSONResponse[T any](resp *Response, err error) (T, *Response, error) {
+	var out T
+	if err != nil {
+		return out, resp, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return out, resp, newHTTPError(resp)
+	}
+	if len(resp.Body) == 0 {
+		return out, resp, nil
+	}
+	if err := json.Unmarshal(resp.Body, &out); err != nil {
+		return out, resp, fmt.Errorf("failed to unmarshal JSON data: %w", err)
+	}
+	return out, resp, nil
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type helperUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func Test_GetJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Accept: got %q", r.Header.Get("Accept"))
+		}
+		switch r.URL.Path {
+		case "/users/1":
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`{"id":1,"name":"ada"}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"no such user"}`))
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+	ctx := context.Background()
+
+	user, resp, err := GetJSON[helperUser](ctx, c, GetRequest{Request: Request{Path: "/users/1"}})
+	if err != nil || user != (helperUser{ID: 1, Name: "ada"}) || resp.Headers.Get("ETag") != `"v1"` {
+		t.Fatalf("got %+v %v %v", user, resp, err)
+	}
+
+	if _, _, err := GetJSON[helperUser](ctx, c, GetRequest{Request: Request{Path: "/empty"}}); err != nil {
+		t.Fatalf("204: %v", err)
+	}
+
+	_, resp, err = GetJSON[helperUser](ctx, c, GetRequest{Request: Request{Path: "/users/2"}})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound || string(httpErr.Body) != `{"error":"no such user"}` {
+		t.Fatalf("err = %v", err)
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatal("the response must come back alongside an HTTPError")
+	}
+}
+
+func Test_PostJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type: got %q", r.Header.Get("Content-Type"))
+		}
+		var in helperUser
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		in.ID = 7
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(in)
+	}))
+	defer srv.Close()
+
+	got, resp, err := PostJSON[helperUser, helperUser](context.Background(), newTestClient(t, srv.URL), Request{Path: "/users"}, helperUser{Name: "grace"})
+	if err != nil || resp.StatusCode != http.StatusCreated || got != (helperUser{ID: 7, Name: "grace"}) {
+		t.Fatalf("got %+v %v %v", got, resp, err)
+	}
+}
+
+func Test_PostJSON_DecodeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer srv.Close()
+
+	_, resp, err := PutJSON[helperUser, helperUser](context.Background(), newTestClient(t, srv.URL), Request{}, helperUser{})
+	if err == nil || resp == nil {
+		t.Fatalf("want a decode error with the response, got %v %v", resp, err)
+	}
+}
@@ -0,0 +1,209 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces the value of every redacted header, query
+// parameter, and body field.
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedact names the headers, query parameters, and JSON or form body
+// fields that are always redacted, matched case-insensitively.
+var DefaultRedact = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"password",
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"client_secret",
+	"api_key",
+}
+
+// RequestInfo describes an outgoing call to Config.OnRequest, redacted.
+type RequestInfo struct {
+	Method string
+	URL    string
+	// Headers are the ones the client sets, before authentication.
+	Headers http.Header
+	// Body is rendered as for the log: truncated by WithLogBodyLimits,
+	// binary bodies replaced by a placeholder, "<streamed>" for a
+	// Request.BodyReader.
+	Body   string
+	Stream bool
+}
+
+// ResponseInfo describes the outcome of a call to Config.OnResponse,
+// redacted like RequestInfo. For streams and streamed bodies Body is
+// "<streamed>" and the call is reported once the response headers arrive.
+type ResponseInfo struct {
+	Request    RequestInfo
+	StatusCode int
+	Headers    http.Header
+	Body       string
+	Duration   time.Duration
+	Err        error
+}
+
+// redactor masks sensitive names in everything the client logs or hands to
+// hooks.
+type redactor struct {
+	names map[string]struct{}
+}
+
+func newRedactor(extra []string) redactor {
+	r := redactor{names: make(map[string]struct{}, len(DefaultRedact)+len(extra))}
+	for _, name := range append(append([]string{}, DefaultRedact...), extra...) {
+		r.names[strings.ToLower(name)] = struct{}{}
+	}
+	return r
+}
+
+func (r redactor) match(name string) bool {
+	_, ok := r.names[strings.ToLower(name)]
+	return ok
+}
+
+func (r redactor) headers(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if r.match(k) {
+			v = []string{RedactedValue}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (r redactor) headerMap(m map[string]string) http.Header {
+	out := make(http.Header, len(m))
+	for k, v := range m {
+		if r.match(k) {
+			v = RedactedValue
+		}
+		out.Set(k, v)
+	}
+	return out
+}
+
+func (r redactor) query(q url.Values) url.Values {
+	if len(q) == 0 {
+		return q
+	}
+	out := make(url.Values, len(q))
+	for k, v := range q {
+		if r.match(k) {
+			v = []string{RedactedValue}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (r redactor) url(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	u.RawQuery = r.query(u.Query()).Encode()
+	return u.String()
+}
+
+// body renders body like logBody, after masking redacted fields of a JSON
+// or form body.
+func (r redactor) body(body []byte, contentType string, limit int64) string {
+	switch {
+	case IsForm(contentType):
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			body = []byte(r.query(form).Encode())
+		}
+	case IsJSON(contentType) || (contentType == "" && json.Valid(body)):
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		// re-encode only when a field was masked, keeping the body as sent
+		if dec.Decode(&v) == nil && r.json(v) {
+			if masked, err := json.Marshal(v); err == nil {
+				body = masked
+			}
+		}
+	}
+	return logBody(body, contentType, limit)
+}
+
+// json masks redacted fields of a decoded JSON value in place, reporting
+// whether any was found.
+func (r redactor) json(v any) bool {
+	masked := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if r.match(k) {
+				v[k] = RedactedValue
+				masked = true
+			} else if r.json(field) {
+				masked = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if r.json(item) {
+				masked = true
+			}
+		}
+	}
+	return masked
+}
+
+// requestInfo describes a call for the log and OnRequest.
+func (h httpClient) requestInfo(method, path string, headers map[string]string, req Request, body []byte, limit int64, stream bool) RequestInfo {
+	info := RequestInfo{
+		Method:  method,
+		URL:     h.redact.url(path),
+		Headers: h.redact.headerMap(headers),
+		Body:    "<streamed>",
+		Stream:  stream,
+	}
+	if req.BodyReader == nil {
+		info.Body = h.redact.body(body, headers["Content-Type"], limit)
+	}
+	if h.onRequest != nil {
+		h.onRequest(info)
+	}
+	return info
+}
+
+// reportResponse hands the outcome of the call info describes to
+// OnResponse.
+func (h httpClient) reportResponse(info RequestInfo, start time.Time, resp *Response, err error) {
+	if h.onResponse == nil || info.Method == "" {
+		return
+	}
+	out := ResponseInfo{Request: info, Duration: time.Since(start), Err: err}
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		out.StatusCode = herr.StatusCode
+		out.Headers = h.redact.headers(herr.Headers)
+		out.Body = h.redact.body(herr.Body, herr.Headers.Get("Content-Type"), h.logBodyLimit)
+	}
+	if resp != nil {
+		out.StatusCode = resp.StatusCode
+		out.Headers = h.redact.headers(resp.Headers)
+		out.Body = "<streamed>"
+		if resp.Reader == nil && !info.Stream {
+			out.Body = h.redact.body(resp.Body, resp.Headers.Get("Content-Type"), h.logBodyLimit)
+		}
+	}
+	h.onResponse(out)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Client_Hooks_Redact(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = io.WriteString(w, `{"id":1,"secret":"s3cr3t"}`)
+	}))
+	defer srv.Close()
+
+	var requests []RequestInfo
+	var responses []ResponseInfo
+	log := &bodyLogger{}
+	c := NewClient(Config{
+		BaseURL:    srv.URL,
+		Headers:    map[string]string{"X-Api-Key": "key", "X-Session": "sess"},
+		Redact:     []string{"x-session", "ssn"},
+		OnRequest:  func(info RequestInfo) { requests = append(requests, info) },
+		OnResponse: func(info ResponseInfo) { responses = append(responses, info) },
+	}, WithLogger(log))
+
+	_, err := c.Post(context.Background(), PostRequest{
+		Request: Request{Path: "/users", Query: map[string][]string{"api_key": {"q-key"}, "page": {"2"}}, Headers: map[string]string{"Content-Type": "application/json"}},
+		Body:    []byte(`{"name":"ann","password":"hunter2","profile":[{"ssn":"123"}]}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("hooks called %d/%d times", len(requests), len(responses))
+	}
+	req, resp := requests[0], responses[0]
+
+	for _, leak := range []string{"key", "sess", "q-key", "hunter2", "123", "s3cr3t", "session=abc"} {
+		for _, s := range append([]string{fmt.Sprint(req), fmt.Sprint(resp)}, log.bodies...) {
+			if strings.Contains(s, `"`+leak+`"`) || strings.Contains(s, "="+leak) || strings.Contains(s, "["+leak+"]") {
+				t.Fatalf("%q leaked in %s", leak, s)
+			}
+		}
+	}
+	if req.Method != http.MethodPost || !strings.Contains(req.URL, "page=2") || !strings.Contains(req.Body, `"name":"ann"`) || req.Headers.Get("X-Session") != RedactedValue {
+		t.Fatalf("request info = %+v", req)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Headers.Get("Set-Cookie") != RedactedValue || !strings.Contains(resp.Body, `"id":1`) || resp.Duration <= 0 {
+		t.Fatalf("response info = %+v", resp)
+	}
+}
+
+func Test_Client_Hooks_Stream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "nope", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: hi\n\n")
+	}))
+	defer srv.Close()
+
+	var responses []ResponseInfo
+	c := NewClient(Config{BaseURL: srv.URL, OnResponse: func(info ResponseInfo) { responses = append(responses, info) }})
+	for _, path := range []string{"/events", "/missing"} {
+		stream := make(chan StreamResponse, 4)
+		_ = c.GetStream(context.Background(), stream, Request{Path: path})
+		for range stream {
+		}
+	}
+	if len(responses) != 2 {
+		t.Fatalf("OnResponse called %d times", len(responses))
+	}
+	if ok := responses[0]; ok.StatusCode != http.StatusOK || ok.Body != "<streamed>" || !ok.Request.Stream || ok.Err != nil {
+		t.Fatalf("stream = %+v", ok)
+	}
+	if missing := responses[1]; missing.StatusCode != http.StatusNotFound || missing.Body != "nope\n" || missing.Err == nil {
+		t.Fatalf("failed stream = %+v", missing)
+	}
+}
+
+func Test_Redactor_Body(t *testing.T) {
+	r := newRedactor(nil)
+	for _, tc := range []struct {
+		name, body, contentType, want string
+	}{
+		{"json", `{"token":"t","n":1.50}`, "application/json", `{"n":1.50,"token":"[REDACTED]"}`},
+		{"untouched json keeps its bytes", `{"b":1, "a":2}`, "application/json", `{"b":1, "a":2}`},
+		{"form", "password=p&user=u", "application/x-www-form-urlencoded", "password=%5BREDACTED%5D&user=u"},
+		{"sniffed json", `[{"secret":"x"}]`, "", `[{"secret":"[REDACTED]"}]`},
+		{"text", "password=p", "text/plain", "password=p"},
+		{"ndjson is not one JSON value", "{\"token\":\"t\"}\n{\"a\":1}", ContentTypeNDJSON, "{\"token\":\"t\"}\n{\"a\":1}"},
+	} {
+		if got := r.body([]byte(tc.body), tc.contentType, 0); got != tc.want {
+			t.Errorf("%s: got %q want %q", tc.name, got, tc.want)
+		}
+	}
+}
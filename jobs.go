@@ -0,0 +1,220 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JobState is the state of a long-running job.
+type JobState string
+
+// Job states, as the server package's Jobs reports them.
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// JobStatus is the body of a job status endpoint. Result holds the job's
+// outcome once it succeeded.
+type JobStatus struct {
+	ID       string          `json:"id"`
+	State    JobState        `json:"state"`
+	Progress float64         `json:"progress,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Done reports whether the job has finished, either way.
+func (s JobStatus) Done() bool { return s.State == JobSucceeded || s.State == JobFailed }
+
+// JobError is returned by SubmitAndWait for a job that failed.
+type JobError struct {
+	Status JobStatus
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("job %s failed: %s", e.Status.ID, e.Status.Error)
+}
+
+// ErrNoJobLocation is returned by SubmitAndWait for a 202 Accepted without
+// a Location to poll.
+var ErrNoJobLocation = errors.New("202 Accepted without a Location header")
+
+// ErrCrossOriginJobLocation is returned by SubmitAndWait for a Location on
+// another origin than the submit URL. It is never polled, so the client's
+// headers and credentials do not reach another host.
+var ErrCrossOriginJobLocation = errors.New("job Location is on another origin")
+
+// Poll defaults applied when PollOptions leaves them unset.
+const (
+	DefaultPollInterval    = time.Second
+	DefaultPollMaxInterval = 30 * time.Second
+)
+
+// PollOptions tunes SubmitAndWait.
+type PollOptions struct {
+	// Interval is the wait between polls when the status endpoint sends no
+	// Retry-After. Defaults to DefaultPollInterval.
+	Interval time.Duration
+	// MaxInterval caps the wait a Retry-After asks for. Defaults to
+	// DefaultPollMaxInterval.
+	MaxInterval time.Duration
+	// Location maps the Location header to the Request.Path polled under
+	// the client's BaseURL. By default Location is resolved against the
+	// submit URL and must stay on its origin and under the BaseURL; set it
+	// for a status endpoint elsewhere on the same API.
+	Location func(location string) string
+	// OnStatus, when set, is called with every status polled, e.g. to
+	// report progress.
+	OnStatus func(JobStatus)
+}
+
+// SubmitAndWait posts req and, when the server accepts it as a job with
+// 202 Accepted and a Location, polls that location until the job is done:
+// the context bounds the whole wait. A response other than 202 is returned
+// as is, the operation having completed right away. Polls answered 202, or
+// a JobStatus that is pending or running, mean the job is still going; a
+// failed JobStatus is returned as a *JobError. Otherwise the last poll's
+// Response is returned: a succeeded JobStatus whose Result is the outcome,
+// or what the status endpoint redirected to on completion.
+func SubmitAndWait(ctx context.Context, c Client, req PostRequest, opts PollOptions) (*Response, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultPollInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = DefaultPollMaxInterval
+	}
+	resp, err := c.Post(ctx, req)
+	if err != nil || resp.StatusCode != http.StatusAccepted {
+		return resp, err
+	}
+	location := resp.Headers.Get("Location")
+	if location == "" {
+		return resp, ErrNoJobLocation
+	}
+	poll := Request{Headers: make(map[string]string, len(req.Headers))}
+	if opts.Location != nil {
+		poll.Path = opts.Location(location)
+	} else if poll.Path, poll.Query, err = jobLocation(c, req.Path, location); err != nil {
+		return resp, err
+	}
+	for k, v := range req.Headers {
+		if http.CanonicalHeaderKey(k) != "Content-Type" {
+			poll.Headers[k] = v
+		}
+	}
+
+	for {
+		if status, ok := jobStatus(resp); ok && opts.OnStatus != nil {
+			opts.OnStatus(status)
+		}
+		timer := time.NewTimer(opts.wait(resp))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		resp, err = c.Get(ctx, GetRequest{Request: poll})
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusAccepted {
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, &HTTPError{StatusCode: resp.StatusCode, Body: resp.Body, Headers: resp.Headers}
+		}
+		status, ok := jobStatus(resp)
+		if !ok || status.Done() {
+			if ok && opts.OnStatus != nil {
+				opts.OnStatus(status)
+			}
+			if ok && status.State == JobFailed {
+				return resp, &JobError{Status: status}
+			}
+			return resp, nil
+		}
+	}
+}
+
+// jobLocation resolves location against the URL submitPath was posted to
+// and returns the path and query polling it through c, relative to the
+// client's BaseURL.
+func jobLocation(c Client, submitPath, location string) (string, url.Values, error) {
+	var baseURL string
+	if h, ok := c.(httpClient); ok {
+		baseURL = h.baseURL
+	}
+	submit := submitPath
+	if baseURL != "" {
+		joined, err := url.JoinPath(baseURL, submitPath)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to join URL: %s: %w", submitPath, err)
+		}
+		submit = joined
+	}
+	from, err := url.Parse(submit)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse submit URL: %w", err)
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse job Location: %s: %w", location, err)
+	}
+	to := from.ResolveReference(ref)
+	if !sameOrigin(from, to) {
+		return "", nil, fmt.Errorf("%w: %s", ErrCrossOriginJobLocation, location)
+	}
+	query := to.Query()
+	to.RawQuery, to.Fragment = "", ""
+	if baseURL == "" {
+		return to.String(), query, nil
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse BaseURL: %w", err)
+	}
+	prefix, path := strings.TrimSuffix(base.EscapedPath(), "/"), to.EscapedPath()
+	if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+		return "", nil, fmt.Errorf("job Location %s is outside BaseURL %s, map it with PollOptions.Location", location, baseURL)
+	}
+	return strings.TrimPrefix(path, prefix), query, nil
+}
+
+// wait returns how long to wait before polling again after resp.
+func (o PollOptions) wait(resp *Response) time.Duration {
+	if secs, err := strconv.Atoi(resp.Headers.Get("Retry-After")); err == nil && secs >= 0 {
+		d := time.Duration(secs) * time.Second
+		if d > o.MaxInterval {
+			d = o.MaxInterval
+		}
+		return d
+	}
+	return o.Interval
+}
+
+// jobStatus decodes resp's body as a JobStatus, reporting false when it is
+// not one.
+func jobStatus(resp *Response) (JobStatus, bool) {
+	var status JobStatus
+	if len(resp.Body) == 0 || json.Unmarshal(resp.Body, &status) != nil {
+		return JobStatus{}, false
+	}
+	switch status.State {
+	case JobPending, JobRunning, JobSucceeded, JobFailed:
+		return status, true
+	}
+	return JobStatus{}, false
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jobServer accepts POST /reports as a job that reports running for the
+// first polls and then finishes with final.
+func jobServer(t *testing.T, final string) (*httptest.Server, *atomic.Int32) {
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/jobs/j1")
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"id":"j1","state":"pending"}`)
+	})
+	mux.HandleFunc("/jobs/j1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t" {
+			t.Errorf("poll without the submit headers")
+		}
+		if polls.Add(1) < 3 {
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, `{"id":"j1","state":"running","progress":0.5}`)
+			return
+		}
+		fmt.Fprint(w, final)
+	})
+	return httptest.NewServer(mux), &polls
+}
+
+func Test_SubmitAndWait(t *testing.T) {
+	srv, polls := jobServer(t, `{"id":"j1","state":"succeeded","result":{"rows":3}}`)
+	defer srv.Close()
+
+	var seen []JobState
+	c := NewClient(Config{BaseURL: srv.URL})
+	resp, err := SubmitAndWait(context.Background(), c, PostRequest{Request: Request{Path: "/reports", Headers: map[string]string{"Authorization": "Bearer t"}}}, PollOptions{
+		Interval: time.Millisecond,
+		OnStatus: func(s JobStatus) { seen = append(seen, s.State) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, ok := jobStatus(resp); !ok || status.State != JobSucceeded || string(status.Result) != `{"rows":3}` {
+		t.Fatalf("final status = %s", resp.Body)
+	}
+	if polls.Load() != 3 {
+		t.Fatalf("polled %d times, want 3", polls.Load())
+	}
+	want := []JobState{JobPending, JobRunning, JobRunning, JobSucceeded}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Fatalf("statuses = %v, want %v", seen, want)
+	}
+}
+
+func Test_SubmitAndWait_Failed(t *testing.T) {
+	srv, _ := jobServer(t, `{"id":"j1","state":"failed","error":"disk full"}`)
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}})
+	_, err := SubmitAndWait(context.Background(), c, PostRequest{Request: Request{Path: "/reports"}}, PollOptions{Interval: time.Millisecond})
+	var jobErr *JobError
+	if !errors.As(err, &jobErr) || jobErr.Status.Error != "disk full" {
+		t.Fatalf("err = %v, want a JobError", err)
+	}
+}
+
+func Test_SubmitAndWait_Synchronous(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"r1"}`)
+	}))
+	defer srv.Close()
+
+	resp, err := SubmitAndWait(context.Background(), NewClient(Config{BaseURL: srv.URL}), PostRequest{}, PollOptions{})
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("resp = %+v, err = %v", resp, err)
+	}
+}
+
+func Test_SubmitAndWait_Canceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/jobs/j1")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := SubmitAndWait(ctx, NewClient(Config{BaseURL: srv.URL}), PostRequest{}, PollOptions{Interval: time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the deadline", err)
+	}
+}
+
+func Test_SubmitAndWait_CrossOriginLocation(t *testing.T) {
+	var polled atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polled.Store(true)
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", other.URL+"/jobs/j1")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}})
+	_, err := SubmitAndWait(context.Background(), c, PostRequest{}, PollOptions{Interval: time.Millisecond})
+	if !errors.Is(err, ErrCrossOriginJobLocation) || polled.Load() {
+		t.Fatalf("err = %v, polled = %v", err, polled.Load())
+	}
+}
+
+func Test_jobLocation(t *testing.T) {
+	tests := []struct {
+		baseURL, location, path, query string
+		err                            bool
+	}{
+		{"https://api.example/v1", "/v1/jobs/1?x=y", "/jobs/1", "x=y", false},
+		{"https://api.example/v1", "jobs/1", "/jobs/1", "", false},
+		{"https://api.example/v1", "https://api.example:443/v1/jobs/1", "/jobs/1", "", false},
+		{"https://api.example/v1", "/jobs/1", "", "", true},
+		{"https://api.example/v1", "https://evil.example/v1/jobs/1", "", "", true},
+		{"", "/jobs/1?x=y", "https://api.example/jobs/1", "x=y", false},
+	}
+	for _, tt := range tests {
+		c, submit := NewClient(Config{BaseURL: tt.baseURL}), "/reports"
+		if tt.baseURL == "" {
+			submit = "https://api.example/reports"
+		}
+		path, query, err := jobLocation(c, submit, tt.location)
+		if (err != nil) != tt.err || path != tt.path || query.Encode() != tt.query {
+			t.Errorf("jobLocation(%q, %q) = %q, %q, %v", tt.baseURL, tt.location, path, query.Encode(), err)
+		}
+	}
+}
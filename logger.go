@@ -1,5 +1,13 @@
 package http
 
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
 // Logger is the minimal leveled logging surface the client writes to. It is
 // satisfied structurally by github.com/toaweme/log's Slog, so callers can
 // inject that directly, or a null logger to discard output. There is
@@ -24,3 +32,62 @@ func (nopLogger) Debug(string, ...any) {}
 func (nopLogger) Info(string, ...any)  {}
 func (nopLogger) Warn(string, ...any)  {}
 func (nopLogger) Error(string, ...any) {}
+
+// logBody renders body for a log line: truncated to maxSize (0 means no cap),
+// or a "<binary N bytes>" placeholder when the content type or the bytes
+// themselves say it is not text, so images and protobuf never garble the log.
+func logBody(body []byte, contentType string, maxSize int64) string {
+	if isBinary(contentType, body) {
+		return "<binary " + strconv.Itoa(len(body)) + " bytes>"
+	}
+	return limitBodySize(body, maxSize)
+}
+
+// sniffLen matches how much of a body http.DetectContentType looks at.
+const sniffLen = 512
+
+// isBinary trusts a declared content type when it is conclusive and otherwise
+// sniffs the first bytes for invalid UTF-8 or NUL bytes.
+func isBinary(contentType string, body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch {
+		case strings.HasPrefix(mediaType, "text/"),
+			strings.HasSuffix(mediaType, "json"),
+			strings.HasSuffix(mediaType, "xml"),
+			mediaType == "application/javascript",
+			mediaType == ContentTypeForm:
+			return false
+		case strings.HasPrefix(mediaType, "image/"),
+			strings.HasPrefix(mediaType, "audio/"),
+			strings.HasPrefix(mediaType, "video/"),
+			strings.Contains(mediaType, "protobuf"),
+			strings.Contains(mediaType, "msgpack"),
+			mediaType == ContentTypeOctetStream,
+			mediaType == "application/zip",
+			mediaType == "application/gzip",
+			mediaType == "application/pdf":
+			return true
+		}
+	}
+
+	sample := body
+	if len(sample) > sniffLen {
+		sample = sample[:sniffLen]
+		// don't let a multi-byte rune split at the cut read as invalid UTF-8
+		for i := 0; i < utf8.UTFMax && len(sample) > 0 && !utf8.RuneStart(body[len(sample)]); i++ {
+			sample = sample[:len(sample)-1]
+		}
+	}
+	if !utf8.Valid(sample) {
+		return true
+	}
+	for _, b := range sample {
+		if b == 0 {
+			return true
+		}
+	}
+	return !strings.HasPrefix(http.DetectContentType(sample), "text/")
+}
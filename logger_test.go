@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_isBinary(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        bool
+	}{
+		{name: "json declared", contentType: "application/json", body: []byte(`{"a":1}`), want: false},
+		{name: "problem json declared", contentType: "application/problem+json; charset=utf-8", body: []byte(`{}`), want: false},
+		{name: "image declared", contentType: "image/png", body: []byte("whatever"), want: true},
+		{name: "protobuf declared", contentType: "application/x-protobuf", body: []byte("\x08\x01"), want: true},
+		{name: "sniffed text", body: []byte("plain words"), want: false},
+		{name: "sniffed png", body: []byte("\x89PNG\r\n\x1a\n\x00\x00"), want: true},
+		{name: "sniffed nul", body: []byte("abc\x00def"), want: true},
+		{name: "empty", contentType: "image/png", want: false},
+		{name: "long utf8 cut mid-rune", body: []byte(strings.Repeat("a", sniffLen-1) + "ė"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBinary(tt.contentType, tt.body); got != tt.want {
+				t.Errorf("isBinary = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_LogBody(t *testing.T) {
+	if got := logBody([]byte("abcdef"), "text/plain", 3); got != "abc..." {
+		t.Errorf("logBody text = %q, want abc...", got)
+	}
+	if got := logBody([]byte("\x89PNG\r\n\x1a\n"), "", 0); got != "<binary 8 bytes>" {
+		t.Errorf("logBody binary = %q, want <binary 8 bytes>", got)
+	}
+}
+
+// bodyLogger keeps the "body" arg of every Trace call.
+type bodyLogger struct {
+	nopLogger
+	bodies []string
+}
+
+func (l *bodyLogger) Trace(_ string, args ...any) {
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "body" {
+			s, _ := args[i+1].(string)
+			l.bodies = append(l.bodies, s)
+		}
+	}
+}
+
+func Test_Client_WithLogBodyLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("\xff\xd8\xff\xe0 jpeg"))
+	}))
+	defer srv.Close()
+
+	log := &bodyLogger{}
+	client := newTestClient(t, srv.URL, WithLogger(log), WithLogBodyLimits(4, 0))
+	_, err := client.Post(context.Background(), PostRequest{Request: Request{Path: "/x"}, Body: []byte("request-body")})
+	if err != nil {
+		t.Fatalf("Post returned error: %v", err)
+	}
+	want := []string{"requ...", "<binary 9 bytes>"}
+	if len(log.bodies) != 2 || log.bodies[0] != want[0] || log.bodies[1] != want[1] {
+		t.Errorf("logged bodies = %q, want %q", log.bodies, want)
+	}
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_MemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(2)
+	_ = c.Set("a", CacheEntry{Body: []byte("a")})
+	_ = c.Set("b", CacheEntry{Body: []byte("b")})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+	_ = c.Set("c", CacheEntry{Body: []byte("c")})
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	if e, ok := c.Get("a"); !ok || string(e.Body) != "a" {
+		t.Fatalf("a = %q %v", e.Body, ok)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len = %d", c.Len())
+	}
+}
+
+func Test_MemoryCache_PruneAndPurge(t *testing.T) {
+	now := time.Now()
+	c := NewMemoryCache(0)
+	_ = c.Set("fresh", CacheEntry{ExpiresAt: now.Add(time.Minute)})
+	_ = c.Set("stale", CacheEntry{ExpiresAt: now.Add(-time.Minute)})
+
+	if err := c.Prune(now); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("stale"); ok || c.Len() != 1 {
+		t.Fatalf("stale entry kept; Len = %d", c.Len())
+	}
+	if err := c.Purge(); err != nil || c.Len() != 0 {
+		t.Fatalf("Purge: %v, Len = %d", err, c.Len())
+	}
+}
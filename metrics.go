@@ -0,0 +1,199 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MetricsHook receives client metrics as they are produced. Every field is
+// optional; set only the callbacks you export. Callbacks run synchronously on
+// the request or stream goroutine, so keep them cheap (increment a counter,
+// push onto a channel).
+type MetricsHook struct {
+	// OnStream is called once per GetStream/PostStream call, when the stream ends.
+	OnStream func(StreamMetrics)
+	// OnConnection is called once per request that obtained a connection,
+	// after the response headers arrive (or the request fails).
+	OnConnection func(ConnMetrics)
+	// OnRequest is called once per call with its status and latency. For
+	// GetStream/PostStream it covers opening the stream.
+	OnRequest func(RequestMetrics)
+}
+
+// WithMetricsHook installs the hook the client reports metrics to.
+func WithMetricsHook(hook MetricsHook) Option {
+	return func(h *httpClient) {
+		h.metrics = hook
+	}
+}
+
+// StreamDisconnectReason classifies how a stream ended. It is set on the
+// terminal EOF frame (StreamResponse.Disconnect), in StreamMetrics, and
+// counted in StreamStats, so dashboards can tell provider flakiness (resets,
+// idle timeouts, 5xx) from client bugs (cancellations, 4xx).
+type StreamDisconnectReason string
+
+// Stream disconnect reasons.
+const (
+	StreamDisconnectDone        StreamDisconnectReason = "done"         // the [DONE] sentinel was received
+	StreamDisconnectEOF         StreamDisconnectReason = "eof"          // the server closed the body
+	StreamDisconnectCanceled    StreamDisconnectReason = "canceled"     // the request context ended
+	StreamDisconnectStatus      StreamDisconnectReason = "status"       // the server answered with a non-200, non-5xx status
+	StreamDisconnectServerError StreamDisconnectReason = "server_error" // the server answered with a 5xx status
+	StreamDisconnectReset       StreamDisconnectReason = "reset"        // the connection was reset or cut mid-stream
+	StreamDisconnectIdleTimeout StreamDisconnectReason = "idle_timeout" // nothing arrived for StreamConfig.IdleTimeout
+	StreamDisconnectError       StreamDisconnectReason = "error"        // any other transport or read failure
+	StreamDisconnectPanic       StreamDisconnectReason = "panic"        // reading the stream panicked
+)
+
+// StreamStats counts how the client's streams ended since it was built.
+type StreamStats struct {
+	// Disconnects maps each reason seen to its count.
+	Disconnects map[StreamDisconnectReason]int64
+}
+
+// StreamStatsProvider is implemented by clients built with NewClient:
+//
+//	if p, ok := client.(http.StreamStatsProvider); ok { stats := p.StreamStats() }
+type StreamStatsProvider interface {
+	StreamStats() StreamStats
+}
+
+var _ StreamStatsProvider = httpClient{}
+
+type streamCounters struct {
+	mu          sync.Mutex
+	disconnects map[StreamDisconnectReason]int64
+}
+
+func (c *streamCounters) add(reason StreamDisconnectReason) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	if c.disconnects == nil {
+		c.disconnects = make(map[StreamDisconnectReason]int64)
+	}
+	c.disconnects[reason]++
+	c.mu.Unlock()
+}
+
+// StreamStats implements StreamStatsProvider.
+func (h httpClient) StreamStats() StreamStats {
+	stats := StreamStats{Disconnects: make(map[StreamDisconnectReason]int64)}
+	if h.streams == nil {
+		return stats
+	}
+	h.streams.mu.Lock()
+	defer h.streams.mu.Unlock()
+	for reason, n := range h.streams.disconnects {
+		stats.Disconnects[reason] = n
+	}
+	return stats
+}
+
+// StreamMetrics summarizes one stream from request to disconnect.
+type StreamMetrics struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// TimeToFirstEvent is measured from sending the request; zero when no
+	// event arrived.
+	TimeToFirstEvent time.Duration
+	// Events counts every frame delivered on the channel, excluding the
+	// terminal EOF.
+	Events int
+	// Duration is measured from sending the request to the disconnect.
+	Duration         time.Duration
+	EventsPerSecond  float64
+	DisconnectReason StreamDisconnectReason
+	// Missed and Duplicates count the events lost and the replayed events
+	// dropped, with StreamConfig.SequentialIDs.
+	Missed     uint64
+	Duplicates int
+}
+
+// streamObserver accumulates StreamMetrics for a single stream and reports
+// them to the hook exactly once.
+type streamObserver struct {
+	hook     func(StreamMetrics)
+	counters *streamCounters
+	start    time.Time
+	metrics  StreamMetrics
+}
+
+func newStreamObserver(hook MetricsHook, counters *streamCounters, method, url string) *streamObserver {
+	return &streamObserver{
+		hook:     hook.OnStream,
+		counters: counters,
+		start:    time.Now(),
+		metrics:  StreamMetrics{Method: method, URL: url},
+	}
+}
+
+func (o *streamObserver) event() {
+	if o.metrics.Events == 0 {
+		o.metrics.TimeToFirstEvent = time.Since(o.start)
+	}
+	o.metrics.Events++
+}
+
+func (o *streamObserver) end(status int, reason StreamDisconnectReason) {
+	if o.counters != nil {
+		o.counters.add(reason)
+		o.counters = nil
+	}
+	if o.hook == nil {
+		return
+	}
+	o.metrics.StatusCode = status
+	o.metrics.DisconnectReason = reason
+	o.metrics.Duration = time.Since(o.start)
+	if secs := o.metrics.Duration.Seconds(); secs > 0 {
+		o.metrics.EventsPerSecond = float64(o.metrics.Events) / secs
+	}
+	o.hook(o.metrics)
+	o.hook = nil
+}
+
+// readErrorReason classifies the error that ended a stream read.
+func readErrorReason(ctxErr, err error) StreamDisconnectReason {
+	switch {
+	case ctxErr != nil:
+		return StreamDisconnectCanceled
+	case errors.Is(err, ErrStreamIdleTimeout):
+		return StreamDisconnectIdleTimeout
+	case errors.Is(err, io.EOF):
+		return StreamDisconnectEOF
+	case isConnReset(err):
+		return StreamDisconnectReset
+	default:
+		return StreamDisconnectError
+	}
+}
+
+// statusReason classifies a stream the server refused with status.
+func statusReason(status int) StreamDisconnectReason {
+	if status >= http.StatusInternalServerError {
+		return StreamDisconnectServerError
+	}
+	return StreamDisconnectStatus
+}
+
+// isConnReset reports whether err is the connection being cut: a TCP reset
+// or broken pipe, a body cut short, or an HTTP/2 stream reset.
+func isConnReset(err error) bool {
+	switch {
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+		return true
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	// net/http's bundled HTTP/2 does not export its stream error type
+	return err != nil && strings.Contains(err.Error(), "stream error:")
+}
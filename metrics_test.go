@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_Client_StreamMetrics(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantEvents int
+		wantReason StreamDisconnectReason
+	}{
+		{name: "sentinel", status: http.StatusOK, body: "data: a\n\ndata: b\n\ndata: [DONE]\n\n", wantEvents: 2, wantReason: StreamDisconnectDone},
+		{name: "server closes", status: http.StatusOK, body: "data: a\n\n", wantEvents: 1, wantReason: StreamDisconnectEOF},
+		{name: "client error status", status: http.StatusNotFound, body: "missing", wantEvents: 0, wantReason: StreamDisconnectStatus},
+		{name: "server error status", status: http.StatusBadGateway, body: "down", wantEvents: 0, wantReason: StreamDisconnectServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			var got []StreamMetrics
+			client := newTestClient(t, srv.URL, WithMetricsHook(MetricsHook{
+				OnStream: func(m StreamMetrics) { got = append(got, m) },
+			}))
+			stream := make(chan StreamResponse, 8)
+			_ = client.GetStream(context.Background(), stream, Request{Path: "/sse"})
+			for range stream {
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("OnStream called %d times, want 1", len(got))
+			}
+			m := got[0]
+			if m.Events != tt.wantEvents || m.DisconnectReason != tt.wantReason || m.StatusCode != tt.status {
+				t.Errorf("metrics = %+v, want events %d reason %q status %d", m, tt.wantEvents, tt.wantReason, tt.status)
+			}
+			if m.Method != http.MethodGet || m.URL != srv.URL+"/sse" {
+				t.Errorf("metrics method/url = %s %s", m.Method, m.URL)
+			}
+			if m.Duration <= 0 {
+				t.Errorf("Duration = %v, want > 0", m.Duration)
+			}
+			if tt.wantEvents > 0 && (m.TimeToFirstEvent <= 0 || m.TimeToFirstEvent > m.Duration) {
+				t.Errorf("TimeToFirstEvent = %v, want within (0, %v]", m.TimeToFirstEvent, m.Duration)
+			}
+		})
+	}
+}
+
+func Test_Client_StreamMetrics_RedactsURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "data: a\n\n")
+	}))
+	defer srv.Close()
+
+	var got StreamMetrics
+	client := NewClient(Config{BaseURL: srv.URL, Redact: []string{"token"}}, WithMetricsHook(MetricsHook{
+		OnStream: func(m StreamMetrics) { got = m },
+	}))
+	stream := make(chan StreamResponse, 8)
+	_ = client.GetStream(context.Background(), stream, Request{Path: "/sse", Query: url.Values{"token": {"secret"}}})
+	for range stream {
+	}
+	if strings.Contains(got.URL, "secret") || !strings.Contains(got.URL, "token=") {
+		t.Fatalf("metrics URL = %q, want the token redacted", got.URL)
+	}
+}
+
+func Test_readErrorReason(t *testing.T) {
+	if got := readErrorReason(context.Canceled, io.ErrUnexpectedEOF); got != StreamDisconnectCanceled {
+		t.Errorf("canceled ctx = %q", got)
+	}
+	if got := readErrorReason(nil, io.EOF); got != StreamDisconnectEOF {
+		t.Errorf("io.EOF = %q", got)
+	}
+	if got := readErrorReason(nil, fmt.Errorf("read: %w", syscall.ECONNRESET)); got != StreamDisconnectReset {
+		t.Errorf("connection reset = %q", got)
+	}
+	if got := readErrorReason(nil, fmt.Errorf("failed to read response body: %w", io.ErrUnexpectedEOF)); got != StreamDisconnectReset {
+		t.Errorf("unexpected EOF = %q", got)
+	}
+	if got := readErrorReason(nil, fmt.Errorf("%w: nothing received for 1s", ErrStreamIdleTimeout)); got != StreamDisconnectIdleTimeout {
+		t.Errorf("idle timeout = %q", got)
+	}
+	if got := readErrorReason(nil, errors.New("reset")); got != StreamDisconnectError {
+		t.Errorf("other error = %q", got)
+	}
+}
+
+func Test_Client_StreamIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: a\n\n")
+		w.(http.Flusher).Flush()
+		// stall until the test ends
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Stream: StreamConfig{IdleTimeout: 50 * time.Millisecond}})
+	stream := make(chan StreamResponse, 4)
+	if err := c.GetStream(context.Background(), stream, Request{Path: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	var last StreamResponse
+	for frame := range stream {
+		last = frame
+	}
+	if last.Type != StreamResponseTypeEOF || last.Disconnect != StreamDisconnectIdleTimeout || !errors.Is(last.Error, ErrStreamIdleTimeout) {
+		t.Fatalf("terminal frame = %+v", last)
+	}
+
+	stats := c.(StreamStatsProvider).StreamStats()
+	if stats.Disconnects[StreamDisconnectIdleTimeout] != 1 || len(stats.Disconnects) != 1 {
+		t.Fatalf("disconnects = %v", stats.Disconnects)
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import "net/http"
+
+// RoundTripFunc sends one request and returns its response, like
+// http.RoundTripper.RoundTrip.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the client's transport step to add cross-cutting behavior:
+// token refresh, metrics, request mutation, response rewriting. It runs once
+// per attempt, after the request is built, signed, and routed, and before the
+// response body is read:
+//
+//	tenant := func(next http.RoundTripFunc) http.RoundTripFunc {
+//		return func(req *nethttp.Request) (*nethttp.Response, error) {
+//			req.Header.Set("X-Tenant", tenantFrom(req.Context()))
+//			return next(req)
+//		}
+//	}
+//	client := http.NewClient(cfg, http.WithMiddleware(tenant))
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// WithMiddleware appends middlewares to the client's chain. The first one
+// registered is the outermost: it sees the request first and the response
+// last.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(h *httpClient) {
+		h.middlewares = append(h.middlewares, mws...)
+	}
+}
+
+// roundTrip sends req through the middleware chain, then the circuit
+// breaker, rate limiter, adaptive limiter, header policy, and clock skew
+// measurement, ending in one attempt bounded by Timeouts.Attempt.
+func (h httpClient) roundTrip(client *http.Client, req *http.Request) (*http.Response, error) {
+	next := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return h.sendAttempt(client, r)
+	})
+	if h.skew != nil {
+		next = h.skew.wrap(next)
+	}
+	if h.headerPolicy != nil {
+		next = h.headerPolicy.wrap(next)
+	}
+	if h.adaptive != nil {
+		next = h.adaptive.wrap(next)
+	}
+	if h.limiter != nil {
+		next = h.limiter.wrap(next)
+	}
+	if h.breaker != nil {
+		next = h.breaker.wrap(next)
+	}
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		next = h.middlewares[i](next)
+	}
+	return next(req)
+}
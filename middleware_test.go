@@ -0,0 +1,90 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_WithMiddleware_ChainOrderAndRewrite(t *testing.T) {
+	var gotTenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant = r.Header.Get("X-Tenant")
+		_, _ = w.Write([]byte("original"))
+	}))
+	defer srv.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+">")
+				resp, err := next(req)
+				order = append(order, "<"+name)
+				return resp, err
+			}
+		}
+	}
+	tenant := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Tenant", "acme")
+			return next(req)
+		}
+	}
+	rewrite := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body.Close()
+			resp.Body = io.NopCloser(strings.NewReader("rewritten"))
+			return resp, nil
+		}
+	}
+
+	c := newTestClient(t, srv.URL, WithMiddleware(trace("outer"), tenant), WithMiddleware(trace("inner"), rewrite))
+	resp, err := c.Get(context.Background(), GetRequest{})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if gotTenant != "acme" {
+		t.Fatalf("X-Tenant: got %q", gotTenant)
+	}
+	if string(resp.Body) != "rewritten" {
+		t.Fatalf("body: got %q", resp.Body)
+	}
+	if want := []string{"outer>", "inner>", "<inner", "<outer"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order: got %v want %v", order, want)
+	}
+}
+
+func Test_WithMiddleware_WrapsStreams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: hi\n\n"))
+	}))
+	defer srv.Close()
+
+	var calls int
+	count := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			calls++
+			return next(req)
+		}
+	}
+	c := newTestClient(t, srv.URL, WithMiddleware(count))
+	stream := make(chan StreamResponse, 8)
+	if err := c.GetStream(context.Background(), stream, Request{}); err != nil {
+		t.Fatalf("GetStream: %v", err)
+	}
+	for range stream {
+	}
+	if calls != 1 {
+		t.Fatalf("middleware ran %d times for a stream, want 1", calls)
+	}
+}
@@ -0,0 +1,542 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoMockRoute is returned by a MockClient for a request no route matches.
+var ErrNoMockRoute = errors.New("no mock route matches request")
+
+// MockCall is one request a MockClient received.
+type MockCall struct {
+	Method  string
+	Request Request
+	// Body is the request body; nil for GET and DELETE.
+	Body []byte
+}
+
+// MockClient is a Client for tests of code that consumes one. Routes are
+// registered with On and matched in registration order; every call is
+// recorded for inspection. It is safe for concurrent use.
+type MockClient struct {
+	mu     sync.Mutex
+	routes []*MockRoute
+	calls  []MockCall
+
+	// logger and errorOnStatus are set in mock mode, standing in for the
+	// client's.
+	logger        Logger
+	errorOnStatus bool
+}
+
+var _ Client = (*MockClient)(nil)
+
+// NewMockClient returns a MockClient with no routes.
+func NewMockClient() *MockClient {
+	return &MockClient{}
+}
+
+// MockRoute is a canned answer to requests of one method and path. Without
+// Respond, RespondJSON, RespondError, or Stream it answers 200 with no body.
+type MockRoute struct {
+	method, path string
+	match        func(MockCall) bool
+
+	status  int
+	headers http.Header
+	body    []byte
+	err     error
+	frames  []StreamResponse
+
+	times int
+	calls int
+}
+
+// On registers a route for method and path (without query). Use Match to
+// narrow it further.
+func (m *MockClient) On(method, path string) *MockRoute {
+	r := &MockRoute{method: method, path: path, status: http.StatusOK}
+	m.mu.Lock()
+	m.routes = append(m.routes, r)
+	m.mu.Unlock()
+	return r
+}
+
+// Match narrows the route to calls fn accepts, e.g. by query or body.
+func (r *MockRoute) Match(fn func(MockCall) bool) *MockRoute {
+	r.match = fn
+	return r
+}
+
+// Respond answers with status and body.
+func (r *MockRoute) Respond(status int, body []byte) *MockRoute {
+	r.status, r.body = status, body
+	return r
+}
+
+// RespondJSON answers with status and v encoded as JSON. It panics when v
+// cannot be encoded, which is a bug in the test.
+func (r *MockRoute) RespondJSON(status int, v any) *MockRoute {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("mock: failed to encode response: %v", err))
+	}
+	r.status, r.body = status, body
+	return r.Header("Content-Type", ContentTypeJSON)
+}
+
+// RespondError fails the call with err, as a transport error would.
+func (r *MockRoute) RespondError(err error) *MockRoute {
+	r.err = err
+	return r
+}
+
+// Header adds a response header.
+func (r *MockRoute) Header(key, value string) *MockRoute {
+	if r.headers == nil {
+		r.headers = make(http.Header)
+	}
+	r.headers.Add(key, value)
+	return r
+}
+
+// Stream scripts the frames GetStream and PostStream deliver. Frames without
+// a Type are sent as DATA; the stream then ends with an EOF frame unless the
+// script already ends with one. A non-200 status set with Respond fails the
+// stream the way the real client does.
+func (r *MockRoute) Stream(frames ...StreamResponse) *MockRoute {
+	r.frames = frames
+	return r
+}
+
+// Times limits the route to n calls, after which later routes are tried.
+// Routes registered for the same request with Times(1) answer in sequence.
+// 0, the default, is unlimited.
+func (r *MockRoute) Times(n int) *MockRoute {
+	r.times = n
+	return r
+}
+
+func (r *MockRoute) matches(call MockCall) bool {
+	if r.method != call.Method || r.path != call.Request.Path {
+		return false
+	}
+	if r.times > 0 && r.calls >= r.times {
+		return false
+	}
+	return r.match == nil || r.match(call)
+}
+
+// Calls returns the requests received so far, in order.
+func (m *MockClient) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCall(nil), m.calls...)
+}
+
+// AssertExpectations reports routes that were never called, or called fewer
+// times than Times asked for.
+func (m *MockClient) AssertExpectations() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var missing []string
+	for _, r := range m.routes {
+		if r.calls == 0 || r.calls < r.times {
+			missing = append(missing, fmt.Sprintf("%s %s (called %d times)", r.method, r.path, r.calls))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("mock: unmet expectations: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// route records call and returns the first route matching it.
+func (m *MockClient) route(call MockCall) (*MockRoute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, call)
+	for _, r := range m.routes {
+		if r.matches(call) {
+			r.calls++
+			return r, nil
+		}
+	}
+	if m.logger != nil {
+		m.logger.Warn("http-client", "type", "mock", "error", ErrNoMockRoute, "method", call.Method, "path", call.Request.Path)
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoMockRoute, call.Method, call.Request.Path)
+}
+
+func (m *MockClient) do(ctx context.Context, method string, req Request, body []byte) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r, err := m.route(MockCall{Method: method, Request: req, Body: body})
+	if err != nil {
+		return nil, err
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	resp := &Response{StatusCode: r.status, Headers: r.headers.Clone()}
+	if resp.Headers == nil {
+		resp.Headers = make(http.Header)
+	}
+	data := append([]byte(nil), r.body...)
+	if req.Stream {
+		resp.Reader = io.NopCloser(bytes.NewReader(data))
+	} else {
+		resp.Body = data
+	}
+	if m.errorOnStatus && resp.StatusCode >= http.StatusBadRequest {
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
+
+func (m *MockClient) doStream(ctx context.Context, method string, stream chan StreamResponse, req Request, body []byte) error {
+	r, err := m.route(MockCall{Method: method, Request: req, Body: body})
+	if err != nil {
+		return err
+	}
+	if r.err != nil {
+		return r.err
+	}
+	headers := r.headers.Clone()
+	if r.status != http.StatusOK {
+		err := &HTTPError{StatusCode: r.status, Body: r.body, Headers: headers}
+		go func() {
+			defer close(stream)
+			stream <- StreamResponse{Type: StreamResponseTypeEOF, StatusCode: r.status, Headers: headers, Error: err, Body: r.body, Disconnect: statusReason(r.status)}
+		}()
+		return err
+	}
+
+	go func() {
+		defer close(stream)
+		var seq uint64
+		for _, frame := range r.frames {
+			if frame.Type == "" {
+				frame.Type = StreamResponseTypeData
+			}
+			if frame.Type == StreamResponseTypeData && frame.Seq == 0 {
+				seq++
+				frame.Seq = seq
+			}
+			if frame.StatusCode == 0 {
+				frame.StatusCode = http.StatusOK
+			}
+			if frame.Headers == nil {
+				frame.Headers = headers
+			}
+			select {
+			case stream <- frame:
+			case <-ctx.Done():
+				return
+			}
+			if frame.Type == StreamResponseTypeEOF {
+				return
+			}
+		}
+		select {
+		case stream <- StreamResponse{Type: StreamResponseTypeEOF, StatusCode: http.StatusOK, Headers: headers, Disconnect: StreamDisconnectEOF}:
+		case <-ctx.Done():
+		}
+	}()
+	return nil
+}
+
+func (m *MockClient) Get(ctx context.Context, req GetRequest) (*Response, error) {
+	return m.do(ctx, http.MethodGet, req.Request, nil)
+}
+
+func (m *MockClient) GetStream(ctx context.Context, stream chan StreamResponse, req Request) error {
+	return m.doStream(ctx, http.MethodGet, stream, req, nil)
+}
+
+func (m *MockClient) Post(ctx context.Context, req PostRequest) (*Response, error) {
+	return m.do(ctx, http.MethodPost, req.Request, req.Body)
+}
+
+func (m *MockClient) PostStream(ctx context.Context, stream chan StreamResponse, req PostRequest) error {
+	return m.doStream(ctx, http.MethodPost, stream, req.Request, req.Body)
+}
+
+func (m *MockClient) Put(ctx context.Context, req PutRequest) (*Response, error) {
+	return m.do(ctx, http.MethodPut, req.Request, req.Body)
+}
+
+func (m *MockClient) Patch(ctx context.Context, req PatchRequest) (*Response, error) {
+	return m.do(ctx, http.MethodPatch, req.Request, req.Body)
+}
+
+func (m *MockClient) Delete(ctx context.Context, req Request) (*Response, error) {
+	return m.do(ctx, http.MethodDelete, req, nil)
+}
+
+// WebSocket only replays errors set with RespondError on a GET route; a
+// MockClient cannot hold a socket open. Test message exchange against an
+// httptest server instead.
+func (m *MockClient) WebSocket(ctx context.Context, req Request) (*WebSocketConn, error) {
+	r, err := m.route(MockCall{Method: http.MethodGet, Request: req})
+	if err != nil {
+		return nil, err
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return nil, &HTTPError{StatusCode: r.status, Body: r.body, Headers: r.headers.Clone()}
+}
+
+// Fixture is one recorded exchange, as written by Recorder and replayed by
+// MockClient.LoadFixtures.
+type Fixture struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Query is the encoded query; when set, replay only matches requests
+	// with the same query.
+	Query   string      `json:"query,omitempty"`
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+	// Events holds the frames of a recorded stream.
+	Events []FixtureEvent `json:"events,omitempty"`
+	// Error is the message of a call that failed without a response.
+	Error string `json:"error,omitempty"`
+}
+
+// FixtureEvent is one recorded stream frame.
+type FixtureEvent struct {
+	Event string        `json:"event,omitempty"`
+	ID    string        `json:"id,omitempty"`
+	Data  string        `json:"data"`
+	Retry time.Duration `json:"retry,omitempty"`
+}
+
+// LoadFixtures registers a route per fixture in the JSON file at path. Each
+// answers once, so repeated exchanges replay in recorded order.
+func (m *MockClient) LoadFixtures(path string) error {
+	fixtures, err := readFixtures(path)
+	if err != nil {
+		return err
+	}
+	m.addFixtures(fixtures, false)
+	return nil
+}
+
+func readFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// addFixtures registers a route per fixture answering once; with sticky,
+// the last fixture for each request keeps answering after the rest were
+// used up, and fixtures with a query go first so that one without cannot
+// shadow them.
+func (m *MockClient) addFixtures(fixtures []Fixture, sticky bool) {
+	if sticky {
+		fixtures = append([]Fixture(nil), fixtures...)
+		sort.SliceStable(fixtures, func(i, j int) bool { return fixtures[i].Query != "" && fixtures[j].Query == "" })
+	}
+	last := make(map[string]int, len(fixtures))
+	for i, f := range fixtures {
+		last[f.Method+" "+f.Path+"?"+f.Query] = i
+	}
+	for i, f := range fixtures {
+		r := m.On(f.Method, f.Path)
+		if !sticky || last[f.Method+" "+f.Path+"?"+f.Query] != i {
+			r.Times(1)
+		}
+		if f.Query != "" {
+			query := f.Query
+			r.Match(func(call MockCall) bool { return call.Request.Query.Encode() == query })
+		}
+		if f.Error != "" {
+			r.RespondError(errors.New(f.Error))
+			continue
+		}
+		r.Respond(f.Status, []byte(f.Body))
+		r.headers = f.Headers
+		if f.Events != nil {
+			frames := make([]StreamResponse, len(f.Events))
+			for i, ev := range f.Events {
+				frames[i] = StreamResponse{Event: ev.Event, ID: ev.ID, Body: []byte(ev.Data), Retry: ev.Retry}
+			}
+			r.Stream(frames...)
+		}
+	}
+}
+
+// Recorder is a Client that passes calls to a real one and records each
+// exchange as a Fixture, so Save can write fixtures that MockClient replays.
+// WebSocket calls pass through unrecorded. A streamed Response is recorded
+// once the caller closes it.
+type Recorder struct {
+	Client
+
+	mu       sync.Mutex
+	fixtures []Fixture
+}
+
+// NewRecorder records the traffic of c.
+func NewRecorder(c Client) *Recorder {
+	return &Recorder{Client: c}
+}
+
+// Fixtures returns the exchanges recorded so far.
+func (r *Recorder) Fixtures() []Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Fixture(nil), r.fixtures...)
+}
+
+// Save writes the recorded fixtures to path as indented JSON.
+func (r *Recorder) Save(path string) error {
+	return SaveFixtures(path, r.Fixtures())
+}
+
+func (r *Recorder) add(f Fixture) {
+	r.mu.Lock()
+	r.fixtures = append(r.fixtures, f)
+	r.mu.Unlock()
+}
+
+func (r *Recorder) record(method string, req Request, resp *Response, err error) (*Response, error) {
+	f := Fixture{Method: method, Path: req.Path, Query: req.Query.Encode()}
+	switch {
+	case err != nil:
+		f.Error = err.Error()
+	case req.Stream:
+		// tee the live body into the fixture as the caller reads it
+		f.Status, f.Headers = resp.StatusCode, resp.Headers
+		resp.Reader = &recordingBody{ReadCloser: resp.Reader, done: func(body []byte) {
+			f.Body = string(body)
+			r.add(f)
+		}}
+		return resp, nil
+	default:
+		f.Status, f.Headers, f.Body = resp.StatusCode, resp.Headers, string(resp.Body)
+	}
+	r.add(f)
+	return resp, err
+}
+
+// recordingBody buffers what the caller reads and hands it over on Close.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+	return b.ReadCloser.Close()
+}
+
+// recordStream forwards frames from the real client to stream and records
+// them once the stream ends.
+func (r *Recorder) recordStream(method string, stream chan StreamResponse, req Request, call func(chan StreamResponse) error) error {
+	// the client sends a failed stream's single EOF frame before returning,
+	// so inner needs room for it
+	size := cap(stream)
+	if size == 0 {
+		size = 1
+	}
+	inner := make(chan StreamResponse, size)
+	if err := call(inner); err != nil {
+		f := Fixture{Method: method, Path: req.Path, Query: req.Query.Encode(), Error: err.Error()}
+		select {
+		case frame, ok := <-inner:
+			if ok {
+				f.Error, f.Status, f.Headers, f.Body = "", frame.StatusCode, frame.Headers, string(frame.Body)
+				go func() {
+					defer close(stream)
+					stream <- frame
+				}()
+			}
+		default:
+			// failed before sending: the client leaves the channel open
+		}
+		r.add(f)
+		return err
+	}
+
+	go func() {
+		defer close(stream)
+		f := Fixture{Method: method, Path: req.Path, Query: req.Query.Encode(), Status: http.StatusOK, Events: []FixtureEvent{}}
+		for frame := range inner {
+			if frame.Type == StreamResponseTypeData {
+				f.Events = append(f.Events, FixtureEvent{Event: frame.Event, ID: frame.ID, Data: string(frame.Body), Retry: frame.Retry})
+			}
+			if f.Headers == nil {
+				f.Headers = frame.Headers
+			}
+			stream <- frame
+		}
+		r.add(f)
+	}()
+	return nil
+}
+
+func (r *Recorder) Get(ctx context.Context, req GetRequest) (*Response, error) {
+	resp, err := r.Client.Get(ctx, req)
+	return r.record(http.MethodGet, req.Request, resp, err)
+}
+
+func (r *Recorder) GetStream(ctx context.Context, stream chan StreamResponse, req Request) error {
+	return r.recordStream(http.MethodGet, stream, req, func(inner chan StreamResponse) error {
+		return r.Client.GetStream(ctx, inner, req)
+	})
+}
+
+func (r *Recorder) Post(ctx context.Context, req PostRequest) (*Response, error) {
+	resp, err := r.Client.Post(ctx, req)
+	return r.record(http.MethodPost, req.Request, resp, err)
+}
+
+func (r *Recorder) PostStream(ctx context.Context, stream chan StreamResponse, req PostRequest) error {
+	return r.recordStream(http.MethodPost, stream, req.Request, func(inner chan StreamResponse) error {
+		return r.Client.PostStream(ctx, inner, req)
+	})
+}
+
+func (r *Recorder) Put(ctx context.Context, req PutRequest) (*Response, error) {
+	resp, err := r.Client.Put(ctx, req)
+	return r.record(http.MethodPut, req.Request, resp, err)
+}
+
+func (r *Recorder) Patch(ctx context.Context, req PatchRequest) (*Response, error) {
+	resp, err := r.Client.Patch(ctx, req)
+	return r.record(http.MethodPatch, req.Request, resp, err)
+}
+
+func (r *Recorder) Delete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := r.Client.Delete(ctx, req)
+	return r.record(http.MethodDelete, req, resp, err)
+}
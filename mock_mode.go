@@ -0,0 +1,74 @@
+package http
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MockEnvName names the environment variable that switches every client
+// NewClient builds to mock mode, its value taking the place of
+// MockConfig.Fixtures: HTTP_CLIENT_MOCK=./fixtures runs an app offline
+// without touching its config.
+const MockEnvName = "HTTP_CLIENT_MOCK"
+
+// MockConfig switches a client to mock mode for demos and offline
+// development: NewClient then returns a MockClient answering from fixture
+// files, as recorded by Recorder, instead of calling the network. Call
+// sites stay as they are.
+type MockConfig struct {
+	// Fixtures is a fixture file, or a directory whose *.json files are
+	// loaded in name order. Setting it enables mock mode.
+	Fixtures string `json:"fixtures"`
+}
+
+// fixtures returns where mock mode loads fixtures from, the environment
+// taking precedence, or "" when it is off.
+func (c MockConfig) fixtures() string {
+	if path := os.Getenv(MockEnvName); path != "" {
+		return path
+	}
+	return c.Fixtures
+}
+
+// newMockModeClient builds the client mock mode returns. Fixtures are
+// matched by method, path, and recorded query; those recorded for the same
+// request replay in order, the last one answering every call after. A
+// request no fixture matches fails with ErrNoMockRoute and is logged.
+func newMockModeClient(path string, errorOnStatus bool, logger Logger) *MockClient {
+	m := &MockClient{logger: logger, errorOnStatus: errorOnStatus}
+	fixtures, files, err := readFixtureFiles(path)
+	if err != nil {
+		logger.Error("http-client", "type", "config", "mock", path, "error", err)
+		return m
+	}
+	m.addFixtures(fixtures, true)
+	logger.Info("http-client", "type", "mock", "fixtures", path, "files", files, "routes", len(fixtures))
+	return m
+}
+
+// readFixtureFiles reads the fixture file at path, or every *.json file in
+// the directory at path, returning how many files it read.
+func readFixtureFiles(path string) ([]Fixture, int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, 0, fmt.Errorf("failed to list fixtures: %w", err)
+		}
+		sort.Strings(files)
+	}
+	var all []Fixture
+	for _, file := range files {
+		fixtures, err := readFixtures(file)
+		if err != nil {
+			return nil, 0, err
+		}
+		all = append(all, fixtures...)
+	}
+	return all, len(files), nil
+}
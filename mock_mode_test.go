@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_NewClient_MockMode(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a-users.json", `[
+		{"method": "GET", "path": "/users", "status": 200, "body": "[]"},
+		{"method": "GET", "path": "/users", "status": 200, "body": "[1]"},
+		{"method": "GET", "path": "/users", "query": "page=2", "status": 200, "body": "[2]"}
+	]`)
+	writeFile("b-orders.json", `[{"method": "POST", "path": "/orders", "status": 409, "body": "taken"}]`)
+	writeFile("notes.txt", `not fixtures`)
+
+	log := &recordingLogger{}
+	c := NewClient(Config{BaseURL: "http://127.0.0.1:1", Mock: MockConfig{Fixtures: dir}, ErrorOnStatus: true}, WithLogger(log))
+	ctx := context.Background()
+	get := func(query url.Values) string {
+		resp, err := c.Get(ctx, GetRequest{Request: Request{Path: "/users", Query: query}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(resp.Body)
+	}
+
+	// recorded answers replay in order, the last one repeating
+	for i, want := range []string{"[]", "[1]", "[1]"} {
+		if got := get(nil); got != want {
+			t.Fatalf("call %d = %s, want %s", i, got, want)
+		}
+	}
+	if got := get(url.Values{"page": {"2"}}); got != "[2]" {
+		t.Fatalf("page 2 = %s", got)
+	}
+
+	// ErrorOnStatus still turns failures into errors
+	var httpErr *HTTPError
+	if _, err := c.Post(ctx, PostRequest{Request: Request{Path: "/orders"}}); !errors.As(err, &httpErr) || httpErr.StatusCode != 409 {
+		t.Fatalf("err = %v, want a 409 HTTPError", err)
+	}
+
+	if _, err := c.Delete(ctx, Request{Path: "/users"}); !errors.Is(err, ErrNoMockRoute) || log.warns != 1 {
+		t.Fatalf("unmatched err = %v, warnings = %d", err, log.warns)
+	}
+}
+
+func Test_NewClient_MockMode_Env(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(file, []byte(`[{"method": "GET", "path": "/ping", "status": 200, "body": "pong"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(MockEnvName, file)
+
+	resp, err := NewClient(Config{BaseURL: "http://127.0.0.1:1"}).Get(context.Background(), GetRequest{Request: Request{Path: "/ping"}})
+	if err != nil || string(resp.Body) != "pong" {
+		t.Fatalf("resp = %+v, err = %v", resp, err)
+	}
+
+	t.Setenv(MockEnvName, filepath.Join(t.TempDir(), "missing"))
+	log := &recordingLogger{}
+	NewClient(Config{}, WithLogger(log))
+	if log.errs != 1 {
+		t.Fatalf("missing fixtures logged %d errors, want 1", log.errs)
+	}
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func Test_MockClient_CannedResponses(t *testing.T) {
+	m := NewMockClient()
+	m.On(http.MethodGet, "/users/1").RespondJSON(http.StatusOK, map[string]string{"name": "ann"})
+	m.On(http.MethodPost, "/users").Match(func(c MockCall) bool { return string(c.Body) == `{"bad":true}` }).Respond(http.StatusBadRequest, []byte("nope"))
+	m.On(http.MethodPost, "/users").Respond(http.StatusCreated, nil)
+	boom := errors.New("connection reset")
+	m.On(http.MethodDelete, "/users/1").RespondError(boom)
+
+	ctx := context.Background()
+	resp, err := m.Get(ctx, GetRequest{Request: Request{Path: "/users/1"}})
+	if err != nil || string(resp.Body) != `{"name":"ann"}` || resp.Headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("get: resp=%+v err=%v", resp, err)
+	}
+	if resp, _ := m.Post(ctx, PostRequest{Request: Request{Path: "/users"}, Body: []byte(`{"bad":true}`)}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("matched post status = %d", resp.StatusCode)
+	}
+	if resp, _ := m.Post(ctx, PostRequest{Request: Request{Path: "/users"}, Body: []byte(`{}`)}); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("fallback post status = %d", resp.StatusCode)
+	}
+	if _, err := m.Delete(ctx, Request{Path: "/users/1"}); !errors.Is(err, boom) {
+		t.Fatalf("delete err = %v", err)
+	}
+	if _, err := m.Put(ctx, PutRequest{Request: Request{Path: "/nowhere"}}); !errors.Is(err, ErrNoMockRoute) {
+		t.Fatalf("unmatched err = %v", err)
+	}
+	if calls := m.Calls(); len(calls) != 5 || calls[4].Method != http.MethodPut {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if err := m.AssertExpectations(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_MockClient_SequenceAndExpectations(t *testing.T) {
+	m := NewMockClient()
+	m.On(http.MethodGet, "/job").Respond(http.StatusAccepted, nil).Times(1)
+	m.On(http.MethodGet, "/job").Respond(http.StatusOK, []byte("done")).Times(1)
+	m.On(http.MethodGet, "/never")
+
+	for _, want := range []int{http.StatusAccepted, http.StatusOK} {
+		resp, err := m.Get(context.Background(), GetRequest{Request: Request{Path: "/job"}})
+		if err != nil || resp.StatusCode != want {
+			t.Fatalf("resp=%v err=%v want %d", resp, err, want)
+		}
+	}
+	if err := m.AssertExpectations(); err == nil {
+		t.Fatal("expected /never to be reported")
+	}
+}
+
+func Test_MockClient_ScriptedStream(t *testing.T) {
+	m := NewMockClient()
+	m.On(http.MethodPost, "/chat").Stream(
+		StreamResponse{Body: []byte("hel")},
+		StreamResponse{Body: []byte("lo"), Event: "delta", ID: "2"},
+	)
+	m.On(http.MethodGet, "/denied").Respond(http.StatusForbidden, []byte("no"))
+
+	stream := make(chan StreamResponse)
+	if err := m.PostStream(context.Background(), stream, PostRequest{Request: Request{Path: "/chat"}}); err != nil {
+		t.Fatal(err)
+	}
+	var frames []StreamResponse
+	for f := range stream {
+		frames = append(frames, f)
+	}
+	if len(frames) != 3 || string(frames[1].Body) != "lo" || frames[1].Event != "delta" || frames[1].Type != StreamResponseTypeData || frames[2].Type != StreamResponseTypeEOF {
+		t.Fatalf("frames = %+v", frames)
+	}
+
+	stream = make(chan StreamResponse, 1)
+	if err := m.GetStream(context.Background(), stream, Request{Path: "/denied"}); err == nil {
+		t.Fatal("expected a status error")
+	}
+	if f := <-stream; f.Type != StreamResponseTypeEOF || f.StatusCode != http.StatusForbidden {
+		t.Fatalf("frame = %+v", f)
+	}
+}
+
+func Test_Recorder_RecordAndReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "event: tick\nid: 1\ndata: a\n\ndata: b\n\n")
+		case "/file":
+			_, _ = io.WriteString(w, "streamed")
+		default:
+			w.Header().Set("X-Page", r.URL.Query().Get("page"))
+			_, _ = io.WriteString(w, "page "+r.URL.Query().Get("page"))
+		}
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder(newTestClient(t, srv.URL))
+	ctx := context.Background()
+	for _, page := range []string{"1", "2"} {
+		if _, err := rec.Get(ctx, GetRequest{Request: Request{Path: "/items", Query: url.Values{"page": {page}}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream := make(chan StreamResponse, 4)
+	if err := rec.GetStream(ctx, stream, Request{Path: "/events"}); err != nil {
+		t.Fatal(err)
+	}
+	for range stream {
+	}
+	resp, err := rec.Get(ctx, GetRequest{Request: Request{Path: "/file", Stream: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp)
+	_ = resp.Close()
+
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMockClient()
+	if err := m.LoadFixtures(path); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = m.Get(ctx, GetRequest{Request: Request{Path: "/items", Query: url.Values{"page": {"2"}}}})
+	if err != nil || string(resp.Body) != "page 2" || resp.Headers.Get("X-Page") != "2" {
+		t.Fatalf("replayed page 2: resp=%+v err=%v", resp, err)
+	}
+	if resp, err := m.Get(ctx, GetRequest{Request: Request{Path: "/file"}}); err != nil || string(resp.Body) != "streamed" {
+		t.Fatalf("replayed file: resp=%+v err=%v", resp, err)
+	}
+	stream = make(chan StreamResponse, 4)
+	if err := m.GetStream(ctx, stream, Request{Path: "/events"}); err != nil {
+		t.Fatal(err)
+	}
+	var data []string
+	for f := range stream {
+		if f.Type == StreamResponseTypeData {
+			data = append(data, f.Event+":"+string(f.Body))
+		}
+	}
+	if len(data) != 2 || data[0] != "tick:a" || data[1] != ":b" {
+		t.Fatalf("replayed events = %q", data)
+	}
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FilePart is one streamed file of a MultipartRequest.
+type FilePart struct {
+	// Field is the form field name the file is sent under.
+	Field string
+	// Name is the file name reported to the server.
+	Name string
+	// ContentType, when empty, is derived from Name's extension, then sniffed
+	// from the first 512 bytes of Reader.
+	ContentType string
+	// Header adds per-part headers (Content-ID, Content-Transfer-Encoding,
+	// ...). Content-Disposition and Content-Type are always set from the
+	// fields above.
+	Header textproto.MIMEHeader
+	// Reader is read once, while the request is sent; it is closed afterwards
+	// when it is an io.Closer.
+	Reader io.Reader
+}
+
+// MultipartRequest streams a multipart/form-data upload: fields first, then
+// each file part read straight from its Reader, so large files never sit in
+// memory. Set Request.OnProgress to follow the upload. Unlike FormRequest it
+// cannot be retried or signed, as the body is produced once.
+type MultipartRequest struct {
+	Request
+
+	Fields map[string]any
+	Files  []FilePart
+}
+
+// PostRequest turns the upload into a PostRequest whose BodyReader produces
+// the multipart body on demand. Convert it to PutRequest or PatchRequest to
+// send it with another verb.
+func (m MultipartRequest) PostRequest() PostRequest {
+	body := &multipartBody{req: m}
+	body.mw = multipart.NewWriter(io.Discard)
+
+	req := m.Request
+	headers := make(map[string]string, len(req.Headers)+1)
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	headers["Content-Type"] = body.mw.FormDataContentType()
+	req.Headers = headers
+	req.BodyReader = body
+	return PostRequest{Request: req}
+}
+
+// PostMultipart streams m to the server with a POST.
+func PostMultipart(ctx context.Context, c Client, m MultipartRequest) (*Response, error) {
+	return c.Post(ctx, m.PostRequest())
+}
+
+// multipartBody encodes the parts through a pipe. The encoding goroutine only
+// starts on the first Read, so a request that is never sent leaks nothing.
+type multipartBody struct {
+	req  MultipartRequest
+	mw   *multipart.Writer
+	once sync.Once
+	pr   *io.PipeReader
+}
+
+func (b *multipartBody) start() {
+	pr, pw := io.Pipe()
+	b.pr = pr
+	// the writer chose the boundary already advertised in Content-Type
+	mw := multipart.NewWriter(pw)
+	if err := mw.SetBoundary(b.mw.Boundary()); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+	go func() {
+		pw.CloseWithError(b.req.encode(mw))
+	}()
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(b.start)
+	return b.pr.Read(p)
+}
+
+func (b *multipartBody) Close() error {
+	b.once.Do(func() {})
+	for _, f := range b.req.Files {
+		if c, ok := f.Reader.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+	if b.pr != nil {
+		return b.pr.Close()
+	}
+	return nil
+}
+
+func (m MultipartRequest) encode(mw *multipart.Writer) error {
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := formValue(m.Fields[k])
+		if err != nil {
+			return fmt.Errorf("failed to encode form field %q: %w", k, err)
+		}
+		if err := mw.WriteField(k, value); err != nil {
+			return fmt.Errorf("failed to write form field %q: %w", k, err)
+		}
+	}
+
+	for _, file := range m.Files {
+		if err := writeFilePart(mw, file); err != nil {
+			return err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart body: %w", err)
+	}
+	return nil
+}
+
+func writeFilePart(mw *multipart.Writer, file FilePart) error {
+	reader := file.Reader
+	if reader == nil {
+		return fmt.Errorf("form file %q has no reader", file.Name)
+	}
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(file.Name))
+	}
+	if contentType == "" {
+		br := bufio.NewReaderSize(reader, sniffLen)
+		head, _ := br.Peek(sniffLen)
+		contentType = http.DetectContentType(head)
+		reader = br
+	}
+
+	header := make(textproto.MIMEHeader, len(file.Header)+2)
+	for k, v := range file.Header {
+		header[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(file.Field), quoteEscaper.Replace(file.Name)))
+	header.Set("Content-Type", contentType)
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("failed to create form file %q: %w", file.Name, err)
+	}
+	if _, err := io.Copy(part, reader); err != nil {
+		return fmt.Errorf("failed to write form file %q: %w", file.Name, err)
+	}
+	return nil
+}
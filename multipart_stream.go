@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// streamPartMaxSize caps one part of a multipart stream, which is buffered
+// whole into its frame.
+const streamPartMaxSize = 64 << 20
+
+// ErrStreamPartTooLarge ends a multipart stream whose part exceeds 64 MiB.
+var ErrStreamPartTooLarge = errors.New("multipart stream part too large")
+
+// readParts delivers each part of a multipart/x-mixed-replace or
+// multipart/byteranges response as a DATA frame carrying the part's
+// headers (Content-Type, Content-Range, ...) and body, then sends a
+// terminal EOF frame and closes stream. Such streams are not resumed.
+func (h httpClient) readParts(ctx context.Context, stream chan StreamResponse, resp *http.Response, boundary string, observer *streamObserver, logCtx []any) {
+	defer close(stream)
+	ended := false
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		err := &StreamPanicError{Value: rec, Stack: debug.Stack()}
+		h.logger.Error("http-client", logArgs(logCtx, "stream", "panicked", "panic", fmt.Sprint(rec), "stack", string(err.Stack))...)
+		if ended {
+			return
+		}
+		resp.Body.Close()
+		stream <- StreamResponse{Type: StreamResponseTypeEOF, StatusCode: resp.StatusCode, Headers: resp.Header, Error: err, Disconnect: StreamDisconnectPanic}
+		observer.end(resp.StatusCode, StreamDisconnectPanic)
+	}()
+
+	resp.Body = watchIdle(resp.Body, h.stream.IdleTimeout)
+	defer resp.Body.Close()
+	reader := multipart.NewReader(resp.Body, boundary)
+	var seq uint64
+	for {
+		body, headers, err := nextStreamPart(reader)
+		if err == nil {
+			seq++
+			stream <- StreamResponse{
+				Type:       StreamResponseTypeData,
+				StatusCode: resp.StatusCode,
+				Headers:    headers,
+				Body:       body,
+				Seq:        seq,
+			}
+			observer.event()
+			h.logger.Debug("http-client", logArgs(logCtx, "part", seq, "content-type", headers.Get("Content-Type"), "size", len(body))...)
+			continue
+		}
+
+		ended = true
+		if errors.Is(err, io.EOF) {
+			stream <- StreamResponse{Type: StreamResponseTypeEOF, StatusCode: resp.StatusCode, Headers: resp.Header, Disconnect: StreamDisconnectEOF}
+			observer.end(resp.StatusCode, StreamDisconnectEOF)
+			return
+		}
+		err = fmt.Errorf("failed to read multipart stream: %w", err)
+		reason := readErrorReason(ctx.Err(), err)
+		stream <- StreamResponse{Type: StreamResponseTypeEOF, StatusCode: resp.StatusCode, Headers: resp.Header, Error: err, Disconnect: reason}
+		h.logger.Error("http-client", logArgs(logCtx, "stream", "ended-with-error", "error", err, "disconnect", reason)...)
+		observer.end(resp.StatusCode, reason)
+		return
+	}
+}
+
+// nextStreamPart reads the next part whole.
+func nextStreamPart(reader *multipart.Reader) ([]byte, http.Header, error) {
+	part, err := reader.NextRawPart()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer part.Close()
+	body, err := io.ReadAll(io.LimitReader(part, streamPartMaxSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) > streamPartMaxSize {
+		return nil, nil, ErrStreamPartTooLarge
+	}
+	return body, http.Header(part.Header), nil
+}
+
+// hasHeader reports whether headers sets name, whatever its case.
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_GetStream_MixedReplace(t *testing.T) {
+	var accept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mw.Boundary())
+		for i := 1; i <= 3; i++ {
+			part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"image/jpeg"}, "X-Frame": {fmt.Sprint(i)}})
+			fmt.Fprintf(part, "jpeg-%d", i)
+			w.(http.Flusher).Flush()
+		}
+		_ = mw.Close()
+	}))
+	defer srv.Close()
+
+	stream := make(chan StreamResponse, 8)
+	err := NewClient(Config{BaseURL: srv.URL}).GetStream(context.Background(), stream, Request{Path: "/camera", Headers: map[string]string{"accept": ContentTypeMixedReplace}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frames []string
+	var last StreamResponse
+	for msg := range stream {
+		if msg.Type == StreamResponseTypeData {
+			frames = append(frames, fmt.Sprintf("%d %s %s %s", msg.Seq, msg.Headers.Get("Content-Type"), msg.Headers.Get("X-Frame"), msg.Body))
+		}
+		last = msg
+	}
+	want := []string{"1 image/jpeg 1 jpeg-1", "2 image/jpeg 2 jpeg-2", "3 image/jpeg 3 jpeg-3"}
+	if fmt.Sprint(frames) != fmt.Sprint(want) {
+		t.Fatalf("frames = %q", frames)
+	}
+	if last.Type != StreamResponseTypeEOF || last.Error != nil || last.Disconnect != StreamDisconnectEOF {
+		t.Fatalf("terminal frame = %+v", last)
+	}
+	if accept != ContentTypeMixedReplace {
+		t.Fatalf("Accept = %q", accept)
+	}
+}
+
+func Test_GetStream_ByteRanges(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data.txt", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer srv.Close()
+
+	stream := make(chan StreamResponse, 8)
+	err := NewClient(Config{BaseURL: srv.URL}).GetStream(context.Background(), stream, Request{Headers: map[string]string{"Range": "bytes=0-1,5-6"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []string
+	for msg := range stream {
+		if msg.Type == StreamResponseTypeData {
+			if msg.StatusCode != http.StatusPartialContent {
+				t.Errorf("status = %d", msg.StatusCode)
+			}
+			parts = append(parts, msg.Headers.Get("Content-Range")+" "+string(msg.Body))
+		}
+	}
+	if want := []string{"bytes 0-1/10 01", "bytes 5-6/10 56"}; fmt.Sprint(parts) != fmt.Sprint(want) {
+		t.Fatalf("parts = %q", parts)
+	}
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type closeTracker struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func Test_PostMultipart_StreamsParts(t *testing.T) {
+	type part struct{ field, name, contentType, contentID, body string }
+	var (
+		parts  []part
+		fields = map[string]string{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("MultipartReader: %v", err)
+			return
+		}
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("NextPart: %v", err)
+				return
+			}
+			body, _ := io.ReadAll(p)
+			if p.FileName() == "" {
+				fields[p.FormName()] = string(body)
+				continue
+			}
+			parts = append(parts, part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), p.Header.Get("Content-Id"), string(body)})
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	big := strings.Repeat("0123456789", 100_000)
+	tracked := &closeTracker{Reader: strings.NewReader("%PDF-1.4 fake")}
+	var lastSent int64
+	req := MultipartRequest{
+		Request: Request{
+			Path:       "/upload",
+			OnProgress: func(sent, total int64) { lastSent = sent },
+		},
+		Fields: map[string]any{"title": "report", "pages": 3},
+		Files: []FilePart{
+			{Field: "data", Name: "data.json", Reader: strings.NewReader(`{"a":1}`)},
+			{Field: "doc", Name: "doc", Reader: tracked, Header: map[string][]string{"Content-ID": {"<doc>"}}},
+			{Field: "blob", Name: "blob.bin", ContentType: "application/x-blob", Reader: strings.NewReader(big)},
+		},
+	}
+
+	resp, err := PostMultipart(context.Background(), newTestClient(t, srv.URL), req)
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PostMultipart: %v %v", resp, err)
+	}
+
+	if fields["title"] != "report" || fields["pages"] != "3" {
+		t.Fatalf("fields: %v", fields)
+	}
+	want := []part{
+		{"data", "data.json", "application/json", "", `{"a":1}`},
+		{"doc", "doc", "application/pdf", "<doc>", "%PDF-1.4 fake"},
+		{"blob", "blob.bin", "application/x-blob", "", big},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("got %d parts want %d", len(parts), len(want))
+	}
+	for i := range want {
+		if parts[i] != want[i] {
+			t.Errorf("part %d: got %s %s %s (%d bytes)", i, parts[i].field, parts[i].contentType, parts[i].contentID, len(parts[i].body))
+		}
+	}
+	if lastSent < int64(len(big)) {
+		t.Fatalf("progress reported %d bytes, want at least %d", lastSent, len(big))
+	}
+	if !tracked.closed.Load() {
+		t.Fatal("file reader not closed after the upload")
+	}
+}
+
+func Test_BodyReader_NotRetriedOrSigned(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Retry: RetryConfig{MaxAttempts: 3}})
+	resp, err := c.Put(context.Background(), PutRequest{Request: Request{BodyReader: strings.NewReader("once")}})
+	if err != nil || hits.Load() != 1 || len(resp.Attempts) != 1 {
+		t.Fatalf("streamed body retried: hits=%d err=%v", hits.Load(), err)
+	}
+
+	signing := newTestClient(t, srv.URL, WithSigningKey(SigningKey{ID: "k", Secret: "s"}))
+	_, err = signing.Post(context.Background(), PostRequest{Request: Request{BodyReader: strings.NewReader("x")}})
+	if !errors.Is(err, errStreamedBodySigning) {
+		t.Fatalf("err = %v, want errStreamedBodySigning", err)
+	}
+}
+
+func Test_OnProgress_ByteBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.ContentLength != 5 {
+			t.Errorf("ContentLength: got %d want 5", r.ContentLength)
+		}
+	}))
+	defer srv.Close()
+
+	var sent, total int64
+	req := PostRequest{Request: Request{OnProgress: func(s, t int64) { sent, total = s, t }}, Body: []byte("hello")}
+	if _, err := newTestClient(t, srv.URL).Post(context.Background(), req); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if sent != 5 || total != 5 {
+		t.Fatalf("progress: sent=%d total=%d", sent, total)
+	}
+}
@@ -0,0 +1,293 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrOpenAPIViolation is wrapped by every error OpenAPI validation returns.
+var ErrOpenAPIViolation = errors.New("request does not match OpenAPI spec")
+
+// OpenAPIMode selects what the client does with a request the spec does not
+// describe.
+type OpenAPIMode int
+
+const (
+	// OpenAPIWarn logs the violation at Warn and sends the request anyway.
+	OpenAPIWarn OpenAPIMode = iota
+	// OpenAPIEnforce fails the call with ErrOpenAPIViolation before sending.
+	OpenAPIEnforce
+)
+
+// OpenAPISpec is the part of an OpenAPI 3 document the client validates
+// against: operations, their parameters, and their request bodies.
+type OpenAPISpec struct {
+	basePath   string
+	operations []openAPIOperation
+}
+
+type openAPIOperation struct {
+	method   string
+	segments []string
+	params   []openAPIParameter
+	body     *openAPIRequestBody
+}
+
+type openAPIParameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Ref      string `json:"$ref"`
+	Schema   struct {
+		Type string `json:"type"`
+	} `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                       `json:"required"`
+	Content  map[string]json.RawMessage `json:"content"`
+	Ref      string                     `json:"$ref"`
+}
+
+type openAPIRawOperation struct {
+	Parameters  []openAPIParameter  `json:"parameters"`
+	RequestBody *openAPIRequestBody `json:"requestBody"`
+}
+
+type openAPIDocument struct {
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters    map[string]openAPIParameter   `json:"parameters"`
+		RequestBodies map[string]openAPIRequestBody `json:"requestBodies"`
+	} `json:"components"`
+}
+
+// ParseOpenAPI reads an OpenAPI 3 document in JSON form. Local $refs to
+// components/parameters and components/requestBodies are resolved; schemas
+// are only consulted for parameter types.
+func ParseOpenAPI(doc []byte) (*OpenAPISpec, error) {
+	var raw openAPIDocument
+	if err := json.Unmarshal(doc, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	spec := &OpenAPISpec{}
+	if len(raw.Servers) > 0 {
+		if u, err := url.Parse(raw.Servers[0].URL); err == nil {
+			spec.basePath = strings.TrimSuffix(u.Path, "/")
+		}
+	}
+
+	resolveParams := func(params []openAPIParameter) ([]openAPIParameter, error) {
+		out := make([]openAPIParameter, 0, len(params))
+		for _, p := range params {
+			if p.Ref != "" {
+				ref, ok := raw.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+				if !ok {
+					return nil, fmt.Errorf("unresolved parameter $ref %q", p.Ref)
+				}
+				p = ref
+			}
+			out = append(out, p)
+		}
+		return out, nil
+	}
+
+	for path, item := range raw.Paths {
+		var shared []openAPIParameter
+		if rawParams, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(rawParams, &shared); err != nil {
+				return nil, fmt.Errorf("failed to parse parameters of %s: %w", path, err)
+			}
+		}
+		for method, rawOp := range item {
+			method = strings.ToUpper(method)
+			switch method {
+			case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions:
+			default:
+				continue
+			}
+			var op openAPIRawOperation
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return nil, fmt.Errorf("failed to parse %s %s: %w", method, path, err)
+			}
+			params, err := resolveParams(append(append([]openAPIParameter(nil), shared...), op.Parameters...))
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			body := op.RequestBody
+			if body != nil && body.Ref != "" {
+				ref, ok := raw.Components.RequestBodies[strings.TrimPrefix(body.Ref, "#/components/requestBodies/")]
+				if !ok {
+					return nil, fmt.Errorf("%s %s: unresolved requestBody $ref %q", method, path, body.Ref)
+				}
+				body = &ref
+			}
+			spec.operations = append(spec.operations, openAPIOperation{
+				method:   method,
+				segments: strings.Split(strings.Trim(path, "/"), "/"),
+				params:   params,
+				body:     body,
+			})
+		}
+	}
+	return spec, nil
+}
+
+// WithOpenAPIValidation checks every outgoing request against spec: the
+// method and path must be a documented operation, required path, query, and
+// header parameters must be present and of the declared primitive type, and
+// the body must be present when required, of a documented content type, and
+// well-formed when JSON. A streamed Request.BodyReader is only checked for its
+// content type.
+func WithOpenAPIValidation(spec *OpenAPISpec, mode OpenAPIMode) Option {
+	return func(h *httpClient) {
+		h.openAPI = spec
+		h.openAPIMode = mode
+	}
+}
+
+// validateOpenAPI applies the configured spec: in warn mode violations are
+// logged and swallowed, in enforce mode they fail the call.
+func (h httpClient) validateOpenAPI(r *http.Request, body []byte) error {
+	if h.openAPI == nil {
+		return nil
+	}
+	err := h.openAPI.Validate(r, body)
+	if err != nil && h.openAPIMode == OpenAPIWarn {
+		h.logger.Warn("http-client", "type", "openapi", "method", r.Method, "url", r.URL.String(), "error", err)
+		return nil
+	}
+	return err
+}
+
+// Validate checks one request against the spec and returns every problem
+// found, wrapped in ErrOpenAPIViolation.
+func (s *OpenAPISpec) Validate(r *http.Request, body []byte) error {
+	path := strings.TrimPrefix(r.URL.Path, s.basePath)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var (
+		op          *openAPIOperation
+		pathValues  map[string]string
+		pathMatched bool
+	)
+	for i := range s.operations {
+		values, ok := s.operations[i].match(segments)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if s.operations[i].method == r.Method {
+			op, pathValues = &s.operations[i], values
+			break
+		}
+	}
+	if op == nil {
+		if pathMatched {
+			return fmt.Errorf("%w: method %s is not documented for %s", ErrOpenAPIViolation, r.Method, r.URL.Path)
+		}
+		return fmt.Errorf("%w: undocumented endpoint %s %s", ErrOpenAPIViolation, r.Method, r.URL.Path)
+	}
+
+	var problems []string
+	query := r.URL.Query()
+	for _, p := range op.params {
+		var value string
+		var present bool
+		switch p.In {
+		case "path":
+			value, present = pathValues[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		default:
+			continue
+		}
+		if !present {
+			if p.Required {
+				problems = append(problems, fmt.Sprintf("missing required %s parameter %q", p.In, p.Name))
+			}
+			continue
+		}
+		if !matchesPrimitive(p.Schema.Type, value) {
+			problems = append(problems, fmt.Sprintf("%s parameter %q is not a valid %s", p.In, p.Name, p.Schema.Type))
+		}
+	}
+
+	// a streamed body (Request.BodyReader) is present but unread
+	streamed := body == nil && r.Body != nil && r.Body != http.NoBody
+	switch {
+	case op.body == nil:
+	case len(body) == 0 && !streamed:
+		if op.body.Required {
+			problems = append(problems, "missing required request body")
+		}
+	default:
+		contentType := r.Header.Get("Content-Type")
+		mediaType, _ := ParseMediaType(contentType)
+		if _, ok := op.body.Content[mediaType]; !ok && len(op.body.Content) > 0 {
+			problems = append(problems, fmt.Sprintf("content type %q is not documented", mediaType))
+		} else if !streamed && IsJSON(contentType) && !json.Valid(body) {
+			problems = append(problems, "request body is not valid JSON")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s %s: %s", ErrOpenAPIViolation, r.Method, r.URL.Path, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// match reports whether segments fit the operation's path template and
+// returns the values of its {placeholders}.
+func (o *openAPIOperation) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(o.segments) {
+		return nil, false
+	}
+	values := make(map[string]string)
+	for i, tmpl := range o.segments {
+		if strings.HasPrefix(tmpl, "{") && strings.HasSuffix(tmpl, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			value, err := url.PathUnescape(segments[i])
+			if err != nil {
+				value = segments[i]
+			}
+			values[tmpl[1:len(tmpl)-1]] = value
+			continue
+		}
+		if tmpl != segments[i] {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+func matchesPrimitive(typ, value string) bool {
+	switch typ {
+	case "integer":
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case "number":
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case "boolean":
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	default:
+		return true
+	}
+}
@@ -0,0 +1,378 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// openAPIMethods are the operations OpenAPIFixtures reads, in the order it
+// emits them per path.
+var openAPIMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
+type openAPIExampleParameter struct {
+	Name     string                    `json:"name"`
+	In       string                    `json:"in"`
+	Ref      string                    `json:"$ref"`
+	Example  json.RawMessage           `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+	Schema   json.RawMessage           `json:"schema"`
+}
+
+type openAPIExample struct {
+	Ref   string          `json:"$ref"`
+	Value json.RawMessage `json:"value"`
+}
+
+type openAPIMedia struct {
+	Example  json.RawMessage           `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+	Schema   json.RawMessage           `json:"schema"`
+}
+
+type openAPIHeader struct {
+	Ref      string                    `json:"$ref"`
+	Example  json.RawMessage           `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+	Schema   json.RawMessage           `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Ref     string                   `json:"$ref"`
+	Headers map[string]openAPIHeader `json:"headers"`
+	Content map[string]openAPIMedia  `json:"content"`
+}
+
+type openAPIExampleOperation struct {
+	Parameters []openAPIExampleParameter  `json:"parameters"`
+	Responses  map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIExampleDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIExampleParameter `json:"parameters"`
+		Responses  map[string]openAPIResponse         `json:"responses"`
+		Headers    map[string]openAPIHeader           `json:"headers"`
+		Examples   map[string]openAPIExample          `json:"examples"`
+		Schemas    map[string]json.RawMessage         `json:"schemas"`
+	} `json:"components"`
+}
+
+// OpenAPIFixtures reads the examples of an OpenAPI 3 document in JSON form
+// into one Fixture per operation, to bootstrap tests of a new integration
+// with MockClient, mock mode, or FixtureHandler instead of hand-written
+// payloads. Each fixture answers with the operation's first 2xx response
+// (else its default, else its first), its body the example of the JSON
+// media type if there is one: the media type's example, its first named
+// example, or its schema's example, local $refs resolved. Documented
+// response headers with examples are set too.
+//
+// Paths are relative to the document's server, as requests made with a
+// BaseURL pointing at it use them; path parameters take their examples, or
+// 1 for numbers and their name otherwise. Queries are left open, so a
+// fixture answers whatever query is sent.
+func OpenAPIFixtures(doc []byte) ([]Fixture, error) {
+	var raw openAPIExampleDocument
+	if err := json.Unmarshal(doc, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	paths := make([]string, 0, len(raw.Paths))
+	for path := range raw.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var fixtures []Fixture
+	for _, path := range paths {
+		item := raw.Paths[path]
+		var shared []openAPIExampleParameter
+		if rawParams, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(rawParams, &shared); err != nil {
+				return nil, fmt.Errorf("failed to parse parameters of %s: %w", path, err)
+			}
+		}
+		ops := make(map[string]json.RawMessage, len(item))
+		for method, rawOp := range item {
+			ops[strings.ToUpper(method)] = rawOp
+		}
+		for _, method := range openAPIMethods {
+			rawOp, ok := ops[method]
+			if !ok {
+				continue
+			}
+			var op openAPIExampleOperation
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return nil, fmt.Errorf("failed to parse %s %s: %w", method, path, err)
+			}
+			fixture, err := raw.fixture(method, path, append(append([]openAPIExampleParameter(nil), shared...), op.Parameters...), op.Responses)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			fixtures = append(fixtures, fixture)
+		}
+	}
+	return fixtures, nil
+}
+
+func (d *openAPIExampleDocument) fixture(method, path string, params []openAPIExampleParameter, responses map[string]openAPIResponse) (Fixture, error) {
+	values := make(map[string]string)
+	for _, p := range params {
+		if p.Ref != "" {
+			ref, ok := d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			if !ok {
+				return Fixture{}, fmt.Errorf("unresolved parameter $ref %q", p.Ref)
+			}
+			p = ref
+		}
+		if p.In == "path" {
+			values[p.Name] = d.parameterValue(p)
+		}
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if value, ok := values[seg[1:len(seg)-1]]; ok {
+				segments[i] = value
+			}
+		}
+	}
+
+	f := Fixture{Method: method, Path: strings.Join(segments, "/"), Status: http.StatusOK}
+	code, ok := preferredResponse(responses)
+	if !ok {
+		return f, nil
+	}
+	resp := responses[code]
+	if resp.Ref != "" {
+		ref, found := d.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+		if !found {
+			return Fixture{}, fmt.Errorf("unresolved response $ref %q", resp.Ref)
+		}
+		resp = ref
+	}
+	if status, err := strconv.Atoi(strings.Replace(code, "XX", "00", 1)); err == nil {
+		f.Status = status
+	}
+
+	names := make([]string, 0, len(resp.Headers))
+	for name := range resp.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := resp.Headers[name]
+		if header.Ref != "" {
+			header = d.Components.Headers[strings.TrimPrefix(header.Ref, "#/components/headers/")]
+		}
+		if example := d.example(header.Example, header.Examples, header.Schema); example != nil {
+			if f.Headers == nil {
+				f.Headers = make(http.Header)
+			}
+			f.Headers.Set(name, exampleText(example))
+		}
+	}
+
+	mediaType, media, ok := preferredMedia(resp.Content)
+	if !ok {
+		return f, nil
+	}
+	if f.Headers == nil {
+		f.Headers = make(http.Header)
+	}
+	f.Headers.Set("Content-Type", mediaType)
+	if example := d.example(media.Example, media.Examples, media.Schema); example != nil {
+		var compact bytes.Buffer
+		if IsJSON(mediaType) && json.Compact(&compact, example) == nil {
+			f.Body = compact.String()
+		} else {
+			f.Body = exampleText(example)
+		}
+	}
+	return f, nil
+}
+
+// preferredResponse picks the status code a fixture answers with: the first
+// success, the default, or the first documented.
+func preferredResponse(responses map[string]openAPIResponse) (string, bool) {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return "", false
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			return code, true
+		}
+	}
+	if _, ok := responses["default"]; ok {
+		return "default", true
+	}
+	return codes[0], true
+}
+
+// preferredMedia picks the JSON media type of content, or the first.
+func preferredMedia(content map[string]openAPIMedia) (string, openAPIMedia, bool) {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	if len(types) == 0 {
+		return "", openAPIMedia{}, false
+	}
+	sort.Strings(types)
+	for _, mediaType := range types {
+		if IsJSON(mediaType) {
+			return mediaType, content[mediaType], true
+		}
+	}
+	return types[0], content[types[0]], true
+}
+
+// example returns the first example found in example, the first of
+// examples by name, or the schema's example, nil when there is none.
+func (d *openAPIExampleDocument) example(example json.RawMessage, examples map[string]openAPIExample, schema json.RawMessage) json.RawMessage {
+	if len(example) > 0 {
+		return example
+	}
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ex := examples[name]
+		if ex.Ref != "" {
+			ex = d.Components.Examples[strings.TrimPrefix(ex.Ref, "#/components/examples/")]
+		}
+		if len(ex.Value) > 0 {
+			return ex.Value
+		}
+	}
+	return d.schemaExample(schema)
+}
+
+// schemaExample returns the example of schema, following a $ref to
+// components/schemas.
+func (d *openAPIExampleDocument) schemaExample(schema json.RawMessage) json.RawMessage {
+	for depth := 0; len(schema) > 0 && depth < 8; depth++ {
+		var s struct {
+			Ref     string          `json:"$ref"`
+			Example json.RawMessage `json:"example"`
+		}
+		if err := json.Unmarshal(schema, &s); err != nil {
+			return nil
+		}
+		if len(s.Example) > 0 || s.Ref == "" {
+			return s.Example
+		}
+		schema = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return nil
+}
+
+// parameterValue is the example of a path parameter, or a stand-in of its
+// type.
+func (d *openAPIExampleDocument) parameterValue(p openAPIExampleParameter) string {
+	if example := d.example(p.Example, p.Examples, p.Schema); example != nil {
+		return exampleText(example)
+	}
+	var schema struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(p.Schema, &schema)
+	switch schema.Type {
+	case "integer", "number":
+		return "1"
+	default:
+		return p.Name
+	}
+}
+
+// exampleText renders a JSON example as text: strings unquoted, anything
+// else as JSON.
+func exampleText(example json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(example, &s); err == nil {
+		return s
+	}
+	return string(example)
+}
+
+// LoadOpenAPI registers a route per operation of the OpenAPI document,
+// answering with its example as OpenAPIFixtures describes, as many times as
+// it is called.
+func (m *MockClient) LoadOpenAPI(doc []byte) error {
+	fixtures, err := OpenAPIFixtures(doc)
+	if err != nil {
+		return err
+	}
+	m.addFixtures(fixtures, true)
+	return nil
+}
+
+// SaveFixtures writes fixtures to path as indented JSON, the form
+// LoadFixtures, mock mode, and FixtureHandler read.
+func SaveFixtures(path string, fixtures []Fixture) error {
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixtures: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write fixtures: %w", err)
+	}
+	return nil
+}
+
+// FixtureHandler serves fixtures over HTTP as a stub server, for code that
+// cannot take a MockClient, e.g. under httptest.NewServer. Fixtures match
+// as in mock mode; a request none matches gets 404, a fixture with events
+// is written as an event stream, and one recording an error aborts the
+// connection.
+func FixtureHandler(fixtures []Fixture) http.Handler {
+	m := &MockClient{}
+	m.addFixtures(fixtures, true)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, err := m.route(MockCall{Method: r.Method, Request: Request{Path: r.URL.Path, Query: r.URL.Query()}})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if route.err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		for key, values := range route.headers {
+			w.Header()[key] = append([]string(nil), values...)
+		}
+		if route.frames == nil {
+			w.WriteHeader(route.status)
+			_, _ = w.Write(route.body)
+			return
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", ContentTypeEventStream)
+		}
+		w.WriteHeader(route.status)
+		for _, frame := range route.frames {
+			if frame.ID != "" {
+				_, _ = io.WriteString(w, "id: "+frame.ID+"\n")
+			}
+			if frame.Retry > 0 {
+				_, _ = io.WriteString(w, "retry: "+strconv.FormatInt(frame.Retry.Milliseconds(), 10)+"\n")
+			}
+			_, _ = io.WriteString(w, formatSSEEvent(frame.Event, frame.Body))
+		}
+	})
+}
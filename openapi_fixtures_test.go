@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const testExampleSpec = `{
+	"openapi": "3.0.3",
+	"servers": [{"url": "https://api.example.com/v1"}],
+	"paths": {
+		"/users": {
+			"get": {"responses": {
+				"200": {
+					"headers": {"X-Total-Count": {"schema": {"type": "integer", "example": 2}}},
+					"content": {
+						"application/xml": {"example": "<users/>"},
+						"application/json": {"examples": {
+							"two": {"$ref": "#/components/examples/TwoUsers"},
+							"zero": {"value": []}
+						}}
+					}
+				},
+				"500": {"$ref": "#/components/responses/Error"}
+			}},
+			"post": {"responses": {
+				"400": {"$ref": "#/components/responses/Error"},
+				"201": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}}
+			}}
+		},
+		"/users/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}, "example": 42}],
+			"delete": {"responses": {"204": {"description": "deleted"}}}
+		},
+		"/teams/{slug}/health": {
+			"get": {
+				"parameters": [{"name": "slug", "in": "path", "required": true, "schema": {"type": "string"}}],
+				"responses": {"default": {"content": {"text/plain": {"example": "ok"}}}}
+			}
+		}
+	},
+	"components": {
+		"examples": {"TwoUsers": {"value": [{"id": 1}, {"id": 2}]}},
+		"schemas": {"User": {"type": "object", "example": {"id": 3, "name": "ada"}}},
+		"responses": {"Error": {"content": {"application/json": {"example": {"error": "boom"}}}}}
+	}
+}`
+
+func Test_OpenAPIFixtures(t *testing.T) {
+	fixtures, err := OpenAPIFixtures([]byte(testExampleSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Fixture{
+		{Method: "GET", Path: "/teams/slug/health", Status: 200, Headers: http.Header{"Content-Type": {"text/plain"}}, Body: "ok"},
+		{Method: "GET", Path: "/users", Status: 200, Headers: http.Header{"Content-Type": {"application/json"}, "X-Total-Count": {"2"}}, Body: `[{"id":1},{"id":2}]`},
+		{Method: "POST", Path: "/users", Status: 201, Headers: http.Header{"Content-Type": {"application/json"}}, Body: `{"id":3,"name":"ada"}`},
+		{Method: "DELETE", Path: "/users/42", Status: 204},
+	}
+	if len(fixtures) != len(want) {
+		t.Fatalf("fixtures = %+v", fixtures)
+	}
+	for i, f := range fixtures {
+		w := want[i]
+		if f.Method != w.Method || f.Path != w.Path || f.Status != w.Status || f.Body != w.Body || len(f.Headers) != len(w.Headers) {
+			t.Fatalf("fixture %d = %+v, want %+v", i, f, w)
+		}
+		for key := range w.Headers {
+			if f.Headers.Get(key) != w.Headers.Get(key) {
+				t.Fatalf("fixture %d header %s = %q, want %q", i, key, f.Headers.Get(key), w.Headers.Get(key))
+			}
+		}
+	}
+}
+
+func Test_OpenAPIFixtures_UnresolvedRef(t *testing.T) {
+	doc := `{"paths": {"/x": {"get": {"responses": {"200": {"$ref": "#/components/responses/Missing"}}}}}}`
+	if _, err := OpenAPIFixtures([]byte(doc)); err == nil {
+		t.Fatal("expected an unresolved $ref error")
+	}
+}
+
+func Test_MockClient_LoadOpenAPI(t *testing.T) {
+	m := NewMockClient()
+	if err := m.LoadOpenAPI([]byte(testExampleSpec)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := m.Post(context.Background(), PostRequest{Request: Request{Path: "/users"}})
+		if err != nil || resp.StatusCode != http.StatusCreated || string(resp.Body) != `{"id":3,"name":"ada"}` {
+			t.Fatalf("call %d: resp=%+v err=%v", i, resp, err)
+		}
+	}
+}
+
+func Test_FixtureHandler(t *testing.T) {
+	fixtures, err := OpenAPIFixtures([]byte(testExampleSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures = append(fixtures, Fixture{Method: "GET", Path: "/events", Status: 200, Events: []FixtureEvent{{Event: "tick", ID: "1", Data: "a"}}})
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := SaveFixtures(path, fixtures); err != nil {
+		t.Fatal(err)
+	}
+	if fixtures, err = readFixtures(path); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(FixtureHandler(fixtures))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/users?page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Total-Count") != "2" || string(body) != `[{"id":1},{"id":2}]` {
+		t.Fatalf("status=%d headers=%v body=%q", resp.StatusCode, resp.Header, body)
+	}
+
+	resp, err = http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != ContentTypeEventStream || string(body) != "id: 1\nevent: tick\ndata: a\n\n" {
+		t.Fatalf("headers=%v body=%q", resp.Header, body)
+	}
+
+	resp, err = http.Get(srv.URL + "/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unmatched status = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+	"openapi": "3.0.3",
+	"servers": [{"url": "https://api.example.com/v1"}],
+	"paths": {
+		"/users": {
+			"get": {"parameters": [{"$ref": "#/components/parameters/Limit"}]},
+			"post": {"requestBody": {"$ref": "#/components/requestBodies/NewUser"}}
+		},
+		"/users/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+			"get": {"parameters": [
+				{"name": "expand", "in": "query", "schema": {"type": "boolean"}},
+				{"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string"}}
+			]}
+		}
+	},
+	"components": {
+		"parameters": {"Limit": {"name": "limit", "in": "query", "required": true, "schema": {"type": "integer"}}},
+		"requestBodies": {"NewUser": {"required": true, "content": {"application/json": {}}}}
+	}
+}`
+
+func Test_OpenAPISpec_Validate(t *testing.T) {
+	spec, err := ParseOpenAPI([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		headers map[string]string
+		body    string
+		want    string // substring of the error; empty means valid
+	}{
+		{name: "valid get with path param", method: "GET", url: "/v1/users/42?expand=true", headers: map[string]string{"X-Tenant": "t1"}},
+		{name: "valid post", method: "POST", url: "/v1/users", headers: map[string]string{"Content-Type": "application/json"}, body: `{"name":"ada"}`},
+		{name: "undocumented path", method: "GET", url: "/v1/admin", want: "undocumented endpoint"},
+		{name: "undocumented method", method: "DELETE", url: "/v1/users/42", want: "method DELETE is not documented"},
+		{name: "bad path param type", method: "GET", url: "/v1/users/abc", headers: map[string]string{"X-Tenant": "t1"}, want: `path parameter "id" is not a valid integer`},
+		{name: "missing ref'd query param", method: "GET", url: "/v1/users", want: `missing required query parameter "limit"`},
+		{name: "missing header", method: "GET", url: "/v1/users/1", want: `missing required header parameter "X-Tenant"`},
+		{name: "missing body", method: "POST", url: "/v1/users", want: "missing required request body"},
+		{name: "wrong content type", method: "POST", url: "/v1/users", headers: map[string]string{"Content-Type": "text/plain"}, body: "x", want: `content type "text/plain"`},
+		{name: "malformed json", method: "POST", url: "/v1/users", headers: map[string]string{"Content-Type": "application/json"}, body: "{", want: "not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.url, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			err := spec.Validate(r, []byte(tt.body))
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrOpenAPIViolation) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Client_OpenAPIValidation(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	spec, err := ParseOpenAPI([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI: %v", err)
+	}
+	req := GetRequest{Request: Request{Path: "/admin"}}
+
+	logger := &recordingLogger{}
+	warn := newTestClient(t, srv.URL+"/v1", WithOpenAPIValidation(spec, OpenAPIWarn), WithLogger(logger))
+	if _, err := warn.Get(context.Background(), req); err != nil || hits != 1 || logger.warns != 1 {
+		t.Fatalf("warn mode: err = %v hits = %d warns = %d", err, hits, logger.warns)
+	}
+
+	enforce := newTestClient(t, srv.URL+"/v1", WithOpenAPIValidation(spec, OpenAPIEnforce))
+	if _, err := enforce.Get(context.Background(), req); !errors.Is(err, ErrOpenAPIViolation) || hits != 1 {
+		t.Fatalf("enforce mode: err = %v hits = %d", err, hits)
+	}
+}
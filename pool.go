@@ -0,0 +1,172 @@
+package http
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats summarizes how the client's connections have been used since it
+// was built. A high NewConnections to ReusedConnections ratio points at
+// keep-alive misconfiguration (bodies not drained, idle limits too low, the
+// server closing connections).
+type PoolStats struct {
+	// Requests counts requests that obtained a connection.
+	Requests          int64
+	NewConnections    int64
+	ReusedConnections int64
+	// InUse counts connections currently carrying a request or an unread
+	// response body.
+	InUse int64
+	// Idle estimates connections parked in the idle pool. The transport does
+	// not report idle-timeout closes, so it can overcount on quiet clients.
+	Idle int64
+}
+
+// PoolStatsProvider is implemented by clients built with NewClient:
+//
+//	if p, ok := client.(http.PoolStatsProvider); ok { stats := p.PoolStats() }
+type PoolStatsProvider interface {
+	PoolStats() PoolStats
+}
+
+var _ PoolStatsProvider = httpClient{}
+
+// ConnMetrics describes the connection behind one request, reported through
+// MetricsHook.OnConnection. Phase durations are zero when the phase did not
+// run (DNS, Connect, and TLS are skipped on a reused connection).
+type ConnMetrics struct {
+	Method   string
+	URL      string
+	Reused   bool
+	WasIdle  bool
+	IdleTime time.Duration
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	// TimeToFirstByte is measured from the request starting to the first
+	// response byte.
+	TimeToFirstByte time.Duration
+}
+
+type poolCounters struct {
+	requests atomic.Int64
+	created  atomic.Int64
+	reused   atomic.Int64
+	inUse    atomic.Int64
+	idle     atomic.Int64
+}
+
+// PoolStats implements PoolStatsProvider.
+func (h httpClient) PoolStats() PoolStats {
+	if h.pool == nil {
+		return PoolStats{}
+	}
+	return PoolStats{
+		Requests:          h.pool.requests.Load(),
+		NewConnections:    h.pool.created.Load(),
+		ReusedConnections: h.pool.reused.Load(),
+		InUse:             h.pool.inUse.Load(),
+		Idle:              h.pool.idle.Load(),
+	}
+}
+
+// send performs httpReq through client with an httptrace attached, feeding
+// the pool counters and MetricsHook.OnConnection. The connection counts as in
+// use until the response body is closed.
+func (h httpClient) send(client *http.Client, httpReq *http.Request) (*http.Response, error) {
+	if h.pool == nil {
+		return client.Do(httpReq)
+	}
+
+	var (
+		mu      sync.Mutex
+		m       = ConnMetrics{Method: httpReq.Method, URL: h.redact.url(httpReq.URL.String())}
+		start   = time.Now()
+		phase   time.Time
+		gotConn bool
+	)
+	startPhase := func() { mu.Lock(); phase = time.Now(); mu.Unlock() }
+	endPhase := func(dst *time.Duration) { mu.Lock(); *dst = time.Since(phase); mu.Unlock() }
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { startPhase() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { endPhase(&m.DNS) },
+		ConnectStart:      func(string, string) { startPhase() },
+		ConnectDone:       func(string, string, error) { endPhase(&m.Connect) },
+		TLSHandshakeStart: startPhase,
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { endPhase(&m.TLS) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			gotConn = true
+			m.Reused, m.WasIdle, m.IdleTime = info.Reused, info.WasIdle, info.IdleTime
+			h.pool.requests.Add(1)
+			h.pool.inUse.Add(1)
+			if info.Reused {
+				h.pool.reused.Add(1)
+			} else {
+				h.pool.created.Add(1)
+			}
+			if info.WasIdle && h.pool.idle.Add(-1) < 0 {
+				h.pool.idle.Store(0)
+			}
+		},
+		PutIdleConn: func(err error) {
+			if err == nil {
+				h.pool.idle.Add(1)
+			}
+		},
+		GotFirstResponseByte: func() { mu.Lock(); m.TimeToFirstByte = time.Since(start); mu.Unlock() },
+	}
+	httpReq = httpReq.WithContext(httptrace.WithClientTrace(httpReq.Context(), trace))
+
+	resp, err := client.Do(httpReq)
+
+	mu.Lock()
+	acquired, metrics := gotConn, m
+	mu.Unlock()
+	if acquired && h.metrics.OnConnection != nil {
+		h.metrics.OnConnection(metrics)
+	}
+	if err != nil {
+		if acquired {
+			h.pool.inUse.Add(-1)
+		}
+		return nil, err
+	}
+	if acquired {
+		body := &releasingBody{ReadCloser: resp.Body, release: func() { h.pool.inUse.Add(-1) }}
+		if w, ok := resp.Body.(io.Writer); ok {
+			// a protocol switch (101) hands back a writable body
+			resp.Body = &releasingRWBody{releasingBody: body, w: w}
+		} else {
+			resp.Body = body
+		}
+	}
+	return resp, nil
+}
+
+// releasingBody runs release once, on the first Close.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// releasingRWBody keeps an upgraded connection's body writable.
+type releasingRWBody struct {
+	*releasingBody
+	w io.Writer
+}
+
+func (b *releasingRWBody) Write(p []byte) (int, error) { return b.w.Write(p) }
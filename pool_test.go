@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_Client_PoolStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	var conns []ConnMetrics
+	c := newTestClient(t, srv.URL,
+		WithHTTPClient(&http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}),
+		WithMetricsHook(MetricsHook{OnConnection: func(m ConnMetrics) { conns = append(conns, m) }}),
+	)
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+
+	stats := c.(PoolStatsProvider).PoolStats()
+	want := PoolStats{Requests: 3, NewConnections: 1, ReusedConnections: 2, InUse: 0, Idle: 1}
+	if stats != want {
+		t.Fatalf("PoolStats = %+v, want %+v", stats, want)
+	}
+	if len(conns) != 3 || conns[0].Reused || conns[0].Connect == 0 || !conns[1].Reused || !conns[1].WasIdle || conns[2].TimeToFirstByte == 0 {
+		t.Fatalf("conn metrics = %+v", conns)
+	}
+}
+
+func Test_Client_ConnMetrics_RedactsURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	var conn ConnMetrics
+	c := NewClient(Config{BaseURL: srv.URL, Redact: []string{"token"}},
+		WithHTTPClient(&http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}),
+		WithMetricsHook(MetricsHook{OnConnection: func(m ConnMetrics) { conn = m }}),
+	)
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", Query: url.Values{"token": {"secret"}}}}); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if strings.Contains(conn.URL, "secret") || !strings.Contains(conn.URL, "token=") {
+		t.Fatalf("conn metrics URL = %q, want the token redacted", conn.URL)
+	}
+}
+
+func Test_Client_PoolStats_StreamHoldsConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL, WithHTTPClient(&http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}))
+
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", Stream: true}})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := c.(PoolStatsProvider).PoolStats().InUse; got != 1 {
+		t.Fatalf("InUse while streaming = %d, want 1", got)
+	}
+	_, _ = io.ReadAll(resp)
+	_ = resp.Close()
+	if got := c.(PoolStatsProvider).PoolStats().InUse; got != 0 {
+		t.Fatalf("InUse after Close = %d, want 0", got)
+	}
+}
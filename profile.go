@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Profile holds the settings of one environment (dev, staging, prod, ...).
+// Selecting it with Config.Profile overlays them on the rest of Config, so
+// an app switches environments by name instead of rebuilding its config.
+type Profile struct {
+	// BaseURL replaces Config.BaseURL when set.
+	BaseURL string `json:"base_url"`
+	// Headers are added to Config.Headers, winning on conflicts.
+	Headers map[string]string `json:"headers"`
+	// Auth sets static credentials for the environment. WithAuth overrides
+	// it.
+	Auth ProfileAuth `json:"auth"`
+	// TLS replaces Config.TLS for the environment, e.g. a staging CA or a
+	// client certificate.
+	TLS TLSConfig `json:"tls"`
+}
+
+// ProfileAuth is the credentials of a Profile; the first one set is used.
+type ProfileAuth struct {
+	BearerToken  string `json:"bearer_token"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	APIKeyHeader string `json:"api_key_header"`
+	APIKey       string `json:"api_key"`
+}
+
+// provider returns the AuthProvider for a, or nil when a is empty.
+func (a ProfileAuth) provider() AuthProvider {
+	switch {
+	case a.BearerToken != "":
+		return BearerAuth(staticToken(a.BearerToken))
+	case a.Username != "":
+		return BasicAuth(a.Username, a.Password)
+	case a.APIKey != "":
+		header := a.APIKeyHeader
+		if header == "" {
+			header = "X-API-Key"
+		}
+		return APIKeyAuth(header, a.APIKey)
+	}
+	return nil
+}
+
+type staticToken string
+
+func (s staticToken) Token(context.Context) (string, error) { return string(s), nil }
+
+// ProfileNames lists the profiles of c, sorted, e.g. for a CLI flag's help.
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns c with the named profile selected, or an error naming
+// the known profiles when there is none by that name. "" selects none.
+func (c Config) WithProfile(name string) (Config, error) {
+	if name != "" {
+		if _, ok := c.Profiles[name]; !ok {
+			return c, fmt.Errorf("unknown profile %q (have %v)", name, c.ProfileNames())
+		}
+	}
+	c.Profile = name
+	return c, nil
+}
+
+// applyProfile overlays the selected profile on c. ok is false when
+// c.Profile names no profile.
+func (c Config) applyProfile() (Config, Profile, bool) {
+	if c.Profile == "" {
+		return c, Profile{}, true
+	}
+	p, ok := c.Profiles[c.Profile]
+	if !ok {
+		return c, Profile{}, false
+	}
+	if p.BaseURL != "" {
+		c.BaseURL = p.BaseURL
+	}
+	if len(p.Headers) > 0 {
+		headers := make(map[string]string, len(c.Headers)+len(p.Headers))
+		for k, v := range c.Headers {
+			headers[k] = v
+		}
+		for k, v := range p.Headers {
+			headers[k] = v
+		}
+		c.Headers = headers
+	}
+	if !p.TLS.empty() {
+		c.TLS = p.TLS
+	}
+	return c, p, true
+}
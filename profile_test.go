@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Client_Profile_Overlay(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	cfg := Config{
+		BaseURL: "http://prod.invalid",
+		Headers: map[string]string{"X-Env": "prod", "X-Team": "core"},
+		Profiles: map[string]Profile{
+			"dev":  {BaseURL: srv.URL, Headers: map[string]string{"X-Env": "dev"}, Auth: ProfileAuth{BearerToken: "dev-token"}},
+			"prod": {},
+		},
+	}
+	cfg, err := cfg.WithProfile("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(cfg).Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("X-Env") != "dev" || got.Get("X-Team") != "core" || got.Get("Authorization") != "Bearer dev-token" {
+		t.Fatalf("headers = %v", got)
+	}
+	if cfg.Headers["X-Env"] != "prod" {
+		t.Fatal("profile overlay mutated the config headers")
+	}
+
+	// WithAuth wins over the profile's credentials
+	if _, err := NewClient(cfg, WithAuth(BasicAuth("u", "p"))).Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("Authorization") != "Basic dTpw" {
+		t.Fatalf("Authorization = %q", got.Get("Authorization"))
+	}
+}
+
+func Test_Config_WithProfile_Unknown(t *testing.T) {
+	cfg := Config{Profiles: map[string]Profile{"staging": {}, "dev": {}}}
+	if _, err := cfg.WithProfile("qa"); err == nil || err.Error() != `unknown profile "qa" (have [dev staging])` {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func Test_Client_Profile_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{BaseURL: srv.URL, Profiles: map[string]Profile{
+		"trusted":  {TLS: TLSConfig{CAFile: caFile}},
+		"insecure": {TLS: TLSConfig{InsecureSkipVerify: true}},
+	}}
+	for _, name := range []string{"trusted", "insecure"} {
+		cfg.Profile = name
+		if _, err := NewClient(cfg).Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	cfg.Profile = ""
+	if _, err := NewClient(cfg).Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err == nil {
+		t.Fatal("expected the test CA to be untrusted without a profile")
+	}
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyRule routes requests for matching hosts through a proxy. Host is an
+// exact hostname ("api.example.com"), a domain suffix with a leading dot
+// (".internal" matches every subdomain), or "*" for every host. URL is the
+// proxy to use: http://, https://, or socks5:// (e.g. a local Tor endpoint).
+type ProxyRule struct {
+	Host string `json:"host"`
+	URL  string `json:"url"`
+}
+
+// matches reports whether the rule applies to host. Matching is
+// case-insensitive, mirroring how NO_PROXY entries are compared.
+func (r ProxyRule) matches(host string) bool {
+	pattern := strings.ToLower(r.Host)
+	host = strings.ToLower(host)
+	switch {
+	case pattern == "":
+		return false
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "."):
+		return strings.HasSuffix(host, pattern) || host == pattern[1:]
+	default:
+		return host == pattern
+	}
+}
+
+// ProxyConfig is the client-wide proxy. By default the client honors the
+// HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables, as net/http
+// does.
+type ProxyConfig struct {
+	// URL sends every request through this proxy: http://, https://, or
+	// socks5://. It replaces the environment variables.
+	URL string `json:"url"`
+	// NoProxy lists hosts reached directly, matched like ProxyRule.Host.
+	NoProxy []string `json:"no_proxy"`
+	// IgnoreEnvironment stops the client honoring the proxy environment
+	// variables, so one process can run clients with and without a proxy.
+	IgnoreEnvironment bool `json:"ignore_environment"`
+}
+
+func (c ProxyConfig) empty() bool {
+	return c.URL == "" && len(c.NoProxy) == 0 && !c.IgnoreEnvironment
+}
+
+// apply sets the transport's Proxy func from c.
+func (c ProxyConfig) apply(t *http.Transport) {
+	fallback := t.Proxy
+	if c.IgnoreEnvironment {
+		fallback = nil
+	}
+	var fixed *url.URL
+	if c.URL != "" {
+		// a malformed URL surfaces as an error on every request
+		fixed, _ = url.Parse(c.URL)
+		fallback = nil
+	}
+	t.Proxy = func(r *http.Request) (*url.URL, error) {
+		for _, host := range c.NoProxy {
+			if (ProxyRule{Host: host}).matches(r.URL.Hostname()) {
+				return nil, nil
+			}
+		}
+		switch {
+		case c.URL != "" && fixed == nil:
+			return nil, fmt.Errorf("failed to parse proxy URL %q", c.URL)
+		case fixed != nil:
+			return fixed, nil
+		case fallback != nil:
+			return fallback(r)
+		}
+		return nil, nil
+	}
+}
+
+// errProxyUnsupported is returned when a request asks for a proxy but the
+// injected *http.Client has a transport the client cannot reconfigure.
+var errProxyUnsupported = errors.New("proxy override requires an *http.Transport")
+
+type proxyContextKey struct{}
+
+// proxyRouter selects a proxy per request. It owns a clone of the base
+// transport whose Proxy func consults the per-request override first, then the
+// host rules, then whatever the base transport would have done.
+type proxyRouter struct {
+	rules  []ProxyRule
+	client *http.Client
+}
+
+// newProxyRouter clones base's transport so proxied requests get their own
+// connection pool. It returns nil when base's transport is not an
+// *http.Transport (e.g. a test stub), leaving proxying unavailable.
+func newProxyRouter(base *http.Client, rules []ProxyRule) *proxyRouter {
+	rt := base.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return nil
+	}
+
+	p := &proxyRouter{rules: rules}
+	fallback := transport.Proxy
+	clone := transport.Clone()
+	clone.Proxy = func(r *http.Request) (*url.URL, error) {
+		if raw, ok := r.Context().Value(proxyContextKey{}).(string); ok && raw != "" {
+			return url.Parse(raw)
+		}
+		if rule, ok := p.match(r.URL.Hostname()); ok {
+			return url.Parse(rule.URL)
+		}
+		if fallback != nil {
+			return fallback(r)
+		}
+		return nil, nil
+	}
+
+	client := *base
+	client.Transport = clone
+	p.client = &client
+	return p
+}
+
+func (p *proxyRouter) match(host string) (ProxyRule, bool) {
+	for _, rule := range p.rules {
+		if rule.matches(host) {
+			return rule, true
+		}
+	}
+	return ProxyRule{}, false
+}
+
+// clientFor returns the *http.Client that should send httpReq, and the request
+// itself carrying the per-request proxy override in its context. Requests with
+// no override and no matching rule keep using the base client untouched; a
+// Request.ServerName override swaps in a client whose transport dials with
+// that name.
+func (h httpClient) clientFor(httpReq *http.Request, req Request) (*http.Client, *http.Request, error) {
+	client, httpReq, err := h.proxyClientFor(httpReq, req.Proxy)
+	if err != nil || req.ServerName == "" {
+		return client, httpReq, err
+	}
+	client, err = h.serverNames.client(client, req.ServerName)
+	return client, httpReq, err
+}
+
+func (h httpClient) proxyClientFor(httpReq *http.Request, proxy string) (*http.Client, *http.Request, error) {
+	if proxy == "" {
+		if h.proxy == nil {
+			return h.client, httpReq, nil
+		}
+		if _, ok := h.proxy.match(httpReq.URL.Hostname()); !ok {
+			return h.client, httpReq, nil
+		}
+		return h.proxy.client, httpReq, nil
+	}
+
+	if h.proxy == nil {
+		return nil, nil, errProxyUnsupported
+	}
+	if _, err := url.Parse(proxy); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+	}
+	ctx := context.WithValue(httpReq.Context(), proxyContextKey{}, proxy)
+	return h.proxy.client, httpReq.WithContext(ctx), nil
+}
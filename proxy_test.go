@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newProxyServer stands in for a forward proxy: it answers every request itself
+// and records the absolute-form URL the client asked it to fetch.
+func newProxyServer(t *testing.T, got *string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got = r.URL.String()
+		_, _ = w.Write([]byte("via-proxy"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func Test_ProxyRule_Matches(t *testing.T) {
+	tests := []struct {
+		rule string
+		host string
+		want bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"api.example.com", "API.example.com", true},
+		{"api.example.com", "other.example.com", false},
+		{".internal", "svc.internal", true},
+		{".internal", "internal", true},
+		{".internal", "notinternal", false},
+		{"*", "anything", true},
+		{"", "anything", false},
+	}
+	for _, tt := range tests {
+		if got := (ProxyRule{Host: tt.rule}).matches(tt.host); got != tt.want {
+			t.Errorf("ProxyRule{%q}.matches(%q) = %v, want %v", tt.rule, tt.host, got, tt.want)
+		}
+	}
+}
+
+func Test_Client_RequestProxyOverride(t *testing.T) {
+	var got string
+	proxy := newProxyServer(t, &got)
+
+	client := NewClient(Config{BaseURL: "http://upstream.invalid"}, WithHTTPClient(&http.Client{Transport: &http.Transport{}}))
+	resp, err := client.Get(context.Background(), GetRequest{Request: Request{Path: "/x", Proxy: proxy.URL}})
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if string(resp.Body) != "via-proxy" {
+		t.Errorf("body = %q, want via-proxy", resp.Body)
+	}
+	if got != "http://upstream.invalid/x" {
+		t.Errorf("proxy saw %q, want http://upstream.invalid/x", got)
+	}
+}
+
+func Test_Client_ProxyRules(t *testing.T) {
+	var got string
+	proxy := newProxyServer(t, &got)
+
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer direct.Close()
+
+	client := NewClient(Config{Proxies: []ProxyRule{{Host: ".onion", URL: proxy.URL}}},
+		WithHTTPClient(&http.Client{Transport: &http.Transport{}}))
+
+	resp, err := client.Get(context.Background(), GetRequest{Request: Request{Path: "http://hidden.onion/page"}})
+	if err != nil {
+		t.Fatalf("Get (matched rule) returned error: %v", err)
+	}
+	if string(resp.Body) != "via-proxy" || got != "http://hidden.onion/page" {
+		t.Errorf("matched rule: body = %q, proxy saw %q", resp.Body, got)
+	}
+
+	// hosts no rule matches go straight to the destination.
+	resp, err = client.Get(context.Background(), GetRequest{Request: Request{Path: direct.URL + "/x"}})
+	if err != nil {
+		t.Fatalf("Get (unmatched) returned error: %v", err)
+	}
+	if string(resp.Body) != "direct" {
+		t.Errorf("unmatched host: body = %q, want direct", resp.Body)
+	}
+}
+
+func Test_Client_ProxyOverrideNeedsTransport(t *testing.T) {
+	stub := &stubRoundTripper{resp: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}}
+	client := NewClient(Config{BaseURL: "http://upstream.invalid"}, WithHTTPClient(&http.Client{Transport: stub}))
+
+	_, err := client.Get(context.Background(), GetRequest{Request: Request{Path: "/x", Proxy: "socks5://127.0.0.1:9050"}})
+	if !errors.Is(err, errProxyUnsupported) {
+		t.Fatalf("err = %v, want errProxyUnsupported", err)
+	}
+	if stub.gotReq != nil {
+		t.Error("request was sent despite the unusable proxy override")
+	}
+}
+
+func Test_Client_ProxyRulesNeedTransport(t *testing.T) {
+	log := &recordingLogger{}
+	stub := &stubRoundTripper{resp: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}}
+	NewClient(Config{Proxies: []ProxyRule{{Host: "*", URL: "http://127.0.0.1:3128"}}},
+		WithHTTPClient(&http.Client{Transport: stub}), WithLogger(log))
+	if log.errs != 1 {
+		t.Fatalf("logged %d errors, want the unusable Proxies reported", log.errs)
+	}
+}
+
+func Test_Client_ConfigProxy(t *testing.T) {
+	var got string
+	proxy := newProxyServer(t, &got)
+
+	client := NewClient(Config{Proxy: ProxyConfig{URL: proxy.URL, NoProxy: []string{".direct.invalid"}}},
+		WithHTTPClient(&http.Client{Transport: &http.Transport{}}))
+
+	resp, err := client.Get(context.Background(), GetRequest{Request: Request{Path: "http://upstream.invalid/a"}})
+	if err != nil || string(resp.Body) != "via-proxy" || got != "http://upstream.invalid/a" {
+		t.Fatalf("proxied: resp=%v err=%v proxy saw %q", resp, err, got)
+	}
+
+	// NoProxy hosts are dialed directly, which fails for .invalid
+	got = ""
+	if _, err := client.Get(context.Background(), GetRequest{Request: Request{Path: "http://svc.direct.invalid/b"}}); err == nil || got != "" {
+		t.Fatalf("NoProxy host: err=%v proxy saw %q", err, got)
+	}
+}
+
+func Test_ProxyConfig_IgnoreEnvironment(t *testing.T) {
+	transport := &http.Transport{Proxy: func(*http.Request) (*url.URL, error) { return url.Parse("http://env-proxy:3128") }}
+	ProxyConfig{IgnoreEnvironment: true}.apply(transport)
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if u, err := transport.Proxy(req); u != nil || err != nil {
+		t.Fatalf("proxy = %v, %v", u, err)
+	}
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig caps the request rate to each host with a token bucket.
+type RateLimitConfig struct {
+	// RPS is the sustained requests per second allowed per host. 0 disables
+	// rate limiting.
+	RPS float64 `json:"rps"`
+	// Burst is how many requests may go out at once after an idle period.
+	// Defaults to RPS rounded up, and at least 1.
+	Burst int `json:"burst"`
+	// Store keeps the buckets outside the process, so every client sharing
+	// it, in any process, draws from one budget per host. Defaults to
+	// buckets in memory.
+	Store TokenBucketStore `json:"-"`
+	// KeyPrefix namespaces the bucket keys in Store, which are the host
+	// otherwise.
+	KeyPrefix string `json:"key_prefix"`
+	// OnStoreError, when set, is called when Store fails; the request then
+	// goes out unlimited rather than failing with it.
+	OnStoreError func(host string, err error) `json:"-"`
+}
+
+// TokenBucketLimit is the refill rate and capacity of a token bucket.
+type TokenBucketLimit struct {
+	// Rate is the tokens added per second.
+	Rate float64
+	// Burst is the most tokens the bucket holds.
+	Burst float64
+}
+
+// TokenBucketStore holds token buckets by key, typically in a store shared
+// across processes so a limit holds cluster-wide. A Redis implementation
+// runs Reserve as one Lua script over a hash of the bucket's tokens and
+// last refill time, keyed by key and expiring once it would be full again.
+// The server package's RateLimit middleware takes the same interface.
+type TokenBucketStore interface {
+	// Reserve takes a token from the bucket at key, creating it full, and
+	// returns how long until the token is actually available: callers that
+	// wait draw the bucket into debt and are served in order. When that wait
+	// would exceed maxWait, nothing is taken and ok is false; a negative
+	// maxWait accepts any wait.
+	Reserve(ctx context.Context, key string, limit TokenBucketLimit, maxWait time.Duration) (wait time.Duration, ok bool, err error)
+}
+
+func (c RateLimitConfig) enabled() bool { return c.RPS > 0 }
+
+// RateLimiter holds one token bucket per host. Every attempt, retries
+// included, takes a token; callers wait for one rather than fail, up to
+// their context's deadline.
+type RateLimiter struct {
+	rps   float64
+	burst float64
+	cfg   RateLimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter builds a RateLimiter from cfg.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	burst := float64(cfg.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(cfg.RPS))
+	}
+	return &RateLimiter{rps: cfg.RPS, burst: burst, cfg: cfg, buckets: make(map[string]*tokenBucket)}
+}
+
+// WithRateLimiter limits every request through l, which may be shared by
+// several clients so they draw from the same per-host budget.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(h *httpClient) {
+		h.limiter = l
+	}
+}
+
+// Wait blocks until a request to host may be sent, or ctx is done. A token
+// reserved in a Store is not returned when ctx ends first.
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	var delay time.Duration
+	if l.cfg.Store != nil {
+		wait, _, err := l.cfg.Store.Reserve(ctx, l.cfg.KeyPrefix+host, TokenBucketLimit{Rate: l.rps, Burst: l.burst}, -1)
+		if err != nil {
+			if l.cfg.OnStoreError != nil {
+				l.cfg.OnStoreError(host, err)
+			}
+			return nil
+		}
+		delay = wait
+	} else {
+		delay = l.reserve(host, time.Now())
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(host)
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from host's bucket and returns how long to wait
+// until it is actually available. Waiters queue behind each other in the
+// bucket's debt, so they are served in order.
+func (l *RateLimiter) reserve(host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[host] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rps)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rps * float64(time.Second))
+}
+
+// cancel returns the token of a waiter that gave up.
+func (l *RateLimiter) cancel(host string) {
+	if l.cfg.Store != nil {
+		return
+	}
+	l.mu.Lock()
+	if b, ok := l.buckets[host]; ok {
+		b.tokens = math.Min(l.burst, b.tokens+1)
+	}
+	l.mu.Unlock()
+}
+
+func (l *RateLimiter) wrap(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		if err := l.Wait(req.Context(), req.URL.Host); err != nil {
+			return nil, err
+		}
+		return next(req)
+	}
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_RateLimiter_BurstThenRate(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{RPS: 10, Burst: 2})
+	now := time.Now()
+	if l.reserve("a", now) != 0 || l.reserve("a", now) != 0 {
+		t.Fatal("burst should go out at once")
+	}
+	if d := l.reserve("a", now); d < 90*time.Millisecond || d > 110*time.Millisecond {
+		t.Fatalf("third wait = %v, want ~100ms", d)
+	}
+	if d := l.reserve("a", now); d < 190*time.Millisecond || d > 210*time.Millisecond {
+		t.Fatalf("fourth wait = %v, want ~200ms queued behind the third", d)
+	}
+	if l.reserve("b", now) != 0 {
+		t.Fatal("hosts must have separate buckets")
+	}
+	if d := l.reserve("a", now.Add(time.Second)); d != 0 {
+		t.Fatalf("refilled bucket wait = %v", d)
+	}
+}
+
+func Test_RateLimiter_WaitHonorsContext(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{RPS: 0.1})
+	_ = l.Wait(context.Background(), "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func Test_Client_RateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, RateLimit: RateLimitConfig{RPS: 20, Burst: 1}})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("3 requests at 20 rps took %v", elapsed)
+	}
+}
+
+// sharedBucketStore is a TokenBucketStore standing in for one shared by
+// several processes.
+type sharedBucketStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	keys    []string
+	err     error
+}
+
+func (s *sharedBucketStore) Reserve(_ context.Context, key string, limit TokenBucketLimit, maxWait time.Duration) (time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, false, s.err
+	}
+	s.keys = append(s.keys, key)
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: limit.Burst, last: now}
+		s.buckets[key] = b
+	}
+	tokens := math.Min(limit.Burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate) - 1
+	wait := time.Duration(math.Max(0, -tokens/limit.Rate) * float64(time.Second))
+	if maxWait >= 0 && wait > maxWait {
+		return wait, false, nil
+	}
+	b.tokens, b.last = tokens, now
+	return wait, true, nil
+}
+
+func Test_RateLimiter_Store(t *testing.T) {
+	store := &sharedBucketStore{buckets: make(map[string]*tokenBucket)}
+	cfg := RateLimitConfig{RPS: 10, Burst: 1, Store: store, KeyPrefix: "svc:"}
+	// two limiters, as in two processes, share the store's budget
+	a, b := NewRateLimiter(cfg), NewRateLimiter(cfg)
+
+	start := time.Now()
+	for _, l := range []*RateLimiter{a, b, a} {
+		if err := l.Wait(context.Background(), "api"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Fatalf("3 requests at 10 rps across limiters took %v", elapsed)
+	}
+	if store.keys[0] != "svc:api" {
+		t.Fatalf("key = %q", store.keys[0])
+	}
+
+	// a failing store lets requests through
+	store.err = errors.New("connection refused")
+	var failed string
+	cfg.OnStoreError = func(host string, err error) { failed = host }
+	if err := NewRateLimiter(cfg).Wait(context.Background(), "api"); err != nil || failed != "api" {
+		t.Fatalf("err = %v, reported %q", err, failed)
+	}
+}
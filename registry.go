@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Route is an endpoint as a Registry lists it.
+type Route struct {
+	Name   string
+	Method string
+	Path   string
+	Expect []int
+}
+
+// Registry holds a service's API surface in one place, bound to the client
+// that calls it. Define adds typed endpoints; Request starts a builder for a
+// registered route by name.
+//
+//	var (
+//		api     = http.NewRegistry(client)
+//		getUser = http.Define(api, "users.get", http.Endpoint[GetUserRequest, User]{Method: "GET", Path: "/users/{id}"})
+//	)
+//
+//	user, err := getUser(ctx, GetUserRequest{ID: "42"})
+type Registry struct {
+	client Client
+	mu     sync.RWMutex
+	routes map[string]Route
+}
+
+// NewRegistry returns an empty registry calling through c.
+func NewRegistry(c Client) *Registry {
+	return &Registry{client: c, routes: make(map[string]Route)}
+}
+
+// Register adds a route. It panics when name is already taken, as the API
+// surface is declared once at startup.
+func (r *Registry) Register(name, method, path string, expect ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.routes[name]; ok {
+		panic(fmt.Sprintf("http: endpoint %q registered twice", name))
+	}
+	r.routes[name] = Route{Name: name, Method: strings.ToUpper(method), Path: path, Expect: expect}
+}
+
+// Route returns the route registered under name.
+func (r *Registry) Route(name string) (Route, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	route, ok := r.routes[name]
+	return route, ok
+}
+
+// Routes lists every route, sorted by name.
+func (r *Registry) Routes() []Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make([]Route, 0, len(r.routes))
+	for _, route := range r.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes
+}
+
+// Request starts a builder for the route registered under name, with its
+// method, path template, and expected statuses filled in. An unknown name
+// fails when the builder is sent.
+func (r *Registry) Request(name string) *RequestBuilder {
+	b := NewRequest(r.client)
+	route, ok := r.Route(name)
+	if !ok {
+		b.fail(fmt.Errorf("no endpoint registered as %q", name))
+		return b
+	}
+	return b.Method(route.Method).Path(route.Path).Expect(route.Expect...)
+}
+
+// Define registers e under name and returns a typed function calling it
+// through r's client.
+func Define[Req, Resp any](r *Registry, name string, e Endpoint[Req, Resp]) func(ctx context.Context, req Req) (Resp, error) {
+	r.Register(name, e.Method, e.Path, e.Expect...)
+	return func(ctx context.Context, req Req) (Resp, error) {
+		return e.Call(ctx, r.client, req)
+	}
+}
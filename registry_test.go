@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_Registry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/items/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"id":"` + r.URL.Path[len("/items/"):] + `"}`))
+	}))
+	defer srv.Close()
+
+	api := NewRegistry(NewClient(Config{BaseURL: srv.URL}))
+	get := Define(api, "items.get", Endpoint[getItemRequest, item]{Method: http.MethodGet, Path: "/items/{id}", Expect: []int{http.StatusAccepted}})
+	api.Register("items.delete", "delete", "/items/{id}", http.StatusGone)
+
+	got, err := get(context.Background(), getItemRequest{ID: "42"})
+	if err != nil || got.ID != "42" {
+		t.Fatalf("get = %+v, %v", got, err)
+	}
+	if _, err := api.Request("items.delete").PathParam("id", "gone").Do(context.Background(), nil); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := api.Request("items.delete").PathParam("id", "1").Do(context.Background(), nil); StatusOf(err) != http.StatusAccepted {
+		t.Fatalf("unexpected status: err = %v", err)
+	}
+	if _, err := api.Request("items.missing").Send(context.Background()); err == nil {
+		t.Fatal("unknown endpoint was sent")
+	}
+
+	want := []Route{
+		{Name: "items.delete", Method: http.MethodDelete, Path: "/items/{id}", Expect: []int{http.StatusGone}},
+		{Name: "items.get", Method: http.MethodGet, Path: "/items/{id}", Expect: []int{http.StatusAccepted}},
+	}
+	if routes := api.Routes(); !reflect.DeepEqual(routes, want) {
+		t.Fatalf("routes = %+v", routes)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a name twice did not panic")
+		}
+	}()
+	api.Register("items.get", http.MethodGet, "/other")
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retry defaults applied when RetryConfig.MaxAttempts enables retries.
+const (
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay  = 10 * time.Second
+)
+
+// RetryConfig enables automatic retries with exponential backoff. The zero
+// value never retries.
+type RetryConfig struct {
+	// MaxAttempts counts the first try; 0 or 1 disables retries.
+	MaxAttempts int `json:"max_attempts"`
+	// BaseDelay is the wait before the first retry, doubling for each one
+	// after. Defaults to DefaultRetryBaseDelay.
+	BaseDelay time.Duration `json:"base_delay"`
+	// MaxDelay caps each wait, including one asked for by Retry-After.
+	// Defaults to DefaultRetryMaxDelay.
+	MaxDelay time.Duration `json:"max_delay"`
+	// Jitter randomizes each wait by up to this fraction of it (0.2 waits
+	// between 80% and 100%), so clients that failed together do not retry in
+	// lockstep. 0 disables.
+	Jitter float64 `json:"jitter"`
+	// RetryNonIdempotent also retries POST and PATCH. Only enable it for APIs
+	// that deduplicate, e.g. through an idempotency key.
+	RetryNonIdempotent bool `json:"retry_non_idempotent"`
+	// Statuses, when set, replaces DefaultRetryStatuses as the response
+	// codes worth retrying, e.g. [408, 425, 429, 503, 504] to add 425 and
+	// drop 502. Transport errors are retried as by DefaultRetryOn.
+	Statuses []int `json:"statuses"`
+	// Methods overrides the attempts and statuses per method name, e.g.
+	// {"POST": {"max_attempts": 3, "statuses": [503]}}.
+	Methods map[string]RetryMethodConfig `json:"methods"`
+	// RetryOn decides whether an attempt is retried; resp is nil when err is
+	// set. It takes precedence over Statuses and Methods' statuses. Defaults
+	// to DefaultRetryOn.
+	RetryOn func(resp *http.Response, err error) bool `json:"-"`
+}
+
+// RetryMethodConfig overrides RetryConfig for one method.
+type RetryMethodConfig struct {
+	// MaxAttempts, when > 0, replaces RetryConfig.MaxAttempts for the
+	// method; 1 disables retries. Setting it for POST or PATCH opts that
+	// method in without RetryNonIdempotent.
+	MaxAttempts int `json:"max_attempts"`
+	// Statuses, when set, replaces the retryable status codes for the method.
+	Statuses []int `json:"statuses"`
+}
+
+// DefaultRetryStatuses are the response codes DefaultRetryOn retries.
+var DefaultRetryStatuses = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryOn retries transport errors, ErrAttemptTimeout included (but
+// not a canceled or expired context, or an open circuit breaker), and
+// DefaultRetryStatuses responses.
+func DefaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrCircuitOpen)
+	}
+	return hasStatus(DefaultRetryStatuses, resp.StatusCode)
+}
+
+func hasStatus(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// method returns the override for method, matched case-insensitively.
+func (c RetryConfig) method(method string) (RetryMethodConfig, bool) {
+	if m, ok := c.Methods[method]; ok {
+		return m, true
+	}
+	for name, m := range c.Methods {
+		if strings.EqualFold(name, method) {
+			return m, true
+		}
+	}
+	return RetryMethodConfig{}, false
+}
+
+// attempts returns how many tries method gets.
+func (c RetryConfig) attempts(method string) int {
+	if m, ok := c.method(method); ok && m.MaxAttempts > 0 {
+		return m.MaxAttempts
+	}
+	if c.MaxAttempts <= 1 {
+		return 1
+	}
+	switch method {
+	case http.MethodPost, http.MethodPatch:
+		if !c.RetryNonIdempotent {
+			return 1
+		}
+	}
+	return c.MaxAttempts
+}
+
+func (c RetryConfig) retryOn(method string, resp *http.Response, err error) bool {
+	if c.RetryOn != nil {
+		return c.RetryOn(resp, err)
+	}
+	statuses := c.Statuses
+	if m, ok := c.method(method); ok && len(m.Statuses) > 0 {
+		statuses = m.Statuses
+	}
+	if err != nil || len(statuses) == 0 {
+		return DefaultRetryOn(resp, err)
+	}
+	return hasStatus(statuses, resp.StatusCode)
+}
+
+// backoff is the wait before retry number n (1 for the first retry). A
+// Retry-After on resp takes precedence over the exponential schedule.
+func (c RetryConfig) backoff(n int, resp *http.Response) time.Duration {
+	base, maxDelay := c.BaseDelay, c.MaxDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if d > maxDelay {
+				d = maxDelay
+			}
+			return d
+		}
+	}
+
+	d := maxDelay
+	if shift := n - 1; shift < 32 {
+		if exp := base << shift; exp > 0 && exp < maxDelay {
+			d = exp
+		}
+	}
+	if c.Jitter > 0 {
+		j := c.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d -= time.Duration(rand.Float64() * j * float64(d))
+	}
+	return d
+}
+
+// retryAfter parses a Retry-After header given as seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Retry_RecoversFromTransientStatus(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Retry: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}})
+	resp, err := c.Put(context.Background(), PutRequest{Body: []byte("payload")})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "payload" {
+		t.Fatalf("got %d %q, want the body replayed on the final attempt", resp.StatusCode, resp.Body)
+	}
+	if len(resp.Attempts) != 3 || resp.Attempts[0].StatusCode != http.StatusServiceUnavailable || resp.Attempts[1].Backoff != time.Millisecond || resp.Attempts[2].Backoff != 2*time.Millisecond {
+		t.Fatalf("Attempts = %+v", resp.Attempts)
+	}
+}
+
+func Test_Retry_PostOptIn(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	retry := RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	resp, err := NewClient(Config{BaseURL: srv.URL, Retry: retry}).Post(context.Background(), PostRequest{})
+	if err != nil || resp.StatusCode != http.StatusBadGateway || hits.Load() != 1 {
+		t.Fatalf("POST retried without opt-in: hits=%d err=%v", hits.Load(), err)
+	}
+
+	retry.RetryNonIdempotent = true
+	resp, err = NewClient(Config{BaseURL: srv.URL, Retry: retry}).Post(context.Background(), PostRequest{})
+	if err != nil || hits.Load() != 4 || len(resp.Attempts) != 3 {
+		t.Fatalf("hits=%d err=%v attempts=%d", hits.Load(), err, len(resp.Attempts))
+	}
+}
+
+func Test_Retry_TransportErrorsCarryAttempts(t *testing.T) {
+	stub := &http.Client{Transport: &stubRoundTripper{err: io.ErrUnexpectedEOF}}
+	c := NewClient(Config{BaseURL: "http://api.test", Retry: RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond}}, WithHTTPClient(stub))
+
+	_, err := c.Get(context.Background(), GetRequest{})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v", err)
+	}
+	if attempts := AttemptsOf(err); len(attempts) != 2 || attempts[1].Err == nil {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+}
+
+func Test_Retry_CustomPredicate(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	never := func(*http.Response, error) bool { return false }
+	c := NewClient(Config{BaseURL: srv.URL, Retry: RetryConfig{MaxAttempts: 5, RetryOn: never}})
+	if _, err := c.Get(context.Background(), GetRequest{}); err != nil || hits.Load() != 1 {
+		t.Fatalf("hits=%d err=%v", hits.Load(), err)
+	}
+}
+
+func Test_Retry_StatusSetsFromJSON(t *testing.T) {
+	var status atomic.Int64
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	var retry RetryConfig
+	err := json.Unmarshal([]byte(`{
+		"max_attempts": 3,
+		"statuses": [425, 503],
+		"methods": {"post": {"max_attempts": 2, "statuses": [409]}, "DELETE": {"max_attempts": 1}}
+	}`), &retry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry.BaseDelay = time.Millisecond
+	c := NewClient(Config{BaseURL: srv.URL, Retry: retry})
+
+	for _, tc := range []struct {
+		name     string
+		status   int
+		call     func() error
+		wantHits int64
+	}{
+		{"added status", http.StatusTooEarly, func() error { _, err := c.Get(context.Background(), GetRequest{}); return err }, 3},
+		{"dropped default status", http.StatusBadGateway, func() error { _, err := c.Get(context.Background(), GetRequest{}); return err }, 1},
+		{"method statuses", http.StatusConflict, func() error { _, err := c.Post(context.Background(), PostRequest{}); return err }, 2},
+		{"method statuses replace the global set", http.StatusServiceUnavailable, func() error { _, err := c.Post(context.Background(), PostRequest{}); return err }, 1},
+		{"method disabled", http.StatusServiceUnavailable, func() error { _, err := c.Delete(context.Background(), Request{}); return err }, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status.Store(int64(tc.status))
+			hits.Store(0)
+			if err := tc.call(); err != nil {
+				t.Fatal(err)
+			}
+			if hits.Load() != tc.wantHits {
+				t.Fatalf("hits = %d, want %d", hits.Load(), tc.wantHits)
+			}
+		})
+	}
+}
+
+func Test_RetryConfig_Backoff(t *testing.T) {
+	c := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second, 80: time.Second} {
+		if got := c.backoff(n, nil); got != want {
+			t.Errorf("backoff(%d) = %v want %v", n, got, want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	if got := c.backoff(1, resp); got != time.Second {
+		t.Errorf("Retry-After must be capped at MaxDelay, got %v", got)
+	}
+	c.MaxDelay = time.Minute
+	if got := c.backoff(1, resp); got != 3*time.Second {
+		t.Errorf("Retry-After = %v", got)
+	}
+
+	c.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		if got := c.backoff(1, nil); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("jittered backoff %v out of range", got)
+		}
+	}
+}
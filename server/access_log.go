@@ -0,0 +1,324 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessLogFormat selects how SlogMiddleware renders each logged request.
+type AccessLogFormat string
+
+// Access log formats. All but AccessLogLogger write one line per request to
+// SlogConfig.Output.
+const (
+	// AccessLogLogger passes each request to the Logger as key/value pairs.
+	// It is the default.
+	AccessLogLogger AccessLogFormat = ""
+	// AccessLogCommon is the NCSA Common Log Format of Apache and nginx:
+	// host ident user [time] "request" status bytes.
+	AccessLogCommon AccessLogFormat = "common"
+	// AccessLogCombined is AccessLogCommon followed by the quoted Referer
+	// and User-Agent, the default of GoAccess and most log analyzers.
+	AccessLogCombined AccessLogFormat = "combined"
+	// AccessLogJSON writes one JSON object per line (JSON Lines), as Loki,
+	// vector, and fluent-bit ingest without a parser.
+	AccessLogJSON AccessLogFormat = "json"
+	// AccessLogOTLP writes one OTLP/JSON ExportLogsServiceRequest per line,
+	// with the request as a log record using the HTTP semantic convention
+	// attributes, as the OpenTelemetry Collector's otlpjsonfile receiver
+	// reads.
+	AccessLogOTLP AccessLogFormat = "otlp"
+)
+
+// commonLogTime is the timestamp layout of the Common Log Format.
+const commonLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// accessEntry is one finished request as the access log formats see it.
+type accessEntry struct {
+	start     time.Time
+	elapsed   time.Duration
+	level     LogLevel
+	method    string
+	uri       string
+	proto     string
+	route     string
+	status    int
+	bytes     int64
+	clientIP  string
+	user      string
+	requestID string
+	referer   string
+	userAgent string
+	trace     TraceContext
+}
+
+func newAccessEntry(r *http.Request, rw *responseRecorder, start time.Time, elapsed time.Duration, level LogLevel) accessEntry {
+	e := accessEntry{
+		start:     start,
+		elapsed:   elapsed,
+		level:     level,
+		method:    r.Method,
+		uri:       r.URL.RequestURI(),
+		proto:     r.Proto,
+		route:     RoutePattern(r),
+		status:    rw.status,
+		bytes:     rw.written,
+		clientIP:  ClientIP(r),
+		requestID: RequestIDFromContext(r.Context()),
+		referer:   r.Referer(),
+		userAgent: r.UserAgent(),
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		e.user = user
+	}
+	e.trace, _ = TraceFromContext(r.Context())
+	return e
+}
+
+// accessLogWriter returns the function that writes entries in cfg.Format
+// to cfg.Output, one whole line per Write. It panics on an unknown format.
+func accessLogWriter(cfg SlogConfig) func(accessEntry) {
+	var render func(accessEntry) []byte
+	switch cfg.Format {
+	case AccessLogCommon:
+		render = func(e accessEntry) []byte { return []byte(e.common() + "\n") }
+	case AccessLogCombined:
+		render = func(e accessEntry) []byte {
+			return []byte(e.common() + " " + quoteLogField(e.referer) + " " + quoteLogField(e.userAgent) + "\n")
+		}
+	case AccessLogJSON:
+		render = func(e accessEntry) []byte { return jsonLine(e.json()) }
+	case AccessLogOTLP:
+		render = func(e accessEntry) []byte { return jsonLine(e.otlp(cfg.ServiceName)) }
+	default:
+		panic(fmt.Sprintf("server: unknown SlogConfig.Format %q", cfg.Format))
+	}
+	out := cfg.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	var mu sync.Mutex
+	return func(e accessEntry) {
+		line := render(e)
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = out.Write(line)
+	}
+}
+
+// common renders e in the Common Log Format.
+func (e accessEntry) common() string {
+	size := "-"
+	if e.bytes > 0 {
+		size = strconv.FormatInt(e.bytes, 10)
+	}
+	return strings.Join([]string{
+		orDash(e.clientIP),
+		"-",
+		orDash(e.user),
+		"[" + e.start.Format(commonLogTime) + "]",
+		quoteLogField(e.method + " " + e.uri + " " + e.proto),
+		strconv.Itoa(e.status),
+		size,
+	}, " ")
+}
+
+// accessLogJSON is a JSON Lines access log record.
+type accessLogJSON struct {
+	Time       string  `json:"time"`
+	Level      string  `json:"level"`
+	Method     string  `json:"method"`
+	URL        string  `json:"url"`
+	Proto      string  `json:"proto"`
+	Route      string  `json:"route,omitempty"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMS float64 `json:"duration_ms"`
+	ClientIP   string  `json:"client_ip,omitempty"`
+	User       string  `json:"user,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"`
+	Referer    string  `json:"referer,omitempty"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	TraceID    string  `json:"trace_id,omitempty"`
+	SpanID     string  `json:"span_id,omitempty"`
+}
+
+func (e accessEntry) json() accessLogJSON {
+	return accessLogJSON{
+		Time:       e.start.UTC().Format(time.RFC3339Nano),
+		Level:      string(e.level),
+		Method:     e.method,
+		URL:        e.uri,
+		Proto:      e.proto,
+		Route:      e.route,
+		Status:     e.status,
+		Bytes:      e.bytes,
+		DurationMS: float64(e.elapsed.Microseconds()) / 1000,
+		ClientIP:   e.clientIP,
+		User:       e.user,
+		RequestID:  e.requestID,
+		Referer:    e.referer,
+		UserAgent:  e.userAgent,
+		TraceID:    e.trace.TraceID,
+		SpanID:     e.trace.SpanID,
+	}
+}
+
+// OTLP/JSON shapes of opentelemetry-proto's logs service, holding the
+// fields an access log record uses. 64-bit integers are strings, as the
+// protobuf JSON mapping requires.
+type (
+	otlpLogs struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes,omitempty"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope       `json:"scope"`
+		LogRecords []otlpLogRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpLogRecord struct {
+		TimeUnixNano         string          `json:"timeUnixNano"`
+		ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+		SeverityNumber       int             `json:"severityNumber"`
+		SeverityText         string          `json:"severityText"`
+		Body                 otlpValue       `json:"body"`
+		Attributes           []otlpAttribute `json:"attributes"`
+		TraceID              string          `json:"traceId,omitempty"`
+		SpanID               string          `json:"spanId,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// otlpSeverity maps levels to OTLP severity numbers.
+var otlpSeverity = map[LogLevel]int{
+	LogLevelTrace: 1,
+	LogLevelDebug: 5,
+	LogLevelInfo:  9,
+	LogLevelWarn:  13,
+	LogLevelError: 17,
+}
+
+func (e accessEntry) otlp(serviceName string) otlpLogs {
+	str := func(key, value string) otlpAttribute {
+		return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+	}
+	num := func(key string, value int64) otlpAttribute {
+		s := strconv.FormatInt(value, 10)
+		return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+	}
+	path, query, _ := strings.Cut(e.uri, "?")
+	seconds := e.elapsed.Seconds()
+	attrs := []otlpAttribute{
+		str("http.request.method", e.method),
+		str("url.path", path),
+		num("http.response.status_code", int64(e.status)),
+		num("http.response.body.size", e.bytes),
+		{Key: "http.server.request.duration", Value: otlpValue{DoubleValue: &seconds}},
+		str("network.protocol.version", strings.TrimPrefix(e.proto, "HTTP/")),
+	}
+	for _, a := range []struct{ key, value string }{
+		{"url.query", query},
+		{"http.route", e.route},
+		{"client.address", e.clientIP},
+		{"user.name", e.user},
+		{"http.request.header.x-request-id", e.requestID},
+		{"http.request.header.referer", e.referer},
+		{"user_agent.original", e.userAgent},
+	} {
+		if a.value != "" {
+			attrs = append(attrs, str(a.key, a.value))
+		}
+	}
+
+	var resource otlpResource
+	if serviceName != "" {
+		resource.Attributes = []otlpAttribute{str("service.name", serviceName)}
+	}
+	body := e.method + " " + e.uri + " " + strconv.Itoa(e.status)
+	severity, ok := otlpSeverity[e.level]
+	if !ok {
+		severity = otlpSeverity[LogLevelInfo]
+	}
+	return otlpLogs{ResourceLogs: []otlpResourceLogs{{
+		Resource: resource,
+		ScopeLogs: []otlpScopeLogs{{
+			Scope: otlpScope{Name: "github.com/toaweme/http/server"},
+			LogRecords: []otlpLogRecord{{
+				TimeUnixNano:         strconv.FormatInt(e.start.UnixNano(), 10),
+				ObservedTimeUnixNano: strconv.FormatInt(e.start.Add(e.elapsed).UnixNano(), 10),
+				SeverityNumber:       severity,
+				SeverityText:         strings.ToUpper(string(e.level)),
+				Body:                 otlpValue{StringValue: &body},
+				Attributes:           attrs,
+				TraceID:              e.trace.TraceID,
+				SpanID:               e.trace.SpanID,
+			}},
+		}},
+	}}}
+}
+
+// jsonLine encodes v as one line of JSON Lines.
+func jsonLine(v any) []byte {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return []byte("{}\n")
+	}
+	return append(line, '\n')
+}
+
+// quoteLogField quotes s for a space-separated log line, escaping quotes,
+// backslashes, and control characters so a client cannot forge fields or
+// lines; empty values render as "-".
+func quoteLogField(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// orDash stands "-" in for an unknown Common Log Format field, and escapes
+// the spaces and control characters a known one must not contain.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	quoted := quoteLogField(s)
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], " ", `\x20`)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func serveAccessLog(t *testing.T, cfg SlogConfig, req *http.Request) string {
+	t.Helper()
+	var out bytes.Buffer
+	cfg.Output = &out
+	serveThrough(cfg, &captureLogger{}, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}, req)
+	return out.String()
+}
+
+func Test_AccessLog_Common(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/items?q=1", http.NoBody)
+	req.SetBasicAuth("frank", "secret")
+	line := serveAccessLog(t, SlogConfig{Format: AccessLogCommon}, req)
+
+	want := regexp.MustCompile(`^192\.0\.2\.1 - frank \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /items\?q=1 HTTP/1\.1" 201 5\n$`)
+	if !want.MatchString(line) {
+		t.Fatalf("line = %q", line)
+	}
+}
+
+func Test_AccessLog_CombinedEscapesFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("User-Agent", "evil\" \"agent\n")
+	line := serveAccessLog(t, SlogConfig{Format: AccessLogCombined}, req)
+
+	if !strings.HasSuffix(line, ` "-" "evil\" \"agent\x0a"`+"\n") {
+		t.Fatalf("line = %q", line)
+	}
+	if strings.Count(line, "\n") != 1 {
+		t.Fatalf("forged line break: %q", line)
+	}
+}
+
+func Test_AccessLog_JSONLines(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items", http.NoBody)
+	req = req.WithContext(ContextWithTrace(req.Context(), TraceContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"}))
+	line := serveAccessLog(t, SlogConfig{Format: AccessLogJSON}, req)
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("line %q: %v", line, err)
+	}
+	if got["method"] != "GET" || got["url"] != "/items" || got["status"] != float64(201) || got["bytes"] != float64(5) || got["level"] != "info" {
+		t.Fatalf("record = %v", got)
+	}
+	if got["trace_id"] != "0af7651916cd43dd8448eb211c80319c" || got["client_ip"] != "192.0.2.1" {
+		t.Fatalf("record = %v", got)
+	}
+}
+
+func Test_AccessLog_OTLP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items?q=1", http.NoBody)
+	line := serveAccessLog(t, SlogConfig{Format: AccessLogOTLP, ServiceName: "api"}, req)
+
+	var got otlpLogs
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatalf("line %q: %v", line, err)
+	}
+	resource := got.ResourceLogs[0]
+	if *resource.Resource.Attributes[0].Value.StringValue != "api" {
+		t.Fatalf("resource = %+v", resource.Resource)
+	}
+	record := resource.ScopeLogs[0].LogRecords[0]
+	if record.SeverityNumber != 9 || record.SeverityText != "INFO" || *record.Body.StringValue != "GET /items?q=1 201" {
+		t.Fatalf("record = %+v", record)
+	}
+	attrs := make(map[string]otlpValue, len(record.Attributes))
+	for _, a := range record.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if *attrs["http.request.method"].StringValue != "GET" || *attrs["url.path"].StringValue != "/items" || *attrs["url.query"].StringValue != "q=1" {
+		t.Fatalf("attributes = %+v", attrs)
+	}
+	if *attrs["http.response.status_code"].IntValue != "201" || *attrs["http.response.body.size"].IntValue != "5" {
+		t.Fatalf("attributes = %+v", attrs)
+	}
+}
+
+func Test_AccessLog_RespectsRouteLevels(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/health", http.NoBody)
+	var out bytes.Buffer
+	r := NewRouter()
+	r.Use(SlogMiddleware(SlogConfig{Format: AccessLogJSON, Output: &out, RouteLevels: map[string]LogLevel{"/health": LogLevelNone}}, &captureLogger{}))
+	r.Get("/health", func(w http.ResponseWriter, _ *http.Request) {})
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if out.Len() != 0 {
+		t.Fatalf("silenced route logged: %q", out.String())
+	}
+}
+
+func Test_AccessLog_UnknownFormatPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	SlogMiddleware(SlogConfig{Format: "apache"}, &captureLogger{})
+}
@@ -38,6 +38,15 @@ const (
 	ctxOrgID contextKey = iota
 	ctxUserID
 	ctxScopes
+	ctxClientIP
+	ctxClientInfo
+	ctxCacheTags
+	ctxPropagatedHeaders
+	ctxTrace
+	ctxCodecs
+	ctxService
+	ctxHijacks
+	ctxBatch
 )
 
 // ContextWithOrgID returns a copy of ctx carrying the org ID.
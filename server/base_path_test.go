@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Server_BasePath(t *testing.T) {
+	var pattern string
+	r := NewRouter()
+	r.Get("/items/{id}", func(w http.ResponseWriter, req *http.Request) {
+		pattern = RoutePattern(req)
+		_, _ = w.Write([]byte(Param(req, "id")))
+	})
+	r.Group("/admin", func(g *Router) {
+		g.Get("/stats", func(http.ResponseWriter, *http.Request) {})
+	})
+	s := NewServer(ServerConfig{BasePath: "api/"}, r, nopLogger{})
+
+	if s.BasePath() != "/api" {
+		t.Fatalf("BasePath = %q", s.BasePath())
+	}
+
+	rec := httptest.NewRecorder()
+	s.HTTP().Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items/7", http.NoBody))
+	if rec.Code != http.StatusOK || rec.Body.String() != "7" {
+		t.Fatalf("mounted route: %d %q", rec.Code, rec.Body.String())
+	}
+	if pattern != "/api/items/{id}" {
+		t.Fatalf("RoutePattern = %q", pattern)
+	}
+
+	rec = httptest.NewRecorder()
+	s.HTTP().Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/7", http.NoBody))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unprefixed path: %d", rec.Code)
+	}
+
+	got := map[string]bool{}
+	for _, rt := range s.Routes() {
+		got[rt.Method+" "+rt.Pattern] = true
+	}
+	if !got["GET /api/items/{id}"] || !got["GET /api/admin/stats"] {
+		t.Fatalf("Routes = %v", got)
+	}
+}
+
+func Test_Server_NoBasePath(t *testing.T) {
+	r := NewRouter()
+	for _, base := range []string{"", "/"} {
+		s := NewServer(ServerConfig{BasePath: base}, r, nopLogger{})
+		if s.BasePath() != "" || s.HTTP().Handler != http.Handler(r) {
+			t.Fatalf("%q: base path applied", base)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BatchConfig bounds a batch endpoint built with BatchHandler.
+type BatchConfig struct {
+	// MaxOperations caps the operations per batch. Defaults to 100.
+	MaxOperations int
+	// Concurrency caps how many operations run at once. Defaults to 4.
+	Concurrency int
+	// ForwardHeaders are copied from the batch request onto every operation
+	// (unless the operation sets them itself). Defaults to Authorization and
+	// Cookie so each operation is authenticated as the caller.
+	ForwardHeaders []string
+}
+
+// BatchOperation is one sub-request of a batch. Body is sent as JSON.
+type BatchOperation struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResult is the outcome of one BatchOperation. JSON bodies are embedded
+// as-is; anything else is embedded as a JSON string.
+type BatchResult struct {
+	ID     string          `json:"id,omitempty"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse is the 207 Multi-Status body, with results in request order.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// BatchHandler serves a batch endpoint: it decodes a JSON array of
+// BatchOperation, dispatches each through h (normally the Router itself, so
+// operations see the same routes and middleware as direct calls) with bounded
+// concurrency, and answers 207 with a status per operation. Operations share
+// the batch request's context, so a client disconnect cancels the rest. An
+// operation that panics is answered 500 without taking the others down, and
+// an operation reaching a batch endpoint is refused.
+func BatchHandler(h http.Handler, cfg BatchConfig) http.HandlerFunc {
+	if cfg.MaxOperations <= 0 {
+		cfg.MaxOperations = 100
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.ForwardHeaders == nil {
+		cfg.ForwardHeaders = []string{"Authorization", "Cookie"}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// a batch inside a batch would multiply the limits away
+		if r.Context().Value(ctxBatch) != nil {
+			WriteBadRequest(w, errors.New("a batch operation cannot be a batch"))
+			return
+		}
+		// operations are routed like the batch route itself: under a
+		// BasePath mount that is the path below it
+		batchPath := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+			batchPath = rctx.RoutePath
+		}
+
+		var ops []BatchOperation
+		if err := ReadJSON(r, &ops); err != nil {
+			WriteBadRequest(w, fmt.Errorf("failed to decode batch: %w", err))
+			return
+		}
+		if len(ops) > cfg.MaxOperations {
+			WriteBadRequest(w, fmt.Errorf("batch has %d operations, limit is %d", len(ops), cfg.MaxOperations))
+			return
+		}
+		for i, op := range ops {
+			if op.Method == "" || !strings.HasPrefix(op.Path, "/") {
+				WriteBadRequest(w, fmt.Errorf("operation %d needs a method and an absolute path", i))
+				return
+			}
+			if opPath, _, _ := strings.Cut(op.Path, "?"); opPath == batchPath {
+				WriteBadRequest(w, fmt.Errorf("operation %d targets the batch endpoint", i))
+				return
+			}
+		}
+
+		results := make([]BatchResult, len(ops))
+		sem := make(chan struct{}, cfg.Concurrency)
+		var wg sync.WaitGroup
+		for i, op := range ops {
+			sem <- struct{}{}
+			wg.Go(func() {
+				defer func() { <-sem }()
+				results[i] = runBatchOperation(h, r, op, cfg.ForwardHeaders)
+			})
+		}
+		wg.Wait()
+
+		WriteJSON(w, http.StatusMultiStatus, BatchResponse{Results: results})
+	}
+}
+
+func runBatchOperation(h http.Handler, parent *http.Request, op BatchOperation, forward []string) (result BatchResult) {
+	// the operation runs off the serving goroutine, where nothing else
+	// would recover its panic
+	defer func() {
+		if rec := recover(); rec != nil {
+			result = BatchResult{ID: op.ID, Status: http.StatusInternalServerError, Body: errorBody(errors.New("internal server error"))}
+		}
+	}()
+
+	// drop the batch route's chi context, or the router would reuse it
+	// instead of routing the operation
+	ctx := context.WithValue(parent.Context(), chi.RouteCtxKey, nil)
+	ctx = context.WithValue(ctx, ctxBatch, true)
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(op.Method), op.Path, bytes.NewReader(op.Body))
+	if err != nil {
+		return BatchResult{ID: op.ID, Status: http.StatusBadRequest, Body: errorBody(err)}
+	}
+	req.Host = parent.Host
+	req.RemoteAddr = parent.RemoteAddr
+	req.TLS = parent.TLS
+	for _, name := range forward {
+		if v := parent.Header.Values(name); len(v) > 0 {
+			req.Header[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	if len(op.Body) > 0 {
+		req.Header.Set("Content-Type", ContentTypeJSON)
+	}
+	for k, v := range op.Headers {
+		req.Header.Set(k, v)
+	}
+
+	bw := &bufferingWriter{header: make(http.Header), status: http.StatusOK}
+	h.ServeHTTP(bw, req)
+
+	return BatchResult{ID: op.ID, Status: bw.status, Body: batchBody(bw.header, bw.buf.Bytes())}
+}
+
+func batchBody(h http.Header, body []byte) json.RawMessage {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	if IsJSON(h.Get("Content-Type")) && json.Valid(body) {
+		return body
+	}
+	raw, _ := json.Marshal(string(body))
+	return raw
+}
+
+func errorBody(err error) json.RawMessage {
+	raw, _ := json.Marshal(ErrorResponse{Error: err.Error()})
+	return raw
+}
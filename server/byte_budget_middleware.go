@@ -0,0 +1,192 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrByteBudgetExceeded is the error body written when a ByteBudget rejects a
+// request of a key that used up its Limit.
+var ErrByteBudgetExceeded = errors.New("byte budget exceeded")
+
+// ByteUsage is the traffic of one request: the request and response body
+// bytes actually read and written, attributed to Key. Headers are not
+// counted.
+type ByteUsage struct {
+	Key           string
+	RequestBytes  int64
+	ResponseBytes int64
+}
+
+// ByteTotals is the traffic accumulated for one key.
+type ByteTotals struct {
+	Requests      int64 `json:"requests"`
+	RequestBytes  int64 `json:"request_bytes"`
+	ResponseBytes int64 `json:"response_bytes"`
+}
+
+// Bytes is the request and response volume together.
+func (t ByteTotals) Bytes() int64 { return t.RequestBytes + t.ResponseBytes }
+
+// ByteAccumulator receives the usage of every finished request, e.g. to feed
+// a billing pipeline or an abuse detector. Add is called concurrently.
+type ByteAccumulator interface {
+	Add(usage ByteUsage)
+}
+
+// ByteUsageReader is implemented by accumulators that can report a key's
+// totals, which ByteBudgetConfig.Limit needs.
+type ByteUsageReader interface {
+	Usage(key string) ByteTotals
+}
+
+// MemoryByteAccumulator sums usage per key in memory. Reset it at the end
+// of each billing or detection window.
+type MemoryByteAccumulator struct {
+	mu     sync.Mutex
+	totals map[string]ByteTotals
+}
+
+var (
+	_ ByteAccumulator = (*MemoryByteAccumulator)(nil)
+	_ ByteUsageReader = (*MemoryByteAccumulator)(nil)
+)
+
+// NewMemoryByteAccumulator returns an empty accumulator.
+func NewMemoryByteAccumulator() *MemoryByteAccumulator {
+	return &MemoryByteAccumulator{totals: make(map[string]ByteTotals)}
+}
+
+// Add implements ByteAccumulator.
+func (a *MemoryByteAccumulator) Add(u ByteUsage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t := a.totals[u.Key]
+	t.Requests++
+	t.RequestBytes += u.RequestBytes
+	t.ResponseBytes += u.ResponseBytes
+	a.totals[u.Key] = t
+}
+
+// Usage implements ByteUsageReader.
+func (a *MemoryByteAccumulator) Usage(key string) ByteTotals {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.totals[key]
+}
+
+// Snapshot returns the totals of every key.
+func (a *MemoryByteAccumulator) Snapshot() map[string]ByteTotals {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make(map[string]ByteTotals, len(a.totals))
+	for k, t := range a.totals {
+		out[k] = t
+	}
+	return out
+}
+
+// Reset clears every key's totals.
+func (a *MemoryByteAccumulator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.totals = make(map[string]ByteTotals)
+}
+
+// ByteBudgetConfig controls the ByteBudget middleware.
+type ByteBudgetConfig struct {
+	// Accumulator receives each request's usage. Required.
+	Accumulator ByteAccumulator
+	// Key attributes a request to a client or tenant. Defaults to
+	// ClientKey, falling back to ClientIP for anonymous requests.
+	Key func(r *http.Request) string
+	// Limit, when positive, answers 429 to requests of a key whose request
+	// and response bytes reached it. The Accumulator must implement
+	// ByteUsageReader.
+	Limit int64
+}
+
+// ClientKey attributes a request to its tenant: the org id set by the auth
+// middleware, else the client id from ClientInfoMiddleware or the
+// X-Client-ID header, else "".
+func ClientKey(r *http.Request) string {
+	if org, ok := OrgIDFromContext(r.Context()); ok && org != "" {
+		return org
+	}
+	if info, ok := ClientInfoFromContext(r.Context()); ok && info.ClientID != "" {
+		return info.ClientID
+	}
+	return r.Header.Get(ClientIDHeaderName)
+}
+
+// clientKeyOrIP is ClientKey, or the client's IP for an anonymous request,
+// so anonymous callers do not share one bucket.
+func clientKeyOrIP(r *http.Request) string {
+	if k := ClientKey(r); k != "" {
+		return k
+	}
+	return ClientIP(r)
+}
+
+// ByteBudget counts the request and response body bytes of every request
+// and reports them to cfg.Accumulator once the handler returns, so a stream
+// is reported when it ends. Mount it after the auth middleware for
+// per-tenant keys.
+func ByteBudget(cfg ByteBudgetConfig) Middleware {
+	key := cfg.Key
+	if key == nil {
+		key = clientKeyOrIP
+	}
+	reader, _ := cfg.Accumulator.(ByteUsageReader)
+	if cfg.Limit > 0 && reader == nil {
+		panic("server: ByteBudgetConfig.Limit needs an Accumulator implementing ByteUsageReader")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if cfg.Limit > 0 && reader.Usage(k).Bytes() >= cfg.Limit {
+				WriteError(w, http.StatusTooManyRequests, ErrByteBudgetExceeded)
+				return
+			}
+
+			body := &countingBody{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			cw := &countingWriter{responseRecorder: responseRecorder{ResponseWriter: w, status: http.StatusOK}}
+			defer func() {
+				cfg.Accumulator.Add(ByteUsage{Key: k, RequestBytes: body.n.Load(), ResponseBytes: cw.n})
+			}()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// countingBody counts the request body bytes the handler reads. The count
+// is atomic as a handler may hand the body to another goroutine.
+type countingBody struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// countingWriter counts the response body bytes written. It reuses
+// responseRecorder for Flush/Hijack pass-through.
+type countingWriter struct {
+	responseRecorder
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.responseRecorder.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_ByteBudget_Accounting(t *testing.T) {
+	acc := NewMemoryByteAccumulator()
+	h := ByteBudget(ByteBudgetConfig{Accumulator: acc})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append(body, body...))
+	}))
+
+	send := func(clientID, body string) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set(ClientIDHeaderName, clientID)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	send("a", "12345")
+	send("a", "123")
+	send("b", "1")
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(httptest.NewRecorder(), r.WithContext(ContextWithOrgID(r.Context(), "org-1")))
+
+	want := map[string]ByteTotals{
+		"a":     {Requests: 2, RequestBytes: 8, ResponseBytes: 16},
+		"b":     {Requests: 1, RequestBytes: 1, ResponseBytes: 2},
+		"org-1": {Requests: 1},
+	}
+	got := acc.Snapshot()
+	if len(got) != len(want) {
+		t.Fatalf("totals = %+v", got)
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s = %+v, want %+v", k, got[k], w)
+		}
+	}
+	acc.Reset()
+	if len(acc.Snapshot()) != 0 {
+		t.Fatal("Reset kept totals")
+	}
+}
+
+func Test_ByteBudget_Limit(t *testing.T) {
+	acc := NewMemoryByteAccumulator()
+	h := ByteBudget(ByteBudgetConfig{
+		Accumulator: acc,
+		Limit:       10,
+		Key:         func(r *http.Request) string { return r.URL.Query().Get("tenant") },
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("0123456"))
+	}))
+
+	var codes []int
+	for _, tenant := range []string{"a", "a", "a", "b"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?tenant="+tenant, nil))
+		codes = append(codes, rec.Code)
+	}
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", codes, want)
+		}
+	}
+	if acc.Usage("a").Requests != 2 {
+		t.Fatalf("a = %+v, rejected request was counted", acc.Usage("a"))
+	}
+}
+
+func Test_ByteBudget_AnonymousFallsBackToIP(t *testing.T) {
+	acc := NewMemoryByteAccumulator()
+	h := ByteBudget(ByteBudgetConfig{Accumulator: acc})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for _, addr := range []string{"198.51.100.1:1000", "198.51.100.2:1000", "198.51.100.2:2000"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if acc.Usage("198.51.100.1").Requests != 1 || acc.Usage("198.51.100.2").Requests != 2 || acc.Usage("").Requests != 0 {
+		t.Fatalf("totals = %+v", acc.Snapshot())
+	}
+}
+
+type addOnly struct{}
+
+func (addOnly) Add(ByteUsage) {}
+
+func Test_ByteBudget_LimitNeedsReader(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("a Limit without a ByteUsageReader did not panic")
+		}
+	}()
+	ByteBudget(ByteBudgetConfig{Accumulator: addOnly{}, Limit: 1})
+}
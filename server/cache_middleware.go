@@ -0,0 +1,339 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStatusHeaderName reports whether a response came from the ResponseCache
+// ("HIT") or the handler ("MISS").
+const CacheStatusHeaderName = "X-Cache"
+
+// ResponseCache bounds applied unless MaxEntries or MaxBodyBytes change them.
+const (
+	DefaultCacheMaxEntries   = 1000
+	DefaultCacheMaxBodyBytes = 1 << 20
+)
+
+// ResponseCache is an in-memory cache of successful GET responses. Handlers
+// tag the entry they produce (TagCache), and mutations invalidate by tag
+// (Invalidate or InvalidateTags), so a GET cache stays consistent after writes
+// without guessing URLs.
+type ResponseCache struct {
+	ttl time.Duration
+	// varyBy are request headers whose values key separate entries.
+	varyBy []string
+	// maxEntries and maxBodyBytes are no cap when negative.
+	maxEntries   int
+	maxBodyBytes int
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	tags    map[string]map[string]struct{}
+	// nextSweep is when store next drops expired entries.
+	nextSweep time.Time
+}
+
+type cacheEntry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	tags    []string
+	expires time.Time
+	// vary fingerprints the request headers the response's Vary named.
+	vary map[string]string
+}
+
+// NewResponseCache builds a cache whose entries live for ttl, holding at most
+// DefaultCacheMaxEntries responses of up to DefaultCacheMaxBodyBytes each.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:          ttl,
+		maxEntries:   DefaultCacheMaxEntries,
+		maxBodyBytes: DefaultCacheMaxBodyBytes,
+		entries:      make(map[string]*cacheEntry),
+		tags:         make(map[string]map[string]struct{}),
+	}
+}
+
+// MaxEntries caps how many responses are cached; once full, expired entries
+// are dropped first, then those closest to expiring. n <= 0 removes the cap.
+// Returns c for chaining after NewResponseCache.
+func (c *ResponseCache) MaxEntries(n int) *ResponseCache {
+	if n <= 0 {
+		n = -1
+	}
+	c.maxEntries = n
+	return c
+}
+
+// MaxBodyBytes caps the body of a cached response; larger responses are
+// passed on but not cached, and only the first n bytes are ever held while
+// one is produced. n <= 0 removes the cap. Returns c for chaining after
+// NewResponseCache.
+func (c *ResponseCache) MaxBodyBytes(n int) *ResponseCache {
+	if n <= 0 {
+		n = -1
+	}
+	c.maxBodyBytes = n
+	return c
+}
+
+// VaryBy keys entries by the values of headers, e.g. Authorization or
+// Accept-Language, so a response is never served to another user or locale
+// even when the handler does not set Vary itself. The headers are added to
+// the Vary of every response the middleware passes on. Returns c for
+// chaining after NewResponseCache.
+func (c *ResponseCache) VaryBy(headers ...string) *ResponseCache {
+	for _, h := range headers {
+		c.varyBy = append(c.varyBy, http.CanonicalHeaderKey(h))
+	}
+	return c
+}
+
+// cacheTags collects the tags a handler attaches while producing a response.
+type cacheTags struct {
+	tags []string
+}
+
+// TagCache tags the response being produced for r, e.g. "user:123", so a
+// later Invalidate("user:123") evicts it. It is a no-op outside a
+// ResponseCache.Middleware.
+func TagCache(r *http.Request, tags ...string) {
+	if ct, ok := r.Context().Value(ctxCacheTags).(*cacheTags); ok {
+		ct.tags = append(ct.tags, tags...)
+	}
+}
+
+// Middleware serves fresh cached GET responses and stores new 200 responses
+// that do not opt out with Cache-Control no-store or private, or "Vary: *",
+// are not streams (server-sent events, NDJSON, multipart/x-mixed-replace),
+// and fit MaxBodyBytes.
+// Responses are keyed by the request URI and the VaryBy headers, and served
+// only to requests matching the header values their Vary names.
+func (c *ResponseCache) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, h := range c.varyBy {
+				w.Header().Add("Vary", h)
+			}
+			key := c.key(r)
+			if entry, ok := c.lookup(key, r); ok {
+				dst := w.Header()
+				for k, v := range entry.header {
+					dst[k] = v
+				}
+				dst.Set(CacheStatusHeaderName, "HIT")
+				w.WriteHeader(entry.status)
+				_, _ = w.Write(entry.body)
+				return
+			}
+
+			ct := &cacheTags{}
+			w.Header().Set(CacheStatusHeaderName, "MISS")
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK, capture: &bodyCapture{max: c.maxBodyBytes}}
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), ctxCacheTags, ct)))
+
+			if rw.status != http.StatusOK || !cacheable(rw.Header()) || rw.capture.total > int64(rw.capture.buf.Len()) {
+				return
+			}
+			vary, ok := varyNames(rw.Header())
+			if !ok {
+				return
+			}
+			header := rw.Header().Clone()
+			header.Del(CacheStatusHeaderName)
+			c.store(key, &cacheEntry{
+				status:  rw.status,
+				header:  header,
+				body:    rw.capture.buf.Bytes(),
+				tags:    ct.tags,
+				expires: time.Now().Add(c.ttl),
+				vary:    varyFingerprints(r, vary),
+			})
+		})
+	}
+}
+
+// InvalidateTags returns a middleware for mutation routes: once the handler
+// succeeds (status < 400) it invalidates the tags tagsFor returns, e.g. the
+// "user:{id}" of the user being updated.
+func (c *ResponseCache) InvalidateTags(tagsFor func(r *http.Request) []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			if rw.status < http.StatusBadRequest {
+				c.Invalidate(tagsFor(r)...)
+			}
+		})
+	}
+}
+
+// Invalidate evicts every entry carrying any of tags.
+func (c *ResponseCache) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			c.evictLocked(key)
+		}
+	}
+}
+
+// Purge evicts every entry.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.tags = make(map[string]map[string]struct{})
+}
+
+// Len reports how many entries are cached, including expired ones not yet
+// evicted.
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// key is the RequestHash of the path, query, and VaryBy headers, so query
+// order and path spelling do not split entries.
+func (c *ResponseCache) key(r *http.Request) string {
+	return RequestHash(r, nil, c.varyBy...)
+}
+
+func (c *ResponseCache) lookup(key string, r *http.Request) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	for name, fingerprint := range entry.vary {
+		if headerFingerprint(r.Header.Values(name)) != fingerprint {
+			return nil, false
+		}
+	}
+	if time.Now().After(entry.expires) {
+		c.evictLocked(key)
+		return nil, false
+	}
+	return entry, true
+}
+
+func (c *ResponseCache) store(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictLocked(key)
+	now := time.Now()
+	if full := c.maxEntries > 0 && len(c.entries) >= c.maxEntries; full || !now.Before(c.nextSweep) {
+		c.sweepLocked(now)
+		c.nextSweep = now.Add(c.ttl)
+	}
+	for c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictLocked(c.soonestLocked())
+	}
+	c.entries[key] = entry
+	for _, tag := range entry.tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+}
+
+// sweepLocked drops the entries expired at now.
+func (c *ResponseCache) sweepLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			c.evictLocked(key)
+		}
+	}
+}
+
+// soonestLocked returns the key of the entry closest to expiring, the oldest
+// as every entry shares the ttl.
+func (c *ResponseCache) soonestLocked() string {
+	var (
+		soonest string
+		expires time.Time
+	)
+	for key, entry := range c.entries {
+		if soonest == "" || entry.expires.Before(expires) {
+			soonest, expires = key, entry.expires
+		}
+	}
+	return soonest
+}
+
+func (c *ResponseCache) evictLocked(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+func cacheable(h http.Header) bool {
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	if strings.Contains(cc, "no-store") || strings.Contains(cc, "private") {
+		return false
+	}
+	mediaType, _ := ParseMediaType(h.Get("Content-Type"))
+	switch mediaType {
+	case ContentTypeEventStream, ContentTypeNDJSON, "multipart/x-mixed-replace":
+		return false
+	}
+	return true
+}
+
+// headerFingerprint hashes header values, so credentials used as a key are
+// not kept in memory in the clear.
+func headerFingerprint(values []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// varyNames lists the header names of a response's Vary; ok is false for
+// "Vary: *", which matches no later request.
+func varyNames(h http.Header) (names []string, ok bool) {
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			switch name = strings.TrimSpace(name); name {
+			case "":
+			case "*":
+				return nil, false
+			default:
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names, true
+}
+
+func varyFingerprints(r *http.Request, names []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		out[name] = headerFingerprint(r.Header.Values(name))
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// cacheRouter serves GET /users/{id} (tagged user:{id}) and PUT /users/{id}
+// (invalidating user:{id}), counting handler hits.
+func cacheRouter(cache *ResponseCache, hits *int) *Router {
+	r := NewRouter()
+	r.Use(cache.Middleware())
+	r.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		*hits++
+		TagCache(req, "user:"+Param(req, "id"))
+		_, _ = w.Write([]byte("user " + Param(req, "id") + " v" + strconv.Itoa(*hits)))
+	})
+	r.With(cache.InvalidateTags(func(req *http.Request) []string {
+		return []string{"user:" + Param(req, "id")}
+	})).Put("/users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return r
+}
+
+func get(t *testing.T, h http.Handler, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, http.NoBody))
+	return rec
+}
+
+func Test_ResponseCache_HitAndTagInvalidation(t *testing.T) {
+	var hits int
+	cache := NewResponseCache(time.Minute)
+	r := cacheRouter(cache, &hits)
+
+	first := get(t, r, http.MethodGet, "/users/1")
+	second := get(t, r, http.MethodGet, "/users/1")
+	if hits != 1 || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected a cache hit: hits=%d bodies %q / %q", hits, first.Body.String(), second.Body.String())
+	}
+	if first.Header().Get(CacheStatusHeaderName) != "MISS" || second.Header().Get(CacheStatusHeaderName) != "HIT" {
+		t.Fatalf("X-Cache: %q then %q", first.Header().Get(CacheStatusHeaderName), second.Header().Get(CacheStatusHeaderName))
+	}
+
+	get(t, r, http.MethodGet, "/users/2")
+	get(t, r, http.MethodPut, "/users/1")
+	if cache.Len() != 1 {
+		t.Fatalf("Len after invalidating user:1: got %d want 1", cache.Len())
+	}
+	if body := get(t, r, http.MethodGet, "/users/1").Body.String(); body != "user 1 v3" {
+		t.Fatalf("after invalidation: got %q want a fresh render", body)
+	}
+}
+
+func Test_ResponseCache_Expiry(t *testing.T) {
+	var hits int
+	cache := NewResponseCache(time.Millisecond)
+	r := cacheRouter(cache, &hits)
+	get(t, r, http.MethodGet, "/users/1")
+	time.Sleep(5 * time.Millisecond)
+	get(t, r, http.MethodGet, "/users/1")
+	if hits != 2 {
+		t.Fatalf("expired entry served: hits=%d", hits)
+	}
+}
+
+func Test_ResponseCache_RespectsNoStore(t *testing.T) {
+	var hits int
+	cache := NewResponseCache(time.Minute)
+	r := NewRouter()
+	r.Use(cache.Middleware())
+	r.Get("/me", func(w http.ResponseWriter, _ *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "private, max-age=60")
+	})
+	get(t, r, http.MethodGet, "/me")
+	get(t, r, http.MethodGet, "/me")
+	if hits != 2 || cache.Len() != 0 {
+		t.Fatalf("private response cached: hits=%d len=%d", hits, cache.Len())
+	}
+}
+
+func Test_ResponseCache_Purge(t *testing.T) {
+	var hits int
+	cache := NewResponseCache(time.Minute)
+	r := cacheRouter(cache, &hits)
+	get(t, r, http.MethodGet, "/users/1")
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Fatalf("Len after Purge: %d", cache.Len())
+	}
+}
+
+func Test_ResponseCache_VaryBy(t *testing.T) {
+	var hits int
+	cache := NewResponseCache(time.Minute).VaryBy("Authorization")
+	r := NewRouter()
+	r.Use(cache.Middleware())
+	r.Get("/me", func(w http.ResponseWriter, req *http.Request) {
+		hits++
+		_, _ = w.Write([]byte(req.Header.Get("Authorization")))
+	})
+	call := func(auth string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", http.NoBody)
+		req.Header.Set("Authorization", auth)
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	call("alice")
+	if rec := call("bob"); rec.Body.String() != "bob" {
+		t.Fatalf("bob got %q", rec.Body.String())
+	}
+	if rec := call("alice"); rec.Body.String() != "alice" || rec.Header().Get(CacheStatusHeaderName) != "HIT" {
+		t.Fatalf("alice got %q (%s)", rec.Body.String(), rec.Header().Get(CacheStatusHeaderName))
+	}
+	if rec := call("alice"); rec.Header().Get("Vary") != "Authorization" {
+		t.Fatalf("Vary = %q", rec.Header().Values("Vary"))
+	}
+	if hits != 2 || cache.Len() != 2 {
+		t.Fatalf("hits=%d len=%d", hits, cache.Len())
+	}
+}
+
+func Test_ResponseCache_HonorsVary(t *testing.T) {
+	var hits int
+	cache := NewResponseCache(time.Minute)
+	r := NewRouter()
+	r.Use(cache.Middleware())
+	r.Get("/greeting", func(w http.ResponseWriter, req *http.Request) {
+		hits++
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte(req.Header.Get("Accept-Language")))
+	})
+	r.Get("/star", func(w http.ResponseWriter, _ *http.Request) {
+		hits++
+		w.Header().Set("Vary", "*")
+	})
+	call := func(path, lang string) string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		req.Header.Set("Accept-Language", lang)
+		r.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	call("/greeting", "en")
+	if got := call("/greeting", "de"); got != "de" {
+		t.Fatalf("de got %q", got)
+	}
+	call("/greeting", "de")
+	if hits != 2 {
+		t.Fatalf("hits = %d, want the de variant cached", hits)
+	}
+
+	call("/star", "en")
+	call("/star", "en")
+	if hits != 4 {
+		t.Fatalf("Vary: * cached; hits = %d", hits)
+	}
+}
+
+func Test_ResponseCache_Bounds(t *testing.T) {
+	cache := NewResponseCache(time.Minute).MaxEntries(2).MaxBodyBytes(4)
+	r := NewRouter()
+	r.Use(cache.Middleware())
+	r.Get("/small/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	r.Get("/large", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("too large"))
+	})
+	r.Get("/events", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeEventStream)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	if rec := get(t, r, http.MethodGet, "/large"); rec.Body.String() != "too large" || cache.Len() != 0 {
+		t.Fatalf("large body: %q, %d entries", rec.Body.String(), cache.Len())
+	}
+	if get(t, r, http.MethodGet, "/events"); cache.Len() != 0 {
+		t.Fatalf("event stream cached")
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		get(t, r, http.MethodGet, "/small/"+id)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("entries = %d, want 2", cache.Len())
+	}
+	if rec := get(t, r, http.MethodGet, "/small/1"); rec.Header().Get(CacheStatusHeaderName) != "MISS" {
+		t.Fatalf("oldest entry not evicted")
+	}
+}
+
+func Test_ResponseCache_SweepsExpired(t *testing.T) {
+	var hits int
+	cache := NewResponseCache(time.Millisecond)
+	r := cacheRouter(cache, &hits)
+	get(t, r, http.MethodGet, "/users/1")
+	get(t, r, http.MethodGet, "/users/2")
+	time.Sleep(5 * time.Millisecond)
+	get(t, r, http.MethodGet, "/users/3")
+	if cache.Len() != 1 {
+		t.Fatalf("entries = %d, want the expired ones swept", cache.Len())
+	}
+}
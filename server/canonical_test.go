@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_CanonicalRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/v1//orders/./7/../8?b=2&a=1", nil)
+	r.Header.Set("X-Tenant", "acme")
+	r.Header.Set("Accept", "application/json")
+
+	got := CanonicalRequest(r, []byte("{}"), "Accept", "x-tenant", "Authorization")
+	want := strings.Join([]string{"PUT", "", "/v1/orders/8", "a=1&b=2", "accept:application/json", "x-tenant:acme", BodyDigest([]byte("{}"))}, "\n")
+	if got != want {
+		t.Fatalf("canonical =\n%s\nwant\n%s", got, want)
+	}
+
+	same := httptest.NewRequest(http.MethodPut, "/v1/orders/8?a=1&b=2", nil)
+	same.Header.Set("x-tenant", "acme")
+	same.Header.Set("Accept", "application/json")
+	same.Header.Set("User-Agent", "curl")
+	if RequestHash(r, []byte("{}"), "Accept", "X-Tenant") != RequestHash(same, []byte("{}"), "accept", "x-tenant") {
+		t.Fatal("equivalent requests hash apart")
+	}
+	if RequestHash(r, nil, "Accept") == RequestHash(r, []byte("{}"), "Accept") {
+		t.Fatal("body not hashed")
+	}
+}
+
+func Test_ResponseCache_QueryOrder(t *testing.T) {
+	calls := 0
+	h := NewResponseCache(time.Minute).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte("ok"))
+	}))
+	for _, target := range []string{"/items?a=1&b=2", "/items?b=2&a=1"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// ClientInfo identifies the client build behind a request, as reported by the
+// X-Client-* headers the toaweme/http client sends.
+type ClientInfo struct {
+	Platform    string
+	Version     string
+	ClientID    string
+	SessionID   string
+	RequestID   string
+	UserAgent   string
+	ServiceName string
+}
+
+// ClientInfoFromRequest reads the client identification headers off r.
+func ClientInfoFromRequest(r *http.Request) ClientInfo {
+	return ClientInfo{
+		Platform:    r.Header.Get(ClientPlatformHeaderName),
+		Version:     r.Header.Get(ClientAppVersionHeaderName),
+		ClientID:    r.Header.Get(ClientIDHeaderName),
+		SessionID:   r.Header.Get(ClientSessionIDHeaderName),
+		RequestID:   r.Header.Get(ClientRequestIDHeaderName),
+		UserAgent:   r.Header.Get(ClientUserAgentHeaderName),
+		ServiceName: r.Header.Get(ServiceNameHeaderName),
+	}
+}
+
+// LogArgs returns the non-empty fields as logger key/value pairs.
+func (c ClientInfo) LogArgs() []any {
+	var args []any
+	for _, kv := range [...]struct{ key, value string }{
+		{"client-platform", c.Platform},
+		{"client-version", c.Version},
+		{"client-id", c.ClientID},
+		{"session-id", c.SessionID},
+		{"request-id", c.RequestID},
+		{"service-name", c.ServiceName},
+	} {
+		if kv.value != "" {
+			args = append(args, kv.key, kv.value)
+		}
+	}
+	return args
+}
+
+// ContextWithClientInfo returns a copy of ctx carrying info.
+func ContextWithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, ctxClientInfo, info)
+}
+
+// ClientInfoFromContext reads the info set by ClientInfoMiddleware or
+// ContextWithClientInfo. ok is false if unset.
+func ClientInfoFromContext(ctx context.Context) (ClientInfo, bool) {
+	v, ok := ctx.Value(ctxClientInfo).(ClientInfo)
+	return v, ok
+}
+
+// ClientInfoMiddleware parses the client identification headers into a
+// ClientInfo and stores it in the request context, so handlers, logs, and
+// metrics agree on which client build made the call.
+func ClientInfoMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := ContextWithClientInfo(r.Context(), ClientInfoFromRequest(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OutboundHeaders returns the correlation headers of the inbound request in
+// ctx (request id, session id, client id) as parsed by ClientInfoMiddleware,
+// for forwarding to upstream calls. Plug it into the client with
+// http.WithContextHeaders(server.OutboundHeaders); handlers then only need to
+// pass r.Context() through.
+func OutboundHeaders(ctx context.Context) map[string]string {
+	info, ok := ClientInfoFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, 3)
+	for name, value := range map[string]string{
+		ClientRequestIDHeaderName: info.RequestID,
+		ClientSessionIDHeaderName: info.SessionID,
+		ClientIDHeaderName:        info.ClientID,
+	} {
+		if value != "" {
+			out[name] = value
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_ClientInfoMiddleware(t *testing.T) {
+	var (
+		got ClientInfo
+		ok  bool
+	)
+	h := ClientInfoMiddleware()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got, ok = ClientInfoFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set("X-Client-Platform", "ios")
+	r.Header.Set("X-Client-Version", "4.2.0")
+	r.Header.Set("X-Client-ID", "device-1")
+	r.Header.Set("X-Session-ID", "sess-1")
+	r.Header.Set("X-Request-ID", "req-1")
+	r.Header.Set("User-Agent", "app/4.2.0")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := ClientInfo{Platform: "ios", Version: "4.2.0", ClientID: "device-1", SessionID: "sess-1", RequestID: "req-1", UserAgent: "app/4.2.0"}
+	if !ok || got != want {
+		t.Fatalf("got %+v (ok=%v) want %+v", got, ok, want)
+	}
+}
+
+func Test_ClientInfoFromContext_Unset(t *testing.T) {
+	if _, ok := ClientInfoFromContext(context.Background()); ok {
+		t.Fatal("expected ok=false when unset")
+	}
+}
+
+func Test_ClientInfo_LogArgs(t *testing.T) {
+	got := ClientInfo{Platform: "web", ClientID: "c1"}.LogArgs()
+	want := []any{"client-platform", "web", "client-id", "c1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+func Test_OutboundHeaders(t *testing.T) {
+	if got := OutboundHeaders(context.Background()); got != nil {
+		t.Fatalf("without ClientInfo: got %v want nil", got)
+	}
+	ctx := ContextWithClientInfo(context.Background(), ClientInfo{Platform: "ios", RequestID: "req-1", ClientID: "device-1"})
+	want := map[string]string{"X-Request-ID": "req-1", "X-Client-ID": "device-1"}
+	if got := OutboundHeaders(ctx); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
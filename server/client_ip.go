@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses CIDRs ("10.0.0.0/8") and bare addresses
+// ("192.0.2.1") into the prefixes RealIPMiddleware trusts to report the
+// client address.
+func ParseTrustedProxies(cidrs ...string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse trusted proxy %q: %w", c, err)
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trusted proxy %q: %w", c, err)
+		}
+		out = append(out, prefix.Masked())
+	}
+	return out, nil
+}
+
+// RealIPMiddleware resolves the real client address with ResolveClientIP and
+// stores it in the request context for ClientIP. Mount it first so rate
+// limits, allowlists, and access logs all see the same address.
+func RealIPMiddleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ResolveClientIP(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxClientIP, ip)))
+		})
+	}
+}
+
+// ClientIP returns the client address resolved by RealIPMiddleware, or the
+// host part of RemoteAddr when the middleware is not mounted.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ctxClientIP).(string); ok {
+		return ip
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+// ResolveClientIP returns the address of the client that made r. Forwarding
+// headers are only believed when the direct peer is a trusted proxy; the hops
+// in Forwarded (or, failing that, X-Forwarded-For) are then walked right to
+// left and the first untrusted address wins, so a client cannot spoof its IP
+// by prepending entries. X-Real-IP is the last resort.
+func ResolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := remoteHost(r.RemoteAddr)
+	if !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	hops := forwardedFor(r.Header.Values("Forwarded"))
+	if len(hops) == 0 {
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(v, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !isTrusted(hops[i], trusted) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return peer
+}
+
+// forwardedFor extracts the for= node of every RFC 7239 Forwarded element,
+// stripping quotes, IPv6 brackets, and ports.
+func forwardedFor(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(key, "for") {
+					continue
+				}
+				val = strings.Trim(val, `"`)
+				if strings.HasPrefix(val, "[") {
+					if end := strings.Index(val, "]"); end > 0 {
+						val = val[1:end]
+					}
+				} else {
+					val = remoteHost(val)
+				}
+				out = append(out, val)
+			}
+		}
+	}
+	return out
+}
+
+// remoteHost strips the port from addr when it has one.
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
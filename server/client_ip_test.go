@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies("10.0.0.0/8", "192.0.2.1", "::ffff:198.51.100.7")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.0.2.1/32", "198.51.100.7/32"}
+	for i, p := range got {
+		if p.String() != want[i] {
+			t.Fatalf("prefix %d: got %s want %s", i, p, want[i])
+		}
+	}
+	if _, err := ParseTrustedProxies("not-an-ip"); err == nil {
+		t.Fatal("expected error for an invalid entry")
+	}
+}
+
+func Test_ResolveClientIP(t *testing.T) {
+	trusted, _ := ParseTrustedProxies("10.0.0.0/8")
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{name: "untrusted peer ignores headers", remote: "203.0.113.9:1234", headers: map[string]string{"X-Forwarded-For": "1.2.3.4"}, want: "203.0.113.9"},
+		{name: "xff rightmost untrusted", remote: "10.0.0.2:1234", headers: map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.1, 10.0.0.5"}, want: "198.51.100.1"},
+		{name: "forwarded wins over xff", remote: "10.0.0.2:1234", headers: map[string]string{"Forwarded": `for=192.0.2.60;proto=http, for="[2001:db8::17]:4711"`, "X-Forwarded-For": "6.6.6.6"}, want: "2001:db8::17"},
+		{name: "all hops trusted", remote: "10.0.0.2:1234", headers: map[string]string{"X-Forwarded-For": "10.1.1.1, 10.2.2.2"}, want: "10.1.1.1"},
+		{name: "x-real-ip fallback", remote: "10.0.0.2:1234", headers: map[string]string{"X-Real-IP": "198.51.100.2"}, want: "198.51.100.2"},
+		{name: "no headers", remote: "10.0.0.2:1234", want: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := ResolveClientIP(r, trusted); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_RealIPMiddleware_SetsClientIP(t *testing.T) {
+	trusted, _ := ParseTrustedProxies("10.0.0.0/8")
+	var got string
+	h := RealIPMiddleware(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "198.51.100.1" {
+		t.Fatalf("ClientIP: got %q", got)
+	}
+}
+
+func Test_ClientIP_WithoutMiddleware(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "203.0.113.9:1234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := ClientIP(r); got != "203.0.113.9" {
+		t.Fatalf("ClientIP: got %q", got)
+	}
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codec encodes and decodes bodies of one media type. It mirrors the
+// client's Codec, so a msgpack or protobuf codec written once serves both
+// sides.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the default Codec.
+type JSONCodec struct{}
+
+// ContentType implements Codec.
+func (JSONCodec) ContentType() string { return ContentTypeJSON }
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// Codecs is a registry of codecs by media type. The first registered codec is
+// the default, used when a request names no type or accepts anything.
+type Codecs struct {
+	byType map[string]Codec
+	def    Codec
+}
+
+// NewCodecs builds a registry from codecs; JSONCodec is the default when none
+// are given.
+func NewCodecs(codecs ...Codec) *Codecs {
+	if len(codecs) == 0 {
+		codecs = []Codec{JSONCodec{}}
+	}
+	c := &Codecs{byType: make(map[string]Codec, len(codecs)), def: codecs[0]}
+	for _, codec := range codecs {
+		c.byType[codec.ContentType()] = codec
+	}
+	return c
+}
+
+// negotiated is the codec pair chosen for one request.
+type negotiated struct {
+	request, response Codec
+}
+
+// Negotiate picks the request codec from Content-Type and the response codec
+// from Accept (q-values and wildcards honored) and stores them for Decode,
+// Encode, and Handle. A body of an unregistered type is rejected with 415 and
+// an Accept nothing satisfies with 406.
+func (c *Codecs) Negotiate() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			response, ok := c.forAccept(r.Header.Get("Accept"))
+			if !ok {
+				WriteError(w, http.StatusNotAcceptable, fmt.Errorf("none of %q can be produced", r.Header.Get("Accept")))
+				return
+			}
+			request := c.def
+			if ct := r.Header.Get("Content-Type"); ct != "" {
+				mediaType, _ := ParseMediaType(ct)
+				if request, ok = c.byType[mediaType]; !ok {
+					writeCodecError(w, response, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", mediaType))
+					return
+				}
+			}
+			ctx := context.WithValue(r.Context(), ctxCodecs, negotiated{request: request, response: response})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// forAccept returns the registered codec the Accept header prefers.
+func (c *Codecs) forAccept(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return c.def, true
+	}
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{mediaType, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, cand := range candidates {
+		if codec, ok := c.byType[cand.mediaType]; ok {
+			return codec, true
+		}
+		if cand.mediaType == "*/*" {
+			return c.def, true
+		}
+		if prefix, ok := strings.CutSuffix(cand.mediaType, "/*"); ok {
+			if strings.HasPrefix(c.def.ContentType(), prefix+"/") {
+				return c.def, true
+			}
+			for mediaType, codec := range c.byType {
+				if strings.HasPrefix(mediaType, prefix+"/") {
+					return codec, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+func codecsFrom(ctx context.Context) negotiated {
+	if n, ok := ctx.Value(ctxCodecs).(negotiated); ok {
+		return n
+	}
+	return negotiated{request: JSONCodec{}, response: JSONCodec{}}
+}
+
+// Decode reads the request body into v with the codec Negotiate chose, or
+// JSON without it. An empty body returns io.EOF.
+func Decode(r *http.Request, v any) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(data) == 0 {
+		return io.EOF
+	}
+	return codecsFrom(r.Context()).request.Unmarshal(data, v)
+}
+
+// Encode writes v with status in the format Negotiate chose for the response,
+// or JSON without it.
+func Encode(w http.ResponseWriter, r *http.Request, status int, v any) {
+	codec := codecsFrom(r.Context()).response
+	data, err := codec.Marshal(v)
+	if err != nil {
+		writeCodecError(w, codec, http.StatusInternalServerError, fmt.Errorf("failed to encode response: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// writeCodecError writes err as an ErrorResponse in codec's format, falling
+// back to JSON when codec cannot encode it.
+func writeCodecError(w http.ResponseWriter, codec Codec, status int, err error) {
+	data, mErr := codec.Marshal(ErrorResponse{Error: err.Error()})
+	if mErr != nil {
+		WriteError(w, status, err)
+		return
+	}
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// StatusError carries the status a Handle function wants for its error.
+// Other errors answer 500.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// Handle adapts a typed function to an http.Handler: the body is decoded into
+// Req and the result encoded as the client asked, both through the codecs
+// Negotiate chose. Requests without a body leave Req zero. Mount Negotiate
+// ahead of it for formats other than JSON.
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if r.Body != nil && r.Body != http.NoBody {
+			if err := Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+				writeCodecError(w, codecsFrom(r.Context()).response, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+				return
+			}
+		}
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			status := http.StatusInternalServerError
+			var serr *StatusError
+			if errors.As(err, &serr) {
+				status = serr.Status
+			}
+			writeCodecError(w, codecsFrom(r.Context()).response, status, err)
+			return
+		}
+		Encode(w, r, http.StatusOK, resp)
+	}
+}
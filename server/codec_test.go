@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// xmlCodec stands in for msgpack/protobuf: any Codec plugs in the same way.
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string                { return "application/xml" }
+func (xmlCodec) Marshal(v any) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+
+type greetRequest struct {
+	XMLName xml.Name `json:"-" xml:"greet"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+type greetResponse struct {
+	XMLName xml.Name `json:"-" xml:"greeting"`
+	Message string   `json:"message" xml:"message"`
+}
+
+func greetServer() http.Handler {
+	codecs := NewCodecs(JSONCodec{}, xmlCodec{})
+	r := NewRouter()
+	r.Use(codecs.Negotiate())
+	r.Post("/greet", Handle(func(_ context.Context, req greetRequest) (greetResponse, error) {
+		if req.Name == "" {
+			return greetResponse{}, &StatusError{Status: http.StatusUnprocessableEntity, Err: errors.New("name is required")}
+		}
+		return greetResponse{Message: "hello " + req.Name}, nil
+	}))
+	return r
+}
+
+func Test_Codecs_Negotiation(t *testing.T) {
+	h := greetServer()
+	for _, tc := range []struct {
+		name, contentType, accept, body string
+		status                          int
+		respType, contains              string
+	}{
+		{"json both ways", "application/json", "", `{"name":"ann"}`, 200, "application/json", `"message":"hello ann"`},
+		{"xml in json out", "application/xml", "application/json", `<greet><name>bo</name></greet>`, 200, "application/json", `hello bo`},
+		{"json in xml out", "application/json", "application/xml;q=0.9, text/html;q=0.1", `{"name":"cy"}`, 200, "application/xml", `<message>hello cy</message>`},
+		{"wildcard accept", "application/json", "*/*", `{"name":"di"}`, 200, "application/json", `hello di`},
+		{"unsupported body", "text/csv", "", `a,b`, 415, "application/json", `unsupported content type`},
+		{"unacceptable", "application/json", "text/html", `{}`, 406, "application/json", `can be produced`},
+		{"typed error", "application/xml", "application/xml", `<greet></greet>`, 422, "application/xml", `name is required`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d want %d: %s", rec.Code, tc.status, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.respType {
+				t.Fatalf("Content-Type = %q want %q", got, tc.respType)
+			}
+			if !bytes.Contains(rec.Body.Bytes(), []byte(tc.contains)) {
+				t.Fatalf("body %q lacks %q", rec.Body.String(), tc.contains)
+			}
+		})
+	}
+}
+
+func Test_Handle_WithoutNegotiate(t *testing.T) {
+	h := Handle(func(_ context.Context, req greetRequest) (greetResponse, error) {
+		return greetResponse{Message: "hi " + req.Name}, nil
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", http.NoBody))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"message":"hi "`) {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+}
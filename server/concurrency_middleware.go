@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTooManyRequests is the error body written when a ConcurrencyLimit
+// rejects a request.
+var ErrTooManyRequests = errors.New("too many concurrent requests")
+
+// ConcurrencyConfig caps how many requests a route group serves at once.
+type ConcurrencyConfig struct {
+	// MaxInFlight is how many requests run concurrently. Must be > 0.
+	MaxInFlight int
+	// MaxQueue is how many further requests may wait for a slot; once it is
+	// full, new requests are rejected with 429 immediately. 0 disables queueing.
+	MaxQueue int
+	// QueueTimeout bounds how long a queued request waits before it is
+	// rejected with 429. 0 waits until the request context ends.
+	QueueTimeout time.Duration
+	// RetryAfter, when set, is sent as Retry-After on rejections.
+	RetryAfter time.Duration
+}
+
+// ConcurrencyLimit returns a middleware that lets at most cfg.MaxInFlight
+// requests through at a time, queueing up to cfg.MaxQueue more and answering
+// the rest with 429. Each call builds an independent limit, so mount one per
+// route group (e.g. two concurrent report exports) with Group or With.
+func ConcurrencyLimit(cfg ConcurrencyConfig) func(http.Handler) http.Handler {
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 1
+	}
+	slots := make(chan struct{}, cfg.MaxInFlight)
+	var queued atomic.Int64
+
+	reject := func(w http.ResponseWriter) {
+		if cfg.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((cfg.RetryAfter+time.Second-1)/time.Second)))
+		}
+		WriteError(w, http.StatusTooManyRequests, ErrTooManyRequests)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				if queued.Add(1) > int64(cfg.MaxQueue) {
+					queued.Add(-1)
+					reject(w)
+					return
+				}
+				acquired := waitForSlot(r, slots, cfg.QueueTimeout)
+				queued.Add(-1)
+				if !acquired {
+					reject(w)
+					return
+				}
+			}
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func waitForSlot(r *http.Request, slots chan struct{}, timeout time.Duration) bool {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-expired:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
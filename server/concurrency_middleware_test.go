@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockUntil returns a handler that signals entry and then blocks on release.
+func blockUntil(entered chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func Test_ConcurrencyLimit_RejectsOverflow(t *testing.T) {
+	entered := make(chan struct{}, 4)
+	release := make(chan struct{})
+	h := ConcurrencyLimit(ConcurrencyConfig{MaxInFlight: 1, RetryAfter: 2 * time.Second})(blockUntil(entered, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("overflow: got %d want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "2" {
+		t.Fatalf("Retry-After: got %q want 2", rec.Header().Get("Retry-After"))
+	}
+	close(release)
+	wg.Wait()
+}
+
+func Test_ConcurrencyLimit_QueuesUntilSlotFrees(t *testing.T) {
+	entered := make(chan struct{}, 4)
+	release := make(chan struct{})
+	h := ConcurrencyLimit(ConcurrencyConfig{MaxInFlight: 1, MaxQueue: 1})(blockUntil(entered, release))
+
+	codes := make(chan int, 2)
+	serve := func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		codes <- rec.Code
+	}
+	go serve()
+	<-entered
+	go serve() // queued behind the first
+
+	select {
+	case <-entered:
+		t.Fatal("queued request ran before a slot freed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Fatalf("request %d: got %d want 200", i, code)
+		}
+	}
+}
+
+func Test_ConcurrencyLimit_QueueTimeout(t *testing.T) {
+	entered := make(chan struct{}, 4)
+	release := make(chan struct{})
+	defer close(release)
+	h := ConcurrencyLimit(ConcurrencyConfig{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})(blockUntil(entered, release))
+
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	<-entered
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("timed-out queue: got %d want 429", rec.Code)
+	}
+}
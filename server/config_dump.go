@@ -0,0 +1,233 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces secrets in ConfigHandler and MaskConfig output,
+// mirrored from github.com/toaweme/http.
+const RedactedValue = "[REDACTED]"
+
+// secretSuffixes mark config field and map key names whose values
+// MaskConfig hides, matched against the end of the name lowercased with
+// "_" and "-" removed. Keep in sync with github.com/toaweme/http.
+var secretSuffixes = []string{
+	"password",
+	"secret",
+	"token",
+	"apikey",
+	"privatekey",
+	"credentials",
+	"authorization",
+	"cookie",
+}
+
+// maxConfigDepth stops MaskConfig following pointers that loop.
+const maxConfigDepth = 16
+
+// Config returns the configuration the server runs with: cfg with defaults
+// filled in, the timeouts and header limit as Options left them on the
+// *http.Server, and the bound port once Listening is closed.
+func (s *Server) Config() ServerConfig {
+	cfg := s.config
+	if addr, ok := s.Addr().(*net.TCPAddr); ok {
+		cfg.Port = addr.Port
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	cfg.ReadTimeout = s.http.ReadTimeout
+	cfg.ReadHeaderTimeout = s.http.ReadHeaderTimeout
+	cfg.WriteTimeout = s.http.WriteTimeout
+	cfg.IdleTimeout = s.http.IdleTimeout
+	cfg.MaxHeaderBytes = s.http.MaxHeaderBytes
+	if cfg.MaxHeaderBytes <= 0 {
+		cfg.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+	if cfg.Health.Enabled {
+		if cfg.Health.LivenessPath == "" {
+			cfg.Health.LivenessPath = DefaultLivenessPath
+		}
+		if cfg.Health.ReadinessPath == "" {
+			cfg.Health.ReadinessPath = DefaultReadinessPath
+		}
+	}
+	if cfg.Prefork != 0 {
+		cfg.Prefork = preforkWorkers(cfg.Prefork)
+	}
+	return cfg
+}
+
+// ConfigHandler returns a handler that reports sections as JSON with
+// secrets masked by MaskConfig, answering "what config is this pod actually
+// running" without a shell on it. A section that is a func() any is called
+// on every request, for configuration that changes at runtime. Mount it
+// behind auth:
+//
+//	r.With(adminOnly).Get("/admin/config", server.ConfigHandler(map[string]any{
+//		"server": srv.Config(),
+//		"client": clientConfig.Dump(),
+//		"app":    appConfig,
+//	}))
+func ConfigHandler(sections map[string]any, mask ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		out := make(map[string]any, len(sections))
+		for name, section := range sections {
+			if fn, ok := section.(func() any); ok {
+				section = fn()
+			}
+			out[name] = MaskConfig(section, mask...)
+		}
+		WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// MaskConfig turns a configuration value into maps, slices, and scalars
+// ready to encode as JSON, replacing secrets with RedactedValue: fields and
+// map keys named like a password, secret, token, API key, private key, or
+// credential, those named in names (case-insensitively), and the password
+// of any URL (written "xxxxx"). Struct fields are keyed by their json tag,
+// or their name when untagged; fields tagged "-", functions, and channels
+// are left out. Durations are written as "1m30s". Empty secrets stay empty,
+// so an unset one shows.
+func MaskConfig(v any, names ...string) any {
+	m := configMasker{names: make(map[string]struct{}, len(names))}
+	for _, name := range names {
+		m.names[strings.ToLower(name)] = struct{}{}
+	}
+	out, _ := m.value(reflect.ValueOf(v), 0)
+	return out
+}
+
+type configMasker struct {
+	names map[string]struct{}
+}
+
+func (m configMasker) secret(name string) bool {
+	if _, ok := m.names[strings.ToLower(name)]; ok {
+		return true
+	}
+	name = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// field masks v when it is a non-empty secret called name.
+func (m configMasker) field(name string, v reflect.Value, depth int) (any, bool) {
+	if m.secret(name) && v.IsValid() && !v.IsZero() {
+		switch v.Kind() {
+		case reflect.Func, reflect.Chan:
+		default:
+			return RedactedValue, true
+		}
+	}
+	return m.value(v, depth+1)
+}
+
+// value converts v, reporting false for what is left out.
+func (m configMasker) value(v reflect.Value, depth int) (any, bool) {
+	if !v.IsValid() {
+		return nil, true
+	}
+	if depth > maxConfigDepth {
+		return fmt.Sprintf("%s(...)", v.Type()), true
+	}
+	switch v.Type() {
+	case reflect.TypeFor[time.Duration]():
+		return time.Duration(v.Int()).String(), true
+	case reflect.TypeFor[time.Time]():
+		return v.Interface().(time.Time).Format(time.RFC3339), true
+	}
+	switch v.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil, false
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, true
+		}
+		return m.value(v.Elem(), depth)
+	case reflect.Struct:
+		return m.structValue(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, true
+		}
+		out := make(map[string]any, v.Len())
+		for key, item := range v.Seq2() {
+			name := fmt.Sprint(key.Interface())
+			if item, ok := m.field(name, item, depth); ok {
+				out[name] = item
+			}
+		}
+		return out, true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, true
+		}
+		out := make([]any, 0, v.Len())
+		for i := range v.Len() {
+			if item, ok := m.value(v.Index(i), depth+1); ok {
+				out = append(out, item)
+			}
+		}
+		return out, true
+	case reflect.String:
+		return maskURLPassword(v.String()), true
+	}
+	return v.Interface(), true
+}
+
+// structValue converts the exported fields of v; a struct with none, such
+// as a store or a logger behind an interface, is written as its type name.
+func (m configMasker) structValue(v reflect.Value, depth int) (any, bool) {
+	t := v.Type()
+	out := make(map[string]any, t.NumField())
+	exported := false
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		exported = true
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		if item, ok := m.field(name, v.Field(i), depth); ok {
+			out[name] = item
+		}
+	}
+	if !exported && t.NumField() > 0 {
+		return t.String(), true
+	}
+	return out, true
+}
+
+// maskURLPassword hides the password of a URL with credentials as
+// url.URL.Redacted does, leaving every other string as it is.
+func maskURLPassword(s string) string {
+	if !strings.Contains(s, "@") || !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Server_Config_Effective(t *testing.T) {
+	s := NewServer(ServerConfig{Port: 8080, Health: HealthConfig{Enabled: true}}, NewRouter(), nopLogger{}, WithWriteTimeout(7*time.Second))
+	cfg := s.Config()
+	if cfg.ShutdownTimeout != defaultShutdownTimeout || cfg.ReadHeaderTimeout != defaultReadHeaderTimeout {
+		t.Errorf("timeouts = %v, %v, want the defaults", cfg.ShutdownTimeout, cfg.ReadHeaderTimeout)
+	}
+	if cfg.WriteTimeout != 7*time.Second {
+		t.Errorf("WriteTimeout = %v, want the Option's", cfg.WriteTimeout)
+	}
+	if cfg.MaxHeaderBytes != http.DefaultMaxHeaderBytes || cfg.Health.ReadinessPath != DefaultReadinessPath {
+		t.Errorf("MaxHeaderBytes = %d, ReadinessPath = %q", cfg.MaxHeaderBytes, cfg.Health.ReadinessPath)
+	}
+}
+
+func Test_ConfigHandler_Masks(t *testing.T) {
+	type database struct {
+		URL      string `json:"url"`
+		Password string `json:"password"`
+		PoolSize int    `json:"pool_size"`
+	}
+	calls := 0
+	h := ConfigHandler(map[string]any{
+		"server": ServerConfig{Port: 8080, ShutdownTimeout: time.Minute, TLS: TLSConfig{CertFile: "/tls/cert.pem"}},
+		"db":     database{URL: "postgres://app:pw@db:5432/app", Password: "pw", PoolSize: 10},
+		"client": map[string]any{"headers": map[string]any{"Authorization": "[REDACTED]", "X-Tenant": "acme"}},
+		"flags":  func() any { calls++; return map[string]bool{"beta": true} },
+	}, "x-tenant")
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, leaked := range []string{":pw@", `"pw"`, "acme"} {
+		if strings.Contains(body, leaked) {
+			t.Errorf("dump leaks %s: %s", leaked, body)
+		}
+	}
+
+	var got struct {
+		Server map[string]any `json:"server"`
+		DB     map[string]any `json:"db"`
+		Flags  map[string]any `json:"flags"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Server["ShutdownTimeout"] != "1m0s" || got.Server["TLS"].(map[string]any)["CertFile"] != "/tls/cert.pem" {
+		t.Errorf("server = %v", got.Server)
+	}
+	if _, ok := got.Server["TLS"].(map[string]any)["GetCertificate"]; ok {
+		t.Error("dump includes a function")
+	}
+	if got.DB["url"] != "postgres://app:xxxxx@db:5432/app" || got.DB["password"] != RedactedValue || got.DB["pool_size"] != float64(10) {
+		t.Errorf("db = %v", got.DB)
+	}
+	if got.Flags["beta"] != true || calls != 1 {
+		t.Errorf("flags = %v after %d calls", got.Flags, calls)
+	}
+}
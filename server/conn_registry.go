@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConnectionClosed is the context cause of a tracked connection that was
+// closed through ConnRegistry.Close.
+var ErrConnectionClosed = errors.New("connection closed by operator")
+
+// Connection describes one tracked long-lived connection.
+type Connection struct {
+	ID        string        `json:"id"`
+	Kind      string        `json:"kind"`
+	ClientID  string        `json:"client_id,omitempty"`
+	ClientIP  string        `json:"client_ip"`
+	Path      string        `json:"path"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// ConnRegistry tracks active streaming connections (SSE, WebSocket, ...) so
+// operators can see who is connected and force a connection closed.
+type ConnRegistry struct {
+	mu    sync.Mutex
+	conns map[string]*trackedConn
+	seq   atomic.Uint64
+}
+
+type trackedConn struct {
+	info   Connection
+	cancel context.CancelCauseFunc
+}
+
+// NewConnRegistry builds an empty ConnRegistry.
+func NewConnRegistry() *ConnRegistry {
+	return &ConnRegistry{conns: make(map[string]*trackedConn)}
+}
+
+// Track registers the connection behind r and returns a context that is
+// canceled with ErrConnectionClosed if an operator closes it. Stream until
+// that context is done, and call done when the handler returns. The client id
+// comes from ClientInfoMiddleware, falling back to the X-Client-ID header.
+func (c *ConnRegistry) Track(r *http.Request, kind string) (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancelCause(r.Context())
+
+	clientID := r.Header.Get(ClientIDHeaderName)
+	if info, ok := ClientInfoFromContext(r.Context()); ok && info.ClientID != "" {
+		clientID = info.ClientID
+	}
+	id := strconv.FormatUint(c.seq.Add(1), 10)
+	tc := &trackedConn{
+		info: Connection{
+			ID:        id,
+			Kind:      kind,
+			ClientID:  clientID,
+			ClientIP:  ClientIP(r),
+			Path:      r.URL.Path,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	c.mu.Lock()
+	c.conns[id] = tc
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.conns, id)
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Middleware tracks every request through it as a connection of kind for as
+// long as the handler runs. Mount it on streaming routes only.
+func (c *ConnRegistry) Middleware(kind string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, done := c.Track(r, kind)
+			defer done()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// List returns the active connections, oldest first.
+func (c *ConnRegistry) List() []Connection {
+	c.mu.Lock()
+	out := make([]Connection, 0, len(c.conns))
+	for _, tc := range c.conns {
+		out = append(out, tc.info)
+	}
+	c.mu.Unlock()
+
+	now := time.Now()
+	for i := range out {
+		out[i].Duration = now.Sub(out[i].StartedAt)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+// Count returns the number of active connections.
+func (c *ConnRegistry) Count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.conns)
+}
+
+// CountByClient returns active connections per client id; connections
+// without one are counted under "".
+func (c *ConnRegistry) CountByClient() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int)
+	for _, tc := range c.conns {
+		out[tc.info.ClientID]++
+	}
+	return out
+}
+
+// Close cancels the connection's context with ErrConnectionClosed. It reports
+// false when no such connection is active.
+func (c *ConnRegistry) Close(id string) bool {
+	c.mu.Lock()
+	tc, ok := c.conns[id]
+	c.mu.Unlock()
+	if ok {
+		tc.cancel(ErrConnectionClosed)
+	}
+	return ok
+}
+
+// Routes registers the admin endpoints on r: GET / lists connections with
+// totals, DELETE /{id} force-closes one. Mount them behind auth, e.g.
+// router.Group("/admin/connections", registry.Routes).
+func (c *ConnRegistry) Routes(r *Router) {
+	r.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		conns := c.List()
+		WriteJSON(w, http.StatusOK, map[string]any{
+			"count":       len(conns),
+			"by_client":   c.CountByClient(),
+			"connections": conns,
+		})
+	})
+	r.Delete("/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if !c.Close(Param(req, "id")) {
+			WriteError(w, http.StatusNotFound, errors.New("connection not found"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_ConnRegistry_TrackAndClose(t *testing.T) {
+	reg := NewConnRegistry()
+
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set(ClientIDHeaderName, "web-1")
+	ctx, done := reg.Track(r, "sse")
+
+	if reg.Count() != 1 || reg.CountByClient()["web-1"] != 1 {
+		t.Fatalf("count = %d by client = %v", reg.Count(), reg.CountByClient())
+	}
+	conns := reg.List()
+	if len(conns) != 1 || conns[0].Kind != "sse" || conns[0].Path != "/events" || conns[0].ClientID != "web-1" {
+		t.Fatalf("list = %+v", conns)
+	}
+
+	if !reg.Close(conns[0].ID) {
+		t.Fatal("Close returned false for an active connection")
+	}
+	<-ctx.Done()
+	if !errors.Is(context.Cause(ctx), ErrConnectionClosed) {
+		t.Fatalf("cause = %v", context.Cause(ctx))
+	}
+
+	done()
+	if reg.Count() != 0 || reg.Close(conns[0].ID) {
+		t.Fatal("connection still tracked after done")
+	}
+}
+
+func Test_ConnRegistry_AdminRoutes(t *testing.T) {
+	reg := NewConnRegistry()
+	r := NewRouter()
+	r.Group("/admin/connections", reg.Routes)
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	r.With(reg.Middleware("sse")).Get("/stream", func(_ http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-req.Context().Done()
+		close(finished)
+	})
+	go r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream", nil))
+	<-started
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/connections/", nil))
+	var list struct {
+		Count       int          `json:"count"`
+		Connections []Connection `json:"connections"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || list.Count != 1 {
+		t.Fatalf("list = %+v (%v)", list, err)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/connections/"+list.Connections[0].ID, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d", rec.Code)
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("stream handler did not stop after Close")
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/connections/999", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown id status = %d", rec.Code)
+	}
+}
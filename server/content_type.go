@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Media types, mirrored from github.com/toaweme/http so the server module
+// never imports the client. Keep the values in sync with the client's
+// constants of the same name.
+const (
+	ContentTypeJSON        = "application/json"
+	ContentTypeProblemJSON = "application/problem+json"
+	ContentTypeNDJSON      = "application/x-ndjson"
+	ContentTypeEventStream = "text/event-stream"
+	ContentTypeForm        = "application/x-www-form-urlencoded"
+	ContentTypeMultipart   = "multipart/form-data"
+	ContentTypeOctetStream = "application/octet-stream"
+	ContentTypeText        = "text/plain"
+)
+
+// ParseMediaType returns the lowercased media type of a Content-Type or
+// Accept value and its parameters. Unlike mime.ParseMediaType it never
+// fails: a malformed value yields "" and a malformed parameter is dropped.
+func ParseMediaType(contentType string) (string, map[string]string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil && err != mime.ErrInvalidMediaParameter {
+		return "", nil
+	}
+	return mediaType, params
+}
+
+// IsJSON reports whether contentType is JSON: application/json or a +json
+// type such as application/problem+json. Streams of JSON values such as
+// NDJSON are not.
+func IsJSON(contentType string) bool {
+	mediaType, _ := ParseMediaType(contentType)
+	return mediaType == ContentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// IsEventStream reports whether contentType is a server-sent event stream.
+func IsEventStream(contentType string) bool {
+	mediaType, _ := ParseMediaType(contentType)
+	return mediaType == ContentTypeEventStream
+}
+
+// IsForm reports whether contentType is a URL-encoded form.
+func IsForm(contentType string) bool {
+	mediaType, _ := ParseMediaType(contentType)
+	return mediaType == ContentTypeForm
+}
+
+// RequireContentType answers 415 to requests with a body whose media type is
+// not one of mediaTypes. Requests without a body pass.
+func RequireContentType(mediaTypes ...string) Middleware {
+	allowed := make(map[string]bool, len(mediaTypes))
+	for _, mt := range mediaTypes {
+		allowed[strings.ToLower(mt)] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if mediaType, _ := ParseMediaType(r.Header.Get("Content-Type")); !allowed[mediaType] {
+				WriteError(w, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", mediaType))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_ContentTypeHelpers(t *testing.T) {
+	tests := []struct {
+		contentType       string
+		mediaType         string
+		json, eventStream bool
+	}{
+		{"application/json", ContentTypeJSON, true, false},
+		{"Application/JSON; charset=utf-8", ContentTypeJSON, true, false},
+		{"application/problem+json", ContentTypeProblemJSON, true, false},
+		{"application/x-ndjson", ContentTypeNDJSON, false, false},
+		{"text/event-stream; charset=utf-8", ContentTypeEventStream, false, true},
+		{"text/plain; charset", ContentTypeText, false, false},
+		{"", "", false, false},
+		{"/;", "", false, false},
+	}
+	for _, tt := range tests {
+		if mt, _ := ParseMediaType(tt.contentType); mt != tt.mediaType {
+			t.Errorf("ParseMediaType(%q) = %q, want %q", tt.contentType, mt, tt.mediaType)
+		}
+		if IsJSON(tt.contentType) != tt.json || IsEventStream(tt.contentType) != tt.eventStream {
+			t.Errorf("%q: IsJSON = %v, IsEventStream = %v", tt.contentType, IsJSON(tt.contentType), IsEventStream(tt.contentType))
+		}
+	}
+	if !IsForm("application/x-www-form-urlencoded; charset=utf-8") {
+		t.Error("IsForm rejected a form")
+	}
+}
+
+func Test_RequireContentType(t *testing.T) {
+	h := RequireContentType(ContentTypeJSON)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	for _, tt := range []struct {
+		body, contentType string
+		want              int
+	}{
+		{`{}`, "application/json; charset=utf-8", http.StatusOK},
+		{"a=1", ContentTypeForm, http.StatusUnsupportedMediaType},
+		{"x", "", http.StatusUnsupportedMediaType},
+		{"", "", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		if tt.body == "" {
+			r = httptest.NewRequest(http.MethodPost, "/", nil)
+		}
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tt.want {
+			t.Errorf("%q as %q: status %d, want %d", tt.body, tt.contentType, rec.Code, tt.want)
+		}
+	}
+}
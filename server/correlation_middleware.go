@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+// CorrelationConfig controls the Correlate middleware.
+type CorrelationConfig struct {
+	// ServedBy identifies this instance in X-Served-By. Defaults to the
+	// hostname; "-" omits the header.
+	ServedBy string
+	// NoResponseTime omits X-Response-Time.
+	NoResponseTime bool
+}
+
+// Correlate sets response headers that let callers correlate and measure
+// requests from their side: the inbound X-Request-ID echoed back (mount
+// RequestID first to always have one), X-Served-By, and X-Response-Time,
+// measured up to the response header.
+func Correlate(cfg CorrelationConfig) Middleware {
+	servedBy := cfg.ServedBy
+	if servedBy == "" {
+		servedBy, _ = os.Hostname()
+	}
+	if servedBy == "-" {
+		servedBy = ""
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := r.Header.Get(ClientRequestIDHeaderName)
+			next.ServeHTTP(&headerHookWriter{
+				responseRecorder: responseRecorder{ResponseWriter: w, status: http.StatusOK},
+				hook: func(_ int, h http.Header) {
+					if requestID != "" && h.Get(ClientRequestIDHeaderName) == "" {
+						h.Set(ClientRequestIDHeaderName, requestID)
+					}
+					if servedBy != "" {
+						h.Set(ServedByHeaderName, servedBy)
+					}
+					if !cfg.NoResponseTime {
+						h.Set(ResponseTimeHeaderName, time.Since(start).String())
+					}
+				},
+			}, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Correlate(t *testing.T) {
+	h := Correlate(CorrelationConfig{ServedBy: "api-1"})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(ClientRequestIDHeaderName, "abc")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(ClientRequestIDHeaderName); got != "abc" {
+		t.Errorf("request id = %q, want abc", got)
+	}
+	if got := rec.Header().Get(ServedByHeaderName); got != "api-1" {
+		t.Errorf("served by = %q, want api-1", got)
+	}
+	if d, err := time.ParseDuration(rec.Header().Get(ResponseTimeHeaderName)); err != nil || d < 5*time.Millisecond {
+		t.Errorf("response time = %q (%v), want at least 5ms", rec.Header().Get(ResponseTimeHeaderName), err)
+	}
+}
+
+func Test_Correlate_Options(t *testing.T) {
+	h := Correlate(CorrelationConfig{ServedBy: "-", NoResponseTime: true})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	for _, name := range []string{ClientRequestIDHeaderName, ServedByHeaderName, ResponseTimeHeaderName} {
+		if v := rec.Header().Get(name); v != "" {
+			t.Errorf("%s = %q, want it omitted", name, v)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	Correlate(CorrelationConfig{})(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get(ServedByHeaderName) == "" {
+		t.Error("served by does not default to the hostname")
+	}
+}
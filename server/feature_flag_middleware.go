@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrFeatureDisabled is the error body written when a FeatureGate blocks a
+// request.
+var ErrFeatureDisabled = errors.New("feature not available")
+
+// FlagProvider decides whether a feature flag is on for the calling client.
+// Return an error for infrastructure failures; the gate then fails closed.
+type FlagProvider interface {
+	Enabled(ctx context.Context, flag string, client ClientInfo) (bool, error)
+}
+
+// FlagProviderFunc adapts a plain function to FlagProvider.
+type FlagProviderFunc func(ctx context.Context, flag string, client ClientInfo) (bool, error)
+
+// Enabled calls f.
+func (f FlagProviderFunc) Enabled(ctx context.Context, flag string, client ClientInfo) (bool, error) {
+	return f(ctx, flag, client)
+}
+
+// FlagRule enables a flag for a subset of clients. Empty fields do not
+// restrict, so the zero rule enables the flag for everyone.
+type FlagRule struct {
+	// Platforms lists the X-Client-Platform values the flag is on for.
+	Platforms []string
+	// MinVersion is the lowest X-Client-Version the flag is on for, compared
+	// as dotted numbers ("2.10.0" > "2.9.1"). Clients without a version are off.
+	MinVersion string
+}
+
+// StaticFlags is a FlagProvider backed by a fixed rule per flag; flags with
+// no rule are off.
+type StaticFlags map[string]FlagRule
+
+// Enabled reports whether client satisfies the flag's rule.
+func (s StaticFlags) Enabled(_ context.Context, flag string, client ClientInfo) (bool, error) {
+	rule, ok := s[flag]
+	if !ok {
+		return false, nil
+	}
+	if len(rule.Platforms) > 0 {
+		matched := false
+		for _, p := range rule.Platforms {
+			if strings.EqualFold(p, client.Platform) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	if rule.MinVersion != "" {
+		if client.Version == "" || compareVersions(client.Version, rule.MinVersion) < 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FeatureGate returns a middleware that serves the route only while flag is
+// enabled for the calling client, answering disabledStatus otherwise (404 to
+// hide the endpoint, 403 to acknowledge it). It reads the ClientInfo stored by
+// ClientInfoMiddleware, falling back to the request headers. Mount it per
+// route with Router.With:
+//
+//	r.With(server.FeatureGate("exports-v2", flags, http.StatusNotFound, logger)).Get("/exports", h)
+func FeatureGate(flag string, provider FlagProvider, disabledStatus int, logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := ClientInfoFromContext(r.Context())
+			if !ok {
+				client = ClientInfoFromRequest(r)
+			}
+
+			enabled, err := provider.Enabled(r.Context(), flag, client)
+			if err != nil {
+				logger.Error("http", "type", "feature-flag", "flag", flag, "error", err)
+			}
+			if err != nil || !enabled {
+				WriteError(w, disabledStatus, ErrFeatureDisabled)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// compareVersions compares dotted numeric versions, ignoring a leading "v" and
+// any pre-release or build suffix. Missing components count as zero.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	out := make([]int, len(fields))
+	for i, f := range fields {
+		out[i], _ = strconv.Atoi(f)
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_compareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.10.0", "2.9.1", 1},
+		{"v1.2", "1.2.0", 0},
+		{"1.2.3-beta", "1.2.3", 0},
+		{"1.0.0", "1.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func Test_StaticFlags(t *testing.T) {
+	flags := StaticFlags{"new-ui": {Platforms: []string{"ios"}, MinVersion: "4.0"}}
+	tests := []struct {
+		name   string
+		flag   string
+		client ClientInfo
+		want   bool
+	}{
+		{name: "matches", flag: "new-ui", client: ClientInfo{Platform: "iOS", Version: "4.1.0"}, want: true},
+		{name: "old version", flag: "new-ui", client: ClientInfo{Platform: "ios", Version: "3.9"}, want: false},
+		{name: "no version", flag: "new-ui", client: ClientInfo{Platform: "ios"}, want: false},
+		{name: "other platform", flag: "new-ui", client: ClientInfo{Platform: "web", Version: "9"}, want: false},
+		{name: "unknown flag", flag: "other", client: ClientInfo{Platform: "ios", Version: "9"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := flags.Enabled(context.Background(), tt.flag, tt.client)
+			if err != nil || got != tt.want {
+				t.Fatalf("got %v, %v want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_FeatureGate(t *testing.T) {
+	flags := StaticFlags{"beta": {MinVersion: "2.0"}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	gate := FeatureGate("beta", flags, http.StatusNotFound, nopLogger{})(ok)
+
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{"2.1", http.StatusOK},
+		{"1.9", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		r.Header.Set(ClientAppVersionHeaderName, tt.version)
+		rec := httptest.NewRecorder()
+		gate.ServeHTTP(rec, r)
+		if rec.Code != tt.want {
+			t.Errorf("version %s: got %d want %d", tt.version, rec.Code, tt.want)
+		}
+	}
+}
+
+func Test_FeatureGate_ProviderErrorFailsClosed(t *testing.T) {
+	failing := FlagProviderFunc(func(context.Context, string, ClientInfo) (bool, error) {
+		return true, errors.New("flag service down")
+	})
+	rec := httptest.NewRecorder()
+	FeatureGate("x", failing, http.StatusForbidden, nopLogger{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("handler reached despite provider error")
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("got %d want 403", rec.Code)
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// isGRPC reports whether r is a gRPC call: HTTP/2 with an application/grpc
+// (or application/grpc+proto, ...) content type.
+func isGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// GRPCHandler routes gRPC calls to grpc and every other request to rest, so
+// both can share one listener. A *grpc.Server from google.golang.org/grpc
+// satisfies http.Handler and can be passed directly; this module does not
+// import it.
+func GRPCHandler(grpc, rest http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGRPC(r) {
+			grpc.ServeHTTP(w, r)
+			return
+		}
+		rest.ServeHTTP(w, r)
+	})
+}
+
+// WithGRPC co-hosts a gRPC server on the server's port: gRPC calls go to grpc,
+// everything else to the router, under one lifecycle. It enables cleartext
+// HTTP/2 (h2c) next to HTTP/1, as gRPC clients dial without TLS inside a
+// cluster; with TLS configured, HTTP/2 is negotiated as usual.
+//
+//	srv := server.NewServer(cfg, router, logger, server.WithGRPC(grpcServer))
+func WithGRPC(grpc http.Handler) Option {
+	return func(srv *http.Server) {
+		srv.Handler = GRPCHandler(grpc, srv.Handler)
+		if srv.Protocols == nil {
+			srv.Protocols = new(http.Protocols)
+			srv.Protocols.SetHTTP1(true)
+			srv.Protocols.SetHTTP2(true)
+		}
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+}
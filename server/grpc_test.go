@@ -0,0 +1,51 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_WithGRPC_RoutesByProtocol(t *testing.T) {
+	rest := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("rest"))
+	})
+	grpc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		_, _ = w.Write([]byte("grpc " + r.Proto))
+	})
+
+	ts := httptest.NewUnstartedServer(rest)
+	WithGRPC(grpc)(ts.Config)
+	ts.Start()
+	defer ts.Close()
+
+	h2c := &http.Client{Transport: &http.Transport{Protocols: new(http.Protocols)}}
+	h2c.Transport.(*http.Transport).Protocols.SetUnencryptedHTTP2(true)
+
+	for _, tc := range []struct {
+		name        string
+		client      *http.Client
+		contentType string
+		want        string
+	}{
+		{"h2c grpc", h2c, "application/grpc+proto", "grpc HTTP/2.0"},
+		{"h2c rest", h2c, "application/json", "rest"},
+		{"http1 grpc content type", http.DefaultClient, "application/grpc", "rest"},
+		{"http1 rest", http.DefaultClient, "application/json", "rest"},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/svc.Echo/Say", strings.NewReader("x"))
+		req.Header.Set("Content-Type", tc.contentType)
+		resp, err := tc.client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != tc.want {
+			t.Errorf("%s: got %q want %q", tc.name, body, tc.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package server
+
+// Client identification headers, mirrored from github.com/toaweme/http so the
+// server module never imports the client. Keep the values in sync with the
+// client's constants of the same name.
+const (
+	ClientUserAgentHeaderName  = "User-Agent"
+	ClientPlatformHeaderName   = "X-Client-Platform"
+	ClientAppVersionHeaderName = "X-Client-Version"
+	ClientIDHeaderName         = "X-Client-ID"
+	ClientSessionIDHeaderName  = "X-Session-ID"
+	ClientRequestIDHeaderName  = "X-Request-ID"
+	ServiceNameHeaderName      = "X-Service-Name"
+)
+
+// Response correlation headers set by Correlate, mirrored from
+// github.com/toaweme/http like the client identification headers above.
+// The request id is echoed under ClientRequestIDHeaderName.
+const (
+	// ServedByHeaderName names the instance that handled the request.
+	ServedByHeaderName = "X-Served-By"
+	// ResponseTimeHeaderName carries the time from receiving the request to
+	// writing the response header, as a Go duration ("12.5ms").
+	ResponseTimeHeaderName = "X-Response-Time"
+)
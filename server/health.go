@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+)
+
+// Default paths of the built-in health endpoints.
+const (
+	DefaultLivenessPath  = "/healthz"
+	DefaultReadinessPath = "/readyz"
+)
+
+// HealthConfig enables a Server's built-in health endpoints. They are served
+// ahead of the base path and the configured middleware, so probes are neither
+// logged nor rewritten.
+type HealthConfig struct {
+	Enabled bool
+	// LivenessPath answers 200 while the process serves. Defaults to
+	// DefaultLivenessPath.
+	LivenessPath string
+	// ReadinessPath answers 200 with a ReadinessReport, or 503 once Stop
+	// began draining or a critical Readiness check fails. Defaults to
+	// DefaultReadinessPath.
+	ReadinessPath string
+	// Readiness holds the dependency checks behind ReadinessPath; nil
+	// reports ready until draining.
+	Readiness *Readiness
+}
+
+// healthHandler serves the health endpoints in front of next.
+func (s *Server) healthHandler(next http.Handler) http.Handler {
+	cfg := s.config.Health
+	liveness, readiness := cfg.LivenessPath, cfg.ReadinessPath
+	if liveness == "" {
+		liveness = DefaultLivenessPath
+	}
+	if readiness == "" {
+		readiness = DefaultReadinessPath
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.URL.Path {
+		case liveness:
+			WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		case readiness:
+			s.serveReadiness(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (s *Server) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	report := ReadinessReport{Ready: true, Checks: []CheckResult{}}
+	select {
+	case <-s.draining:
+		report.Ready, report.Draining = false, true
+	default:
+		if rd := s.config.Health.Readiness; rd != nil {
+			report = rd.Check(r.Context())
+		}
+	}
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	WriteJSON(w, status, report)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Server_HealthEndpoints(t *testing.T) {
+	rd := NewReadiness(0)
+	var dbErr error
+	rd.Register("db", true, func(context.Context) error { return dbErr })
+	r := NewRouter()
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) { t.Error("router reached for a health path") })
+	s := NewServer(ServerConfig{BasePath: "/api", Health: HealthConfig{Enabled: true, Readiness: rd}}, r, nopLogger{})
+
+	get := func(path string) (int, ReadinessReport) {
+		rec := httptest.NewRecorder()
+		s.HTTP().Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var report ReadinessReport
+		_ = json.NewDecoder(rec.Body).Decode(&report)
+		return rec.Code, report
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("liveness = %d, want 200", code)
+	}
+	if code, report := get("/readyz"); code != http.StatusOK || !report.Ready {
+		t.Fatalf("readiness = %d %+v, want ready", code, report)
+	}
+	dbErr = errors.New("down")
+	if code, report := get("/readyz"); code != http.StatusServiceUnavailable || report.Ready || report.Draining {
+		t.Fatalf("readiness with db down = %d %+v", code, report)
+	}
+
+	dbErr = nil
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code, report := get("/readyz"); code != http.StatusServiceUnavailable || !report.Draining {
+		t.Fatalf("readiness while draining = %d %+v", code, report)
+	}
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("liveness while draining = %d, want 200", code)
+	}
+}
+
+func Test_Server_HealthCustomPaths(t *testing.T) {
+	r := NewRouter()
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	s := NewServer(ServerConfig{Health: HealthConfig{Enabled: true, LivenessPath: "/live", ReadinessPath: "/ready"}}, r, nopLogger{})
+
+	for path, want := range map[string]int{"/live": http.StatusOK, "/ready": http.StatusOK, "/healthz": http.StatusTeapot} {
+		rec := httptest.NewRecorder()
+		s.HTTP().Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
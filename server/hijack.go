@@ -0,0 +1,243 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrServerShuttingDown is the context cause of hijacked connections once
+// Server.Stop begins.
+var ErrServerShuttingDown = errors.New("server shutting down")
+
+// HijackOptions configures Hijack and Upgrade.
+type HijackOptions struct {
+	// Registry, when set, lists the connection under Kind so operators can
+	// see it and force it closed.
+	Registry *ConnRegistry
+	// Kind names the connection in Registry; defaults to the upgrade
+	// protocol, or "hijack".
+	Kind string
+}
+
+// HijackedConn is a connection taken over from net/http for a custom
+// protocol. Reads go through the buffer net/http already filled, so no byte
+// the client sent after the request is lost. Close it when done: a Server
+// waits for hijacked connections in Stop, as it does for requests, and
+// force-closes those still open at the deadline.
+type HijackedConn struct {
+	net.Conn
+	br     *bufio.Reader
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   func()
+	once   sync.Once
+	err    error
+}
+
+// Read reads from the connection through the hijack buffer.
+func (c *HijackedConn) Read(p []byte) (int, error) { return c.br.Read(p) }
+
+// Context carries the request's values but outlives its handler, so the
+// protocol may run in another goroutine. It is cancelled when the
+// connection is closed, with ErrConnectionClosed when an operator closed
+// it through the ConnRegistry (which also closes the connection), and with
+// ErrServerShuttingDown once the server starts stopping, so the protocol
+// can say goodbye before the shutdown deadline.
+func (c *HijackedConn) Context() context.Context { return c.ctx }
+
+// Close closes the connection and stops tracking it. It is safe to call
+// more than once.
+func (c *HijackedConn) Close() error {
+	c.once.Do(func() {
+		c.err = c.Conn.Close()
+		c.done()
+	})
+	return c.err
+}
+
+// Hijack takes over the connection behind w without writing a response;
+// the caller speaks first. It fails for HTTP/2 requests, which cannot be
+// hijacked.
+func Hijack(w http.ResponseWriter, r *http.Request, opts HijackOptions) (*HijackedConn, error) {
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+	kind := opts.Kind
+	if kind == "" {
+		kind = "hijack"
+	}
+
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(r.Context()))
+	untrack := func() {}
+	if opts.Registry != nil {
+		ctx, untrack = opts.Registry.Track(r.WithContext(ctx), kind)
+	}
+	c := &HijackedConn{Conn: netConn, br: brw.Reader, ctx: ctx, cancel: cancel}
+	release := trackHijack(r.Context(), netConn, cancel)
+	c.done = func() {
+		release()
+		untrack()
+		cancel(nil)
+	}
+	// a blocked read only returns once the connection is closed
+	context.AfterFunc(ctx, func() {
+		if errors.Is(context.Cause(ctx), ErrConnectionClosed) {
+			_ = c.Close()
+		}
+	})
+	return c, nil
+}
+
+// Upgrade switches the connection to protocol, which the request's Upgrade
+// header must offer, answering 101 Switching Protocols with the headers set
+// on w so far (such as the request id echoed by Correlate). A request not
+// offering protocol is answered 426 Upgrade Required.
+func Upgrade(w http.ResponseWriter, r *http.Request, protocol string, opts HijackOptions) (*HijackedConn, error) {
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", protocol) {
+		w.Header().Set("Upgrade", protocol)
+		w.Header().Set("Connection", "Upgrade")
+		err := fmt.Errorf("not an upgrade to %s", protocol)
+		WriteError(w, http.StatusUpgradeRequired, err)
+		return nil, err
+	}
+	if opts.Kind == "" {
+		opts.Kind = protocol
+	}
+	header := w.Header().Clone()
+	c, err := Hijack(w, r, opts)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.New("upgrade not supported"))
+		return nil, err
+	}
+
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + protocol + "\r\n")
+	header.Del("Connection")
+	header.Del("Upgrade")
+	if err := header.Write(&resp); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	resp.WriteString("\r\n")
+	if _, err := io.WriteString(c.Conn, resp.String()); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("failed to write upgrade response: %w", err)
+	}
+	return c, nil
+}
+
+// hijackTracker holds a server's hijacked connections, which
+// http.Server.Shutdown no longer sees.
+type hijackTracker struct {
+	mu       sync.Mutex
+	conns    map[net.Conn]context.CancelCauseFunc
+	stopping bool
+	// changed is closed and replaced whenever a connection is released.
+	changed chan struct{}
+}
+
+// instrument makes the tracker reachable from every request's context, so
+// Hijack and UpgradeWebSocket register with it. It runs at Start.
+func (t *hijackTracker) instrument(srv *http.Server) {
+	t.mu.Lock()
+	t.conns = make(map[net.Conn]context.CancelCauseFunc)
+	t.changed = make(chan struct{})
+	t.mu.Unlock()
+
+	next := srv.Handler
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxHijacks, t)))
+	})
+}
+
+// trackHijack registers conn with the tracker of the server serving ctx, if
+// any; cancel, when set, is called with ErrServerShuttingDown once Stop
+// begins. The returned release is safe to call more than once.
+func trackHijack(ctx context.Context, conn net.Conn, cancel context.CancelCauseFunc) (release func()) {
+	t, ok := ctx.Value(ctxHijacks).(*hijackTracker)
+	if !ok {
+		return func() {}
+	}
+	if cancel == nil {
+		cancel = func(error) {}
+	}
+	t.mu.Lock()
+	t.conns[conn] = cancel
+	stopping := t.stopping
+	t.mu.Unlock()
+	if stopping {
+		// after returning, so the caller holds release by the time it runs
+		go cancel(ErrServerShuttingDown)
+	}
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.conns[conn]; ok {
+			delete(t.conns, conn)
+			close(t.changed)
+			t.changed = make(chan struct{})
+		}
+	}
+}
+
+// count returns the number of hijacked connections still open.
+func (t *hijackTracker) count() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return int64(len(t.conns))
+}
+
+// shutdown tells every hijacked connection, and those hijacked later, that
+// the server is stopping.
+func (t *hijackTracker) shutdown() {
+	t.mu.Lock()
+	t.stopping = true
+	cancels := make([]context.CancelCauseFunc, 0, len(t.conns))
+	for _, cancel := range t.conns {
+		cancels = append(cancels, cancel)
+	}
+	t.mu.Unlock()
+	for _, cancel := range cancels {
+		cancel(ErrServerShuttingDown)
+	}
+}
+
+// wait blocks until every hijacked connection is closed or ctx is done.
+func (t *hijackTracker) wait(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		remaining, changed := len(t.conns), t.changed
+		t.mu.Unlock()
+		if remaining == 0 {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// closeAll force-closes the hijacked connections still open and returns how
+// many there were.
+func (t *hijackTracker) closeAll() int64 {
+	t.mu.Lock()
+	conns := make([]net.Conn, 0, len(t.conns))
+	for conn := range t.conns {
+		conns = append(conns, conn)
+	}
+	t.mu.Unlock()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	return int64(len(conns))
+}
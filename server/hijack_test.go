@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startHijackServer serves h on a free port and returns the server and its
+// address.
+func startHijackServer(t *testing.T, h http.HandlerFunc, timeout time.Duration) (*Server, string) {
+	t.Helper()
+	r := NewRouter()
+	r.Get("/", h)
+	s := NewServer(ServerConfig{Host: "127.0.0.1", ShutdownTimeout: timeout}, r, nopLogger{})
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start() }()
+	select {
+	case <-s.Listening():
+	case err := <-errCh:
+		t.Fatalf("Start: %v", err)
+	}
+	return s, s.Addr().String()
+}
+
+// dialUpgrade opens a connection asking to upgrade to protocol and returns
+// it once the 101 response was read.
+func dialUpgrade(t *testing.T, addr, protocol string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: "+protocol+"\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != protocol {
+		t.Fatalf("status = %d, Upgrade = %q", resp.StatusCode, resp.Header.Get("Upgrade"))
+	}
+	return conn, br
+}
+
+func Test_Upgrade_EchoAndGracefulStop(t *testing.T) {
+	var cause error
+	s, addr := startHijackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto-Version", "1")
+		conn, err := Upgrade(w, r, "echo", HijackOptions{})
+		if err != nil {
+			return
+		}
+		go func() {
+			<-conn.Context().Done()
+			cause = context.Cause(conn.Context())
+			_, _ = io.WriteString(conn, "bye\n")
+			_ = conn.Close()
+		}()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		_, _ = io.WriteString(conn, line)
+	}, 2*time.Second)
+
+	conn, br := dialUpgrade(t, addr, "echo")
+	defer conn.Close()
+	_, _ = io.WriteString(conn, "hello\n")
+	if line, _ := br.ReadString('\n'); line != "hello\n" {
+		t.Fatalf("echo = %q", line)
+	}
+	if n := s.Stats().HijackedConnections; n != 1 {
+		t.Fatalf("HijackedConnections = %d, want 1", n)
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if line, _ := br.ReadString('\n'); line != "bye\n" {
+		t.Fatalf("goodbye = %q", line)
+	}
+	if !errors.Is(cause, ErrServerShuttingDown) {
+		t.Fatalf("context cause = %v", cause)
+	}
+	if n := s.Stats().HijackedConnections; n != 0 {
+		t.Fatalf("HijackedConnections after Stop = %d", n)
+	}
+}
+
+func Test_Hijack_StopForceClosesStuckConnections(t *testing.T) {
+	s, addr := startHijackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// ignores the shutdown notice and never closes
+		_, _ = Hijack(w, r, HijackOptions{})
+	}, 100*time.Millisecond)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _ = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+	for s.Stats().HijackedConnections == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	err = s.Stop(context.Background())
+	var serr *ShutdownError
+	if !errors.As(err, &serr) || serr.ForceClosed != 1 {
+		t.Fatalf("Stop = %v, want a ShutdownError with one force-closed connection", err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("connection still open after Stop")
+	}
+}
+
+func Test_Hijack_RegistryClose(t *testing.T) {
+	registry := NewConnRegistry()
+	closed := make(chan error, 1)
+	_, addr := startHijackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, "raw", HijackOptions{Registry: registry})
+		if err != nil {
+			return
+		}
+		_, _ = conn.Read(make([]byte, 1))
+		closed <- context.Cause(conn.Context())
+	}, time.Second)
+
+	conn, _ := dialUpgrade(t, addr, "raw")
+	defer conn.Close()
+	list := registry.List()
+	if len(list) != 1 || list[0].Kind != "raw" {
+		t.Fatalf("registry = %+v", list)
+	}
+	registry.Close(list[0].ID)
+	if cause := <-closed; !errors.Is(cause, ErrConnectionClosed) {
+		t.Fatalf("cause = %v", cause)
+	}
+	deadline := time.Now().Add(time.Second)
+	for registry.Count() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("registry still lists %d connections", registry.Count())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func Test_Upgrade_RequiresProtocol(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_, err := Upgrade(rec, httptest.NewRequest(http.MethodGet, "/", nil), "echo", HijackOptions{})
+	if err == nil || rec.Code != http.StatusUpgradeRequired || !strings.EqualFold(rec.Header().Get("Upgrade"), "echo") {
+		t.Fatalf("err = %v, status = %d, Upgrade = %q", err, rec.Code, rec.Header().Get("Upgrade"))
+	}
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobState is the state of a long-running job.
+type JobState string
+
+// Job states, mirrored from github.com/toaweme/http.
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// ErrJobCanceled is the error of a job canceled through its DELETE
+// endpoint or Jobs.Close.
+var ErrJobCanceled = errors.New("job canceled")
+
+// JobStatus is what a job status endpoint reports, in the shape the
+// client's SubmitAndWait polls for.
+type JobStatus struct {
+	ID        string    `json:"id"`
+	State     JobState  `json:"state"`
+	Progress  float64   `json:"progress,omitempty"`
+	Result    any       `json:"result,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JobFunc runs a job. ctx carries the submitting request's values but not
+// its cancellation, and is cancelled when the job is. The result is
+// encoded as the status' Result.
+type JobFunc func(ctx context.Context, job *Job) (any, error)
+
+// JobsConfig configures NewJobs.
+type JobsConfig struct {
+	// Path is where Routes is mounted as clients see it, base path included
+	// (e.g. "/api/jobs"); Location headers point below it.
+	Path string
+	// TTL is how long a finished job's status stays available. Defaults to
+	// an hour.
+	TTL time.Duration
+	// RetryAfter is the poll interval suggested while a job runs. Defaults
+	// to a second.
+	RetryAfter time.Duration
+	// Logger, when set, records every failed job.
+	Logger Logger
+}
+
+// Jobs runs long-running operations behind the 202 Accepted + Location
+// pattern: Submit starts one and answers 202 pointing at its status, which
+// Routes serves until TTL after it finished.
+//
+//	jobs := server.NewJobs(server.JobsConfig{Path: "/jobs"})
+//	r.Group("/jobs", jobs.Routes)
+//	r.Post("/reports", func(w http.ResponseWriter, r *http.Request) {
+//		jobs.Submit(w, r, func(ctx context.Context, job *server.Job) (any, error) {
+//			return buildReport(ctx, job.SetProgress)
+//		})
+//	})
+type Jobs struct {
+	cfg JobsConfig
+	wg  sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// Job is one submitted job.
+type Job struct {
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	status   JobStatus
+	finished time.Time
+}
+
+// NewJobs builds an empty job registry.
+func NewJobs(cfg JobsConfig) *Jobs {
+	cfg.Path = strings.TrimSuffix(cfg.Path, "/")
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Hour
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Second
+	}
+	return &Jobs{cfg: cfg, jobs: make(map[string]*Job)}
+}
+
+// ID returns the job's id.
+func (j *Job) ID() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.ID
+}
+
+// SetProgress reports how far the job got, from 0 to 1.
+func (j *Job) SetProgress(progress float64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Progress = progress
+	j.status.UpdatedAt = time.Now()
+}
+
+// Status returns a snapshot of the job's status.
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Submit starts fn as a job and answers 202 Accepted with its status and a
+// Location to poll.
+func (js *Jobs) Submit(w http.ResponseWriter, r *http.Request, fn JobFunc) *Job {
+	now := time.Now()
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(r.Context()))
+	job := &Job{cancel: cancel, status: JobStatus{ID: randomHex(16), State: JobPending, CreatedAt: now, UpdatedAt: now}}
+
+	js.mu.Lock()
+	js.sweep(now)
+	js.jobs[job.status.ID] = job
+	js.mu.Unlock()
+
+	js.wg.Add(1)
+	go js.run(ctx, job, fn)
+
+	w.Header().Set("Location", js.cfg.Path+"/"+job.status.ID)
+	js.writeStatus(w, job.Status())
+	return job
+}
+
+func (js *Jobs) run(ctx context.Context, job *Job, fn JobFunc) {
+	defer js.wg.Done()
+	job.mu.Lock()
+	job.status.State, job.status.UpdatedAt = JobRunning, time.Now()
+	job.mu.Unlock()
+
+	result, err := func() (result any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("job panicked: %v", rec)
+			}
+		}()
+		return fn(ctx, job)
+	}()
+	if cause := context.Cause(ctx); err == nil && errors.Is(cause, ErrJobCanceled) {
+		err = cause
+	}
+	job.cancel(nil)
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.finished = time.Now()
+	job.status.UpdatedAt = job.finished
+	if err != nil {
+		job.status.State, job.status.Error = JobFailed, err.Error()
+		if js.cfg.Logger != nil {
+			js.cfg.Logger.Warn("http", "type", "job", "job", job.status.ID, "error", err)
+		}
+		return
+	}
+	job.status.State, job.status.Result, job.status.Progress = JobSucceeded, result, 1
+}
+
+// Get returns the job with id, unless it is unknown or expired.
+func (js *Jobs) Get(id string) (*Job, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.sweep(time.Now())
+	job, ok := js.jobs[id]
+	return job, ok
+}
+
+// Cancel cancels the job with id, reporting false when there is none. A
+// job that already finished keeps its outcome.
+func (js *Jobs) Cancel(id string) bool {
+	job, ok := js.Get(id)
+	if ok {
+		job.cancel(ErrJobCanceled)
+	}
+	return ok
+}
+
+// Close cancels every running job and waits for them to return, or for ctx
+// to be done.
+func (js *Jobs) Close(ctx context.Context) error {
+	js.mu.Lock()
+	for _, job := range js.jobs {
+		job.cancel(ErrJobCanceled)
+	}
+	js.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		js.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Routes registers the status endpoints on r: GET /{id} reports a job,
+// answering 202 with Retry-After while it runs and 200 once it finished;
+// DELETE /{id} cancels it.
+func (js *Jobs) Routes(r *Router) {
+	r.Get("/{id}", func(w http.ResponseWriter, req *http.Request) {
+		job, ok := js.Get(Param(req, "id"))
+		if !ok {
+			WriteError(w, http.StatusNotFound, errors.New("job not found"))
+			return
+		}
+		js.writeStatus(w, job.Status())
+	})
+	r.Delete("/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if !js.Cancel(Param(req, "id")) {
+			WriteError(w, http.StatusNotFound, errors.New("job not found"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (js *Jobs) writeStatus(w http.ResponseWriter, status JobStatus) {
+	if status.State == JobSucceeded || status.State == JobFailed {
+		WriteJSON(w, http.StatusOK, status)
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int((js.cfg.RetryAfter+time.Second-1)/time.Second)))
+	WriteJSON(w, http.StatusAccepted, status)
+}
+
+// sweep drops jobs finished longer than TTL ago; js.mu must be held.
+func (js *Jobs) sweep(now time.Time) {
+	for id, job := range js.jobs {
+		job.mu.Lock()
+		expired := !job.finished.IsZero() && now.Sub(job.finished) > js.cfg.TTL
+		job.mu.Unlock()
+		if expired {
+			delete(js.jobs, id)
+		}
+	}
+}
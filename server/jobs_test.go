@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Jobs(t *testing.T) {
+	jobs := NewJobs(JobsConfig{Path: "/jobs/"})
+	release := make(chan struct{})
+	r := NewRouter()
+	r.Group("/jobs", jobs.Routes)
+	r.Post("/reports", func(w http.ResponseWriter, req *http.Request) {
+		jobs.Submit(w, req, func(ctx context.Context, job *Job) (any, error) {
+			job.SetProgress(0.5)
+			<-release
+			return map[string]int{"rows": 3}, nil
+		})
+	})
+	get := func(path string) (*httptest.ResponseRecorder, JobStatus) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var status JobStatus
+		_ = json.Unmarshal(rec.Body.Bytes(), &status)
+		return rec, status
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reports", nil))
+	location := rec.Header().Get("Location")
+	if rec.Code != http.StatusAccepted || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("submit = %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if len(location) <= len("/jobs/") || location[:len("/jobs/")] != "/jobs/" {
+		t.Fatalf("Location = %q", location)
+	}
+
+	if rec, status := get(location); rec.Code != http.StatusAccepted || status.State == JobSucceeded {
+		t.Fatalf("poll while running = %d %+v", rec.Code, status)
+	}
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		rec, status := get(location)
+		if rec.Code == http.StatusOK {
+			if status.State != JobSucceeded || status.Progress != 1 || status.Result.(map[string]any)["rows"] != float64(3) {
+				t.Fatalf("finished status = %+v", status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job never finished")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if rec, _ := get("/jobs/unknown"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown job = %d", rec.Code)
+	}
+}
+
+func Test_Jobs_Cancel(t *testing.T) {
+	jobs := NewJobs(JobsConfig{Path: "/jobs"})
+	r := NewRouter()
+	r.Group("/jobs", jobs.Routes)
+
+	started := make(chan struct{})
+	rec := httptest.NewRecorder()
+	job := jobs.Submit(rec, httptest.NewRequest(http.MethodPost, "/reports", nil), func(ctx context.Context, job *Job) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, nil
+	})
+	<-started
+
+	del := httptest.NewRecorder()
+	r.ServeHTTP(del, httptest.NewRequest(http.MethodDelete, "/jobs/"+job.ID(), nil))
+	if del.Code != http.StatusNoContent {
+		t.Fatalf("cancel = %d", del.Code)
+	}
+	if err := jobs.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if status := job.Status(); status.State != JobFailed || status.Error != ErrJobCanceled.Error() {
+		t.Fatalf("canceled status = %+v", status)
+	}
+}
+
+func Test_Jobs_Failure(t *testing.T) {
+	jobs := NewJobs(JobsConfig{TTL: time.Millisecond})
+	rec := httptest.NewRecorder()
+	job := jobs.Submit(rec, httptest.NewRequest(http.MethodPost, "/", nil), func(ctx context.Context, job *Job) (any, error) {
+		panic("boom")
+	})
+	_ = jobs.Close(context.Background())
+	if status := job.Status(); status.State != JobFailed || status.Error != "job panicked: boom" {
+		t.Fatalf("panicked status = %+v", status)
+	}
+
+	// finished jobs expire after the TTL
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := jobs.Get(job.ID()); ok {
+		t.Fatal("expired job still reported")
+	}
+}
@@ -12,7 +12,7 @@ type ErrorResponse struct {
 
 // WriteJSON encodes v as JSON with the given status code.
 func WriteJSON(w http.ResponseWriter, status int, v any) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", ContentTypeJSON)
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
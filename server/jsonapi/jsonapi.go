@@ -0,0 +1,188 @@
+// Package jsonapi provides request parsing and response serialization helpers
+// for the JSON:API format (https://jsonapi.org): resources, relationships,
+// included resources, and error objects.
+//
+// Layout:
+//
+//   - Document: the top-level envelope, with either primary Data (one
+//     resource or a collection) or Errors.
+//   - NewResource / Resource.DecodeAttributes convert between a Go struct
+//     and a resource's attributes object.
+//   - Write / WriteErrors / Read speak the media type over net/http.
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// MediaType is the JSON:API media type, used for both Content-Type and Accept.
+const MediaType = "application/vnd.api+json"
+
+// ErrUnsupportedMediaType is returned by Read when the request is not sent as
+// MediaType. Map it to HTTP 415.
+var ErrUnsupportedMediaType = errors.New("jsonapi: unsupported media type")
+
+// Links maps link names ("self", "related", "next", ...) to URLs.
+type Links map[string]string
+
+// ResourceIdentifier points at a resource by type and id.
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relationship links a resource to one or many others. Data is a
+// *ResourceIdentifier for to-one and a []ResourceIdentifier for to-many; use
+// ToOne and ToMany to build it.
+type Relationship struct {
+	Data  any            `json:"data"`
+	Links Links          `json:"links,omitempty"`
+	Meta  map[string]any `json:"meta,omitempty"`
+}
+
+// ToOne builds a to-one relationship; an empty id yields a null linkage.
+func ToOne(typ, id string) Relationship {
+	if id == "" {
+		return Relationship{Data: nil}
+	}
+	return Relationship{Data: &ResourceIdentifier{Type: typ, ID: id}}
+}
+
+// ToMany builds a to-many relationship; no ids yields an empty array.
+func ToMany(typ string, ids ...string) Relationship {
+	data := make([]ResourceIdentifier, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, ResourceIdentifier{Type: typ, ID: id})
+	}
+	return Relationship{Data: data}
+}
+
+// Resource is a resource object.
+type Resource struct {
+	Type          string                  `json:"type"`
+	ID            string                  `json:"id,omitempty"`
+	Attributes    json.RawMessage         `json:"attributes,omitempty"`
+	Relationships map[string]Relationship `json:"relationships,omitempty"`
+	Links         Links                   `json:"links,omitempty"`
+	Meta          map[string]any          `json:"meta,omitempty"`
+}
+
+// NewResource builds a resource whose attributes are attrs marshaled as JSON.
+// attrs must marshal to an object; its id field, if any, should be left out
+// (tag it json:"-") since the id lives on the resource itself.
+func NewResource(typ, id string, attrs any) (Resource, error) {
+	raw, err := json.Marshal(attrs)
+	if err != nil {
+		return Resource{}, fmt.Errorf("failed to marshal %s attributes: %w", typ, err)
+	}
+	if len(raw) == 0 || raw[0] != '{' {
+		return Resource{}, fmt.Errorf("jsonapi: %s attributes must marshal to an object", typ)
+	}
+	return Resource{Type: typ, ID: id, Attributes: raw}, nil
+}
+
+// DecodeAttributes unmarshals the resource's attributes into v.
+func (r Resource) DecodeAttributes(v any) error {
+	if len(r.Attributes) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Attributes, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s attributes: %w", r.Type, err)
+	}
+	return nil
+}
+
+// Error is a JSON:API error object.
+type Error struct {
+	ID     string       `json:"id,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Source *ErrorSource `json:"source,omitempty"`
+}
+
+// ErrorSource points at the part of the request document an Error refers to.
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Header    string `json:"header,omitempty"`
+}
+
+// Document is the top-level JSON:API envelope. Data holds a Resource, a
+// []Resource, or nil; set Errors instead of Data for a failure document.
+type Document struct {
+	Data     any            `json:"data,omitempty"`
+	Errors   []Error        `json:"errors,omitempty"`
+	Included []Resource     `json:"included,omitempty"`
+	Links    Links          `json:"links,omitempty"`
+	Meta     map[string]any `json:"meta,omitempty"`
+}
+
+// Write encodes doc with the JSON:API media type and the given status.
+func Write(w http.ResponseWriter, status int, doc Document) {
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(doc)
+}
+
+// WriteErrors writes an error document, filling each error's Status from
+// status when it is empty.
+func WriteErrors(w http.ResponseWriter, status int, errs ...Error) {
+	for i := range errs {
+		if errs[i].Status == "" {
+			errs[i].Status = fmt.Sprint(status)
+		}
+	}
+	Write(w, status, Document{Errors: errs})
+}
+
+// requestDocument mirrors Document with raw primary data, so Read can tell a
+// single resource from a collection.
+type requestDocument struct {
+	Data     json.RawMessage `json:"data"`
+	Included []Resource      `json:"included,omitempty"`
+	Meta     map[string]any  `json:"meta,omitempty"`
+}
+
+// Read decodes a single-resource request document (the shape of create and
+// update requests) and returns its primary resource and included resources.
+// It rejects requests not sent as MediaType with ErrUnsupportedMediaType.
+func Read(r *http.Request) (Resource, []Resource, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	// the spec forbids media type parameters other than ext/profile
+	if err != nil || mediaType != MediaType || hasForbiddenParams(params) {
+		return Resource{}, nil, ErrUnsupportedMediaType
+	}
+
+	var doc requestDocument
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		return Resource{}, nil, fmt.Errorf("failed to decode jsonapi document: %w", err)
+	}
+	data := bytes.TrimSpace(doc.Data)
+	if len(data) == 0 || data[0] != '{' {
+		return Resource{}, nil, errors.New("jsonapi: request data must be a single resource object")
+	}
+	var res Resource
+	if err := json.Unmarshal(data, &res); err != nil {
+		return Resource{}, nil, fmt.Errorf("failed to decode jsonapi resource: %w", err)
+	}
+	if res.Type == "" {
+		return Resource{}, nil, errors.New("jsonapi: resource type is required")
+	}
+	return res, doc.Included, nil
+}
+
+func hasForbiddenParams(params map[string]string) bool {
+	for k := range params {
+		if k != "ext" && k != "profile" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type article struct {
+	ID    string `json:"-"`
+	Title string `json:"title"`
+}
+
+func Test_Write_ResourceWithRelationshipsAndIncluded(t *testing.T) {
+	res, err := NewResource("articles", "1", article{ID: "1", Title: "Hello"})
+	if err != nil {
+		t.Fatalf("NewResource: %v", err)
+	}
+	res.Relationships = map[string]Relationship{
+		"author": ToOne("people", "9"),
+		"tags":   ToMany("tags"),
+	}
+	author, _ := NewResource("people", "9", map[string]string{"name": "Ada"})
+
+	rec := httptest.NewRecorder()
+	Write(rec, http.StatusOK, Document{Data: res, Included: []Resource{author}, Links: Links{"self": "/articles/1"}})
+
+	if rec.Header().Get("Content-Type") != MediaType {
+		t.Fatalf("Content-Type: %q", rec.Header().Get("Content-Type"))
+	}
+	want := `{"data":{"type":"articles","id":"1","attributes":{"title":"Hello"},` +
+		`"relationships":{"author":{"data":{"type":"people","id":"9"}},"tags":{"data":[]}}},` +
+		`"included":[{"type":"people","id":"9","attributes":{"name":"Ada"}}],"links":{"self":"/articles/1"}}` + "\n"
+	if rec.Body.String() != want {
+		t.Fatalf("got  %s\nwant %s", rec.Body.String(), want)
+	}
+}
+
+func Test_NewResource_RejectsNonObject(t *testing.T) {
+	if _, err := NewResource("x", "1", []int{1}); err == nil {
+		t.Fatal("expected error for array attributes")
+	}
+}
+
+func Test_WriteErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteErrors(rec, http.StatusUnprocessableEntity, Error{Title: "Invalid title", Source: &ErrorSource{Pointer: "/data/attributes/title"}})
+
+	var doc struct {
+		Errors []Error `json:"errors"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity || len(doc.Errors) != 1 || doc.Errors[0].Status != "422" || doc.Errors[0].Source.Pointer != "/data/attributes/title" {
+		t.Fatalf("got %d %+v", rec.Code, doc)
+	}
+}
+
+func Test_Read(t *testing.T) {
+	body := `{"data":{"type":"articles","attributes":{"title":"New"},"relationships":{"author":{"data":{"type":"people","id":"9"}}}}}`
+	r := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(body))
+	r.Header.Set("Content-Type", MediaType)
+
+	res, included, err := Read(r)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var a article
+	if err := res.DecodeAttributes(&a); err != nil {
+		t.Fatalf("DecodeAttributes: %v", err)
+	}
+	if res.Type != "articles" || a.Title != "New" || included != nil {
+		t.Fatalf("got %+v %+v %v", res, a, included)
+	}
+	if _, ok := res.Relationships["author"]; !ok {
+		t.Fatal("relationships not decoded")
+	}
+}
+
+func Test_Read_Rejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantMedia   bool
+	}{
+		{name: "plain json", contentType: "application/json", body: `{"data":{"type":"a"}}`, wantMedia: true},
+		{name: "charset param", contentType: MediaType + "; charset=utf-8", body: `{"data":{"type":"a"}}`, wantMedia: true},
+		{name: "collection", contentType: MediaType, body: `{"data":[{"type":"a"}]}`},
+		{name: "missing type", contentType: MediaType, body: `{"data":{"id":"1"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			_, _, err := Read(r)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if errors.Is(err, ErrUnsupportedMediaType) != tt.wantMedia {
+				t.Fatalf("err = %v, want ErrUnsupportedMediaType=%v", err, tt.wantMedia)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// LinkBuilder builds absolute links (self, next, related) relative to the
+// request being served, so handlers never hard-code the public hostname.
+type LinkBuilder struct {
+	base  url.URL
+	path  string
+	query url.Values
+}
+
+// NewLinkBuilder derives the public scheme and host from r. As with
+// ResolveClientIP, forwarding headers (Forwarded proto=/host=, then
+// X-Forwarded-Proto / X-Forwarded-Host) are only believed when the direct peer
+// is in trusted; otherwise the TLS state and Host header are used.
+func NewLinkBuilder(r *http.Request, trusted []netip.Prefix) LinkBuilder {
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if isTrusted(remoteHost(r.RemoteAddr), trusted) {
+		proto, fwdHost := forwardedProtoHost(r.Header)
+		if proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if fwdHost != "" {
+			host = fwdHost
+		}
+	}
+	return LinkBuilder{
+		base:  url.URL{Scheme: scheme, Host: host},
+		path:  r.URL.Path,
+		query: r.URL.Query(),
+	}
+}
+
+// Base returns the public origin, e.g. "https://api.example.com".
+func (b LinkBuilder) Base() string {
+	return b.base.String()
+}
+
+// Self returns the absolute URL of the current request, query included.
+func (b LinkBuilder) Self() string {
+	return b.build(b.path, b.query)
+}
+
+// Resolve returns the absolute URL for path, which may carry its own query
+// ("/users/42/orders?status=open"). Use it for related links.
+func (b LinkBuilder) Resolve(path string) string {
+	p, rawQuery, _ := strings.Cut(path, "?")
+	query, _ := url.ParseQuery(rawQuery)
+	return b.build(p, query)
+}
+
+// WithQuery returns the current URL with key set to value, keeping every other
+// query parameter. Use it for pagination links: b.WithQuery("cursor", next).
+func (b LinkBuilder) WithQuery(key, value string) string {
+	query := make(url.Values, len(b.query)+1)
+	for k, v := range b.query {
+		query[k] = v
+	}
+	query.Set(key, value)
+	return b.build(b.path, query)
+}
+
+func (b LinkBuilder) build(path string, query url.Values) string {
+	u := b.base
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u.Path = path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// forwardedProtoHost reads proto and host from the first (client-facing) hop
+// of Forwarded, falling back to the first X-Forwarded-Proto / X-Forwarded-Host
+// entry.
+func forwardedProtoHost(h http.Header) (proto, host string) {
+	if v := h.Get("Forwarded"); v != "" {
+		element, _, _ := strings.Cut(v, ",")
+		for _, pair := range strings.Split(element, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			val = strings.Trim(val, `"`)
+			switch strings.ToLower(key) {
+			case "proto":
+				proto = strings.ToLower(val)
+			case "host":
+				host = val
+			}
+		}
+	}
+	if proto == "" {
+		proto, _, _ = strings.Cut(h.Get("X-Forwarded-Proto"), ",")
+		proto = strings.ToLower(strings.TrimSpace(proto))
+	}
+	if host == "" {
+		host, _, _ = strings.Cut(h.Get("X-Forwarded-Host"), ",")
+		host = strings.TrimSpace(host)
+	}
+	return proto, host
+}
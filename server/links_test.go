@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_LinkBuilder(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		tls     bool
+		headers map[string]string
+		want    string
+	}{
+		{name: "plain host", remote: "203.0.113.5:1234", want: "http://api.internal"},
+		{name: "tls", remote: "203.0.113.5:1234", tls: true, want: "https://api.internal"},
+		{name: "x-forwarded from trusted proxy", remote: "10.0.0.2:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com, lb"},
+			want:    "https://api.example.com"},
+		{name: "forwarded wins over x-forwarded", remote: "10.0.0.2:1234",
+			headers: map[string]string{"Forwarded": `proto=https;host="edge.example.com", proto=http`, "X-Forwarded-Host": "other"},
+			want:    "https://edge.example.com"},
+		{name: "untrusted peer ignored", remote: "203.0.113.5:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example.com"},
+			want:    "http://api.internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://api.internal/users?page=2&sort=name", nil)
+			r.RemoteAddr = tt.remote
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := NewLinkBuilder(r, trusted).Base(); got != tt.want {
+				t.Fatalf("Base() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_LinkBuilder_Links(t *testing.T) {
+	r := httptest.NewRequest("GET", "https://api.example.com/users?page=2&sort=name", nil)
+	b := NewLinkBuilder(r, nil)
+
+	if got := b.Self(); got != "https://api.example.com/users?page=2&sort=name" {
+		t.Errorf("Self() = %q", got)
+	}
+	if got := b.WithQuery("page", "3"); got != "https://api.example.com/users?page=3&sort=name" {
+		t.Errorf("WithQuery() = %q", got)
+	}
+	if got := b.Resolve("/users/42/orders?status=open"); got != "https://api.example.com/users/42/orders?status=open" {
+		t.Errorf("Resolve() = %q", got)
+	}
+	if got := b.Self(); got != "https://api.example.com/users?page=2&sort=name" {
+		t.Errorf("WithQuery mutated the builder: Self() = %q", got)
+	}
+}
@@ -12,3 +12,51 @@ type Logger interface {
 	Warn(msg string, args ...any)
 	Error(msg string, args ...any)
 }
+
+// LogLevel names a Logger method, letting configuration pick the level a
+// message is written at.
+type LogLevel string
+
+// Levels understood by logAt, in increasing severity. LogLevelNone discards.
+const (
+	LogLevelNone  LogLevel = "none"
+	LogLevelTrace LogLevel = "trace"
+	LogLevelDebug LogLevel = "debug"
+	LogLevelInfo  LogLevel = "info"
+	LogLevelWarn  LogLevel = "warn"
+	LogLevelError LogLevel = "error"
+)
+
+var levelRank = map[LogLevel]int{
+	LogLevelNone:  0,
+	LogLevelTrace: 1,
+	LogLevelDebug: 2,
+	LogLevelInfo:  3,
+	LogLevelWarn:  4,
+	LogLevelError: 5,
+}
+
+// atLeast returns the more severe of l and floor.
+func (l LogLevel) atLeast(floor LogLevel) LogLevel {
+	if levelRank[l] < levelRank[floor] {
+		return floor
+	}
+	return l
+}
+
+// logAt writes msg at level; unknown levels fall back to Info.
+func logAt(logger Logger, level LogLevel, msg string, args ...any) {
+	switch level {
+	case LogLevelNone:
+	case LogLevelTrace:
+		logger.Trace(msg, args...)
+	case LogLevelDebug:
+		logger.Debug(msg, args...)
+	case LogLevelWarn:
+		logger.Warn(msg, args...)
+	case LogLevelError:
+		logger.Error(msg, args...)
+	default:
+		logger.Info(msg, args...)
+	}
+}
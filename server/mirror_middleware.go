@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// MirrorHeaderName marks a mirrored request, so the shadow environment can
+// skip side effects such as sending email or charging cards.
+const MirrorHeaderName = "X-Shadow-Request"
+
+// MirrorConfig configures Mirror.
+type MirrorConfig struct {
+	// Send delivers one mirrored request to the shadow environment; its
+	// response is discarded. With the toaweme/http client:
+	//
+	//	Send: func(ctx context.Context, r *http.Request) error {
+	//		_, err := client.Forward(ctx, shadow, r)
+	//		return err
+	//	}
+	Send func(ctx context.Context, r *http.Request) error
+	// SampleRate is the fraction of requests mirrored, e.g. 0.05 for 5%.
+	// 0 mirrors every request.
+	SampleRate float64
+	// MaxBodyBytes skips mirroring requests with larger bodies. Defaults to
+	// 1 MiB.
+	MaxBodyBytes int64
+	// Timeout bounds each mirrored call. Defaults to 5s.
+	Timeout time.Duration
+	// MaxInFlight caps concurrent mirrored calls; requests beyond it are not
+	// mirrored, so a slow shadow never backs up production. Defaults to 64.
+	MaxInFlight int
+	// Logger, when set, receives mirror failures at Debug.
+	Logger Logger
+}
+
+// Mirror asynchronously replays a sample of inbound requests to a shadow
+// environment through cfg.Send, to validate a new deployment under real
+// traffic. The inbound request is served normally and never waits on the
+// mirror. Mirrored copies carry MirrorHeaderName.
+func Mirror(cfg MirrorConfig) Middleware {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 64
+	}
+	var inFlight atomic.Int64
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Send == nil || r.Header.Get(MirrorHeaderName) != "" ||
+				(cfg.SampleRate > 0 && cfg.SampleRate < 1 && sample() >= cfg.SampleRate) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, ok := peekBody(r, cfg.MaxBodyBytes)
+			if ok && inFlight.Add(1) <= int64(cfg.MaxInFlight) {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), cfg.Timeout)
+				shadow := r.Clone(ctx)
+				shadow.Body = io.NopCloser(bytes.NewReader(body))
+				shadow.ContentLength = int64(len(body))
+				shadow.Header.Set(MirrorHeaderName, "1")
+				go func() {
+					defer inFlight.Add(-1)
+					defer cancel()
+					if err := cfg.Send(ctx, shadow); err != nil && cfg.Logger != nil {
+						cfg.Logger.Debug("http", "type", "mirror", "method", shadow.Method, "url", shadow.URL.RequestURI(), "error", err)
+					}
+				}()
+			} else if ok {
+				inFlight.Add(-1)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// peekBody reads up to limit bytes of r's body and puts them back in front of
+// the rest, so the handler still sees the whole body. ok is false when the
+// body is larger than limit or unreadable.
+func peekBody(r *http.Request, limit int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	if r.ContentLength > limit {
+		return nil, false
+	}
+	head, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err != nil || int64(len(head)) > limit {
+		return nil, false
+	}
+	return head, true
+}
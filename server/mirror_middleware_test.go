@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mirrored struct {
+	method, uri, header, body string
+}
+
+func Test_Mirror_ReplaysRequests(t *testing.T) {
+	got := make(chan mirrored, 4)
+	mw := Mirror(MirrorConfig{Send: func(_ context.Context, r *http.Request) error {
+		body, _ := io.ReadAll(r.Body)
+		got <- mirrored{r.Method, r.URL.RequestURI(), r.Header.Get(MirrorHeaderName), string(body)}
+		return nil
+	}})
+
+	var handlerBody string
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		handlerBody = string(b)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders?x=1", strings.NewReader(`{"id":1}`)))
+	if rec.Code != http.StatusCreated || handlerBody != `{"id":1}` {
+		t.Fatalf("primary request disturbed: %d %q", rec.Code, handlerBody)
+	}
+
+	select {
+	case m := <-got:
+		if m != (mirrored{http.MethodPost, "/orders?x=1", "1", `{"id":1}`}) {
+			t.Fatalf("mirrored %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not mirrored")
+	}
+}
+
+func Test_Mirror_SkipsLargeBodiesAndSamples(t *testing.T) {
+	sent := make(chan struct{}, 4)
+	send := func(context.Context, *http.Request) error { sent <- struct{}{}; return nil }
+
+	var handlerBody string
+	handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		handlerBody = string(b)
+	})
+
+	big := strings.Repeat("x", 100)
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(big)))
+	req.ContentLength = -1
+	Mirror(MirrorConfig{Send: send, MaxBodyBytes: 10})(handler).ServeHTTP(httptest.NewRecorder(), req)
+	if handlerBody != big {
+		t.Fatalf("handler saw %d bytes want %d", len(handlerBody), len(big))
+	}
+
+	orig := sample
+	defer func() { sample = orig }()
+	sample = func() float64 { return 0.9 }
+	Mirror(MirrorConfig{Send: send, SampleRate: 0.5})(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	// a mirrored request is never mirrored again
+	loop := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	loop.Header.Set(MirrorHeaderName, "1")
+	Mirror(MirrorConfig{Send: send})(handler).ServeHTTP(httptest.NewRecorder(), loop)
+
+	select {
+	case <-sent:
+		t.Fatal("request mirrored despite size cap, sampling, or loop guard")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
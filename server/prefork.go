@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// PreforkChildEnv is set to "1" in the worker processes a prefork supervisor
+// starts.
+const PreforkChildEnv = "TOAWEME_HTTP_PREFORK_CHILD"
+
+// ErrPreforkUnsupported is returned by Start when ServerConfig.Prefork is set on a
+// platform without SO_REUSEPORT.
+var ErrPreforkUnsupported = errors.New("prefork is not supported on this platform")
+
+// preforkRestartDelay spaces out restarts of a worker that exited on its own,
+// so a crash loop does not spin the supervisor.
+const preforkRestartDelay = time.Second
+
+// IsPreforkChild reports whether this process is a prefork worker. Code that
+// must run once per deployment (migrations, schedulers) should skip it when
+// this is true.
+func IsPreforkChild() bool { return os.Getenv(PreforkChildEnv) == "1" }
+
+// preforkWorkers resolves ServerConfig.Prefork to a process count.
+func preforkWorkers(n int) int {
+	if n < 0 {
+		return runtime.NumCPU()
+	}
+	return n
+}
+
+// supervisor runs the prefork worker processes: it re-executes the current
+// binary n times, restarts workers that die, and terminates them on stop.
+type supervisor struct {
+	workers int
+	logger  Logger
+	// command builds one worker process; tests swap it for a stand-in.
+	command func() *exec.Cmd
+
+	mu       sync.Mutex
+	procs    map[int]*os.Process
+	stopping bool
+	// running is set once run starts; until then there is no run to wait for.
+	running bool
+	exited  chan int
+	done    chan struct{}
+}
+
+func newSupervisor(workers int, logger Logger) *supervisor {
+	return &supervisor{
+		workers: workers,
+		logger:  logger,
+		command: selfCommand,
+		procs:   make(map[int]*os.Process),
+		exited:  make(chan int),
+		done:    make(chan struct{}),
+	}
+}
+
+// selfCommand re-executes the running binary with the same arguments and the
+// child marker set.
+func selfCommand() *exec.Cmd {
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), PreforkChildEnv+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd
+}
+
+// run starts the workers and blocks until stop has terminated all of them.
+func (sv *supervisor) run() error {
+	sv.mu.Lock()
+	sv.running = true
+	sv.mu.Unlock()
+	defer close(sv.done)
+	if !preforkSupported {
+		return ErrPreforkUnsupported
+	}
+	for i := 0; i < sv.workers; i++ {
+		if err := sv.spawn(); err != nil {
+			sv.terminateAll()
+			sv.waitAll(len(sv.snapshot()))
+			return fmt.Errorf("failed to start prefork worker: %w", err)
+		}
+	}
+	sv.logger.Info("service", "http", "prefork", "workers", sv.workers)
+
+	for {
+		sv.mu.Lock()
+		stopping, remaining := sv.stopping, len(sv.procs)
+		sv.mu.Unlock()
+		if stopping && remaining == 0 {
+			return nil
+		}
+
+		pid := <-sv.exited
+		sv.mu.Lock()
+		delete(sv.procs, pid)
+		stopping = sv.stopping
+		sv.mu.Unlock()
+		if stopping {
+			continue
+		}
+		sv.logger.Warn("service", "http", "prefork", "msg", "worker exited, restarting", "pid", pid)
+		time.Sleep(preforkRestartDelay)
+		if err := sv.spawn(); err != nil {
+			sv.logger.Error("service", "http", "prefork", "msg", "failed to restart worker", "error", err)
+		}
+	}
+}
+
+// spawn starts one worker and reports its exit on sv.exited.
+func (sv *supervisor) spawn() error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	if sv.stopping {
+		return nil
+	}
+	cmd := sv.command()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pid := cmd.Process.Pid
+	sv.procs[pid] = cmd.Process
+	go func() {
+		_ = cmd.Wait()
+		sv.exited <- pid
+	}()
+	return nil
+}
+
+func (sv *supervisor) snapshot() []*os.Process {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	procs := make([]*os.Process, 0, len(sv.procs))
+	for _, p := range sv.procs {
+		procs = append(procs, p)
+	}
+	return procs
+}
+
+func (sv *supervisor) terminateAll() {
+	sv.mu.Lock()
+	sv.stopping = true
+	sv.mu.Unlock()
+	for _, p := range sv.snapshot() {
+		_ = terminate(p)
+	}
+}
+
+// waitAll collects n exits during a failed startup, when run's loop is not
+// consuming them.
+func (sv *supervisor) waitAll(n int) {
+	for ; n > 0; n-- {
+		pid := <-sv.exited
+		sv.mu.Lock()
+		delete(sv.procs, pid)
+		sv.mu.Unlock()
+	}
+}
+
+// stop asks every worker to shut down gracefully and waits for run to return,
+// killing the workers still alive when ctx ends. It returns at once if run
+// never started; a later run then starts no workers.
+func (sv *supervisor) stop(ctx context.Context) error {
+	sv.terminateAll()
+	sv.mu.Lock()
+	running := sv.running
+	sv.mu.Unlock()
+	if !running {
+		return nil
+	}
+	select {
+	case <-sv.done:
+		return nil
+	case <-ctx.Done():
+	}
+	procs := sv.snapshot()
+	for _, p := range procs {
+		_ = p.Kill()
+	}
+	<-sv.done
+	return fmt.Errorf("prefork workers did not exit in time, killed %d: %w", len(procs), ctx.Err())
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+const preforkSupported = false
+
+func reusePort(_, _ string, _ syscall.RawConn) error { return ErrPreforkUnsupported }
+
+func terminate(p *os.Process) error { return p.Kill() }
@@ -0,0 +1,7 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || (linux && (mips || mipsle || mips64 || mips64le || sparc64))
+
+package server
+
+// soReusePort is SO_REUSEPORT on the BSDs and on the Linux ports that kept
+// their native socket option numbers.
+const soReusePort = 0x200
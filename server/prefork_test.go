@@ -0,0 +1,106 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func Test_reusePort_SharesAddress(t *testing.T) {
+	lc := net.ListenConfig{Control: reusePort}
+	first, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer first.Close()
+	second, err := lc.Listen(context.Background(), "tcp", first.Addr().String())
+	if err != nil {
+		t.Fatalf("second Listen on %s: %v", first.Addr(), err)
+	}
+	_ = second.Close()
+}
+
+func Test_supervisor_RunsAndStopsWorkers(t *testing.T) {
+	sv := newSupervisor(3, nopLogger{})
+	sv.command = func() *exec.Cmd { return exec.Command("sleep", "30") }
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- sv.run() }()
+	waitFor(t, func() bool { return len(sv.snapshot()) == 3 })
+
+	if err := sv.stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n := len(sv.snapshot()); n != 0 {
+		t.Fatalf("%d workers left after stop", n)
+	}
+}
+
+func Test_supervisor_RestartsCrashedWorkers(t *testing.T) {
+	sv := newSupervisor(1, nopLogger{})
+	starts := make(chan struct{}, 4)
+	sv.command = func() *exec.Cmd {
+		starts <- struct{}{}
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	go func() { _ = sv.run() }()
+	defer func() { _ = sv.stop(context.Background()) }()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-starts:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("worker started %d times, want a restart", i)
+		}
+	}
+}
+
+func Test_supervisor_KillsWorkersIgnoringTerm(t *testing.T) {
+	sv := newSupervisor(1, nopLogger{})
+	sv.command = func() *exec.Cmd { return exec.Command("sh", "-c", `trap "" TERM; sleep 30`) }
+	go func() { _ = sv.run() }()
+	waitFor(t, func() bool { return len(sv.snapshot()) == 1 })
+	time.Sleep(100 * time.Millisecond) // let the shell install its trap
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := sv.stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("stop: got %v want a deadline error after killing the worker", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_supervisor_StopBeforeRun(t *testing.T) {
+	sv := newSupervisor(2, nopLogger{})
+	sv.command = func() *exec.Cmd { return exec.Command("sleep", "30") }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sv.stop(ctx); err != nil {
+		t.Fatalf("stop without run: %v", err)
+	}
+	if err := sv.run(); err != nil {
+		t.Fatalf("run after stop: %v", err)
+	}
+	if n := len(sv.snapshot()); n != 0 {
+		t.Fatalf("%d workers started after stop", n)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+const preforkSupported = true
+
+// reusePort lets every prefork worker bind the same address; the kernel
+// spreads incoming connections across them.
+func reusePort(_, _ string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// terminate asks a worker to shut down gracefully.
+func terminate(p *os.Process) error { return p.Signal(syscall.SIGTERM) }
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// DefaultPropagatedHeaders is the allowlist PropagateHeaders uses when none is
+// given: credentials, W3C trace context, and the client identification headers.
+var DefaultPropagatedHeaders = []string{
+	"Authorization",
+	"Traceparent",
+	"Tracestate",
+	"Baggage",
+	ClientRequestIDHeaderName,
+	ClientSessionIDHeaderName,
+	ClientIDHeaderName,
+	ClientPlatformHeaderName,
+	ClientAppVersionHeaderName,
+}
+
+// hopByHopHeaders are connection-scoped (RFC 9110 section 7.6.1) and are never
+// forwarded, even when allowlisted.
+var hopByHopHeaders = map[string]bool{
+	"Connection":          true,
+	"Proxy-Connection":    true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Host":                true,
+	"Content-Length":      true,
+}
+
+// PropagateHeaders copies the allowlisted inbound headers into the request
+// context so outbound calls made while handling the request can carry them.
+// Hop-by-hop headers, and any header the inbound Connection header names, are
+// dropped even when allowlisted. A nil allow uses DefaultPropagatedHeaders.
+//
+// Pair it with PropagatedHeaders on the client side:
+//
+//	client := http.NewClient(cfg, http.WithContextHeaders(server.PropagatedHeaders))
+func PropagateHeaders(allow []string) Middleware {
+	if allow == nil {
+		allow = DefaultPropagatedHeaders
+	}
+	names := make([]string, 0, len(allow))
+	for _, name := range allow {
+		name = http.CanonicalHeaderKey(name)
+		if !hopByHopHeaders[name] {
+			names = append(names, name)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			connection := connectionTokens(r.Header)
+			out := make(map[string]string, len(names))
+			for _, name := range names {
+				if connection[name] {
+					continue
+				}
+				if value := r.Header.Get(name); value != "" {
+					out[name] = value
+				}
+			}
+			ctx := context.WithValue(r.Context(), ctxPropagatedHeaders, out)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// PropagatedHeaders returns the headers PropagateHeaders captured for the
+// request in ctx, or nil outside such a request. It has the shape
+// http.WithContextHeaders expects.
+func PropagatedHeaders(ctx context.Context) map[string]string {
+	captured, _ := ctx.Value(ctxPropagatedHeaders).(map[string]string)
+	if len(captured) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(captured))
+	for k, v := range captured {
+		out[k] = v
+	}
+	return out
+}
+
+// connectionTokens returns the canonical header names listed in the
+// Connection header, which are hop-by-hop for this request.
+func connectionTokens(h http.Header) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, v := range h.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens[http.CanonicalHeaderKey(token)] = true
+			}
+		}
+	}
+	return tokens
+}
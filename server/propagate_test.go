@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_PropagateHeaders(t *testing.T) {
+	var got map[string]string
+	h := PropagateHeaders([]string{"Authorization", "X-Tenant", "X-Debug", "Keep-Alive", "Transfer-Encoding"})(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			got = PropagatedHeaders(r.Context())
+		}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set("Authorization", "Bearer t")
+	r.Header.Set("X-Tenant", "acme")
+	r.Header.Set("X-Debug", "1")
+	r.Header.Set("Connection", "keep-alive, x-debug")
+	r.Header.Set("Keep-Alive", "timeout=5")
+	r.Header.Set("X-Other", "not allowlisted")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := map[string]string{"Authorization": "Bearer t", "X-Tenant": "acme"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+func Test_PropagateHeaders_Defaults(t *testing.T) {
+	var got map[string]string
+	h := PropagateHeaders(nil)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = PropagatedHeaders(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	r.Header.Set("X-Request-ID", "req-1")
+	r.Header.Set("Cookie", "s=1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := map[string]string{
+		"Traceparent":  "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"X-Request-Id": "req-1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+func Test_PropagatedHeaders_Unset(t *testing.T) {
+	if got := PropagatedHeaders(context.Background()); got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is the error body written when RateLimit rejects a request.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// TokenBucketLimit is the refill rate and capacity of a token bucket,
+// mirrored from github.com/toaweme/http.
+type TokenBucketLimit struct {
+	// Rate is the tokens added per second.
+	Rate float64
+	// Burst is the most tokens the bucket holds.
+	Burst float64
+}
+
+// TokenBucketStore holds token buckets by key, typically in a store shared
+// by every replica so a quota holds cluster-wide; the client's
+// RateLimitConfig.Store takes the same interface. A Redis implementation
+// runs Reserve as one Lua script over a hash of the bucket's tokens and last
+// refill time, expiring once it would be full again.
+type TokenBucketStore interface {
+	// Reserve takes a token from the bucket at key, creating it full, and
+	// returns how long until the token is actually available. When that wait
+	// would exceed maxWait, nothing is taken and ok is false; a negative
+	// maxWait accepts any wait.
+	Reserve(ctx context.Context, key string, limit TokenBucketLimit, maxWait time.Duration) (wait time.Duration, ok bool, err error)
+}
+
+// MemoryTokenBucketStore keeps buckets in process memory, forgetting those
+// that refilled. It is the default store of RateLimit.
+type MemoryTokenBucketStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	// reserves counts calls between sweeps.
+	reserves int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  TokenBucketLimit
+}
+
+var _ TokenBucketStore = (*MemoryTokenBucketStore)(nil)
+
+// NewMemoryTokenBucketStore returns an empty store.
+func NewMemoryTokenBucketStore() *MemoryTokenBucketStore {
+	return &MemoryTokenBucketStore{buckets: make(map[string]*tokenBucket)}
+}
+
+// Reserve implements TokenBucketStore.
+func (s *MemoryTokenBucketStore) Reserve(_ context.Context, key string, limit TokenBucketLimit, maxWait time.Duration) (time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.reserves++; s.reserves >= 1024 {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: limit.Burst, last: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	tokens := math.Min(limit.Burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate) - 1
+	var wait time.Duration
+	if tokens < 0 {
+		wait = time.Duration(-tokens / limit.Rate * float64(time.Second))
+	}
+	if maxWait >= 0 && wait > maxWait {
+		return wait, false, nil
+	}
+	b.tokens, b.last = tokens, now
+	return wait, true, nil
+}
+
+// sweep drops the buckets that refilled, which are as good as new.
+func (s *MemoryTokenBucketStore) sweep(now time.Time) {
+	s.reserves = 0
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= b.limit.Burst {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimitConfig controls the RateLimit middleware.
+type RateLimitConfig struct {
+	// RPS is the sustained requests per second allowed per key. Must be > 0.
+	RPS float64
+	// Burst is how many requests a key may send at once after an idle
+	// period. Defaults to RPS rounded up, and at least 1.
+	Burst int
+	// Store holds the buckets; share one across replicas to enforce the
+	// quota cluster-wide. Defaults to a MemoryTokenBucketStore, a quota per
+	// process.
+	Store TokenBucketStore
+	// Key attributes a request to a quota. Defaults to ClientKey, falling
+	// back to ClientIP for requests without a tenant.
+	Key func(r *http.Request) string
+	// KeyPrefix namespaces the keys in Store, e.g. per route group.
+	KeyPrefix string
+	// FailClosed answers 503 when Store fails instead of letting the request
+	// through.
+	FailClosed bool
+	// Logger, when set, records Store failures.
+	Logger Logger
+}
+
+// RateLimit returns a middleware that admits each key's requests at
+// cfg.RPS with bursts of cfg.Burst, answering the rest with 429 and a
+// Retry-After saying when a token frees up. Rejected requests take no
+// token.
+func RateLimit(cfg RateLimitConfig) Middleware {
+	if cfg.RPS <= 0 {
+		panic("server: RateLimitConfig.RPS must be > 0")
+	}
+	limit := TokenBucketLimit{Rate: cfg.RPS, Burst: float64(cfg.Burst)}
+	if limit.Burst <= 0 {
+		limit.Burst = math.Max(1, math.Ceil(cfg.RPS))
+	}
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryTokenBucketStore()
+	}
+	key := cfg.Key
+	if key == nil {
+		key = clientKeyOrIP
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			wait, ok, err := store.Reserve(r.Context(), cfg.KeyPrefix+k, limit, 0)
+			if err != nil {
+				if cfg.Logger != nil {
+					cfg.Logger.Warn("http", "type", "rate-limit", "key", k, "error", err)
+				}
+				if cfg.FailClosed {
+					WriteError(w, http.StatusServiceUnavailable, ErrRateLimited)
+					return
+				}
+			} else if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+				WriteError(w, http.StatusTooManyRequests, ErrRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_RateLimit(t *testing.T) {
+	// two replicas sharing one store enforce one quota
+	store := NewMemoryTokenBucketStore()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	replicas := []http.Handler{
+		RateLimit(RateLimitConfig{RPS: 1, Burst: 2, Store: store})(ok),
+		RateLimit(RateLimitConfig{RPS: 1, Burst: 2, Store: store})(ok),
+	}
+	send := func(i int, client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(ClientIDHeaderName, client)
+		rec := httptest.NewRecorder()
+		replicas[i].ServeHTTP(rec, req)
+		return rec
+	}
+
+	if send(0, "a").Code != http.StatusOK || send(1, "a").Code != http.StatusOK {
+		t.Fatal("burst rejected")
+	}
+	rec := send(0, "a")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("over quota = %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if send(1, "b").Code != http.StatusOK {
+		t.Fatal("another client shares the quota")
+	}
+
+	// without a client id, the quota is per address
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec = httptest.NewRecorder()
+	replicas[0].ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("anonymous client = %d", rec.Code)
+	}
+}
+
+type failingBucketStore struct{}
+
+func (failingBucketStore) Reserve(context.Context, string, TokenBucketLimit, time.Duration) (time.Duration, bool, error) {
+	return 0, false, errors.New("connection refused")
+}
+
+func Test_RateLimit_StoreFailure(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, c := range []struct {
+		failClosed bool
+		want       int
+	}{{false, http.StatusOK}, {true, http.StatusServiceUnavailable}} {
+		rec := httptest.NewRecorder()
+		RateLimit(RateLimitConfig{RPS: 1, Store: failingBucketStore{}, FailClosed: c.failClosed})(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != c.want {
+			t.Errorf("FailClosed=%v: %d, want %d", c.failClosed, rec.Code, c.want)
+		}
+	}
+}
+
+func Test_MemoryTokenBucketStore_Sweep(t *testing.T) {
+	s := NewMemoryTokenBucketStore()
+	limit := TokenBucketLimit{Rate: 1000, Burst: 1}
+	_, _, _ = s.Reserve(context.Background(), "old", limit, 0)
+	time.Sleep(5 * time.Millisecond)
+	s.sweep(time.Now())
+	if len(s.buckets) != 0 {
+		t.Fatalf("refilled bucket kept: %d", len(s.buckets))
+	}
+}
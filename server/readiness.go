@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckFunc probes one dependency; a nil error means it is usable. The
+// client package's HealthChecker returns this shape for upstream services.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of one registered check.
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Critical bool          `json:"critical"`
+	OK       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// ReadinessReport is the outcome of every check. Ready is false when any
+// critical check failed; non-critical failures are reported but do not flip it.
+// Draining is set by a Server's readiness endpoint once it is shutting down.
+type ReadinessReport struct {
+	Ready    bool          `json:"ready"`
+	Draining bool          `json:"draining,omitempty"`
+	Checks   []CheckResult `json:"checks"`
+}
+
+type readinessCheck struct {
+	name     string
+	critical bool
+	check    CheckFunc
+}
+
+// Readiness is a registry of dependency checks behind a readiness endpoint,
+// so a service reports not-ready while a critical upstream is down.
+type Readiness struct {
+	mu      sync.RWMutex
+	checks  []readinessCheck
+	timeout time.Duration
+}
+
+// NewReadiness builds an empty registry. timeout bounds each check (default
+// 2s) so one hung dependency cannot stall the probe.
+func NewReadiness(timeout time.Duration) *Readiness {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Readiness{timeout: timeout}
+}
+
+// Register adds a named check. Critical checks gate readiness; the rest are
+// reported for visibility only.
+func (rd *Readiness) Register(name string, critical bool, check CheckFunc) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	rd.checks = append(rd.checks, readinessCheck{name: name, critical: critical, check: check})
+}
+
+// Check runs every registered check concurrently and collects the report,
+// with results sorted by name.
+func (rd *Readiness) Check(ctx context.Context) ReadinessReport {
+	rd.mu.RLock()
+	checks := append([]readinessCheck(nil), rd.checks...)
+	rd.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, rd.timeout)
+			defer cancel()
+			start := time.Now()
+			err := c.check(ctx)
+			results[i] = CheckResult{Name: c.name, Critical: c.critical, OK: err == nil, Duration: time.Since(start)}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		})
+	}
+	wg.Wait()
+
+	report := ReadinessReport{Ready: true, Checks: results}
+	for _, res := range results {
+		if res.Critical && !res.OK {
+			report.Ready = false
+		}
+	}
+	sort.Slice(report.Checks, func(i, j int) bool { return report.Checks[i].Name < report.Checks[j].Name })
+	return report
+}
+
+// Handler serves the report as JSON: 200 when ready, 503 otherwise.
+func (rd *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := rd.Check(r.Context())
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		WriteJSON(w, status, report)
+	}
+}
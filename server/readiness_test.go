@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Readiness(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	hung := func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
+
+	tests := []struct {
+		name      string
+		register  func(rd *Readiness)
+		wantReady bool
+	}{
+		{name: "no checks", register: func(*Readiness) {}, wantReady: true},
+		{name: "all ok", register: func(rd *Readiness) { rd.Register("db", true, ok) }, wantReady: true},
+		{name: "non-critical down", register: func(rd *Readiness) {
+			rd.Register("db", true, ok)
+			rd.Register("search", false, down)
+		}, wantReady: true},
+		{name: "critical down", register: func(rd *Readiness) { rd.Register("billing", true, down) }, wantReady: false},
+		{name: "critical hung times out", register: func(rd *Readiness) { rd.Register("billing", true, hung) }, wantReady: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := NewReadiness(20 * time.Millisecond)
+			tt.register(rd)
+
+			rec := httptest.NewRecorder()
+			rd.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var report ReadinessReport
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			wantStatus := http.StatusOK
+			if !tt.wantReady {
+				wantStatus = http.StatusServiceUnavailable
+			}
+			if report.Ready != tt.wantReady || rec.Code != wantStatus {
+				t.Fatalf("ready = %v status = %d, want %v: %+v", report.Ready, rec.Code, tt.wantReady, report)
+			}
+		})
+	}
+}
+
+func Test_Readiness_ReportsFailures(t *testing.T) {
+	rd := NewReadiness(0)
+	rd.Register("search", false, func(context.Context) error { return errors.New("timeout") })
+	rd.Register("db", true, func(context.Context) error { return nil })
+
+	report := rd.Check(context.Background())
+	if len(report.Checks) != 2 || report.Checks[0].Name != "db" || report.Checks[1].Error != "timeout" || report.Checks[1].OK {
+		t.Fatalf("report = %+v", report)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoveryConfig controls the Recover middleware.
+type RecoveryConfig struct {
+	// OnPanic writes the response for a recovered panic. It is only called
+	// when the handler has not written a status yet. Defaults to a 500
+	// ErrorResponse that does not leak the panic value.
+	OnPanic func(w http.ResponseWriter, r *http.Request, recovered any)
+	// LogStack includes the goroutine stack in the log entry.
+	LogStack bool
+}
+
+// Recover turns a panicking handler into a logged error response instead of
+// a dropped connection. http.ErrAbortHandler is re-panicked so net/http can
+// abort the response as intended.
+func Recover(cfg RecoveryConfig, logger Logger) Middleware {
+	onPanic := cfg.OnPanic
+	if onPanic == nil {
+		onPanic = func(w http.ResponseWriter, _ *http.Request, _ any) {
+			WriteError(w, http.StatusInternalServerError, errors.New("internal server error"))
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				args := []any{"method", r.Method, "url", r.URL.RequestURI(), "panic", fmt.Sprint(rec)}
+				if info, ok := ClientInfoFromContext(r.Context()); ok {
+					args = append(args, info.LogArgs()...)
+				}
+				if cfg.LogStack {
+					args = append(args, "stack", string(debug.Stack()))
+				}
+				logger.Error("http", args...)
+
+				if !rw.wroteHeader {
+					onPanic(rw, r, rec)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type errorLogger struct {
+	nopLogger
+	errors [][]any
+}
+
+func (l *errorLogger) Error(_ string, args ...any) { l.errors = append(l.errors, args) }
+
+func Test_Recover_WritesErrorResponse(t *testing.T) {
+	logger := &errorLogger{}
+	h := Recover(RecoveryConfig{}, logger)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("secret detail")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+	if len(logger.errors) != 1 || !strings.Contains(strings.Join(toStrings(logger.errors[0]), " "), "secret detail") {
+		t.Fatalf("logged %v", logger.errors)
+	}
+}
+
+func Test_Recover_CustomResponse(t *testing.T) {
+	h := Recover(RecoveryConfig{OnPanic: func(w http.ResponseWriter, _ *http.Request, rec any) {
+		WriteError(w, http.StatusServiceUnavailable, errors.New("try later"))
+	}}, nopLogger{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic(errors.New("x")) }))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d", rec.Code)
+	}
+}
+
+func Test_Recover_KeepsWrittenStatus(t *testing.T) {
+	h := Recover(RecoveryConfig{}, nopLogger{})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func Test_Recover_RepanicsAbortHandler(t *testing.T) {
+	h := Recover(RecoveryConfig{}, nopLogger{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Fatalf("recovered %v", rec)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func toStrings(args []any) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i], _ = a.(string)
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrReplayedRequest is returned when a request id was already used within
+// the replay window.
+var ErrReplayedRequest = errors.New("duplicate request id")
+
+// defaultReplayWindow is ReplayConfig.Window when unset.
+const defaultReplayWindow = 10 * time.Minute
+
+// NonceForgetter is implemented by NonceStores that can drop a recorded
+// nonce before it expires. RejectReplays uses it to let a request whose
+// first attempt failed be retried under the same id.
+type NonceForgetter interface {
+	Forget(nonce string)
+}
+
+// ReplayConfig configures RejectReplays.
+type ReplayConfig struct {
+	// Store records the request ids seen. Defaults to a MemoryNonceStore;
+	// use a shared store when running more than one instance.
+	Store NonceStore
+	// Window is how long a request id stays used. Defaults to 10 minutes.
+	Window time.Duration
+	// Methods are the methods checked. Defaults to POST and PATCH, the
+	// methods retrying is not safe for.
+	Methods []string
+	// Key scopes request ids; defaults to ClientKey, so two tenants
+	// picking the same id do not collide.
+	Key func(r *http.Request) string
+	// Require rejects requests of the checked methods without an
+	// X-Request-ID with 400, instead of letting them through unchecked.
+	Require bool
+	// Logger, when set, records every rejected request.
+	Logger Logger
+}
+
+// RejectReplays returns a middleware that answers 409 to a request reusing
+// the X-Request-ID of an earlier one to the same method and path within
+// Window, guarding non-idempotent endpoints against a client submitting
+// twice even where Idempotency-Key is not adopted. An id is released again
+// when its request was answered 408, 429, or 5xx, or the handler panicked,
+// if the store is a NonceForgetter, so retrying a failed attempt works.
+// Mount it before RequestID, which fills in a fresh id when the client sent
+// none.
+func RejectReplays(cfg ReplayConfig) Middleware {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryNonceStore()
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultReplayWindow
+	}
+	if cfg.Key == nil {
+		cfg.Key = ClientKey
+	}
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	checked := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		checked[strings.ToUpper(m)] = true
+	}
+	forgetter, _ := cfg.Store.(NonceForgetter)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !checked[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id := strings.TrimSpace(r.Header.Get(ClientRequestIDHeaderName))
+			if id == "" {
+				if cfg.Require {
+					WriteError(w, http.StatusBadRequest, errors.New("missing "+ClientRequestIDHeaderName))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			nonce := strings.Join([]string{cfg.Key(r), r.Method, r.URL.Path, id}, "\n")
+			if cfg.Store.Seen(nonce, time.Now().Add(cfg.Window)) {
+				if cfg.Logger != nil {
+					cfg.Logger.Warn("http", "type", "replay", "request-id", id, "method", r.Method, "url", r.URL.RequestURI(), "client-ip", ClientIP(r))
+				}
+				WriteError(w, http.StatusConflict, ErrReplayedRequest)
+				return
+			}
+			if forgetter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed || retryableStatus(rec.status) {
+					forgetter.Forget(nonce)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			completed = true
+		})
+	}
+}
+
+// retryableStatus reports whether a response invites the client to retry
+// the same request.
+func retryableStatus(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_RejectReplays(t *testing.T) {
+	status := http.StatusCreated
+	calls := 0
+	h := RejectReplays(ReplayConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	send := func(method, path, id, client string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if id != "" {
+			req.Header.Set(ClientRequestIDHeaderName, id)
+		}
+		req.Header.Set(ClientIDHeaderName, client)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if got := send(http.MethodPost, "/orders", "r1", "a"); got != http.StatusCreated {
+		t.Fatalf("first = %d", got)
+	}
+	if got := send(http.MethodPost, "/orders", "r1", "a"); got != http.StatusConflict {
+		t.Fatalf("replay = %d, want 409", got)
+	}
+	// another client, path, or an unchecked method is not a replay
+	for _, c := range []struct{ method, path, client string }{
+		{http.MethodPost, "/orders", "b"},
+		{http.MethodPost, "/refunds", "a"},
+		{http.MethodPut, "/orders", "a"},
+	} {
+		if got := send(c.method, c.path, "r1", c.client); got != http.StatusCreated {
+			t.Errorf("%s %s for %s = %d", c.method, c.path, c.client, got)
+		}
+	}
+	// requests without an id pass unchecked
+	if send(http.MethodPost, "/orders", "", "a") != http.StatusCreated || send(http.MethodPost, "/orders", "", "a") != http.StatusCreated {
+		t.Error("requests without an id were rejected")
+	}
+
+	// a failed attempt releases its id for the retry
+	status = http.StatusServiceUnavailable
+	send(http.MethodPost, "/orders", "r2", "a")
+	status = http.StatusCreated
+	if got := send(http.MethodPost, "/orders", "r2", "a"); got != http.StatusCreated {
+		t.Fatalf("retry after 503 = %d", got)
+	}
+	if calls != 8 {
+		t.Fatalf("handler ran %d times, want 8", calls)
+	}
+}
+
+func Test_RejectReplays_Require(t *testing.T) {
+	h := RejectReplays(ReplayConfig{Require: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// maxRequestIDLength caps an inbound X-Request-ID; longer values are replaced
+// so a client cannot inflate every log line.
+const maxRequestIDLength = 128
+
+// RequestID makes sure every request carries an X-Request-ID: an inbound id
+// is kept, otherwise a random one is generated. The id is set on the request
+// headers, echoed on the response, and stored in the ClientInfo in the
+// context, so logs, RequestIDFromContext, and OutboundHeaders all see it.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimSpace(r.Header.Get(ClientRequestIDHeaderName))
+			if id == "" || len(id) > maxRequestIDLength {
+				id = randomHex(16)
+			}
+			r.Header.Set(ClientRequestIDHeaderName, id)
+			w.Header().Set(ClientRequestIDHeaderName, id)
+
+			info, ok := ClientInfoFromContext(r.Context())
+			if ok {
+				info.RequestID = id
+			} else {
+				info = ClientInfoFromRequest(r)
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithClientInfo(r.Context(), info)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request id set by RequestID or
+// ClientInfoMiddleware, or "" when there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	info, _ := ClientInfoFromContext(ctx)
+	return info.RequestID
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RequestID_GeneratesAndPropagates(t *testing.T) {
+	var seen string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(seen) != 32 || rec.Header().Get(ClientRequestIDHeaderName) != seen {
+		t.Fatalf("generated id %q, response %q", seen, rec.Header().Get(ClientRequestIDHeaderName))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(ClientRequestIDHeaderName, "req-1")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if seen != "req-1" || rec.Header().Get(ClientRequestIDHeaderName) != "req-1" {
+		t.Fatalf("inbound id not kept: ctx %q, response %q", seen, rec.Header().Get(ClientRequestIDHeaderName))
+	}
+	if got := OutboundHeaders(req.Context()); got != nil {
+		t.Fatalf("original request context mutated: %v", got)
+	}
+}
+
+func Test_RequestID_ReplacesOversizedID(t *testing.T) {
+	var seen string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(ClientRequestIDHeaderName, strings.Repeat("x", maxRequestIDLength+1))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if len(seen) != 32 {
+		t.Fatalf("id = %q", seen)
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ResponseHeadersConfig declares default response headers (API version, cache
+// policy, ...) so handlers need not set them one by one. Handlers can still
+// override any of them.
+type ResponseHeadersConfig struct {
+	// Default applies to every response.
+	Default map[string]string
+	// Groups applies per path prefix ("/api/v2", "/static"), on top of
+	// Default. A prefix matches whole segments; when several match, the
+	// longest wins.
+	Groups map[string]map[string]string
+}
+
+func (c ResponseHeadersConfig) empty() bool {
+	return len(c.Default) == 0 && len(c.Groups) == 0
+}
+
+// DefaultHeaders returns a middleware that sets headers on every response
+// before the handler runs. Mount it on a Group to scope it to that group.
+func DefaultHeaders(headers map[string]string) Middleware {
+	return ResponseHeaders(ResponseHeadersConfig{Default: headers})
+}
+
+// ResponseHeaders returns a middleware applying cfg. NewServer installs it
+// from ServerConfig.ResponseHeaders.
+func ResponseHeaders(cfg ResponseHeadersConfig) Middleware {
+	// longest prefix first, so the first match is the most specific
+	prefixes := make([]string, 0, len(cfg.Groups))
+	for prefix := range cfg.Groups {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			for k, v := range cfg.Default {
+				h.Set(k, v)
+			}
+			for _, prefix := range prefixes {
+				if matchesPrefix(r.URL.Path, prefix) {
+					for k, v := range cfg.Groups[prefix] {
+						h.Set(k, v)
+					}
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchesPrefix reports whether path is prefix or lies below it.
+func matchesPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
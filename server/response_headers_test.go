@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ResponseHeaders(t *testing.T) {
+	cfg := ResponseHeadersConfig{
+		Default: map[string]string{"X-API-Version": "1", "Cache-Control": "no-store"},
+		Groups: map[string]map[string]string{
+			"/api":        {"X-API-Version": "2"},
+			"/api/static": {"Cache-Control": "public, max-age=3600"},
+		},
+	}
+	r := NewRouter()
+	r.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/override" {
+			w.Header().Set("X-API-Version", "handler")
+		}
+	})
+	s := NewServer(ServerConfig{ResponseHeaders: cfg}, r, nopLogger{})
+
+	for _, tc := range []struct {
+		path, version, cache string
+	}{
+		{"/health", "1", "no-store"},
+		{"/api/users", "2", "no-store"},
+		{"/apiv2", "1", "no-store"},
+		{"/api/static/app.js", "1", "public, max-age=3600"},
+		{"/api/override", "handler", "no-store"},
+	} {
+		rec := httptest.NewRecorder()
+		s.HTTP().Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, http.NoBody))
+		if got := rec.Header().Get("X-API-Version"); got != tc.version {
+			t.Errorf("%s: X-API-Version = %q want %q", tc.path, got, tc.version)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != tc.cache {
+			t.Errorf("%s: Cache-Control = %q want %q", tc.path, got, tc.cache)
+		}
+	}
+}
+
+func Test_DefaultHeaders_OnGroup(t *testing.T) {
+	r := NewRouter()
+	r.Group("/v2", func(g *Router) {
+		g.Use(DefaultHeaders(map[string]string{"Deprecation": "false"}))
+		g.Get("/items", func(http.ResponseWriter, *http.Request) {})
+	})
+	r.Get("/items", func(http.ResponseWriter, *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/items", http.NoBody))
+	if rec.Header().Get("Deprecation") != "false" {
+		t.Fatal("group header missing")
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", http.NoBody))
+	if rec.Header().Get("Deprecation") != "" {
+		t.Fatal("group header leaked outside the group")
+	}
+}
@@ -15,6 +15,7 @@ package server
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -40,6 +41,17 @@ func (r *Router) LogRoutes(logger Logger) {
 	}
 }
 
+// Routes lists the registered routes with their full patterns, sub-routers
+// and mounts flattened, e.g. for generating API documentation.
+func (r *Router) Routes() []Route {
+	var routes []Route
+	_ = chi.Walk(r.chi, func(method string, route string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, Route{Method: method, Pattern: route, Handler: handler})
+		return nil
+	})
+	return routes
+}
+
 // Use appends middleware to this router's scope. chi panics if called after
 // any route is registered on this scope.
 func (r *Router) Use(mw ...func(http.Handler) http.Handler) {
@@ -61,9 +73,10 @@ func (r *Router) With(mw ...func(http.Handler) http.Handler) *Router {
 
 // Handle registers handler for method+pattern. Pattern uses chi's syntax,
 // which mirrors stdlib's {name} placeholders for single segments and adds a
-// trailing /* for catch-all.
+// trailing /* for catch-all. A handler implementing RouteMiddleware is
+// wrapped in the middleware it declares.
 func (r *Router) Handle(method, pattern string, h http.Handler) {
-	r.chi.Method(method, pattern, h)
+	r.chi.Method(method, pattern, withRouteMiddleware(h))
 }
 
 // Get registers h for GET requests to pattern p.
@@ -81,6 +94,28 @@ func (r *Router) Delete(p string, h http.HandlerFunc) { r.Handle("DELETE", p, h)
 // Patch registers h for PATCH requests to pattern p.
 func (r *Router) Patch(p string, h http.HandlerFunc) { r.Handle("PATCH", p, h) }
 
+// Static serves h, such as a StaticHandler, for GET and HEAD requests under
+// prefix, with the path below prefix as the request path.
+func (r *Router) Static(prefix string, h http.Handler) {
+	serve := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		u := *req.URL
+		u.Path, u.RawPath = "/"+Param(req, "*"), ""
+		sub := req.Clone(req.Context())
+		sub.URL = &u
+		h.ServeHTTP(w, sub)
+	})
+	pattern := strings.TrimSuffix(prefix, "/") + "/*"
+	r.Handle(http.MethodGet, pattern, serve)
+	r.Handle(http.MethodHead, pattern, serve)
+}
+
+// Chi returns the underlying chi router for features this wrapper does not
+// expose (custom NotFound/MethodNotAllowed handlers, Mount, Walk, ...).
+// Routes and middleware registered through it behave exactly as if added via
+// Router, but code using it is tied to chi and may break if the router
+// implementation ever changes; prefer the Router methods where they suffice.
+func (r *Router) Chi() chi.Router { return r.chi }
+
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.chi.ServeHTTP(w, req)
 }
@@ -8,6 +8,42 @@ type Route struct {
 	Method  string
 	Pattern string
 	Handler http.Handler
+	// Middleware wraps Handler for this route only, outermost first, outside
+	// any middleware the handler declares itself.
+	Middleware []Middleware
+}
+
+// RouteMiddleware is implemented by handlers that declare their own
+// middleware stack, such as auth scopes, rate limits, or body limits.
+// Router.Handle and Register wrap such a handler in it when registering, so
+// the requirements live next to the handler rather than in a global chain.
+//
+//	func (h *createUser) Middleware() []server.Middleware {
+//		return []server.Middleware{
+//			server.ConcurrencyLimit(server.ConcurrencyConfig{MaxInFlight: 8}),
+//			server.RequireContentType(server.ContentTypeJSON),
+//		}
+//	}
+type RouteMiddleware interface {
+	http.Handler
+	Middleware() []Middleware
+}
+
+// withRouteMiddleware wraps h in the middleware it declares, if any, then in
+// mw, the first of mw outermost. The result no longer declares middleware,
+// so wrapping it again is a no-op.
+func withRouteMiddleware(h http.Handler, mw ...Middleware) http.Handler {
+	if rm, ok := h.(RouteMiddleware); ok {
+		mw = append(append([]Middleware{}, mw...), rm.Middleware()...)
+	}
+	if len(mw) == 0 {
+		return h
+	}
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	// hide any RouteMiddleware method of the innermost handler
+	return http.HandlerFunc(h.ServeHTTP)
 }
 
 // HandleRouter is the minimal interface satisfied by any HTTP framework that
@@ -16,9 +52,10 @@ type HandleRouter interface {
 	Handle(method, pattern string, handler http.Handler)
 }
 
-// Register registers all routes on r.
+// Register registers all routes on r, each wrapped in its Middleware and in
+// the middleware its handler declares through RouteMiddleware.
 func Register(r HandleRouter, routes []Route) {
 	for _, rt := range routes {
-		r.Handle(rt.Method, rt.Pattern, rt.Handler)
+		r.Handle(rt.Method, rt.Pattern, withRouteMiddleware(rt.Handler, rt.Middleware...))
 	}
 }
@@ -2,6 +2,8 @@ package server
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -46,3 +48,62 @@ func Test_Register_EmptyIsNoop(t *testing.T) {
 		t.Fatalf("expected no calls, got %d", len(rr.calls))
 	}
 }
+
+// declaringHandler declares its own middleware through RouteMiddleware.
+type declaringHandler struct {
+	mw []Middleware
+}
+
+func (declaringHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (h declaringHandler) Middleware() []Middleware { return h.mw }
+
+// tagMiddleware appends name to the X-Order response header on the way in.
+func tagMiddleware(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Order", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func Test_Router_Handle_AppliesDeclaredMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.Handle(http.MethodGet, "/scoped", declaringHandler{mw: []Middleware{tagMiddleware("a"), tagMiddleware("b")}})
+	r.Get("/plain", func(w http.ResponseWriter, _ *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scoped", nil))
+	if got := rec.Header().Values("X-Order"); strings.Join(got, ",") != "a,b" {
+		t.Fatalf("order = %v, want [a b]", got)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plain", nil))
+	if got := rec.Header().Values("X-Order"); len(got) != 0 {
+		t.Fatalf("plain route got middleware %v", got)
+	}
+}
+
+func Test_Register_RouteAndDeclaredMiddleware(t *testing.T) {
+	r := NewRouter()
+	Register(r, []Route{{
+		Method:     http.MethodGet,
+		Pattern:    "/x",
+		Handler:    declaringHandler{mw: []Middleware{tagMiddleware("handler")}},
+		Middleware: []Middleware{tagMiddleware("route")},
+	}})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	// declared middleware runs once, inside the route's
+	if got := rec.Header().Values("X-Order"); strings.Join(got, ",") != "route,handler" {
+		t.Fatalf("order = %v, want [route handler]", got)
+	}
+	if rec.Body.String() != "ok" {
+		t.Fatalf("body = %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Service is a long-running part of an app in the {Name, Start, Stop}
+// contract Server implements: Start runs until Stop is called, and Stop
+// returns once the service is done, within ctx's deadline.
+type Service interface {
+	Name() string
+	Start() error
+	Stop(ctx context.Context) error
+}
+
+var _ Service = (*Server)(nil)
+
+// Run launches each service's Start in order and blocks until ctx ends, the
+// process gets SIGINT or SIGTERM, or a service's Start returns. The services
+// then run concurrently: Run does not wait for one to be ready before
+// launching the next. It stops the services that were started in reverse
+// order and returns the errors of every Start that failed and every Stop.
+//
+// shutdownTimeout bounds the whole shutdown, every Stop and the wait for
+// their Start to return included; a service still running at the deadline
+// is reported and left behind. It defaults to 30s, and a negative value
+// waits indefinitely. A client's background tasks run through the client
+// package's NewService, so main() shrinks to:
+//
+//	srv := server.NewServer(cfg, r, logger)
+//	client := http.NewClient(clientCfg)
+//	if err := server.Run(ctx, 0, http.NewService(client), srv); err != nil {
+//		log.Fatal(err)
+//	}
+func Run(ctx context.Context, shutdownTimeout time.Duration, services ...Service) error {
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	// a service is only started while ctx is live, and only started ones
+	// are stopped, so Stop never runs for a service whose Start never will
+	exited := make([]chan struct{}, 0, len(services))
+	for _, svc := range services {
+		if ctx.Err() != nil {
+			break
+		}
+		done, entered := make(chan struct{}), make(chan struct{})
+		exited = append(exited, done)
+		go func() {
+			defer close(done)
+			// a service returning on its own takes the rest down with it
+			defer cancel()
+			close(entered)
+			if err := svc.Start(); err != nil {
+				fail(fmt.Errorf("failed to start %s: %w", svc.Name(), err))
+			}
+		}()
+		<-entered
+	}
+	<-ctx.Done()
+
+	stopCtx := context.WithoutCancel(ctx)
+	if shutdownTimeout == 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	if shutdownTimeout > 0 {
+		var cancelStop context.CancelFunc
+		stopCtx, cancelStop = context.WithTimeout(stopCtx, shutdownTimeout)
+		defer cancelStop()
+	}
+	for i := len(exited) - 1; i >= 0; i-- {
+		if err := services[i].Stop(stopCtx); err != nil {
+			fail(fmt.Errorf("failed to stop %s: %w", services[i].Name(), err))
+		}
+	}
+	for i, done := range exited {
+		select {
+		case <-done:
+		case <-stopCtx.Done():
+			fail(fmt.Errorf("%s still running after shutdown: %w", services[i].Name(), stopCtx.Err()))
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeService runs until stopped, or fails its Start with err.
+type fakeService struct {
+	name    string
+	err     error
+	log     *[]string
+	mu      *sync.Mutex
+	started chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newFakeService(name string, log *[]string, mu *sync.Mutex) *fakeService {
+	return &fakeService{name: name, log: log, mu: mu, started: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+func (s *fakeService) Name() string { return s.name }
+
+func (s *fakeService) Start() error {
+	s.record("start " + s.name)
+	if s.err != nil {
+		return s.err
+	}
+	close(s.started)
+	<-s.stopped
+	return nil
+}
+
+func (s *fakeService) Stop(context.Context) error {
+	s.record("stop " + s.name)
+	s.once.Do(func() { close(s.stopped) })
+	return nil
+}
+
+func (s *fakeService) record(event string) {
+	s.mu.Lock()
+	*s.log = append(*s.log, event)
+	s.mu.Unlock()
+}
+
+// stops keeps the stop events of log; starts run concurrently, so only the
+// order of stops is fixed.
+func stops(log []string) []string {
+	var out []string
+	for _, event := range log {
+		if strings.HasPrefix(event, "stop ") {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+func Test_Run_StopsInReverseOrder(t *testing.T) {
+	var (
+		log []string
+		mu  sync.Mutex
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	last := newFakeService("http", &log, &mu)
+	go func() {
+		done <- Run(ctx, 0, newFakeService("db", &log, &mu), newFakeService("client", &log, &mu), last)
+	}()
+	<-last.started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	want := []string{"stop http", "stop client", "stop db"}
+	if fmt.Sprint(stops(log)) != fmt.Sprint(want) {
+		t.Fatalf("log = %v, want %v", log, want)
+	}
+}
+
+func Test_Run_FailedStartStopsTheRest(t *testing.T) {
+	var (
+		log []string
+		mu  sync.Mutex
+	)
+	broken := newFakeService("broken", &log, &mu)
+	broken.err = errors.New("port in use")
+	err := Run(context.Background(), 0, newFakeService("worker", &log, &mu), broken)
+	if err == nil || !errors.Is(err, broken.err) {
+		t.Fatalf("err = %v", err)
+	}
+	want := []string{"stop broken", "stop worker"}
+	if fmt.Sprint(stops(log)) != fmt.Sprint(want) {
+		t.Fatalf("log = %v, want %v", log, want)
+	}
+}
+
+func Test_Run_NeverStartedServicesAreNotStopped(t *testing.T) {
+	var (
+		log []string
+		mu  sync.Mutex
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Run(ctx, 0, newFakeService("db", &log, &mu)); err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 0 {
+		t.Fatalf("stopped = %v", log)
+	}
+}
+
+// stuckService ignores Stop.
+type stuckService struct{ block chan struct{} }
+
+func (stuckService) Name() string                   { return "stuck" }
+func (s stuckService) Start() error                 { <-s.block; return nil }
+func (stuckService) Stop(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
+
+func Test_Run_ShutdownTimeout(t *testing.T) {
+	stuck := stuckService{block: make(chan struct{})}
+	defer close(stuck.block)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := Run(ctx, 50*time.Millisecond, stuck)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Run took %v", elapsed)
+	}
+}
+
+func Test_Run_Server(t *testing.T) {
+	srv := NewServer(ServerConfig{Host: "127.0.0.1"}, NewRouter(), nopLogger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, 0, srv) }()
+	<-srv.Listening()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// SchemaValidationResponse is the 400 body for a request that fails its
+// schema, and the 500 body for a response that fails its schema in dev mode.
+type SchemaValidationResponse struct {
+	Error      string            `json:"error"`
+	Violations []SchemaViolation `json:"violations"`
+}
+
+// DefaultSchemaMaxBodyBytes caps the request body ValidateSchema buffers when
+// SchemaConfig.MaxBodyBytes is unset.
+const DefaultSchemaMaxBodyBytes = 10 << 20
+
+// SchemaConfig attaches schemas to a route.
+type SchemaConfig struct {
+	// Request validates JSON request bodies; nil skips inbound validation.
+	Request *Schema
+	// MaxBodyBytes caps the request body buffered for validation; larger
+	// bodies are rejected with 413. Defaults to DefaultSchemaMaxBodyBytes.
+	MaxBodyBytes int64
+	// Response validates JSON response bodies, but only when ValidateResponse
+	// is set: it buffers every response, so keep it to development builds.
+	Response         *Schema
+	ValidateResponse bool
+	// Logger, when set, reports response violations so drift shows up in logs
+	// as well as in the failing call.
+	Logger Logger
+}
+
+// ValidateSchema returns a middleware enforcing cfg on a route. Requests with
+// a JSON body (or one without a Content-Type) that is not valid JSON or
+// violates cfg.Request are rejected with 400 and the violations; the handler
+// never sees them. Bodies of other media types, such as forms and multipart
+// uploads, pass unvalidated; pair it with RequireContentType to refuse them.
+// With ValidateResponse, a 2xx JSON response violating cfg.Response is
+// replaced with a 500 listing the violations, so contract drift fails loudly
+// in development.
+func ValidateSchema(cfg SchemaConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		if cfg.ValidateResponse && cfg.Response != nil {
+			next = TransformResponse(func(resp *BufferedResponse) error {
+				mediaType, _ := ParseMediaType(resp.Header.Get("Content-Type"))
+				if resp.Status < 200 || resp.Status >= 300 || mediaType != ContentTypeJSON {
+					return nil
+				}
+				var body any
+				if err := json.Unmarshal(resp.Body, &body); err != nil {
+					return errors.New("response body is not valid JSON")
+				}
+				violations := cfg.Response.Validate(body)
+				if len(violations) == 0 {
+					return nil
+				}
+				if cfg.Logger != nil {
+					cfg.Logger.Error("response does not match schema", "path", resp.Request.URL.Path, "violations", violations)
+				}
+				raw, err := json.Marshal(SchemaValidationResponse{Error: "response body does not match schema", Violations: violations})
+				if err != nil {
+					return err
+				}
+				resp.Status = http.StatusInternalServerError
+				resp.Body = append(raw, '\n')
+				return nil
+			})(next)
+		}
+
+		if cfg.Request == nil {
+			return next
+		}
+		if cfg.MaxBodyBytes <= 0 {
+			cfg.MaxBodyBytes = DefaultSchemaMaxBodyBytes
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType := r.Header.Get("Content-Type")
+			if r.Body == nil || r.Body == http.NoBody || (contentType != "" && !IsJSON(contentType)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				WriteError(w, http.StatusRequestEntityTooLarge, errors.New("request body too large"))
+				return
+			}
+			if err != nil {
+				WriteBadRequest(w, errors.New("failed to read request body"))
+				return
+			}
+			var body any
+			if err := json.Unmarshal(raw, &body); err != nil {
+				WriteBadRequest(w, errors.New("request body is not valid JSON"))
+				return
+			}
+			if violations := cfg.Request.Validate(body); len(violations) > 0 {
+				WriteJSON(w, http.StatusBadRequest, SchemaValidationResponse{Error: "request body does not match schema", Violations: violations})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(raw))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
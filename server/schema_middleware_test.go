@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_ValidateSchema_Request(t *testing.T) {
+	var got string
+	h := ValidateSchema(SchemaConfig{Request: MustCompileSchema(userSchema)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "valid passes body through", body: `{"name":"ada","age":36}`, want: http.StatusNoContent},
+		{name: "violation", body: `{"name":"ada"}`, want: http.StatusBadRequest},
+		{name: "malformed", body: `{`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusNoContent && got != tt.body {
+				t.Fatalf("handler saw body %q", got)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"ada"}`)))
+	var resp SchemaValidationResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Violations) != 1 || resp.Violations[0].Path != "/age" {
+		t.Fatalf("violations = %+v (%v)", resp, err)
+	}
+}
+
+func Test_ValidateSchema_RequestLimitsAndMediaTypes(t *testing.T) {
+	h := ValidateSchema(SchemaConfig{Request: MustCompileSchema(userSchema), MaxBodyBytes: 32})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{name: "json with parameters", contentType: "application/json; charset=utf-8", body: `{"name":"ada"}`, want: http.StatusBadRequest},
+		{name: "form passes", contentType: ContentTypeForm, body: "name=ada", want: http.StatusNoContent},
+		{name: "multipart passes", contentType: ContentTypeMultipart + "; boundary=x", body: "--x--", want: http.StatusNoContent},
+		{name: "too large", contentType: ContentTypeJSON, body: `{"name":"` + strings.Repeat("a", 64) + `","age":1}`, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func Test_ValidateSchema_Response(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]any{"name": "ada"})
+	})
+	schema := MustCompileSchema(userSchema)
+
+	t.Run("dev mode rejects drift", func(t *testing.T) {
+		logger := &levelLogger{}
+		rec := httptest.NewRecorder()
+		ValidateSchema(SchemaConfig{Response: schema, ValidateResponse: true, Logger: logger})(handler).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"/age"`) {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		if len(logger.levels) != 1 || logger.levels[0] != LogLevelError {
+			t.Fatalf("logged %v, want one error", logger.levels)
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ValidateSchema(SchemaConfig{Response: schema})(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d", rec.Code)
+		}
+	})
+}
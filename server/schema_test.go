@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 2, "pattern": "^[a-z]+$"},
+		"age": {"type": "integer", "minimum": 0},
+		"role": {"enum": ["admin", "member"]},
+		"nick": {"type": ["string", "null"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+	}
+}`
+
+func Test_Schema_Validate(t *testing.T) {
+	s := MustCompileSchema(userSchema)
+
+	tests := []struct {
+		name string
+		doc  string
+		want []SchemaViolation
+	}{
+		{name: "valid", doc: `{"name":"ada","age":36,"role":"admin","nick":null,"tags":["x"]}`},
+		{name: "wrong root type", doc: `[]`, want: []SchemaViolation{{Path: "", Message: "expected object, got array"}}},
+		{name: "missing and extra", doc: `{"name":"ada","extra/key":1}`, want: []SchemaViolation{
+			{Path: "/age", Message: "is required"},
+			{Path: "/extra~1key", Message: "is not allowed"},
+		}},
+		{name: "keyword failures", doc: `{"name":"A","age":1.5,"role":"root","tags":["a",2,"c"]}`, want: []SchemaViolation{
+			{Path: "/age", Message: "expected integer, got number"},
+			{Path: "/name", Message: "must be at least 2 characters"},
+			{Path: "/name", Message: `must match pattern "^[a-z]+$"`},
+			{Path: "/role", Message: "must be one of [admin member]"},
+			{Path: "/tags", Message: "must have at most 2 items"},
+			{Path: "/tags/1", Message: "expected string, got number"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := s.Validate(doc); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_CompileSchema_Errors(t *testing.T) {
+	for _, raw := range []string{`{"type": 3}`, `{"properties":{"a":{"pattern":"("}}}`, `nope`} {
+		if _, err := CompileSchema([]byte(raw)); err == nil {
+			t.Errorf("CompileSchema(%s) expected error", raw)
+		}
+	}
+}
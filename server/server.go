@@ -2,9 +2,15 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,12 +19,107 @@ import (
 // construction; override with WithReadHeaderTimeout or by mutating HTTP().
 const defaultReadHeaderTimeout = 10 * time.Second
 
-// Config configures a Server's listen address. Anything beyond the address
-// (timeouts, TLS, connection hooks) is set via Option or by mutating the
-// underlying server returned by HTTP.
-type Config struct {
+// defaultShutdownTimeout bounds Stop when its context has no deadline.
+const defaultShutdownTimeout = 30 * time.Second
+
+// ShutdownError reports a Stop whose graceful phase ran out of time, after
+// which the remaining connections were force-closed.
+type ShutdownError struct {
+	// ForceClosed counts the connections still open at the deadline.
+	ForceClosed int64
+	Err         error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("graceful shutdown timed out, force-closed %d connections: %v", e.ForceClosed, e.Err)
+}
+
+func (e *ShutdownError) Unwrap() error { return e.Err }
+
+// ServerConfig configures a Server: listen address, identity, timeouts, TLS,
+// health endpoints, and shutdown. Anything beyond that (connection hooks,
+// error log, ...) is set via Option or by mutating the underlying server
+// returned by HTTP.
+type ServerConfig struct {
 	Host string
+	// Port 0 binds a free port chosen by the OS; read it back from Addr
+	// once Listening is closed. Prefork needs a fixed port.
 	Port int
+	// Build identifies the service; serve it with VersionHandler(cfg.Build).
+	Build BuildInfo
+	// WellKnown holds robots/security metadata; serve it with
+	// RegisterWellKnown(router, cfg.WellKnown).
+	WellKnown WellKnownConfig
+	// ShutdownTimeout bounds Stop when its context carries no deadline.
+	// Defaults to 30s; negative waits for handlers indefinitely.
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long Stop keeps serving with readiness failing
+	// before it closes the listener, so load balancers stop routing to the
+	// instance first. It counts against the shutdown deadline.
+	DrainDelay time.Duration
+	// ReadTimeout bounds reading a whole request, body included.
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout bounds reading request headers. Defaults to 10s;
+	// negative disables it (not recommended - exposes the server to
+	// Slowloris).
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout bounds writing a response. It also cuts off SSE streams
+	// and other long responses, so leave it 0 on servers that stream.
+	WriteTimeout time.Duration
+	// IdleTimeout is how long a keep-alive connection may sit idle.
+	IdleTimeout time.Duration
+	// MaxHeaderBytes caps the size of request headers; 0 means net/http's
+	// 1 MB.
+	MaxHeaderBytes int
+	// TLS serves HTTPS instead of plain HTTP.
+	TLS TLSConfig
+	// Health serves built-in liveness and readiness endpoints.
+	Health HealthConfig
+	// Prefork, when non-zero, runs that many worker processes (negative means
+	// one per CPU) sharing the listen address through SO_REUSEPORT, for
+	// CPU-bound APIs on large machines. Start in the parent then supervises
+	// the workers instead of serving, and Stop terminates them; the workers
+	// re-execute the binary and serve as usual. Check IsPreforkChild before
+	// doing once-per-deployment work.
+	Prefork int
+	// ResponseHeaders sets default response headers, globally and per path
+	// prefix, ahead of every handler.
+	ResponseHeaders ResponseHeadersConfig
+	// BasePath mounts the router under a path prefix (e.g. "/api") for
+	// deployments behind path-based ingress. Routes are registered without
+	// it; RoutePattern, Routes, and the logged routes include it.
+	BasePath string
+	// Middleware enables the standard request id, access log, recovery, and
+	// CORS middleware from configuration.
+	Middleware MiddlewareConfig
+}
+
+// Config is the former name of ServerConfig.
+//
+// Deprecated: use ServerConfig, which does not read like the client's Config.
+type Config = ServerConfig
+
+// TLSConfig serves HTTPS from a certificate and key file, or from
+// GetCertificate.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// GetCertificate picks the certificate per handshake and takes
+	// precedence over the files. Plug in an ACME manager here, e.g.
+	// autocert.Manager's GetCertificate, for automatic certificates.
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.CertFile != "" || c.GetCertificate != nil
+}
+
+// certFiles returns the files to serve, none when GetCertificate is set.
+func (c TLSConfig) certFiles() (cert, key string) {
+	if c.GetCertificate != nil {
+		return "", ""
+	}
+	return c.CertFile, c.KeyFile
 }
 
 // Option mutates the underlying *http.Server during construction. Options run
@@ -50,26 +151,77 @@ func WithIdleTimeout(d time.Duration) Option {
 // Server wraps net/http.Server around a *Router. Implements the
 // {Name, Start, Stop} contract expected by go-shared/service.Service.
 type Server struct {
-	config Config
+	config ServerConfig
 	router *Router
+	// root is what the server dispatches to: router, or a router mounting it
+	// under ServerConfig.BasePath.
+	root   *Router
 	logger Logger
 	http   *http.Server
+	stats  serverStats
+	// hijacks tracks connections taken over by Hijack, Upgrade, and
+	// UpgradeWebSocket, which Shutdown does not wait for.
+	hijacks hijackTracker
+	// supervisor is set in a prefork parent (see ServerConfig.Prefork).
+	supervisor *supervisor
+	// draining is closed once Stop begins.
+	draining  chan struct{}
+	drainOnce sync.Once
+	// listening is closed once serve has bound addr.
+	listening  chan struct{}
+	listenOnce sync.Once
+	addrMu     sync.Mutex
+	addr       net.Addr
 }
 
 // NewServer wires a Server around the router. A github.com/toaweme/log logger
 // can be injected directly, or a null logger to discard output. Pass Options
 // to tune the underlying *http.Server, or reach for HTTP to set fields no
 // Option covers.
-func NewServer(cfg Config, router *Router, logger Logger, opts ...Option) *Server {
+func NewServer(cfg ServerConfig, router *Router, logger Logger, opts ...Option) *Server {
+	root := router
+	cfg.BasePath = normalizeBasePath(cfg.BasePath)
+	if cfg.BasePath != "" {
+		root = NewRouter()
+		root.chi.Mount(cfg.BasePath, router.chi)
+	}
+	var handler http.Handler = root
+	if !cfg.ResponseHeaders.empty() {
+		handler = ResponseHeaders(cfg.ResponseHeaders)(root)
+	}
+	handler = cfg.Middleware.wrap(handler, logger)
+	s := &Server{config: cfg, router: router, root: root, logger: logger, draining: make(chan struct{}), listening: make(chan struct{})}
+	if cfg.Health.Enabled {
+		handler = s.healthHandler(handler)
+	}
+
+	readHeaderTimeout := defaultReadHeaderTimeout
+	switch {
+	case cfg.ReadHeaderTimeout > 0:
+		readHeaderTimeout = cfg.ReadHeaderTimeout
+	case cfg.ReadHeaderTimeout < 0:
+		readHeaderTimeout = 0
+	}
 	srv := &http.Server{
 		Addr:              fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Handler:           router,
-		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	if cfg.TLS.GetCertificate != nil {
+		srv.TLSConfig = &tls.Config{GetCertificate: cfg.TLS.GetCertificate}
 	}
 	for _, opt := range opts {
 		opt(srv)
 	}
-	return &Server{config: cfg, router: router, logger: logger, http: srv}
+	s.http = srv
+	if cfg.Prefork != 0 && !IsPreforkChild() {
+		s.supervisor = newSupervisor(preforkWorkers(cfg.Prefork), logger)
+	}
+	return s
 }
 
 // HTTP returns the underlying *http.Server for callers that need to set fields
@@ -78,29 +230,198 @@ func NewServer(cfg Config, router *Router, logger Logger, opts ...Option) *Serve
 // effect.
 func (s *Server) HTTP() *http.Server { return s.http }
 
+// Router returns the router the server dispatches to. Register routes before
+// Start; use Router().Chi() to reach chi itself. Lifecycle (Start/Stop) stays
+// with Server - do not call ListenAndServe on HTTP() directly, or Stats and
+// graceful shutdown are bypassed.
+func (s *Server) Router() *Router { return s.router }
+
+// BasePath is the normalized ServerConfig.BasePath ("/api"), or "" when the router
+// is served at the root. Prefix it to paths when building links.
+func (s *Server) BasePath() string { return s.config.BasePath }
+
+// Routes lists every route as served, base path included.
+func (s *Server) Routes() []Route { return s.root.Routes() }
+
+// normalizeBasePath gives p a leading slash and no trailing one; "/" and ""
+// mean no base path.
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// Listening is closed once Start has bound its listener, after which Addr
+// and URL report where the server is reachable. It stays open if binding
+// fails, so wait on Start's error too:
+//
+//	go func() { errCh <- s.Start() }()
+//	select {
+//	case <-s.Listening():
+//	case err := <-errCh:
+//		return err
+//	}
+//	base := s.URL() // e.g. http://127.0.0.1:54321 for Port 0
+func (s *Server) Listening() <-chan struct{} { return s.listening }
+
+// Addr is the address the server is bound to, with the actual port when
+// ServerConfig.Port is 0, or nil before Start has bound its listener.
+func (s *Server) Addr() net.Addr {
+	s.addrMu.Lock()
+	defer s.addrMu.Unlock()
+	return s.addr
+}
+
+// URL is the base URL of the bound server, such as https://[::]:8443, or ""
+// before Start has bound its listener.
+func (s *Server) URL() string {
+	addr := s.Addr()
+	if addr == nil {
+		return ""
+	}
+	if s.config.TLS.enabled() {
+		return "https://" + addr.String()
+	}
+	return "http://" + addr.String()
+}
+
 // Name identifies the service in a service registry.
 func (s *Server) Name() string { return "http" }
 
 // Start serves until Stop is called. It blocks and returns nil on a clean
 // shutdown.
 func (s *Server) Start() error {
-	s.router.LogRoutes(s.logger)
+	if s.supervisor != nil {
+		if s.config.Port == 0 {
+			return errors.New("prefork needs a fixed port, the workers cannot share port 0")
+		}
+		return s.supervisor.run()
+	}
 
-	s.logger.Info("service", "http", "server", "addr", "http://"+s.http.Addr)
-	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	s.root.LogRoutes(s.logger)
+	s.stats.instrument(s.http)
+	s.hijacks.instrument(s.http)
+
+	if err := s.serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		s.logger.Error("service", "http", "server", "error", err)
 		return err
 	}
 	return nil
 }
 
-// Stop gracefully shuts the server down, respecting ctx's deadline.
+// serve listens, records and logs the bound address, and serves; a prefork
+// worker binds with SO_REUSEPORT so its siblings can share the address.
+func (s *Server) serve() error {
+	tlsCfg := s.config.TLS
+	prefork := s.config.Prefork != 0 && IsPreforkChild()
+	var lc net.ListenConfig
+	if prefork {
+		lc.Control = reusePort
+	}
+	ln, err := lc.Listen(context.Background(), "tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+	s.addrMu.Lock()
+	s.addr = ln.Addr()
+	s.addrMu.Unlock()
+	s.listenOnce.Do(func() { close(s.listening) })
+	s.logger.Info("service", "http", "server", "addr", s.URL())
+
+	if prefork {
+		// split the machine between the workers rather than oversubscribing it
+		runtime.GOMAXPROCS(max(1, runtime.NumCPU()/preforkWorkers(s.config.Prefork)))
+		go s.exitWithParent()
+	}
+	if tlsCfg.enabled() {
+		cert, key := tlsCfg.certFiles()
+		return s.http.ServeTLS(ln, cert, key)
+	}
+	return s.http.Serve(ln)
+}
+
+// exitWithParent stops a prefork worker whose supervisor died, so workers
+// are never orphaned.
+func (s *Server) exitWithParent() {
+	parent := os.Getppid()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if os.Getppid() != parent {
+			s.logger.Warn("service", "http", "prefork", "msg", "supervisor exited, stopping worker")
+			_ = s.Stop(context.Background())
+			return
+		}
+	}
+}
+
+// Draining is closed once Stop begins. Long-lived handlers such as SSE
+// streams should select on it and finish, since Stop waits for them.
+func (s *Server) Draining() <-chan struct{} { return s.draining }
+
+// Stop drains and shuts the server down, respecting ctx's deadline, or
+// ServerConfig.ShutdownTimeout when ctx has none. It first fails the readiness
+// endpoint and closes Draining, keeps serving for ServerConfig.DrainDelay, then
+// stops accepting connections and waits for in-flight requests, streams and
+// hijacked connections included; hijacked connections see
+// ErrServerShuttingDown on their context as soon as Stop begins. Connections
+// still open at the deadline are force-closed so a stuck handler cannot block
+// shutdown; that case returns a *ShutdownError carrying the count. In a
+// prefork parent it sends the workers SIGTERM and kills those still running
+// at the deadline.
 func (s *Server) Stop(ctx context.Context) error {
 	if s.http == nil {
 		return nil
 	}
-	if err := s.http.Shutdown(ctx); err != nil {
+	s.drainOnce.Do(func() {
+		close(s.draining)
+		s.hijacks.shutdown()
+	})
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := s.config.ShutdownTimeout
+		if timeout == 0 {
+			timeout = defaultShutdownTimeout
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+	if s.supervisor != nil {
+		return s.supervisor.stop(ctx)
+	}
+
+	if s.config.DrainDelay > 0 && s.stats.startedAt.Load() != 0 {
+		s.logger.Info("service", "http", "server", "msg", "draining", "delay", s.config.DrainDelay)
+		timer := time.NewTimer(s.config.DrainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	err := s.http.Shutdown(ctx)
+	if err == nil {
+		if err := s.hijacks.wait(ctx); err != nil {
+			remaining := s.hijacks.closeAll()
+			s.logger.Warn("service", "http", "server", "msg", "graceful shutdown timed out, force-closing hijacked connections", "connections", remaining)
+			return &ShutdownError{ForceClosed: remaining, Err: err}
+		}
+		return nil
+	}
+	if ctx.Err() == nil {
 		return fmt.Errorf("failed to shutdown http server: %w", err)
 	}
-	return nil
+
+	remaining := s.stats.connections.Load()
+	s.logger.Warn("service", "http", "server", "msg", "graceful shutdown timed out, force-closing connections", "connections", remaining)
+	remaining += s.hijacks.closeAll()
+	if closeErr := s.http.Close(); closeErr != nil {
+		return fmt.Errorf("failed to close http server: %w", closeErr)
+	}
+	return &ShutdownError{ForceClosed: remaining, Err: err}
 }
@@ -2,16 +2,20 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
 
 func Test_NewServer_Defaults(t *testing.T) {
 	r := NewRouter()
-	s := NewServer(Config{Host: "127.0.0.1", Port: 8080}, r, nopLogger{})
+	s := NewServer(ServerConfig{Host: "127.0.0.1", Port: 8080}, r, nopLogger{})
 
 	if s.Name() != "http" {
 		t.Fatalf("Name: got %q want http", s.Name())
@@ -33,7 +37,7 @@ func Test_NewServer_Defaults(t *testing.T) {
 
 func Test_NewServer_OptionsApplied(t *testing.T) {
 	r := NewRouter()
-	s := NewServer(Config{Host: "", Port: 0}, r, nopLogger{},
+	s := NewServer(ServerConfig{Host: "", Port: 0}, r, nopLogger{},
 		WithReadHeaderTimeout(3*time.Second),
 		WithReadTimeout(4*time.Second),
 		WithWriteTimeout(5*time.Second),
@@ -56,14 +60,14 @@ func Test_NewServer_OptionsApplied(t *testing.T) {
 }
 
 func Test_NewServer_OptionsOverrideDefault(t *testing.T) {
-	s := NewServer(Config{}, NewRouter(), nopLogger{}, WithReadHeaderTimeout(0))
+	s := NewServer(ServerConfig{}, NewRouter(), nopLogger{}, WithReadHeaderTimeout(0))
 	if got := s.HTTP().ReadHeaderTimeout; got != 0 {
 		t.Fatalf("ReadHeaderTimeout: got %v want 0 (disabled via option)", got)
 	}
 }
 
 func Test_Server_HTTPEscapeHatch(t *testing.T) {
-	s := NewServer(Config{}, NewRouter(), nopLogger{})
+	s := NewServer(ServerConfig{}, NewRouter(), nopLogger{})
 	// fields no Option covers are still reachable before Start
 	s.HTTP().MaxHeaderBytes = 4096
 	if s.HTTP().MaxHeaderBytes != 4096 {
@@ -72,7 +76,7 @@ func Test_Server_HTTPEscapeHatch(t *testing.T) {
 }
 
 func Test_Server_StopBeforeStart(t *testing.T) {
-	s := NewServer(Config{}, NewRouter(), nopLogger{})
+	s := NewServer(ServerConfig{}, NewRouter(), nopLogger{})
 	if err := s.Stop(t.Context()); err != nil {
 		t.Fatalf("Stop before Start: got %v want nil", err)
 	}
@@ -84,7 +88,7 @@ func Test_Server_StartStopLifecycle(t *testing.T) {
 	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {
 		_, _ = w.Write([]byte("pong"))
 	})
-	s := NewServer(Config{Host: "127.0.0.1", Port: port}, r, nopLogger{})
+	s := NewServer(ServerConfig{Host: "127.0.0.1", Port: port}, r, nopLogger{})
 
 	errCh := make(chan error, 1)
 	go func() { errCh <- s.Start() }()
@@ -111,6 +115,57 @@ func Test_Server_StartStopLifecycle(t *testing.T) {
 	}
 }
 
+func Test_Server_PortZero(t *testing.T) {
+	r := NewRouter()
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	})
+	s := NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, r, nopLogger{})
+	if s.Addr() != nil || s.URL() != "" {
+		t.Fatalf("Addr before Start = %v, URL = %q", s.Addr(), s.URL())
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start() }()
+	select {
+	case <-s.Listening():
+	case err := <-errCh:
+		t.Fatalf("Start: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server never started listening")
+	}
+
+	port := s.Addr().(*net.TCPAddr).Port
+	if port == 0 {
+		t.Fatal("Addr reports port 0")
+	}
+	if want := fmt.Sprintf("http://127.0.0.1:%d", port); s.URL() != want {
+		t.Fatalf("URL = %q, want %q", s.URL(), want)
+	}
+	resp, err := http.Get(s.URL() + "/ping")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: got %d want 200", resp.StatusCode)
+	}
+
+	if err := s.Stop(t.Context()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start returned error after clean shutdown: %v", err)
+	}
+}
+
+func Test_Server_PreforkNeedsPort(t *testing.T) {
+	s := NewServer(ServerConfig{Host: "127.0.0.1", Prefork: 2}, NewRouter(), nopLogger{})
+	if err := s.Start(); err == nil {
+		t.Fatal("Start with Prefork and Port 0 succeeded")
+	}
+}
+
 // freePort binds an ephemeral port, releases it, and returns the number. There
 // is an inherent race before the server re-binds, but it is acceptable in tests.
 func freePort(t *testing.T) int {
@@ -135,3 +190,167 @@ func waitReachable(t *testing.T, url string) {
 	}
 	t.Fatalf("server never became reachable at %s", url)
 }
+
+func Test_Server_RouterAccessors(t *testing.T) {
+	r := NewRouter()
+	s := NewServer(ServerConfig{}, r, nopLogger{})
+	if s.Router() != r {
+		t.Fatal("Router() is not the router passed to NewServer")
+	}
+
+	r.Chi().NotFound(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("custom NotFound via Chi(): got %d", rec.Code)
+	}
+}
+
+func Test_Server_StopForceClosesStuckHandlers(t *testing.T) {
+	port := freePort(t)
+	release := make(chan struct{})
+	defer close(release)
+	r := NewRouter()
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {})
+	r.Get("/stuck", func(w http.ResponseWriter, _ *http.Request) { <-release })
+	s := NewServer(ServerConfig{Host: "127.0.0.1", Port: port, ShutdownTimeout: 100 * time.Millisecond}, r, nopLogger{})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start() }()
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	waitReachable(t, base+"/ping")
+
+	go func() {
+		if resp, err := http.Get(base + "/stuck"); err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	for s.Stats().InFlightRequests == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	start := time.Now()
+	err := s.Stop(context.Background())
+	var serr *ShutdownError
+	if !errors.As(err, &serr) || serr.ForceClosed < 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop: got %v want a ShutdownError with a force-closed connection", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Stop took %v, ShutdownTimeout not applied", elapsed)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+}
+
+func Test_NewServer_ConfigTimeouts(t *testing.T) {
+	s := NewServer(ServerConfig{
+		ReadTimeout:       4 * time.Second,
+		ReadHeaderTimeout: 3 * time.Second,
+		WriteTimeout:      5 * time.Second,
+		IdleTimeout:       6 * time.Second,
+		MaxHeaderBytes:    8 << 10,
+	}, NewRouter(), nopLogger{}, WithIdleTimeout(7*time.Second))
+
+	srv := s.HTTP()
+	if srv.ReadTimeout != 4*time.Second || srv.ReadHeaderTimeout != 3*time.Second || srv.WriteTimeout != 5*time.Second || srv.MaxHeaderBytes != 8<<10 {
+		t.Fatalf("config not applied: %+v", srv)
+	}
+	if srv.IdleTimeout != 7*time.Second {
+		t.Fatalf("IdleTimeout: got %v, want the option to override the config", srv.IdleTimeout)
+	}
+	if got := NewServer(ServerConfig{ReadHeaderTimeout: -1}, NewRouter(), nopLogger{}).HTTP().ReadHeaderTimeout; got != 0 {
+		t.Fatalf("negative ReadHeaderTimeout: got %v want disabled", got)
+	}
+}
+
+func Test_Server_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	cert := ts.TLS.Certificates[0]
+
+	port := freePort(t)
+	r := NewRouter()
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("pong")) })
+	s := NewServer(ServerConfig{Host: "127.0.0.1", Port: port, TLS: TLSConfig{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &cert, nil },
+	}}, r, nopLogger{})
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start() }()
+
+	client := ts.Client()
+	url := fmt.Sprintf("https://127.0.0.1:%d/ping", port)
+	var resp *http.Response
+	var err error
+	for range 100 {
+		if resp, err = client.Get(url); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.TLS == nil || string(body) != "pong" {
+		t.Fatalf("got %q over TLS %v", body, resp.TLS != nil)
+	}
+
+	if err := s.Stop(t.Context()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+}
+
+func Test_Server_StopDrainsStreams(t *testing.T) {
+	port := freePort(t)
+	r := NewRouter()
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {})
+	var s *Server
+	r.Get("/events", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-s.Draining()
+		_, _ = w.Write([]byte("data: bye\n\n"))
+	})
+	s = NewServer(ServerConfig{Host: "127.0.0.1", Port: port, DrainDelay: 100 * time.Millisecond, Health: HealthConfig{Enabled: true}}, r, nopLogger{})
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start() }()
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	waitReachable(t, base+"/ping")
+
+	resp, err := http.Get(base + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+	<-s.Draining()
+	// the listener stays open for DrainDelay, reporting not ready
+	ready, err := http.Get(base + "/readyz")
+	if err != nil {
+		t.Fatalf("readiness during drain delay: %v", err)
+	}
+	_ = ready.Body.Close()
+	if ready.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("readiness during drain: %d, want 503", ready.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "data: bye\n\n" {
+		t.Fatalf("stream body = %q, want the final event", body)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ServiceTokenHeaderName carries the service identity token, mirrored from
+// github.com/toaweme/http. Keep the name and the token format verified
+// here in sync with the client's MintServiceToken.
+const ServiceTokenHeaderName = "X-Service-Token"
+
+// ErrInvalidServiceToken is the 401 body for any request failing service
+// identity verification; the reason is only logged.
+var ErrInvalidServiceToken = errors.New("invalid service token")
+
+// ServiceKey verifies the tokens signed by one key: a shared HMAC Secret,
+// in the same JSON shape as the client's ServiceIdentity, or the Ed25519
+// PublicKey matching a client's PrivateKey.
+type ServiceKey struct {
+	ID        string            `json:"key_id"`
+	Secret    string            `json:"secret"`
+	PublicKey ed25519.PublicKey `json:"-"`
+}
+
+// ServiceIdentityConfig configures VerifyServiceIdentity.
+type ServiceIdentityConfig struct {
+	// Keys are the accepted keys, looked up by the token's key id. Several
+	// can be active at once for rotation.
+	Keys []ServiceKey
+	// Audience, when set, is this service's name: tokens must be minted for
+	// it.
+	Audience string
+	// Services, when set, lists the only calling services accepted.
+	Services []string
+	// Leeway is the allowed clock skew when checking the token's times.
+	// Defaults to 30s.
+	Leeway time.Duration
+	// Logger, when set, records why each rejected request failed.
+	Logger Logger
+}
+
+// serviceClaims mirrors the client's ServiceClaims.
+type serviceClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// VerifyServiceIdentity returns a middleware that authenticates internal
+// callers by the service identity token the client's WithServiceIdentity
+// attaches: it must be signed by a known key with the algorithm that key
+// implies, be unexpired, name this service when Audience is set, and be
+// issued by the service the X-Service-Name header claims. The verified
+// name is available to handlers through ServiceFromContext. Failures get
+// 401.
+func VerifyServiceIdentity(cfg ServiceIdentityConfig) Middleware {
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = 30 * time.Second
+	}
+	keys := make(map[string]ServiceKey, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		keys[k.ID] = k
+	}
+	var services map[string]struct{}
+	if len(cfg.Services) > 0 {
+		services = make(map[string]struct{}, len(cfg.Services))
+		for _, s := range cfg.Services {
+			services[s] = struct{}{}
+		}
+	}
+	now := time.Now
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reject := func(reason string) {
+				if cfg.Logger != nil {
+					cfg.Logger.Warn("http", "type", "service-identity", "reason", reason, "service", r.Header.Get(ServiceNameHeaderName), "url", r.URL.RequestURI(), "client-ip", ClientIP(r))
+				}
+				WriteError(w, http.StatusUnauthorized, ErrInvalidServiceToken)
+			}
+
+			token := r.Header.Get(ServiceTokenHeaderName)
+			if token == "" {
+				reject("missing token")
+				return
+			}
+			claims, reason := verifyServiceToken(token, keys)
+			if reason != "" {
+				reject(reason)
+				return
+			}
+			t := now()
+			if t.After(time.Unix(claims.ExpiresAt, 0).Add(cfg.Leeway)) {
+				reject("token expired")
+				return
+			}
+			if t.Add(cfg.Leeway).Before(time.Unix(claims.IssuedAt, 0)) {
+				reject("token issued in the future")
+				return
+			}
+			if cfg.Audience != "" && claims.Audience != cfg.Audience {
+				reject("wrong audience")
+				return
+			}
+			if name := r.Header.Get(ServiceNameHeaderName); name != "" && name != claims.Issuer {
+				reject("service name does not match token")
+				return
+			}
+			if services != nil {
+				if _, ok := services[claims.Issuer]; !ok {
+					reject("service not allowed")
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxService, claims.Issuer)))
+		})
+	}
+}
+
+// verifyServiceToken checks the signature of a compact token and decodes
+// its claims, returning the reason it is rejected, if any.
+func verifyServiceToken(token string, keys map[string]ServiceKey) (serviceClaims, string) {
+	var claims serviceClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, "malformed token"
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return claims, "malformed header"
+	}
+	key, ok := keys[header.Kid]
+	if !ok {
+		return claims, "unknown key"
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, "malformed signature"
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	// the key decides the algorithm, never the token, so an HMAC token
+	// cannot pass as a public-key one
+	switch {
+	case key.PublicKey != nil:
+		if header.Alg != "EdDSA" || !ed25519.Verify(key.PublicKey, signed, sig) {
+			return claims, "signature mismatch"
+		}
+	case key.Secret != "":
+		mac := hmac.New(sha256.New, []byte(key.Secret))
+		mac.Write(signed)
+		if header.Alg != "HS256" || !hmac.Equal(sig, mac.Sum(nil)) {
+			return claims, "signature mismatch"
+		}
+	default:
+		return claims, "key has no secret or public key"
+	}
+	if err := decodeTokenPart(parts[1], &claims); err != nil || claims.Issuer == "" {
+		return claims, "malformed claims"
+	}
+	return claims, ""
+}
+
+func decodeTokenPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ServiceFromContext returns the calling service verified by
+// VerifyServiceIdentity.
+func ServiceFromContext(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(ctxService).(string)
+	return s, ok
+}
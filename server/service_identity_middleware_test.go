@@ -0,0 +1,95 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// mintServiceToken mirrors the client's MintServiceToken.
+func mintServiceToken(t *testing.T, alg, kid string, claims serviceClaims, secret string, priv ed25519.PrivateKey) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	body, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	var sig []byte
+	if priv != nil {
+		sig = ed25519.Sign(priv, []byte(signed))
+	} else {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func Test_VerifyServiceIdentity(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw := VerifyServiceIdentity(ServiceIdentityConfig{
+		Keys:     []ServiceKey{{ID: "shared", Secret: "s3cret"}, {ID: "ed", PublicKey: pub}},
+		Audience: "billing",
+		Services: []string{"orders", "search"},
+	})
+	var caller string
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ = ServiceFromContext(r.Context())
+	}))
+
+	now := time.Now().Unix()
+	valid := serviceClaims{Issuer: "orders", Audience: "billing", IssuedAt: now, ExpiresAt: now + 60}
+	expired := valid
+	expired.IssuedAt, expired.ExpiresAt = now-600, now-300
+	otherAudience := valid
+	otherAudience.Audience = "search"
+	unlisted := valid
+	unlisted.Issuer = "intruder"
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"hmac", mintServiceToken(t, "HS256", "shared", valid, "s3cret", nil), "orders", http.StatusOK},
+		{"ed25519", mintServiceToken(t, "EdDSA", "ed", valid, "", priv), "", http.StatusOK},
+		{"missing", "", "", http.StatusUnauthorized},
+		{"wrong secret", mintServiceToken(t, "HS256", "shared", valid, "guess", nil), "", http.StatusUnauthorized},
+		{"unknown key", mintServiceToken(t, "HS256", "other", valid, "s3cret", nil), "", http.StatusUnauthorized},
+		{"algorithm confusion", mintServiceToken(t, "HS256", "ed", valid, string(pub), nil), "", http.StatusUnauthorized},
+		{"expired", mintServiceToken(t, "HS256", "shared", expired, "s3cret", nil), "", http.StatusUnauthorized},
+		{"audience", mintServiceToken(t, "HS256", "shared", otherAudience, "s3cret", nil), "", http.StatusUnauthorized},
+		{"name mismatch", mintServiceToken(t, "HS256", "shared", valid, "s3cret", nil), "search", http.StatusUnauthorized},
+		{"not allowed", mintServiceToken(t, "HS256", "shared", unlisted, "s3cret", nil), "", http.StatusUnauthorized},
+		{"malformed", "a.b", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller = ""
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				r.Header.Set(ServiceTokenHeaderName, tt.token)
+			}
+			if tt.header != "" {
+				r.Header.Set(ServiceNameHeaderName, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && caller != "orders" {
+				t.Fatalf("ServiceFromContext = %q", caller)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net"
 	"net/http"
 	"strings"
@@ -23,50 +24,134 @@ type SlogConfig struct {
 	LogRequestHeaders bool
 	// LogResponseHeaders logs outgoing response headers (captured at end of request).
 	LogResponseHeaders bool
-	// MaxBodyBytes caps how much of each body is captured. 0 means no cap.
+	// MaxBodyBytes caps how much of each body is captured for the log.
+	// Bodies stream through a capped tee, so the handler and client still see
+	// every byte while memory stays bounded on upload-heavy routes. 0 means
+	// no cap; DefaultMaxBodyBytes suits most uses.
 	MaxBodyBytes int
+
+	// SampleRate is the fraction of successful (< 400) requests that are
+	// logged, e.g. 0.01 for 1%. 0 logs every request. Error responses and slow
+	// requests are always logged regardless of sampling.
+	SampleRate float64
+	// SlowThreshold marks requests that take at least this long as slow; they
+	// are always logged, at Warn unless their route level is higher. 0 disables it.
+	SlowThreshold time.Duration
+	// RouteLevels sets the log level per route pattern (as registered, e.g.
+	// "/health" or "/items/{id}"). LogLevelNone silences a route's successful
+	// requests entirely, which suits high-QPS probes. Unlisted routes log at Info.
+	RouteLevels map[string]LogLevel
+
+	// Format writes each logged request to Output as a line of a standard
+	// access log format instead of through the Logger, so GoAccess, Loki, or
+	// vector ingest it without a custom parser. Sampling, slow requests, and
+	// route levels apply as before; headers and bodies are not part of any
+	// format. The zero value keeps the Logger.
+	Format AccessLogFormat
+	// Output receives Format's lines. Defaults to os.Stdout.
+	Output io.Writer
+	// ServiceName is the service.name resource attribute of AccessLogOTLP
+	// records.
+	ServiceName string
+}
+
+// DefaultMaxBodyBytes is a body capture cap for SlogConfig.MaxBodyBytes that
+// keeps most JSON payloads whole.
+const DefaultMaxBodyBytes = 64 << 10
+
+// sample draws the number compared against SlogConfig.SampleRate. Tests swap
+// it for a deterministic source.
+var sample = rand.Float64
+
+// level decides whether a finished request is logged and at which level.
+func (cfg SlogConfig) level(pattern string, status int, elapsed time.Duration) (LogLevel, bool) {
+	level, ok := cfg.RouteLevels[pattern]
+	if !ok {
+		level = LogLevelInfo
+	}
+
+	slow := cfg.SlowThreshold > 0 && elapsed >= cfg.SlowThreshold
+	switch {
+	case status >= http.StatusInternalServerError:
+		return level.atLeast(LogLevelError), true
+	case status >= http.StatusBadRequest:
+		return level.atLeast(LogLevelInfo), true
+	case slow:
+		return level.atLeast(LogLevelWarn), true
+	case level == LogLevelNone:
+		return level, false
+	case cfg.SampleRate > 0 && cfg.SampleRate < 1:
+		return level, sample() < cfg.SampleRate
+	default:
+		return level, true
+	}
 }
 
 // SlogMiddleware logs method, url and duration for every request, plus
 // optionally the request and response bodies when the config opts in.
+// Sampling, slow-request promotion, and per-route levels decide whether and at
+// which level each request is logged; see SlogConfig. A Format writes
+// requests as access log lines instead, and panics when unknown.
 func SlogMiddleware(cfg SlogConfig, logger Logger) func(http.Handler) http.Handler {
+	var writeAccess func(accessEntry)
+	if cfg.Format != AccessLogLogger {
+		writeAccess = accessLogWriter(cfg)
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			maxBytes := cfg.MaxBodyBytes
+			if maxBytes <= 0 {
+				maxBytes = -1
+			}
 
-			var reqBody []byte
+			var reqBody *bodyCapture
 			if cfg.LogRequestBody && r.Body != nil {
-				reqBody, r.Body = readAndReplace(r.Body, cfg.MaxBodyBytes)
+				reqBody = &bodyCapture{max: maxBytes}
+				r.Body = &teeBody{ReadCloser: r.Body, capture: reqBody}
 			}
 
 			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
 			if cfg.LogResponseBody {
-				rw.buf = &bytes.Buffer{}
-				rw.maxBytes = cfg.MaxBodyBytes
+				rw.capture = &bodyCapture{max: maxBytes}
 			}
 
 			next.ServeHTTP(rw, r)
 
+			elapsed := time.Since(start)
+			level, ok := cfg.level(RoutePattern(r), rw.status, elapsed)
+			if !ok {
+				return
+			}
+			if writeAccess != nil {
+				writeAccess(newAccessEntry(r, rw, start, elapsed, level))
+				return
+			}
+
 			args := []any{
 				"method", r.Method,
 				"url", r.URL.RequestURI(),
-				"duration", time.Since(start).String(),
+				"duration", elapsed.String(),
 				"code", rw.status,
+				"client-ip", ClientIP(r),
+			}
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				args = append(args, "request-id", id)
 			}
 			if cfg.LogRequestHeaders {
 				args = append(args, "request-headers", flattenHeaders(r.Header))
 			}
-			if cfg.LogRequestBody {
-				args = append(args, "request-body", string(reqBody))
+			if reqBody != nil {
+				args = reqBody.appendArgs(args, "request-body")
 			}
 			if cfg.LogResponseHeaders {
 				args = append(args, "response-headers", flattenHeaders(rw.Header()))
 			}
-			if cfg.LogResponseBody && rw.buf != nil {
-				args = append(args, "response-body", rw.buf.String())
+			if rw.capture != nil {
+				args = rw.capture.appendArgs(args, "response-body")
 			}
 
-			logger.Info("http", args...)
+			logAt(logger, level, "http", args...)
 		})
 	}
 }
@@ -83,32 +168,60 @@ func flattenHeaders(h http.Header) map[string]string {
 	return out
 }
 
-func readAndReplace(body io.ReadCloser, maxBytes int) ([]byte, io.ReadCloser) {
-	defer body.Close()
-	var (
-		buf []byte
-		err error
-	)
-	if maxBytes > 0 {
-		buf, err = io.ReadAll(io.LimitReader(body, int64(maxBytes)))
-	} else {
-		buf, err = io.ReadAll(body)
+// bodyCapture keeps the first max bytes written to it (all of them when max
+// is negative) and counts the rest.
+type bodyCapture struct {
+	buf   bytes.Buffer
+	max   int
+	total int64
+}
+
+func (c *bodyCapture) write(p []byte) {
+	c.total += int64(len(p))
+	if c.max < 0 {
+		c.buf.Write(p)
+		return
+	}
+	if room := c.max - c.buf.Len(); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		c.buf.Write(p)
+	}
+}
+
+// appendArgs logs the captured prefix under key, plus the full size when the
+// body outgrew the cap.
+func (c *bodyCapture) appendArgs(args []any, key string) []any {
+	args = append(args, key, c.buf.String())
+	if c.total > int64(c.buf.Len()) {
+		args = append(args, key+"-size", c.total)
 	}
-	if err != nil {
-		return nil, io.NopCloser(bytes.NewReader(nil))
+	return args
+}
+
+// teeBody copies what the handler reads from a request body into a capture.
+// Bytes the handler never reads are never captured.
+type teeBody struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.capture.write(p[:n])
 	}
-	// drain any remainder so the handler still sees a full, untruncated body
-	rest, _ := io.ReadAll(body)
-	full := append(buf, rest...)
-	return buf, io.NopCloser(bytes.NewReader(full))
+	return n, err
 }
 
 type responseRecorder struct {
 	http.ResponseWriter
 	status      int
 	wroteHeader bool
-	buf         *bytes.Buffer
-	maxBytes    int
+	capture     *bodyCapture
+	// written counts the body bytes sent.
+	written int64
 }
 
 // Flush forwards to the underlying writer when it supports flushing, so SSE
@@ -145,18 +258,10 @@ func (r *responseRecorder) Write(b []byte) (int, error) {
 	if !r.wroteHeader {
 		r.wroteHeader = true
 	}
-	if r.buf != nil {
-		remaining := len(b)
-		if r.maxBytes > 0 {
-			if room := r.maxBytes - r.buf.Len(); room > 0 {
-				if remaining > room {
-					remaining = room
-				}
-				r.buf.Write(b[:remaining])
-			}
-		} else {
-			r.buf.Write(b)
-		}
+	if r.capture != nil {
+		r.capture.write(b)
 	}
-	return r.ResponseWriter.Write(b)
+	n, err := r.ResponseWriter.Write(b)
+	r.written += int64(n)
+	return n, err
 }
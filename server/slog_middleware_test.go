@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 // captureLogger records the args of the last Info call so tests can assert
@@ -105,6 +106,49 @@ func Test_SlogMiddleware_CapturesResponseBodyWithCap(t *testing.T) {
 	}
 }
 
+func Test_SlogMiddleware_CaptureIsCappedTee(t *testing.T) {
+	log := &captureLogger{}
+	payload := strings.Repeat("x", DefaultMaxBodyBytes+10)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+
+	var seen int64
+	serveThrough(SlogConfig{LogRequestBody: true, LogResponseBody: true, MaxBodyBytes: DefaultMaxBodyBytes}, log, func(w http.ResponseWriter, r *http.Request) {
+		// the handler streams the body; nothing was read ahead of it
+		if _, ok := r.Body.(*teeBody); !ok {
+			t.Errorf("body is %T, want the streaming tee", r.Body)
+		}
+		seen, _ = io.Copy(w, r.Body)
+	}, req)
+
+	if seen != int64(len(payload)) {
+		t.Fatalf("handler saw %d bytes want %d", seen, len(payload))
+	}
+	for _, key := range []string{"request-body", "response-body"} {
+		if got, _ := log.last[key].(string); len(got) != DefaultMaxBodyBytes {
+			t.Fatalf("%s: captured %d bytes want the cap %d", key, len(got), DefaultMaxBodyBytes)
+		}
+		if got := log.last[key+"-size"]; got != int64(len(payload)) {
+			t.Fatalf("%s-size: got %v want %d", key, got, len(payload))
+		}
+	}
+}
+
+func Test_SlogMiddleware_ZeroCapCapturesEverything(t *testing.T) {
+	log := &captureLogger{}
+	payload := strings.Repeat("y", DefaultMaxBodyBytes+10)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	serveThrough(SlogConfig{LogRequestBody: true}, log, func(_ http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}, req)
+
+	if got := log.last["request-body"]; got != payload {
+		t.Fatalf("request-body: captured %d bytes want all %d", len(got.(string)), len(payload))
+	}
+	if _, ok := log.last["request-body-size"]; ok {
+		t.Fatal("request-body-size logged for an untruncated body")
+	}
+}
+
 func Test_SlogMiddleware_LogsHeadersWhenEnabled(t *testing.T) {
 	log := &captureLogger{}
 	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
@@ -261,3 +305,70 @@ func Test_flattenHeaders_JoinsMultiValue(t *testing.T) {
 		t.Fatalf("single: got %q want 'one'", out["X-Single"])
 	}
 }
+
+// levelLogger records the level of every call.
+type levelLogger struct {
+	levels []LogLevel
+}
+
+var _ Logger = (*levelLogger)(nil)
+
+func (l *levelLogger) Trace(string, ...any) { l.levels = append(l.levels, LogLevelTrace) }
+func (l *levelLogger) Debug(string, ...any) { l.levels = append(l.levels, LogLevelDebug) }
+func (l *levelLogger) Info(string, ...any)  { l.levels = append(l.levels, LogLevelInfo) }
+func (l *levelLogger) Warn(string, ...any)  { l.levels = append(l.levels, LogLevelWarn) }
+func (l *levelLogger) Error(string, ...any) { l.levels = append(l.levels, LogLevelError) }
+
+func Test_SlogMiddleware_Sampling(t *testing.T) {
+	orig := sample
+	defer func() { sample = orig }()
+
+	cfg := SlogConfig{SampleRate: 0.1}
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	fail := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusBadGateway) }
+
+	log := &levelLogger{}
+	sample = func() float64 { return 0.5 } // outside the 10% sample
+	serveThrough(cfg, log, ok, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if len(log.levels) != 0 {
+		t.Fatalf("unsampled 200 was logged: %v", log.levels)
+	}
+	serveThrough(cfg, log, fail, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if len(log.levels) != 1 || log.levels[0] != LogLevelError {
+		t.Fatalf("5xx must always log at error: %v", log.levels)
+	}
+
+	sample = func() float64 { return 0.05 } // inside the sample
+	serveThrough(cfg, log, ok, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if len(log.levels) != 2 || log.levels[1] != LogLevelInfo {
+		t.Fatalf("sampled 200 not logged at info: %v", log.levels)
+	}
+}
+
+func Test_SlogMiddleware_RouteLevels(t *testing.T) {
+	log := &levelLogger{}
+	r := NewRouter()
+	r.Use(SlogMiddleware(SlogConfig{RouteLevels: map[string]LogLevel{
+		"/health":      LogLevelNone,
+		"/items/{id}":  LogLevelDebug,
+		"/slow":        LogLevelNone,
+		"/broken/{id}": LogLevelNone,
+	}, SlowThreshold: 20 * time.Millisecond}, log))
+	r.Get("/health", func(http.ResponseWriter, *http.Request) {})
+	r.Get("/items/{id}", func(http.ResponseWriter, *http.Request) {})
+	r.Get("/slow", func(http.ResponseWriter, *http.Request) { time.Sleep(25 * time.Millisecond) })
+	r.Get("/broken/{id}", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) })
+
+	for _, path := range []string{"/health", "/items/1", "/slow", "/broken/1"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, http.NoBody))
+	}
+	want := []LogLevel{LogLevelDebug, LogLevelWarn, LogLevelError}
+	if len(log.levels) != len(want) {
+		t.Fatalf("levels: got %v want %v", log.levels, want)
+	}
+	for i := range want {
+		if log.levels[i] != want[i] {
+			t.Fatalf("levels: got %v want %v", log.levels, want)
+		}
+	}
+}
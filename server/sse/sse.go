@@ -22,12 +22,18 @@ import (
 	"time"
 )
 
+// ContentType is the media type of an event stream.
+const ContentType = "text/event-stream"
+
 // Event is one SSE record. ID is optional; Type maps to "event:"; Data is the
-// payload. Empty Type emits a default "message" event.
+// payload. Empty Type emits a default "message" event. Retry, when positive,
+// also sets the client's reconnect delay ("retry:"). These mirror the Event,
+// ID, and Body of the client's StreamResponse.
 type Event struct {
-	ID   string
-	Type string
-	Data string
+	ID    string
+	Type  string
+	Data  string
+	Retry time.Duration
 }
 
 // JSONEvent is a convenience constructor - marshals the payload into Data.
@@ -45,6 +51,8 @@ func JSONEvent(eventType string, payload any) (Event, error) {
 // every Write reaches the client immediately. The first call sets the
 // streaming headers; subsequent calls just emit events.
 type Writer struct {
+	// mu serialises writes, so a heartbeat can share the connection.
+	mu      sync.Mutex
 	w       http.ResponseWriter
 	flusher http.Flusher
 	started bool
@@ -62,11 +70,17 @@ func NewWriter(w http.ResponseWriter) (*Writer, error) {
 
 // Start writes the streaming headers and flushes. Safe to call multiple times.
 func (w *Writer) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.start()
+}
+
+func (w *Writer) start() {
 	if w.started {
 		return
 	}
 	h := w.w.Header()
-	h.Set("Content-Type", "text/event-stream")
+	h.Set("Content-Type", ContentType)
 	h.Set("Cache-Control", "no-cache")
 	h.Set("Connection", "keep-alive")
 	// disable response buffering proxies - nginx in particular needs this
@@ -79,9 +93,6 @@ func (w *Writer) Start() {
 // Write emits one SSE event and flushes. Returns the underlying write error
 // (typically because the client disconnected) so the caller knows to stop.
 func (w *Writer) Write(ev Event) error {
-	if !w.started {
-		w.Start()
-	}
 	var b strings.Builder
 	if ev.ID != "" {
 		b.WriteString("id: ")
@@ -93,6 +104,11 @@ func (w *Writer) Write(ev Event) error {
 		b.WriteString(ev.Type)
 		b.WriteByte('\n')
 	}
+	if ev.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatInt(ev.Retry.Milliseconds(), 10))
+		b.WriteByte('\n')
+	}
 	// data may be multi-line - emit one `data:` per line per spec
 	for _, line := range strings.Split(ev.Data, "\n") {
 		b.WriteString("data: ")
@@ -100,22 +116,53 @@ func (w *Writer) Write(ev Event) error {
 		b.WriteByte('\n')
 	}
 	b.WriteByte('\n')
-	if _, err := w.w.Write([]byte(b.String())); err != nil {
+	if err := w.emit(b.String()); err != nil {
 		return fmt.Errorf("failed to write sse event: %w", err)
 	}
-	w.flusher.Flush()
+	return nil
+}
+
+// Retry tells the client how long to wait before reconnecting, without
+// dispatching an event.
+func (w *Writer) Retry(d time.Duration) error {
+	if err := w.emit("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n"); err != nil {
+		return fmt.Errorf("failed to write sse retry: %w", err)
+	}
+	return nil
+}
+
+// Comment emits text as comment lines, which clients ignore.
+func (w *Writer) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(": ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	if err := w.emit(b.String()); err != nil {
+		return fmt.Errorf("failed to write sse comment: %w", err)
+	}
 	return nil
 }
 
 // Ping emits an SSE comment line - useful as a heartbeat to keep proxies
 // from idle-closing the connection. Comments are ignored by clients.
 func (w *Writer) Ping() error {
-	if !w.started {
-		w.Start()
-	}
-	if _, err := w.w.Write([]byte(": ping\n\n")); err != nil {
+	if err := w.emit(": ping\n\n"); err != nil {
 		return fmt.Errorf("failed to write sse ping: %w", err)
 	}
+	return nil
+}
+
+// emit starts the stream if needed, then writes and flushes raw.
+func (w *Writer) emit(raw string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.start()
+	if _, err := w.w.Write([]byte(raw)); err != nil {
+		return err
+	}
 	w.flusher.Flush()
 	return nil
 }
@@ -83,6 +83,11 @@ func Test_Writer_WriteFormat(t *testing.T) {
 			ev:   Event{Data: "a\nb"},
 			want: "data: a\ndata: b\n\n",
 		},
+		{
+			name: "retry is sent in milliseconds",
+			ev:   Event{Type: "msg", Data: "x", Retry: 2 * time.Second},
+			want: "event: msg\nretry: 2000\ndata: x\n\n",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,120 @@
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DoneSentinel is the data payload the toaweme/http client treats as the end
+// of a stream (its DefaultStreamDoneSentinel, mirrored here because this
+// module does not import the client).
+const DoneSentinel = "[DONE]"
+
+// DefaultHeartbeat is the keep-alive interval of a Stream.
+const DefaultHeartbeat = 15 * time.Second
+
+// ErrClientGone is returned by Stream writes once the client disconnected.
+var ErrClientGone = errors.New("sse client disconnected")
+
+// StreamOptions tunes NewStream.
+type StreamOptions struct {
+	// Heartbeat is the interval of keep-alive comments; 0 means
+	// DefaultHeartbeat and a negative value disables them.
+	Heartbeat time.Duration
+	// Retry, when positive, is sent first as the client's reconnect delay.
+	Retry time.Duration
+}
+
+// Stream is a Writer bound to one request: it starts the response, sends
+// heartbeats in the background, and reports when the client goes away.
+// Writes are safe from any goroutine.
+type Stream struct {
+	*Writer
+	r    *http.Request
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// NewStream starts an event stream on w for r. Call Close (or End) when the
+// handler is done to stop the heartbeat. Fails, writing a 500, when w cannot
+// flush.
+func NewStream(w http.ResponseWriter, r *http.Request, opts StreamOptions) (*Stream, error) {
+	sw, err := NewWriter(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	sw.Start()
+	s := &Stream{Writer: sw, r: r, stop: make(chan struct{})}
+	if opts.Retry > 0 {
+		if err := sw.Retry(opts.Retry); err != nil {
+			return nil, err
+		}
+	}
+	interval := opts.Heartbeat
+	if interval == 0 {
+		interval = DefaultHeartbeat
+	}
+	if interval > 0 {
+		s.wg.Add(1)
+		go s.heartbeat(interval)
+	}
+	return s, nil
+}
+
+func (s *Stream) heartbeat(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.r.Context().Done():
+			return
+		case <-ticker.C:
+			if err := s.Writer.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Done is closed once the client disconnects.
+func (s *Stream) Done() <-chan struct{} {
+	return s.r.Context().Done()
+}
+
+// Write emits ev, or returns ErrClientGone after a disconnect.
+func (s *Stream) Write(ev Event) error {
+	if s.r.Context().Err() != nil {
+		return ErrClientGone
+	}
+	return s.Writer.Write(ev)
+}
+
+// WriteJSON marshals payload into an event of type eventType and writes it.
+func (s *Stream) WriteJSON(eventType string, payload any) error {
+	ev, err := JSONEvent(eventType, payload)
+	if err != nil {
+		return err
+	}
+	return s.Write(ev)
+}
+
+// End closes s and writes the DoneSentinel, ending the stream for the
+// client without a reconnect.
+func (s *Stream) End() error {
+	s.Close()
+	return s.Write(Event{Data: DoneSentinel})
+}
+
+// Close stops the heartbeat and waits for it to return. Safe to call more
+// than once.
+func (s *Stream) Close() {
+	s.once.Do(func() { close(s.stop) })
+	s.wg.Wait()
+}
@@ -0,0 +1,63 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Stream_HeartbeatRetryAndEnd(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stream", http.NoBody)
+	s, err := NewStream(rec, req, StreamOptions{Heartbeat: 5 * time.Millisecond, Retry: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := s.WriteJSON("update", map[string]int{"n": 1}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if err := s.End(); err != nil {
+		t.Fatalf("End: %v", err)
+	}
+
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "retry: 1500\n\n") {
+		t.Fatalf("retry not sent first: %q", body)
+	}
+	for _, want := range []string{": ping\n\n", "event: update\ndata: {\"n\":1}\n\n"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("body missing %q: %q", want, body)
+		}
+	}
+	if !strings.HasSuffix(body, "data: "+DoneSentinel+"\n\n") {
+		t.Fatalf("stream not ended with the sentinel: %q", body)
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func Test_Stream_DetectsDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	req := httptest.NewRequest(http.MethodGet, "/stream", http.NoBody).WithContext(ctx)
+	s, err := NewStream(httptest.NewRecorder(), req, StreamOptions{Heartbeat: -1})
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+	defer s.Close()
+
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done not closed after disconnect")
+	}
+	if err := s.Write(Event{Data: "late"}); !errors.Is(err, ErrClientGone) {
+		t.Fatalf("Write after disconnect = %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// PublishJSON marshals v and writes it to w as one event of the given type,
+// the server-side counterpart of decoding typed events on the client.
+func PublishJSON[T any](w *Writer, event string, v T) error {
+	ev, err := JSONEvent(event, v)
+	if err != nil {
+		return err
+	}
+	return w.Write(ev)
+}
+
+// TypedEvent is an event whose payload was decoded into T. Err is set, and
+// Data left zero, when the payload did not decode.
+type TypedEvent[T any] struct {
+	ID   string
+	Type string
+	Data T
+	Err  error
+}
+
+// Topic is a typed view of one hub topic: Publish marshals T, Subscribe
+// decodes it, so producers and consumers share the payload type rather than
+// raw strings. Events published to the topic by other means reach typed
+// subscribers too, with Err set when they do not decode.
+type Topic[T any] struct {
+	hub   *Hub
+	name  string
+	event string
+}
+
+// NewTopic binds topic on hub; event is the "event:" type stamped on every
+// published payload (empty means the default "message").
+func NewTopic[T any](hub *Hub, topic, event string) *Topic[T] {
+	return &Topic[T]{hub: hub, name: topic, event: event}
+}
+
+// Publish marshals v and publishes it to every subscriber of the topic.
+func (t *Topic[T]) Publish(v T) error {
+	ev, err := JSONEvent(t.event, v)
+	if err != nil {
+		return err
+	}
+	t.hub.Publish(t.name, ev)
+	return nil
+}
+
+// Subscribe registers a typed subscriber, with the same buffering, slow
+// subscriber, and cancellation rules as Hub.Subscribe.
+func (t *Topic[T]) Subscribe(ctx context.Context, buffer int) (<-chan TypedEvent[T], func()) {
+	raw, cancelRaw := t.hub.Subscribe(ctx, t.name, buffer)
+	out := make(chan TypedEvent[T], cap(raw))
+	done := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() { close(done) })
+		cancelRaw()
+	}
+
+	go func() {
+		defer close(out)
+		for ev := range raw {
+			typed := TypedEvent[T]{ID: ev.ID, Type: ev.Type}
+			if err := json.Unmarshal([]byte(ev.Data), &typed.Data); err != nil {
+				typed.Err = fmt.Errorf("failed to decode sse payload: %w", err)
+			}
+			select {
+			case out <- typed:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, cancel
+}
+
+// ServeTopic streams a typed topic like ServeStream does a raw one.
+func ServeTopic[T any](w http.ResponseWriter, r *http.Request, topic *Topic[T]) error {
+	return ServeStream(w, r, topic.hub, topic.name)
+}
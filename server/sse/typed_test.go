@@ -0,0 +1,76 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type priceUpdate struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price"`
+}
+
+func Test_PublishJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w, err := NewWriter(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishJSON(w, "price", priceUpdate{Symbol: "ACME", Price: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	want := "event: price\ndata: {\"symbol\":\"ACME\",\"price\":1.5}\n\n"
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("body: got %q", rec.Body.String())
+	}
+	if err := PublishJSON(w, "bad", make(chan int)); err == nil {
+		t.Fatal("expected marshal error")
+	}
+}
+
+func Test_Topic_PublishSubscribe(t *testing.T) {
+	hub := NewHub()
+	prices := NewTopic[priceUpdate](hub, "prices", "price")
+	ch, cancel := prices.Subscribe(t.Context(), 8)
+	defer cancel()
+
+	if err := prices.Publish(priceUpdate{Symbol: "ACME", Price: 2}); err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish("prices", Event{Data: "not json"})
+
+	for _, check := range []func(TypedEvent[priceUpdate]){
+		func(ev TypedEvent[priceUpdate]) {
+			if ev.Err != nil || ev.Type != "price" || ev.Data.Symbol != "ACME" || ev.ID == "" {
+				t.Fatalf("typed event: %+v", ev)
+			}
+		},
+		func(ev TypedEvent[priceUpdate]) {
+			if ev.Err == nil {
+				t.Fatal("expected decode error for raw event")
+			}
+		},
+	} {
+		select {
+		case ev := <-ch:
+			check(ev)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("channel delivered after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+	if hub.Subscribers("prices") != 0 {
+		t.Fatal("subscriber not removed")
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import "net/http"
+
+// MiddlewareConfig turns on the standard middleware NewServer wraps around
+// every request, base path and 404s included. Each is off while its field is
+// zero. They run outermost first in field order: request id, access log,
+// recovery, CORS.
+type MiddlewareConfig struct {
+	// RequestID assigns or propagates X-Request-ID; see RequestID.
+	RequestID bool
+	// AccessLog logs each request through SlogMiddleware with this config.
+	AccessLog *SlogConfig
+	// Recovery recovers handler panics; see Recover.
+	Recovery *RecoveryConfig
+	// CORS applies CrossOrigin with this policy.
+	CORS *CorsConfig
+	// Correlation sets X-Request-ID, X-Served-By, and X-Response-Time on
+	// responses; see Correlate.
+	Correlation *CorrelationConfig
+}
+
+// wrap applies the enabled middleware around h.
+func (cfg MiddlewareConfig) wrap(h http.Handler, logger Logger) http.Handler {
+	if cfg.CORS != nil {
+		h = CrossOrigin(*cfg.CORS)(h)
+	}
+	if cfg.Recovery != nil {
+		h = Recover(*cfg.Recovery, logger)(h)
+	}
+	if cfg.Correlation != nil {
+		h = Correlate(*cfg.Correlation)(h)
+	}
+	if cfg.AccessLog != nil {
+		h = SlogMiddleware(*cfg.AccessLog, logger)(h)
+	}
+	if cfg.RequestID {
+		h = RequestID()(h)
+	}
+	return h
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_NewServer_MiddlewareFromConfig(t *testing.T) {
+	r := NewRouter()
+	r.Get("/boom", func(http.ResponseWriter, *http.Request) { panic("boom") })
+	r.Get("/ok", func(http.ResponseWriter, *http.Request) {})
+
+	logger := &captureLogger{}
+	srv := NewServer(ServerConfig{Middleware: MiddlewareConfig{
+		RequestID:   true,
+		AccessLog:   &SlogConfig{},
+		Recovery:    &RecoveryConfig{},
+		CORS:        &CorsConfig{AllowedOrigins: []string{"https://app.example"}},
+		Correlation: &CorrelationConfig{ServedBy: "api-1"},
+	}}, r, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set(ClientRequestIDHeaderName, "abc")
+	rec := httptest.NewRecorder()
+	srv.HTTP().Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if rec.Header().Get(ClientRequestIDHeaderName) != "abc" || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example" || rec.Header().Get(ServedByHeaderName) != "api-1" {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+
+	// the access log carries the request id
+	req = httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(ClientRequestIDHeaderName, "def")
+	srv.HTTP().Handler.ServeHTTP(httptest.NewRecorder(), req)
+	if logger.last["request-id"] != "def" {
+		t.Fatalf("logged %v", logger.last)
+	}
+}
+
+func Test_NewServer_NoMiddlewareByDefault(t *testing.T) {
+	r := NewRouter()
+	r.Get("/", func(http.ResponseWriter, *http.Request) {})
+	rec := httptest.NewRecorder()
+	NewServer(ServerConfig{}, r, nopLogger{}).HTTP().Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get(ClientRequestIDHeaderName) != "" {
+		t.Fatalf("request id set without opting in")
+	}
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticVersionHeaderName carries the asset version on every static
+// response, so a dev page can poll for changes.
+const StaticVersionHeaderName = "X-Static-Version"
+
+// defaultStaticPollInterval is StaticConfig.PollInterval when unset.
+const defaultStaticPollInterval = 500 * time.Millisecond
+
+// StaticConfig configures NewStaticHandler.
+type StaticConfig struct {
+	// FS holds the assets served in production, typically an embed.FS
+	// narrowed with fs.Sub.
+	FS fs.FS
+	// Dev serves Dir from disk instead of FS and watches it, so edited,
+	// added, and removed files are served without a restart and never
+	// cached by the browser.
+	Dev bool
+	// Dir is the asset directory on disk, used in Dev mode.
+	Dir string
+	// PollInterval is how often Dev mode checks Dir for changes. Defaults
+	// to 500ms.
+	PollInterval time.Duration
+	// OnChange, when set, is called with the new version after Dev mode saw
+	// Dir change, e.g. to push a live-reload event.
+	OnChange func(version string)
+	// Logger, when set, records every reload.
+	Logger Logger
+}
+
+// StaticHandler serves static assets with cache-busting: Version changes
+// whenever the content does, and a request for a path carrying the current
+// version (see URL) is cached by the browser for a year, while any other is
+// revalidated through its ETag. Mount it with Router.Static.
+type StaticHandler struct {
+	cfg    StaticConfig
+	fsys   fs.FS
+	files  http.Handler
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.RWMutex
+	version string
+	// hashes maps every file to its content hash and the stat it was taken
+	// at.
+	hashes map[string]staticFile
+}
+
+// staticFile is the content hash of one asset, kept with the size and
+// modification time it was computed at so a rescan skips unchanged files.
+type staticFile struct {
+	hash    string
+	size    int64
+	modTime time.Time
+}
+
+// NewStaticHandler builds a StaticHandler from cfg. In Dev mode it scans Dir
+// every PollInterval until Close.
+func NewStaticHandler(cfg StaticConfig) (*StaticHandler, error) {
+	fsys := cfg.FS
+	if cfg.Dev {
+		if cfg.Dir == "" {
+			return nil, errors.New("static dev mode needs a Dir")
+		}
+		if info, err := os.Stat(cfg.Dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("static dir %q is not a directory", cfg.Dir)
+		}
+		fsys = os.DirFS(cfg.Dir)
+	}
+	if fsys == nil {
+		return nil, errors.New("static handler needs an FS or a Dev Dir")
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultStaticPollInterval
+	}
+
+	s := &StaticHandler{cfg: cfg, fsys: fsys, files: http.FileServerFS(fsys), done: make(chan struct{})}
+	version, hashes, err := scanStatic(fsys, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.version, s.hashes = version, hashes
+	if !cfg.Dev {
+		close(s.done)
+		return s, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.watch(ctx)
+	return s, nil
+}
+
+// Version identifies the current content of every asset.
+func (s *StaticHandler) Version() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// URL returns p with the current version appended as ?v=, for templates to
+// link assets that browsers cache until the content changes.
+func (s *StaticHandler) URL(p string) string {
+	sep := "?"
+	if strings.Contains(p, "?") {
+		sep = "&"
+	}
+	return p + sep + "v=" + s.Version()
+}
+
+// Close stops watching Dir. It is a no-op outside Dev mode.
+func (s *StaticHandler) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	<-s.done
+}
+
+// ServeHTTP serves the asset at the request path.
+func (s *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	s.mu.RLock()
+	version, hash := s.version, s.hashes[name].hash
+	s.mu.RUnlock()
+
+	w.Header().Set(StaticVersionHeaderName, version)
+	switch {
+	case s.cfg.Dev:
+		w.Header().Set("Cache-Control", "no-store")
+	case r.URL.Query().Get("v") == version:
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	default:
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if hash != "" && !s.cfg.Dev {
+		// embedded files have no modification time to revalidate with
+		w.Header().Set("ETag", `"`+hash+`"`)
+	}
+	s.files.ServeHTTP(w, r)
+}
+
+// watch rescans the directory until ctx is done. Only files whose size or
+// modification time changed are read again.
+func (s *StaticHandler) watch(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.mu.RLock()
+		prev := s.hashes
+		s.mu.RUnlock()
+		version, hashes, err := scanStatic(s.fsys, prev)
+		if err != nil {
+			if s.cfg.Logger != nil {
+				s.cfg.Logger.Error("http", "type", "static", "dir", s.cfg.Dir, "error", err)
+			}
+			continue
+		}
+		s.mu.Lock()
+		changed := version != s.version
+		s.version, s.hashes = version, hashes
+		s.mu.Unlock()
+		if !changed {
+			continue
+		}
+		if s.cfg.Logger != nil {
+			s.cfg.Logger.Info("http", "type", "static", "reloaded", s.cfg.Dir, "files", len(hashes), "version", version)
+		}
+		if s.cfg.OnChange != nil {
+			s.cfg.OnChange(version)
+		}
+	}
+}
+
+// scanStatic hashes every file of fsys, returning the hashes by path and a
+// version over all of them. A file whose size and modification time match
+// its entry in prev keeps that hash without being read.
+func scanStatic(fsys fs.FS, prev map[string]staticFile) (string, map[string]staticFile, error) {
+	hashes := make(map[string]staticFile)
+	all := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file, ok := prev[p]
+		if !ok || file.size != info.Size() || !file.modTime.Equal(info.ModTime()) {
+			sum, err := hashStaticFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			file = staticFile{hash: sum, size: info.Size(), modTime: info.ModTime()}
+		}
+		hashes[p] = file
+		// WalkDir visits in lexical order, so the version is stable
+		fmt.Fprintf(all, "%s %s\n", p, file.hash)
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to scan static files: %w", err)
+	}
+	return hex.EncodeToString(all.Sum(nil))[:12], hashes, nil
+}
+
+// hashStaticFile returns the shortened content hash of the file at p.
+func hashStaticFile(fsys fs.FS, p string) (string, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func Test_StaticHandler_Embedded(t *testing.T) {
+	s, err := NewStaticHandler(StaticConfig{FS: fstest.MapFS{
+		"app.js":       {Data: []byte("console.log(1)")},
+		"css/site.css": {Data: []byte("body{}")},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := NewRouter()
+	r.Static("/assets", s)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	rec := get("/assets/css/site.css")
+	if rec.Code != http.StatusOK || rec.Body.String() != "body{}" {
+		t.Fatalf("status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != "no-cache" || rec.Header().Get("ETag") == "" || rec.Header().Get(StaticVersionHeaderName) != s.Version() {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+
+	// a versioned URL is cached for good; the ETag revalidates the rest
+	rec = get(s.URL("/assets/app.js"))
+	if rec.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Fatalf("versioned Cache-Control = %q", rec.Header().Get("Cache-Control"))
+	}
+	req := httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("revalidation = %d, want 304", rec.Code)
+	}
+}
+
+func Test_StaticHandler_DevReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app.js", "v1")
+	changed := make(chan string, 4)
+	s, err := NewStaticHandler(StaticConfig{Dev: true, Dir: dir, PollInterval: 10 * time.Millisecond, OnChange: func(v string) { changed <- v }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	before := s.Version()
+
+	write("app.js", "v2")
+	write("new.js", "added")
+	select {
+	case v := <-changed:
+		if v == before {
+			t.Fatal("version did not change")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not detected")
+	}
+
+	for path, want := range map[string]string{"/app.js": "v2", "/new.js": "added"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Body.String() != want || rec.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s = %q with Cache-Control %q", path, rec.Body.String(), rec.Header().Get("Cache-Control"))
+		}
+	}
+}
+
+func Test_scanStatic_SkipsUnchangedFiles(t *testing.T) {
+	mod := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{"app.js": {Data: []byte("v1"), ModTime: mod}}
+	version, hashes, err := scanStatic(fsys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same size and mtime: the stale hash is kept, proving the file was not read
+	fsys["app.js"] = &fstest.MapFile{Data: []byte("v2"), ModTime: mod}
+	if again, _, _ := scanStatic(fsys, hashes); again != version {
+		t.Fatal("unchanged stat re-hashed the file")
+	}
+
+	fsys["app.js"] = &fstest.MapFile{Data: []byte("v2"), ModTime: mod.Add(time.Second)}
+	if changed, _, _ := scanStatic(fsys, hashes); changed == version {
+		t.Fatal("newer mtime did not re-hash the file")
+	}
+}
+
+func Test_StaticHandler_Config(t *testing.T) {
+	if _, err := NewStaticHandler(StaticConfig{}); err == nil {
+		t.Error("no FS accepted")
+	}
+	if _, err := NewStaticHandler(StaticConfig{Dev: true, Dir: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("missing Dir accepted")
+	}
+}
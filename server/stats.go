@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time snapshot of a Server, cheap enough to embed in a
+// health payload or poll from a dashboard without a metrics stack.
+type Stats struct {
+	StartedAt         time.Time     `json:"started_at"`
+	Uptime            time.Duration `json:"uptime"`
+	TotalRequests     int64         `json:"total_requests"`
+	InFlightRequests  int64         `json:"in_flight_requests"`
+	ActiveConnections int64         `json:"active_connections"`
+	// HijackedConnections counts connections taken over for WebSockets or
+	// other upgrades and not closed yet; ActiveConnections excludes them.
+	HijackedConnections int64 `json:"hijacked_connections"`
+	// StatusCounts maps each response status seen to its count.
+	StatusCounts map[int]int64 `json:"status_counts"`
+	Goroutines   int           `json:"goroutines"`
+}
+
+// serverStats holds the counters behind Server.Stats. Status codes outside
+// 100-599 are counted under 0.
+type serverStats struct {
+	startedAt   atomic.Int64 // unix nanos; 0 until Start
+	requests    atomic.Int64
+	inFlight    atomic.Int64
+	connections atomic.Int64
+	statuses    [600]atomic.Int64
+}
+
+// Stats returns a snapshot of the server's counters. Requests and connections
+// are counted from Start; before that the snapshot only carries Goroutines.
+func (s *Server) Stats() Stats {
+	st := Stats{
+		TotalRequests:       s.stats.requests.Load(),
+		InFlightRequests:    s.stats.inFlight.Load(),
+		ActiveConnections:   s.stats.connections.Load(),
+		HijackedConnections: s.hijacks.count(),
+		StatusCounts:        make(map[int]int64),
+		Goroutines:          runtime.NumGoroutine(),
+	}
+	if started := s.stats.startedAt.Load(); started != 0 {
+		st.StartedAt = time.Unix(0, started)
+		st.Uptime = time.Since(st.StartedAt)
+	}
+	for code := range s.stats.statuses {
+		if n := s.stats.statuses[code].Load(); n > 0 {
+			st.StatusCounts[code] = n
+		}
+	}
+	return st
+}
+
+// instrument wraps srv's handler and ConnState hook with the counters. It
+// runs at Start, after every Option and HTTP() mutation, and chains any
+// ConnState hook already set.
+func (st *serverStats) instrument(srv *http.Server) {
+	st.startedAt.Store(time.Now().UnixNano())
+
+	next := srv.Handler
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st.requests.Add(1)
+		st.inFlight.Add(1)
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			st.inFlight.Add(-1)
+			code := rw.status
+			if code < 100 || code >= len(st.statuses) {
+				code = 0
+			}
+			st.statuses[code].Add(1)
+		}()
+		next.ServeHTTP(rw, r)
+	})
+
+	prev := srv.ConnState
+	srv.ConnState = func(c net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			st.connections.Add(1)
+		case http.StateClosed, http.StateHijacked:
+			st.connections.Add(-1)
+		}
+		if prev != nil {
+			prev(c, state)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Server_Stats(t *testing.T) {
+	port := freePort(t)
+	r := NewRouter()
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	})
+	s := NewServer(ServerConfig{Host: "127.0.0.1", Port: port}, r, nopLogger{})
+
+	if st := s.Stats(); !st.StartedAt.IsZero() || st.TotalRequests != 0 || st.Goroutines == 0 {
+		t.Fatalf("stats before Start = %+v", st)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start() }()
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	waitReachable(t, base+"/ping")
+
+	resp, err := http.Get(base + "/missing")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	st := s.Stats()
+	if st.StartedAt.IsZero() || st.Uptime <= 0 {
+		t.Fatalf("uptime not tracked: %+v", st)
+	}
+	if st.StatusCounts[http.StatusNotFound] != 1 || st.StatusCounts[http.StatusOK] < 1 {
+		t.Fatalf("status counts = %v", st.StatusCounts)
+	}
+	if st.TotalRequests != st.StatusCounts[http.StatusOK]+1 || st.InFlightRequests != 0 {
+		t.Fatalf("requests = %d in flight = %d", st.TotalRequests, st.InFlightRequests)
+	}
+	// the client keeps its connection alive, so at least one is still open
+	if st.ActiveConnections < 1 {
+		t.Fatalf("active connections = %d", st.ActiveConnections)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+}
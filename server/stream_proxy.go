@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamProxyConfig tunes StreamProxy.
+type StreamProxyConfig struct {
+	// ContentType is sent downstream; defaults to text/event-stream.
+	ContentType string
+	// Status is the downstream status code; defaults to 200.
+	Status int
+	// BufferSize is the largest chunk read from upstream before it is written
+	// and flushed downstream; defaults to 32 KiB.
+	BufferSize int
+	// Header holds the upstream response headers, passed downstream through
+	// HeaderFilter. An upstream Content-Type is kept when ContentType is
+	// unset.
+	Header http.Header
+	// HeaderFilter picks, renames, and rewrites the Header passed downstream.
+	HeaderFilter ResponseHeaderFilter
+}
+
+// StreamProxy forwards an upstream streaming body (for example the Response of
+// a toaweme/http client call made with Request.Stream, which is an
+// io.ReadCloser) to w, flushing after every chunk - the "LLM gateway" pattern.
+//
+// Reads and writes alternate, so a slow downstream client applies
+// backpressure to the upstream instead of buffering in memory. Issue the
+// upstream call with r.Context() so a downstream disconnect cancels it; an
+// upstream that stalls is closed here as soon as r's context ends. upstream is
+// always closed on return. Returns the bytes forwarded and nil when upstream
+// ended cleanly or the downstream client went away.
+func StreamProxy(w http.ResponseWriter, r *http.Request, upstream io.ReadCloser, cfg StreamProxyConfig) (int64, error) {
+	forwarded := cfg.HeaderFilter.Apply(cfg.Header)
+	if cfg.ContentType == "" {
+		cfg.ContentType = forwarded.Get("Content-Type")
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = ContentTypeEventStream
+	}
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusOK
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 32 << 10
+	}
+
+	ctx := r.Context()
+	// closing the upstream is the only way to interrupt a blocked Read, so tie
+	// it to the downstream request's lifetime.
+	stop := context.AfterFunc(ctx, func() { _ = upstream.Close() })
+	defer stop()
+	defer upstream.Close()
+
+	h := w.Header()
+	for name, values := range forwarded {
+		h[name] = values
+	}
+	h.Set("Content-Type", cfg.ContentType)
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(cfg.Status)
+
+	rc := http.NewResponseController(w)
+	_ = rc.Flush()
+
+	var (
+		written int64
+		buf     = make([]byte, cfg.BufferSize)
+	)
+	for {
+		n, readErr := upstream.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				// downstream went away; nothing left to forward to
+				return written, nil
+			}
+			written += int64(n)
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return written, nil
+			}
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) || ctx.Err() != nil {
+				return written, nil
+			}
+			return written, fmt.Errorf("failed to read upstream stream: %w", readErr)
+		}
+	}
+}
+
+// responseSkipHeaders are never passed downstream: connection-scoped, or
+// describing the body as the upstream framed it. Mirrored from
+// github.com/toaweme/http.
+var responseSkipHeaders = map[string]bool{
+	"Connection":         true,
+	"Proxy-Connection":   true,
+	"Keep-Alive":         true,
+	"Proxy-Authenticate": true,
+	"Te":                 true,
+	"Trailer":            true,
+	"Transfer-Encoding":  true,
+	"Upgrade":            true,
+	"Content-Length":     true,
+	"Content-Encoding":   true,
+}
+
+// DefaultInternalResponseHeaders reveal the upstream's software or topology,
+// so ResponseHeaderFilter strips them unless KeepInternal is set.
+var DefaultInternalResponseHeaders = []string{
+	"Server",
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-Runtime",
+	ServedByHeaderName,
+	ResponseTimeHeaderName,
+	"Server-Timing",
+}
+
+// HeaderRewrite replaces Old with New in the values of one header, e.g. to
+// point a Location at the gateway instead of the upstream. Func, when set,
+// rewrites each value instead.
+type HeaderRewrite struct {
+	Name string
+	Old  string
+	New  string
+	Func func(value string) string
+}
+
+// ResponseHeaderFilter picks the upstream response headers a gateway passes
+// downstream, mirrored from github.com/toaweme/http. Hop-by-hop headers,
+// those named by Connection, and Content-Length and Content-Encoding are
+// always dropped. Names in Allow and Deny are case-insensitive; a trailing
+// "*" matches a prefix. The zero value forwards every end-to-end header but
+// DefaultInternalResponseHeaders.
+type ResponseHeaderFilter struct {
+	// Allow, when set, forwards only the headers it matches.
+	Allow []string
+	// Deny drops the headers it matches, even allowed ones.
+	Deny []string
+	// KeepInternal forwards DefaultInternalResponseHeaders too.
+	KeepInternal bool
+	// Rename maps upstream header names to the names sent downstream; Allow
+	// and Deny match the upstream names.
+	Rename map[string]string
+	// Rewrite edits the values of forwarded headers, by downstream name.
+	Rewrite []HeaderRewrite
+}
+
+// Apply returns the headers of upstream f forwards, renamed and rewritten.
+func (f ResponseHeaderFilter) Apply(upstream http.Header) http.Header {
+	hop := make(map[string]bool)
+	for _, v := range upstream.Values("Connection") {
+		for name := range strings.SplitSeq(v, ",") {
+			hop[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	out := make(http.Header, len(upstream))
+	for name, values := range upstream {
+		name = http.CanonicalHeaderKey(name)
+		if responseSkipHeaders[name] || hop[name] || !f.forwards(name) {
+			continue
+		}
+		to := name
+		for from, renamed := range f.Rename {
+			if strings.EqualFold(from, name) {
+				to = http.CanonicalHeaderKey(renamed)
+				break
+			}
+		}
+		for _, v := range values {
+			out.Add(to, f.rewrite(to, v))
+		}
+	}
+	return out
+}
+
+func (f ResponseHeaderFilter) forwards(name string) bool {
+	if len(f.Allow) > 0 && !matchHeaderName(f.Allow, name) {
+		return false
+	}
+	if matchHeaderName(f.Deny, name) {
+		return false
+	}
+	return f.KeepInternal || !matchHeaderName(DefaultInternalResponseHeaders, name)
+}
+
+func (f ResponseHeaderFilter) rewrite(name, value string) string {
+	for _, rw := range f.Rewrite {
+		if !strings.EqualFold(rw.Name, name) {
+			continue
+		}
+		if rw.Func != nil {
+			value = rw.Func(value)
+		} else if rw.Old != "" {
+			value = strings.ReplaceAll(value, rw.Old, rw.New)
+		}
+	}
+	return value
+}
+
+// matchHeaderName reports whether name matches one of patterns: a header
+// name, or a prefix ending in "*".
+func matchHeaderName(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
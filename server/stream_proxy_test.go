@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flushRecorder counts flushes on top of httptest.ResponseRecorder.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushRecorder) Flush() { f.flushes++ }
+
+func Test_StreamProxy_ForwardsAndFlushes(t *testing.T) {
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	upstream := io.NopCloser(strings.NewReader("data: a\n\ndata: b\n\n"))
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+
+	n, err := StreamProxy(rec, r, upstream, StreamProxyConfig{BufferSize: 4})
+	if err != nil {
+		t.Fatalf("StreamProxy: %v", err)
+	}
+	if n != 18 || rec.Body.String() != "data: a\n\ndata: b\n\n" {
+		t.Fatalf("forwarded %d bytes: %q", n, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type: got %q", rec.Header().Get("Content-Type"))
+	}
+	if rec.flushes < 5 {
+		t.Fatalf("flushes: got %d, want one per chunk", rec.flushes)
+	}
+}
+
+// blockingBody blocks reads until it is closed.
+type blockingBody struct {
+	closed chan struct{}
+}
+
+func (b *blockingBody) Read([]byte) (int, error) {
+	<-b.closed
+	return 0, errors.New("use of closed body")
+}
+
+func (b *blockingBody) Close() error {
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+	return nil
+}
+
+func Test_StreamProxy_DownstreamCancelClosesUpstream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody).WithContext(ctx)
+	upstream := &blockingBody{closed: make(chan struct{})}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := StreamProxy(httptest.NewRecorder(), r, upstream, StreamProxyConfig{})
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("StreamProxy after cancel: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("StreamProxy did not return after downstream cancel")
+	}
+}
+
+func Test_StreamProxy_UpstreamError(t *testing.T) {
+	upstream := io.NopCloser(io.MultiReader(strings.NewReader("partial"), errReader{}))
+	_, err := StreamProxy(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody), upstream, StreamProxyConfig{})
+	if err == nil {
+		t.Fatal("expected upstream read error")
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func Test_StreamProxy_Headers(t *testing.T) {
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	upstream := io.NopCloser(strings.NewReader(`{"token":"a"}` + "\n"))
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+
+	_, err := StreamProxy(rec, r, upstream, StreamProxyConfig{
+		Header: http.Header{
+			"Content-Type":      {"application/x-ndjson"},
+			"Content-Length":    {"14"},
+			"Transfer-Encoding": {"chunked"},
+			"Server":            {"llm-7"},
+			"X-Model":           {"small"},
+			"X-Internal-Shard":  {"3"},
+			"Openai-Request-Id": {"r1"},
+		},
+		HeaderFilter: ResponseHeaderFilter{
+			Deny:   []string{"X-Internal-*"},
+			Rename: map[string]string{"OpenAI-Request-ID": "X-Upstream-Request-ID"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := rec.Header()
+	if h.Get("Content-Type") != "application/x-ndjson" || h.Get("X-Model") != "small" || h.Get("X-Upstream-Request-Id") != "r1" {
+		t.Fatalf("forwarded headers = %v", h)
+	}
+	for _, name := range []string{"Content-Length", "Transfer-Encoding", "Server", "X-Internal-Shard", "Openai-Request-Id"} {
+		if h.Get(name) != "" {
+			t.Errorf("%s forwarded", name)
+		}
+	}
+}
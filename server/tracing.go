@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// W3C trace context headers, mirrored from github.com/toaweme/http.
+const (
+	TraceParentHeaderName = "traceparent"
+	TraceStateHeaderName  = "tracestate"
+	BaggageHeaderName     = "baggage"
+)
+
+// TraceContext is the server span of the current request, with ids in
+// lowercase hex as they appear on the wire.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	// ParentID is the caller's span id; empty when the trace started here.
+	ParentID string
+	Flags    string
+	State    string
+	Baggage  string
+}
+
+// TraceParent renders the server span as a traceparent header value, so
+// outbound calls become its children.
+func (tc TraceContext) TraceParent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+// ServerSpan is reported once per request by Tracing.
+type ServerSpan struct {
+	Trace      TraceContext
+	Method     string
+	Route      string
+	Path       string
+	StatusCode int
+	Duration   time.Duration
+}
+
+// TracingConfig configures the Tracing middleware.
+type TracingConfig struct {
+	// OnSpan receives each finished server span; use it to export spans or
+	// record status/latency metrics. Optional.
+	OnSpan func(ServerSpan)
+	// Logger, when set, writes each finished span at Debug.
+	Logger Logger
+}
+
+// Tracing extracts the W3C trace context of inbound requests (starting a new
+// trace when there is none or it is malformed), opens a server span for the
+// request and stores it in the context. TraceHeaders hands it to the client,
+// so upstream calls continue the trace:
+//
+//	client := http.NewClient(cfg, http.WithContextHeaders(server.TraceHeaders))
+func Tracing(cfg TracingConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			tc := extractTrace(r.Header)
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r.WithContext(ContextWithTrace(r.Context(), tc)))
+
+			span := ServerSpan{
+				Trace:      tc,
+				Method:     r.Method,
+				Route:      RoutePattern(r),
+				Path:       r.URL.Path,
+				StatusCode: rw.status,
+				Duration:   time.Since(start),
+			}
+			if cfg.Logger != nil {
+				cfg.Logger.Debug("http-server", "type", "span", "trace-id", tc.TraceID, "span-id", tc.SpanID,
+					"parent-id", tc.ParentID, "method", span.Method, "route", span.Route, "status", span.StatusCode, "duration", span.Duration)
+			}
+			if cfg.OnSpan != nil {
+				cfg.OnSpan(span)
+			}
+		})
+	}
+}
+
+// ContextWithTrace returns a copy of ctx carrying tc.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, ctxTrace, tc)
+}
+
+// TraceFromContext returns the span stored by Tracing. ok is false if unset.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(ctxTrace).(TraceContext)
+	return tc, ok
+}
+
+// TraceHeaders returns the trace context headers for outbound calls made
+// under ctx: traceparent naming the server span as parent, plus the inbound
+// tracestate and baggage. Its shape matches the client's WithContextHeaders.
+func TraceHeaders(ctx context.Context) map[string]string {
+	tc, ok := TraceFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	out := map[string]string{TraceParentHeaderName: tc.TraceParent()}
+	if tc.State != "" {
+		out[TraceStateHeaderName] = tc.State
+	}
+	if tc.Baggage != "" {
+		out[BaggageHeaderName] = tc.Baggage
+	}
+	return out
+}
+
+// extractTrace continues the inbound trace with a fresh span id, or starts a
+// new sampled trace.
+func extractTrace(h http.Header) TraceContext {
+	tc := TraceContext{SpanID: randomHex(8)}
+	if traceID, parentID, flags, ok := parseTraceParent(h.Get(TraceParentHeaderName)); ok {
+		tc.TraceID, tc.ParentID, tc.Flags = traceID, parentID, flags
+		tc.State = h.Get(TraceStateHeaderName)
+	} else {
+		tc.TraceID, tc.Flags = randomHex(16), "01"
+	}
+	tc.Baggage = h.Get(BaggageHeaderName)
+	return tc
+}
+
+func parseTraceParent(s string) (traceID, parentID, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return "", "", "", false
+	}
+	traceID, parentID, flags = parts[1], parts[2], parts[3]
+	if len(traceID) != 32 || len(parentID) != 16 || len(flags) != 2 ||
+		!isLowerHex(traceID+parentID+flags) ||
+		traceID == strings.Repeat("0", 32) || parentID == strings.Repeat("0", 16) {
+		return "", "", "", false
+	}
+	return traceID, parentID, flags, true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
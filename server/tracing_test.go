@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Tracing_ContinuesInboundTrace(t *testing.T) {
+	var (
+		spans    []ServerSpan
+		inner    TraceContext
+		outbound map[string]string
+	)
+	r := NewRouter()
+	r.Use(Tracing(TracingConfig{OnSpan: func(s ServerSpan) { spans = append(spans, s) }}))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		inner, _ = TraceFromContext(r.Context())
+		outbound = TraceHeaders(r.Context())
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/1", http.NoBody)
+	req.Header.Set(TraceParentHeaderName, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TraceStateHeaderName, "vendor=1")
+	req.Header.Set(BaggageHeaderName, "client.platform=cli")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if inner.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || inner.ParentID != "00f067aa0ba902b7" || len(inner.SpanID) != 16 {
+		t.Fatalf("trace = %+v", inner)
+	}
+	if outbound[TraceParentHeaderName] != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+inner.SpanID+"-01" ||
+		outbound[TraceStateHeaderName] != "vendor=1" || outbound[BaggageHeaderName] != "client.platform=cli" {
+		t.Fatalf("outbound = %v", outbound)
+	}
+	if len(spans) != 1 || spans[0].Route != "/items/{id}" || spans[0].StatusCode != http.StatusCreated {
+		t.Fatalf("spans = %+v", spans)
+	}
+}
+
+func Test_Tracing_StartsNewTrace(t *testing.T) {
+	for _, header := range []string{"", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "garbage"} {
+		var tc TraceContext
+		h := Tracing(TracingConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc, _ = TraceFromContext(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		if header != "" {
+			req.Header.Set(TraceParentHeaderName, header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if len(tc.TraceID) != 32 || tc.ParentID != "" || tc.Flags != "01" {
+			t.Errorf("%q: trace = %+v", header, tc)
+		}
+	}
+}
+
+func Test_TraceHeaders_WithoutTracing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	if got := TraceHeaders(req.Context()); got != nil {
+		t.Fatalf("got %v", got)
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Middleware is the shape of every middleware in this package: it wraps the
+// next handler. Router.Use, Group, and With accept it directly.
+type Middleware func(http.Handler) http.Handler
+
+// BufferedResponse is a fully captured response handed to a
+// TransformResponse function. Mutate any field; what is left is sent.
+type BufferedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Request is the request being answered, for context-dependent rewrites.
+	Request *http.Request
+}
+
+// TransformResponse returns a middleware that buffers the whole response,
+// passes it to fn, and writes what fn leaves behind, fixing up
+// Content-Length. If fn returns an error the client gets a 500 instead.
+//
+// Buffering defeats streaming, so do not mount it in front of SSE or other
+// flushed responses; the buffering writer deliberately does not implement
+// http.Flusher so sse.NewWriter fails loudly there.
+func TransformResponse(fn func(*BufferedResponse) error) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bw := &bufferingWriter{header: make(http.Header), status: http.StatusOK}
+			next.ServeHTTP(bw, r)
+
+			resp := &BufferedResponse{Status: bw.status, Header: bw.header, Body: bw.buf.Bytes(), Request: r}
+			if err := fn(resp); err != nil {
+				WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to transform response: %w", err))
+				return
+			}
+
+			dst := w.Header()
+			for k, v := range resp.Header {
+				dst[k] = v
+			}
+			if dst.Get("Content-Length") != "" {
+				dst.Set("Content-Length", strconv.Itoa(len(resp.Body)))
+			}
+			w.WriteHeader(resp.Status)
+			_, _ = w.Write(resp.Body)
+		})
+	}
+}
+
+// InjectJSONFields returns a middleware that adds fields to every JSON object
+// response body (e.g. an api_version or request id), overwriting keys the
+// handler already set. Non-JSON and non-object bodies pass through untouched.
+func InjectJSONFields(fields func(r *http.Request) map[string]any) Middleware {
+	return TransformResponse(func(resp *BufferedResponse) error {
+		mediaType, _ := ParseMediaType(resp.Header.Get("Content-Type"))
+		if mediaType != ContentTypeJSON || len(bytes.TrimSpace(resp.Body)) == 0 {
+			return nil
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(resp.Body, &obj); err != nil {
+			return nil //nolint:nilerr // not a JSON object; leave the body as the handler wrote it
+		}
+		for k, v := range fields(resp.Request) {
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to marshal injected field %q: %w", k, err)
+			}
+			obj[k] = raw
+		}
+		body, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal response body: %w", err)
+		}
+		resp.Body = append(body, '\n')
+		return nil
+	})
+}
+
+// OnResponseHeaders returns a middleware that calls fn with the status and
+// headers just before they are sent, without buffering the body, so it is safe
+// on streaming routes.
+func OnResponseHeaders(fn func(status int, h http.Header)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&headerHookWriter{responseRecorder: responseRecorder{ResponseWriter: w, status: http.StatusOK}, hook: fn}, r)
+		})
+	}
+}
+
+// StripHeaders returns a middleware that removes the named response headers
+// (e.g. X-Internal-Debug set by inner layers) before the response is sent.
+func StripHeaders(names ...string) Middleware {
+	return OnResponseHeaders(func(_ int, h http.Header) {
+		for _, name := range names {
+			h.Del(name)
+		}
+	})
+}
+
+type bufferingWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+}
+
+func (b *bufferingWriter) Header() http.Header { return b.header }
+
+func (b *bufferingWriter) WriteHeader(code int) {
+	if b.wroteHeader {
+		return
+	}
+	b.status = code
+	b.wroteHeader = true
+}
+
+func (b *bufferingWriter) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.buf.Write(p)
+}
+
+// headerHookWriter runs hook once, right before the header is written: on
+// the first WriteHeader, Write or Flush, or before a Hijack.
+type headerHookWriter struct {
+	responseRecorder
+	hook func(status int, h http.Header)
+}
+
+func (h *headerHookWriter) WriteHeader(code int) {
+	if !h.wroteHeader {
+		h.hook(code, h.Header())
+	}
+	h.responseRecorder.WriteHeader(code)
+}
+
+func (h *headerHookWriter) Write(b []byte) (int, error) {
+	if !h.wroteHeader {
+		h.WriteHeader(http.StatusOK)
+	}
+	return h.responseRecorder.Write(b)
+}
+
+func (h *headerHookWriter) Flush() {
+	if !h.wroteHeader {
+		h.WriteHeader(http.StatusOK)
+	}
+	h.responseRecorder.Flush()
+}
+
+// Hijack runs hook with 101 Switching Protocols, since the handler takes
+// over the connection and writes its own header.
+func (h *headerHookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !h.wroteHeader {
+		h.wroteHeader = true
+		h.hook(http.StatusSwitchingProtocols, h.Header())
+	}
+	return h.responseRecorder.Hijack()
+}
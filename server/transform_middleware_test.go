@@ -0,0 +1,124 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_TransformResponse_Rewrites(t *testing.T) {
+	mw := TransformResponse(func(resp *BufferedResponse) error {
+		resp.Status = http.StatusAccepted
+		resp.Header.Set("X-Rewritten", "yes")
+		resp.Body = []byte(strings.ToUpper(string(resp.Body)))
+		return nil
+	})
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("hello"))
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "HELLO" || rec.Header().Get("X-Rewritten") != "yes" {
+		t.Fatalf("got %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func Test_TransformResponse_ErrorIs500(t *testing.T) {
+	mw := TransformResponse(func(*BufferedResponse) error { return errors.New("boom") })
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d want 500", rec.Code)
+	}
+}
+
+func Test_InjectJSONFields(t *testing.T) {
+	mw := InjectJSONFields(func(*http.Request) map[string]any {
+		return map[string]any{"api_version": "v2"}
+	})
+	tests := []struct {
+		name string
+		h    http.HandlerFunc
+		want string
+	}{
+		{name: "object", h: func(w http.ResponseWriter, _ *http.Request) {
+			WriteJSON(w, http.StatusOK, map[string]string{"id": "1"})
+		}, want: `{"api_version":"v2","id":"1"}` + "\n"},
+		{name: "array untouched", h: func(w http.ResponseWriter, _ *http.Request) {
+			WriteJSON(w, http.StatusOK, []int{1})
+		}, want: "[1]\n"},
+		{name: "plain text untouched", h: func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("hi"))
+		}, want: "hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mw(tt.h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			if rec.Body.String() != tt.want {
+				t.Fatalf("got %q want %q", rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_StripHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		h    http.HandlerFunc
+	}{
+		{name: "explicit WriteHeader", h: func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-Internal", "secret")
+			w.WriteHeader(http.StatusOK)
+		}},
+		{name: "implicit via Write", h: func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-Internal", "secret")
+			_, _ = w.Write([]byte("x"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			StripHeaders("X-Internal")(tt.h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			if rec.Header().Get("X-Internal") != "" {
+				t.Fatal("X-Internal was not stripped")
+			}
+		})
+	}
+}
+
+func Test_OnResponseHeaders_KeepsFlusher(t *testing.T) {
+	var flushable bool
+	OnResponseHeaders(func(int, http.Header) {})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, flushable = w.(http.Flusher)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if !flushable {
+		t.Fatal("header hook writer must stay an http.Flusher for streaming routes")
+	}
+}
+
+func Test_OnResponseHeaders_FlushBeforeWrite(t *testing.T) {
+	calls := 0
+	rec := httptest.NewRecorder()
+	OnResponseHeaders(func(status int, h http.Header) {
+		calls++
+		h.Set("X-Hooked", strconv.Itoa(status))
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("event"))
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	if calls != 1 {
+		t.Fatalf("hook ran %d times, want 1", calls)
+	}
+	if got := rec.Result().Header.Get("X-Hooked"); got != "200" {
+		t.Fatalf("X-Hooked = %q, want 200 set before the flush", got)
+	}
+}
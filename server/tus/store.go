@@ -0,0 +1,140 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store for an unknown upload id.
+var ErrNotFound = errors.New("tus: upload not found")
+
+// Upload is the state of one resumable upload.
+type Upload struct {
+	ID string
+	// Length is the total size declared at creation.
+	Length int64
+	// Offset is how many bytes have been received so far.
+	Offset   int64
+	Metadata map[string]string
+	// ExpiresAt is zero when uploads never expire.
+	ExpiresAt time.Time
+}
+
+// Done reports whether every declared byte has arrived.
+func (u Upload) Done() bool {
+	return u.Offset >= u.Length
+}
+
+// Store persists uploads. Implementations must be safe for concurrent use;
+// the handler serializes PATCHes per upload only through the offset check, so
+// Append must reject an offset that no longer matches with ErrOffsetMismatch.
+type Store interface {
+	Create(ctx context.Context, upload Upload) error
+	Info(ctx context.Context, id string) (Upload, error)
+	// Append writes r at offset and returns the bytes written; a partial write
+	// must still advance the offset by what was written so clients can resume.
+	Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Touch moves the upload's ExpiresAt, after a PATCH keeps it alive.
+	Touch(ctx context.Context, id string, expiresAt time.Time) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ErrOffsetMismatch is returned by Store.Append when offset is not the
+// upload's current offset.
+var ErrOffsetMismatch = errors.New("tus: offset mismatch")
+
+// MemoryStore is an in-memory Store for tests and development. Completed
+// uploads are read back with Bytes.
+type MemoryStore struct {
+	mu      sync.Mutex
+	uploads map[string]*memoryUpload
+}
+
+type memoryUpload struct {
+	info Upload
+	data []byte
+}
+
+// NewMemoryStore builds an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{uploads: make(map[string]*memoryUpload)}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(_ context.Context, upload Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[upload.ID]; ok {
+		return fmt.Errorf("tus: upload %q already exists", upload.ID)
+	}
+	s.uploads[upload.ID] = &memoryUpload{info: upload}
+	return nil
+}
+
+// Info implements Store.
+func (s *MemoryStore) Info(_ context.Context, id string) (Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return Upload{}, ErrNotFound
+	}
+	return u.info, nil
+}
+
+// Append implements Store. The body is read before the lock is taken so a
+// slow client does not block other uploads.
+func (s *MemoryStore) Append(_ context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	chunk, readErr := io.ReadAll(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if u.info.Offset != offset {
+		return 0, ErrOffsetMismatch
+	}
+	u.data = append(u.data, chunk...)
+	u.info.Offset += int64(len(chunk))
+	return int64(len(chunk)), readErr
+}
+
+// Touch implements Store.
+func (s *MemoryStore) Touch(_ context.Context, id string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return ErrNotFound
+	}
+	u.info.ExpiresAt = expiresAt
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.uploads, id)
+	return nil
+}
+
+// Bytes returns a copy of the data received so far for id.
+func (s *MemoryStore) Bytes(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), u.data...), nil
+}
@@ -0,0 +1,32 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_MemoryStore_Append(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.Create(ctx, Upload{ID: "a", Length: 4}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := s.Create(ctx, Upload{ID: "a"}); err == nil {
+		t.Fatal("duplicate Create should fail")
+	}
+	if _, err := s.Append(ctx, "a", 0, strings.NewReader("ab")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if _, err := s.Append(ctx, "a", 0, strings.NewReader("cd")); !errors.Is(err, ErrOffsetMismatch) {
+		t.Fatalf("stale Append err = %v, want ErrOffsetMismatch", err)
+	}
+	if _, err := s.Append(ctx, "missing", 0, strings.NewReader("x")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Append to missing err = %v", err)
+	}
+	info, _ := s.Info(ctx, "a")
+	if info.Offset != 2 || info.Done() {
+		t.Fatalf("info = %+v", info)
+	}
+}
@@ -0,0 +1,372 @@
+// Package tus implements the server side of the tus resumable upload
+// protocol (https://tus.io/protocols/resumable-upload), version 1.0.0.
+//
+// Layout:
+//
+//   - Handler: serves the core protocol (HEAD offset, PATCH append, OPTIONS
+//     discovery) plus the creation, expiration, checksum, and termination
+//     extensions. Mount it under its BasePath with a catch-all route.
+//   - Store: pluggable persistence for upload state and bytes; MemoryStore is
+//     the in-process implementation.
+package tus
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // offered because tus clients ask for it, not for security
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // sha1 is the checksum algorithm the tus spec requires
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the protocol version this package speaks.
+const Version = "1.0.0"
+
+// Protocol headers.
+const (
+	ResumableHeaderName          = "Tus-Resumable"
+	VersionHeaderName            = "Tus-Version"
+	ExtensionHeaderName          = "Tus-Extension"
+	MaxSizeHeaderName            = "Tus-Max-Size"
+	ChecksumAlgorithmHeaderName  = "Tus-Checksum-Algorithm"
+	UploadOffsetHeaderName       = "Upload-Offset"
+	UploadLengthHeaderName       = "Upload-Length"
+	UploadMetadataHeaderName     = "Upload-Metadata"
+	UploadExpiresHeaderName      = "Upload-Expires"
+	UploadChecksumHeaderName     = "Upload-Checksum"
+	offsetOctetStreamContentType = "application/offset+octet-stream"
+)
+
+// StatusChecksumMismatch is the non-standard status the checksum extension
+// assigns to a chunk whose checksum does not match.
+const StatusChecksumMismatch = 460
+
+var checksums = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// Config configures a Handler.
+type Config struct {
+	// BasePath is where the handler is mounted, e.g. "/files". Upload URLs are
+	// BasePath + "/" + id.
+	BasePath string
+	Store    Store
+	// MaxSize rejects uploads declaring a larger Upload-Length; 0 means no cap.
+	MaxSize int64
+	// Expiration is how long an unfinished upload may sit idle after its last
+	// PATCH before it is answered with 410 and deleted; 0 disables expiry.
+	Expiration time.Duration
+	// MaxChecksumChunk caps a PATCH carrying Upload-Checksum, since such a
+	// chunk is buffered until verified. Defaults to 64 MiB.
+	MaxChecksumChunk int64
+	// OnComplete, when set, is called after the final byte of an upload is
+	// stored, from the request that completed it.
+	OnComplete func(ctx context.Context, upload Upload)
+}
+
+// Handler serves the tus protocol.
+type Handler struct {
+	cfg Config
+	now func() time.Time
+}
+
+// NewHandler builds a Handler over cfg.Store.
+func NewHandler(cfg Config) *Handler {
+	cfg.BasePath = strings.TrimSuffix(cfg.BasePath, "/")
+	if cfg.MaxChecksumChunk <= 0 {
+		cfg.MaxChecksumChunk = 64 << 20
+	}
+	return &Handler{cfg: cfg, now: time.Now}
+}
+
+// ServeHTTP dispatches on method: POST on the base path creates an upload;
+// HEAD, PATCH, and DELETE address one upload; OPTIONS advertises the server's
+// capabilities.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(ResumableHeaderName, Version)
+
+	if r.Method == http.MethodOptions {
+		h.options(w)
+		return
+	}
+	if r.Header.Get(ResumableHeaderName) != Version {
+		w.Header().Set(VersionHeaderName, Version)
+		http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, h.cfg.BasePath), "/")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		h.create(w, r)
+	case id == "" || strings.Contains(id, "/"):
+		http.NotFound(w, r)
+	case r.Method == http.MethodHead:
+		h.head(w, r, id)
+	case r.Method == http.MethodPatch:
+		h.patch(w, r, id)
+	case r.Method == http.MethodDelete:
+		h.delete(w, r, id)
+	default:
+		w.Header().Set("Allow", "HEAD, PATCH, DELETE, OPTIONS")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) options(w http.ResponseWriter) {
+	extensions := []string{"creation", "checksum", "termination"}
+	if h.cfg.Expiration > 0 {
+		extensions = append(extensions, "expiration")
+	}
+	algorithms := make([]string, 0, len(checksums))
+	for name := range checksums {
+		algorithms = append(algorithms, name)
+	}
+	sort.Strings(algorithms)
+
+	hdr := w.Header()
+	hdr.Set(VersionHeaderName, Version)
+	hdr.Set(ExtensionHeaderName, strings.Join(extensions, ","))
+	hdr.Set(ChecksumAlgorithmHeaderName, strings.Join(algorithms, ","))
+	if h.cfg.MaxSize > 0 {
+		hdr.Set(MaxSizeHeaderName, strconv.FormatInt(h.cfg.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get(UploadLengthHeaderName), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if h.cfg.MaxSize > 0 && length > h.cfg.MaxSize {
+		http.Error(w, "upload exceeds Tus-Max-Size", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := ParseMetadata(r.Header.Get(UploadMetadataHeaderName))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		http.Error(w, "failed to generate upload id", http.StatusInternalServerError)
+		return
+	}
+
+	upload := Upload{ID: id, Length: length, Metadata: metadata, ExpiresAt: h.expiry()}
+	if err := h.cfg.Store.Create(r.Context(), upload); err != nil {
+		http.Error(w, "failed to create upload", http.StatusInternalServerError)
+		return
+	}
+	// an empty upload is complete on creation, no PATCH will follow
+	if upload.Done() && h.cfg.OnComplete != nil {
+		h.cfg.OnComplete(r.Context(), upload)
+	}
+	h.setExpires(w, upload)
+	w.Header().Set("Location", h.cfg.BasePath+"/"+id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) head(w http.ResponseWriter, r *http.Request, id string) {
+	upload, ok := h.lookup(w, r, id)
+	if !ok {
+		return
+	}
+	hdr := w.Header()
+	hdr.Set("Cache-Control", "no-store")
+	hdr.Set(UploadOffsetHeaderName, strconv.FormatInt(upload.Offset, 10))
+	hdr.Set(UploadLengthHeaderName, strconv.FormatInt(upload.Length, 10))
+	if len(upload.Metadata) > 0 {
+		hdr.Set(UploadMetadataHeaderName, FormatMetadata(upload.Metadata))
+	}
+	h.setExpires(w, upload)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Header.Get("Content-Type") != offsetOctetStreamContentType {
+		http.Error(w, "Content-Type must be "+offsetOctetStreamContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get(UploadOffsetHeaderName), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+	upload, ok := h.lookup(w, r, id)
+	if !ok {
+		return
+	}
+	if offset != upload.Offset {
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+
+	// never accept bytes past the declared length
+	var body io.Reader = io.LimitReader(r.Body, upload.Length-upload.Offset)
+	if checksum := r.Header.Get(UploadChecksumHeaderName); checksum != "" {
+		verified, status, err := h.verifyChecksum(body, checksum)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		body = verified
+	}
+
+	n, err := h.cfg.Store.Append(r.Context(), id, offset, body)
+	upload.Offset += n
+	if errors.Is(err, ErrOffsetMismatch) {
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+	if err != nil && n == 0 {
+		http.Error(w, "failed to store chunk", http.StatusInternalServerError)
+		return
+	}
+
+	if h.cfg.Expiration > 0 && !upload.Done() {
+		// the old expiry stands when it cannot be moved, so advertise that one
+		if expiresAt := h.expiry(); h.cfg.Store.Touch(r.Context(), id, expiresAt) == nil {
+			upload.ExpiresAt = expiresAt
+		}
+		h.setExpires(w, upload)
+	}
+	if upload.Done() && h.cfg.OnComplete != nil {
+		h.cfg.OnComplete(r.Context(), upload)
+	}
+	w.Header().Set(UploadOffsetHeaderName, strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.cfg.Store.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "failed to delete upload", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookup loads an upload, answering 404 for unknown ids and 410 (after
+// deleting it) for expired ones.
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request, id string) (Upload, bool) {
+	upload, err := h.cfg.Store.Info(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return Upload{}, false
+	}
+	if err != nil {
+		http.Error(w, "failed to load upload", http.StatusInternalServerError)
+		return Upload{}, false
+	}
+	if !upload.Done() && !upload.ExpiresAt.IsZero() && h.now().After(upload.ExpiresAt) {
+		_ = h.cfg.Store.Delete(r.Context(), id)
+		http.Error(w, "upload expired", http.StatusGone)
+		return Upload{}, false
+	}
+	return upload, true
+}
+
+// verifyChecksum buffers the chunk and checks it against an
+// "<algorithm> <base64 digest>" Upload-Checksum value.
+func (h *Handler) verifyChecksum(body io.Reader, value string) (io.Reader, int, error) {
+	algorithm, encoded, _ := strings.Cut(value, " ")
+	newHash, ok := checksums[algorithm]
+	if !ok {
+		return nil, http.StatusBadRequest, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+	want, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("invalid Upload-Checksum digest")
+	}
+
+	chunk, err := io.ReadAll(io.LimitReader(body, h.cfg.MaxChecksumChunk+1))
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("failed to read chunk")
+	}
+	if int64(len(chunk)) > h.cfg.MaxChecksumChunk {
+		return nil, http.StatusRequestEntityTooLarge, errors.New("checksummed chunk too large")
+	}
+	sum := newHash()
+	sum.Write(chunk)
+	if !bytes.Equal(sum.Sum(nil), want) {
+		return nil, StatusChecksumMismatch, errors.New("checksum mismatch")
+	}
+	return bytes.NewReader(chunk), 0, nil
+}
+
+func (h *Handler) expiry() time.Time {
+	if h.cfg.Expiration <= 0 {
+		return time.Time{}
+	}
+	return h.now().Add(h.cfg.Expiration)
+}
+
+func (h *Handler) setExpires(w http.ResponseWriter, upload Upload) {
+	if !upload.ExpiresAt.IsZero() {
+		w.Header().Set(UploadExpiresHeaderName, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// ParseMetadata decodes an Upload-Metadata header: comma-separated pairs of
+// a key and an optional base64 value.
+func ParseMetadata(header string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q", key)
+		}
+		out[key] = string(value)
+	}
+	return out, nil
+}
+
+// FormatMetadata encodes metadata as an Upload-Metadata header, keys sorted.
+func FormatMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if metadata[k] == "" {
+			pairs = append(pairs, k)
+			continue
+		}
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(metadata[k])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
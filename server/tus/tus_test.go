@@ -0,0 +1,194 @@
+package tus
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // the checksum the spec requires
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func do(t *testing.T, h http.Handler, method, path string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set(ResumableHeaderName, Version)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func patchHeaders(offset string) map[string]string {
+	return map[string]string{"Content-Type": offsetOctetStreamContentType, UploadOffsetHeaderName: offset}
+}
+
+func Test_Handler_UploadInChunks(t *testing.T) {
+	store := NewMemoryStore()
+	var completed Upload
+	h := NewHandler(Config{BasePath: "/files", Store: store, OnComplete: func(_ context.Context, u Upload) { completed = u }})
+
+	rec := do(t, h, http.MethodPost, "/files", map[string]string{
+		UploadLengthHeaderName:   "11",
+		UploadMetadataHeaderName: FormatMetadata(map[string]string{"filename": "hello.txt"}),
+	}, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d", rec.Code)
+	}
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/files/") {
+		t.Fatalf("Location = %q", location)
+	}
+
+	if rec := do(t, h, http.MethodPatch, location, patchHeaders("0"), "hello "); rec.Code != http.StatusNoContent || rec.Header().Get(UploadOffsetHeaderName) != "6" {
+		t.Fatalf("first patch = %d offset %q", rec.Code, rec.Header().Get(UploadOffsetHeaderName))
+	}
+
+	rec = do(t, h, http.MethodHead, location, nil, "")
+	if rec.Header().Get(UploadOffsetHeaderName) != "6" || rec.Header().Get(UploadLengthHeaderName) != "11" {
+		t.Fatalf("head = %v", rec.Header())
+	}
+	if md, _ := ParseMetadata(rec.Header().Get(UploadMetadataHeaderName)); md["filename"] != "hello.txt" {
+		t.Fatalf("metadata = %v", md)
+	}
+
+	if rec := do(t, h, http.MethodPatch, location, patchHeaders("0"), "again"); rec.Code != http.StatusConflict {
+		t.Fatalf("stale offset status = %d, want 409", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPatch, location, patchHeaders("6"), "world and more"); rec.Header().Get(UploadOffsetHeaderName) != "11" {
+		t.Fatalf("final offset = %q", rec.Header().Get(UploadOffsetHeaderName))
+	}
+
+	data, _ := store.Bytes(strings.TrimPrefix(location, "/files/"))
+	if string(data) != "hello world" {
+		t.Fatalf("data = %q", data)
+	}
+	if completed.Offset != 11 {
+		t.Fatalf("OnComplete not called with the finished upload: %+v", completed)
+	}
+}
+
+func Test_Handler_Checksum(t *testing.T) {
+	h := NewHandler(Config{BasePath: "/files", Store: NewMemoryStore()})
+	location := do(t, h, http.MethodPost, "/files", map[string]string{UploadLengthHeaderName: "5"}, "").Header().Get("Location")
+
+	sum := sha1.Sum([]byte("hello")) //nolint:gosec // the checksum the spec requires
+	good := "sha1 " + base64.StdEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		checksum string
+		want     int
+	}{
+		{name: "unknown algorithm", checksum: "crc32 AAAA", want: http.StatusBadRequest},
+		{name: "mismatch", checksum: "sha1 " + base64.StdEncoding.EncodeToString(make([]byte, 20)), want: StatusChecksumMismatch},
+		{name: "match", checksum: good, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := patchHeaders("0")
+			headers[UploadChecksumHeaderName] = tt.checksum
+			if rec := do(t, h, http.MethodPatch, location, headers, "hello"); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Handler_Expiration(t *testing.T) {
+	store := NewMemoryStore()
+	h := NewHandler(Config{BasePath: "/files", Store: store, Expiration: time.Hour})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	rec := do(t, h, http.MethodPost, "/files", map[string]string{UploadLengthHeaderName: "5"}, "")
+	if rec.Header().Get(UploadExpiresHeaderName) != "Mon, 01 Jan 2024 01:00:00 GMT" {
+		t.Fatalf("Upload-Expires = %q", rec.Header().Get(UploadExpiresHeaderName))
+	}
+	location := rec.Header().Get("Location")
+
+	now = now.Add(2 * time.Hour)
+	if rec := do(t, h, http.MethodHead, location, nil, ""); rec.Code != http.StatusGone {
+		t.Fatalf("expired head status = %d, want 410", rec.Code)
+	}
+	if _, err := store.Info(context.Background(), strings.TrimPrefix(location, "/files/")); err != ErrNotFound {
+		t.Fatalf("expired upload not deleted: %v", err)
+	}
+}
+
+func Test_Handler_ExpirationExtendedByPatch(t *testing.T) {
+	h := NewHandler(Config{BasePath: "/files", Store: NewMemoryStore(), Expiration: time.Hour})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+	location := do(t, h, http.MethodPost, "/files", map[string]string{UploadLengthHeaderName: "5"}, "").Header().Get("Location")
+
+	now = now.Add(50 * time.Minute)
+	rec := do(t, h, http.MethodPatch, location, patchHeaders("0"), "he")
+	if rec.Code != http.StatusNoContent || rec.Header().Get(UploadExpiresHeaderName) != "Mon, 01 Jan 2024 01:50:00 GMT" {
+		t.Fatalf("patch = %d, Upload-Expires = %q", rec.Code, rec.Header().Get(UploadExpiresHeaderName))
+	}
+
+	// past the creation-time expiry, within the one the PATCH advertised
+	now = now.Add(30 * time.Minute)
+	if rec := do(t, h, http.MethodPatch, location, patchHeaders("2"), "llo"); rec.Code != http.StatusNoContent {
+		t.Fatalf("patch after the original expiry = %d, want 204", rec.Code)
+	}
+}
+
+func Test_Handler_EmptyUploadCompletes(t *testing.T) {
+	var completed []Upload
+	h := NewHandler(Config{BasePath: "/files", Store: NewMemoryStore(), OnComplete: func(_ context.Context, u Upload) { completed = append(completed, u) }})
+	rec := do(t, h, http.MethodPost, "/files", map[string]string{UploadLengthHeaderName: "0"}, "")
+	if rec.Code != http.StatusCreated || len(completed) != 1 || completed[0].Length != 0 {
+		t.Fatalf("create = %d, completed = %+v", rec.Code, completed)
+	}
+}
+
+func Test_Handler_Rejects(t *testing.T) {
+	h := NewHandler(Config{BasePath: "/files", Store: NewMemoryStore(), MaxSize: 10})
+	location := do(t, h, http.MethodPost, "/files", map[string]string{UploadLengthHeaderName: "5"}, "").Header().Get("Location")
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{name: "too large", method: http.MethodPost, path: "/files", headers: map[string]string{UploadLengthHeaderName: "11"}, want: http.StatusRequestEntityTooLarge},
+		{name: "missing length", method: http.MethodPost, path: "/files", want: http.StatusBadRequest},
+		{name: "wrong content type", method: http.MethodPatch, path: location, headers: map[string]string{UploadOffsetHeaderName: "0"}, want: http.StatusUnsupportedMediaType},
+		{name: "unknown upload", method: http.MethodHead, path: "/files/nope", want: http.StatusNotFound},
+		{name: "wrong version", method: http.MethodHead, path: location, headers: map[string]string{ResumableHeaderName: "0.2.2"}, want: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(t, h, tt.method, tt.path, tt.headers, ""); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Handler_OptionsAndTermination(t *testing.T) {
+	h := NewHandler(Config{BasePath: "/files", Store: NewMemoryStore(), MaxSize: 10, Expiration: time.Hour})
+
+	rec := do(t, h, http.MethodOptions, "/files", nil, "")
+	if rec.Header().Get(ExtensionHeaderName) != "creation,checksum,termination,expiration" ||
+		rec.Header().Get(ChecksumAlgorithmHeaderName) != "md5,sha1,sha256" ||
+		rec.Header().Get(MaxSizeHeaderName) != "10" {
+		t.Fatalf("options headers = %v", rec.Header())
+	}
+
+	location := do(t, h, http.MethodPost, "/files", map[string]string{UploadLengthHeaderName: "5"}, "").Header().Get("Location")
+	if rec := do(t, h, http.MethodDelete, location, nil, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodHead, location, nil, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("head after delete = %d", rec.Code)
+	}
+}
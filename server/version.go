@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// BuildInfo identifies a running service. Fields left empty are filled from
+// the binary's embedded build info (module version, vcs.revision, vcs.time)
+// when VersionHandler is built, so `go build` from a git checkout reports a
+// commit and date without any -ldflags plumbing.
+type BuildInfo struct {
+	Service   string `json:"service,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	// Modified is true when the binary was built from a dirty working tree.
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// VersionHandler returns a handler that reports info as JSON, completed from
+// debug.ReadBuildInfo and the Go runtime. Mount it at /version (or /info) so
+// every service reports its identity the same way:
+//
+//	r.Get("/version", server.VersionHandler(cfg.Build))
+func VersionHandler(info BuildInfo) http.HandlerFunc {
+	info = resolveBuildInfo(info, debug.ReadBuildInfo)
+	return func(w http.ResponseWriter, _ *http.Request) {
+		WriteJSON(w, http.StatusOK, info)
+	}
+}
+
+func resolveBuildInfo(info BuildInfo, read func() (*debug.BuildInfo, bool)) BuildInfo {
+	info.GoVersion = runtime.Version()
+	info.OS = runtime.GOOS
+	info.Arch = runtime.GOARCH
+
+	bi, ok := read()
+	if !ok {
+		return info
+	}
+	if info.Service == "" {
+		info.Service = bi.Main.Path
+	}
+	if info.Version == "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = info.Modified || s.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func Test_resolveBuildInfo_FillsFromBuildInfo(t *testing.T) {
+	read := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/svc", Version: "v1.2.3"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc123"},
+				{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+		}, true
+	}
+	got := resolveBuildInfo(BuildInfo{}, read)
+	want := BuildInfo{
+		Service: "example.com/svc", Version: "v1.2.3", Commit: "abc123", BuildDate: "2026-01-02T03:04:05Z",
+		Modified: true, GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH,
+	}
+	if got != want {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func Test_resolveBuildInfo_ConfigWins(t *testing.T) {
+	read := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			Main:     debug.Module{Path: "example.com/svc", Version: "(devel)"},
+			Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc123"}},
+		}, true
+	}
+	got := resolveBuildInfo(BuildInfo{Service: "billing", Commit: "pinned"}, read)
+	if got.Service != "billing" || got.Commit != "pinned" {
+		t.Fatalf("configured fields overridden: %+v", got)
+	}
+	if got.Version != "" {
+		t.Fatalf("Version: got %q, want empty for a (devel) build", got.Version)
+	}
+}
+
+func Test_VersionHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	VersionHandler(BuildInfo{Service: "billing", Version: "v9"})(rec, httptest.NewRequest(http.MethodGet, "/version", http.NoBody))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d", rec.Code)
+	}
+	var got BuildInfo
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Service != "billing" || got.Version != "v9" || got.GoVersion != runtime.Version() {
+		t.Fatalf("got %+v", got)
+	}
+}
@@ -0,0 +1,443 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// WebSocket handshake headers (RFC 6455), mirrored from github.com/toaweme/http.
+const (
+	WebSocketKeyHeaderName      = "Sec-WebSocket-Key"
+	WebSocketAcceptHeaderName   = "Sec-WebSocket-Accept"
+	WebSocketVersionHeaderName  = "Sec-WebSocket-Version"
+	WebSocketProtocolHeaderName = "Sec-WebSocket-Protocol"
+)
+
+// DefaultWebSocketMaxMessageBytes caps a reassembled incoming message.
+const DefaultWebSocketMaxMessageBytes = 16 << 20
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketMessageType is the type of a data message.
+type WebSocketMessageType int
+
+const (
+	WebSocketText   WebSocketMessageType = 1
+	WebSocketBinary WebSocketMessageType = 2
+)
+
+// WebSocket close codes commonly sent or received.
+const (
+	WebSocketCloseNormal        = 1000
+	WebSocketCloseGoingAway     = 1001
+	WebSocketCloseProtocolError = 1002
+	WebSocketCloseNoStatus      = 1005
+	WebSocketCloseTooLarge      = 1009
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+var (
+	errWebSocketProtocol = errors.New("websocket: protocol error")
+	errWebSocketTooLarge = errors.New("websocket: message too large")
+	errWebSocketClosed   = errors.New("websocket: connection closed")
+)
+
+// WebSocketCloseError is returned by ReadMessage once the peer closes the
+// connection.
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// WebSocketOptions configures the upgrade.
+type WebSocketOptions struct {
+	// Subprotocols the server speaks, in preference order. The first one the
+	// client also offered is selected.
+	Subprotocols []string
+	// AllowedOrigins lists the Origin values accepted besides the request's
+	// own host; "*" accepts any. Requests without Origin (non-browser
+	// clients) are always accepted.
+	AllowedOrigins []string
+	// MaxMessageBytes caps a reassembled message; 0 means
+	// DefaultWebSocketMaxMessageBytes.
+	MaxMessageBytes int
+}
+
+// WebSocketConn is an upgraded server-side connection. ReadMessage must be
+// called from one goroutine; WriteMessage and Close are safe for concurrent
+// use. Pings are answered while reading.
+type WebSocketConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	ctx         context.Context
+	subprotocol string
+	maxBytes    int
+	// release stops the server's shutdown accounting of the connection.
+	release func()
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Context is the handshake request's context, carrying its ClientInfo.
+func (c *WebSocketConn) Context() context.Context { return c.ctx }
+
+// Subprotocol is the negotiated subprotocol, if any.
+func (c *WebSocketConn) Subprotocol() string { return c.subprotocol }
+
+// WebSocketHandlerFunc serves one upgraded connection. The connection is
+// closed when it returns.
+type WebSocketHandlerFunc func(conn *WebSocketConn)
+
+// WebSocketHandler upgrades requests and hands the connection to fn, so
+// WebSocket endpoints register like any other route. Failed handshakes are
+// answered with a plain HTTP error.
+func WebSocketHandler(opts WebSocketOptions, fn WebSocketHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWebSocket(w, r, opts)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		fn(conn)
+	})
+}
+
+// WebSocket registers a GET route upgrading to a WebSocket with default
+// options.
+func (r *Router) WebSocket(pattern string, fn WebSocketHandlerFunc) {
+	r.Handle(http.MethodGet, pattern, WebSocketHandler(WebSocketOptions{}, fn))
+}
+
+// UpgradeWebSocket performs the handshake on r and hijacks the connection.
+// On failure the error response has already been written. The client
+// identification headers of the handshake are parsed into the connection's
+// context and the request id is echoed back, as for plain requests.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request, opts WebSocketOptions) (*WebSocketConn, error) {
+	if err := checkWebSocketHandshake(r, opts); err != nil {
+		w.Header().Set(WebSocketVersionHeaderName, "13")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, err
+	}
+	subprotocol := selectSubprotocol(r, opts.Subprotocols)
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket: upgrade not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	resp.WriteString(WebSocketAcceptHeaderName + ": " + websocketAccept(r.Header.Get(WebSocketKeyHeaderName)) + "\r\n")
+	if subprotocol != "" {
+		resp.WriteString(WebSocketProtocolHeaderName + ": " + subprotocol + "\r\n")
+	}
+	info := ClientInfoFromRequest(r)
+	if info.RequestID != "" {
+		resp.WriteString(ClientRequestIDHeaderName + ": " + info.RequestID + "\r\n")
+	}
+	resp.WriteString("\r\n")
+	if _, err := io.WriteString(netConn, resp.String()); err != nil {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	maxBytes := opts.MaxMessageBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultWebSocketMaxMessageBytes
+	}
+	c := &WebSocketConn{
+		conn:        netConn,
+		br:          brw.Reader,
+		ctx:         ContextWithClientInfo(r.Context(), info),
+		subprotocol: subprotocol,
+		maxBytes:    maxBytes,
+	}
+	// a stopping server says goodbye, which ends the handler's read loop;
+	// closing needs release, so wait until it is set
+	tracked := make(chan struct{})
+	c.release = trackHijack(r.Context(), netConn, func(error) {
+		<-tracked
+		_ = c.CloseWithReason(WebSocketCloseGoingAway, "server shutting down")
+	})
+	close(tracked)
+	return c, nil
+}
+
+func checkWebSocketHandshake(r *http.Request, opts WebSocketOptions) error {
+	switch {
+	case r.Method != http.MethodGet:
+		return errors.New("websocket: method must be GET")
+	case !headerHasToken(r.Header, "Connection", "upgrade"), !headerHasToken(r.Header, "Upgrade", "websocket"):
+		return errors.New("websocket: not an upgrade request")
+	case r.Header.Get(WebSocketVersionHeaderName) != "13":
+		return errors.New("websocket: unsupported version")
+	}
+	if key, err := base64.StdEncoding.DecodeString(r.Header.Get(WebSocketKeyHeaderName)); err != nil || len(key) != 16 {
+		return errors.New("websocket: invalid key")
+	}
+	if !websocketOriginAllowed(r, opts.AllowedOrigins) {
+		return errors.New("websocket: origin not allowed")
+	}
+	return nil
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// websocketOriginAllowed accepts same-host origins, allowlisted ones, and
+// requests without Origin.
+func websocketOriginAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || originAllowed(allowed, origin) || originAllowed(allowed, "*") {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, s := range supported {
+		if headerHasToken(r.Header, WebSocketProtocolHeaderName, s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// ReadMessage returns the next text or binary message. Once the peer closes,
+// it replies in kind and returns a *WebSocketCloseError.
+func (c *WebSocketConn) ReadMessage() (WebSocketMessageType, []byte, error) {
+	var (
+		typ     WebSocketMessageType
+		message []byte
+	)
+	for {
+		fin, op, payload, err := readWebSocketFrame(c.br, true, c.maxBytes)
+		if err != nil {
+			c.failOn(err)
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			cerr := parseWebSocketClose(payload)
+			_ = c.closeWith(cerr.Code, "")
+			return 0, nil, cerr
+		case opText, opBinary:
+			if typ != 0 {
+				c.failOn(errWebSocketProtocol)
+				return 0, nil, errWebSocketProtocol
+			}
+			typ, message = WebSocketMessageType(op), payload
+		case opContinuation:
+			if typ == 0 {
+				c.failOn(errWebSocketProtocol)
+				return 0, nil, errWebSocketProtocol
+			}
+			message = append(message, payload...)
+		default:
+			c.failOn(errWebSocketProtocol)
+			return 0, nil, errWebSocketProtocol
+		}
+		if len(message) > c.maxBytes {
+			c.failOn(errWebSocketTooLarge)
+			return 0, nil, errWebSocketTooLarge
+		}
+		if fin {
+			if typ == WebSocketText && !utf8.Valid(message) {
+				c.failOn(errWebSocketProtocol)
+				return 0, nil, errWebSocketProtocol
+			}
+			return typ, message, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single text or binary frame.
+func (c *WebSocketConn) WriteMessage(typ WebSocketMessageType, data []byte) error {
+	if typ != WebSocketText && typ != WebSocketBinary {
+		return fmt.Errorf("websocket: invalid message type %d", typ)
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+// Close sends a normal close frame and closes the connection.
+func (c *WebSocketConn) Close() error {
+	return c.closeWith(WebSocketCloseNormal, "")
+}
+
+// CloseWithReason sends a close frame with code and reason, then closes the
+// connection.
+func (c *WebSocketConn) CloseWithReason(code int, reason string) error {
+	return c.closeWith(code, reason)
+}
+
+func (c *WebSocketConn) closeWith(code int, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	defer c.release()
+	var payload []byte
+	if code != WebSocketCloseNoStatus {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	writeErr := writeWebSocketFrame(c.conn, opClose, payload)
+	if err := c.conn.Close(); err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// failOn closes the connection after a read error, telling the peer why
+// when the error is ours to report.
+func (c *WebSocketConn) failOn(err error) {
+	switch {
+	case errors.Is(err, errWebSocketTooLarge):
+		_ = c.closeWith(WebSocketCloseTooLarge, "")
+	case errors.Is(err, errWebSocketProtocol):
+		_ = c.closeWith(WebSocketCloseProtocolError, "")
+	default:
+		_ = c.closeWith(WebSocketCloseNoStatus, "")
+	}
+}
+
+func (c *WebSocketConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errWebSocketClosed
+	}
+	return writeWebSocketFrame(c.conn, op, payload)
+}
+
+// readWebSocketFrame reads one frame. Clients must mask, servers must not.
+func readWebSocketFrame(r io.Reader, wantMasked bool, limit int) (fin bool, op byte, payload []byte, err error) {
+	var hdr [8]byte
+	if _, err = io.ReadFull(r, hdr[:2]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = hdr[0]&0x80 != 0
+	op = hdr[0] & 0x0f
+	masked := hdr[1]&0x80 != 0
+	if hdr[0]&0x70 != 0 || masked != wantMasked {
+		return false, 0, nil, errWebSocketProtocol
+	}
+
+	n := uint64(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		if _, err = io.ReadFull(r, hdr[:2]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(hdr[:2]))
+	case 127:
+		if _, err = io.ReadFull(r, hdr[:8]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(hdr[:8])
+	}
+	if op >= opClose && (n > 125 || !fin) {
+		return false, 0, nil, errWebSocketProtocol
+	}
+	if n > uint64(limit) {
+		return false, 0, nil, errWebSocketTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeWebSocketFrame writes payload as one final, unmasked frame.
+func writeWebSocketFrame(w io.Writer, op byte, payload []byte) error {
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|op)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+	_, err := w.Write(frame)
+	return err
+}
+
+func parseWebSocketClose(payload []byte) *WebSocketCloseError {
+	if len(payload) < 2 {
+		return &WebSocketCloseError{Code: WebSocketCloseNoStatus}
+	}
+	return &WebSocketCloseError{
+		Code:   int(binary.BigEndian.Uint16(payload)),
+		Reason: string(payload[2:]),
+	}
+}
+
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testWebSocketKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// dialWebSocket performs a raw client handshake against srv.
+func dialWebSocket(t *testing.T, srv *httptest.Server, path string, extra http.Header) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, http.NoBody)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set(WebSocketVersionHeaderName, "13")
+	req.Header.Set(WebSocketKeyHeaderName, testWebSocketKey)
+	for k, v := range extra {
+		req.Header[k] = v
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp
+}
+
+// writeMaskedFrame writes a client frame, which must be masked.
+func writeMaskedFrame(w io.Writer, op byte, fin bool, payload []byte) error {
+	first := op
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	key := [4]byte{1, 2, 3, 4}
+	frame = append(frame, key[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+func Test_WebSocket_Echo(t *testing.T) {
+	var gotInfo ClientInfo
+	r := NewRouter()
+	r.WebSocket("/ws", func(conn *WebSocketConn) {
+		gotInfo, _ = ClientInfoFromContext(conn.Context())
+		for {
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(typ, data); err != nil {
+				return
+			}
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	conn, br, resp := dialWebSocket(t, srv, "/ws", http.Header{
+		ClientRequestIDHeaderName: {"req-1"},
+		ClientIDHeaderName:        {"client-1"},
+	})
+	defer conn.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	// RFC 6455 section 1.3 sample
+	if got := resp.Header.Get(WebSocketAcceptHeaderName); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("accept = %q", got)
+	}
+	if resp.Header.Get(ClientRequestIDHeaderName) != "req-1" {
+		t.Fatal("request id not echoed")
+	}
+
+	// fragmented text with a ping in between
+	_ = writeMaskedFrame(conn, opText, false, []byte("hel"))
+	_ = writeMaskedFrame(conn, opPing, true, []byte("p"))
+	_ = writeMaskedFrame(conn, opContinuation, true, []byte("lo"))
+
+	_, op, payload, err := readWebSocketFrame(br, false, 1<<20)
+	if err != nil || op != opPong || string(payload) != "p" {
+		t.Fatalf("pong: op=%x payload=%q err=%v", op, payload, err)
+	}
+	_, op, payload, err = readWebSocketFrame(br, false, 1<<20)
+	if err != nil || op != opText || string(payload) != "hello" {
+		t.Fatalf("echo: op=%x payload=%q err=%v", op, payload, err)
+	}
+
+	big := bytes.Repeat([]byte("y"), 70000)
+	_ = writeMaskedFrame(conn, opBinary, true, big)
+	_, op, payload, err = readWebSocketFrame(br, false, 1<<20)
+	if err != nil || op != opBinary || !bytes.Equal(payload, big) {
+		t.Fatalf("binary echo: op=%x len=%d err=%v", op, len(payload), err)
+	}
+
+	_ = writeMaskedFrame(conn, opClose, true, []byte{0x03, 0xe8})
+	_, op, payload, err = readWebSocketFrame(br, false, 1<<20)
+	if err != nil || op != opClose || binary.BigEndian.Uint16(payload) != WebSocketCloseNormal {
+		t.Fatalf("close: op=%x payload=%v err=%v", op, payload, err)
+	}
+	if gotInfo.ClientID != "client-1" || gotInfo.RequestID != "req-1" {
+		t.Fatalf("client info = %+v", gotInfo)
+	}
+}
+
+func Test_WebSocket_UnmaskedFrameIsProtocolError(t *testing.T) {
+	errc := make(chan error, 1)
+	srv := httptest.NewServer(WebSocketHandler(WebSocketOptions{}, func(conn *WebSocketConn) {
+		_, _, err := conn.ReadMessage()
+		errc <- err
+	}))
+	defer srv.Close()
+
+	conn, br, _ := dialWebSocket(t, srv, "/", nil)
+	defer conn.Close()
+	_ = writeWebSocketFrame(conn, opText, []byte("x"))
+
+	if err := <-errc; !errors.Is(err, errWebSocketProtocol) {
+		t.Fatalf("err = %v", err)
+	}
+	_, _, payload, _ := readWebSocketFrame(br, false, 1<<20)
+	if len(payload) < 2 || binary.BigEndian.Uint16(payload) != WebSocketCloseProtocolError {
+		t.Fatalf("close payload = %v", payload)
+	}
+}
+
+func Test_WebSocket_Handshake(t *testing.T) {
+	opts := WebSocketOptions{Subprotocols: []string{"v2", "v1"}, AllowedOrigins: []string{"https://app.example"}}
+	srv := httptest.NewServer(WebSocketHandler(opts, func(*WebSocketConn) {}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name     string
+		headers  http.Header
+		status   int
+		protocol string
+	}{
+		{"subprotocol", http.Header{WebSocketProtocolHeaderName: {"v1, v2"}}, http.StatusSwitchingProtocols, "v2"},
+		{"allowed origin", http.Header{"Origin": {"https://app.example"}}, http.StatusSwitchingProtocols, ""},
+		{"foreign origin", http.Header{"Origin": {"https://evil.example"}}, http.StatusBadRequest, ""},
+		{"bad version", http.Header{WebSocketVersionHeaderName: {"8"}}, http.StatusBadRequest, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, _, resp := dialWebSocket(t, srv, "/", tc.headers)
+			defer conn.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d want %d", resp.StatusCode, tc.status)
+			}
+			if got := resp.Header.Get(WebSocketProtocolHeaderName); got != tc.protocol {
+				t.Fatalf("protocol = %q want %q", got, tc.protocol)
+			}
+		})
+	}
+}
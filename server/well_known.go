@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WellKnownConfig drives the metadata routes RegisterWellKnown serves. A route
+// is only registered when its part of the config is set.
+type WellKnownConfig struct {
+	// Robots is served verbatim as /robots.txt.
+	Robots string
+	// Security is served as /.well-known/security.txt (RFC 9116).
+	Security SecurityTxt
+	// ChangePasswordURL is where /.well-known/change-password redirects, so
+	// password managers can deep-link users to it.
+	ChangePasswordURL string
+}
+
+// SecurityTxt is the content of a security.txt file. Contact and Expires are
+// required by RFC 9116; the file is only served when Contact is set.
+type SecurityTxt struct {
+	Contact            []string
+	Expires            time.Time
+	Encryption         []string
+	Policy             string
+	PreferredLanguages []string
+	Canonical          string
+}
+
+// RobotsDisallowAll is a robots.txt asking every crawler to stay away, for
+// APIs that should never be indexed.
+const RobotsDisallowAll = "User-agent: *\nDisallow: /\n"
+
+// RegisterWellKnown registers /robots.txt, /.well-known/security.txt, and
+// /.well-known/change-password on r for the parts of cfg that are set.
+func RegisterWellKnown(r *Router, cfg WellKnownConfig) {
+	if cfg.Robots != "" {
+		r.Get("/robots.txt", textHandler(cfg.Robots))
+	}
+	if len(cfg.Security.Contact) > 0 {
+		r.Get("/.well-known/security.txt", textHandler(cfg.Security.String()))
+	}
+	if cfg.ChangePasswordURL != "" {
+		r.Get("/.well-known/change-password", func(w http.ResponseWriter, req *http.Request) {
+			http.Redirect(w, req, cfg.ChangePasswordURL, http.StatusFound)
+		})
+	}
+}
+
+// String renders the file in RFC 9116 field order.
+func (s SecurityTxt) String() string {
+	var b strings.Builder
+	field := func(name, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	for _, c := range s.Contact {
+		field("Contact", c)
+	}
+	if !s.Expires.IsZero() {
+		field("Expires", s.Expires.UTC().Format(time.RFC3339))
+	}
+	for _, e := range s.Encryption {
+		field("Encryption", e)
+	}
+	field("Policy", s.Policy)
+	field("Preferred-Languages", strings.Join(s.PreferredLanguages, ", "))
+	field("Canonical", s.Canonical)
+	return b.String()
+}
+
+func textHandler(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		_, _ = w.Write([]byte(body))
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_RegisterWellKnown(t *testing.T) {
+	r := NewRouter()
+	RegisterWellKnown(r, WellKnownConfig{
+		Robots: RobotsDisallowAll,
+		Security: SecurityTxt{
+			Contact:            []string{"mailto:security@example.com"},
+			Expires:            time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+			PreferredLanguages: []string{"en", "lt"},
+		},
+		ChangePasswordURL: "https://example.com/account/password",
+	})
+
+	rec := get(t, r, http.MethodGet, "/robots.txt")
+	if rec.Code != http.StatusOK || rec.Body.String() != RobotsDisallowAll {
+		t.Fatalf("robots: %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = get(t, r, http.MethodGet, "/.well-known/security.txt")
+	want := "Contact: mailto:security@example.com\nExpires: 2027-01-01T00:00:00Z\nPreferred-Languages: en, lt\n"
+	if rec.Body.String() != want {
+		t.Fatalf("security.txt: got %q want %q", rec.Body.String(), want)
+	}
+	if rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("Content-Type: %q", rec.Header().Get("Content-Type"))
+	}
+
+	rec = get(t, r, http.MethodGet, "/.well-known/change-password")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/account/password" {
+		t.Fatalf("change-password: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func Test_RegisterWellKnown_SkipsUnset(t *testing.T) {
+	r := NewRouter()
+	RegisterWellKnown(r, WellKnownConfig{})
+	for _, path := range []string{"/robots.txt", "/.well-known/security.txt", "/.well-known/change-password"} {
+		if rec := get(t, r, http.MethodGet, path); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: got %d want 404", path, rec.Code)
+		}
+	}
+}
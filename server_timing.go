@@ -0,0 +1,97 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerTimingHeaderName carries server-reported phase timings (W3C Server Timing).
+const ServerTimingHeaderName = "Server-Timing"
+
+// ServerTiming is one metric from a Server-Timing response header, e.g.
+// `db;dur=53.2;desc="Primary DB"`.
+type ServerTiming struct {
+	Name        string
+	Duration    time.Duration
+	Description string
+}
+
+// ParseServerTiming parses every Server-Timing header value into metrics, in
+// the order the server sent them. Malformed parameters are skipped rather than
+// failing the whole header; a metric without dur has a zero Duration.
+func ParseServerTiming(values []string) []ServerTiming {
+	var out []ServerTiming
+	for _, value := range values {
+		for _, metric := range splitUnquoted(value, ',') {
+			params := splitUnquoted(metric, ';')
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+			st := ServerTiming{Name: name}
+			for _, param := range params[1:] {
+				key, val, ok := strings.Cut(param, "=")
+				if !ok {
+					continue
+				}
+				val = unquote(strings.TrimSpace(val))
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "dur":
+					ms, err := strconv.ParseFloat(val, 64)
+					if err == nil {
+						st.Duration = time.Duration(ms * float64(time.Millisecond))
+					}
+				case "desc":
+					st.Description = val
+				}
+			}
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// splitUnquoted splits s on sep, ignoring separators inside double quotes so a
+// desc like "read, then write" stays whole.
+func splitUnquoted(s string, sep byte) []string {
+	var (
+		parts   []string
+		quoted  bool
+		escaped bool
+		start   int
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
+	return s[1 : len(s)-1]
+}
+
+// serverTimingArgs flattens metrics into logger args (name -> duration).
+func serverTimingArgs(timings []ServerTiming) map[string]string {
+	out := make(map[string]string, len(timings))
+	for _, t := range timings {
+		out[t.Name] = t.Duration.String()
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ParseServerTiming(t *testing.T) {
+	got := ParseServerTiming([]string{
+		`cache;desc="Cache Read";dur=23.2, db;dur=53`,
+		`miss, app;desc="render, then flush";dur=bogus`,
+	})
+	want := []ServerTiming{
+		{Name: "cache", Duration: 23200 * time.Microsecond, Description: "Cache Read"},
+		{Name: "db", Duration: 53 * time.Millisecond},
+		{Name: "miss"},
+		{Name: "app", Description: "render, then flush"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseServerTiming = %+v, want %+v", got, want)
+	}
+}
+
+func Test_ParseServerTiming_Empty(t *testing.T) {
+	if got := ParseServerTiming(nil); got != nil {
+		t.Errorf("ParseServerTiming(nil) = %v, want nil", got)
+	}
+}
+
+func Test_Client_ServerTimingOnResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(ServerTimingHeaderName, "db;dur=12")
+	}))
+	defer srv.Close()
+
+	log := &recordingLogger{}
+	resp, err := newTestClient(t, srv.URL, WithLogger(log)).Get(context.Background(), GetRequest{Request: Request{Path: "/x"}})
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	want := []ServerTiming{{Name: "db", Duration: 12 * time.Millisecond}}
+	if !reflect.DeepEqual(resp.ServerTiming, want) {
+		t.Errorf("ServerTiming = %+v, want %+v", resp.ServerTiming, want)
+	}
+	if log.debugs == 0 {
+		t.Error("server timings were not logged")
+	}
+}
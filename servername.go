@@ -0,0 +1,47 @@
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// errServerNameUnsupported is returned when a request overrides the TLS
+// server name but the injected *http.Client has a transport the client
+// cannot reconfigure.
+var errServerNameUnsupported = errors.New("server name override requires an *http.Transport")
+
+type serverNameKey struct {
+	base *http.Client
+	name string
+}
+
+// serverNameClients caches one clone of a base client's transport per TLS
+// server name, so a connection opened under one name is never reused for
+// another.
+type serverNameClients struct {
+	mu      sync.Mutex
+	clients map[serverNameKey]*http.Client
+}
+
+// client returns base with its transport presenting and verifying name.
+func (s *serverNameClients) client(base *http.Client, name string) (*http.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := serverNameKey{base: base, name: name}
+	if client, ok := s.clients[key]; ok {
+		return client, nil
+	}
+	client := withTransport(base, func(t *http.Transport) {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.ServerName = name
+	})
+	if client == base {
+		return nil, errServerNameUnsupported
+	}
+	s.clients[key] = client
+	return client, nil
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Client_HostAndServerNameOverride(t *testing.T) {
+	var gotHost, gotSNI string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotSNI = r.Host, r.TLS.ServerName
+	}))
+	// the rejected handshake is expected
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	c := NewClient(Config{BaseURL: srv.URL}, WithHTTPClient(srv.Client()))
+
+	// httptest's certificate is valid for example.com and 127.0.0.1
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", Host: "api.example.com", ServerName: "example.com"}}); err != nil {
+		t.Fatal(err)
+	}
+	if gotHost != "api.example.com" || gotSNI != "example.com" {
+		t.Fatalf("host = %q sni = %q, want api.example.com and example.com", gotHost, gotSNI)
+	}
+
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+		t.Fatal(err)
+	}
+	if gotHost != srv.Listener.Addr().String() || gotSNI != "" {
+		t.Fatalf("without overrides: host = %q sni = %q", gotHost, gotSNI)
+	}
+
+	_, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", ServerName: "other.test"}})
+	if err == nil {
+		t.Fatal("a server name the certificate does not cover was accepted")
+	}
+}
+
+func Test_serverNameClients(t *testing.T) {
+	s := &serverNameClients{clients: make(map[serverNameKey]*http.Client)}
+	base := &http.Client{Transport: &http.Transport{}}
+
+	a, err := s.client(base, "a.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := s.client(base, "a.test")
+	b, _ := s.client(base, "b.test")
+	if a != again || a == b || a == base {
+		t.Fatal("clients are not cached per server name")
+	}
+	if name := a.Transport.(*http.Transport).TLSClientConfig.ServerName; name != "a.test" {
+		t.Fatalf("ServerName = %q", name)
+	}
+	if cfg := base.Transport.(*http.Transport).TLSClientConfig; cfg != nil && cfg.ServerName != "" {
+		t.Fatal("base transport was modified")
+	}
+
+	if _, err := s.client(&http.Client{Transport: &stubRoundTripper{}}, "a.test"); err != errServerNameUnsupported {
+		t.Fatalf("custom transport: err = %v", err)
+	}
+}
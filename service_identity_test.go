@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_MintServiceToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := MintServiceToken(ServiceIdentity{Service: "orders", Audience: "billing", KeyID: "k1", Secret: "s3cret"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %q is not compact", token)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if parts[2] != base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) {
+		t.Fatal("HS256 signature mismatch")
+	}
+	var header map[string]string
+	data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	_ = json.Unmarshal(data, &header)
+	if header["alg"] != "HS256" || header["kid"] != "k1" {
+		t.Fatalf("header = %v", header)
+	}
+	var claims ServiceClaims
+	data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	_ = json.Unmarshal(data, &claims)
+	want := ServiceClaims{Issuer: "orders", Audience: "billing", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+	if claims != want {
+		t.Fatalf("claims = %+v, want %+v", claims, want)
+	}
+
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	token, err = MintServiceToken(ServiceIdentity{Service: "orders", PrivateKey: priv}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts = strings.Split(token, ".")
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if !ed25519.Verify(pub, []byte(parts[0]+"."+parts[1]), sig) {
+		t.Fatal("EdDSA signature does not verify")
+	}
+
+	if _, err := MintServiceToken(ServiceIdentity{Service: "orders"}, now); err == nil {
+		t.Fatal("minted a token without a key")
+	}
+}
+
+func Test_Client_WithServiceIdentity(t *testing.T) {
+	var tokens []string
+	var name string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get(ServiceTokenHeaderName))
+		name = r.Header.Get(ServiceNameHeaderName)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, ServiceName: "orders"}, WithServiceIdentity(ServiceIdentity{Secret: "s3cret"}))
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(context.Background(), GetRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(tokens) != 2 || tokens[0] == "" || name != "orders" {
+		t.Fatalf("tokens = %q, service name = %q", tokens, name)
+	}
+	var claims ServiceClaims
+	data, _ := base64.RawURLEncoding.DecodeString(strings.Split(tokens[0], ".")[1])
+	if err := json.Unmarshal(data, &claims); err != nil || claims.Issuer != "orders" {
+		t.Fatalf("claims = %+v, err = %v", claims, err)
+	}
+}
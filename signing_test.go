@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// signatureGolden is shared with the server's Test_Signature_MatchesClient so
+// a change to the canonical form on either side fails both.
+const signatureGolden = "c35c6cf4b5557842e86647d11441ff333dbed96a865496a590b0fd658b96ac04"
+
+func Test_Signature(t *testing.T) {
+	got := Signature("s3cret", "post", "/hooks?source=billing", "1700000000", "abc", []byte(`{"amount":1}`))
+	if got != signatureGolden {
+		t.Fatalf("Signature = %s, want %s", got, signatureGolden)
+	}
+}
+
+func Test_Client_WithSigningKey(t *testing.T) {
+	var got http.Header
+	var uri string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, uri = r.Header.Clone(), r.URL.RequestURI()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, WithSigningKey(SigningKey{ID: "k1", Secret: "s3cret"}))
+	body := []byte(`{"amount":1}`)
+	if _, err := c.Post(context.Background(), PostRequest{Request: Request{Path: "/hooks"}, Body: body}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	ts, err := strconv.ParseInt(got.Get(SignatureTimestampHeaderName), 10, 64)
+	if err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Fatalf("timestamp = %q", got.Get(SignatureTimestampHeaderName))
+	}
+	if got.Get(SignatureKeyIDHeaderName) != "k1" || len(got.Get(SignatureNonceHeaderName)) != 32 {
+		t.Fatalf("headers = %v", got)
+	}
+	want := Signature("s3cret", http.MethodPost, uri, got.Get(SignatureTimestampHeaderName), got.Get(SignatureNonceHeaderName), body)
+	if got.Get(SignatureHeaderName) != want {
+		t.Fatalf("signature = %s, want %s", got.Get(SignatureHeaderName), want)
+	}
+}
@@ -0,0 +1,298 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// LastEventIDHeaderName carries the last seen event ID when a stream
+// reconnects, so the server can resume after it.
+const LastEventIDHeaderName = "Last-Event-ID"
+
+// Stream defaults applied when StreamConfig leaves them unset.
+const (
+	DefaultStreamDoneSentinel   = "[DONE]"
+	DefaultStreamReconnectDelay = 3 * time.Second
+)
+
+// StreamConfig tunes how GetStream and PostStream end and resume streams.
+type StreamConfig struct {
+	// DoneSentinel is the data payload that ends a stream; it is consumed
+	// rather than delivered. Defaults to DefaultStreamDoneSentinel.
+	DoneSentinel string `json:"done_sentinel"`
+	// NoDoneSentinel disables sentinel detection, for streams where "[DONE]"
+	// is ordinary data.
+	NoDoneSentinel bool `json:"no_done_sentinel"`
+	// MaxReconnects, when > 0, resumes a stream that ends or fails before the
+	// sentinel, up to this many times in a row, sending Last-Event-ID. Streams
+	// with a Request.BodyReader body never reconnect.
+	MaxReconnects int `json:"max_reconnects"`
+	// ReconnectDelay is the wait before reconnecting until the server sets
+	// one with retry:. Defaults to DefaultStreamReconnectDelay.
+	ReconnectDelay time.Duration `json:"reconnect_delay"`
+	// IdleTimeout, when > 0, ends a stream that receives nothing, heartbeat
+	// comments included, for this long with ErrStreamIdleTimeout, or
+	// reconnects it within MaxReconnects. Timeouts.StreamIdle takes
+	// precedence.
+	IdleTimeout time.Duration `json:"idle_timeout"`
+	// SequentialIDs declares that the server gives every event a
+	// consecutive integer id, letting the client check them: an event whose
+	// id is not past the previous one, as a replay after reconnecting with
+	// Last-Event-ID can send, is dropped, and one skipping ids is delivered
+	// with StreamResponse.Gap set.
+	SequentialIDs bool `json:"sequential_ids"`
+}
+
+func (c StreamConfig) doneSentinel() string {
+	if c.DoneSentinel == "" {
+		return DefaultStreamDoneSentinel
+	}
+	return c.DoneSentinel
+}
+
+func (c StreamConfig) reconnectDelay() time.Duration {
+	if c.ReconnectDelay <= 0 {
+		return DefaultStreamReconnectDelay
+	}
+	return c.ReconnectDelay
+}
+
+// StreamGap describes events lost before a DATA frame.
+type StreamGap struct {
+	// LastID is the id of the event delivered before the gap.
+	LastID string
+	// Missed is how many ids were skipped.
+	Missed uint64
+	// Reconnected reports whether the gap spans a reconnect: the server
+	// could not replay everything after Last-Event-ID.
+	Reconnected bool
+}
+
+// streamSequencer assigns StreamResponse.Seq and, with sequential ids,
+// checks each event's id against the previous one.
+type streamSequencer struct {
+	sequential bool
+	seq        uint64
+	lastID     uint64
+	hasID      bool
+	// reconnected is set until the first event after a reconnect.
+	reconnected bool
+}
+
+// next returns the Seq and Gap of the event with id, or duplicate when it
+// must be dropped.
+func (s *streamSequencer) next(id string) (seq uint64, gap *StreamGap, duplicate bool) {
+	reconnected := s.reconnected
+	s.reconnected = false
+	if n, err := strconv.ParseUint(id, 10, 64); s.sequential && err == nil {
+		if s.hasID && n <= s.lastID {
+			return 0, nil, true
+		}
+		if s.hasID && n > s.lastID+1 {
+			gap = &StreamGap{LastID: strconv.FormatUint(s.lastID, 10), Missed: n - s.lastID - 1, Reconnected: reconnected}
+		}
+		s.lastID, s.hasID = n, true
+	}
+	s.seq++
+	return s.seq, gap, false
+}
+
+// retryDelay is the reconnect interval in effect for r: the server's latest
+// retry:, else the configured delay.
+func (c StreamConfig) retryDelay(r *SSEReader) time.Duration {
+	if d := r.Retry(); d > 0 {
+		return d
+	}
+	return c.reconnectDelay()
+}
+
+// ErrStreamIdleTimeout ends a stream that stalled for StreamConfig.IdleTimeout.
+var ErrStreamIdleTimeout = errors.New("stream idle timeout")
+
+// watchIdle returns body closing itself once nothing was read from it for
+// timeout, or body itself when timeout is not set.
+func watchIdle(body io.ReadCloser, timeout time.Duration) io.ReadCloser {
+	if timeout <= 0 {
+		return body
+	}
+	b := &idleBody{ReadCloser: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.fired.Store(true)
+		_ = body.Close()
+	})
+	return b
+}
+
+type idleBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	fired   atomic.Bool
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && b.fired.Load() {
+		err = fmt.Errorf("%w: nothing received for %s", ErrStreamIdleTimeout, b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
+
+// sseMaxLineSize caps a single SSE line; large model outputs can put
+// megabytes in one data: line.
+const sseMaxLineSize = 4 << 20
+
+// SSEEvent is one dispatched Server-Sent Event.
+type SSEEvent struct {
+	// ID is the last event ID in effect when the event was dispatched; it
+	// persists across events until the server sends a new id: field.
+	ID string
+	// Event is the event: type, empty for the default "message".
+	Event string
+	// Data joins the event's data: lines with "\n".
+	Data []byte
+	// Retry is the reconnection delay the server set while this event was
+	// being read, 0 when it sent none.
+	Retry time.Duration
+}
+
+// SSEReader parses a text/event-stream per the WHATWG HTML specification:
+// CR, LF, and CRLF line endings; multi-line data assembled into one event;
+// dispatch on blank lines; comments ignored; id: and retry: tracked across
+// events.
+type SSEReader struct {
+	scanner *bufio.Scanner
+	lastID  string
+	retry   time.Duration
+	started bool
+}
+
+// NewSSEReader reads events from r.
+func NewSSEReader(r io.Reader) *SSEReader {
+	s := &SSEReader{}
+	s.reset(r)
+	return s
+}
+
+// reset continues on a reconnected stream, keeping the last event ID and
+// retry delay.
+func (s *SSEReader) reset(r io.Reader) {
+	s.scanner = bufio.NewScanner(r)
+	s.scanner.Buffer(make([]byte, 0, 4096), sseMaxLineSize)
+	s.scanner.Split(scanSSELines)
+	s.started = false
+}
+
+// LastEventID is the id to send as Last-Event-ID when reconnecting.
+func (s *SSEReader) LastEventID() string { return s.lastID }
+
+// Retry is the latest reconnection delay the server asked for, 0 if none.
+func (s *SSEReader) Retry() time.Duration { return s.retry }
+
+// Next returns the next event. At the end of the stream it returns io.EOF;
+// an event not terminated by a blank line is discarded, as the spec requires.
+func (s *SSEReader) Next() (SSEEvent, error) {
+	var (
+		data      bytes.Buffer
+		hasData   bool
+		eventType string
+		retry     time.Duration
+	)
+	for s.scanner.Scan() {
+		line := s.scanner.Bytes()
+		if !s.started {
+			s.started = true
+			line = bytes.TrimPrefix(line, []byte("\xEF\xBB\xBF"))
+		}
+
+		if len(line) == 0 {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			return SSEEvent{ID: s.lastID, Event: eventType, Data: data.Bytes(), Retry: retry}, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			value = bytes.TrimPrefix(value, []byte(" "))
+		}
+		switch string(field) {
+		case "event":
+			eventType = string(value)
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.Write(value)
+			hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				s.lastID = string(value)
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 63); err == nil && isDigits(value) {
+				s.retry = time.Duration(ms) * time.Millisecond
+				retry = s.retry
+			}
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return SSEEvent{}, err
+	}
+	return SSEEvent{}, io.EOF
+}
+
+func isDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(b) > 0
+}
+
+// scanSSELines splits on CR, LF, or CRLF.
+func scanSSELines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// a CR at the end of the buffer may be the first half of a CRLF
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
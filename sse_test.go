@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func readAllSSE(t *testing.T, input string) ([]SSEEvent, *SSEReader) {
+	t.Helper()
+	r := NewSSEReader(strings.NewReader(input))
+	var events []SSEEvent
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			return events, r
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		events = append(events, ev)
+	}
+}
+
+func Test_SSEReader_Spec(t *testing.T) {
+	input := "\xEF\xBB\xBFdata: first\r\n" +
+		"data:second\r\n" +
+		"\r\n" +
+		": comment\n" +
+		"event: update\rid: 7\rdata\r\r" +
+		"retry: 2500\n\n" +
+		"retry: soon\n" +
+		"id: bad\x00id\n" +
+		"data: {\"a\":1}\n" +
+		"unknown: field\n\n" +
+		"data: dangling, never dispatched"
+
+	events, r := readAllSSE(t, input)
+	want := []SSEEvent{
+		{Data: []byte("first\nsecond")},
+		{ID: "7", Event: "update"},
+		// the retry: from the data-less block rides on the next event
+		{ID: "7", Data: []byte(`{"a":1}`), Retry: 2500 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %+v\nwant %+v", events, want)
+	}
+	if r.Retry() != 2500*time.Millisecond || r.LastEventID() != "7" {
+		t.Fatalf("Retry = %v LastEventID = %q", r.Retry(), r.LastEventID())
+	}
+}
+
+func Test_SSEReader_EventTypeResetWithoutData(t *testing.T) {
+	events, _ := readAllSSE(t, "event: ping\n\ndata: x\n\n")
+	if len(events) != 1 || events[0].Event != "" {
+		t.Fatalf("events = %+v, want one default-type event", events)
+	}
+}
+
+func Test_Stream_ReconnectsWithLastEventID(t *testing.T) {
+	var conns atomic.Int64
+	var lastIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs = append(lastIDs, r.Header.Get(LastEventIDHeaderName))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if conns.Add(1) == 1 {
+			_, _ = io.WriteString(w, "retry: 1\nid: 1\ndata: a\n\n")
+			return // drop the connection before the sentinel
+		}
+		_, _ = io.WriteString(w, "id: 2\ndata: b\n\ndata: END\n\n")
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Stream: StreamConfig{DoneSentinel: "END", MaxReconnects: 2}})
+	stream := make(chan StreamResponse, 8)
+	if err := c.GetStream(context.Background(), stream, Request{}); err != nil {
+		t.Fatalf("GetStream: %v", err)
+	}
+	var data []string
+	var last StreamResponse
+	for msg := range stream {
+		if msg.Type == StreamResponseTypeData {
+			data = append(data, msg.ID+":"+string(msg.Body))
+		}
+		last = msg
+	}
+
+	if want := []string{"1:a", "2:b"}; !reflect.DeepEqual(data, want) {
+		t.Fatalf("data = %v want %v", data, want)
+	}
+	if last.Type != StreamResponseTypeEOF || last.Error != nil {
+		t.Fatalf("terminal frame = %+v, want a clean EOF", last)
+	}
+	if want := []string{"", "1"}; !reflect.DeepEqual(lastIDs, want) {
+		t.Fatalf("Last-Event-ID sent = %q want %q", lastIDs, want)
+	}
+}
+
+func Test_Stream_SequentialIDs(t *testing.T) {
+	var conns atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch conns.Add(1) {
+		case 1:
+			_, _ = io.WriteString(w, "retry: 1\nid: 1\ndata: a\n\nid: 2\ndata: b\n\n")
+		case 2:
+			// replays the last event it saw, then drops again
+			_, _ = io.WriteString(w, "id: 2\ndata: b\n\nid: 3\ndata: c\n\n")
+		default:
+			// resumes past events it no longer has
+			_, _ = io.WriteString(w, "id: 6\ndata: f\n\nid: 8\ndata: h\n\ndata: END\n\n")
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Stream: StreamConfig{DoneSentinel: "END", MaxReconnects: 3, SequentialIDs: true}})
+	stream := make(chan StreamResponse, 16)
+	if err := c.GetStream(context.Background(), stream, Request{}); err != nil {
+		t.Fatalf("GetStream: %v", err)
+	}
+	var got []string
+	for msg := range stream {
+		if msg.Type != StreamResponseTypeData {
+			continue
+		}
+		frame := fmt.Sprintf("%d:%s", msg.Seq, msg.Body)
+		if msg.Gap != nil {
+			frame += fmt.Sprintf(" gap after %s missed %d reconnected %v", msg.Gap.LastID, msg.Gap.Missed, msg.Gap.Reconnected)
+		}
+		got = append(got, frame)
+	}
+
+	want := []string{"1:a", "2:b", "3:c", "4:f gap after 3 missed 2 reconnected true", "5:h gap after 6 missed 1 reconnected false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("frames = %q\nwant %q", got, want)
+	}
+}
+
+func Test_Stream_NoDoneSentinel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Stream: StreamConfig{NoDoneSentinel: true}})
+	stream := make(chan StreamResponse, 4)
+	if err := c.GetStream(context.Background(), stream, Request{}); err != nil {
+		t.Fatalf("GetStream: %v", err)
+	}
+	first := <-stream
+	if first.Type != StreamResponseTypeData || string(first.Body) != "[DONE]" {
+		t.Fatalf("first frame = %+v, want [DONE] delivered as data", first)
+	}
+	for range stream {
+	}
+}
+
+func Test_Stream_ExposesRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: a\n\nretry: 1500\n\ndata: b\n\n")
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Stream: StreamConfig{ReconnectDelay: 200 * time.Millisecond}})
+	stream := make(chan StreamResponse, 4)
+	if err := c.GetStream(context.Background(), stream, Request{}); err != nil {
+		t.Fatalf("GetStream: %v", err)
+	}
+	var retries []time.Duration
+	for msg := range stream {
+		retries = append(retries, msg.Retry)
+	}
+	// the retry-only block is not an event but takes effect for the next one
+	if want := []time.Duration{0, 1500 * time.Millisecond, 1500 * time.Millisecond}; !reflect.DeepEqual(retries, want) {
+		t.Fatalf("Retry per frame = %v want %v", retries, want)
+	}
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrStreamMuxClosed is returned by Subscribe after the StreamMux was
+// closed.
+var ErrStreamMuxClosed = errors.New("stream mux is closed")
+
+// streamMuxBuffer is how many frames a subscription holds for a slow
+// reader before the upstream stream waits.
+const streamMuxBuffer = 16
+
+// StreamMux runs many logical event-stream subscriptions through one client,
+// each with its own channel and cancellation. Built on a client with
+// TransportConfig.Multiplex, the subscriptions to a host share one HTTP/2
+// connection as concurrent streams, keeping the connection count down
+// against providers that limit it.
+//
+//	mux := http.NewStreamMux(http.NewClient(http.Config{BaseURL: url, Transport: http.TransportConfig{Multiplex: true}}))
+//	defer mux.Close()
+//	prices, err := mux.Subscribe(ctx, http.Request{Path: "/prices"})
+//	for frame := range prices.Events { ... }
+type StreamMux struct {
+	client Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+	subs   map[*Subscription]struct{}
+}
+
+// NewStreamMux returns a mux opening its subscriptions through c.
+func NewStreamMux(c Client) *StreamMux {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamMux{client: c, ctx: ctx, cancel: cancel, subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription is one logical stream of a StreamMux.
+type Subscription struct {
+	// Events delivers the frames of the stream, as GetStream does, and is
+	// closed once the stream ended or the subscription was closed.
+	Events <-chan StreamResponse
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Subscribe opens req as an event stream with GetStream. The subscription
+// ends when its stream ends, ctx is done, it is closed, or the mux is.
+func (m *StreamMux) Subscribe(ctx context.Context, req Request) (*Subscription, error) {
+	return m.subscribe(ctx, func(ctx context.Context, stream chan StreamResponse) error {
+		return m.client.GetStream(ctx, stream, req)
+	})
+}
+
+// SubscribePost is Subscribe for an event stream opened with PostStream.
+func (m *StreamMux) SubscribePost(ctx context.Context, req PostRequest) (*Subscription, error) {
+	return m.subscribe(ctx, func(ctx context.Context, stream chan StreamResponse) error {
+		return m.client.PostStream(ctx, stream, req)
+	})
+}
+
+func (m *StreamMux) subscribe(ctx context.Context, open func(context.Context, chan StreamResponse) error) (*Subscription, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrStreamMuxClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	// closing the mux cancels every subscription
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-m.ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+	events := make(chan StreamResponse, streamMuxBuffer)
+	sub := &Subscription{Events: events, cancel: cancel, done: make(chan struct{})}
+	m.subs[sub] = struct{}{}
+	m.mu.Unlock()
+
+	stream := make(chan StreamResponse, 1)
+	if err := open(ctx, stream); err != nil {
+		close(stop)
+		cancel()
+		close(events)
+		m.remove(sub)
+		close(sub.done)
+		return nil, err
+	}
+	go func() {
+		defer close(sub.done)
+		defer m.remove(sub)
+		defer close(stop)
+		defer close(events)
+		sub.forward(ctx, stream, events)
+	}()
+	return sub, nil
+}
+
+// forward relays stream to events until either ends; the upstream reader
+// blocks on stream, so what is left is drained after a cancel.
+func (s *Subscription) forward(ctx context.Context, stream chan StreamResponse, events chan StreamResponse) {
+	defer func() {
+		s.cancel()
+		for range stream {
+		}
+	}()
+	for frame := range stream {
+		select {
+		case events <- frame:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *StreamMux) remove(sub *Subscription) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subs, sub)
+}
+
+// Len returns the number of open subscriptions.
+func (m *StreamMux) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.subs)
+}
+
+// Close ends every subscription, waits for them to finish, and makes later
+// Subscribe calls fail with ErrStreamMuxClosed.
+func (m *StreamMux) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	subs := make([]*Subscription, 0, len(m.subs))
+	for sub := range m.subs {
+		subs = append(subs, sub)
+	}
+	m.mu.Unlock()
+
+	m.cancel()
+	for _, sub := range subs {
+		<-sub.done
+	}
+	return nil
+}
+
+// Close ends this subscription only, leaving the others of its mux and
+// their shared connection open, and waits for it to finish. Frames not yet
+// read from Events are discarded.
+func (s *Subscription) Close() {
+	s.cancel()
+	go func() {
+		for range s.Events {
+		}
+	}()
+	<-s.done
+}
+
+// Done is closed once the subscription has finished.
+func (s *Subscription) Done() <-chan struct{} { return s.done }
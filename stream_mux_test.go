@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// muxUpstream serves /topic/{name} as an endless HTTP/2 event stream of
+// "{name}-{n}" events, counting the connections it accepts.
+func muxUpstream(t *testing.T, conns *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeEventStream)
+		topic := r.URL.Path[len("/topic/"):]
+		for n := 0; ; n++ {
+			if _, err := fmt.Fprintf(w, "data: %s-%d\n\n", topic, n); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	return srv
+}
+
+func Test_StreamMux_SharesOneConnection(t *testing.T) {
+	var conns atomic.Int32
+	srv := muxUpstream(t, &conns)
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Transport: TransportConfig{Multiplex: true}, TLS: TLSConfig{InsecureSkipVerify: true}})
+	mux := NewStreamMux(c)
+	defer mux.Close()
+
+	a, err := mux.Subscribe(context.Background(), Request{Path: "/topic/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := mux.Subscribe(context.Background(), Request{Path: "/topic/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mux.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", mux.Len())
+	}
+	for _, tc := range []struct {
+		sub  *Subscription
+		want string
+	}{{a, "a-0"}, {b, "b-0"}} {
+		frame := <-tc.sub.Events
+		if string(frame.Body) != tc.want {
+			t.Fatalf("first event = %q, want %q", frame.Body, tc.want)
+		}
+	}
+
+	// closing one subscription leaves the other streaming
+	a.Close()
+	if frame := <-b.Events; string(frame.Body) != "b-1" {
+		t.Fatalf("after closing a, b got %q", frame.Body)
+	}
+	if mux.Len() != 1 {
+		t.Fatalf("Len = %d after Close, want 1", mux.Len())
+	}
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("upstream saw %d connections, want 1", n)
+	}
+}
+
+func Test_StreamMux_Close(t *testing.T) {
+	var conns atomic.Int32
+	srv := muxUpstream(t, &conns)
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, TLS: TLSConfig{InsecureSkipVerify: true}})
+	mux := NewStreamMux(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	a, err := mux.Subscribe(ctx, Request{Path: "/topic/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := mux.SubscribePost(context.Background(), PostRequest{Request: Request{Path: "/topic/b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cancelling a subscription's context ends it alone
+	cancel()
+	select {
+	case <-a.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription did not end with its context")
+	}
+	select {
+	case <-b.Done():
+		t.Fatal("cancelling a ended b")
+	default:
+	}
+
+	if err := mux.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-b.Done():
+	default:
+		t.Fatal("Close returned with b still open")
+	}
+	for range b.Events {
+	}
+	if _, err := mux.Subscribe(context.Background(), Request{Path: "/topic/c"}); err != ErrStreamMuxClosed {
+		t.Fatalf("Subscribe after Close: err = %v", err)
+	}
+}
+
+func Test_StreamMux_OpenFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, "slow down")
+	}))
+	defer srv.Close()
+
+	mux := NewStreamMux(newTestClient(t, srv.URL))
+	defer mux.Close()
+	if _, err := mux.Subscribe(context.Background(), Request{Path: "/"}); StatusOf(err) != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want a 429", err)
+	}
+	if mux.Len() != 0 {
+		t.Fatalf("Len = %d after a failed Subscribe", mux.Len())
+	}
+}
@@ -0,0 +1,209 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeouts bounds each phase of a call. Zero leaves a phase unbounded, or
+// to the transport's own limit for Connect, TLS, and ResponseHeader. The
+// phases nest: dialing, the TLS handshake, and the wait for headers happen
+// within an attempt, and every attempt, backoff, and reading the body
+// within Total.
+type Timeouts struct {
+	// Connect bounds dialing.
+	Connect time.Duration `json:"connect"`
+	// TLS bounds the TLS handshake. Defaults to Connect.
+	TLS time.Duration `json:"tls"`
+	// ResponseHeader bounds the wait for response headers once the request
+	// is sent.
+	ResponseHeader time.Duration `json:"response_header"`
+	// Attempt bounds each try until its response headers arrive, sending
+	// the request body included. An attempt that runs out fails with
+	// ErrAttemptTimeout, which DefaultRetryOn retries. Upgrade handshakes
+	// (WebSocket) are bounded by Connect and ResponseHeader alone.
+	Attempt time.Duration `json:"attempt"`
+	// Total is the default deadline of a buffered call, covering every
+	// attempt, the backoff between them, and reading the body;
+	// Request.Timeout overrides it. Streams are not bounded by it.
+	Total time.Duration `json:"total"`
+	// StreamIdle ends or reconnects a stream that receives nothing for this
+	// long, like StreamConfig.IdleTimeout.
+	StreamIdle time.Duration `json:"stream_idle"`
+}
+
+// ErrAttemptTimeout is the error of an attempt that ran out of
+// Timeouts.Attempt while the call itself still had time.
+var ErrAttemptTimeout = errors.New("attempt timeout")
+
+// Validate reports a negative timeout, or one that cannot elapse because
+// the timeout containing it is shorter: an Attempt over Total, or a
+// Connect, TLS, or ResponseHeader over Attempt or Total. The shorter bound
+// wins either way.
+func (t Timeouts) Validate() error {
+	phases := []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"connect", t.Connect},
+		{"tls", t.TLS},
+		{"response_header", t.ResponseHeader},
+		{"attempt", t.Attempt},
+		{"total", t.Total},
+		{"stream_idle", t.StreamIdle},
+	}
+	for _, p := range phases {
+		if p.timeout < 0 {
+			return fmt.Errorf("invalid timeouts: %s is negative", p.name)
+		}
+	}
+	if t.Attempt > 0 && t.Total > 0 && t.Attempt > t.Total {
+		return fmt.Errorf("invalid timeouts: attempt %s exceeds total %s", t.Attempt, t.Total)
+	}
+	for _, p := range phases[:3] {
+		if t.Attempt > 0 && p.timeout > t.Attempt {
+			return fmt.Errorf("invalid timeouts: %s %s exceeds attempt %s", p.name, p.timeout, t.Attempt)
+		}
+		if t.Total > 0 && p.timeout > t.Total {
+			return fmt.Errorf("invalid timeouts: %s %s exceeds total %s", p.name, p.timeout, t.Total)
+		}
+	}
+	return nil
+}
+
+// timeouts returns config.Timeouts with the phases it leaves unset taken
+// from the single timeouts that predate it.
+func (c Config) timeouts() Timeouts {
+	t := c.Timeouts
+	if t.Connect == 0 {
+		t.Connect = c.ConnectTimeout
+	}
+	if t.TLS == 0 {
+		t.TLS = t.Connect
+	}
+	if t.ResponseHeader == 0 {
+		t.ResponseHeader = c.ResponseHeaderTimeout
+	}
+	if t.Total == 0 {
+		t.Total = c.RequestTimeout
+	}
+	if t.StreamIdle == 0 {
+		t.StreamIdle = c.Stream.IdleTimeout
+	}
+	return t
+}
+
+// defaultKeepAlive matches the keep-alive net/http's default transport dials
+// with.
+const defaultKeepAlive = 30 * time.Second
+
+// withTransport returns a copy of base whose transport is a clone of base's
+// with fn applied, leaving base and its connection pool untouched. A
+// transport that is not an *http.Transport (e.g. a test stub) cannot be
+// tuned, and base is returned as is.
+func withTransport(base *http.Client, fn func(*http.Transport)) *http.Client {
+	rt := base.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return base
+	}
+	clone := transport.Clone()
+	fn(clone)
+	client := *base
+	client.Transport = clone
+	return &client
+}
+
+// applyTimeouts sets the connect, TLS handshake, and response-header
+// timeouts of config on the client's transport.
+func applyTimeouts(base *http.Client, config Config) *http.Client {
+	timeouts := config.timeouts()
+	if timeouts.Connect <= 0 && timeouts.TLS <= 0 && timeouts.ResponseHeader <= 0 {
+		return base
+	}
+	return withTransport(base, func(t *http.Transport) {
+		if timeouts.ResponseHeader > 0 {
+			t.ResponseHeaderTimeout = timeouts.ResponseHeader
+		}
+		if timeouts.TLS > 0 {
+			t.TLSHandshakeTimeout = timeouts.TLS
+		}
+		if timeouts.Connect > 0 {
+			dial := t.DialContext
+			if dial == nil {
+				dial = (&net.Dialer{KeepAlive: defaultKeepAlive}).DialContext
+			}
+			timeout := timeouts.Connect
+			t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+				ctx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				return dial(ctx, network, address)
+			}
+		}
+	})
+}
+
+// sendAttempt sends one attempt of req, bounded by Timeouts.Attempt until
+// its headers arrive. The attempt's context lives on until the response
+// body is closed, so reading it is left to the call's own deadline.
+func (h httpClient) sendAttempt(client *http.Client, req *http.Request) (*http.Response, error) {
+	timeout := h.attemptTimeout
+	if timeout <= 0 || req.Header.Get("Upgrade") != "" {
+		return h.send(client, req)
+	}
+	parent := req.Context()
+	ctx, cancel := context.WithCancel(parent)
+	timer := time.AfterFunc(timeout, cancel)
+	resp, err := h.send(client, req.WithContext(ctx))
+	if !timer.Stop() && parent.Err() == nil {
+		// the attempt ran out, even if headers arrived just in time
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("%w after %s", ErrAttemptTimeout, timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// withTimeout bounds a buffered call by req.Timeout, or Timeouts.Total when
+// the request sets none. The deadline covers every attempt and reading the
+// body.
+func (h httpClient) withTimeout(ctx context.Context, req Request) (context.Context, context.CancelFunc) {
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = h.requestTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// cancelOnClose releases a call's deadline once the caller closes the live
+// body of a streamed Response, rather than when the call returns.
+type cancelOnClose struct {
+	io.ReadCloser
+	once   sync.Once
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.once.Do(c.cancel)
+	return err
+}
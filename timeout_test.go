@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		_, _ = io.WriteString(w, "late")
+	}))
+}
+
+func Test_Client_RequestTimeout(t *testing.T) {
+	srv := slowServer(t, 300*time.Millisecond)
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, RequestTimeout: 50 * time.Millisecond})
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("default deadline: err = %v", err)
+	}
+	// the request overrides the default, and negative disables it
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/", Timeout: -1}})
+	if err != nil || string(resp.Body) != "late" {
+		t.Fatalf("no deadline: resp=%v err=%v", resp, err)
+	}
+	if _, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{Request: Request{Path: "/", Timeout: 50 * time.Millisecond}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request deadline: err = %v", err)
+	}
+}
+
+func Test_Client_RequestTimeout_StreamedBodyOutlivesCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "body")
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{Request: Request{Path: "/", Stream: true, Timeout: time.Second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Close()
+	if body, err := io.ReadAll(resp); err != nil || string(body) != "body" {
+		t.Fatalf("body=%q err=%v", body, err)
+	}
+}
+
+func Test_Client_ResponseHeaderTimeout(t *testing.T) {
+	srv := slowServer(t, 300*time.Millisecond)
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, ResponseHeaderTimeout: 50 * time.Millisecond})
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err == nil {
+		t.Fatal("expected a response header timeout")
+	}
+}
+
+func Test_applyTimeouts_TunesClonedTransport(t *testing.T) {
+	base := &http.Client{Transport: &http.Transport{}}
+	tuned := applyTimeouts(base, Config{ConnectTimeout: time.Second, ResponseHeaderTimeout: 2 * time.Second})
+	transport := tuned.Transport.(*http.Transport)
+	if transport.DialContext == nil || transport.TLSHandshakeTimeout != time.Second || transport.ResponseHeaderTimeout != 2*time.Second {
+		t.Fatalf("transport not tuned: %+v", transport)
+	}
+	if base.Transport.(*http.Transport).ResponseHeaderTimeout != 0 {
+		t.Fatal("base transport mutated")
+	}
+	if applyTimeouts(base, Config{}) != base {
+		t.Fatal("no timeouts should keep the base client")
+	}
+}
+
+func Test_applyTimeouts_TLSApartFromConnect(t *testing.T) {
+	base := &http.Client{Transport: &http.Transport{}}
+	tuned := applyTimeouts(base, Config{Timeouts: Timeouts{Connect: time.Second, TLS: 3 * time.Second}})
+	if transport := tuned.Transport.(*http.Transport); transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Fatalf("TLSHandshakeTimeout = %s", transport.TLSHandshakeTimeout)
+	}
+}
+
+func Test_Config_timeouts_FillsFromSingleTimeouts(t *testing.T) {
+	got := Config{
+		ConnectTimeout: time.Second,
+		RequestTimeout: time.Minute,
+		Stream:         StreamConfig{IdleTimeout: 5 * time.Second},
+		Timeouts:       Timeouts{ResponseHeader: 2 * time.Second, Total: 30 * time.Second},
+	}.timeouts()
+	want := Timeouts{Connect: time.Second, TLS: time.Second, ResponseHeader: 2 * time.Second, Total: 30 * time.Second, StreamIdle: 5 * time.Second}
+	if got != want {
+		t.Fatalf("timeouts = %+v, want %+v", got, want)
+	}
+}
+
+func Test_Timeouts_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts Timeouts
+		valid    bool
+	}{
+		{"zero", Timeouts{}, true},
+		{"nested", Timeouts{Connect: time.Second, TLS: time.Second, ResponseHeader: 2 * time.Second, Attempt: 5 * time.Second, Total: 20 * time.Second}, true},
+		{"attempt without total", Timeouts{Connect: time.Second, Attempt: 5 * time.Second}, true},
+		{"negative", Timeouts{StreamIdle: -time.Second}, false},
+		{"attempt over total", Timeouts{Attempt: 10 * time.Second, Total: 5 * time.Second}, false},
+		{"connect over attempt", Timeouts{Connect: 10 * time.Second, Attempt: 5 * time.Second}, false},
+		{"response header over total", Timeouts{ResponseHeader: 10 * time.Second, Total: 5 * time.Second}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.timeouts.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func Test_Client_AttemptTimeout_Retries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{
+		BaseURL:  srv.URL,
+		Retry:    RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond},
+		Timeouts: Timeouts{Attempt: 50 * time.Millisecond, Total: 5 * time.Second},
+	})
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	if err != nil || string(resp.Body) != "ok" {
+		t.Fatalf("resp=%v err=%v", resp, err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2", calls)
+	}
+
+	c = NewClient(Config{BaseURL: srv.URL, Timeouts: Timeouts{Attempt: 50 * time.Millisecond}})
+	atomic.StoreInt32(&calls, 0)
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); !errors.Is(err, ErrAttemptTimeout) {
+		t.Fatalf("err = %v, want ErrAttemptTimeout", err)
+	}
+}
+
+func Test_Client_AttemptTimeout_BodyOutlivesAttempt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, "slow body")
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Timeouts: Timeouts{Attempt: 50 * time.Millisecond}})
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	if err != nil || string(resp.Body) != "slow body" {
+		t.Fatalf("resp=%v err=%v", resp, err)
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig configures the TLS side of the client's transport from files,
+// so it can live in JSON config.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of extra roots trusted alongside the system
+	// ones, e.g. an internal CA.
+	CAFile string `json:"ca_file"`
+	// CertFile and KeyFile are the PEM client certificate and key sent for
+	// mutual TLS.
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// InsecureSkipVerify accepts any server certificate. For local
+	// development only.
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+func (c TLSConfig) empty() bool {
+	return c == TLSConfig{}
+}
+
+// build loads the files c names into a *tls.Config.
+func (c TLSConfig) build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		//nolint:gosec // opt-in, documented as development only
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCert issues a client certificate from a fresh CA and writes the
+// certificate and key to dir. It returns the CA pool for the server.
+func writeClientCert(t *testing.T, dir string) (*x509.CertPool, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	_ = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	_ = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return pool, certFile, keyFile
+}
+
+func Test_Client_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCAs, certFile, keyFile := writeClientCert(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "server-ca.pem")
+	_ = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600)
+
+	c := NewClient(Config{BaseURL: srv.URL, TLS: TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}})
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	if err != nil || string(resp.Body) != "test-client" {
+		t.Fatalf("resp=%v err=%v", resp, err)
+	}
+
+	// without the certificate the handshake fails
+	c = NewClient(Config{BaseURL: srv.URL, TLS: TLSConfig{CAFile: caFile}})
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err == nil {
+		t.Fatal("expected the server to reject a client without a certificate")
+	}
+}
+
+func Test_TLSConfig_BadFiles(t *testing.T) {
+	if _, err := (TLSConfig{CAFile: "/nonexistent.pem"}).build(); err == nil {
+		t.Fatal("expected an error for a missing CA file")
+	}
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	_ = os.WriteFile(empty, []byte("not a cert"), 0o600)
+	if _, err := (TLSConfig{CAFile: empty}).build(); err == nil {
+		t.Fatal("expected an error for a CA file without certificates")
+	}
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// W3C trace context headers (https://www.w3.org/TR/trace-context/,
+// https://www.w3.org/TR/baggage/).
+const (
+	TraceParentHeaderName = "traceparent"
+	TraceStateHeaderName  = "tracestate"
+	BaggageHeaderName     = "baggage"
+)
+
+// tracerName identifies the client's instrumentation to a TracerProvider.
+const tracerName = "github.com/toaweme/http"
+
+// TraceContext is a W3C trace context: the identity of one span.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Flags carries the trace flags; bit 0 is "sampled".
+	Flags byte
+	// State is the opaque tracestate header, passed through untouched.
+	State string
+}
+
+// IsValid reports whether both ids are non-zero, as the spec requires.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// TraceParent renders tc as a version 00 traceparent header value.
+func (tc TraceContext) TraceParent() string {
+	return "00-" + hex.EncodeToString(tc.TraceID[:]) + "-" + hex.EncodeToString(tc.SpanID[:]) + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// ParseTraceParent parses a traceparent header value. ok is false for
+// malformed or all-zero ids.
+func ParseTraceParent(s string) (tc TraceContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return TraceContext{}, false
+	}
+	var flags [1]byte
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return TraceContext{}, false
+	}
+	if _, err := hex.Decode(tc.TraceID[:], []byte(parts[1])); err != nil {
+		return TraceContext{}, false
+	}
+	if _, err := hex.Decode(tc.SpanID[:], []byte(parts[2])); err != nil {
+		return TraceContext{}, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return TraceContext{}, false
+	}
+	tc.Flags = flags[0]
+	return tc, tc.IsValid()
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying tc. With tracing enabled,
+// client spans started from the returned context are children of tc.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceFromContext returns the trace context stored by ContextWithTrace.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// Span is the slice of a tracing span the client drives. It mirrors the
+// OpenTelemetry span API, so adapting an OTel tracer takes a few lines and
+// the client itself stays free of the SDK.
+type Span interface {
+	SpanContext() TraceContext
+	// SetAttributes records key/value pairs, as logger args.
+	SetAttributes(kv ...any)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. The returned context carries the new span.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// TracerProvider hands out named tracers, like its OTel namesake.
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+// WithTracerProvider traces every request through tp: a client span wraps
+// each call and its context is sent as traceparent. It takes precedence over
+// Config.Tracing.
+func WithTracerProvider(tp TracerProvider) Option {
+	return func(h *httpClient) {
+		if tp != nil {
+			h.tracer = tp.Tracer(tracerName)
+		}
+	}
+}
+
+// RequestMetrics summarizes one Get/Post/Put/Patch/Delete call, or the
+// opening of a stream.
+type RequestMetrics struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// requestSpan instruments one call: the optional tracing span and the
+// MetricsHook.OnRequest report.
+type requestSpan struct {
+	span  Span
+	hook  func(RequestMetrics)
+	start time.Time
+	m     RequestMetrics
+}
+
+// startSpan opens the client span for a call, parenting it to the trace in
+// ctx or, failing that, to a traceparent the context headers supply (an
+// inbound request's, via the server package's TraceHeaders).
+func (h httpClient) startSpan(ctx context.Context, method string, req Request) (context.Context, *requestSpan) {
+	s := &requestSpan{
+		hook:  h.metrics.OnRequest,
+		start: time.Now(),
+		m:     RequestMetrics{Method: method, URL: req.Path},
+	}
+	if h.tracer == nil {
+		return ctx, s
+	}
+	if _, ok := TraceFromContext(ctx); !ok && h.contextHeaders != nil {
+		headers := h.contextHeaders(ctx)
+		if parent, ok := ParseTraceParent(headers[TraceParentHeaderName]); ok {
+			parent.State = headers[TraceStateHeaderName]
+			ctx = ContextWithTrace(ctx, parent)
+		}
+	}
+
+	ctx, s.span = h.tracer.Start(ctx, "HTTP "+method)
+	s.span.SetAttributes("http.request.method", method, "url.full", h.spanURL(req.Path))
+	return ContextWithTrace(ctx, s.span.SpanContext()), s
+}
+
+func (s *requestSpan) end(status int, err error) {
+	s.m.StatusCode, s.m.Err = status, err
+	s.m.Duration = time.Since(s.start)
+	if s.span != nil {
+		if status != 0 {
+			s.span.SetAttributes("http.response.status_code", status)
+		}
+		if err != nil {
+			s.span.RecordError(err)
+		}
+		s.span.End()
+	}
+	if s.hook != nil {
+		s.hook(s.m)
+	}
+}
+
+// spanURL resolves path against the base URL and drops any userinfo.
+func (h httpClient) spanURL(path string) string {
+	full := path
+	if h.baseURL != "" {
+		if joined, err := url.JoinPath(h.baseURL, path); err == nil {
+			full = joined
+		}
+	}
+	if u, err := url.Parse(full); err == nil && u.User != nil {
+		u.User = nil
+		return u.String()
+	}
+	return full
+}
+
+// traceHeaders returns the trace context headers for ctx: traceparent and
+// tracestate, and the client identity as baggage.
+func traceHeaders(ctx context.Context, headers map[string]string) map[string]string {
+	out := make(map[string]string, 3)
+	if tc, ok := TraceFromContext(ctx); ok {
+		out[TraceParentHeaderName] = tc.TraceParent()
+		if tc.State != "" {
+			out[TraceStateHeaderName] = tc.State
+		}
+	}
+	var baggage []string
+	for _, kv := range [...]struct{ key, header string }{
+		{"client.platform", ClientPlatformHeaderName},
+		{"client.version", ClientAppVersionHeaderName},
+		{"client.client_id", ClientIDHeaderName},
+		{"client.session_id", ClientSessionIDHeaderName},
+	} {
+		if v := headers[kv.header]; v != "" {
+			baggage = append(baggage, kv.key+"="+url.PathEscape(v))
+		}
+	}
+	if len(baggage) > 0 {
+		out[BaggageHeaderName] = strings.Join(baggage, ",")
+	}
+	return out
+}
+
+// logTracer is the built-in tracer behind Config.Tracing: it generates W3C
+// ids and writes each finished span to the logger at Debug.
+type logTracer struct {
+	logger Logger
+}
+
+func (t logTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &logSpan{logger: t.logger, name: name, start: time.Now()}
+	if parent, ok := TraceFromContext(ctx); ok {
+		s.tc.TraceID, s.tc.Flags, s.tc.State = parent.TraceID, parent.Flags, parent.State
+		s.parent = parent.SpanID
+	} else {
+		_, _ = rand.Read(s.tc.TraceID[:])
+		s.tc.Flags = 1
+	}
+	_, _ = rand.Read(s.tc.SpanID[:])
+	return ContextWithTrace(ctx, s.tc), s
+}
+
+type logSpan struct {
+	logger Logger
+	name   string
+	start  time.Time
+	tc     TraceContext
+	parent [8]byte
+
+	mu    sync.Mutex
+	attrs []any
+	err   error
+}
+
+func (s *logSpan) SpanContext() TraceContext { return s.tc }
+
+func (s *logSpan) SetAttributes(kv ...any) {
+	s.mu.Lock()
+	s.attrs = append(s.attrs, kv...)
+	s.mu.Unlock()
+}
+
+func (s *logSpan) RecordError(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+func (s *logSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	args := []any{"type", "span", "name", s.name,
+		"trace-id", hex.EncodeToString(s.tc.TraceID[:]),
+		"span-id", hex.EncodeToString(s.tc.SpanID[:]),
+		"duration", time.Since(s.start)}
+	if s.parent != [8]byte{} {
+		args = append(args, "parent-id", hex.EncodeToString(s.parent[:]))
+	}
+	if s.err != nil {
+		args = append(args, "error", s.err)
+	}
+	s.logger.Debug("http-client", append(args, s.attrs...)...)
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_ParseTraceParent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tc, ok := ParseTraceParent(valid)
+	if !ok || tc.Flags != 1 || tc.TraceParent() != valid {
+		t.Fatalf("round trip: %+v %v", tc, ok)
+	}
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-xyz92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceParent(bad); ok {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+	// future versions may append fields
+	if _, ok := ParseTraceParent(valid[2:] + "-ab"); ok {
+		t.Error("version-less value parsed")
+	}
+	if _, ok := ParseTraceParent("01" + valid[2:] + "-ab"); !ok {
+		t.Error("future version rejected")
+	}
+}
+
+func Test_Client_Tracing_PropagatesTraceContext(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	logger := &recordingLogger{}
+	var metrics []RequestMetrics
+	c := NewClient(Config{BaseURL: srv.URL, Tracing: true, Platform: "cli", ClientID: "abc"},
+		WithLogger(logger),
+		WithMetricsHook(MetricsHook{OnRequest: func(m RequestMetrics) { metrics = append(metrics, m) }}))
+
+	parent, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.State = "vendor=1"
+	if _, err := c.Get(ContextWithTrace(context.Background(), parent), GetRequest{Request: Request{Path: "/x"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent, ok := ParseTraceParent(got.Get(TraceParentHeaderName))
+	if !ok || sent.TraceID != parent.TraceID || sent.SpanID == parent.SpanID {
+		t.Fatalf("traceparent = %q, want a child of %s", got.Get(TraceParentHeaderName), parent.TraceParent())
+	}
+	if got.Get(TraceStateHeaderName) != "vendor=1" {
+		t.Fatalf("tracestate = %q", got.Get(TraceStateHeaderName))
+	}
+	if b := got.Get(BaggageHeaderName); !strings.Contains(b, "client.platform=cli") || !strings.Contains(b, "client.client_id=abc") {
+		t.Fatalf("baggage = %q", b)
+	}
+	if logger.debugs == 0 {
+		t.Fatal("span not logged")
+	}
+	if len(metrics) != 1 || metrics[0].StatusCode != http.StatusOK || metrics[0].Method != http.MethodGet {
+		t.Fatalf("metrics = %+v", metrics)
+	}
+}
+
+func Test_Client_Tracing_OffByDefault(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(t, srv.URL).Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get(TraceParentHeaderName) != "" || got.Get(BaggageHeaderName) != "" {
+		t.Fatalf("trace headers sent without tracing: %v", got)
+	}
+}
+
+type fakeTracer struct{ spans []*fakeSpan }
+
+func (f *fakeTracer) Tracer(string) Tracer { return f }
+
+func (f *fakeTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &fakeSpan{name: name}
+	s.tc.TraceID[0], s.tc.SpanID[0] = 1, byte(len(f.spans)+1)
+	f.spans = append(f.spans, s)
+	return ctx, s
+}
+
+type fakeSpan struct {
+	name  string
+	tc    TraceContext
+	attrs []any
+	err   error
+	ended bool
+}
+
+func (s *fakeSpan) SpanContext() TraceContext { return s.tc }
+func (s *fakeSpan) SetAttributes(kv ...any)   { s.attrs = append(s.attrs, kv...) }
+func (s *fakeSpan) RecordError(err error)     { s.err = err }
+func (s *fakeSpan) End()                      { s.ended = true }
+
+func Test_Client_WithTracerProvider(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(TraceParentHeaderName)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	tracer := &fakeTracer{}
+	c := newTestClient(t, srv.URL, WithTracerProvider(tracer))
+	if _, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(tracer.spans) != 1 || !tracer.spans[0].ended || tracer.spans[0].name != "HTTP GET" {
+		t.Fatalf("spans = %+v", tracer.spans)
+	}
+	if got != tracer.spans[0].tc.TraceParent() {
+		t.Fatalf("traceparent = %q, want %q", got, tracer.spans[0].tc.TraceParent())
+	}
+	attrs := tracer.spans[0].attrs
+	if attrs[len(attrs)-2] != "http.response.status_code" || attrs[len(attrs)-1] != http.StatusTeapot {
+		t.Fatalf("attrs = %v", attrs)
+	}
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func trailerServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		_, _ = io.WriteString(w, "payload")
+		w.Header().Set("X-Checksum", "abc")
+		// undeclared trailers use the TrailerPrefix convention
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	}))
+}
+
+func Test_Client_ResponseTrailers(t *testing.T) {
+	srv := trailerServer()
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	resp, err := c.Get(context.Background(), GetRequest{Request: Request{Path: "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Trailers.Get("X-Checksum") != "abc" || resp.Trailers.Get("Grpc-Status") != "0" {
+		t.Fatalf("buffered trailers = %v", resp.Trailers)
+	}
+
+	resp, err = c.Get(context.Background(), GetRequest{Request: Request{Path: "/", Stream: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Close()
+	if resp.Trailers.Get("X-Checksum") != "" {
+		t.Fatal("trailers available before the body was read")
+	}
+	if _, err := io.ReadAll(resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Trailers.Get("X-Checksum") != "abc" || resp.Trailers.Get("Grpc-Status") != "0" {
+		t.Fatalf("streamed trailers = %v", resp.Trailers)
+	}
+}
+
+// hashingReader fills the checksum trailer as the body is read.
+type hashingReader struct {
+	r        io.Reader
+	h        hash.Hash
+	trailers http.Header
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.h.Write(p[:n])
+	if err == io.EOF {
+		h.trailers.Set("X-Content-Sha256", hex.EncodeToString(h.h.Sum(nil)))
+	}
+	return n, err
+}
+
+func Test_Client_RequestTrailers(t *testing.T) {
+	var gotTrailer, gotEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		gotTrailer = r.Trailer.Get("X-Content-Sha256")
+		gotEncoding = strings.Join(r.TransferEncoding, ",")
+	}))
+	defer srv.Close()
+
+	trailers := http.Header{"X-Content-Sha256": nil}
+	body := &hashingReader{r: strings.NewReader("upload"), h: sha256.New(), trailers: trailers}
+	_, err := newTestClient(t, srv.URL).Post(context.Background(), PostRequest{Request: Request{Path: "/", BodyReader: body, Trailers: trailers}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("upload"))
+	if gotTrailer != hex.EncodeToString(sum[:]) || gotEncoding != "chunked" {
+		t.Fatalf("trailer=%q encoding=%q", gotTrailer, gotEncoding)
+	}
+}
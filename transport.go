@@ -0,0 +1,98 @@
+package http
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxIdleConnsPerHost replaces net/http's default of 2 idle
+// connections per host on the transport NewClient builds, which throttles
+// clients that talk to one API at high concurrency.
+const DefaultMaxIdleConnsPerHost = 32
+
+// TransportConfig tunes the connection pool and protocol of the client's own
+// transport. Zero fields keep net/http's defaults, except MaxIdleConnsPerHost.
+type TransportConfig struct {
+	// MaxIdleConns caps idle connections across all hosts.
+	MaxIdleConns int `json:"max_idle_conns"`
+	// MaxIdleConnsPerHost caps idle connections kept per host. Defaults to
+	// DefaultMaxIdleConnsPerHost.
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host"`
+	// MaxConnsPerHost caps all connections per host, dialing, active, and
+	// idle; requests over the cap wait. 0 means no cap.
+	MaxConnsPerHost int `json:"max_conns_per_host"`
+	// IdleConnTimeout closes connections idle this long.
+	IdleConnTimeout time.Duration `json:"idle_conn_timeout"`
+	// KeepAlive is the TCP keep-alive probe interval; negative disables
+	// probes.
+	KeepAlive time.Duration `json:"keep_alive"`
+	// DisableKeepAlives opens a new connection for every request.
+	DisableKeepAlives bool `json:"disable_keep_alives"`
+	// ForceHTTP2 attempts HTTP/2 even with a custom TLS config or dialer.
+	ForceHTTP2 bool `json:"force_http2"`
+	// DisableHTTP2 keeps the client on HTTP/1.1.
+	DisableHTTP2 bool `json:"disable_http2"`
+	// Multiplex keeps one connection per host and runs concurrent calls as
+	// HTTP/2 streams over it, e.g. the subscriptions of a StreamMux against
+	// a provider with a strict connection limit. It implies ForceHTTP2 and
+	// MaxConnsPerHost 1, so against an HTTP/1.1-only host concurrent calls
+	// wait for each other.
+	Multiplex bool `json:"multiplex"`
+}
+
+// apply sets c on t.
+func (c TransportConfig) apply(t *http.Transport) {
+	t.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if c.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	}
+	if c.MaxIdleConns > 0 {
+		t.MaxIdleConns = c.MaxIdleConns
+	}
+	if c.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = c.MaxConnsPerHost
+	}
+	if c.Multiplex && !c.DisableHTTP2 {
+		t.MaxConnsPerHost = 1
+		t.ForceAttemptHTTP2 = true
+	}
+	if c.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = c.IdleConnTimeout
+	}
+	if c.KeepAlive != 0 {
+		t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: c.KeepAlive}).DialContext
+	}
+	t.DisableKeepAlives = c.DisableKeepAlives
+	switch {
+	case c.DisableHTTP2:
+		// a non-nil empty map turns off the transport's HTTP/2 upgrade
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	case c.ForceHTTP2:
+		t.ForceAttemptHTTP2 = true
+	}
+}
+
+// ClientStats is a snapshot of a client's load.
+type ClientStats struct {
+	// InFlight counts unfinished calls: buffered requests, unread streamed
+	// bodies, event streams, and open WebSockets.
+	InFlight int
+	PoolStats
+}
+
+// StatsProvider is implemented by clients built with NewClient:
+//
+//	if s, ok := client.(http.StatsProvider); ok { stats := s.Stats() }
+type StatsProvider interface {
+	Stats() ClientStats
+}
+
+var _ StatsProvider = httpClient{}
+
+// Stats implements StatsProvider.
+func (h httpClient) Stats() ClientStats {
+	return ClientStats{InFlight: h.inflight.count(), PoolStats: h.PoolStats()}
+}
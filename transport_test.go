@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_NewClient_OwnTransport(t *testing.T) {
+	c := NewClient(Config{Transport: TransportConfig{MaxIdleConns: 7, MaxConnsPerHost: 3, IdleConnTimeout: time.Second}}).(httpClient)
+	transport, ok := c.client.Transport.(*http.Transport)
+	if !ok || transport == http.DefaultTransport {
+		t.Fatalf("transport = %T, want a dedicated *http.Transport", c.client.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || transport.MaxIdleConns != 7 || transport.MaxConnsPerHost != 3 || transport.IdleConnTimeout != time.Second {
+		t.Fatalf("transport not tuned: %+v", transport)
+	}
+	if http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost == DefaultMaxIdleConnsPerHost {
+		t.Fatal("http.DefaultTransport was modified")
+	}
+
+	// a caller's client is left alone
+	own := &http.Client{}
+	if got := NewClient(Config{}, WithHTTPClient(own)).(httpClient).client; got != own {
+		t.Fatalf("client = %p, want the one passed in", got)
+	}
+}
+
+func Test_TransportConfig_HTTP2(t *testing.T) {
+	var proto int
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.ProtoMajor
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name      string
+		transport TransportConfig
+		want      int
+	}{
+		{"default", TransportConfig{}, 2},
+		{"disabled", TransportConfig{DisableHTTP2: true}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(Config{BaseURL: srv.URL, Transport: tc.transport, TLS: TLSConfig{InsecureSkipVerify: true}})
+			if _, err := c.Get(context.Background(), GetRequest{}); err != nil {
+				t.Fatal(err)
+			}
+			if proto != tc.want {
+				t.Fatalf("HTTP/%d, want HTTP/%d", proto, tc.want)
+			}
+		})
+	}
+}
+
+func Test_Client_Stats(t *testing.T) {
+	arrived, finish := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-finish
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.Get(context.Background(), GetRequest{})
+	}()
+	<-arrived
+	stats := c.(StatsProvider).Stats()
+	if stats.InFlight != 1 || stats.InUse != 1 {
+		t.Fatalf("during call: %+v", stats)
+	}
+	close(finish)
+	<-done
+	if stats = c.(StatsProvider).Stats(); stats.InFlight != 0 || stats.InUse != 0 || stats.Requests != 1 {
+		t.Fatalf("after call: %+v", stats)
+	}
+}
@@ -0,0 +1,453 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// WebSocket handshake headers (RFC 6455).
+const (
+	WebSocketKeyHeaderName      = "Sec-WebSocket-Key"
+	WebSocketAcceptHeaderName   = "Sec-WebSocket-Accept"
+	WebSocketVersionHeaderName  = "Sec-WebSocket-Version"
+	WebSocketProtocolHeaderName = "Sec-WebSocket-Protocol"
+)
+
+// DefaultWebSocketMaxMessageBytes caps a reassembled incoming message.
+const DefaultWebSocketMaxMessageBytes = 16 << 20
+
+// websocketGUID is the fixed key suffix of the accept hash.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket message types, matching the frame opcodes.
+type WebSocketMessageType int
+
+const (
+	WebSocketText   WebSocketMessageType = 1
+	WebSocketBinary WebSocketMessageType = 2
+)
+
+// WebSocket close codes commonly sent or received.
+const (
+	WebSocketCloseNormal        = 1000
+	WebSocketCloseGoingAway     = 1001
+	WebSocketCloseProtocolError = 1002
+	WebSocketCloseNoStatus      = 1005
+	WebSocketCloseTooLarge      = 1009
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+var (
+	errWebSocketProtocol = errors.New("websocket: protocol error")
+	errWebSocketTooLarge = errors.New("websocket: message too large")
+	errWebSocketClosed   = errors.New("websocket: connection closed")
+)
+
+// WebSocketCloseError is returned by ReadMessage once the peer closes the
+// connection.
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// WebSocketConn is an established WebSocket connection. ReadMessage must be
+// called from one goroutine; WriteMessage and Close are safe for concurrent
+// use. Pings are answered while reading.
+type WebSocketConn struct {
+	rwc  io.ReadWriteCloser
+	br   *bufio.Reader
+	resp *http.Response
+
+	// MaxMessageBytes caps a reassembled message; 0 means
+	// DefaultWebSocketMaxMessageBytes.
+	MaxMessageBytes int
+
+	// server marks the accepting side, which reads masked frames and
+	// writes unmasked ones.
+	server bool
+	// onClose, when set, runs once the connection is closed.
+	onClose func()
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Response is the handshake response (status 101 and its headers), or nil
+// for a connection accepted by a bridge handler.
+func (c *WebSocketConn) Response() *http.Response { return c.resp }
+
+// Subprotocol is the protocol the server selected, if any.
+func (c *WebSocketConn) Subprotocol() string {
+	if c.resp == nil {
+		return ""
+	}
+	return c.resp.Header.Get(WebSocketProtocolHeaderName)
+}
+
+// WebSocket opens a WebSocket connection to req.Path. ws:// and wss:// are
+// accepted alongside http(s)://. The handshake carries the same headers as
+// any other request (defaults, context headers, request/session id) and goes
+// through the client's middleware and proxy selection.
+func (h httpClient) WebSocket(ctx context.Context, req Request) (*WebSocketConn, error) {
+	// a draining client closes connections still open at its deadline
+	ctx, cancel := context.WithCancel(ctx)
+	var open atomic.Pointer[WebSocketConn]
+	release, err := h.inflight.track(func() {
+		cancel()
+		if c := open.Load(); c != nil {
+			_ = c.CloseWithReason(WebSocketCloseGoingAway, "")
+		}
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	conn, err := h.dialWebSocket(ctx, req)
+	if err != nil {
+		release()
+		cancel()
+		return nil, err
+	}
+	conn.onClose = func() { release(); cancel() }
+	open.Store(conn)
+	return conn, nil
+}
+
+func (h httpClient) dialWebSocket(ctx context.Context, req Request) (*WebSocketConn, error) {
+	path, headers, err := h.buildRequestParams(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request URI: %w", err)
+	}
+	path = websocketHTTPURL(path)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, path, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Host = req.Host
+	for k, v := range headers {
+		httpReq.Header.Add(k, v)
+	}
+	key, err := websocketKey()
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Connection", "Upgrade")
+	httpReq.Header.Set("Upgrade", "websocket")
+	httpReq.Header.Set(WebSocketVersionHeaderName, "13")
+	httpReq.Header.Set(WebSocketKeyHeaderName, key)
+
+	if h.egress != nil {
+		if err := h.egress.check(httpReq.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.authenticate(httpReq); err != nil {
+		return nil, fmt.Errorf("failed to authenticate request: %w", err)
+	}
+
+	h.logger.Trace("http-client", "type", "websocket", "url", h.redact.url(path), "headers", h.redact.headerMap(headers))
+
+	client, httpReq, err := h.clientFor(httpReq, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select transport: %w", err)
+	}
+	// the connection outlives any whole-request timeout
+	noTimeout := *client
+	noTimeout.Timeout = 0
+
+	resp, err := h.roundTrip(&noTimeout, httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: body, Headers: resp.Header}
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") || resp.Header.Get(WebSocketAcceptHeaderName) != websocketAccept(key) {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: invalid handshake response", errWebSocketProtocol)
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: transport does not support upgrades", errWebSocketProtocol)
+	}
+	return &WebSocketConn{rwc: rwc, br: bufio.NewReader(rwc), resp: resp}, nil
+}
+
+// ReadMessage returns the next text or binary message. Once the peer closes,
+// it replies in kind and returns a *WebSocketCloseError.
+func (c *WebSocketConn) ReadMessage() (WebSocketMessageType, []byte, error) {
+	limit := c.MaxMessageBytes
+	if limit <= 0 {
+		limit = DefaultWebSocketMaxMessageBytes
+	}
+	var (
+		typ     WebSocketMessageType
+		message []byte
+	)
+	for {
+		fin, op, payload, err := readWebSocketFrame(c.br, c.server, limit)
+		if err != nil {
+			c.failOn(err)
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			cerr := parseWebSocketClose(payload)
+			_ = c.closeWith(cerr.Code, "")
+			return 0, nil, cerr
+		case opText, opBinary:
+			if typ != 0 {
+				c.failOn(errWebSocketProtocol)
+				return 0, nil, errWebSocketProtocol
+			}
+			typ, message = WebSocketMessageType(op), payload
+		case opContinuation:
+			if typ == 0 {
+				c.failOn(errWebSocketProtocol)
+				return 0, nil, errWebSocketProtocol
+			}
+			message = append(message, payload...)
+		default:
+			c.failOn(errWebSocketProtocol)
+			return 0, nil, errWebSocketProtocol
+		}
+		if len(message) > limit {
+			c.failOn(errWebSocketTooLarge)
+			return 0, nil, errWebSocketTooLarge
+		}
+		if fin {
+			if typ == WebSocketText && !utf8.Valid(message) {
+				c.failOn(errWebSocketProtocol)
+				return 0, nil, errWebSocketProtocol
+			}
+			return typ, message, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single text or binary frame.
+func (c *WebSocketConn) WriteMessage(typ WebSocketMessageType, data []byte) error {
+	if typ != WebSocketText && typ != WebSocketBinary {
+		return fmt.Errorf("websocket: invalid message type %d", typ)
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+// Close sends a normal close frame and closes the connection.
+func (c *WebSocketConn) Close() error {
+	return c.closeWith(WebSocketCloseNormal, "")
+}
+
+// CloseWithReason sends a close frame with code and reason, then closes the
+// connection.
+func (c *WebSocketConn) CloseWithReason(code int, reason string) error {
+	return c.closeWith(code, reason)
+}
+
+func (c *WebSocketConn) closeWith(code int, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.onClose != nil {
+		defer c.onClose()
+	}
+	var payload []byte
+	if code != WebSocketCloseNoStatus {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	writeErr := writeWebSocketFrame(c.rwc, opClose, payload, !c.server)
+	if err := c.rwc.Close(); err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// failOn closes the connection after a read error, telling the peer why
+// when the error is ours to report.
+func (c *WebSocketConn) failOn(err error) {
+	switch {
+	case errors.Is(err, errWebSocketTooLarge):
+		_ = c.closeWith(WebSocketCloseTooLarge, "")
+	case errors.Is(err, errWebSocketProtocol):
+		_ = c.closeWith(WebSocketCloseProtocolError, "")
+	default:
+		_ = c.closeWith(WebSocketCloseNoStatus, "")
+	}
+}
+
+func (c *WebSocketConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errWebSocketClosed
+	}
+	return writeWebSocketFrame(c.rwc, op, payload, !c.server)
+}
+
+// readWebSocketFrame reads one frame. Clients must mask, servers must not.
+func readWebSocketFrame(r io.Reader, wantMasked bool, limit int) (fin bool, op byte, payload []byte, err error) {
+	var hdr [8]byte
+	if _, err = io.ReadFull(r, hdr[:2]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = hdr[0]&0x80 != 0
+	op = hdr[0] & 0x0f
+	masked := hdr[1]&0x80 != 0
+	if hdr[0]&0x70 != 0 || masked != wantMasked {
+		return false, 0, nil, errWebSocketProtocol
+	}
+
+	n := uint64(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		if _, err = io.ReadFull(r, hdr[:2]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(hdr[:2]))
+	case 127:
+		if _, err = io.ReadFull(r, hdr[:8]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(hdr[:8])
+	}
+	if op >= opClose && (n > 125 || !fin) {
+		return false, 0, nil, errWebSocketProtocol
+	}
+	if n > uint64(limit) {
+		return false, 0, nil, errWebSocketTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeWebSocketFrame writes payload as one final frame, masked when the
+// writer is a client.
+func writeWebSocketFrame(w io.Writer, op byte, payload []byte, mask bool) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|op)
+
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if !mask {
+		frame = append(frame, payload...)
+		_, err := w.Write(frame)
+		return err
+	}
+	var key [4]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	frame = append(frame, key[:]...)
+	start := len(frame)
+	frame = append(frame, payload...)
+	for i := start; i < len(frame); i++ {
+		frame[i] ^= key[(i-start)%4]
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+func parseWebSocketClose(payload []byte) *WebSocketCloseError {
+	if len(payload) < 2 {
+		return &WebSocketCloseError{Code: WebSocketCloseNoStatus}
+	}
+	return &WebSocketCloseError{
+		Code:   int(binary.BigEndian.Uint16(payload)),
+		Reason: string(payload[2:]),
+	}
+}
+
+func websocketKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate websocket key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b[:]), nil
+}
+
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// websocketHTTPURL maps ws:// and wss:// onto the schemes net/http dials.
+func websocketHTTPURL(path string) string {
+	switch {
+	case strings.HasPrefix(path, "ws://"):
+		return "http://" + path[len("ws://"):]
+	case strings.HasPrefix(path, "wss://"):
+		return "https://" + path[len("wss://"):]
+	}
+	return path
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoWebSocketServer upgrades, pings once, then echoes messages until the
+// client closes. It records the handshake headers.
+func echoWebSocketServer(t *testing.T, got *http.Header) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got = r.Header.Clone()
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			WebSocketAcceptHeaderName+": "+websocketAccept(r.Header.Get(WebSocketKeyHeaderName))+"\r\n\r\n")
+
+		_ = writeWebSocketFrame(conn, opPing, []byte("hi"), false)
+		for {
+			_, op, payload, err := readWebSocketFrame(brw.Reader, true, 1<<20)
+			if err != nil {
+				return
+			}
+			if op == opClose {
+				_ = writeWebSocketFrame(conn, opClose, payload, false)
+				return
+			}
+			if op == opPong {
+				continue
+			}
+			_ = writeWebSocketFrame(conn, op, payload, false)
+		}
+	}))
+}
+
+func Test_Client_WebSocket_Echo(t *testing.T) {
+	var got http.Header
+	srv := echoWebSocketServer(t, &got)
+	defer srv.Close()
+
+	c := newTestClient(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	conn, err := c.WebSocket(context.Background(), Request{Path: "/ws", ID: "req-1", SessionID: "sess-1"})
+	if err != nil {
+		t.Fatalf("WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	if got.Get(ClientRequestIDHeaderName) != "req-1" || got.Get(ClientSessionIDHeaderName) != "sess-1" {
+		t.Fatalf("handshake headers not injected: %v", got)
+	}
+
+	big := bytes.Repeat([]byte("x"), 70000)
+	for _, msg := range []struct {
+		typ  WebSocketMessageType
+		data []byte
+	}{{WebSocketText, []byte("hello")}, {WebSocketBinary, big}} {
+		if err := conn.WriteMessage(msg.typ, msg.data); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if typ != msg.typ || !bytes.Equal(data, msg.data) {
+			t.Fatalf("echo mismatch: type %d, %d bytes", typ, len(data))
+		}
+	}
+}
+
+func Test_Client_WebSocket_PeerClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			WebSocketAcceptHeaderName+": "+websocketAccept(r.Header.Get(WebSocketKeyHeaderName))+"\r\n\r\n")
+		_ = writeWebSocketFrame(conn, opClose, append([]byte{0x03, 0xe9}, "bye"...), false)
+	}))
+	defer srv.Close()
+
+	conn, err := newTestClient(t, srv.URL).WebSocket(context.Background(), Request{Path: "/"})
+	if err != nil {
+		t.Fatalf("WebSocket: %v", err)
+	}
+	_, _, err = conn.ReadMessage()
+	var cerr *WebSocketCloseError
+	if !errors.As(err, &cerr) || cerr.Code != WebSocketCloseGoingAway || cerr.Reason != "bye" {
+		t.Fatalf("err = %v", err)
+	}
+	if err := conn.WriteMessage(WebSocketText, []byte("late")); err == nil {
+		t.Fatal("write after close succeeded")
+	}
+}
+
+func Test_Client_WebSocket_Rejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, err := newTestClient(t, srv.URL).WebSocket(context.Background(), Request{Path: "/"})
+	var herr *HTTPError
+	if !errors.As(err, &herr) || herr.StatusCode != http.StatusForbidden {
+		t.Fatalf("err = %v", err)
+	}
+}